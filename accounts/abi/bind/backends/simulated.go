@@ -711,15 +711,18 @@ type callMsg struct {
 	ethereum.CallMsg
 }
 
-func (m callMsg) From() common.Address         { return m.CallMsg.From }
-func (m callMsg) Nonce() uint64                { return 0 }
-func (m callMsg) CheckNonce() bool             { return false }
-func (m callMsg) To() *common.Address          { return m.CallMsg.To }
-func (m callMsg) GasPrice() *big.Int           { return m.CallMsg.GasPrice }
-func (m callMsg) Gas() uint64                  { return m.CallMsg.Gas }
-func (m callMsg) Value() *big.Int              { return m.CallMsg.Value }
-func (m callMsg) Data() []byte                 { return m.CallMsg.Data }
-func (m callMsg) AccessList() types.AccessList { return m.CallMsg.AccessList }
+func (m callMsg) From() common.Address                                { return m.CallMsg.From }
+func (m callMsg) Nonce() uint64                                       { return 0 }
+func (m callMsg) CheckNonce() bool                                    { return false }
+func (m callMsg) To() *common.Address                                 { return m.CallMsg.To }
+func (m callMsg) GasPrice() *big.Int                                  { return m.CallMsg.GasPrice }
+func (m callMsg) Gas() uint64                                         { return m.CallMsg.Gas }
+func (m callMsg) Value() *big.Int                                     { return m.CallMsg.Value }
+func (m callMsg) Data() []byte                                        { return m.CallMsg.Data }
+func (m callMsg) AccessList() types.AccessList                        { return m.CallMsg.AccessList }
+func (m callMsg) BlobHashes() []common.Hash                           { return nil }
+func (m callMsg) BlobGasFeeCap() *big.Int                             { return nil }
+func (m callMsg) SetCodeAuthorizations() []types.SetCodeAuthorization { return nil }
 
 // filterBackend implements filters.Backend to support filtering for logs without
 // taking bloom-bits acceleration structures into account.
@@ -786,6 +789,10 @@ func (fb *filterBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event
 	return nullSubscription()
 }
 
+func (fb *filterBackend) SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription {
+	return fb.bc.SubscribeStateDiffEvent(ch)
+}
+
 func (fb *filterBackend) BloomStatus() (uint64, uint64) { return 4096, 0 }
 
 func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
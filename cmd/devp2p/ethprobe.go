@@ -0,0 +1,225 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/rlpx"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ethProbeTimeout bounds the handshake with a single node.
+const ethProbeTimeout = 10 * time.Second
+
+// Message codes of the devp2p base protocol, the eth messages following them
+// as it is the only protocol announced.
+const (
+	helloMsg      = 0x00
+	disconnectMsg = 0x01
+	pingMsg       = 0x02
+	pongMsg       = 0x03
+	ethMsgOffset  = 0x10
+)
+
+// nodeProbe is what the handshakes with a node revealed about it.
+type nodeProbe struct {
+	ID     enode.ID    `json:"id"`
+	N      *enode.Node `json:"record"`
+	Client string      `json:"client,omitempty"`
+	Caps   []string    `json:"caps,omitempty"`
+	Status *ethStatus  `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// ethStatus is the eth protocol status of a node.
+type ethStatus struct {
+	Version    uint32        `json:"version"`
+	NetworkID  uint64        `json:"networkId"`
+	Genesis    common.Hash   `json:"genesis"`
+	ForkHash   hexutil.Bytes `json:"forkHash"`
+	ForkNext   uint64        `json:"forkNext"`
+	Head       common.Hash   `json:"head"`
+	HeadNumber *uint64       `json:"headNumber,omitempty"`
+	TD         *big.Int      `json:"td"`
+}
+
+// probeNode performs the devp2p and eth handshakes with a node, and requests the
+// header of its head block.
+func probeNode(n *enode.Node, key *ecdsa.PrivateKey) *nodeProbe {
+	probe := &nodeProbe{ID: n.ID(), N: n}
+	if err := probe.run(key); err != nil {
+		probe.Error = err.Error()
+	}
+	return probe
+}
+
+func (probe *nodeProbe) run(key *ecdsa.PrivateKey) error {
+	if probe.N.IP() == nil || probe.N.TCP() == 0 {
+		return errors.New("no TCP endpoint")
+	}
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", probe.N.IP(), probe.N.TCP()), ethProbeTimeout)
+	if err != nil {
+		return err
+	}
+	conn := rlpx.NewConn(fd, probe.N.Pubkey())
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(ethProbeTimeout))
+	if _, err := conn.Handshake(key); err != nil {
+		return err
+	}
+	// Exchange the devp2p handshakes, announcing the eth protocol only
+	var caps []p2p.Cap
+	for _, version := range eth.ProtocolVersions {
+		caps = append(caps, p2p.Cap{Name: eth.ProtocolName, Version: version})
+	}
+	hello := &ethtest.Hello{Version: 5, Caps: caps, ID: crypto.FromECDSAPub(&key.PublicKey)[1:]}
+	if err := writeProbeMsg(conn, helloMsg, hello); err != nil {
+		return err
+	}
+	remote := new(ethtest.Hello)
+	if err := readProbeMsg(conn, helloMsg, remote); err != nil {
+		return err
+	}
+	probe.Client = remote.Name
+	var version uint
+	for _, cap := range remote.Caps {
+		probe.Caps = append(probe.Caps, cap.String())
+		for _, ours := range caps {
+			if cap == ours && cap.Version > version {
+				version = cap.Version
+			}
+		}
+	}
+	if remote.Version >= 5 {
+		conn.SetSnappy(true)
+	}
+	if version == 0 {
+		return errors.New("no common eth protocol version")
+	}
+	// Echo the status of the node back, for it to accept the handshake and serve
+	// the header of its head
+	status := new(eth.StatusPacket)
+	if err := readProbeMsg(conn, ethMsgOffset+eth.StatusMsg, status); err != nil {
+		return err
+	}
+	probe.Status = &ethStatus{
+		Version:   status.ProtocolVersion,
+		NetworkID: status.NetworkID,
+		Genesis:   status.Genesis,
+		ForkHash:  status.ForkID.Hash[:],
+		ForkNext:  status.ForkID.Next,
+		Head:      status.Head,
+		TD:        status.TD,
+	}
+	status.ProtocolVersion = uint32(version)
+	if err := writeProbeMsg(conn, ethMsgOffset+eth.StatusMsg, status); err != nil {
+		return err
+	}
+	if version >= eth.ETH67 {
+		extension, err := (&eth.UpgradeStatusExtension{DisablePeerTxBroadcast: true}).Encode()
+		if err != nil {
+			return err
+		}
+		if err := writeProbeMsg(conn, ethMsgOffset+eth.UpgradeStatusMsg, &eth.UpgradeStatusPacket{Extension: extension}); err != nil {
+			return err
+		}
+		if err := readProbeMsg(conn, ethMsgOffset+eth.UpgradeStatusMsg, new(eth.UpgradeStatusPacket)); err != nil {
+			return err
+		}
+	}
+	// Request the header of the head block for its number
+	var (
+		query   = &eth.GetBlockHeadersPacket{Origin: eth.HashOrNumber{Hash: status.Head}, Amount: 1}
+		headers eth.BlockHeadersPacket
+	)
+	if version >= eth.ETH66 {
+		err = writeProbeMsg(conn, ethMsgOffset+eth.GetBlockHeadersMsg, &eth.GetBlockHeadersPacket66{RequestId: rand.Uint64(), GetBlockHeadersPacket: query})
+	} else {
+		err = writeProbeMsg(conn, ethMsgOffset+eth.GetBlockHeadersMsg, query)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= eth.ETH66 {
+		res := new(eth.BlockHeadersPacket66)
+		err = readProbeMsg(conn, ethMsgOffset+eth.BlockHeadersMsg, res)
+		headers = res.BlockHeadersPacket
+	} else {
+		err = readProbeMsg(conn, ethMsgOffset+eth.BlockHeadersMsg, &headers)
+	}
+	if err != nil {
+		return err
+	}
+	if len(headers) == 1 && headers[0].Hash() == status.Head {
+		number := headers[0].Number.Uint64()
+		probe.Status.HeadNumber = &number
+	}
+	writeProbeMsg(conn, disconnectMsg, []p2p.DiscReason{p2p.DiscRequested})
+	return nil
+}
+
+// writeProbeMsg sends a message to the probed node.
+func writeProbeMsg(conn *rlpx.Conn, code uint64, msg interface{}) error {
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(code, payload)
+	return err
+}
+
+// readProbeMsg reads messages from the probed node until one with the given code
+// arrives, answering pings and skipping the announcements.
+func readProbeMsg(conn *rlpx.Conn, code uint64, msg interface{}) error {
+	for {
+		have, data, _, err := conn.Read()
+		if err != nil {
+			return err
+		}
+		switch {
+		case have == code:
+			return rlp.DecodeBytes(data, msg)
+		case have == disconnectMsg:
+			var reason []p2p.DiscReason
+			if rlp.DecodeBytes(data, &reason); len(reason) == 0 {
+				return errors.New("disconnected")
+			}
+			return fmt.Errorf("disconnected: %v", reason[0])
+		case have == pingMsg:
+			if err := writeProbeMsg(conn, pongMsg, []struct{}{}); err != nil {
+				return err
+			}
+		case have < ethMsgOffset:
+			return fmt.Errorf("unexpected devp2p message %d", have)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+// fakeEthProtocol performs the eth handshake and serves the header of its head.
+func fakeEthProtocol(head *types.Header, genesis common.Hash) p2p.Protocol {
+	return p2p.Protocol{
+		Name:    eth.ProtocolName,
+		Version: eth.ETH67,
+		Length:  17,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			status := &eth.StatusPacket{
+				ProtocolVersion: eth.ETH67,
+				NetworkID:       88882,
+				TD:              big.NewInt(1000),
+				Head:            head.Hash(),
+				Genesis:         genesis,
+				ForkID:          forkid.ID{Hash: [4]byte{0xde, 0xad, 0xbe, 0xef}},
+			}
+			if err := p2p.Send(rw, eth.StatusMsg, status); err != nil {
+				return err
+			}
+			if err := p2p.ExpectMsg(rw, eth.StatusMsg, status); err != nil {
+				return err
+			}
+			extension, _ := (&eth.UpgradeStatusExtension{}).Encode()
+			if err := p2p.Send(rw, eth.UpgradeStatusMsg, &eth.UpgradeStatusPacket{Extension: extension}); err != nil {
+				return err
+			}
+			if msg, err := rw.ReadMsg(); err != nil || msg.Code != eth.UpgradeStatusMsg {
+				return fmt.Errorf("upgrade status expected: %v", err)
+			}
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				return err
+			}
+			query := new(eth.GetBlockHeadersPacket66)
+			if err := msg.Decode(query); err != nil {
+				return err
+			}
+			if err := p2p.Send(rw, eth.BlockHeadersMsg, &eth.BlockHeadersPacket66{RequestId: query.RequestId, BlockHeadersPacket: []*types.Header{head}}); err != nil {
+				return err
+			}
+			_, err = rw.ReadMsg()
+			return err
+		},
+	}
+}
+
+func TestSnapshotProbe(t *testing.T) {
+	head := &types.Header{Number: big.NewInt(1234), Difficulty: big.NewInt(2)}
+	genesis := common.HexToHash("0x01")
+
+	key, _ := crypto.GenerateKey()
+	srv := &p2p.Server{Config: p2p.Config{
+		PrivateKey:  key,
+		MaxPeers:    10,
+		ListenAddr:  "127.0.0.1:0",
+		NoDiscovery: true,
+		Name:        "Geth/v1.1.8-stable/linux-amd64/go1.17",
+		Protocols:   []p2p.Protocol{fakeEthProtocol(head, genesis)},
+	}}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	ourKey, _ := crypto.GenerateKey()
+	probe := probeNode(srv.Self(), ourKey)
+	if probe.Error != "" {
+		t.Fatalf("probe failed: %v", probe.Error)
+	}
+	if probe.Client != srv.Name || len(probe.Caps) != 1 || probe.Caps[0] != "eth/67" {
+		t.Errorf("client mismatch: %s %v", probe.Client, probe.Caps)
+	}
+	if probe.Status.NetworkID != 88882 || probe.Status.Genesis != genesis || probe.Status.Head != head.Hash() {
+		t.Errorf("status mismatch: %+v", probe.Status)
+	}
+	if probe.Status.HeadNumber == nil || *probe.Status.HeadNumber != 1234 {
+		t.Errorf("head number mismatch: %v", probe.Status.HeadNumber)
+	}
+	snapshot := makeSnapshot([]*nodeProbe{probe, {Error: "connection refused"}})
+	if snapshot.Nodes != 2 || snapshot.Reachable != 1 || len(snapshot.Networks) != 1 {
+		t.Fatalf("snapshot mismatch: %+v", snapshot)
+	}
+	if network := snapshot.Networks[0]; network.HighestHead != 1234 || network.Clients["Geth/v1.1.8-stable"] != 1 || network.ForkIDs["0xdeadbeef/0"] != 1 {
+		t.Errorf("network summary mismatch: %+v", network)
+	}
+	var metrics bytes.Buffer
+	writeSnapshotMetrics(&metrics, snapshot)
+	for _, line := range []string{
+		"devp2p_nodes_reachable 1",
+		fmt.Sprintf(`devp2p_network_clients{network="88882",genesis="%x",client="Geth",version="v1.1.8-stable"} 1`, genesis),
+		fmt.Sprintf(`devp2p_network_head{network="88882",genesis="%x"} 1234`, genesis),
+	} {
+		if !strings.Contains(metrics.String(), line+"\n") {
+			t.Errorf("metric missing: %s\n%s", line, metrics.String())
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:      "snapshot",
+		Usage:     "Crawls the DHT and handshakes the nodes found into a network snapshot",
+		ArgsUsage: "<nodes.json>",
+		Action:    snapshotNetwork,
+		Flags: []cli.Flag{
+			bootnodesFlag,
+			nodekeyFlag,
+			crawlTimeoutFlag,
+			snapshotWorkersFlag,
+			snapshotOutputFlag,
+			snapshotMetricsFlag,
+		},
+		Description: `
+Crawl the discovery v4 DHT like discv4 crawl, updating the given nodes.json file,
+then perform the devp2p and eth handshakes with every node found, recording its
+client version, capabilities, network, fork ID and head block. The snapshot of
+the networks found is written as JSON, and optionally as Prometheus metrics in
+the text exposition format, for the node exporter textfile collector.`,
+	}
+)
+
+var (
+	snapshotWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of nodes handshaked concurrently",
+		Value: 32,
+	}
+	snapshotOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Snapshot JSON file (- for stdout)",
+		Value: "-",
+	}
+	snapshotMetricsFlag = cli.StringFlag{
+		Name:  "metrics",
+		Usage: "Prometheus metrics file",
+	}
+)
+
+// networkSnapshot is the composition of the networks found in the DHT.
+type networkSnapshot struct {
+	Time      time.Time         `json:"time"`
+	Nodes     int               `json:"nodes"`
+	Reachable int               `json:"reachable"`
+	Networks  []*networkSummary `json:"networks"`
+	Probes    []*nodeProbe      `json:"probes"`
+}
+
+// networkSummary aggregates the nodes of a network completing the eth handshake.
+type networkSummary struct {
+	NetworkID   uint64         `json:"networkId"`
+	Genesis     common.Hash    `json:"genesis"`
+	Nodes       int            `json:"nodes"`
+	Clients     map[string]int `json:"clients"`
+	ForkIDs     map[string]int `json:"forkIds"`
+	HighestHead uint64         `json:"highestHead"`
+}
+
+func snapshotNetwork(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("need nodes file as argument")
+	}
+	nodesFile := ctx.Args().First()
+	var inputSet nodeSet
+	if common.FileExist(nodesFile) {
+		inputSet = loadNodesJSON(nodesFile)
+	}
+	disc := startV4(ctx)
+	c := newCrawler(inputSet, disc, disc.RandomNodes())
+	c.revalidateInterval = 10 * time.Minute
+	output := c.run(ctx.Duration(crawlTimeoutFlag.Name))
+	disc.Close()
+	writeNodesJSON(nodesFile, output)
+
+	key, err := crypto.GenerateKey()
+	if ctx.IsSet(nodekeyFlag.Name) {
+		key, err = crypto.HexToECDSA(ctx.String(nodekeyFlag.Name))
+	}
+	if err != nil {
+		return err
+	}
+	snapshot := makeSnapshot(probeNodes(output.nodes(), key, ctx.Int(snapshotWorkersFlag.Name)))
+
+	out, err := json.MarshalIndent(snapshot, "", jsonIndent)
+	if err != nil {
+		return err
+	}
+	if file := ctx.String(snapshotOutputFlag.Name); file == "-" {
+		os.Stdout.Write(out)
+	} else if err := ioutil.WriteFile(file, out, 0644); err != nil {
+		return err
+	}
+	if file := ctx.String(snapshotMetricsFlag.Name); file != "" {
+		var buf bytes.Buffer
+		writeSnapshotMetrics(&buf, snapshot)
+		return ioutil.WriteFile(file, buf.Bytes(), 0644)
+	}
+	return nil
+}
+
+// probeNodes handshakes the given nodes with a pool of workers.
+func probeNodes(nodes []*enode.Node, key *ecdsa.PrivateKey, workers int) []*nodeProbe {
+	var (
+		probes = make([]*nodeProbe, len(nodes))
+		next   = make(chan int)
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				probes[i] = probeNode(nodes[i], key)
+				log.Info("Probed node", "id", nodes[i].ID(), "client", probes[i].Client, "err", probes[i].Error)
+			}
+		}()
+	}
+	for i := range nodes {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return probes
+}
+
+// makeSnapshot aggregates the node probes per network.
+func makeSnapshot(probes []*nodeProbe) *networkSnapshot {
+	snapshot := &networkSnapshot{Time: truncNow(), Nodes: len(probes), Probes: probes}
+
+	networks := make(map[common.Hash]*networkSummary)
+	for _, probe := range probes {
+		if probe.Status == nil {
+			continue
+		}
+		snapshot.Reachable++
+
+		network := networks[probe.Status.Genesis]
+		if network == nil {
+			network = &networkSummary{
+				NetworkID: probe.Status.NetworkID,
+				Genesis:   probe.Status.Genesis,
+				Clients:   make(map[string]int),
+				ForkIDs:   make(map[string]int),
+			}
+			networks[probe.Status.Genesis] = network
+			snapshot.Networks = append(snapshot.Networks, network)
+		}
+		network.Nodes++
+		client, version := clientVersion(probe.Client)
+		network.Clients[client+"/"+version]++
+		network.ForkIDs[fmt.Sprintf("%s/%d", probe.Status.ForkHash, probe.Status.ForkNext)]++
+		if head := probe.Status.HeadNumber; head != nil && *head > network.HighestHead {
+			network.HighestHead = *head
+		}
+	}
+	sort.Slice(snapshot.Networks, func(i, j int) bool {
+		return snapshot.Networks[i].Nodes > snapshot.Networks[j].Nodes
+	})
+	return snapshot
+}
+
+// clientVersion splits the client name and version out of the name a node
+// announces, e.g. Geth/v1.1.8-stable/linux-amd64/go1.17.
+func clientVersion(name string) (string, string) {
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) < 2 {
+		return name, ""
+	}
+	return parts[0], parts[1]
+}
+
+// writeSnapshotMetrics writes the snapshot in the Prometheus text exposition
+// format.
+func writeSnapshotMetrics(w io.Writer, snapshot *networkSnapshot) {
+	fmt.Fprintf(w, "# HELP devp2p_nodes Number of nodes found in the DHT\n# TYPE devp2p_nodes gauge\n")
+	fmt.Fprintf(w, "devp2p_nodes %d\n", snapshot.Nodes)
+	fmt.Fprintf(w, "# HELP devp2p_nodes_reachable Number of nodes completing the eth handshake\n# TYPE devp2p_nodes_reachable gauge\n")
+	fmt.Fprintf(w, "devp2p_nodes_reachable %d\n", snapshot.Reachable)
+
+	fmt.Fprintf(w, "# HELP devp2p_network_nodes Number of nodes per network\n# TYPE devp2p_network_nodes gauge\n")
+	for _, network := range snapshot.Networks {
+		fmt.Fprintf(w, "devp2p_network_nodes{network=\"%d\",genesis=\"%x\"} %d\n", network.NetworkID, network.Genesis, network.Nodes)
+	}
+	fmt.Fprintf(w, "# HELP devp2p_network_head Highest head block per network\n# TYPE devp2p_network_head gauge\n")
+	for _, network := range snapshot.Networks {
+		fmt.Fprintf(w, "devp2p_network_head{network=\"%d\",genesis=\"%x\"} %d\n", network.NetworkID, network.Genesis, network.HighestHead)
+	}
+	fmt.Fprintf(w, "# HELP devp2p_network_clients Number of nodes per network and client version\n# TYPE devp2p_network_clients gauge\n")
+	for _, network := range snapshot.Networks {
+		for _, key := range sortedKeys(network.Clients) {
+			client, version := clientVersion(key)
+			fmt.Fprintf(w, "devp2p_network_clients{network=\"%d\",genesis=\"%x\",client=\"%s\",version=\"%s\"} %d\n", network.NetworkID, network.Genesis, metricsLabel(client), metricsLabel(version), network.Clients[key])
+		}
+	}
+	fmt.Fprintf(w, "# HELP devp2p_network_forkids Number of nodes per network and fork ID\n# TYPE devp2p_network_forkids gauge\n")
+	for _, network := range snapshot.Networks {
+		for _, key := range sortedKeys(network.ForkIDs) {
+			parts := strings.SplitN(key, "/", 2)
+			fmt.Fprintf(w, "devp2p_network_forkids{network=\"%d\",genesis=\"%x\",hash=\"%s\",next=\"%s\"} %d\n", network.NetworkID, network.Genesis, parts[0], parts[1], network.ForkIDs[key])
+		}
+	}
+}
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsLabel escapes a label value announced by a remote node.
+func metricsLabel(value string) string {
+	return metricsLabelEscaper.Replace(value)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+
+	"gopkg.in/urfave/cli.v1"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/backup"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+var (
+	backupBlockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Number of the block to back up or to restore up to (defaults to the latest)",
+	}
+	backupKeyFlag = cli.StringFlag{
+		Name:  "key",
+		Usage: "File holding the private key signing the backup",
+	}
+	backupCommand = cli.Command{
+		Name:      "backup",
+		Usage:     "Incremental backups of the chain database",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			backupCreateCmd,
+			backupRestoreCmd,
+			backupListCmd,
+			backupSignCmd,
+		},
+	}
+	backupCreateCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupCreate),
+		Name:      "create",
+		Usage:     "Append an increment holding the chain since the last one to a backup",
+		ArgsUsage: "<target>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV3Flag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			backupBlockFlag,
+		},
+		Description: `This command backs up the canonical blocks since the previous increment of the
+backup and the state of the chosen block, by default the latest one whose state
+is on disk. Only the trie nodes and codes not part of the state of the previous
+increment are stored. The target is a local directory or an object storage URL
+of the form s3://bucket/prefix or gs://bucket/prefix.
+
+The database is read without locking it, so the node may keep running. Unlike
+copying the database directory, the backup is consistent at the chosen block.`,
+	}
+	backupRestoreCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupRestore),
+		Name:      "restore",
+		Usage:     "Restore the chain database from a backup",
+		ArgsUsage: "<source>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV3Flag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			backupBlockFlag,
+			verifyStateThreadsFlag,
+		},
+		Description: `This command restores the increments of the backup up to the one ending at the
+chosen block, by default the latest one, into an empty database. No genesis file
+is needed, the chain configuration is restored from the backup. The checksums
+of all the files, the blocks against their headers and the complete restored
+state are verified before the head of the chain is set. The node must not be
+running.`,
+	}
+	backupListCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupList),
+		Name:      "list",
+		Usage:     "List the increments of a backup",
+		ArgsUsage: "<source>",
+		Description: `This command prints the blocks, the creation times and the sizes of the
+increments of the backup.`,
+	}
+	backupSignCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupSign),
+		Name:      "sign",
+		Usage:     "Sign the manifest of a backup for nodes to bootstrap from it",
+		ArgsUsage: "<target>",
+		Flags: []cli.Flag{
+			backupKeyFlag,
+		},
+		Description: `This command signs the manifest listing the increments and the checksums of
+the files of the backup. Nodes started with --bootstrap.url pointing to the
+backup and --bootstrap.signers listing the signer restore it into their empty
+database instead of syncing from the network. The backup must be signed again
+after every "backup create".`,
+	}
+)
+
+// openBackupStore opens the backup given as the only argument of the command.
+func openBackupStore(ctx *cli.Context) (backup.Store, error) {
+	if ctx.NArg() != 1 {
+		return nil, fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	return backup.OpenStore(ctx.Args().Get(0))
+}
+
+// backupNumber returns the block chosen with --block, if any.
+func backupNumber(ctx *cli.Context) *uint64 {
+	if !ctx.IsSet(backupBlockFlag.Name) {
+		return nil
+	}
+	number := ctx.Uint64(backupBlockFlag.Name)
+	return &number
+}
+
+func backupCreate(ctx *cli.Context) error {
+	store, err := openBackupStore(ctx)
+	if err != nil {
+		return err
+	}
+	// Open the database as a secondary, next to the node possibly running
+	if err := ctx.GlobalSet(utils.DataDirReadOnlyFlag.Name, "true"); err != nil {
+		return err
+	}
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db, err := rawdb.NewSecondaryDatabase(rawdb.OpenOptions{
+		Directory:         stack.ResolvePath("chaindata"),
+		AncientsDirectory: stack.ResolveAncient("chaindata", config.Eth.DatabaseFreezer),
+		Cache:             ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100,
+		Handles:           utils.MakeDatabaseHandles(),
+	})
+	if err != nil {
+		utils.Fatalf("Could not open database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = backup.Create(db, store, backupNumber(ctx), db.Refresh)
+	return err
+}
+
+func backupRestore(ctx *cli.Context) error {
+	store, err := openBackupStore(ctx)
+	if err != nil {
+		return err
+	}
+	// The genesis block is restored from the backup too
+	stack, _ := makeUninitialisedNode(ctx, nil)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false, true)
+	defer db.Close()
+
+	_, err = backup.Restore(db, store, backupNumber(ctx), ctx.Int(verifyStateThreadsFlag.Name))
+	return err
+}
+
+func backupList(ctx *cli.Context) error {
+	store, err := openBackupStore(ctx)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.ReadManifest(store)
+	if err != nil {
+		return err
+	}
+	if len(manifest.Increments) == 0 {
+		fmt.Println("No increments found")
+		return nil
+	}
+	fmt.Printf("Genesis: %x\n", manifest.Genesis)
+	for _, inc := range manifest.Increments {
+		var size int64
+		for _, file := range inc.Files {
+			size += file.Size
+		}
+		fmt.Printf("#%d-#%d hash=%x root=%x created=%s size=%v nodes=%d codes=%d\n",
+			inc.First, inc.Number, inc.Hash, inc.Root, inc.Created.Format(time.RFC3339), common.StorageSize(size), inc.Nodes, inc.Codes)
+	}
+	return nil
+}
+
+func backupSign(ctx *cli.Context) error {
+	store, err := openBackupStore(ctx)
+	if err != nil {
+		return err
+	}
+	if !ctx.IsSet(backupKeyFlag.Name) {
+		return fmt.Errorf("missing --%s", backupKeyFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(ctx.String(backupKeyFlag.Name))
+	if err != nil {
+		return err
+	}
+	return backup.Sign(store, key)
+}
+
+// bootstrapChain restores the backup given with --bootstrap.url into the chain
+// database, unless it already holds a chain.
+func bootstrapChain(ctx *cli.Context, stack *node.Node) {
+	if ctx.GlobalBool(utils.DataDirReadOnlyFlag.Name) || ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		utils.Fatalf("Bootstrapping requires the database of a full node")
+	}
+	var trust backup.Trust
+	for _, signer := range utils.SplitAndTrim(ctx.GlobalString(utils.BootstrapSignersFlag.Name)) {
+		if !common.IsHexAddress(signer) {
+			utils.Fatalf("Invalid bootstrap signer: %s", signer)
+		}
+		trust.Signers = append(trust.Signers, common.HexToAddress(signer))
+	}
+	if ctx.GlobalIsSet(utils.BootstrapCheckpointFlag.Name) {
+		checkpoint, err := backup.ParseCheckpoint(ctx.GlobalString(utils.BootstrapCheckpointFlag.Name))
+		if err != nil {
+			utils.Fatalf("Invalid bootstrap checkpoint: %v", err)
+		}
+		trust.Checkpoint = checkpoint
+	}
+	store, err := backup.OpenStore(ctx.GlobalString(utils.BootstrapURLFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to open bootstrap backup: %v", err)
+	}
+	db := utils.MakeChainDatabase(ctx, stack, false, true)
+	defer db.Close()
+
+	if head := rawdb.ReadHeadHeader(db); head != nil && head.Number.Uint64() > 0 {
+		log.Info("Chain already initialised, skipping bootstrap", "number", head.Number, "hash", head.Hash())
+		return
+	}
+	if _, err := backup.Bootstrap(db, store, trust, runtime.NumCPU()); err != nil {
+		utils.Fatalf("Failed to bootstrap chain: %v", err)
+	}
+}
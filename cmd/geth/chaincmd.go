@@ -19,6 +19,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"path"
@@ -34,10 +35,12 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/tests"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -114,6 +117,7 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.MetricsInfluxDBUsernameFlag,
 			utils.MetricsInfluxDBPasswordFlag,
 			utils.MetricsInfluxDBTagsFlag,
+			utils.ImportTraceOTLPFlag,
 			utils.TxLookupLimitFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -141,6 +145,65 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	importHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(importHistory),
+		Name:      "import-history",
+		Usage:     "Import blockchain history from era1 archives",
+		ArgsUsage: "<dir|url>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.TxLookupLimitFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-history command imports blocks and receipts from era1 archives, read
+from a local directory or downloaded from an HTTP(S) location serving a history
+export. The node must be at genesis. The archives are verified against their
+checksums and accumulators, the headers by the consensus engine, and the data is
+written straight into the ancient store, after which the node only needs to sync
+the state and the recent blocks.`,
+	}
+	exportHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportHistory),
+		Name:      "export-history",
+		Usage:     "Export blockchain history to era1 archives",
+		ArgsUsage: "<dir> <first> <last>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-history command exports the blocks in the [first, last] range along
+with their receipts into era1 archives of 8192 blocks each, in the given directory.
+The first block must be a multiple of 8192. A checksums.txt file listing the sha256
+checksum of every archive is written next to them, so the directory can be served
+over HTTP to bootstrap other nodes with import-history.`,
+	}
+	exportTestsCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportTests),
+		Name:      "export-tests",
+		Usage:     "Export blocks as a blockchain test",
+		ArgsUsage: "<filename> <first> <last>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-tests command converts the blocks in the [first, last] range into a
+fixture of the standard blockchain tests, to compare their execution with other
+clients. The state of the parent of the first block must be available.
+
+The transactions are sealed anew by ethash, in blocks numbered from 1 on top of a
+genesis holding the accounts they touch. The chain config, with the forks moved
+back accordingly, is embedded in the fixture; the consensus specific transactions,
+like the parlia system transactions, are left out.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -449,6 +512,86 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// importHistory imports era1 archives from a directory or a URL.
+func importHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	start := time.Now()
+	if err := utils.ImportHistory(chain, ctx.Args().First()); err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportHistory exports a block range into era1 archives.
+func exportHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	first, ferr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	last, lerr := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	if first > last {
+		utils.Fatalf("Export error: first block %d after last block %d\n", first, last)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	start := time.Now()
+	if err := utils.ExportHistory(chain, ctx.Args().First(), first, last, era.MaxEra1Size); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportTests exports a range of blocks as a blockchain test.
+func exportTests(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	first, ferr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	last, lerr := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	start := time.Now()
+	test, err := tests.ExportBlockTest(chain, first, last)
+	if err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	name := fmt.Sprintf("blocks_%d_%d", first, last)
+	out, err := json.MarshalIndent(map[string]*tests.BlockTest{name: test}, "", "  ")
+	if err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	if err := ioutil.WriteFile(ctx.Args().First(), out, 0644); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
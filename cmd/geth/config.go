@@ -32,7 +32,9 @@ import (
 	"github.com/naoina/toml"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -80,11 +82,22 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+type exporterConfig struct {
+	URL string `toml:",omitempty"`
+}
+
+type webhookConfig struct {
+	Enabled bool `toml:",omitempty"`
+}
+
 type gethConfig struct {
 	Eth      ethconfig.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
+	Exporter exporterConfig
+	Webhook  webhookConfig
 	Metrics  metrics.Config
+	Log      logConfig
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -125,6 +138,18 @@ func readGenesisConfig(ctx *cli.Context) *core.Genesis {
 	if ctx.GlobalBool(utils.ChilizMainnetFlag.Name) {
 		return config.ChilizMainnetGenesisConfig
 	}
+	// Named networks bundle their genesis config
+	if ctx.GlobalIsSet(utils.NetworkFlag.Name) {
+		return utils.MakeNetwork(ctx).Genesis
+	}
+	// Developer chains generate their genesis along with the developer account
+	if ctx.GlobalBool(utils.DeveloperFlag.Name) || ctx.GlobalBool(utils.DeveloperParliaFlag.Name) {
+		return nil
+	}
+	// A read-only data directory uses the genesis stored by the running node
+	if ctx.GlobalBool(utils.DataDirReadOnlyFlag.Name) && !ctx.GlobalIsSet(utils.GenesisFlag.Name) {
+		return nil
+	}
 	// Make sure we have a valid genesis JSON
 	genesisPath := ctx.GlobalString(utils.GenesisFlag.Name)
 	if len(genesisPath) == 0 {
@@ -145,6 +170,31 @@ func readGenesisConfig(ctx *cli.Context) *core.Genesis {
 
 // makeConfigNode loads geth configuration and creates a blank node instance.
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
+	stack, cfg := makeUninitialisedNode(ctx, readGenesisConfig(ctx))
+
+	// Open and initialise both full and light databases, unless served remotely
+	// or owned by another running node
+	if cfg.Node.DBEngine != rawdb.DBRemote && !cfg.Node.DataDirReadOnly {
+		for _, name := range []string{"chaindata", "lightchaindata"} {
+			chaindb, err := stack.OpenDatabase(name, 0, 0, "", false)
+			if err != nil {
+				utils.Fatalf("Failed to open database: %v", err)
+			}
+			_, hash, err := core.SetupGenesisBlock(chaindb, cfg.Eth.Genesis)
+			if err != nil {
+				utils.Fatalf("Failed to write genesis block: %v", err)
+			}
+			_ = chaindb.Close()
+			log.Info("Successfully wrote genesis state", "database", name, "hash", hash.String())
+		}
+	}
+
+	return stack, cfg
+}
+
+// makeUninitialisedNode loads geth configuration and creates a blank node
+// instance, without writing the genesis block into its databases.
+func makeUninitialisedNode(ctx *cli.Context, genesis *core.Genesis) (*node.Node, gethConfig) {
 	// Load defaults.
 	cfg := gethConfig{
 		Eth:     ethconfig.Defaults,
@@ -159,7 +209,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		}
 	}
 
-	cfg.Eth.Genesis = readGenesisConfig(ctx)
+	cfg.Eth.Genesis = genesis
 
 	// Apply flags.
 	utils.SetNodeConfig(ctx, &cfg.Node)
@@ -171,22 +221,16 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
+	if ctx.GlobalIsSet(utils.ExporterURLFlag.Name) {
+		cfg.Exporter.URL = ctx.GlobalString(utils.ExporterURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(utils.WebhooksFlag.Name) {
+		cfg.Webhook.Enabled = ctx.GlobalBool(utils.WebhooksFlag.Name)
+	}
 	applyMetricConfig(ctx, &cfg)
-
-	// Open and initialise both full and light databases
-	for _, name := range []string{"chaindata", "lightchaindata"} {
-		chaindb, err := stack.OpenDatabase(name, 0, 0, "", false)
-		if err != nil {
-			utils.Fatalf("Failed to open database: %v", err)
-		}
-		_, hash, err := core.SetupGenesisBlock(chaindb, cfg.Eth.Genesis)
-		if err != nil {
-			utils.Fatalf("Failed to write genesis block: %v", err)
-		}
-		_ = chaindb.Close()
-		log.Info("Successfully wrote genesis state", "database", name, "hash", hash.String())
+	if err := applyLogConfig(cfg.Log); err != nil {
+		utils.Fatalf("Invalid log configuration: %v", err)
 	}
-
 	return stack, cfg
 }
 
@@ -196,6 +240,23 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	if ctx.GlobalIsSet(utils.OverrideBerlinFlag.Name) {
 		cfg.Eth.OverrideBerlin = new(big.Int).SetUint64(ctx.GlobalUint64(utils.OverrideBerlinFlag.Name))
 	}
+	if ctx.GlobalIsSet(utils.OverrideForksFlag.Name) {
+		var signers []common.Address
+		for _, signer := range utils.SplitAndTrim(ctx.GlobalString(utils.OverrideSignersFlag.Name)) {
+			if !common.IsHexAddress(signer) {
+				utils.Fatalf("Invalid fork override signer: %s", signer)
+			}
+			signers = append(signers, common.HexToAddress(signer))
+		}
+		overrides, err := core.LoadForkOverrides(ctx.GlobalString(utils.OverrideForksFlag.Name), signers)
+		if err != nil {
+			utils.Fatalf("Failed to load the fork overrides: %v", err)
+		}
+		cfg.Eth.OverrideForks = overrides
+	}
+	if ctx.GlobalIsSet(utils.BootstrapURLFlag.Name) {
+		bootstrapChain(ctx, stack)
+	}
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Configure catalyst.
@@ -212,10 +273,32 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, backend, cfg.Node)
 	}
+	// Configure gRPC if requested
+	if cfg.Node.GRPCHost != "" {
+		utils.RegisterGRPCService(stack, backend, cfg.Node)
+	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
 	}
+	// Add the chain data exporter if requested.
+	if cfg.Exporter.URL != "" {
+		if eth == nil {
+			utils.Fatalf("The chain data exporter does not work in light client mode.")
+		}
+		utils.RegisterExporterService(stack, eth, cfg.Exporter.URL)
+	}
+	// Add the webhook service if requested.
+	if cfg.Webhook.Enabled {
+		if eth == nil {
+			utils.Fatalf("The webhook service does not work in light client mode.")
+		}
+		utils.RegisterWebhookService(stack, eth)
+	}
+	// Allow changing a subset of the configuration at runtime
+	if eth != nil {
+		registerConfigReloader(stack, eth, ctx.GlobalString(configFileFlag.Name), &cfg)
+	}
 	return stack, backend
 }
 
@@ -17,9 +17,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"time"
@@ -29,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/trie"
@@ -63,6 +67,9 @@ Remove blockchain and state databases`,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
 			ancientInspectCmd,
+			dbRecompressCmd,
+			dbVerifyStateCmd,
+			dbMigrateCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -196,6 +203,81 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		},
 		Description: "This command displays information about the freezer index.",
 	}
+	dbRecompressCmd = cli.Command{
+		Action:    utils.MigrateFlags(freezerRecompress),
+		Name:      "recompress",
+		Usage:     "Recompress a freezer table with another codec",
+		ArgsUsage: "<type> <codec> <zstd dictionary file (optional)>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV3Flag,
+		},
+		Description: `This command rewrites the given ancient table with the chosen codec
+("none", "snappy" or "zstd"). A zstd dictionary trained on the table items, e.g.
+with "zstd --train", may be passed to improve the compression of small items.
+The node must not be running.`,
+	}
+	verifyStateRootFlag = cli.StringFlag{
+		Name:  "root",
+		Usage: "State root to verify (defaults to the head state)",
+	}
+	verifyStateThreadsFlag = cli.IntFlag{
+		Name:  "threads",
+		Usage: "Number of storage tries verified in parallel",
+		Value: runtime.NumCPU(),
+	}
+	dbVerifyStateCmd = cli.Command{
+		Action: utils.MigrateFlags(dbVerifyState),
+		Name:   "verify-state",
+		Usage:  "Verify that all the state trie nodes and codes are present and valid",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV3Flag,
+			verifyStateRootFlag,
+			verifyStateThreadsFlag,
+		},
+		Description: `This command walks every node of the account trie and of all the storage
+tries of the given state, verifying that it is present and hashes to its
+reference, and that all the contract codes are present. Unlike "snapshot
+traverse-rawstate" it doesn't stop at the first damaged node, but reports all of
+them. A damaged state can be healed from the network without a resync by running
+the node and calling debug_healState with the same root.`,
+	}
+	dbMigrateCmd = cli.Command{
+		Action:    utils.MigrateFlags(dbMigrate),
+		Name:      "migrate",
+		Usage:     "Convert the key-value database to another engine",
+		ArgsUsage: "<engine>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.RinkebyFlag,
+			utils.GoerliFlag,
+			utils.YoloV3Flag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			utils.DBPebbleCompactionsFlag,
+			utils.DBPebbleWALFlag,
+		},
+		Description: `This command copies all the entries of the key-value database into a new
+one backed by the given engine ("leveldb" or "pebble"), then swaps it in place of
+the old one. The ancient store and the other directories inside the database
+directory are moved over as they are. The old database is kept next to the new
+one until removed by hand. The node must not be running.`,
+	}
 	ancientInspectCmd = cli.Command{
 		Action: utils.MigrateFlags(ancientInspect),
 		Name:   "inspect-reserved-oldest-blocks",
@@ -527,3 +609,184 @@ func freezerInspect(ctx *cli.Context) error {
 	}
 	return nil
 }
+
+func freezerRecompress(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		kind        = ctx.Args().Get(0)
+		compression = ctx.Args().Get(1)
+		dict        []byte
+		err         error
+	)
+	if ctx.NArg() > 2 {
+		if dict, err = ioutil.ReadFile(ctx.Args().Get(2)); err != nil {
+			return err
+		}
+	}
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	path := config.Eth.DatabaseFreezer
+	switch {
+	case path == "":
+		path = filepath.Join(stack.ResolvePath("chaindata"), "ancient")
+	case !filepath.IsAbs(path):
+		path = config.Node.ResolvePath(path)
+	}
+	log.Info("Recompressing freezer table", "location", path, "name", kind, "compression", compression)
+	return rawdb.RecompressFreezerTable(path, kind, compression, dict)
+}
+
+func dbVerifyState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true, false)
+	defer db.Close()
+
+	var root common.Hash
+	if ctx.IsSet(verifyStateRootFlag.Name) {
+		var err error
+		if root, err = parseRoot(ctx.String(verifyStateRootFlag.Name)); err != nil {
+			log.Error("Failed to resolve state root", "err", err)
+			return err
+		}
+	} else {
+		head := rawdb.ReadHeadBlock(db)
+		if head == nil {
+			log.Error("Failed to load head block")
+			return errors.New("no head block")
+		}
+		root = head.Root()
+	}
+	log.Info("Start verifying the state", "root", root, "threads", ctx.Int(verifyStateThreadsFlag.Name))
+
+	res, err := state.VerifyState(db, root, ctx.Int(verifyStateThreadsFlag.Name), nil)
+	if err != nil {
+		log.Error("Failed to verify state", "root", root, "err", err)
+		return err
+	}
+	for _, issue := range res.Issues {
+		log.Error("Damaged trie node", "owner", issue.Owner, "path", hexutil.Bytes(issue.Path), "hash", issue.Hash, "corrupt", issue.Corrupt)
+	}
+	for _, code := range res.MissingCodes {
+		log.Error("Missing contract code", "account", code.Account, "hash", code.Hash)
+	}
+	if !res.Healthy() {
+		log.Error("State is damaged", "nodes", len(res.Issues), "codes", len(res.MissingCodes), "elapsed", res.Elapsed)
+		log.Error("Heal it from the network by running the node and calling debug_healState", "root", root)
+		return errors.New("damaged state")
+	}
+	log.Info("State is complete", "nodes", res.Nodes, "accounts", res.Accounts, "slots", res.Slots, "codes", res.Codes, "elapsed", res.Elapsed)
+	return nil
+}
+
+func dbMigrate(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	target := ctx.Args().Get(0)
+	if target != rawdb.DBLeveldb && target != rawdb.DBPebble {
+		return fmt.Errorf("unsupported engine %q, allowed %q or %q", target, rawdb.DBLeveldb, rawdb.DBPebble)
+	}
+	stack, config := makeConfigNode(ctx)
+	defer stack.Close()
+
+	name := "chaindata"
+	if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
+		name = "lightchaindata"
+	}
+	var (
+		path    = stack.ResolvePath(name)
+		engine  = rawdb.PreexistingDatabase(path)
+		tmp     = path + "." + target
+		backup  = path + "." + engine + ".bak"
+		cache   = ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100
+		handles = utils.MakeDatabaseHandles()
+	)
+	switch {
+	case engine == "":
+		return fmt.Errorf("no database found in %s", path)
+	case engine == target:
+		return fmt.Errorf("database in %s already uses %s", path, target)
+	case common.FileExist(tmp):
+		return fmt.Errorf("unfinished migration found in %s, remove it first", tmp)
+	case common.FileExist(backup):
+		return fmt.Errorf("previous database backup found in %s, remove it first", backup)
+	}
+	src, err := rawdb.Open(rawdb.OpenOptions{Directory: path, Cache: cache / 2, Handles: handles / 2, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	dst, err := rawdb.Open(rawdb.OpenOptions{Type: target, Directory: tmp, Cache: cache / 2, Handles: handles / 2, Pebble: config.Node.DBPebble})
+	if err != nil {
+		src.Close()
+		return err
+	}
+	log.Info("Migrating database", "path", path, "from", engine, "to", target)
+
+	var (
+		it         = src.NewIterator(nil, nil)
+		batch      = dst.NewBatch()
+		count      int
+		size       common.StorageSize
+		start      = time.Now()
+		lastReport time.Time
+	)
+	for it.Next() {
+		if err = batch.Put(it.Key(), it.Value()); err != nil {
+			break
+		}
+		count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err = batch.Write(); err != nil {
+				break
+			}
+			batch.Reset()
+		}
+		if time.Since(lastReport) > 8*time.Second {
+			log.Info("Migrating database", "entries", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			lastReport = time.Now()
+		}
+	}
+	if err == nil {
+		err = it.Error()
+	}
+	it.Release()
+	if err == nil {
+		err = batch.Write()
+	}
+	src.Close()
+	dst.Close()
+	if err != nil {
+		log.Error("Database migration failed, remove the partial copy before retrying", "path", tmp, "err", err)
+		return err
+	}
+	// Move the ancient store and any other directories into the new database,
+	// then swap it in place of the old one
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := os.Rename(filepath.Join(path, entry.Name()), filepath.Join(tmp, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(path, backup); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	log.Info("Database migrated", "entries", count, "size", size, "engine", target, "elapsed", common.PrettyDuration(time.Since(start)))
+	log.Info("Old database kept, remove it once the node runs fine", "path", backup)
+	return nil
+}
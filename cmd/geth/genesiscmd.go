@@ -0,0 +1,96 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/config"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	genesisCommand = cli.Command{
+		Name:      "genesis",
+		Usage:     "Generate the genesis of new parlia networks",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			genesisNewCommand,
+		},
+	}
+	genesisNewCommand = cli.Command{
+		Action:    utils.MigrateFlags(newGenesis),
+		Name:      "new",
+		Usage:     "Generate a genesis from a network description",
+		ArgsUsage: "<specfile> [<outfile>]",
+		Flags: []cli.Flag{
+			utils.NetworkFlag,
+			utils.NetworkConfigFlag,
+		},
+		Description: `
+Generate the genesis of a new parlia network from a JSON description of its chain
+id, block period, epoch length, validators with their stakes and vote keys,
+initial balances and system contract parameters. The bytecode of the system
+contracts and the forks are taken from the network selected with --network,
+spicy by default, all forks being activated at genesis.
+
+The genesis is written to the given file, or to stdout.`,
+	}
+)
+
+func newGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 || len(ctx.Args()) > 2 {
+		utils.Fatalf("This command requires a network description and an optional output file.")
+	}
+	blob, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read network description: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.DisallowUnknownFields()
+
+	spec := new(config.GenesisSpec)
+	if err := dec.Decode(spec); err != nil {
+		utils.Fatalf("Invalid network description: %v", err)
+	}
+	template := config.SpicyGenesisConfig
+	if ctx.GlobalIsSet(utils.NetworkFlag.Name) {
+		template = utils.MakeNetwork(ctx).Genesis
+	}
+	genesis, err := config.NewGenesis(spec, template)
+	if err != nil {
+		utils.Fatalf("Failed to generate genesis: %v", err)
+	}
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	if len(ctx.Args()) == 1 {
+		_, err = os.Stdout.Write(append(out, '\n'))
+	} else {
+		err = ioutil.WriteFile(ctx.Args().Get(1), out, 0644)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to write genesis: %v", err)
+	}
+	return nil
+}
@@ -69,18 +69,41 @@ var (
 		utils.BootnodesFlag,
 		utils.DataDirFlag,
 		utils.AncientFlag,
+		utils.DataDirReadOnlyFlag,
+		utils.DataDirRefreshFlag,
+		utils.BootstrapURLFlag,
+		utils.BootstrapSignersFlag,
+		utils.BootstrapCheckpointFlag,
+		utils.DBEngineFlag,
+		utils.DBRemoteFlag,
+		utils.DBPebbleCompactionsFlag,
+		utils.DBPebbleWALFlag,
 		utils.MinFreeDiskSpaceFlag,
+		utils.ShutdownTimeoutFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
 		utils.DirectBroadcastFlag,
+		utils.PrivateTxPeersFlag,
+		utils.SentryValidatorsFlag,
+		utils.SentriesFlag,
 		utils.DisableSnapProtocolFlag,
 		utils.DiffSyncFlag,
+		utils.ServeWitnessFlag,
+		utils.TracePropagationFlag,
 		utils.PipeCommitFlag,
+		utils.ParallelTxFlag,
+		utils.ParallelTxNumFlag,
 		utils.RangeLimitFlag,
+		utils.LogsMaxBlockRangeFlag,
+		utils.LogsMaxResultsFlag,
 		utils.USBFlag,
 		utils.SmartCardDaemonPathFlag,
 		utils.OverrideBerlinFlag,
+		utils.NetworkFlag,
+		utils.NetworkConfigFlag,
+		utils.OverrideForksFlag,
+		utils.OverrideSignersFlag,
 		utils.EthashCacheDirFlag,
 		utils.EthashCachesInMemoryFlag,
 		utils.EthashCachesOnDiskFlag,
@@ -93,19 +116,44 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolJournalRemotesFlag,
+		utils.TxPoolJournalLimitFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolSenderQuotaFlag,
+		utils.TxPoolOriginQuotaFlag,
+		utils.TxPoolQuotaExemptFlag,
+		utils.TxPoolBundlersFlag,
+		utils.TxPoolBundlerPriceBumpFlag,
+		utils.TxPoolBlobSlotsFlag,
+		utils.TxPoolBlobAccountSlotsFlag,
+		utils.TxPoolBlobPriceLimitFlag,
+		utils.TxPoolBlobPriceBumpFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolPrefetchFlag,
 		utils.TxPoolReannounceTimeFlag,
 		utils.SyncModeFlag,
+		utils.SyncCheckpointFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
+		utils.SnapshotVerifyRateFlag,
+		utils.SnapshotRepairFlag,
 		utils.TxLookupLimitFlag,
+		utils.TxLookupAllowlistFlag,
+		utils.CallIndexFlag,
+		utils.SenderIndexFlag,
+		utils.ContractIndexFlag,
+		utils.TokenIndexFlag,
+		utils.LogIndexFlag,
+		utils.TraceDBFlag,
+		utils.RevertReasonsFlag,
+		utils.OtterscanFlag,
+		utils.DoubleSignReporterFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -130,8 +178,13 @@ var (
 		utils.PersistDiffFlag,
 		utils.DiffBlockFlag,
 		utils.ListenPortFlag,
+		utils.QUICPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.DialRatioFlag,
+		utils.MaxPeersPerIPFlag,
+		utils.MaxPeersPerSubnetFlag,
+		utils.ProtectedPeersFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
 		utils.MinerNotifyFlag,
@@ -143,16 +196,36 @@ var (
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerDelayLeftoverFlag,
 		utils.MinerNoVerfiyFlag,
+		utils.MinerBuildersFlag,
+		utils.MinerOrderingFlag,
+		utils.MinerPriorityFlag,
+		utils.MinerBuildRoundsFlag,
+		utils.VoteSignerRemoteFlag,
+		utils.VoteSignerPubKeyFlag,
+		utils.VoteSignerTimeoutFlag,
+		utils.HARoleFlag,
+		utils.HAPeerFlag,
+		utils.HAIntervalFlag,
+		utils.HAFailoverTimeoutFlag,
+		utils.GovernorDiskLowFlag,
+		utils.GovernorDiskCriticalFlag,
+		utils.GovernorHeapLowFlag,
+		utils.GovernorHeapCriticalFlag,
+		utils.GovernorIntervalFlag,
 		utils.NATFlag,
+		utils.NATRecheckFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.CaptureDirFlag,
+		utils.CapturePeersFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
 		utils.MainnetFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
+		utils.DeveloperParliaFlag,
 		utils.RopstenFlag,
 		utils.RinkebyFlag,
 		utils.GoerliFlag,
@@ -160,6 +233,8 @@ var (
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
+		utils.ExporterURLFlag,
+		utils.WebhooksFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -167,6 +242,8 @@ var (
 		utils.GpoMaxGasPriceFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
+		utils.VMBackendFlag,
+		utils.VMBackendVerifyFlag,
 		utils.MinerNotifyFullFlag,
 		configFileFlag,
 		utils.CatalystFlag,
@@ -189,6 +266,9 @@ var (
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GRPCEnabledFlag,
+		utils.GRPCListenAddrFlag,
+		utils.GRPCPortFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
 		utils.WSEnabledFlag,
@@ -197,12 +277,25 @@ var (
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
 		utils.WSPathPrefixFlag,
+		utils.WSMaxSubscriptionsFlag,
+		utils.WSMaxPendingNotificationsFlag,
+		utils.WSMaxBytesPerSecondFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
 		utils.AllowUnprotectedTxs,
+		utils.BatchRequestLimitFlag,
+		utils.BatchResponseMaxSizeFlag,
+		utils.BatchParallelismFlag,
+		utils.RPCComputeUnitBudgetFlag,
+		utils.RPCComputeUnitBurstFlag,
+		utils.RPCCallTimeoutFlag,
+		utils.RPCAPIKeyHeaderFlag,
+		utils.RPCAuthFileFlag,
+		utils.RPCTracingEndpointFlag,
+		utils.RPCTracingThresholdFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -216,6 +309,7 @@ var (
 		utils.MetricsInfluxDBUsernameFlag,
 		utils.MetricsInfluxDBPasswordFlag,
 		utils.MetricsInfluxDBTagsFlag,
+		utils.ImportTraceOTLPFlag,
 	}
 )
 
@@ -230,11 +324,15 @@ func init() {
 		initNetworkCommand,
 		importCommand,
 		exportCommand,
+		importHistoryCommand,
+		exportHistoryCommand,
+		exportTestsCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		genesisCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -256,6 +354,12 @@ func init() {
 		utils.ShowDeprecated,
 		// See snapshot.go
 		snapshotCommand,
+		// See votecmd.go
+		voteProtectionCommand,
+		// See backupcmd.go
+		backupCommand,
+		// See p2pcmd.go
+		p2pCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -302,13 +406,16 @@ func prepare(ctx *cli.Context) {
 	case ctx.GlobalIsSet(utils.DeveloperFlag.Name):
 		log.Info("Starting Geth in ephemeral dev mode...")
 
+	case ctx.GlobalIsSet(utils.DeveloperParliaFlag.Name):
+		log.Info("Starting Geth in ephemeral parlia dev mode...")
+
 	case !ctx.GlobalIsSet(utils.NetworkIdFlag.Name):
 		log.Info("Starting Geth on Ethereum mainnet...")
 	}
 	// If we're a full node on mainnet without --cache specified, bump default cache allowance
 	if ctx.GlobalString(utils.SyncModeFlag.Name) != "light" && !ctx.GlobalIsSet(utils.CacheFlag.Name) && !ctx.GlobalIsSet(utils.NetworkIdFlag.Name) {
 		// Make sure we're not on any supported preconfigured testnet either
-		if !ctx.GlobalIsSet(utils.RopstenFlag.Name) && !ctx.GlobalIsSet(utils.RinkebyFlag.Name) && !ctx.GlobalIsSet(utils.GoerliFlag.Name) && !ctx.GlobalIsSet(utils.DeveloperFlag.Name) {
+		if !ctx.GlobalIsSet(utils.RopstenFlag.Name) && !ctx.GlobalIsSet(utils.RinkebyFlag.Name) && !ctx.GlobalIsSet(utils.GoerliFlag.Name) && !ctx.GlobalIsSet(utils.DeveloperFlag.Name) && !ctx.GlobalIsSet(utils.DeveloperParliaFlag.Name) {
 			// Nope, we're really on mainnet. Bump that cache up!
 			log.Info("Bumping default cache on mainnet", "provided", ctx.GlobalInt(utils.CacheFlag.Name), "updated", 4096)
 			ctx.GlobalSet(utils.CacheFlag.Name, strconv.Itoa(4096))
@@ -425,7 +532,7 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) {
 	}
 
 	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
+	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) || ctx.GlobalBool(utils.DeveloperParliaFlag.Name) {
 		// Mining only makes sense if a full Ethereum node is running
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support mining")
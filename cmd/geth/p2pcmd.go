@@ -0,0 +1,85 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	p2pCommand = cli.Command{
+		Name:      "p2p",
+		Usage:     "Debug the peer-to-peer protocols",
+		ArgsUsage: "",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Subcommands: []cli.Command{
+			p2pReplayCommand,
+		},
+	}
+	p2pReplayCommand = cli.Command{
+		Action:    utils.MigrateFlags(replayCapture),
+		Name:      "replay",
+		Usage:     "Replay a protocol message capture into the node",
+		ArgsUsage: "<capturefile>",
+		Flags:     append(nodeFlags, rpcFlags...),
+		Description: `
+Feed the eth and snap messages received from a peer, recorded with --capture.dir
+and --capture.peers or admin.startCapture, back into the protocol handlers of the
+node, as if the peer connected again. Each message is fed once the node sent the
+messages it sent before receiving it in the capture, reporting the ones it sends
+differently.
+
+The node doesn't connect to the network during the replay, but imports what the
+replayed peer serves it: replay into a copy of the datadir the capture was made
+with to reproduce a sync failure.`,
+	}
+)
+
+func replayCapture(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a capture file.")
+	}
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	// Disconnect the node from the network, the replayed peer being its only one
+	server := stack.Server()
+	server.NoDial, server.NoDiscovery, server.DiscoveryV5, server.ListenAddr = true, true, false, ""
+
+	_, ethereum := utils.RegisterEthService(stack, &cfg.Eth)
+	if ethereum == nil {
+		utils.Fatalf("Replaying captures requires a full node.")
+	}
+	utils.StartNode(ctx, stack)
+
+	stats, err := p2p.ReplayCapture(ethereum.Protocols(), ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to replay capture: %v", err)
+	}
+	if stats.Dropped != nil {
+		log.Warn("Replayed peer dropped", "err", stats.Dropped)
+	}
+	head := ethereum.BlockChain().CurrentBlock()
+	fmt.Printf("Fed %d messages, node sent %d, %d diverging from the capture\n", stats.Fed, stats.Sent, stats.Diverged)
+	fmt.Printf("Head block %d [%x]\n", head.NumberU64(), head.Hash())
+	return nil
+}
@@ -0,0 +1,404 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNoConfigFile = errors.New("no configuration file to reload, start with --config")
+
+// logConfig is the logging section of the configuration file. Settings given in
+// the file take precedence over --verbosity and --vmodule.
+type logConfig struct {
+	Verbosity int    `toml:",omitempty"` // Log level ceiling (1=error .. 5=detail), 0 keeps the current level
+	Vmodule   string `toml:",omitempty"` // Per-module verbosity pattern, empty keeps the current pattern
+}
+
+// applyLogConfig sets the log verbosity settings given in the configuration file.
+func applyLogConfig(cfg logConfig) error {
+	if cfg.Verbosity != 0 {
+		debug.Handler.Verbosity(cfg.Verbosity)
+	}
+	if cfg.Vmodule != "" {
+		return debug.Handler.Vmodule(cfg.Vmodule)
+	}
+	return nil
+}
+
+// txPoolLimits are the transaction pool settings which can be changed at runtime.
+type txPoolLimits struct {
+	PriceLimit   uint64
+	PriceBump    uint64
+	AccountSlots uint64
+	GlobalSlots  uint64
+	AccountQueue uint64
+	GlobalQueue  uint64
+	Lifetime     time.Duration
+}
+
+func newTxPoolLimits(cfg core.TxPoolConfig) txPoolLimits {
+	return txPoolLimits{
+		PriceLimit:   cfg.PriceLimit,
+		PriceBump:    cfg.PriceBump,
+		AccountSlots: cfg.AccountSlots,
+		GlobalSlots:  cfg.GlobalSlots,
+		AccountQueue: cfg.AccountQueue,
+		GlobalQueue:  cfg.GlobalQueue,
+		Lifetime:     cfg.Lifetime,
+	}
+}
+
+// apply copies the limits into the given transaction pool configuration.
+func (l txPoolLimits) apply(cfg *core.TxPoolConfig) {
+	cfg.PriceLimit, cfg.PriceBump = l.PriceLimit, l.PriceBump
+	cfg.AccountSlots, cfg.GlobalSlots = l.AccountSlots, l.GlobalSlots
+	cfg.AccountQueue, cfg.GlobalQueue = l.AccountQueue, l.GlobalQueue
+	cfg.Lifetime = l.Lifetime
+}
+
+// reloadableConfig is the subset of the configuration which can be reloaded from
+// the configuration file at runtime, taken from the [Log], [Eth.TxPool], [Eth.GPO],
+// [Node.P2P] and [Node.RPCMetering] sections.
+//
+// A reload only applies the settings edited in the file since it was last read,
+// so values given on the command line stay in effect until the corresponding
+// entry of the file is changed.
+type reloadableConfig struct {
+	Log         logConfig
+	TxPool      txPoolLimits
+	GPO         gasprice.Config
+	MaxPeers    int
+	RPCMetering *rpc.MeteringConfig `json:",omitempty"`
+}
+
+func newReloadableConfig(cfg *gethConfig) reloadableConfig {
+	return reloadableConfig{
+		Log:         cfg.Log,
+		TxPool:      newTxPoolLimits(cfg.Eth.TxPool),
+		GPO:         cfg.Eth.GPO,
+		MaxPeers:    cfg.Node.P2P.MaxPeers,
+		RPCMetering: cfg.Node.RPCMetering,
+	}
+}
+
+// defaultReloadableConfig returns the reloadable settings of a node started
+// without configuration file or flags.
+func defaultReloadableConfig() reloadableConfig {
+	return newReloadableConfig(&gethConfig{
+		Eth:  ethconfig.Defaults,
+		Node: defaultNodeConfig(),
+	})
+}
+
+// base returns a configuration holding copies of the reloadable settings, for
+// the configuration file to be decoded over without touching shared values.
+func (c *reloadableConfig) base() *gethConfig {
+	cfg := new(gethConfig)
+	c.TxPool.apply(&cfg.Eth.TxPool)
+
+	cfg.Eth.GPO = c.GPO
+	if c.GPO.Default != nil {
+		cfg.Eth.GPO.Default = new(big.Int).Set(c.GPO.Default)
+	}
+	if c.GPO.MaxPrice != nil {
+		cfg.Eth.GPO.MaxPrice = new(big.Int).Set(c.GPO.MaxPrice)
+	}
+	cfg.Node.P2P.MaxPeers = c.MaxPeers
+
+	if c.RPCMetering != nil {
+		metering := *c.RPCMetering
+		metering.MethodCosts = make(map[string]int, len(c.RPCMetering.MethodCosts))
+		for method, cost := range c.RPCMetering.MethodCosts {
+			metering.MethodCosts[method] = cost
+		}
+		metering.MethodTimeouts = make(map[string]time.Duration, len(c.RPCMetering.MethodTimeouts))
+		for method, timeout := range c.RPCMetering.MethodTimeouts {
+			metering.MethodTimeouts[method] = timeout
+		}
+		cfg.Node.RPCMetering = &metering
+	}
+	return cfg
+}
+
+// merge returns the settings in effect after reloading a file, taking the sections
+// which changed between the previously and the newly read file contents.
+func (c *reloadableConfig) merge(prev, next *reloadableConfig) reloadableConfig {
+	merged := *c
+	if next.Log != prev.Log {
+		merged.Log = next.Log
+	}
+	if next.TxPool != prev.TxPool {
+		merged.TxPool = next.TxPool
+	}
+	if !reflect.DeepEqual(next.GPO, prev.GPO) {
+		merged.GPO = next.GPO
+	}
+	if next.MaxPeers != prev.MaxPeers {
+		merged.MaxPeers = next.MaxPeers
+	}
+	if !reflect.DeepEqual(next.RPCMetering, prev.RPCMetering) {
+		merged.RPCMetering = next.RPCMetering
+	}
+	return merged
+}
+
+// validate checks the reloaded settings, rejecting any value the components
+// would otherwise silently replace or which can't be applied at runtime.
+func (c *reloadableConfig) validate(active *reloadableConfig) error {
+	if c.Log.Verbosity < 0 || c.Log.Verbosity > int(log.LvlTrace) {
+		return fmt.Errorf("invalid log verbosity %d", c.Log.Verbosity)
+	}
+	if c.Log.Vmodule != "" {
+		if err := log.NewGlogHandler(log.DiscardHandler()).Vmodule(c.Log.Vmodule); err != nil {
+			return fmt.Errorf("invalid log vmodule: %v", err)
+		}
+	}
+	pool := c.TxPool
+	if pool.PriceLimit < 1 || pool.PriceBump < 1 || pool.AccountSlots < 1 || pool.GlobalSlots < 1 ||
+		pool.AccountQueue < 1 || pool.GlobalQueue < 1 || pool.Lifetime < 1 {
+		return errors.New("invalid txpool limits, all must be positive")
+	}
+	if c.GPO.Blocks < 1 {
+		return fmt.Errorf("invalid gas price oracle blocks %d", c.GPO.Blocks)
+	}
+	if c.GPO.Percentile < 0 || c.GPO.Percentile > 100 {
+		return fmt.Errorf("invalid gas price oracle percentile %d", c.GPO.Percentile)
+	}
+	if c.GPO.MaxPrice == nil || c.GPO.MaxPrice.Sign() <= 0 {
+		return errors.New("invalid gas price oracle price cap, must be positive")
+	}
+	if c.MaxPeers < 0 {
+		return fmt.Errorf("invalid maximum peer count %d", c.MaxPeers)
+	}
+	if (c.RPCMetering == nil) != (active.RPCMetering == nil) {
+		return errors.New("enabling or disabling RPC metering requires a restart")
+	}
+	if c.RPCMetering != nil && (c.RPCMetering.UnitsPerSecond < 0 || c.RPCMetering.Burst < 0) {
+		return errors.New("invalid RPC compute unit budget, must not be negative")
+	}
+	return nil
+}
+
+// reloadBackend is the running node the reloaded settings are applied to.
+type reloadBackend interface {
+	SetMaxPeers(maxPeers int) error
+	SetRPCMetering(config rpc.MeteringConfig) error
+	SetTxPoolLimits(limits core.TxPoolConfig)
+	SetGasPriceOracle(config gasprice.Config)
+}
+
+// fullNodeBackend applies the reloaded settings to a full node.
+type fullNodeBackend struct {
+	stack *node.Node
+	eth   *eth.Ethereum
+}
+
+func (b *fullNodeBackend) SetMaxPeers(maxPeers int) error { return b.eth.SetMaxPeers(maxPeers) }
+
+func (b *fullNodeBackend) SetRPCMetering(config rpc.MeteringConfig) error {
+	return b.stack.SetRPCMetering(config)
+}
+
+func (b *fullNodeBackend) SetTxPoolLimits(limits core.TxPoolConfig) {
+	b.eth.TxPool().SetLimits(limits)
+}
+
+func (b *fullNodeBackend) SetGasPriceOracle(config gasprice.Config) {
+	b.eth.APIBackend.SetGasPriceOracle(config)
+}
+
+// configReloader applies the reloadable settings of the configuration file to
+// the running node, on SIGHUP or when requested through the admin API.
+type configReloader struct {
+	file    string // Configuration file, empty if the node was started without one
+	backend reloadBackend
+
+	loaded reloadableConfig // Settings of the file when last read
+	active reloadableConfig // Settings currently in effect
+	lock   sync.Mutex
+
+	sighup chan os.Signal
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// registerConfigReloader creates the configuration reloader of a full node and
+// registers it with the stack.
+func registerConfigReloader(stack *node.Node, backend *eth.Ethereum, file string, cfg *gethConfig) {
+	r := &configReloader{
+		file:    file,
+		backend: &fullNodeBackend{stack: stack, eth: backend},
+		active:  newReloadableConfig(cfg),
+		quit:    make(chan struct{}),
+	}
+	if file != "" {
+		loaded, err := r.read()
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+		r.loaded = loaded
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   &reloadAPI{r},
+	}})
+	stack.RegisterLifecycle(r)
+}
+
+// Start implements node.Lifecycle, reloading the configuration file on SIGHUP if
+// the node was started with one.
+func (r *configReloader) Start() error {
+	if r.file == "" {
+		return nil
+	}
+	r.sighup = make(chan os.Signal, 1)
+	signal.Notify(r.sighup, syscall.SIGHUP)
+
+	r.wg.Add(1)
+	go r.loop()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the signal listener.
+func (r *configReloader) Stop() error {
+	if r.sighup != nil {
+		signal.Stop(r.sighup)
+	}
+	close(r.quit)
+	r.wg.Wait()
+	return nil
+}
+
+func (r *configReloader) loop() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.sighup:
+			log.Info("Got SIGHUP, reloading configuration", "file", r.file)
+			if _, err := r.reload(); err != nil {
+				log.Error("Failed to reload configuration", "file", r.file, "err", err)
+			}
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// read returns the reloadable settings of the configuration file.
+func (r *configReloader) read() (reloadableConfig, error) {
+	defaults := defaultReloadableConfig()
+	cfg := defaults.base()
+	if err := loadConfig(r.file, cfg); err != nil {
+		return reloadableConfig{}, err
+	}
+	return newReloadableConfig(cfg), nil
+}
+
+// reload reads the configuration file and applies the edited reloadable settings,
+// returning the settings in effect afterwards. Invalid files are rejected as a
+// whole, leaving the running settings untouched.
+func (r *configReloader) reload() (*reloadableConfig, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == "" {
+		return nil, errNoConfigFile
+	}
+	loaded, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	next := r.active.merge(&r.loaded, &loaded)
+	if err := next.validate(&r.active); err != nil {
+		return nil, err
+	}
+	// Apply the only setting which may still fail first, so a failure leaves
+	// the node as it was
+	if next.MaxPeers != r.active.MaxPeers {
+		if err := r.backend.SetMaxPeers(next.MaxPeers); err != nil {
+			return nil, err
+		}
+	}
+	if next.RPCMetering != nil && !reflect.DeepEqual(next.RPCMetering, r.active.RPCMetering) {
+		if err := r.backend.SetRPCMetering(*next.RPCMetering); err != nil && err != node.ErrNoRPCMetering {
+			log.Error("Failed to update RPC metering", "err", err)
+		}
+	}
+	if err := applyLogConfig(next.Log); err != nil {
+		log.Error("Failed to update log settings", "err", err)
+	}
+	if next.TxPool != r.active.TxPool {
+		var limits core.TxPoolConfig
+		next.TxPool.apply(&limits)
+		r.backend.SetTxPoolLimits(limits)
+	}
+	if !reflect.DeepEqual(next.GPO, r.active.GPO) {
+		r.backend.SetGasPriceOracle(next.GPO)
+	}
+	r.active, r.loaded = next, loaded
+	log.Info("Reloaded configuration", "file", r.file)
+
+	return r.config(), nil
+}
+
+// config returns the reloadable settings currently in effect. The caller must
+// hold the lock.
+func (r *configReloader) config() *reloadableConfig {
+	cfg := r.active
+	cfg.Log.Verbosity, cfg.Log.Vmodule = debug.LogSettings()
+	return &cfg
+}
+
+// reloadAPI offers the configuration reloader through the admin namespace.
+type reloadAPI struct {
+	r *configReloader
+}
+
+// ReloadConfig reloads the runtime-adjustable settings from the configuration file
+// the node was started with, returning the settings in effect afterwards.
+func (api *reloadAPI) ReloadConfig() (*reloadableConfig, error) {
+	return api.r.reload()
+}
+
+// Config returns the runtime-adjustable settings currently in effect.
+func (api *reloadAPI) Config() *reloadableConfig {
+	api.r.lock.Lock()
+	defer api.r.lock.Unlock()
+
+	return api.r.config()
+}
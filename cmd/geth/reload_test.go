@@ -0,0 +1,178 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testReloadBackend records the settings applied by a reload.
+type testReloadBackend struct {
+	maxPeersErr error // Error returned when changing the peer count
+
+	maxPeers int
+	txPool   *core.TxPoolConfig
+	gpo      *gasprice.Config
+	metering *rpc.MeteringConfig
+}
+
+func (b *testReloadBackend) SetMaxPeers(maxPeers int) error {
+	if b.maxPeersErr != nil {
+		return b.maxPeersErr
+	}
+	b.maxPeers = maxPeers
+	return nil
+}
+
+func (b *testReloadBackend) SetRPCMetering(config rpc.MeteringConfig) error {
+	b.metering = &config
+	return nil
+}
+
+func (b *testReloadBackend) SetTxPoolLimits(limits core.TxPoolConfig) { b.txPool = &limits }
+func (b *testReloadBackend) SetGasPriceOracle(config gasprice.Config) { b.gpo = &config }
+
+// Tests that the settings edited in the configuration file override the ones in
+// effect, whereas the untouched sections keep the command line values.
+func TestReloadableConfigMerge(t *testing.T) {
+	active := defaultReloadableConfig()
+	active.MaxPeers = 80           // --maxpeers
+	active.TxPool.PriceLimit = 5   // --txpool.pricelimit
+	active.GPO.Blocks = 40         // --gpo.blocks
+	active.Log.Vmodule = "eth/*=5" // --vmodule
+	prev, next := defaultReloadableConfig(), defaultReloadableConfig()
+	prev.MaxPeers, next.MaxPeers = 25, 25
+	prev.Log.Vmodule, next.Log.Vmodule = "p2p/*=4", "p2p/*=4"
+	next.TxPool.PriceLimit = 7
+	next.GPO.Percentile = 50
+
+	merged := active.merge(&prev, &next)
+	if merged.MaxPeers != 80 {
+		t.Errorf("unedited peer count replaced: have %d, want 80", merged.MaxPeers)
+	}
+	if merged.Log.Vmodule != "eth/*=5" {
+		t.Errorf("unedited log settings replaced: have %q, want %q", merged.Log.Vmodule, "eth/*=5")
+	}
+	if merged.TxPool.PriceLimit != 7 {
+		t.Errorf("edited txpool limits not applied: have price limit %d, want 7", merged.TxPool.PriceLimit)
+	}
+	// Sections are taken as a whole, the command line value of an edited one is lost
+	if merged.GPO.Percentile != 50 || merged.GPO.Blocks != next.GPO.Blocks {
+		t.Errorf("edited gas price oracle settings mismatch: have %+v, want %+v", merged.GPO, next.GPO)
+	}
+	if active.MaxPeers != 80 || active.TxPool.PriceLimit != 5 {
+		t.Errorf("active settings modified by the merge")
+	}
+}
+
+// Tests that invalid reloaded settings are rejected.
+func TestReloadableConfigValidate(t *testing.T) {
+	metered := defaultReloadableConfig()
+	metered.RPCMetering = &rpc.MeteringConfig{UnitsPerSecond: 100, Burst: 100}
+
+	tests := []struct {
+		name   string
+		active reloadableConfig
+		modify func(cfg *reloadableConfig)
+	}{
+		{"verbosity", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.Log.Verbosity = 6 }},
+		{"vmodule", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.Log.Vmodule = "eth=x" }},
+		{"txpool slots", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.TxPool.AccountSlots = 0 }},
+		{"txpool lifetime", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.TxPool.Lifetime = 0 }},
+		{"gpo blocks", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.GPO.Blocks = 0 }},
+		{"gpo percentile", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.GPO.Percentile = 101 }},
+		{"gpo price cap", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.GPO.MaxPrice = new(big.Int) }},
+		{"peer count", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.MaxPeers = -1 }},
+		{"metering enabled", defaultReloadableConfig(), func(cfg *reloadableConfig) { cfg.RPCMetering = &rpc.MeteringConfig{} }},
+		{"metering disabled", metered, func(cfg *reloadableConfig) { cfg.RPCMetering = nil }},
+		{"metering budget", metered, func(cfg *reloadableConfig) { cfg.RPCMetering = &rpc.MeteringConfig{Burst: -1} }},
+	}
+	for _, tt := range tests {
+		cfg := tt.active
+		if err := cfg.validate(&tt.active); err != nil {
+			t.Fatalf("%s: active settings rejected: %v", tt.name, err)
+		}
+		tt.modify(&cfg)
+		if err := cfg.validate(&tt.active); err == nil {
+			t.Errorf("%s: invalid settings accepted", tt.name)
+		}
+	}
+}
+
+// Tests that a reload failing to apply its settings leaves the running ones, and
+// the file contents it compares the next reload against, untouched.
+func TestReloadFailedApply(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	write := func(maxPeers int, priceLimit uint64) {
+		// The decoded sections replace the defaults, so the txpool one is given whole
+		content := fmt.Sprintf("[Node.P2P]\nMaxPeers = %d\n\n[Eth.TxPool]\nPriceLimit = %d\nPriceBump = 10\n"+
+			"AccountSlots = 16\nGlobalSlots = 4096\nAccountQueue = 64\nGlobalQueue = 1024\nLifetime = 10800000000000\n", maxPeers, priceLimit)
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write configuration file: %v", err)
+		}
+	}
+	write(30, 1)
+
+	backend := &testReloadBackend{maxPeersErr: errors.New("peer count rejected")}
+	r := &configReloader{file: file, backend: backend, active: defaultReloadableConfig()}
+	r.active.MaxPeers = 30
+
+	loaded, err := r.read()
+	if err != nil {
+		t.Fatalf("failed to read configuration file: %v", err)
+	}
+	r.loaded = loaded
+
+	// Edit the peer count along with the txpool limits, applying the former fails
+	write(10, 9)
+
+	active := r.active
+	if _, err := r.reload(); err != backend.maxPeersErr {
+		t.Fatalf("reload error mismatch: have %v, want %v", err, backend.maxPeersErr)
+	}
+	if !reflect.DeepEqual(r.active, active) {
+		t.Errorf("running settings modified: have %+v, want %+v", r.active, active)
+	}
+	if !reflect.DeepEqual(r.loaded, loaded) {
+		t.Errorf("loaded file contents modified: have %+v, want %+v", r.loaded, loaded)
+	}
+	if backend.txPool != nil || backend.gpo != nil {
+		t.Errorf("settings applied despite the failure")
+	}
+	// Once the peer count is accepted, retrying applies the whole edit
+	backend.maxPeersErr = nil
+	cfg, err := r.reload()
+	if err != nil {
+		t.Fatalf("failed to reload configuration: %v", err)
+	}
+	if cfg.MaxPeers != 10 || backend.maxPeers != 10 {
+		t.Errorf("peer count mismatch: have %d, applied %d, want 10", cfg.MaxPeers, backend.maxPeers)
+	}
+	if cfg.TxPool.PriceLimit != 9 || backend.txPool == nil || backend.txPool.PriceLimit != 9 {
+		t.Errorf("txpool limits not applied: have %+v", cfg.TxPool)
+	}
+}
@@ -71,6 +71,7 @@ var (
 					utils.CacheTrieJournalFlag,
 					utils.BloomFilterSizeFlag,
 					utils.TriesInMemoryFlag,
+					utils.KeepBlocksFlag,
 				},
 				Description: `
 geth snapshot prune-state <state-root>
@@ -81,6 +82,15 @@ two version states are available: genesis and the specific one.
 
 The default pruning target is the HEAD-127 state.
 
+geth snapshot prune-state --keep-blocks <N>
+will instead keep the states of the N most recent blocks, deleting all
+the older ones except genesis. It's meant to trim the history of archive
+nodes, which must have the state of the head block persisted. The snapshot
+is flattened into a single layer at the head.
+
+An interrupted pruning is resumed from where it stopped by running the
+command again or by starting Geth.
+
 WARNING: It's necessary to delete the trie clean cache after the pruning.
 If you specify another directory for the trie clean cache via "--cache.trie.journal"
 during the use of Geth, please also specify it here for correct deletion. Otherwise
@@ -338,7 +348,7 @@ func pruneState(ctx *cli.Context) error {
 	defer stack.Close()
 
 	chaindb := utils.MakeChainDatabase(ctx, stack, false, false)
-	pruner, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), stack.ResolvePath(config.Eth.TrieCleanCacheJournal), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name), ctx.GlobalUint64(utils.TriesInMemoryFlag.Name))
+	pruner, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), stack.ResolvePath(config.Eth.TrieCleanCacheJournal), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name), ctx.GlobalUint64(utils.TriesInMemoryFlag.Name), ctx.GlobalUint64(utils.KeepBlocksFlag.Name))
 	if err != nil {
 		log.Error("Failed to open snapshot tree", "err", err)
 		return err
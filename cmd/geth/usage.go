@@ -36,12 +36,31 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.AncientFlag,
+			utils.DataDirReadOnlyFlag,
+			utils.DataDirRefreshFlag,
+			utils.BootstrapURLFlag,
+			utils.BootstrapSignersFlag,
+			utils.BootstrapCheckpointFlag,
+			utils.DBEngineFlag,
+			utils.DBRemoteFlag,
+			utils.DBPebbleCompactionsFlag,
+			utils.DBPebbleWALFlag,
 			utils.MinFreeDiskSpaceFlag,
+			utils.ShutdownTimeoutFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.DirectBroadcastFlag,
+			utils.PrivateTxPeersFlag,
+			utils.SentryValidatorsFlag,
+			utils.SentriesFlag,
 			utils.DisableSnapProtocolFlag,
+			utils.ServeWitnessFlag,
+			utils.TracePropagationFlag,
 			utils.RangeLimitFlag,
+			utils.LogsMaxBlockRangeFlag,
+			utils.LogsMaxResultsFlag,
+			utils.ParallelTxFlag,
+			utils.ParallelTxNumFlag,
 			utils.SmartCardDaemonPathFlag,
 			utils.NetworkIdFlag,
 			utils.MainnetFlag,
@@ -49,11 +68,26 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RinkebyFlag,
 			utils.YoloV3Flag,
 			utils.RopstenFlag,
+			utils.NetworkFlag,
+			utils.NetworkConfigFlag,
 			utils.SyncModeFlag,
+			utils.SyncCheckpointFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.TxLookupAllowlistFlag,
+			utils.CallIndexFlag,
+			utils.SenderIndexFlag,
+			utils.ContractIndexFlag,
+			utils.TokenIndexFlag,
+			utils.LogIndexFlag,
+			utils.TraceDBFlag,
+			utils.RevertReasonsFlag,
+			utils.OtterscanFlag,
+			utils.DoubleSignReporterFlag,
 			utils.EthStatsURLFlag,
+			utils.ExporterURLFlag,
+			utils.WebhooksFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
@@ -81,6 +115,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.DeveloperFlag,
 			utils.DeveloperPeriodFlag,
+			utils.DeveloperParliaFlag,
 		},
 	},
 	{
@@ -103,13 +138,25 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolJournalRemotesFlag,
+			utils.TxPoolJournalLimitFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolSenderQuotaFlag,
+			utils.TxPoolOriginQuotaFlag,
+			utils.TxPoolQuotaExemptFlag,
+			utils.TxPoolBundlersFlag,
+			utils.TxPoolBundlerPriceBumpFlag,
+			utils.TxPoolBlobSlotsFlag,
+			utils.TxPoolBlobAccountSlotsFlag,
+			utils.TxPoolBlobPriceLimitFlag,
+			utils.TxPoolBlobPriceBumpFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolPrefetchFlag,
 			utils.TxPoolReannounceTimeFlag,
 		},
 	},
@@ -153,12 +200,28 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.WSApiFlag,
 			utils.WSPathPrefixFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSMaxSubscriptionsFlag,
+			utils.WSMaxPendingNotificationsFlag,
+			utils.WSMaxBytesPerSecondFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.GRPCEnabledFlag,
+			utils.GRPCListenAddrFlag,
+			utils.GRPCPortFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
 			utils.AllowUnprotectedTxs,
+			utils.BatchRequestLimitFlag,
+			utils.BatchResponseMaxSizeFlag,
+			utils.BatchParallelismFlag,
+			utils.RPCComputeUnitBudgetFlag,
+			utils.RPCComputeUnitBurstFlag,
+			utils.RPCCallTimeoutFlag,
+			utils.RPCAPIKeyHeaderFlag,
+			utils.RPCAuthFileFlag,
+			utils.RPCTracingEndpointFlag,
+			utils.RPCTracingThresholdFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -170,12 +233,20 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.BootnodesFlag,
 			utils.DNSDiscoveryFlag,
 			utils.ListenPortFlag,
+			utils.QUICPortFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.DialRatioFlag,
+			utils.MaxPeersPerIPFlag,
+			utils.MaxPeersPerSubnetFlag,
+			utils.ProtectedPeersFlag,
 			utils.NATFlag,
+			utils.NATRecheckFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.CaptureDirFlag,
+			utils.CapturePeersFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -195,6 +266,37 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerDelayLeftoverFlag,
 			utils.MinerNoVerfiyFlag,
+			utils.MinerBuildersFlag,
+			utils.MinerOrderingFlag,
+			utils.MinerPriorityFlag,
+			utils.MinerBuildRoundsFlag,
+		},
+	},
+	{
+		Name: "VOTE SIGNER",
+		Flags: []cli.Flag{
+			utils.VoteSignerRemoteFlag,
+			utils.VoteSignerPubKeyFlag,
+			utils.VoteSignerTimeoutFlag,
+		},
+	},
+	{
+		Name: "VALIDATOR FAILOVER",
+		Flags: []cli.Flag{
+			utils.HARoleFlag,
+			utils.HAPeerFlag,
+			utils.HAIntervalFlag,
+			utils.HAFailoverTimeoutFlag,
+		},
+	},
+	{
+		Name: "RESOURCE GOVERNOR",
+		Flags: []cli.Flag{
+			utils.GovernorDiskLowFlag,
+			utils.GovernorDiskCriticalFlag,
+			utils.GovernorHeapLowFlag,
+			utils.GovernorHeapCriticalFlag,
+			utils.GovernorIntervalFlag,
 		},
 	},
 	{
@@ -211,6 +313,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.VMEnableDebugFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
+			utils.VMBackendFlag,
+			utils.VMBackendVerifyFlag,
 		},
 	},
 	{
@@ -240,6 +344,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Name: "MISC",
 		Flags: []cli.Flag{
 			utils.SnapshotFlag,
+			utils.SnapshotVerifyRateFlag,
+			utils.SnapshotRepairFlag,
 			utils.BloomFilterSizeFlag,
 			cli.HelpFlag,
 			utils.CatalystFlag,
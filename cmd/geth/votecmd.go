@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vote"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	voteProtectionCommand = cli.Command{
+		Name:      "vote-protection",
+		Usage:     "Manage the slashing protection database of the vote signer",
+		ArgsUsage: "",
+		Category:  "ACCOUNT COMMANDS",
+		Subcommands: []cli.Command{
+			voteProtectionExportCommand,
+			voteProtectionImportCommand,
+		},
+	}
+	voteProtectionExportCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportVoteProtection),
+		Name:      "export",
+		Usage:     "Export the slashing protection database in EIP-3076 interchange format",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+		},
+		Description: `
+Export the highest source and target numbers signed by every vote key into an
+EIP-3076 slashing protection interchange file, to be imported into the node
+taking over the keys.`,
+	}
+	voteProtectionImportCommand = cli.Command{
+		Action:    utils.MigrateFlags(importVoteProtection),
+		Name:      "import",
+		Usage:     "Import an EIP-3076 slashing protection interchange file",
+		ArgsUsage: "<filename>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+		},
+		Description: `
+Merge an EIP-3076 slashing protection interchange file into the local database.
+The local watermark of every key is only ever raised by an import.`,
+	}
+)
+
+// openVoteProtection opens the slashing protection database of the node along
+// with the genesis hash of its chain, which binds interchange files to it.
+func openVoteProtection(ctx *cli.Context, readonly bool) (*vote.ProtectionDB, common.Hash, func()) {
+	stack, _ := makeConfigNode(ctx)
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true, false)
+	genesis := rawdb.ReadCanonicalHash(chaindb, 0)
+	chaindb.Close()
+	if genesis == (common.Hash{}) {
+		utils.Fatalf("No genesis block found, initialise the chain first")
+	}
+	db, err := stack.OpenDatabase(vote.ProtectionDatabaseName, 0, 0, "", readonly)
+	if err != nil {
+		utils.Fatalf("Failed to open slashing protection database: %v", err)
+	}
+	return vote.NewProtectionDB(db), genesis, func() {
+		db.Close()
+		stack.Close()
+	}
+}
+
+func exportVoteProtection(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	db, genesis, release := openVoteProtection(ctx, true)
+	defer release()
+
+	out, err := os.Create(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := db.Export(out, genesis); err != nil {
+		utils.Fatalf("Export error: %v", err)
+	}
+	log.Info("Exported slashing protection data", "file", ctx.Args().First())
+	return nil
+}
+
+func importVoteProtection(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	db, genesis, release := openVoteProtection(ctx, false)
+	defer release()
+
+	in, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	keys, err := db.Import(in, genesis)
+	if err != nil {
+		utils.Fatalf("Import error: %v", err)
+	}
+	fmt.Printf("Imported slashing protection data of %d keys\n", keys)
+	return nil
+}
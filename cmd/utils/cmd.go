@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/governor"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
 	"github.com/ethereum/go-ethereum/log"
@@ -88,6 +89,16 @@ func StartNode(ctx *cli.Context, stack *node.Node) {
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
 		go stack.Close()
+
+		// Bound the time spent flushing state, a stuck shutdown would otherwise
+		// only be escapable by repeated interrupts
+		if timeout := ctx.GlobalDuration(ShutdownTimeoutFlag.Name); timeout > 0 {
+			time.AfterFunc(timeout, func() {
+				log.Error("Graceful shutdown timed out, forcing exit", "timeout", timeout)
+				debug.Exit()
+				os.Exit(1)
+			})
+		}
 		for i := 10; i > 0; i-- {
 			<-sigc
 			if i > 1 {
@@ -101,7 +112,7 @@ func StartNode(ctx *cli.Context, stack *node.Node) {
 
 func monitorFreeDiskSpace(sigc chan os.Signal, path string, freeDiskSpaceCritical uint64) {
 	for {
-		freeSpace, err := getFreeDiskSpace(path)
+		freeSpace, err := governor.FreeDiskSpace(path)
 		if err != nil {
 			log.Warn("Failed to get free disk space", "path", path, "err", err)
 			break
@@ -191,6 +191,10 @@ func GlobalBig(ctx *cli.Context, name string) *big.Int {
 // 3. cleans the path, e.g. /a/b/../c -> /a/c
 // Note, it has limitations, e.g. ~someuser/tmp will not be expanded
 func expandPath(p string) string {
+	// Object storage URLs are not local paths
+	if strings.Contains(p, "://") {
+		return p
+	}
 	if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~\\") {
 		if home := HomeDir(); home != "" {
 			p = home + p[1:]
@@ -41,20 +41,29 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/config"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/importtrace"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vote"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/governor"
+	"github.com/ethereum/go-ethereum/eth/ha"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	"github.com/ethereum/go-ethereum/ethdb/remotedb"
+	"github.com/ethereum/go-ethereum/ethgrpc"
 	"github.com/ethereum/go-ethereum/ethstats"
+	"github.com/ethereum/go-ethereum/exporter"
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
@@ -70,6 +79,8 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/webhook"
 )
 
 func init() {
@@ -124,6 +135,21 @@ var (
 		Name:  "directbroadcast",
 		Usage: "Enable directly broadcast mined block to all peers",
 	}
+	PrivateTxPeersFlag = cli.StringFlag{
+		Name:  "privatetx.peers",
+		Usage: "Comma separated enode URLs of the trusted eth/69 peers to relay private transactions to and accept them from",
+		Value: "",
+	}
+	SentryValidatorsFlag = cli.StringFlag{
+		Name:  "sentry.validators",
+		Usage: "Comma separated enode URLs of the hidden validators to act as a sentry for",
+		Value: "",
+	}
+	SentriesFlag = cli.StringFlag{
+		Name:  "sentry.nodes",
+		Usage: "Comma separated enode URLs of the sentries to hide this validator behind, the only peers accepted",
+		Value: "",
+	}
 	DisableSnapProtocolFlag = cli.BoolFlag{
 		Name:  "disablesnapprotocol",
 		Usage: "Disable snap protocol",
@@ -133,26 +159,92 @@ var (
 		Usage: "Enable diffy sync, Please note that enable diffsync will improve the syncing speed, " +
 			"but will degrade the security to light client level",
 	}
+	ServeWitnessFlag = cli.BoolFlag{
+		Name:  "witness.serve",
+		Usage: "Serve the execution witnesses of the recent blocks to the peers over eth/69 (re-executes the blocks)",
+	}
+	TracePropagationFlag = cli.BoolFlag{
+		Name:  "propagation.trace",
+		Usage: "Trace the block propagation timings with the peers enabling it too (debug_blockPropagation, debug_propagationReport)",
+	}
 	PipeCommitFlag = cli.BoolFlag{
 		Name:  "pipecommit",
 		Usage: "Enable MPT pipeline commit, it will improve syncing performance. It is an experimental feature(default is false)",
 	}
+	ParallelTxFlag = cli.BoolFlag{
+		Name:  "parallel",
+		Usage: "Enable the experimental parallel transaction execution when importing blocks",
+	}
+	ParallelTxNumFlag = cli.IntFlag{
+		Name:  "parallel.num",
+		Usage: "Number of transactions executed concurrently by the parallel execution (default = number of CPUs)",
+	}
 	RangeLimitFlag = cli.BoolFlag{
 		Name:  "rangelimit",
 		Usage: "Enable 5000 blocks limit for range query",
 	}
+	LogsMaxBlockRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxrange",
+		Usage: "Maximum block range of the log queries (0 = unlimited)",
+	}
+	LogsMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Maximum number of logs returned by a log query (0 = unlimited)",
+	}
 	AncientFlag = DirectoryFlag{
 		Name:  "datadir.ancient",
-		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata), or an s3:// or gs:// URL to keep them in an object storage",
+	}
+	DataDirReadOnlyFlag = cli.BoolFlag{
+		Name:  "datadir.readonly",
+		Usage: "Open the data directory of a running node without locking it to serve RPC queries, with networking and mining disabled",
+	}
+	DataDirRefreshFlag = cli.DurationFlag{
+		Name:  "datadir.readonly.refresh",
+		Usage: "Interval at which a read-only data directory catches up with the running node",
+		Value: 5 * time.Second,
+	}
+	BootstrapURLFlag = cli.StringFlag{
+		Name:  "bootstrap.url",
+		Usage: "URL of a chain backup to bootstrap an empty database from (http(s)://, s3:// or gs://)",
+	}
+	BootstrapSignersFlag = cli.StringFlag{
+		Name:  "bootstrap.signers",
+		Usage: "Comma separated addresses trusted to sign the bootstrap backup",
+	}
+	BootstrapCheckpointFlag = cli.StringFlag{
+		Name:  "bootstrap.checkpoint",
+		Usage: "Finalized block the bootstrap backup must end at (<number>:<hash>)",
 	}
 	DiffFlag = DirectoryFlag{
 		Name:  "datadir.diff",
 		Usage: "Data directory for difflayer segments (default = inside chaindata)",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation of new databases ('leveldb', 'pebble' in builds with the pebble tag, or 'remote' for read-only database commands), existing ones keep theirs",
+	}
+	DBRemoteFlag = cli.StringFlag{
+		Name:  "db.remote",
+		Usage: "RPC endpoint of the node serving the 'remote' database engine through its debug API",
+	}
+	DBPebbleCompactionsFlag = cli.IntFlag{
+		Name:  "db.pebble.compactions",
+		Usage: "Maximum number of concurrent pebble compactions (0 = number of CPUs)",
+	}
+	DBPebbleWALFlag = cli.StringFlag{
+		Name:  "db.pebble.wal",
+		Usage: "Pebble write-ahead log mode ('sync', 'async' or 'off', losing unflushed writes on a crash)",
+		Value: pebble.WALSync,
+	}
 	MinFreeDiskSpaceFlag = DirectoryFlag{
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
 	}
+	ShutdownTimeoutFlag = cli.DurationFlag{
+		Name:  "shutdown.timeout",
+		Usage: "Maximum time to flush sync and txpool state on shutdown before forcing exit (0 = unlimited)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -207,6 +299,14 @@ var (
 		Name:  "spicy",
 		Usage: "Chiliz Spicy testnet network",
 	}
+	NetworkFlag = cli.StringFlag{
+		Name:  "network",
+		Usage: "Named network to join (bundled: " + strings.Join(config.BundledNetworks(), ", ") + ")",
+	}
+	NetworkConfigFlag = DirectoryFlag{
+		Name:  "network-config",
+		Usage: "Directory of network definitions (<name>.json), taking precedence over the bundled networks",
+	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
 		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
@@ -215,6 +315,10 @@ var (
 		Name:  "dev.period",
 		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
 	}
+	DeveloperParliaFlag = cli.BoolFlag{
+		Name:  "dev.parlia",
+		Usage: "Ephemeral single-validator parlia network with a pre-funded developer account, sealing enabled and the clock warpable over RPC",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -250,6 +354,10 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
 		Value: &defaultSyncMode,
 	}
+	SyncCheckpointFlag = cli.StringFlag{
+		Name:  "sync.checkpoint",
+		Usage: "Hash of a trusted block, fast and snap sync only pivot to chains containing it",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
@@ -259,11 +367,60 @@ var (
 		Name:  "snapshot",
 		Usage: `Enables snapshot-database mode (default = enable)`,
 	}
+	SnapshotVerifyRateFlag = cli.IntFlag{
+		Name:  "snapshot.verify.rate",
+		Usage: "Accounts per second the background snapshot self-check verifies against the trie (0 = disabled)",
+		Value: ethconfig.Defaults.SnapshotVerifyRate,
+	}
+	SnapshotRepairFlag = cli.BoolFlag{
+		Name:  "snapshot.verify.repair",
+		Usage: "Regenerate the snapshot when the self-check finds it diverging from the trie",
+	}
 	TxLookupLimitFlag = cli.Uint64Flag{
 		Name:  "txlookuplimit",
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	TxLookupAllowlistFlag = cli.StringFlag{
+		Name:  "txlookupallowlist",
+		Usage: "Comma separated addresses whose transactions stay indexed beyond --txlookuplimit",
+	}
+	CallIndexFlag = cli.BoolFlag{
+		Name:  "callindex",
+		Usage: "Index the addresses touched by the internal calls of the imported blocks (eth_getInternalTransactionsByAddress)",
+	}
+	SenderIndexFlag = cli.BoolFlag{
+		Name:  "senderindex",
+		Usage: "Index the transactions of the imported blocks by sender and nonce (eth_getTransactionBySenderAndNonce)",
+	}
+	ContractIndexFlag = cli.BoolFlag{
+		Name:  "contractindex",
+		Usage: "Index the creators and creation transactions of the contracts deployed by the imported blocks (eth_getContractCreation)",
+	}
+	TokenIndexFlag = cli.BoolFlag{
+		Name:  "tokenindex",
+		Usage: "Index the ERC-20, ERC-721 and ERC-1155 transfers of the imported blocks by account (token_getTransfers, token_getBalancesAt)",
+	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain a precise address/topic log index to speed up eth_getLogs over wide block ranges",
+	}
+	TraceDBFlag = cli.BoolFlag{
+		Name:  "tracedb",
+		Usage: "Record the call traces, state diffs and rewards of the imported blocks (trace_* RPC APIs)",
+	}
+	RevertReasonsFlag = cli.BoolFlag{
+		Name:  "revertreasons",
+		Usage: "Record the revert reasons of the failed transactions of the imported blocks (debug_getRevertReason)",
+	}
+	OtterscanFlag = cli.BoolFlag{
+		Name:  "ots",
+		Usage: "Index the transactions by sender, recipient and internal calls of the imported blocks (ots_* RPC APIs)",
+	}
+	DoubleSignReporterFlag = cli.StringFlag{
+		Name:  "parlia.reporter",
+		Usage: "Unlocked account submitting the detected validator double signs to the slash contract",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -286,6 +443,14 @@ var (
 		Name:  "override.berlin",
 		Usage: "Manually specify Berlin fork-block, overriding the bundled setting",
 	}
+	OverrideForksFlag = cli.StringFlag{
+		Name:  "override.forks",
+		Usage: "Signed manifest (JSON) rescheduling upcoming hard forks, overriding the bundled settings",
+	}
+	OverrideSignersFlag = cli.StringFlag{
+		Name:  "override.signers",
+		Usage: "Comma separated addresses trusted to sign the hard fork override manifest",
+	}
 	// Light server and client settings
 	LightServeFlag = cli.IntFlag{
 		Name:  "light.serve",
@@ -386,6 +551,15 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolJournalRemotesFlag = cli.BoolFlag{
+		Name:  "txpool.journalremotes",
+		Usage: "Also journal the pending and queued remote transactions to survive node restarts",
+	}
+	TxPoolJournalLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.journallimit",
+		Usage: "Maximum number of remote transactions restored from the journal (0 = all)",
+		Value: core.DefaultTxPoolConfig.JournalLimit,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -416,6 +590,53 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: ethconfig.Defaults.TxPool.GlobalQueue,
 	}
+	TxPoolSenderQuotaFlag = cli.Uint64Flag{
+		Name:  "txpool.senderquota",
+		Usage: "Maximum number of pending and queued transactions per remote sender (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.SenderQuota,
+	}
+	TxPoolOriginQuotaFlag = cli.Uint64Flag{
+		Name:  "txpool.originquota",
+		Usage: "Maximum number of pending and queued transactions per peer IP address (0 = unlimited)",
+		Value: ethconfig.Defaults.TxPool.OriginQuota,
+	}
+	TxPoolQuotaExemptFlag = cli.StringFlag{
+		Name:  "txpool.quotaexempt",
+		Usage: "Comma separated sender addresses and peer IP addresses or IDs exempt from the quotas (e.g. known relayers)",
+	}
+	TxPoolBundlersFlag = cli.StringFlag{
+		Name:  "txpool.bundlers",
+		Usage: "Comma separated addresses of the EIP-4337 bundlers whose bundles are replaceable with the bundler price bump",
+	}
+	TxPoolBundlerPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.bundlerpricebump",
+		Usage: "Price bump percentage to replace a bundle of a bundler (0 = any increase)",
+		Value: ethconfig.Defaults.TxPool.BundlerPriceBump,
+	}
+	TxPoolBlobSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.blobslots",
+		Usage: "Maximum number of blob transactions in the blob sub-pool",
+		Value: ethconfig.Defaults.TxPool.BlobSlots,
+	}
+	TxPoolBlobAccountSlotsFlag = cli.Uint64Flag{
+		Name:  "txpool.blobaccountslots",
+		Usage: "Maximum number of blob transactions permitted per account",
+		Value: ethconfig.Defaults.TxPool.BlobAccountSlots,
+	}
+	TxPoolBlobPriceLimitFlag = cli.Uint64Flag{
+		Name:  "txpool.blobpricelimit",
+		Usage: "Minimum blob fee cap to enforce for acceptance into the blob sub-pool",
+		Value: ethconfig.Defaults.TxPool.BlobPriceLimit,
+	}
+	TxPoolBlobPriceBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.blobpricebump",
+		Usage: "Blob fee cap bump percentage to replace an already existing blob transaction",
+		Value: ethconfig.Defaults.TxPool.BlobPriceBump,
+	}
+	TxPoolPrefetchFlag = cli.BoolFlag{
+		Name:  "txpool.prefetch",
+		Usage: "Prefetch the state accessed by the pending transactions on top of every new head",
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
@@ -531,6 +752,81 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerBuildersFlag = cli.StringFlag{
+		Name:  "miner.builders",
+		Usage: "Comma separated addresses of the external block builders allowed to submit bids",
+		Value: "",
+	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: "Transaction ordering policy of the mined blocks (price, tip, priority, bundles)",
+		Value: miner.DefaultOrdering,
+	}
+	MinerPriorityFlag = cli.StringFlag{
+		Name:  "miner.priority",
+		Usage: "Comma separated addresses of the senders whose transactions the priority ordering includes first",
+		Value: "",
+	}
+	MinerBuildRoundsFlag = cli.StringFlag{
+		Name:  "miner.rounds",
+		Usage: "Comma separated fractions of the block interval at which the block candidate is extended with new transactions (empty = disabled)",
+		Value: "0.5,0.8,0.95",
+	}
+	// Vote signer settings
+	VoteSignerRemoteFlag = cli.StringFlag{
+		Name:  "vote.signer.remote",
+		Usage: "Web3Signer-compatible URL to delegate the BLS signing of votes to",
+	}
+	VoteSignerPubKeyFlag = cli.StringFlag{
+		Name:  "vote.signer.pubkey",
+		Usage: "Hex encoded BLS public key of the validator held by the remote signer",
+	}
+	VoteSignerTimeoutFlag = cli.DurationFlag{
+		Name:  "vote.signer.timeout",
+		Usage: "Timeout of a single remote vote signing request",
+		Value: ethconfig.Defaults.VoteSigner.RemoteTimeout,
+	}
+	// Validator failover settings
+	HARoleFlag = cli.StringFlag{
+		Name:  "ha.role",
+		Usage: "Role of the node in a validator failover pair (primary, backup)",
+	}
+	HAPeerFlag = cli.StringFlag{
+		Name:  "ha.peer",
+		Usage: "RPC endpoint of the partner node of the failover pair, exposing the ha API",
+	}
+	HAIntervalFlag = cli.DurationFlag{
+		Name:  "ha.interval",
+		Usage: "Interval between two status polls of the failover partner",
+		Value: ethconfig.Defaults.HA.Interval,
+	}
+	HAFailoverTimeoutFlag = cli.DurationFlag{
+		Name:  "ha.timeout",
+		Usage: "Time after which an unresponsive or stalled failover partner is replaced",
+		Value: ethconfig.Defaults.HA.FailoverTimeout,
+	}
+	// Resource governor settings
+	GovernorDiskLowFlag = cli.Uint64Flag{
+		Name:  "governor.disk.low",
+		Usage: "Free disk space in MB below which the node stops accepting transactions and serving snap (0 = disabled)",
+	}
+	GovernorDiskCriticalFlag = cli.Uint64Flag{
+		Name:  "governor.disk.critical",
+		Usage: "Free disk space in MB below which the node shuts down (0 = disabled)",
+	}
+	GovernorHeapLowFlag = cli.Uint64Flag{
+		Name:  "governor.heap.low",
+		Usage: "Heap usage in MB above which the node flushes its caches, stops accepting transactions and serving snap (0 = disabled)",
+	}
+	GovernorHeapCriticalFlag = cli.Uint64Flag{
+		Name:  "governor.heap.critical",
+		Usage: "Heap usage in MB above which the node shuts down (0 = disabled)",
+	}
+	GovernorIntervalFlag = cli.DurationFlag{
+		Name:  "governor.interval",
+		Usage: "Interval between two resource usage polls of the governor",
+		Value: ethconfig.Defaults.Governor.Interval,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 
@@ -571,6 +867,14 @@ var (
 		Name:  "ethstats",
 		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
 	}
+	ExporterURLFlag = cli.StringFlag{
+		Name:  "exporter",
+		Usage: "Broker URL to publish canonical blocks, receipts, logs and reorgs to (nats://host:port/subject or kafka://host:port/topic)",
+	}
+	WebhooksFlag = cli.BoolFlag{
+		Name:  "webhooks",
+		Usage: "Notify the webhooks registered through the webhook API of the activity of their watched addresses",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -636,6 +940,20 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GRPCEnabledFlag = cli.BoolFlag{
+		Name:  "grpc",
+		Usage: "Enable the gRPC server",
+	}
+	GRPCListenAddrFlag = cli.StringFlag{
+		Name:  "grpc.addr",
+		Usage: "gRPC server listening interface",
+		Value: node.DefaultGRPCHost,
+	}
+	GRPCPortFlag = cli.IntFlag{
+		Name:  "grpc.port",
+		Usage: "gRPC server listening port",
+		Value: node.DefaultGRPCPort,
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -665,6 +983,18 @@ var (
 		Usage: "HTTP path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	WSMaxSubscriptionsFlag = cli.IntFlag{
+		Name:  "ws.maxsubs",
+		Usage: "Maximum number of subscriptions per WS-RPC connection (0 = unlimited)",
+	}
+	WSMaxPendingNotificationsFlag = cli.IntFlag{
+		Name:  "ws.maxpending",
+		Usage: "Maximum number of notifications queued for a WS-RPC connection before it is dropped (0 = unlimited)",
+	}
+	WSMaxBytesPerSecondFlag = cli.IntFlag{
+		Name:  "ws.maxbytes",
+		Usage: "Maximum notification bytes per second sent to a WS-RPC connection (0 = unlimited)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -673,6 +1003,46 @@ var (
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	BatchRequestLimitFlag = cli.IntFlag{
+		Name:  "rpc.batch-request-limit",
+		Usage: "Maximum number of requests in a batch (0 = unlimited)",
+	}
+	BatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batch-response-max-size",
+		Usage: "Maximum number of result bytes returned from a batch (0 = unlimited)",
+	}
+	BatchParallelismFlag = cli.IntFlag{
+		Name:  "rpc.batch-parallelism",
+		Usage: "Number of requests of a batch executed concurrently",
+	}
+	RPCComputeUnitBudgetFlag = cli.IntFlag{
+		Name:  "rpc.cu-budget",
+		Usage: "Compute units credited to each RPC client per second (0 = unlimited)",
+	}
+	RPCComputeUnitBurstFlag = cli.IntFlag{
+		Name:  "rpc.cu-burst",
+		Usage: "Maximum compute units a RPC client may accumulate (defaults to the per second budget)",
+	}
+	RPCCallTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.call-timeout",
+		Usage: "Execution deadline of RPC calls (0 = none)",
+	}
+	RPCAPIKeyHeaderFlag = cli.StringFlag{
+		Name:  "rpc.apikey-header",
+		Usage: "HTTP header carrying the API key which identifies a RPC client for metering",
+	}
+	RPCAuthFileFlag = cli.StringFlag{
+		Name:  "rpc.authfile",
+		Usage: "TOML file with the API keys and JWT secrets accepted by the HTTP-RPC and WS-RPC servers",
+	}
+	RPCTracingEndpointFlag = cli.StringFlag{
+		Name:  "rpc.otlp",
+		Usage: "OTLP/HTTP endpoint receiving the HTTP-RPC and WS-RPC calls as OpenTelemetry spans (e.g. http://localhost:4318/v1/traces)",
+	}
+	RPCTracingThresholdFlag = cli.DurationFlag{
+		Name:  "rpc.otlp.threshold",
+		Usage: "Minimum duration of a RPC call for it to be exported as a span",
+	}
 	AllowUnprotectedTxs = cli.BoolFlag{
 		Name:  "rpc.allow-unprotected-txs",
 		Usage: "Allow for unprotected (non EIP155 signed) transactions to be submitted via RPC",
@@ -689,11 +1059,35 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: node.DefaultConfig.P2P.MaxPendingPeers,
 	}
+	DialRatioFlag = cli.IntFlag{
+		Name:  "dialratio",
+		Usage: "Reserve 1/N of the peer slots for outbound connections, keeping inbound ones from taking them (defaults used if set to 0)",
+		Value: node.DefaultConfig.P2P.DialRatio,
+	}
+	MaxPeersPerIPFlag = cli.IntFlag{
+		Name:  "maxpeers.ip",
+		Usage: "Maximum number of network peers from the same IP address (unlimited if set to 0)",
+		Value: node.DefaultConfig.P2P.MaxPeersPerIP,
+	}
+	MaxPeersPerSubnetFlag = cli.IntFlag{
+		Name:  "maxpeers.subnet",
+		Usage: "Maximum number of network peers from the same /24 IPv4 or /48 IPv6 network (unlimited if set to 0)",
+		Value: node.DefaultConfig.P2P.MaxPeersPerSubnet,
+	}
+	ProtectedPeersFlag = cli.IntFlag{
+		Name:  "maxpeers.protected",
+		Usage: "Number of longest connected inbound peers protected when evicting one for a new inbound peer (eviction disabled if set to 0)",
+		Value: node.DefaultConfig.P2P.ProtectedPeers,
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
 		Value: 30303,
 	}
+	QUICPortFlag = cli.IntFlag{
+		Name:  "quic.port",
+		Usage: "UDP listening port of the experimental QUIC transport for RLPx (0 = disabled, requires a build with the quic tag)",
+	}
 	BootnodesFlag = cli.StringFlag{
 		Name:  "bootnodes",
 		Usage: "Comma separated enode URLs for P2P discovery bootstrap",
@@ -709,9 +1103,14 @@ var (
 	}
 	NATFlag = cli.StringFlag{
 		Name:  "nat",
-		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|extip:<IP>)",
+		Usage: "NAT port mapping mechanism (any|none|upnp|pmp|stun[:<host:port>]|extip:<IP>), comma separated ones are tried in order",
 		Value: "any",
 	}
+	NATRecheckFlag = cli.DurationFlag{
+		Name:  "nat.recheck",
+		Usage: "Interval of re-checking the external IP for changes",
+		Value: 5 * time.Minute,
+	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
@@ -728,6 +1127,14 @@ var (
 		Name:  "discovery.dns",
 		Usage: "Sets DNS discovery entry points (use \"\" to disable DNS)",
 	}
+	CaptureDirFlag = DirectoryFlag{
+		Name:  "capture.dir",
+		Usage: "Directory recording the eth and snap messages of the captured peers (enables admin.startCapture)",
+	}
+	CapturePeersFlag = cli.StringFlag{
+		Name:  "capture.peers",
+		Usage: "Comma separated node IDs of the peers to capture the messages of",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -810,6 +1217,10 @@ var (
 		Usage: "Comma-separated InfluxDB tags (key/values) attached to all measurements",
 		Value: metrics.DefaultConfig.InfluxDBTags,
 	}
+	ImportTraceOTLPFlag = cli.StringFlag{
+		Name:  "importtrace.otlp",
+		Usage: "OpenTelemetry collector endpoint to export the block import stages to as OTLP/HTTP spans (e.g. http://localhost:4318/v1/traces)",
+	}
 	EWASMInterpreterFlag = cli.StringFlag{
 		Name:  "vm.ewasm",
 		Usage: "External ewasm configuration (default = built-in interpreter)",
@@ -820,6 +1231,15 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	VMBackendFlag = cli.StringFlag{
+		Name:  "vm.backend",
+		Usage: "EVM execution backend (interpreter, compiled)",
+		Value: "interpreter",
+	}
+	VMBackendVerifyFlag = cli.Uint64Flag{
+		Name:  "vm.backend.verify",
+		Usage: "Number of imported blocks also executed with the interpreter, to cross-check the EVM backend",
+	}
 
 	// Init network
 	InitNetworkSize = cli.IntFlag{
@@ -856,6 +1276,11 @@ var (
 		Usage: "Sets the expected remained amount of blocks for offline block prune",
 	}
 
+	KeepBlocksFlag = cli.Uint64Flag{
+		Name:  "keep-blocks",
+		Usage: "Number of recent blocks whose states are kept by the offline state prune (requires an archive node)",
+	}
+
 	CheckSnapshotWithMPT = cli.BoolFlag{
 		Name:  "check-snapshot-with-mpt",
 		Usage: "Enable checking between snapshot and MPT ",
@@ -945,6 +1370,8 @@ func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 		urls = params.ChilizScovilleBootnodes
 	case ctx.GlobalBool(ChilizSpicyFlag.Name):
 		urls = params.ChilizSpicyBootnodes
+	case ctx.GlobalIsSet(NetworkFlag.Name):
+		urls = MakeNetwork(ctx).Bootnodes
 	case cfg.BootstrapNodes != nil:
 		return // already set, don't apply defaults.
 	}
@@ -992,6 +1419,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(ListenPortFlag.Name) {
 		cfg.ListenAddr = fmt.Sprintf(":%d", ctx.GlobalInt(ListenPortFlag.Name))
 	}
+	if ctx.GlobalInt(QUICPortFlag.Name) != 0 {
+		cfg.QUICAddr = fmt.Sprintf(":%d", ctx.GlobalInt(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1003,6 +1433,9 @@ func setNAT(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NAT = natif
 	}
+	if ctx.GlobalIsSet(NATRecheckFlag.Name) {
+		cfg.NATRecheck = ctx.GlobalDuration(NATRecheckFlag.Name)
+	}
 }
 
 // SplitAndTrim splits input separated by a comma
@@ -1073,6 +1506,25 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.GlobalIsSet(BatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(BatchRequestLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(BatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(BatchResponseMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(BatchParallelismFlag.Name) {
+		cfg.BatchParallelism = ctx.GlobalInt(BatchParallelismFlag.Name)
+	}
+	setRPCMetering(ctx, cfg)
+	if ctx.GlobalIsSet(RPCAuthFileFlag.Name) {
+		cfg.RPCAuthFile = ctx.GlobalString(RPCAuthFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTracingEndpointFlag.Name) {
+		cfg.RPCTracingEndpoint = ctx.GlobalString(RPCTracingEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTracingThresholdFlag.Name) {
+		cfg.RPCTracingThreshold = ctx.GlobalDuration(RPCTracingThresholdFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1086,6 +1538,20 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setGRPC creates the gRPC listener interface string from the set command line
+// flags, returning empty if the gRPC endpoint is disabled.
+func setGRPC(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(GRPCEnabledFlag.Name) && cfg.GRPCHost == "" {
+		cfg.GRPCHost = "127.0.0.1"
+		if ctx.GlobalIsSet(GRPCListenAddrFlag.Name) {
+			cfg.GRPCHost = ctx.GlobalString(GRPCListenAddrFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(GRPCPortFlag.Name) {
+		cfg.GRPCPort = ctx.GlobalInt(GRPCPortFlag.Name)
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -1110,6 +1576,41 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSPathPrefixFlag.Name) {
 		cfg.WSPathPrefix = ctx.GlobalString(WSPathPrefixFlag.Name)
 	}
+
+	if ctx.GlobalIsSet(WSMaxSubscriptionsFlag.Name) {
+		cfg.WSMaxSubscriptions = ctx.GlobalInt(WSMaxSubscriptionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSMaxPendingNotificationsFlag.Name) {
+		cfg.WSMaxPendingNotifications = ctx.GlobalInt(WSMaxPendingNotificationsFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSMaxBytesPerSecondFlag.Name) {
+		cfg.WSMaxBytesPerSecond = ctx.GlobalInt(WSMaxBytesPerSecondFlag.Name)
+	}
+}
+
+// setRPCMetering configures RPC call metering from the command line flags.
+func setRPCMetering(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalIsSet(RPCComputeUnitBudgetFlag.Name) && !ctx.GlobalIsSet(RPCCallTimeoutFlag.Name) {
+		return
+	}
+	if cfg.RPCMetering == nil {
+		cfg.RPCMetering = new(rpc.MeteringConfig)
+	}
+	if cfg.RPCMetering.MethodCosts == nil {
+		cfg.RPCMetering.MethodCosts = node.DefaultRPCMethodCosts
+	}
+	if ctx.GlobalIsSet(RPCComputeUnitBudgetFlag.Name) {
+		cfg.RPCMetering.UnitsPerSecond = ctx.GlobalInt(RPCComputeUnitBudgetFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCComputeUnitBurstFlag.Name) {
+		cfg.RPCMetering.Burst = ctx.GlobalInt(RPCComputeUnitBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCCallTimeoutFlag.Name) {
+		cfg.RPCMetering.DefaultTimeout = ctx.GlobalDuration(RPCCallTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAPIKeyHeaderFlag.Name) {
+		cfg.RPCMetering.APIKeyHeader = ctx.GlobalString(RPCAPIKeyHeaderFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1279,6 +1780,18 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(DialRatioFlag.Name) {
+		cfg.DialRatio = ctx.GlobalInt(DialRatioFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPeersPerIPFlag.Name) {
+		cfg.MaxPeersPerIP = ctx.GlobalInt(MaxPeersPerIPFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxPeersPerSubnetFlag.Name) {
+		cfg.MaxPeersPerSubnet = ctx.GlobalInt(MaxPeersPerSubnetFlag.Name)
+	}
+	if ctx.GlobalIsSet(ProtectedPeersFlag.Name) {
+		cfg.ProtectedPeers = ctx.GlobalInt(ProtectedPeersFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -1301,7 +1814,24 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
-	if ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(CatalystFlag.Name) {
+	if ctx.GlobalIsSet(CaptureDirFlag.Name) {
+		cfg.CaptureDir = ctx.GlobalString(CaptureDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(CapturePeersFlag.Name) {
+		if cfg.CaptureDir == "" {
+			Fatalf("Option %q requires %q", CapturePeersFlag.Name, CaptureDirFlag.Name)
+		}
+		cfg.CapturePeers = nil
+		for _, id := range SplitAndTrim(ctx.GlobalString(CapturePeersFlag.Name)) {
+			node, err := enode.ParseID(id)
+			if err != nil {
+				Fatalf("Option %q: invalid node ID %s: %v", CapturePeersFlag.Name, id, err)
+			}
+			cfg.CapturePeers = append(cfg.CapturePeers, node)
+		}
+	}
+
+	if ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(DeveloperParliaFlag.Name) || ctx.GlobalBool(CatalystFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
 		cfg.ListenAddr = ""
@@ -1317,9 +1847,12 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
+	setGRPC(ctx, cfg)
 	setWS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
+	setDatabase(ctx, cfg)
+	setDataDirReadOnly(ctx, cfg)
 	setSmartCard(ctx, cfg)
 
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
@@ -1376,7 +1909,7 @@ func setDataDir(ctx *cli.Context, cfg *node.Config) {
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
-	case ctx.GlobalBool(DeveloperFlag.Name):
+	case ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(DeveloperParliaFlag.Name):
 		cfg.DataDir = "" // unless explicitly requested, use memory databases
 	case ctx.GlobalBool(RopstenFlag.Name) && cfg.DataDir == node.DefaultDataDir():
 		// Maintain compatibility with older Geth configurations storing the
@@ -1402,9 +1935,62 @@ func setDataDir(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "chiliz")
 	case ctx.GlobalBool(ChilizSpicyFlag.Name) && cfg.DataDir == node.DefaultDataDir():
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "chiliz")
+	case ctx.GlobalIsSet(NetworkFlag.Name) && cfg.DataDir == node.DefaultDataDir():
+		cfg.DataDir = filepath.Join(node.DefaultDataDir(), ctx.GlobalString(NetworkFlag.Name))
 	}
 }
 
+func setDatabase(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		switch engine := ctx.GlobalString(DBEngineFlag.Name); engine {
+		case rawdb.DBLeveldb, rawdb.DBPebble, rawdb.DBRemote:
+			cfg.DBEngine = engine
+		default:
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb', 'pebble' or 'remote'", engine)
+		}
+	}
+	if cfg.DBEngine == rawdb.DBPebble && !rawdb.PebbleEnabled {
+		Fatalf("The pebble database engine is not available in this build, rebuild on a 64-bit platform with the pebble build tag")
+	}
+	if ctx.GlobalIsSet(DBPebbleCompactionsFlag.Name) {
+		cfg.DBPebble.Compactions = ctx.GlobalInt(DBPebbleCompactionsFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBPebbleWALFlag.Name) {
+		switch wal := ctx.GlobalString(DBPebbleWALFlag.Name); wal {
+		case pebble.WALSync, pebble.WALAsync, pebble.WALOff:
+			cfg.DBPebble.WAL = wal
+		default:
+			Fatalf("Invalid choice for db.pebble.wal '%s', allowed 'sync', 'async' or 'off'", wal)
+		}
+	}
+}
+
+// setDataDirReadOnly configures opening the data directory of a running node
+// for reading. Everything which would write into the directory, or clash with
+// the running node, is disabled.
+func setDataDirReadOnly(ctx *cli.Context, cfg *node.Config) {
+	if !ctx.GlobalBool(DataDirReadOnlyFlag.Name) {
+		return
+	}
+	if cfg.DataDir == "" {
+		Fatalf("Option --%s requires a data directory", DataDirReadOnlyFlag.Name)
+	}
+	if cfg.DBEngine == rawdb.DBRemote {
+		Fatalf("Option --%s is not supported by the remote database engine", DataDirReadOnlyFlag.Name)
+	}
+	cfg.DataDirReadOnly = true
+	cfg.DataDirRefresh = ctx.GlobalDuration(DataDirRefreshFlag.Name)
+
+	// The default IPC endpoint is the one of the running node
+	if !ctx.GlobalIsSet(IPCPathFlag.Name) {
+		cfg.IPCPath = ""
+	}
+	cfg.P2P.MaxPeers = 0
+	cfg.P2P.NoDiscovery = true
+	cfg.P2P.DiscoveryV5 = false
+	cfg.P2P.ListenAddr = ""
+}
+
 func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	// If we are running the light client, apply another group
 	// settings for gas oracle.
@@ -1443,6 +2029,12 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalRemotesFlag.Name) {
+		cfg.JournalRemotes = ctx.GlobalBool(TxPoolJournalRemotesFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolJournalLimitFlag.Name) {
+		cfg.JournalLimit = ctx.GlobalUint64(TxPoolJournalLimitFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1461,6 +2053,45 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSenderQuotaFlag.Name) {
+		cfg.SenderQuota = ctx.GlobalUint64(TxPoolSenderQuotaFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolOriginQuotaFlag.Name) {
+		cfg.OriginQuota = ctx.GlobalUint64(TxPoolOriginQuotaFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolQuotaExemptFlag.Name) {
+		for _, exempt := range strings.Split(ctx.GlobalString(TxPoolQuotaExemptFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(exempt); common.IsHexAddress(trimmed) {
+				cfg.QuotaExemptSenders = append(cfg.QuotaExemptSenders, common.HexToAddress(trimmed))
+			} else if trimmed != "" {
+				cfg.QuotaExemptOrigins = append(cfg.QuotaExemptOrigins, trimmed)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolBundlersFlag.Name) {
+		for _, bundler := range strings.Split(ctx.GlobalString(TxPoolBundlersFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(bundler); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --txpool.bundlers: %s", trimmed)
+			} else {
+				cfg.Bundlers = append(cfg.Bundlers, common.HexToAddress(trimmed))
+			}
+		}
+	}
+	if ctx.GlobalIsSet(TxPoolBundlerPriceBumpFlag.Name) {
+		cfg.BundlerPriceBump = ctx.GlobalUint64(TxPoolBundlerPriceBumpFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBlobSlotsFlag.Name) {
+		cfg.BlobSlots = ctx.GlobalUint64(TxPoolBlobSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBlobAccountSlotsFlag.Name) {
+		cfg.BlobAccountSlots = ctx.GlobalUint64(TxPoolBlobAccountSlotsFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBlobPriceLimitFlag.Name) {
+		cfg.BlobPriceLimit = ctx.GlobalUint64(TxPoolBlobPriceLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolBlobPriceBumpFlag.Name) {
+		cfg.BlobPriceBump = ctx.GlobalUint64(TxPoolBlobPriceBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
@@ -1522,6 +2153,100 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerBuildersFlag.Name) {
+		cfg.Builders = nil
+		for _, builder := range strings.Split(ctx.GlobalString(MinerBuildersFlag.Name), ",") {
+			if builder = strings.TrimSpace(builder); builder == "" {
+				continue
+			}
+			if !common.IsHexAddress(builder) {
+				Fatalf("Invalid builder address %q", builder)
+			}
+			cfg.Builders = append(cfg.Builders, common.HexToAddress(builder))
+		}
+	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.GlobalString(MinerOrderingFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerPriorityFlag.Name) {
+		cfg.PriorityAddresses = nil
+		for _, addr := range strings.Split(ctx.GlobalString(MinerPriorityFlag.Name), ",") {
+			if addr = strings.TrimSpace(addr); addr == "" {
+				continue
+			}
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid priority address %q", addr)
+			}
+			cfg.PriorityAddresses = append(cfg.PriorityAddresses, common.HexToAddress(addr))
+		}
+	}
+	if ctx.GlobalIsSet(MinerBuildRoundsFlag.Name) {
+		cfg.BuildRounds = nil
+		for _, round := range strings.Split(ctx.GlobalString(MinerBuildRoundsFlag.Name), ",") {
+			if round = strings.TrimSpace(round); round == "" {
+				continue
+			}
+			fraction, err := strconv.ParseFloat(round, 64)
+			if err != nil || fraction <= 0 || fraction >= 1 {
+				Fatalf("Invalid miner build round %q, want a fraction in (0, 1)", round)
+			}
+			cfg.BuildRounds = append(cfg.BuildRounds, fraction)
+		}
+	}
+	if err := miner.CheckOrdering(cfg); err != nil {
+		Fatalf("Invalid miner ordering: %v", err)
+	}
+}
+
+func setVoteSigner(ctx *cli.Context, cfg *vote.Config) {
+	if ctx.GlobalIsSet(VoteSignerRemoteFlag.Name) {
+		cfg.RemoteSignerURL = ctx.GlobalString(VoteSignerRemoteFlag.Name)
+	}
+	if ctx.GlobalIsSet(VoteSignerPubKeyFlag.Name) {
+		cfg.RemotePublicKey = ctx.GlobalString(VoteSignerPubKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(VoteSignerTimeoutFlag.Name) {
+		cfg.RemoteTimeout = ctx.GlobalDuration(VoteSignerTimeoutFlag.Name)
+	}
+	if cfg.RemoteSignerURL != "" && cfg.RemotePublicKey == "" {
+		Fatalf("--%s is required when using a remote vote signer", VoteSignerPubKeyFlag.Name)
+	}
+}
+
+func setFailover(ctx *cli.Context, cfg *ha.Config) {
+	if ctx.GlobalIsSet(HARoleFlag.Name) {
+		cfg.Role = ctx.GlobalString(HARoleFlag.Name)
+	}
+	if ctx.GlobalIsSet(HAPeerFlag.Name) {
+		cfg.Peer = ctx.GlobalString(HAPeerFlag.Name)
+	}
+	if ctx.GlobalIsSet(HAIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(HAIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(HAFailoverTimeoutFlag.Name) {
+		cfg.FailoverTimeout = ctx.GlobalDuration(HAFailoverTimeoutFlag.Name)
+	}
+	if cfg.Role != "" && cfg.Peer == "" {
+		Fatalf("--%s is required for validator failover", HAPeerFlag.Name)
+	}
+}
+
+func setGovernor(ctx *cli.Context, cfg *governor.Config) {
+	if ctx.GlobalIsSet(GovernorDiskLowFlag.Name) {
+		cfg.DiskLow = ctx.GlobalUint64(GovernorDiskLowFlag.Name)
+	}
+	if ctx.GlobalIsSet(GovernorDiskCriticalFlag.Name) {
+		cfg.DiskCritical = ctx.GlobalUint64(GovernorDiskCriticalFlag.Name)
+	}
+	if ctx.GlobalIsSet(GovernorHeapLowFlag.Name) {
+		cfg.HeapLow = ctx.GlobalUint64(GovernorHeapLowFlag.Name)
+	}
+	if ctx.GlobalIsSet(GovernorHeapCriticalFlag.Name) {
+		cfg.HeapCritical = ctx.GlobalUint64(GovernorHeapCriticalFlag.Name)
+	}
+	if ctx.GlobalIsSet(GovernorIntervalFlag.Name) {
+		cfg.Interval = ctx.GlobalDuration(GovernorIntervalFlag.Name)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1591,9 +2316,10 @@ func CheckExclusive(ctx *cli.Context, args ...interface{}) {
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	// Avoid conflicting network flags
-	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, RopstenFlag, RinkebyFlag, GoerliFlag, YoloV3Flag, ChilizMainnetFlag, ChilizTestnetFlag, ChilizSpicyFlag)
+	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, DeveloperParliaFlag, RopstenFlag, RinkebyFlag, GoerliFlag, YoloV3Flag, ChilizMainnetFlag, ChilizTestnetFlag, ChilizSpicyFlag, NetworkFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
-	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag)       // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, DeveloperParliaFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
 	if ctx.GlobalString(GCModeFlag.Name) == "archive" && ctx.GlobalUint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.GlobalSet(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1610,6 +2336,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
+	setVoteSigner(ctx, &cfg.VoteSigner)
+	setFailover(ctx, &cfg.HA)
+	setGovernor(ctx, &cfg.Governor)
 	setWhitelist(ctx, cfg)
 	setLes(ctx, cfg)
 
@@ -1636,6 +2365,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.GlobalIsSet(SyncCheckpointFlag.Name) {
+		if cfg.SyncMode != downloader.FastSync && cfg.SyncMode != downloader.SnapSync {
+			Fatalf("--%s requires --%s snap or fast", SyncCheckpointFlag.Name, SyncModeFlag.Name)
+		}
+		if err := cfg.SyncCheckpoint.UnmarshalText([]byte(ctx.GlobalString(SyncCheckpointFlag.Name))); err != nil {
+			Fatalf("Invalid sync checkpoint hash %s: %v", ctx.GlobalString(SyncCheckpointFlag.Name), err)
+		}
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1664,18 +2401,53 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(DirectBroadcastFlag.Name) {
 		cfg.DirectBroadcast = ctx.GlobalBool(DirectBroadcastFlag.Name)
 	}
+	if ctx.GlobalIsSet(PrivateTxPeersFlag.Name) {
+		cfg.PrivateTxPeers = nil
+		for _, url := range strings.Split(ctx.GlobalString(PrivateTxPeersFlag.Name), ",") {
+			if url = strings.TrimSpace(url); url != "" {
+				cfg.PrivateTxPeers = append(cfg.PrivateTxPeers, url)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(SentryValidatorsFlag.Name) {
+		cfg.SentryValidators = SplitAndTrim(ctx.GlobalString(SentryValidatorsFlag.Name))
+	}
+	if ctx.GlobalIsSet(SentriesFlag.Name) {
+		cfg.Sentries = SplitAndTrim(ctx.GlobalString(SentriesFlag.Name))
+	}
 	if ctx.GlobalIsSet(DisableSnapProtocolFlag.Name) {
 		cfg.DisableSnapProtocol = ctx.GlobalBool(DisableSnapProtocolFlag.Name)
 	}
 	if ctx.GlobalIsSet(DiffSyncFlag.Name) {
 		cfg.DiffSync = ctx.GlobalBool(DiffSyncFlag.Name)
 	}
+	if ctx.GlobalIsSet(ServeWitnessFlag.Name) {
+		cfg.ServeWitness = ctx.GlobalBool(ServeWitnessFlag.Name)
+	}
+	if ctx.GlobalIsSet(TracePropagationFlag.Name) {
+		cfg.TracePropagation = ctx.GlobalBool(TracePropagationFlag.Name)
+	}
 	if ctx.GlobalIsSet(PipeCommitFlag.Name) {
 		cfg.PipeCommit = ctx.GlobalBool(PipeCommitFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolPrefetchFlag.Name) {
+		cfg.TxPoolPrefetch = ctx.GlobalBool(TxPoolPrefetchFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelTxFlag.Name) {
+		cfg.ParallelTxMode = ctx.GlobalBool(ParallelTxFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelTxNumFlag.Name) {
+		cfg.ParallelTxNum = ctx.GlobalInt(ParallelTxNumFlag.Name)
+	}
 	if ctx.GlobalIsSet(RangeLimitFlag.Name) {
 		cfg.RangeLimit = ctx.GlobalBool(RangeLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogsMaxBlockRangeFlag.Name) {
+		cfg.LogsMaxBlockRange = ctx.GlobalUint64(LogsMaxBlockRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogsMaxResultsFlag.Name) {
+		cfg.LogsMaxResults = ctx.GlobalInt(LogsMaxResultsFlag.Name)
+	}
 	// Read the value from the flag no matter if it's set or not.
 	cfg.Preimages = ctx.GlobalBool(CachePreimagesFlag.Name)
 	if cfg.NoPruning && !cfg.Preimages {
@@ -1685,6 +2457,52 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxLookupAllowlistFlag.Name) {
+		cfg.TxLookupAllowlist = nil
+		for _, addr := range strings.Split(ctx.GlobalString(TxLookupAllowlistFlag.Name), ",") {
+			if addr = strings.TrimSpace(addr); addr == "" {
+				continue
+			}
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid transaction index allowlist address %q", addr)
+			}
+			cfg.TxLookupAllowlist = append(cfg.TxLookupAllowlist, common.HexToAddress(addr))
+		}
+	}
+	if len(cfg.TxLookupAllowlist) > 0 && cfg.TxLookupLimit == 0 {
+		log.Warn("Transaction index allowlist ignored, the entire chain is indexed")
+	}
+	if ctx.GlobalIsSet(CallIndexFlag.Name) {
+		cfg.CallIndex = ctx.GlobalBool(CallIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(SenderIndexFlag.Name) {
+		cfg.SenderIndex = ctx.GlobalBool(SenderIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(ContractIndexFlag.Name) {
+		cfg.ContractIndex = ctx.GlobalBool(ContractIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TokenIndexFlag.Name) {
+		cfg.TokenIndex = ctx.GlobalBool(TokenIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(TraceDBFlag.Name) {
+		cfg.TraceDB = ctx.GlobalBool(TraceDBFlag.Name)
+	}
+	if ctx.GlobalIsSet(RevertReasonsFlag.Name) {
+		cfg.RevertReasons = ctx.GlobalBool(RevertReasonsFlag.Name)
+	}
+	if ctx.GlobalIsSet(OtterscanFlag.Name) {
+		cfg.Otterscan = ctx.GlobalBool(OtterscanFlag.Name)
+	}
+	if ctx.GlobalIsSet(DoubleSignReporterFlag.Name) {
+		reporter := ctx.GlobalString(DoubleSignReporterFlag.Name)
+		if !common.IsHexAddress(reporter) {
+			Fatalf("Invalid double sign reporter address %q", reporter)
+		}
+		cfg.DoubleSignReporter = common.HexToAddress(reporter)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1703,6 +2521,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
 		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(SnapshotVerifyRateFlag.Name) {
+		cfg.SnapshotVerifyRate = ctx.GlobalInt(SnapshotVerifyRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotRepairFlag.Name) {
+		cfg.SnapshotRepair = ctx.GlobalBool(SnapshotRepairFlag.Name)
+	}
 	if !ctx.GlobalBool(SnapshotFlag.Name) {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -1727,6 +2551,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(EVMInterpreterFlag.Name) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMBackendFlag.Name) {
+		if _, err := vm.ParseBackend(ctx.GlobalString(VMBackendFlag.Name)); err != nil {
+			Fatalf("Option %q: %v", VMBackendFlag.Name, err)
+		}
+		cfg.VMBackend = ctx.GlobalString(VMBackendFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMBackendVerifyFlag.Name) {
+		cfg.VMBackendVerify = ctx.GlobalUint64(VMBackendVerifyFlag.Name)
+	}
 	if ctx.GlobalIsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCapFlag.Name)
 	}
@@ -1748,6 +2581,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			cfg.EthDiscoveryURLs = SplitAndTrim(urls)
 		}
 	}
+	if ctx.GlobalBool(DataDirReadOnlyFlag.Name) {
+		if ctx.GlobalBool(MiningEnabledFlag.Name) || ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(DeveloperParliaFlag.Name) {
+			Fatalf("Mining is not supported on a read-only data directory")
+		}
+		// Snapshots would be generated in memory, the journals are the running node's
+		cfg.SnapshotCache = 0
+		cfg.TrieCleanCacheJournal = ""
+		cfg.TxPool.Journal = ""
+		cfg.PersistDiff = false
+		cfg.TxLookupAllowlist = nil
+		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
+	}
 	// Override any default configs for hard coded networks.
 	switch {
 	case ctx.GlobalBool(MainnetFlag.Name):
@@ -1791,7 +2636,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
 			cfg.NetworkId = 88882
 		}
-	case ctx.GlobalBool(DeveloperFlag.Name):
+	case ctx.GlobalIsSet(NetworkFlag.Name):
+		network := MakeNetwork(ctx)
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkId = network.NetworkID
+		}
+		cfg.Genesis = network.Genesis
+	case ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(DeveloperParliaFlag.Name):
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
 			cfg.NetworkId = 1337
 		}
@@ -1824,7 +2675,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		if ctx.GlobalBool(DeveloperParliaFlag.Name) {
+			// The developer account is the single validator, sealing from the start
+			cfg.Genesis = config.DeveloperGenesis(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+			cfg.Miner.Etherbase = developer.Address
+			cfg.TimeWarp = true
+		} else {
+			cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), developer.Address)
+		}
 		if ctx.GlobalIsSet(DataDirFlag.Name) {
 			// Check if we have an already initialized chain and fall back to
 			// that if so. Otherwise we need to generate a new genesis spec.
@@ -1894,6 +2752,20 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterExporterService configures the chain data exporter and adds it to the
+// given node.
+func RegisterExporterService(stack *node.Node, backend *eth.Ethereum, url string) {
+	if err := exporter.New(stack, backend.BlockChain(), backend.ChainDb(), url); err != nil {
+		Fatalf("Failed to register the chain data exporter: %v", err)
+	}
+}
+
+// RegisterWebhookService configures the webhook service and adds it to the given
+// node.
+func RegisterWebhookService(stack *node.Node, backend *eth.Ethereum) {
+	webhook.New(stack, backend.BlockChain(), backend.ChainDb())
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
 	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
@@ -1901,6 +2773,14 @@ func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.C
 	}
 }
 
+// RegisterGRPCService is a utility function to construct a new gRPC service and
+// register it against a node.
+func RegisterGRPCService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
+	if _, err := ethgrpc.New(stack, backend, cfg.GRPCEndpoint()); err != nil {
+		Fatalf("Failed to register the gRPC service: %v", err)
+	}
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -1927,6 +2807,10 @@ func SetupMetrics(ctx *cli.Context) {
 			exp.Setup(address)
 		}
 	}
+	if endpoint := ctx.GlobalString(ImportTraceOTLPFlag.Name); endpoint != "" {
+		log.Info("Enabling block import span export", "endpoint", endpoint)
+		importtrace.EnableOTLP(endpoint)
+	}
 }
 
 func SplitTagsFlag(tagsFlag string) map[string]string {
@@ -1946,7 +2830,7 @@ func SplitTagsFlag(tagsFlag string) map[string]string {
 	return tagsMap
 }
 
-// MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
+// MakeChainDatabase open a database using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly, disableFreeze bool) ethdb.Database {
 	var (
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1955,6 +2839,20 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly, disableFree
 		err     error
 		chainDb ethdb.Database
 	)
+	if ctx.GlobalString(DBEngineFlag.Name) == rawdb.DBRemote {
+		if !readonly {
+			Fatalf("The remote database engine is read-only")
+		}
+		url := ctx.GlobalString(DBRemoteFlag.Name)
+		if url == "" {
+			Fatalf("The remote database engine requires --%s", DBRemoteFlag.Name)
+		}
+		client, err := rpc.Dial(url)
+		if err != nil {
+			Fatalf("Could not connect to remote database: %v", err)
+		}
+		return remotedb.New(client)
+	}
 	if ctx.GlobalString(SyncModeFlag.Name) == "light" {
 		name := "lightchaindata"
 		chainDb, err = stack.OpenDatabase(name, cache, handles, "", readonly)
@@ -1987,12 +2885,23 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 		genesis = core.DefaultChilizTestnetGenesisBlock()
 	case ctx.GlobalBool(ChilizSpicyFlag.Name):
 		genesis = core.DefaultChilizSpicyGenesisBlock()
-	case ctx.GlobalBool(DeveloperFlag.Name):
+	case ctx.GlobalIsSet(NetworkFlag.Name):
+		genesis = MakeNetwork(ctx).Genesis
+	case ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(DeveloperParliaFlag.Name):
 		Fatalf("Developer chains are ephemeral")
 	}
 	return genesis
 }
 
+// MakeNetwork loads the named network selected on the command line.
+func MakeNetwork(ctx *cli.Context) *config.Network {
+	network, err := config.LoadNetwork(ctx.GlobalString(NetworkFlag.Name), ctx.GlobalString(NetworkConfigFlag.Name))
+	if err != nil {
+		Fatalf("Failed to load the network: %v", err)
+	}
+	return network
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
@@ -0,0 +1,391 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// historyChecksums is the file listing the sha256 checksums of the archives
+// of a history export, one "<checksum> <filename>" line per archive.
+const historyChecksums = "checksums.txt"
+
+// HistoryNetwork returns the network name used in the filenames of the era1
+// archives of the given chain.
+func HistoryNetwork(config *params.ChainConfig) string {
+	if config.ChainID == nil {
+		return "unknown"
+	}
+	switch config.ChainID.Uint64() {
+	case 1:
+		return "mainnet"
+	case 56:
+		return "bsc"
+	case 97:
+		return "chapel"
+	default:
+		return fmt.Sprintf("chain%d", config.ChainID.Uint64())
+	}
+}
+
+// ExportHistory exports the blocks in the [first, last] range along with their
+// receipts into era1 archives of step blocks each, in the given directory. The
+// first block must be the start of an epoch.
+func ExportHistory(chain *core.BlockChain, dir string, first, last, step uint64) error {
+	log.Info("Exporting blockchain history", "dir", dir)
+	if step == 0 || step > era.MaxEra1Size {
+		return fmt.Errorf("invalid archive size %d", step)
+	}
+	if first%step != 0 {
+		return fmt.Errorf("first block %d is not the start of an epoch", first)
+	}
+	if head := chain.CurrentFastBlock().NumberU64(); head < last {
+		return fmt.Errorf("last block %d beyond head %d", last, head)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	var (
+		start     = time.Now()
+		reported  = time.Now()
+		network   = HistoryNetwork(chain.Config())
+		checksums []string
+	)
+	for batch := first; batch <= last; batch += step {
+		var (
+			idx = int(batch / step)
+			tmp = filepath.Join(dir, fmt.Sprintf("%s-%05d.era1.tmp", network, idx))
+		)
+		root, checksum, err := exportEra(chain, tmp, batch, min(batch+step-1, last))
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		name := era.Filename(network, idx, root)
+		if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+			return err
+		}
+		checksums = append(checksums, fmt.Sprintf("%x %s", checksum, name))
+
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting blocks", "exported", min(batch+step-1, last)-first+1, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, historyChecksums), []byte(strings.Join(checksums, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain history", "dir", dir, "archives", len(checksums), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// exportEra writes the blocks in the [first, last] range into a single archive,
+// returning its accumulator root and sha256 checksum.
+func exportEra(chain *core.BlockChain, path string, first, last uint64) (common.Hash, []byte, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return common.Hash{}, nil, fmt.Errorf("could not create era file: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		hasher  = sha256.New()
+		builder = era.NewBuilder(io.MultiWriter(f, hasher))
+	)
+	for n := first; n <= last; n++ {
+		block := chain.GetBlockByNumber(n)
+		if block == nil {
+			return common.Hash{}, nil, fmt.Errorf("export failed on #%d: not found", n)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if receipts == nil {
+			return common.Hash{}, nil, fmt.Errorf("export failed on #%d: receipts not found", n)
+		}
+		td := chain.GetTd(block.Hash(), n)
+		if td == nil {
+			return common.Hash{}, nil, fmt.Errorf("export failed on #%d: total difficulty not found", n)
+		}
+		if err := builder.Add(block, receipts, td); err != nil {
+			return common.Hash{}, nil, err
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		return common.Hash{}, nil, fmt.Errorf("export failed to finalize: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return common.Hash{}, nil, err
+	}
+	return root, hasher.Sum(nil), nil
+}
+
+// ImportHistory imports the era1 archives of a history export into a chain at
+// genesis, from either a local directory or an HTTP(S) location serving it. The
+// headers are verified by the consensus engine and the bodies and receipts are
+// checked against them, then everything is written into the ancient store.
+func ImportHistory(chain *core.BlockChain, source string) error {
+	if chain.CurrentHeader().Number.Uint64() != 0 || chain.CurrentFastBlock().NumberU64() != 0 {
+		return errors.New("history import only supported when starting from genesis")
+	}
+	var (
+		network = HistoryNetwork(chain.Config())
+		remote  = strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+		dir     = source
+	)
+	checksums, err := readHistoryChecksums(source, remote)
+	if err != nil {
+		return err
+	}
+	if remote {
+		if dir, err = ioutil.TempDir("", "era1-"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+	var entries []string
+	if remote {
+		for _, name := range checksums.names {
+			if strings.HasPrefix(name, network+"-") {
+				entries = append(entries, name)
+			}
+		}
+	} else if entries, err = era.ReadDir(dir, network); err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no %s era1 archives found in %s", network, source)
+	}
+	start := time.Now()
+	for i, name := range entries {
+		path := filepath.Join(dir, name)
+		if remote {
+			log.Info("Downloading history archive", "name", name)
+			if err := downloadFile(strings.TrimSuffix(source, "/")+"/"+name, path); err != nil {
+				return err
+			}
+		}
+		sum, ok := checksums.sums[name]
+		if !ok && len(checksums.sums) > 0 {
+			return fmt.Errorf("no checksum for %s", name)
+		}
+		if err := verifyChecksum(path, sum); err != nil {
+			return err
+		}
+		if err := importEra(chain, path); err != nil {
+			return fmt.Errorf("error importing %s: %w", name, err)
+		}
+		if remote {
+			os.Remove(path)
+		}
+		log.Info("Imported history archive", "name", name, "archive", i+1, "total", len(entries), "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return nil
+}
+
+// importEra verifies a single archive and imports its blocks and receipts.
+func importEra(chain *core.BlockChain, path string) error {
+	e, err := era.Open(path)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	if err := e.Verify(); err != nil {
+		return err
+	}
+	var (
+		blocks   = make(types.Blocks, 0, e.Count())
+		receipts = make([]types.Receipts, 0, e.Count())
+		tds      = make([]*big.Int, 0, e.Count())
+		headers  = make([]*types.Header, 0, e.Count())
+	)
+	it := era.NewIterator(e)
+	for it.Next() {
+		block := it.Block()
+		if block.NumberU64() == 0 {
+			// The genesis is already present, just make sure it's the same
+			if block.Hash() != chain.Genesis().Hash() {
+				return fmt.Errorf("genesis mismatch: have %x, want %x", block.Hash(), chain.Genesis().Hash())
+			}
+			continue
+		}
+		if err := verifyHistoryBlock(block, it.Receipts()); err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+		receipts = append(receipts, it.Receipts())
+		tds = append(tds, it.TotalDifficulty())
+		headers = append(headers, block.Header())
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	if _, err := chain.InsertHeaderChain(headers, 1); err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		if td := chain.GetTd(block.Hash(), block.NumberU64()); td == nil || td.Cmp(tds[i]) != 0 {
+			return fmt.Errorf("total difficulty mismatch for block #%d: have %v, want %v", block.NumberU64(), td, tds[i])
+		}
+	}
+	if _, err := chain.InsertReceiptChain(blocks, receipts, math.MaxUint64); err != nil {
+		return err
+	}
+	return nil
+}
+
+// verifyHistoryBlock checks the body and the receipts of a block against the
+// roots in its header.
+func verifyHistoryBlock(block *types.Block, receipts types.Receipts) error {
+	if hash := types.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle root mismatch for block #%d: have %x, want %x", block.NumberU64(), hash, block.UncleHash())
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("transaction root mismatch for block #%d: have %x, want %x", block.NumberU64(), hash, block.TxHash())
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return fmt.Errorf("receipt root mismatch for block #%d: have %x, want %x", block.NumberU64(), hash, block.ReceiptHash())
+	}
+	return nil
+}
+
+// historyChecksumList is the parsed content of a checksum file.
+type historyChecksumList struct {
+	names []string          // archive names in file order
+	sums  map[string][]byte // checksums by archive name
+}
+
+// readHistoryChecksums reads the checksum file of a history export. It is
+// mandatory for remote sources, but optional for local directories.
+func readHistoryChecksums(source string, remote bool) (*historyChecksumList, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if remote {
+		var res *http.Response
+		if res, err = http.Get(strings.TrimSuffix(source, "/") + "/" + historyChecksums); err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, fmt.Errorf("failed to fetch %s: %s", historyChecksums, res.Status)
+		}
+		r = res.Body
+	} else if r, err = os.Open(filepath.Join(source, historyChecksums)); os.IsNotExist(err) {
+		log.Warn("No checksum file found, skipping archive verification", "dir", source)
+		return &historyChecksumList{sums: make(map[string][]byte)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	list := &historyChecksumList{sums: make(map[string][]byte)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed checksum line %q", scanner.Text())
+		}
+		sum, err := hex.DecodeString(fields[0])
+		if err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("malformed checksum for %s", fields[1])
+		}
+		list.names = append(list.names, fields[1])
+		list.sums[fields[1]] = sum
+	}
+	return list, scanner.Err()
+}
+
+// verifyChecksum compares the sha256 checksum of a file with the expected one,
+// if any.
+func verifyChecksum(path string, want []byte) error {
+	if want == nil {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if have := hasher.Sum(nil); !bytes.Equal(have, want) {
+		return fmt.Errorf("checksum mismatch for %s: have %x, want %x", filepath.Base(path), have, want)
+	}
+	return nil
+}
+
+// downloadFile fetches the given URL into a local file.
+func downloadFile(url, path string) error {
+	res, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, res.Status)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a chain exported into era1 archives can be imported back into an
+// empty node, both from a local directory and over HTTP.
+func TestHistoryExportImport(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{addr: {Balance: big.NewInt(1e18)}}}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(params.TestChainConfig)
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 20, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), addr, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "history-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ExportHistory(chain, dir, 1, 20, 8); err == nil {
+		t.Fatal("export not starting at an epoch boundary succeeded")
+	}
+	if err := ExportHistory(chain, dir, 0, 20, 8); err != nil {
+		t.Fatalf("failed to export history: %v", err)
+	}
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+
+	for _, source := range []string{dir, srv.URL} {
+		ancients, err := ioutil.TempDir("", "history-import")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(ancients)
+
+		db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), ancients, "", false, false, false)
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		gspec.MustCommit(db)
+		imported, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+
+		if err := ImportHistory(imported, source); err != nil {
+			t.Fatalf("failed to import history from %s: %v", source, err)
+		}
+		if head := imported.CurrentFastBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+			t.Fatalf("head mismatch: have #%d, want #%d", head.NumberU64(), len(blocks))
+		}
+		for _, block := range blocks {
+			if receipts := imported.GetReceiptsByHash(block.Hash()); len(receipts) != len(block.Transactions()) {
+				t.Fatalf("block #%d: receipt count mismatch: have %d, want %d", block.NumberU64(), len(receipts), len(block.Transactions()))
+			}
+		}
+		if err := ImportHistory(imported, source); err == nil {
+			t.Fatal("import into a non-empty chain succeeded")
+		}
+		imported.Stop()
+		db.Close()
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+
+	totalRewardShares = 10000 // System reward shares are expressed in basis points
+)
+
+// ctorSlot is the storage slot of the calldata a system contract initializes
+// itself with on its first call.
+var ctorSlot = common.BigToHash(common.Big1)
+
+// GenesisSpec describes a new parlia network to generate the genesis of.
+type GenesisSpec struct {
+	ChainID    uint64                                   `json:"chainId"`
+	Period     uint64                                   `json:"period"`    // Number of seconds between blocks, 0 to seal on transaction arrival
+	Epoch      uint64                                   `json:"epoch"`     // Number of blocks between validator set updates
+	GasLimit   math.HexOrDecimal64                      `json:"gasLimit"`  // Gas limit of the genesis block
+	Timestamp  math.HexOrDecimal64                      `json:"timestamp"` // Timestamp of the genesis block
+	Validators []*GenesisValidator                      `json:"validators"`
+	Balances   map[common.Address]*math.HexOrDecimal256 `json:"balances"` // Initial balances of the user accounts
+	Contracts  SystemContractParams                     `json:"contracts"`
+}
+
+// GenesisValidator is a validator of the genesis validator set.
+type GenesisValidator struct {
+	Address common.Address        `json:"address"`
+	VoteKey *types.BLSPublicKey   `json:"voteKey,omitempty"` // BLS key the validator signs votes with
+	Stake   *math.HexOrDecimal256 `json:"stake"`             // Initial stake, held by the staking contract
+}
+
+// RewardShare is the share of the system rewards paid to an account.
+type RewardShare struct {
+	Account common.Address `json:"account"`
+	Share   uint16         `json:"share"` // Basis points, the shares of all accounts adding up to 10000
+}
+
+// SystemContractParams are the initialization parameters of the system contracts.
+// Zero values are replaced by defaults.
+type SystemContractParams struct {
+	CommissionRate       uint16                `json:"commissionRate"`       // Commission of the genesis validators in basis points
+	ActiveValidators     uint32                `json:"activeValidators"`     // Number of validators elected per epoch
+	MisdemeanorThreshold uint32                `json:"misdemeanorThreshold"` // Missed blocks before the validator rewards are slashed
+	FelonyThreshold      uint32                `json:"felonyThreshold"`      // Missed blocks before the validator is jailed
+	JailEpochs           uint32                `json:"jailEpochs"`           // Number of epochs a validator stays in jail
+	UndelegatePeriod     uint32                `json:"undelegatePeriod"`     // Number of epochs before undelegated stakes can be claimed
+	MinValidatorStake    *math.HexOrDecimal256 `json:"minValidatorStake"`    // Minimum stake of a validator
+	MinStake             *math.HexOrDecimal256 `json:"minStake"`             // Minimum amount of a delegation
+	VotingPeriod         uint64                `json:"votingPeriod"`         // Number of blocks governance proposals are open for voting
+	Rewards              []*RewardShare        `json:"rewards"`              // Distribution of the system rewards
+	Deployers            []common.Address      `json:"deployers"`            // Accounts allowed to deploy contracts
+}
+
+// defaultContractParams are the system contract parameters of the bundled test
+// networks.
+var defaultContractParams = SystemContractParams{
+	MisdemeanorThreshold: 400,
+	FelonyThreshold:      800,
+	JailEpochs:           4,
+	UndelegatePeriod:     1,
+	MinValidatorStake:    (*math.HexOrDecimal256)(new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))),
+	MinStake:             (*math.HexOrDecimal256)(big.NewInt(params.Ether)),
+	VotingPeriod:         1200,
+}
+
+// NewGenesis generates the genesis of a new parlia network. The bytecode of the
+// system contracts and the chain config are taken from the template genesis, all
+// of its forks being activated at genesis.
+func NewGenesis(spec *GenesisSpec, template *core.Genesis) (*core.Genesis, error) {
+	contracts := spec.Contracts
+	if contracts.ActiveValidators == 0 {
+		contracts.ActiveValidators = uint32(len(spec.Validators))
+	}
+	if contracts.MisdemeanorThreshold == 0 {
+		contracts.MisdemeanorThreshold = defaultContractParams.MisdemeanorThreshold
+	}
+	if contracts.FelonyThreshold == 0 {
+		contracts.FelonyThreshold = defaultContractParams.FelonyThreshold
+	}
+	if contracts.JailEpochs == 0 {
+		contracts.JailEpochs = defaultContractParams.JailEpochs
+	}
+	if contracts.UndelegatePeriod == 0 {
+		contracts.UndelegatePeriod = defaultContractParams.UndelegatePeriod
+	}
+	if contracts.MinValidatorStake == nil {
+		contracts.MinValidatorStake = defaultContractParams.MinValidatorStake
+	}
+	if contracts.MinStake == nil {
+		contracts.MinStake = defaultContractParams.MinStake
+	}
+	if contracts.VotingPeriod == 0 {
+		contracts.VotingPeriod = defaultContractParams.VotingPeriod
+	}
+	if err := validateSpec(spec, &contracts); err != nil {
+		return nil, err
+	}
+	config, err := genesisConfig(spec, template)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble the initial validator set into the extra-data
+	extra := make([]byte, extraVanity, extraVanity+len(spec.Validators)*common.AddressLength+extraSeal)
+	for _, validator := range spec.Validators {
+		extra = append(extra, validator.Address.Bytes()...)
+	}
+	extra = append(extra, make([]byte, extraSeal)...)
+
+	// Initialize the system contracts with the given parameters
+	var (
+		validators = make([]common.Address, len(spec.Validators))
+		stakes     = make([]*big.Int, len(spec.Validators))
+		staked     = new(big.Int)
+		accounts   = make([]common.Address, len(contracts.Rewards))
+		shares     = make([]uint16, len(contracts.Rewards))
+	)
+	for i, validator := range spec.Validators {
+		validators[i], stakes[i] = validator.Address, (*big.Int)(validator.Stake)
+		staked.Add(staked, stakes[i])
+	}
+	for i, reward := range contracts.Rewards {
+		accounts[i], shares[i] = reward.Account, reward.Share
+	}
+	ctors := make(map[common.Address][]byte)
+	for _, ctor := range []struct {
+		contract  string
+		signature string
+		args      []interface{}
+	}{
+		{systemcontract.ValidatorContract, "ctor(address[],uint256[],uint16)", []interface{}{validators, stakes, contracts.CommissionRate}},
+		{systemcontract.SystemRewardContract, "ctor(address[],uint16[])", []interface{}{accounts, shares}},
+		{systemcontract.GovernanceContract, "ctor(uint256)", []interface{}{new(big.Int).SetUint64(contracts.VotingPeriod)}},
+		{systemcontract.ChainConfigContract, "ctor(uint32,uint32,uint32,uint32,uint32,uint32,uint256,uint256)", []interface{}{
+			contracts.ActiveValidators, uint32(spec.Epoch), contracts.MisdemeanorThreshold, contracts.FelonyThreshold,
+			contracts.JailEpochs, contracts.UndelegatePeriod, (*big.Int)(contracts.MinValidatorStake), (*big.Int)(contracts.MinStake),
+		}},
+		{systemcontract.DeployerProxyContract, "ctor(address[])", []interface{}{contracts.Deployers}},
+	} {
+		calldata, err := packCtor(ctor.signature, ctor.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack the initialization of %s: %v", ctor.contract, err)
+		}
+		ctors[common.HexToAddress(ctor.contract)] = calldata
+	}
+	alloc := make(core.GenesisAlloc)
+	for addr, account := range template.Alloc {
+		if len(account.Code) == 0 || !systemcontract.IsSystemContract(addr) {
+			continue
+		}
+		contract := core.GenesisAccount{Code: common.CopyBytes(account.Code), Balance: new(big.Int)}
+		if calldata, ok := ctors[addr]; ok {
+			contract.Storage = ctorStorage(calldata)
+		} else {
+			contract.Storage = make(map[common.Hash]common.Hash, len(account.Storage))
+			for key, value := range account.Storage {
+				contract.Storage[key] = value
+			}
+		}
+		alloc[addr] = contract
+	}
+	for addr := range ctors {
+		if _, ok := alloc[addr]; !ok {
+			return nil, fmt.Errorf("template genesis lacks the bytecode of system contract %v", addr)
+		}
+	}
+	alloc[common.HexToAddress(systemcontract.ValidatorContract)].Balance.Set(staked)
+
+	for addr, balance := range spec.Balances {
+		alloc[addr] = core.GenesisAccount{Balance: new(big.Int).Set((*big.Int)(balance))}
+	}
+	return &core.Genesis{
+		Config:     config,
+		Timestamp:  uint64(spec.Timestamp),
+		ExtraData:  extra,
+		GasLimit:   uint64(spec.GasLimit),
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}, nil
+}
+
+// DeveloperGenesis returns the genesis of the 'geth --dev.parlia' network, sealed
+// by the faucet account as its single validator.
+func DeveloperGenesis(period uint64, faucet common.Address) *core.Genesis {
+	spec := &GenesisSpec{
+		ChainID:    1337,
+		Period:     period,
+		Epoch:      200,
+		GasLimit:   11500000,
+		Validators: []*GenesisValidator{{Address: faucet, Stake: defaultContractParams.MinValidatorStake}},
+		Balances: map[common.Address]*math.HexOrDecimal256{
+			faucet: (*math.HexOrDecimal256)(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(9))),
+		},
+		Contracts: SystemContractParams{
+			Rewards:   []*RewardShare{{Account: faucet, Share: totalRewardShares}},
+			Deployers: []common.Address{faucet},
+		},
+	}
+	genesis, err := NewGenesis(spec, SpicyGenesisConfig)
+	if err != nil {
+		panic(err)
+	}
+	return genesis
+}
+
+// validateSpec checks the consistency of the network description.
+func validateSpec(spec *GenesisSpec, contracts *SystemContractParams) error {
+	if spec.ChainID == 0 {
+		return errors.New("chain id missing")
+	}
+	if spec.Epoch == 0 || spec.Epoch > math.MaxUint32 {
+		return fmt.Errorf("invalid epoch length %d", spec.Epoch)
+	}
+	if uint64(spec.GasLimit) < params.MinGasLimit {
+		return fmt.Errorf("gas limit %d below the minimum %d", spec.GasLimit, params.MinGasLimit)
+	}
+	if len(spec.Validators) == 0 {
+		return errors.New("no genesis validators")
+	}
+	var (
+		addresses = make(map[common.Address]bool)
+		voteKeys  = make(map[types.BLSPublicKey]bool)
+	)
+	for _, validator := range spec.Validators {
+		if validator.Address == (common.Address{}) {
+			return errors.New("validator without address")
+		}
+		if addresses[validator.Address] {
+			return fmt.Errorf("duplicate validator %v", validator.Address)
+		}
+		addresses[validator.Address] = true
+
+		if validator.VoteKey != nil {
+			if voteKeys[*validator.VoteKey] {
+				return fmt.Errorf("duplicate vote key of validator %v", validator.Address)
+			}
+			voteKeys[*validator.VoteKey] = true
+		}
+		if validator.Stake == nil || (*big.Int)(validator.Stake).Cmp((*big.Int)(contracts.MinValidatorStake)) < 0 {
+			return fmt.Errorf("stake of validator %v below the minimum %v", validator.Address, (*big.Int)(contracts.MinValidatorStake))
+		}
+	}
+	if len(voteKeys) != 0 && len(voteKeys) != len(spec.Validators) {
+		return errors.New("vote keys missing for some validators")
+	}
+	// Every active validator must get an in-turn block each epoch
+	if uint64(contracts.ActiveValidators) > spec.Epoch {
+		return fmt.Errorf("epoch length %d shorter than the %d active validators", spec.Epoch, contracts.ActiveValidators)
+	}
+	if contracts.MisdemeanorThreshold >= contracts.FelonyThreshold {
+		return fmt.Errorf("misdemeanor threshold %d not below the felony threshold %d", contracts.MisdemeanorThreshold, contracts.FelonyThreshold)
+	}
+	if len(contracts.Rewards) == 0 {
+		return errors.New("no system reward accounts")
+	}
+	total := 0
+	for _, reward := range contracts.Rewards {
+		total += int(reward.Share)
+	}
+	if total != totalRewardShares {
+		return fmt.Errorf("system reward shares add up to %d, want %d", total, totalRewardShares)
+	}
+	for addr := range spec.Balances {
+		if systemcontract.IsSystemContract(addr) {
+			return fmt.Errorf("initial balance of system contract %v", addr)
+		}
+	}
+	return nil
+}
+
+// genesisConfig derives the chain config of the new network from the template,
+// activating all of its forks at genesis.
+func genesisConfig(spec *GenesisSpec, template *core.Genesis) (*params.ChainConfig, error) {
+	if template.Config == nil || template.Config.Parlia == nil {
+		return nil, errors.New("template genesis isn't a parlia network")
+	}
+	blob, err := json.Marshal(template.Config)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	forks := make(map[string]*big.Int)
+	for name, value := range fields {
+		if strings.HasSuffix(name, "Block") && string(value) != "null" {
+			forks[name] = new(big.Int)
+		}
+	}
+	config, err := core.SetForks(template.Config, forks)
+	if err != nil {
+		return nil, err
+	}
+	config.ChainID = new(big.Int).SetUint64(spec.ChainID)
+	parlia := *template.Config.Parlia
+	parlia.Period, parlia.Epoch = spec.Period, spec.Epoch
+	config.Parlia = &parlia
+	return config, config.CheckConfigForkOrder()
+}
+
+// packCtor encodes the call of the initializer with the given signature.
+func packCtor(signature string, args ...interface{}) ([]byte, error) {
+	names := strings.Split(strings.TrimSuffix(signature[strings.Index(signature, "(")+1:], ")"), ",")
+	arguments := make(abi.Arguments, 0, len(names))
+	for _, name := range names {
+		typ, err := abi.NewType(name, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, abi.Argument{Type: typ})
+	}
+	packed, err := arguments.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(crypto.Keccak256([]byte(signature))[:4], packed...), nil
+}
+
+// ctorStorage lays out the initializer calldata as the bytes storage variable
+// the system contracts read it from.
+func ctorStorage(calldata []byte) map[common.Hash]common.Hash {
+	storage := make(map[common.Hash]common.Hash)
+	if len(calldata) < common.HashLength {
+		// Short byte arrays are stored along with their doubled length
+		var value common.Hash
+		copy(value[:], calldata)
+		value[common.HashLength-1] = byte(2 * len(calldata))
+		storage[ctorSlot] = value
+		return storage
+	}
+	storage[ctorSlot] = common.BigToHash(big.NewInt(int64(2*len(calldata) + 1)))
+
+	start := new(big.Int).SetBytes(crypto.Keccak256(ctorSlot[:]))
+	for i := 0; i < len(calldata); i += common.HashLength {
+		var value common.Hash
+		copy(value[:], calldata[i:])
+		if value != (common.Hash{}) {
+			storage[common.BigToHash(new(big.Int).Add(start, big.NewInt(int64(i/common.HashLength))))] = value
+		}
+	}
+	return storage
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func ether(amount int64) *math.HexOrDecimal256 {
+	return (*math.HexOrDecimal256)(new(big.Int).Mul(big.NewInt(amount), big.NewInt(params.Ether)))
+}
+
+// spicySpec returns the description of the bundled spicy test network.
+func spicySpec() *GenesisSpec {
+	spec := &GenesisSpec{
+		ChainID:   88882,
+		Period:    3,
+		Epoch:     7200,
+		GasLimit:  math.HexOrDecimal64(SpicyGenesisConfig.GasLimit),
+		Timestamp: math.HexOrDecimal64(SpicyGenesisConfig.Timestamp),
+		Contracts: SystemContractParams{
+			ActiveValidators: 5,
+			Rewards:          []*RewardShare{{Account: common.HexToAddress("0x060ea461cf7e78a38400de9255687beb9b2c7298"), Share: 10000}},
+			Deployers:        []common.Address{common.HexToAddress("0x02880217b082cc24d371eb5bad0827d208bcbc6d")},
+		},
+	}
+	extra := SpicyGenesisConfig.ExtraData
+	for i := extraVanity; i < len(extra)-extraSeal; i += common.AddressLength {
+		stake := ether(1000)
+		if len(spec.Validators) == 0 {
+			stake = ether(100000)
+		}
+		spec.Validators = append(spec.Validators, &GenesisValidator{Address: common.BytesToAddress(extra[i : i+common.AddressLength]), Stake: stake})
+	}
+	return spec
+}
+
+func TestNewGenesis(t *testing.T) {
+	genesis, err := NewGenesis(spicySpec(), SpicyGenesisConfig)
+	if err != nil {
+		t.Fatalf("failed to generate genesis: %v", err)
+	}
+	if !bytes.Equal(genesis.ExtraData, SpicyGenesisConfig.ExtraData) {
+		t.Errorf("extra-data mismatch: have %x, want %x", genesis.ExtraData, SpicyGenesisConfig.ExtraData)
+	}
+	for _, contract := range []string{
+		systemcontract.ValidatorContract, systemcontract.SlashContract, systemcontract.SystemRewardContract,
+		systemcontract.StakingPoolContract, systemcontract.GovernanceContract, systemcontract.ChainConfigContract,
+		systemcontract.RuntimeUpgradeContract, systemcontract.DeployerProxyContract,
+	} {
+		addr := common.HexToAddress(contract)
+		have, want := genesis.Alloc[addr], SpicyGenesisConfig.Alloc[addr]
+		if !bytes.Equal(have.Code, want.Code) {
+			t.Errorf("contract %v: code mismatch", addr)
+		}
+		if !reflect.DeepEqual(have.Storage, want.Storage) {
+			t.Errorf("contract %v: storage mismatch: have %v, want %v", addr, have.Storage, want.Storage)
+		}
+		if have.Balance.Cmp(want.Balance) != 0 {
+			t.Errorf("contract %v: balance mismatch: have %v, want %v", addr, have.Balance, want.Balance)
+		}
+	}
+	// All forks of the template are active at genesis
+	if block := genesis.Config.DeployerFactoryBlock; block == nil || block.Sign() != 0 {
+		t.Errorf("template fork not activated at genesis: %v", block)
+	}
+	if genesis.Config.ChainID.Uint64() != 88882 || genesis.Config.Parlia.Epoch != 7200 {
+		t.Errorf("chain config mismatch: chain %v, epoch %d", genesis.Config.ChainID, genesis.Config.Parlia.Epoch)
+	}
+	if _, err := genesis.Commit(rawdb.NewMemoryDatabase()); err != nil {
+		t.Errorf("failed to commit genesis: %v", err)
+	}
+}
+
+func TestNewGenesisValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(spec *GenesisSpec)
+	}{
+		{"no chain id", func(spec *GenesisSpec) { spec.ChainID = 0 }},
+		{"no validators", func(spec *GenesisSpec) { spec.Validators = nil }},
+		{"duplicate validator", func(spec *GenesisSpec) { spec.Validators[1].Address = spec.Validators[0].Address }},
+		{"low stake", func(spec *GenesisSpec) { spec.Validators[1].Stake = ether(1) }},
+		{"short epoch", func(spec *GenesisSpec) { spec.Epoch = 4 }},
+		{"thresholds", func(spec *GenesisSpec) { spec.Contracts.MisdemeanorThreshold = 900 }},
+		{"reward shares", func(spec *GenesisSpec) { spec.Contracts.Rewards[0].Share = 5000 }},
+		{"system balance", func(spec *GenesisSpec) {
+			spec.Balances = map[common.Address]*math.HexOrDecimal256{common.HexToAddress(systemcontract.ValidatorContract): ether(1)}
+		}},
+	}
+	for _, tt := range tests {
+		spec := spicySpec()
+		tt.modify(spec)
+		if _, err := NewGenesis(spec, SpicyGenesisConfig); err == nil {
+			t.Errorf("%s: invalid spec accepted", tt.name)
+		}
+	}
+}
+
+func TestDeveloperGenesis(t *testing.T) {
+	faucet := common.HexToAddress("0x36428aca245eb8ff5b3c18d3ba64aab71dbe581f")
+	genesis := DeveloperGenesis(0, faucet)
+	if genesis.Config.Parlia.Period != 0 {
+		t.Errorf("period mismatch: have %d, want 0", genesis.Config.Parlia.Period)
+	}
+	if !bytes.Equal(genesis.ExtraData[extraVanity:len(genesis.ExtraData)-extraSeal], faucet.Bytes()) {
+		t.Errorf("faucet not the single validator: %x", genesis.ExtraData)
+	}
+	if _, err := genesis.Commit(rawdb.NewMemoryDatabase()); err != nil {
+		t.Errorf("failed to commit genesis: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Network is a named network the node can join.
+type Network struct {
+	NetworkID uint64              `json:"networkId"`
+	Genesis   *core.Genesis       `json:"genesis"`
+	Bootnodes []string            `json:"bootnodes,omitempty"`
+	Forks     map[string]*big.Int `json:"forks,omitempty"` // Fork schedule, overriding the activation blocks of the genesis config
+}
+
+// bundledNetworks are the networks built into the binary.
+var bundledNetworks = map[string]func() *Network{
+	"mainnet": func() *Network {
+		return &Network{NetworkID: 1, Genesis: core.DefaultGenesisBlock(), Bootnodes: params.MainnetBootnodes}
+	},
+	"ropsten": func() *Network {
+		return &Network{NetworkID: 3, Genesis: core.DefaultRopstenGenesisBlock(), Bootnodes: params.RopstenBootnodes}
+	},
+	"rinkeby": func() *Network {
+		return &Network{NetworkID: 4, Genesis: core.DefaultRinkebyGenesisBlock(), Bootnodes: params.RinkebyBootnodes}
+	},
+	"goerli": func() *Network {
+		return &Network{NetworkID: 5, Genesis: core.DefaultGoerliGenesisBlock(), Bootnodes: params.GoerliBootnodes}
+	},
+	"chiliz": func() *Network {
+		return &Network{NetworkID: 88888, Genesis: ChilizMainnetGenesisConfig, Bootnodes: params.ChilizMainnetBootnodes}
+	},
+	"scoville": func() *Network {
+		return &Network{NetworkID: 88880, Genesis: ScovilleGenesisConfig, Bootnodes: params.ChilizScovilleBootnodes}
+	},
+	"spicy": func() *Network {
+		return &Network{NetworkID: 88882, Genesis: SpicyGenesisConfig, Bootnodes: params.ChilizSpicyBootnodes}
+	},
+}
+
+// BundledNetworks returns the names of the networks built into the binary.
+func BundledNetworks() []string {
+	names := make([]string, 0, len(bundledNetworks))
+	for name := range bundledNetworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadNetwork returns the network with the given name. If a directory is given,
+// the network is first looked up in there as <name>.json, then among the bundled
+// networks.
+func LoadNetwork(name string, dir string) (*Network, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid network name %q", name)
+	}
+	if dir != "" {
+		blob, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+		switch {
+		case err == nil:
+			return decodeNetwork(name, blob)
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	if bundled, ok := bundledNetworks[name]; ok {
+		return bundled(), nil
+	}
+	return nil, fmt.Errorf("unknown network %q", name)
+}
+
+func decodeNetwork(name string, blob []byte) (*Network, error) {
+	network := new(Network)
+	if err := json.Unmarshal(blob, network); err != nil {
+		return nil, fmt.Errorf("invalid network %q: %v", name, err)
+	}
+	if network.Genesis == nil || network.Genesis.Config == nil {
+		return nil, fmt.Errorf("network %q without genesis config", name)
+	}
+	if network.NetworkID == 0 && network.Genesis.Config.ChainID != nil {
+		network.NetworkID = network.Genesis.Config.ChainID.Uint64()
+	}
+	// The genesis hash doesn't cover the config, so the schedule can be changed
+	config, err := core.SetForks(network.Genesis.Config, network.Forks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fork schedule of network %q: %v", name, err)
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		return nil, fmt.Errorf("invalid fork schedule of network %q: %v", name, err)
+	}
+	network.Genesis.Config = config
+	return network, nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadNetwork(t *testing.T) {
+	dir := t.TempDir()
+	staging := `{
+		"networkId": 1234,
+		"genesis": {"config": {"chainId": 77, "homesteadBlock": 0, "eip150Block": 0, "eip155Block": 0, "eip158Block": 0, "byzantiumBlock": 0}, "gasLimit": "0x47b760", "difficulty": "0x1", "alloc": {}},
+		"bootnodes": ["enode://a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c@52.16.188.185:30303"],
+		"forks": {"constantinopleBlock": 100, "petersburgBlock": 100}
+	}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "staging.json"), []byte(staging), 0600); err != nil {
+		t.Fatal(err)
+	}
+	network, err := LoadNetwork("staging", dir)
+	if err != nil {
+		t.Fatalf("failed to load network: %v", err)
+	}
+	if network.NetworkID != 1234 || len(network.Bootnodes) != 1 {
+		t.Errorf("network mismatch: id %d, %d bootnodes", network.NetworkID, len(network.Bootnodes))
+	}
+	if block := network.Genesis.Config.ConstantinopleBlock; block == nil || block.Uint64() != 100 {
+		t.Errorf("fork schedule not applied: constantinople at %v", block)
+	}
+	// Bundled networks are found without a directory
+	if network, err := LoadNetwork("spicy", ""); err != nil || network.NetworkID != 88882 {
+		t.Errorf("failed to load bundled network: %v", err)
+	}
+	// Networks of the directory take precedence over the bundled ones
+	if err := ioutil.WriteFile(filepath.Join(dir, "spicy.json"), []byte(staging), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if network, err := LoadNetwork("spicy", dir); err != nil || network.NetworkID != 1234 {
+		t.Errorf("bundled network not overridden: %v", err)
+	}
+	for _, name := range []string{"unknown", "", "..", "../staging"} {
+		if _, err := LoadNetwork(name, dir); err == nil {
+			t.Errorf("network %q loaded", name)
+		}
+	}
+	// Fork schedules must be valid
+	invalid := `{"genesis": {"config": {"chainId": 77}}, "forks": {"fooBlock": 1}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "invalid.json"), []byte(invalid), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadNetwork("invalid", dir); err == nil {
+		t.Errorf("network with invalid fork schedule loaded")
+	}
+}
@@ -147,4 +147,5 @@ type PoSA interface {
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
 
 	BlockRewards(blockNumber *big.Int) *big.Int
+	Validators(chain ChainHeaderReader, header *types.Header) ([]common.Address, error)
 }
@@ -291,6 +291,10 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainHeaderReader, header, pa
 	if uint64(diff) >= limit || header.GasLimit < params.MinGasLimit {
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
+	// Verify the base fee once the fork activated it
+	if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
 	// Verify that the block number is parent's +1
 	if diff := new(big.Int).Sub(header.Number, parent.Number); diff.Cmp(big.NewInt(1)) != 0 {
 		return consensus.ErrInvalidNumber
@@ -605,7 +609,7 @@ func (ethash *Ethash) Delay(_ consensus.ChainReader, _ *types.Header) *time.Dura
 func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewLegacyKeccak256()
 
-	rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -619,7 +623,11 @@ func (ethash *Ethash) SealHash(header *types.Header) (hash common.Hash) {
 		header.GasUsed,
 		header.Time,
 		header.Extra,
-	})
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	rlp.Encode(hasher, enc)
 	hasher.Sum(hash[:0])
 	return hash
 }
@@ -630,6 +638,43 @@ var (
 	big32 = big.NewInt(32)
 )
 
+// blockReward returns the static block reward at the given block.
+func blockReward(config *params.ChainConfig, number *big.Int) *big.Int {
+	// Select the correct block reward based on chain progression
+	reward := FrontierBlockReward
+	if config.IsByzantium(number) {
+		reward = ByzantiumBlockReward
+	}
+	if config.IsConstantinople(number) {
+		reward = ConstantinopleBlockReward
+	}
+	return reward
+}
+
+// Rewards returns the amounts minted by the given block: the reward of its miner,
+// including the rewards for the included uncles, and the total reward of the
+// uncle miners.
+func Rewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) (*big.Int, *big.Int) {
+	minerReward, uncleRewards := new(big.Int), new(big.Int)
+	if config.IsCatalyst(header.Number) {
+		return minerReward, uncleRewards
+	}
+	blockReward := blockReward(config, header.Number)
+	minerReward.Set(blockReward)
+	r := new(big.Int)
+	for _, uncle := range uncles {
+		r.Add(uncle.Number, big8)
+		r.Sub(r, header.Number)
+		r.Mul(r, blockReward)
+		r.Div(r, big8)
+		uncleRewards.Add(uncleRewards, r)
+
+		r.Div(blockReward, big32)
+		minerReward.Add(minerReward, r)
+	}
+	return minerReward, uncleRewards
+}
+
 // AccumulateRewards credits the coinbase of the given block with the mining
 // reward. The total reward consists of the static block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
@@ -638,14 +683,8 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 	if config.IsCatalyst(header.Number) {
 		return
 	}
-	// Select the correct block reward based on chain progression
-	blockReward := FrontierBlockReward
-	if config.IsByzantium(header.Number) {
-		blockReward = ByzantiumBlockReward
-	}
-	if config.IsConstantinople(header.Number) {
-		blockReward = ConstantinopleBlockReward
-	}
+	blockReward := blockReward(config, header.Number)
+
 	// Accumulate the rewards for the miner and any included uncles
 	reward := new(big.Int).Set(blockReward)
 	r := new(big.Int)
@@ -0,0 +1,87 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// VerifyBaseFee verifies the base fee of a header against its parent. Headers
+// before the base fee fork mustn't have any.
+func VerifyBaseFee(config *params.ChainConfig, parent, header *types.Header) error {
+	if !config.IsBaseFee(header.Number) {
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %v, want <nil>", header.BaseFee)
+		}
+		return nil
+	}
+	if header.BaseFee == nil {
+		return fmt.Errorf("header is missing baseFee")
+	}
+	if expected := CalcBaseFee(config, parent); header.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("invalid baseFee: have %v, want %v, parentBaseFee %v, parentGasUsed %d",
+			header.BaseFee, expected, parent.BaseFee, parent.GasUsed)
+	}
+	return nil
+}
+
+// CalcBaseFee calculates the base fee of the header following the parent, the
+// initial base fee if the parent precedes the base fee fork.
+//
+// The base fee moves towards the gas target, the gas limit divided by the
+// elasticity multiplier, by at most 1/denominator of itself per block, and never
+// falls below the configured minimum.
+func CalcBaseFee(config *params.ChainConfig, parent *types.Header) *big.Int {
+	if !config.IsBaseFee(parent.Number) || parent.BaseFee == nil {
+		return new(big.Int).SetUint64(config.InitialBaseFee())
+	}
+	var (
+		gasTarget   = parent.GasLimit / config.ElasticityMultiplier()
+		denominator = new(big.Int).SetUint64(config.BaseFeeChangeDenominator())
+		baseFee     *big.Int
+	)
+	switch {
+	case parent.GasUsed == gasTarget || gasTarget == 0:
+		baseFee = new(big.Int).Set(parent.BaseFee)
+
+	case parent.GasUsed > gasTarget:
+		// max(1, parentBaseFee * gasUsedDelta / gasTarget / denominator)
+		delta := new(big.Int).SetUint64(parent.GasUsed - gasTarget)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, denominator)
+		baseFee = delta.Add(parent.BaseFee, math.BigMax(delta, common.Big1))
+
+	default:
+		// parentBaseFee * gasUsedDelta / gasTarget / denominator
+		delta := new(big.Int).SetUint64(gasTarget - parent.GasUsed)
+		delta.Mul(delta, parent.BaseFee)
+		delta.Div(delta, new(big.Int).SetUint64(gasTarget))
+		delta.Div(delta, denominator)
+		baseFee = delta.Sub(parent.BaseFee, delta)
+	}
+	if min := new(big.Int).SetUint64(config.MinBaseFee()); baseFee.Cmp(min) < 0 {
+		baseFee = min
+	}
+	return baseFee
+}
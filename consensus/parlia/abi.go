@@ -674,3 +674,47 @@ const slashABI = `
     }
   ]
 `
+
+// evidenceABI is the double sign evidence submission of the slash contract.
+const evidenceABI = `
+[
+  {
+    "inputs": [
+      {
+        "internalType": "bytes",
+        "name": "header1",
+        "type": "bytes"
+      },
+      {
+        "internalType": "bytes",
+        "name": "header2",
+        "type": "bytes"
+      }
+    ],
+    "name": "submitDoubleSignEvidence",
+    "outputs": [],
+    "stateMutability": "nonpayable",
+    "type": "function"
+  }
+]
+`
+
+// maintenanceABI is the maintenance signalling of the validator set contract.
+const maintenanceABI = `
+[
+  {
+    "inputs": [],
+    "name": "enterMaintenance",
+    "outputs": [],
+    "stateMutability": "nonpayable",
+    "type": "function"
+  },
+  {
+    "inputs": [],
+    "name": "exitMaintenance",
+    "outputs": [],
+    "stateMutability": "nonpayable",
+    "type": "function"
+  }
+]
+`
@@ -17,9 +17,15 @@
 package parlia
 
 import (
+	"context"
+	"errors"
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -86,3 +92,280 @@ func (api *API) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error)
 	}
 	return snap.validators(), nil
 }
+
+// TurnInfo is the turn-taking state of the validators for sealing a block.
+type TurnInfo struct {
+	Number          uint64           `json:"number"`          // Block the turn-taking state applies to
+	TurnLength      uint64           `json:"turnLength"`      // Consecutive blocks sealed by the in-turn validator
+	InturnValidator common.Address   `json:"inturnValidator"` // Validator expected to seal the block
+	Validators      []common.Address `json:"validators"`      // Validators authorized to seal the block
+	RecentlySigned  []common.Address `json:"recentlySigned"`  // Validators banned from sealing the block as they signed recently
+}
+
+// GetTurnInfo retrieves the turn-taking state for sealing the block following
+// the specified one.
+func (api *API) GetTurnInfo(number *rpc.BlockNumber) (*TurnInfo, error) {
+	header := api.header(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.parlia.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	next := snap.Number + 1
+	info := &TurnInfo{
+		Number:          next,
+		TurnLength:      snap.turnLength(next),
+		InturnValidator: snap.blockProducer(),
+		Validators:      snap.validators(),
+		RecentlySigned:  []common.Address{},
+	}
+	for _, val := range info.Validators {
+		if snap.signRecently(val, next) {
+			info.RecentlySigned = append(info.RecentlySigned, val)
+		}
+	}
+	return info, nil
+}
+
+// ExportSnapshot retrieves the snapshot of the last checkpoint at or below the
+// specified block, in the format accepted by admin_importParliaSnapshot.
+func (api *API) ExportSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	header := api.header(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	checkpoint := header.Number.Uint64() / checkpointInterval * checkpointInterval
+	if header = api.chain.GetHeaderByNumber(checkpoint); header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.parlia.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// AdminAPI is the RPC API altering the consensus state of the engine, which is
+// only served in the admin namespace.
+type AdminAPI struct {
+	chain  consensus.ChainHeaderReader
+	parlia *Parlia
+}
+
+// ImportParliaSnapshot stores an exported checkpoint snapshot, so the snapshots
+// of the following blocks can be served without replaying the headers preceding
+// it. The snapshot is rebuilt from the validator set announced by the local epoch
+// headers and the recent headers sealed on top of it, and it is only accepted if
+// the validators and recent signers match the rebuilt one, which gets stored.
+func (api *AdminAPI) ImportParliaSnapshot(snap *Snapshot) error {
+	if snap.Number%checkpointInterval != 0 {
+		return fmt.Errorf("snapshot at block %d is not a checkpoint", snap.Number)
+	}
+	header := api.chain.GetHeaderByNumber(snap.Number)
+	if header == nil || header.Hash() != snap.Hash {
+		return errUnknownBlock
+	}
+	local, err := api.parlia.rebuildSnapshot(api.chain, header)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild snapshot: %v", err)
+	}
+	if len(snap.Validators) != len(local.Validators) {
+		return fmt.Errorf("validator set mismatch: have %d validators, want %d", len(snap.Validators), len(local.Validators))
+	}
+	for val := range local.Validators {
+		if _, ok := snap.Validators[val]; !ok {
+			return fmt.Errorf("validator set mismatch: %x missing", val)
+		}
+	}
+	if len(snap.Recents) != len(local.Recents) {
+		return fmt.Errorf("recent validators mismatch: have %d, want %d", len(snap.Recents), len(local.Recents))
+	}
+	for seen, val := range local.Recents {
+		if snap.Recents[seen] != val {
+			return fmt.Errorf("recent validator mismatch at block %d: have %x, want %x", seen, snap.Recents[seen], val)
+		}
+	}
+	if err := local.store(api.parlia.db); err != nil {
+		return err
+	}
+	api.parlia.recentSnaps.Add(local.Hash, local)
+	log.Info("Imported parlia snapshot", "number", local.Number, "hash", local.Hash, "validators", len(local.Validators))
+	return nil
+}
+
+// rebuildSnapshot recreates the snapshot at the given header from the local chain
+// alone. The headers are replayed on top of the validator set in effect before
+// them, far enough back for everything sealed earlier to have aged out of the
+// recent validators and fork hashes.
+func (p *Parlia) rebuildSnapshot(chain consensus.ChainHeaderReader, header *types.Header) (*Snapshot, error) {
+	number := header.Number.Uint64()
+	validators, err := p.epochValidators(chain, number)
+	if err != nil {
+		return nil, err
+	}
+	snap := newSnapshot(p.config, p.signatures, number, header.Hash(), validators, p.ethAPI)
+	depth := snap.minerHistoryCheckLen(number) + 1 + uint64(len(validators))
+	if depth > number {
+		depth = number
+	}
+	headers := make([]*types.Header, depth)
+	for i := len(headers) - 1; i >= 0; i-- {
+		headers[i] = header
+		if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+	}
+	if validators, err = p.epochValidators(chain, header.Number.Uint64()); err != nil {
+		return nil, err
+	}
+	snap = newSnapshot(p.config, p.signatures, header.Number.Uint64(), header.Hash(), validators, p.ethAPI)
+	return snap.apply(headers, chain, nil, p.chainConfig.ChainID)
+}
+
+// epochValidators derives the validator set in effect after the given block from
+// the validators listed in the extra-data of the epoch headers. The set announced
+// by an epoch header takes over once half of the previous set sealed after it.
+func (p *Parlia) epochValidators(chain consensus.ChainHeaderReader, number uint64) ([]common.Address, error) {
+	epoch := number / p.config.Epoch * p.config.Epoch
+	current, err := headerValidators(chain, epoch)
+	if err != nil || epoch == 0 {
+		return current, err
+	}
+	previous, err := headerValidators(chain, epoch-p.config.Epoch)
+	if err != nil {
+		return nil, err
+	}
+	if number-epoch < uint64(len(previous)/2) {
+		return previous, nil
+	}
+	return current, nil
+}
+
+// headerValidators parses the validator set listed in the extra-data of the epoch
+// header with the given number.
+func headerValidators(chain consensus.ChainHeaderReader, number uint64) ([]common.Address, error) {
+	header := chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	validators, err := ParseValidators(header.Extra[extraVanity : len(header.Extra)-extraSeal])
+	if err != nil {
+		return nil, err
+	}
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("epoch header %d without validators", number)
+	}
+	return validators, nil
+}
+
+// maxStatsRange is the maximum number of blocks covered by a single validator
+// statistics query.
+const maxStatsRange = 10000
+
+// ValidatorStats retrieves how many blocks each validator sealed in and out of
+// turn, how many turns it missed and how long its blocks took to reach the local
+// node, between the given blocks (inclusive). The range defaults to the last
+// 1000 blocks. The latency is only known for the recently received blocks.
+func (api *API) ValidatorStats(fromBlock, toBlock *rpc.BlockNumber) (*ProductionStats, error) {
+	to := api.chain.CurrentHeader().Number.Uint64()
+	if toBlock != nil && *toBlock >= 0 {
+		to = uint64(*toBlock)
+	}
+	from := uint64(1)
+	if to > 1000 {
+		from = to - 999
+	}
+	if fromBlock != nil && *fromBlock >= 0 {
+		from = uint64(*fromBlock)
+	}
+	if from == 0 {
+		from = 1 // Genesis isn't sealed
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	if to-from >= maxStatsRange {
+		return nil, fmt.Errorf("block range %d-%d exceeds the limit of %d blocks", from, to, maxStatsRange)
+	}
+	return api.parlia.productionStats(api.chain, from, to)
+}
+
+// EpochStats retrieves how many blocks each validator sealed in and out of turn,
+// how many turns it missed and how long its blocks took to reach the local node
+// over the given epoch. The epoch defaults to the latest complete one.
+func (api *API) EpochStats(epoch *hexutil.Uint64) (*EpochStats, error) {
+	if epoch != nil {
+		return api.parlia.epochStats(api.chain, uint64(*epoch))
+	}
+	latest, ok := api.parlia.latestEpoch(api.chain)
+	if !ok {
+		return nil, errors.New("no complete epoch")
+	}
+	return api.parlia.epochStats(api.chain, latest)
+}
+
+// maxEvidenceResults is the maximum number of double sign evidence entries
+// returned by a single query.
+const maxEvidenceResults = 1024
+
+// GetDoubleSignEvidence retrieves the double signs detected between the given
+// blocks (inclusive), defaulting to the whole chain.
+func (api *API) GetDoubleSignEvidence(fromBlock, toBlock *rpc.BlockNumber) ([]*DoubleSignEvidence, error) {
+	from, to := uint64(0), api.chain.CurrentHeader().Number.Uint64()
+	if fromBlock != nil && *fromBlock >= 0 {
+		from = uint64(*fromBlock)
+	}
+	if toBlock != nil && *toBlock >= 0 {
+		to = uint64(*toBlock)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	evidence, err := loadEvidence(api.parlia.db, from, to, maxEvidenceResults)
+	if err != nil {
+		return nil, err
+	}
+	if evidence == nil {
+		evidence = []*DoubleSignEvidence{}
+	}
+	return evidence, nil
+}
+
+// DoubleSigns creates a subscription that fires with the evidence of every
+// double sign detected.
+func (api *API) DoubleSigns(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan DoubleSignEvent, 16)
+		sub := api.parlia.SubscribeDoubleSignEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev.Evidence)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// header retrieves the requested header, or the current one if none requested.
+func (api *API) header(number *rpc.BlockNumber) *types.Header {
+	if number == nil || *number == rpc.LatestBlockNumber {
+		return api.chain.CurrentHeader()
+	}
+	return api.chain.GetHeaderByNumber(uint64(number.Int64()))
+}
@@ -0,0 +1,205 @@
+package parlia
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newSealedChain creates a chain of the given length sealed by the in-turn
+// validators. The epoch header at switchAt announces a fourth validator on top
+// of the three initial ones.
+func newSealedChain(t *testing.T, config *params.ChainConfig, length, switchAt uint64) (*testHeaderChain, []common.Address) {
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+		keys[validators[i]] = key
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	extra := func(vals []common.Address) []byte {
+		extra := make([]byte, extraVanity, extraVanity+len(vals)*validatorBytesLength+extraSeal)
+		for _, val := range vals {
+			extra = append(extra, val.Bytes()...)
+		}
+		return append(extra, make([]byte, extraSeal)...)
+	}
+	var (
+		announced = validators[:3]
+		active    = validators[:3]
+		chain     = &testHeaderChain{headers: []*types.Header{{Number: big.NewInt(0), Extra: extra(announced)}}}
+	)
+	for number := uint64(1); number <= length; number++ {
+		header := &types.Header{
+			ParentHash: chain.headers[number-1].Hash(),
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Coinbase:   active[number%uint64(len(active))],
+			Extra:      extra(nil),
+		}
+		if number%config.Parlia.Epoch == 0 {
+			if number == switchAt {
+				announced = validators
+			}
+			header.Extra = extra(announced)
+		}
+		sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), keys[header.Coinbase])
+		if err != nil {
+			t.Fatalf("failed to seal block %d: %v", number, err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		chain.headers = append(chain.headers, header)
+
+		// The announced set takes over after half of the current one sealed
+		if number%config.Parlia.Epoch == uint64(len(active)/2) {
+			active = announced
+		}
+	}
+	return chain, validators
+}
+
+func TestImportParliaSnapshot(t *testing.T) {
+	config, parliaConfig := *params.ChapelChainConfig, *params.ChapelChainConfig.Parlia
+	parliaConfig.Epoch = 200
+	parliaConfig.PeriodForks = nil
+	config.Parlia = &parliaConfig
+
+	db := rawdb.NewMemoryDatabase()
+	engine := New(&config, db, nil, common.Hash{})
+	chain, validators := newSealedChain(t, &config, checkpointInterval+2, 1000)
+	api := &AdminAPI{chain: chain, parlia: engine}
+
+	// The snapshot at the checkpoint holds the switched set and the three most
+	// recent signers, as two consecutive turns of four validators overlap in three
+	checkpoint := chain.headers[checkpointInterval]
+	valid := func() *Snapshot {
+		snap := &Snapshot{
+			Number:     checkpointInterval,
+			Hash:       checkpoint.Hash(),
+			Validators: make(map[common.Address]struct{}),
+			Recents:    make(map[uint64]common.Address),
+		}
+		for _, val := range validators {
+			snap.Validators[val] = struct{}{}
+		}
+		for seen := uint64(checkpointInterval - 2); seen <= checkpointInterval; seen++ {
+			snap.Recents[seen] = chain.headers[seen].Coinbase
+		}
+		return snap
+	}
+	tests := []struct {
+		name   string
+		modify func(snap *Snapshot)
+		err    string
+	}{
+		{
+			name: "not a checkpoint",
+			modify: func(snap *Snapshot) {
+				snap.Number, snap.Hash = checkpointInterval+1, chain.headers[checkpointInterval+1].Hash()
+			},
+			err: "not a checkpoint",
+		},
+		{
+			name:   "unknown block",
+			modify: func(snap *Snapshot) { snap.Hash = common.Hash{0xff} },
+			err:    errUnknownBlock.Error(),
+		},
+		{
+			name:   "validator set before the switch",
+			modify: func(snap *Snapshot) { delete(snap.Validators, validators[3]) },
+			err:    "validator set mismatch",
+		},
+		{
+			name: "foreign validator",
+			modify: func(snap *Snapshot) {
+				delete(snap.Validators, validators[3])
+				snap.Validators[common.Address{0xff}] = struct{}{}
+			},
+			err: "validator set mismatch",
+		},
+		{
+			name:   "missing recent validator",
+			modify: func(snap *Snapshot) { delete(snap.Recents, checkpointInterval) },
+			err:    "recent validators mismatch",
+		},
+		{
+			name: "forged recent validator",
+			modify: func(snap *Snapshot) {
+				snap.Recents[checkpointInterval] = chain.headers[checkpointInterval-1].Coinbase
+			},
+			err: "recent validator mismatch at block 1024",
+		},
+	}
+	for _, tt := range tests {
+		snap := valid()
+		tt.modify(snap)
+		if err := api.ImportParliaSnapshot(snap); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error mismatch: have %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if _, err := loadSnapshot(engine.config, engine.signatures, db, checkpoint.Hash(), nil); err == nil {
+		t.Fatalf("rejected snapshot stored")
+	}
+	// A matching snapshot is stored, along with the locally derived fork hashes
+	if err := api.ImportParliaSnapshot(valid()); err != nil {
+		t.Fatalf("failed to import snapshot: %v", err)
+	}
+	stored, err := loadSnapshot(engine.config, engine.signatures, db, checkpoint.Hash(), nil)
+	if err != nil {
+		t.Fatalf("imported snapshot not stored: %v", err)
+	}
+	if stored.Number != checkpointInterval || len(stored.Validators) != len(validators) || len(stored.Recents) != 3 {
+		t.Fatalf("stored snapshot mismatch: have %+v", stored)
+	}
+	if len(stored.RecentForkHashes) == 0 {
+		t.Errorf("stored snapshot without fork hashes")
+	}
+	if _, ok := engine.recentSnaps.Get(checkpoint.Hash()); !ok {
+		t.Errorf("imported snapshot not cached")
+	}
+}
+
+func TestEpochValidators(t *testing.T) {
+	config, parliaConfig := *params.ChapelChainConfig, *params.ChapelChainConfig.Parlia
+	parliaConfig.Epoch = 10
+	parliaConfig.PeriodForks = nil
+	config.Parlia = &parliaConfig
+
+	engine := New(&config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	chain, _ := newSealedChain(t, &config, 25, 10)
+
+	// The set announced at block 10 takes over once one of the three old
+	// validators sealed after it
+	tests := []struct {
+		number uint64
+		want   int
+	}{
+		{0, 3}, {9, 3}, {10, 3}, {11, 4}, {20, 4}, {21, 4},
+	}
+	for _, tt := range tests {
+		have, err := engine.epochValidators(chain, tt.number)
+		if err != nil {
+			t.Fatalf("block %d: failed to derive validators: %v", tt.number, err)
+		}
+		if len(have) != tt.want {
+			t.Errorf("block %d: validator count mismatch: have %d, want %d", tt.number, len(have), tt.want)
+		}
+		snap, err := engine.rebuildSnapshot(chain, chain.headers[tt.number])
+		if err != nil {
+			t.Fatalf("block %d: failed to rebuild snapshot: %v", tt.number, err)
+		}
+		if len(snap.Validators) != tt.want {
+			t.Errorf("block %d: rebuilt validator count mismatch: have %d, want %d", tt.number, len(snap.Validators), tt.want)
+		}
+	}
+}
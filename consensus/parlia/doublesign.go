@@ -0,0 +1,175 @@
+package parlia
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/gopool"
+	"github.com/ethereum/go-ethereum/common/systemcontract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// inMemorySealers is the number of recently verified headers remembered per
+// height and validator to detect double signs.
+const inMemorySealers = 4096
+
+// evidencePrefix + number (uint64 big endian) + validator -> evidence (json)
+var evidencePrefix = []byte("parlia-evidence-")
+
+var (
+	doubleSignCounter   = metrics.NewRegisteredCounter("parlia/doublesign", nil)
+	evidenceSubmitMeter = metrics.NewRegisteredMeter("parlia/doublesign/submit", nil)
+	evidenceFailMeter   = metrics.NewRegisteredMeter("parlia/doublesign/fail", nil)
+)
+
+// EvidenceSubmitter sends a transaction with the given data to a system contract.
+type EvidenceSubmitter func(to common.Address, data []byte) error
+
+// DoubleSignEvidence is a pair of different headers sealed by the same validator
+// at the same height.
+type DoubleSignEvidence struct {
+	Validator common.Address `json:"validator"`
+	Number    uint64         `json:"number"`
+	Header1   *types.Header  `json:"header1"` // Header seen first
+	Header2   *types.Header  `json:"header2"` // Conflicting header seen later
+	Time      uint64         `json:"time"`    // Unix time the double sign was detected at
+}
+
+// DoubleSignEvent is posted when a validator is detected to double sign.
+type DoubleSignEvent struct{ Evidence *DoubleSignEvidence }
+
+// sealer identifies the header sealed by a validator at a height.
+type sealer struct {
+	number    uint64
+	validator common.Address
+}
+
+func evidenceKey(number uint64, validator common.Address) []byte {
+	key := make([]byte, len(evidencePrefix)+8+common.AddressLength)
+	copy(key, evidencePrefix)
+	binary.BigEndian.PutUint64(key[len(evidencePrefix):], number)
+	copy(key[len(evidencePrefix)+8:], validator[:])
+	return key
+}
+
+// SetEvidenceSubmitter enables the submission of the detected double signs to
+// the slash contract with the given submitter.
+func (p *Parlia) SetEvidenceSubmitter(submit EvidenceSubmitter) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.submitEvidence = submit
+}
+
+// SubscribeDoubleSignEvent registers a subscription of DoubleSignEvent.
+func (p *Parlia) SubscribeDoubleSignEvent(ch chan<- DoubleSignEvent) event.Subscription {
+	return p.scope.Track(p.doubleSignFeed.Subscribe(ch))
+}
+
+// checkDoubleSign remembers the header sealed by an authorized validator and
+// reports it if the validator already sealed a different header at the same
+// height.
+func (p *Parlia) checkDoubleSign(header *types.Header, validator common.Address) {
+	key := sealer{number: header.Number.Uint64(), validator: validator}
+	seen, ok := p.sealers.Get(key)
+	if !ok {
+		p.sealers.Add(key, header)
+		return
+	}
+	first := seen.(*types.Header)
+	if first.Hash() == header.Hash() || p.SealHash(first) == p.SealHash(header) {
+		// Same header, possibly with a malleated signature
+		return
+	}
+	if has, _ := p.db.Has(evidenceKey(key.number, validator)); has {
+		return
+	}
+	evidence := &DoubleSignEvidence{
+		Validator: validator,
+		Number:    key.number,
+		Header1:   first,
+		Header2:   header,
+		Time:      uint64(time.Now().Unix()),
+	}
+	if err := storeEvidence(p.db, evidence); err != nil {
+		log.Error("Failed to store double sign evidence", "number", key.number, "validator", validator, "err", err)
+	}
+	doubleSignCounter.Inc(1)
+	log.Error("Validator double signed", "number", key.number, "validator", validator, "first", first.Hash(), "second", header.Hash())
+
+	p.doubleSignFeed.Send(DoubleSignEvent{Evidence: evidence})
+
+	p.lock.RLock()
+	submit := p.submitEvidence
+	p.lock.RUnlock()
+	if submit != nil {
+		gopool.Submit(func() { p.reportDoubleSign(submit, evidence) })
+	}
+}
+
+// reportDoubleSign submits the evidence to the slash contract.
+func (p *Parlia) reportDoubleSign(submit EvidenceSubmitter, evidence *DoubleSignEvidence) {
+	header1, err := rlp.EncodeToBytes(evidence.Header1)
+	if err != nil {
+		log.Error("Failed to encode double sign evidence", "err", err)
+		return
+	}
+	header2, err := rlp.EncodeToBytes(evidence.Header2)
+	if err != nil {
+		log.Error("Failed to encode double sign evidence", "err", err)
+		return
+	}
+	data, err := p.evidenceABI.Pack("submitDoubleSignEvidence", header1, header2)
+	if err != nil {
+		log.Error("Unable to pack tx for double sign evidence", "err", err)
+		return
+	}
+	if err := submit(common.HexToAddress(systemcontract.SlashContract), data); err != nil {
+		evidenceFailMeter.Mark(1)
+		log.Warn("Failed to submit double sign evidence", "number", evidence.Number, "validator", evidence.Validator, "err", err)
+		return
+	}
+	evidenceSubmitMeter.Mark(1)
+	log.Info("Submitted double sign evidence", "number", evidence.Number, "validator", evidence.Validator)
+}
+
+func storeEvidence(db ethdb.KeyValueWriter, evidence *DoubleSignEvidence) error {
+	blob, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	return db.Put(evidenceKey(evidence.Number, evidence.Validator), blob)
+}
+
+// loadEvidence retrieves the stored double sign evidence between the given
+// heights (inclusive), at most limit entries.
+func loadEvidence(db ethdb.Iteratee, from, to uint64, limit int) ([]*DoubleSignEvidence, error) {
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, from)
+
+	it := db.NewIterator(evidencePrefix, start)
+	defer it.Release()
+
+	var evidence []*DoubleSignEvidence
+	for it.Next() && len(evidence) < limit {
+		if len(it.Key()) != len(evidencePrefix)+8+common.AddressLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(it.Key()[len(evidencePrefix):]) > to {
+			break
+		}
+		entry := new(DoubleSignEvidence)
+		if err := json.Unmarshal(it.Value(), entry); err != nil {
+			return nil, err
+		}
+		evidence = append(evidence, entry)
+	}
+	return evidence, it.Error()
+}
@@ -0,0 +1,64 @@
+package parlia
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestDoubleSignMonitor(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		engine    = New(params.ChapelChainConfig, db, nil, common.Hash{})
+		validator = randomAddress()
+		events    = make(chan DoubleSignEvent, 2)
+		submitted = make(chan []byte, 2)
+	)
+	sub := engine.SubscribeDoubleSignEvent(events)
+	defer sub.Unsubscribe()
+
+	engine.SetEvidenceSubmitter(func(to common.Address, data []byte) error {
+		submitted <- data
+		return nil
+	})
+	header := func(time uint64) *types.Header {
+		return &types.Header{Number: big.NewInt(10), Coinbase: validator, Time: time, Difficulty: diffInTurn, Extra: make([]byte, extraVanity+extraSeal)}
+	}
+	// Seeing the same header again, or a header of another height, isn't a double sign
+	engine.checkDoubleSign(header(1), validator)
+	engine.checkDoubleSign(header(1), validator)
+	other := header(1)
+	other.Number = big.NewInt(11)
+	engine.checkDoubleSign(other, validator)
+	if len(events) != 0 {
+		t.Fatalf("double sign reported for a single header per height")
+	}
+	// A different header at the same height is reported once
+	engine.checkDoubleSign(header(2), validator)
+	engine.checkDoubleSign(header(3), validator)
+	if len(events) != 1 {
+		t.Fatalf("double sign events mismatch: have %d, want 1", len(events))
+	}
+	ev := <-events
+	if ev.Evidence.Validator != validator || ev.Evidence.Number != 10 || ev.Evidence.Header1.Time != 1 || ev.Evidence.Header2.Time != 2 {
+		t.Fatalf("evidence mismatch: %+v", ev.Evidence)
+	}
+	data := <-submitted
+	if len(data) < 4 || common.Bytes2Hex(data[:4]) != common.Bytes2Hex(engine.evidenceABI.Methods["submitDoubleSignEvidence"].ID) {
+		t.Fatalf("submitted evidence calls the wrong method: %x", data)
+	}
+	stored, err := loadEvidence(db, 0, 100, 10)
+	if err != nil {
+		t.Fatalf("failed to load evidence: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Header2.Hash() != header(2).Hash() {
+		t.Fatalf("stored evidence mismatch: %+v", stored)
+	}
+	if stored, _ := loadEvidence(db, 11, 100, 10); len(stored) != 0 {
+		t.Fatalf("evidence outside of the range returned: %+v", stored)
+	}
+}
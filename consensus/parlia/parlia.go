@@ -34,8 +34,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -136,6 +138,10 @@ var (
 type SignerFn func(accounts.Account, string, []byte) ([]byte, error)
 type SignerTxFn func(accounts.Account, *types.Transaction, *big.Int) (*types.Transaction, error)
 
+// SealGuard is a slashing protection callback checking that the validator may
+// deliver the block with the given seal hash at the given height, recording it.
+type SealGuard func(validator common.Address, number uint64, sealHash common.Hash) error
+
 func isToSystemContract(to common.Address) bool {
 	return systemcontract.IsSystemContract(to)
 }
@@ -190,15 +196,34 @@ type Parlia struct {
 
 	signer types.Signer
 
-	val      common.Address // Ethereum address of the signing key
-	signFn   SignerFn       // Signer function to authorize hashes with
-	signTxFn SignerTxFn
+	val         common.Address // Ethereum address of the signing key
+	signFn      SignerFn       // Signer function to authorize hashes with
+	signTxFn    SignerTxFn
+	maintenance bool      // Whether the local validator skips its sealing turns
+	standby     bool      // Whether another node holding the same key seals the turns
+	sealGuard   SealGuard // Slashing protection consulted before delivering a sealed block, if set
 
 	lock sync.RWMutex // Protects the signer fields
 
 	ethAPI          *ethapi.PublicBlockChainAPI
 	validatorSetABI abi.ABI
 	slashABI        abi.ABI
+	evidenceABI     abi.ABI
+	maintenanceABI  abi.ABI
+
+	sealers        *lru.ARCCache     // Headers sealed by each validator at recent heights
+	arrivals       *lru.ARCCache     // Local arrival times of recent headers in milliseconds
+	submitEvidence EvidenceSubmitter // Submits detected double signs to the slash contract, if set
+	doubleSignFeed event.Feed
+	scope          event.SubscriptionScope
+
+	epochMetrics bool        // Whether the epoch stats are reported as labeled metrics
+	lastEpoch    *EpochStats // Stats of the most recently computed epoch
+	lastEpochEnd common.Hash // Hash of the last block of the cached epoch stats
+	epochLock    sync.Mutex  // Protects the cached epoch stats
+
+	timeWarp   bool  // Whether the clock can be warped over RPC, developer chains only
+	timeOffset int64 // Milliseconds the clock is warped ahead of the system time, accessed atomically
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -236,6 +261,22 @@ func New(
 	if err != nil {
 		panic(err)
 	}
+	eABI, err := abi.JSON(strings.NewReader(evidenceABI))
+	if err != nil {
+		panic(err)
+	}
+	mABI, err := abi.JSON(strings.NewReader(maintenanceABI))
+	if err != nil {
+		panic(err)
+	}
+	sealers, err := lru.NewARC(inMemorySealers)
+	if err != nil {
+		panic(err)
+	}
+	arrivals, err := lru.NewARC(inMemoryArrivals)
+	if err != nil {
+		panic(err)
+	}
 	c := &Parlia{
 		chainConfig:     chainConfig,
 		config:          parliaConfig,
@@ -246,6 +287,10 @@ func New(
 		signatures:      signatures,
 		validatorSetABI: vABI,
 		slashABI:        sABI,
+		evidenceABI:     eABI,
+		maintenanceABI:  mABI,
+		sealers:         sealers,
+		arrivals:        arrivals,
 		signer:          types.NewEIP155Signer(chainConfig.ChainID),
 	}
 
@@ -314,9 +359,10 @@ func (p *Parlia) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 		return errUnknownBlock
 	}
 	number := header.Number.Uint64()
+	p.recordArrival(header)
 
 	// Don't waste time checking blocks from the future
-	if header.Time > uint64(time.Now().Unix()+time.Second.Milliseconds()/1000) {
+	if header.Time > uint64(p.now().Unix()+time.Second.Milliseconds()/1000) {
 		return consensus.ErrFutureBlock
 	}
 	// Check that the extra-data contains the vanity, validators and signature.
@@ -339,9 +385,10 @@ func (p *Parlia) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 		return errInvalidSpanValidators
 	}
 
-	// Ensure that the mix digest is zero as we don't have fork protection currently
-	if header.MixDigest != (common.Hash{}) {
-		return errInvalidMixDigest
+	// Ensure that the mix digest is zero as we don't have fork protection currently,
+	// apart from the milliseconds of the timestamp after a period fork
+	if err := p.verifyMixDigest(header); err != nil {
+		return err
 	}
 	// Ensure that the block doesn't contain any uncles which are meaningless in PoA
 	if header.UncleHash != uncleHash {
@@ -414,6 +461,11 @@ func (p *Parlia) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 		return fmt.Errorf("invalid gas limit: have %d, want %d += %d", header.GasLimit, parent.GasLimit, limit)
 	}
 
+	// Verify the base fee once the fork activated it
+	if err := misc.VerifyBaseFee(chain.Config(), parent, header); err != nil {
+		return err
+	}
+
 	// All basic checks passed, verify the seal and return
 	return p.verifySeal(chain, header, parents)
 }
@@ -563,14 +615,11 @@ func (p *Parlia) verifySeal(chain consensus.ChainHeaderReader, header *types.Hea
 	if _, ok := snap.Validators[signer]; !ok {
 		return errUnauthorizedValidator
 	}
+	p.checkDoubleSign(header, signer)
 
-	for seen, recent := range snap.Recents {
-		if recent == signer {
-			// Signer is among recents, only fail if the current block doesn't shift it out
-			if limit := uint64(len(snap.Validators)/2 + 1); seen > number-limit {
-				return errRecentlySigned
-			}
-		}
+	// Signer is among recents, only fail if the current block doesn't shift it out
+	if snap.signRecently(signer, number) {
+		return errRecentlySigned
 	}
 
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
@@ -641,10 +690,11 @@ func (p *Parlia) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	header.Time = p.blockTimeForRamanujanFork(snap, header, parent)
-	if header.Time < uint64(time.Now().Unix()) {
-		header.Time = uint64(time.Now().Unix())
+	blockTime := p.blockTimeForRamanujanFork(snap, header, parent)
+	if now := uint64(p.now().UnixNano() / int64(time.Millisecond)); blockTime < now {
+		blockTime = now
 	}
+	p.setBlockTime(header, blockTime)
 	return nil
 }
 
@@ -697,13 +747,7 @@ func (p *Parlia) Finalize(chain consensus.ChainHeaderReader, header *types.Heade
 	}
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
 		spoiledVal := snap.supposeValidator()
-		signedRecently := false
-		for _, recent := range snap.Recents {
-			if recent == spoiledVal {
-				signedRecently = true
-				break
-			}
-		}
+		signedRecently := snap.countRecents(spoiledVal) >= snap.turnLength(header.Number.Uint64())
 		if !signedRecently {
 			log.Trace("slash validator", "block hash", header.Hash(), "address", spoiledVal)
 			err = p.slash(spoiledVal, state, header, cx, txs, receipts, systemTxs, usedGas, false)
@@ -750,13 +794,7 @@ func (p *Parlia) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *
 			return nil, nil, err
 		}
 		spoiledVal := snap.supposeValidator()
-		signedRecently := false
-		for _, recent := range snap.Recents {
-			if recent == spoiledVal {
-				signedRecently = true
-				break
-			}
-		}
+		signedRecently := snap.countRecents(spoiledVal) >= snap.turnLength(header.Number.Uint64())
 		if !signedRecently {
 			err = p.slash(spoiledVal, state, header, cx, &txs, &receipts, nil, &header.GasUsed, true)
 			if err != nil {
@@ -803,7 +841,60 @@ func (p *Parlia) Authorize(val common.Address, signFn SignerFn, signTxFn SignerT
 	p.signTxFn = signTxFn
 }
 
+// SetMaintenance enables or disables the maintenance mode, in which the local
+// validator skips its sealing turns.
+func (p *Parlia) SetMaintenance(maintenance bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.maintenance = maintenance
+}
+
+// InMaintenance returns whether the local validator skips its sealing turns.
+func (p *Parlia) InMaintenance() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.maintenance
+}
+
+// SetStandby enables or disables the standby mode, in which the local validator
+// skips its sealing turns as another node holding the same key seals them.
+func (p *Parlia) SetStandby(standby bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.standby = standby
+}
+
+// SetSealGuard installs the slashing protection consulted before delivering a
+// sealed block.
+func (p *Parlia) SetSealGuard(guard SealGuard) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.sealGuard = guard
+}
+
+// MaintenanceCall returns the validator set contract call signalling that the
+// local validator enters or exits maintenance. Older contracts don't support it.
+func (p *Parlia) MaintenanceCall(enter bool) (common.Address, []byte, error) {
+	method := "exitMaintenance"
+	if enter {
+		method = "enterMaintenance"
+	}
+	data, err := p.maintenanceABI.Pack(method)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return common.HexToAddress(systemcontract.ValidatorContract), data, nil
+}
+
 func (p *Parlia) Delay(chain consensus.ChainReader, header *types.Header) *time.Duration {
+	// Blocks of 0-period chains are sealed on transaction arrival, without deadline
+	if p.config.PeriodMs(header.Number) == 0 {
+		return nil
+	}
 	number := header.Number.Uint64()
 	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
 	if err != nil {
@@ -811,7 +902,7 @@ func (p *Parlia) Delay(chain consensus.ChainReader, header *types.Header) *time.
 	}
 	delay := p.delayForRamanujanFork(snap, header)
 	// The blocking time should be no more than half of period
-	half := time.Duration(p.config.Period) * time.Second / 2
+	half := time.Duration(p.config.PeriodMs(header.Number)) * time.Millisecond / 2
 	if delay > half {
 		delay = half
 	}
@@ -829,15 +920,26 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 		return errUnknownBlock
 	}
 	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if p.config.Period == 0 && len(block.Transactions()) == 0 {
+	if p.config.PeriodMs(header.Number) == 0 && len(block.Transactions()) == 0 {
 		log.Info("Sealing paused, waiting for transactions")
 		return nil
 	}
 	// Don't hold the val fields for the entire sealing procedure
 	p.lock.RLock()
-	val, signFn := p.val, p.signFn
+	val, signFn, maintenance, standby, guard := p.val, p.signFn, p.maintenance, p.standby, p.sealGuard
 	p.lock.RUnlock()
 
+	// Skip our turns while the operator maintains the node
+	if maintenance {
+		log.Info("Sealing skipped, validator in maintenance", "number", number)
+		return nil
+	}
+	// Skip our turns while a failover partner seals them
+	if standby {
+		log.Debug("Sealing skipped, validator on standby", "number", number)
+		return nil
+	}
+
 	snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
 	if err != nil {
 		return err
@@ -849,14 +951,9 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	}
 
 	// If we're amongst the recent signers, wait for the next block
-	for seen, recent := range snap.Recents {
-		if recent == val {
-			// Signer is among recents, only wait if the current block doesn't shift it out
-			if limit := uint64(len(snap.Validators)/2 + 1); number < limit || seen > number-limit {
-				log.Info("Signed recently, must wait for others")
-				return nil
-			}
-		}
+	if snap.signRecently(val, number) {
+		log.Info("Signed recently, must wait for others")
+		return nil
 	}
 
 	// Sweet, the protocol permits us to sign the block, wait for our time
@@ -889,7 +986,15 @@ func (p *Parlia) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 				log.Info("Process backoff time exhausted, start to seal block")
 			}
 		}
-
+		// Never deliver a block which may conflict with one delivered before. The
+		// check is done this late as the miner keeps replacing its sealing task
+		// with new candidates for the same height, which are never published.
+		if guard != nil {
+			if err := guard(val, number, p.SealHash(header)); err != nil {
+				log.Error("Sealed block refused by slashing protection", "number", number, "err", err)
+				return
+			}
+		}
 		select {
 		case results <- block.WithSeal(header):
 		default:
@@ -953,15 +1058,7 @@ func (p *Parlia) SignRecently(chain consensus.ChainReader, parent *types.Header)
 
 	// If we're amongst the recent signers, wait for the next block
 	number := parent.Number.Uint64() + 1
-	for seen, recent := range snap.Recents {
-		if recent == p.val {
-			// Signer is among recents, only wait if the current block doesn't shift it out
-			if limit := uint64(len(snap.Validators)/2 + 1); number < limit || seen > number-limit {
-				return true, nil
-			}
-		}
-	}
-	return false, nil
+	return snap.signRecently(p.val, number), nil
 }
 
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
@@ -992,16 +1089,39 @@ func (p *Parlia) SealHash(header *types.Header) common.Hash {
 
 // APIs implements consensus.Engine, returning the user facing RPC API to query snapshot.
 func (p *Parlia) APIs(chain consensus.ChainHeaderReader) []rpc.API {
-	return []rpc.API{{
+	apis := []rpc.API{{
 		Namespace: "parlia",
 		Version:   "1.0",
 		Service:   &API{chain: chain, parlia: p},
 		Public:    false,
+	}, {
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   &AdminAPI{chain: chain, parlia: p},
+		Public:    false,
 	}}
+	if p.timeWarp {
+		apis = append(apis, timeWarpAPIs(p)...)
+	}
+	return apis
 }
 
-// Close implements consensus.Engine. It's a noop for parlia as there are no background threads.
+// Validators implements consensus.PoSA, returning the validators authorized to
+// seal the blocks following the given one, in ascending order.
+func (p *Parlia) Validators(chain consensus.ChainHeaderReader, header *types.Header) ([]common.Address, error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// Close implements consensus.Engine, terminating the double sign subscriptions.
 func (p *Parlia) Close() error {
+	p.scope.Close()
+	if p.epochMetrics {
+		prometheus.UnregisterLabeledGauges(epochMetricsSource)
+	}
 	return nil
 }
 
@@ -1267,7 +1387,7 @@ func SealHash(header *types.Header, chainId *big.Int) (hash common.Hash) {
 }
 
 func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
-	err := rlp.Encode(w, []interface{}{
+	enc := []interface{}{
 		chainId,
 		header.ParentHash,
 		header.UncleHash,
@@ -1284,13 +1404,18 @@ func encodeSigHeader(w io.Writer, header *types.Header, chainId *big.Int) {
 		header.Extra[:len(header.Extra)-65], // this will panic if extra is too short, should check before calling encodeSigHeader
 		header.MixDigest,
 		header.Nonce,
-	})
-	if err != nil {
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	if err := rlp.Encode(w, enc); err != nil {
 		panic("can't encode: " + err.Error())
 	}
 }
 
-func backOffTime(snap *Snapshot, val common.Address) uint64 {
+// backOffTime returns the number of milliseconds an out-of-turn validator has
+// to wait after the period of the given block before sealing it.
+func (p *Parlia) backOffTime(snap *Snapshot, header *types.Header, val common.Address) uint64 {
 	if snap.inturn(val) {
 		return 0
 	} else {
@@ -1309,7 +1434,13 @@ func backOffTime(snap *Snapshot, val common.Address) uint64 {
 		r.Shuffle(n, func(i, j int) {
 			backOffSteps[i], backOffSteps[j] = backOffSteps[j], backOffSteps[i]
 		})
-		delay := initialBackOffTime + backOffSteps[idx]*wiggleTime
+		initial, wiggle := initialBackOffTime*1000, wiggleTime*1000
+		if p.config.IsMilliPeriod(header.Number) {
+			// Scale the back off to the period, matching the legacy values at 3 seconds
+			initial = p.config.PeriodMs(header.Number) / 3
+			wiggle = initial
+		}
+		delay := initial + backOffSteps[idx]*wiggle
 		return delay
 	}
 }
@@ -1,11 +1,19 @@
 package parlia
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestImpactOfValidatorOutOfService(t *testing.T) {
@@ -144,3 +152,123 @@ func randomAddress() common.Address {
 	rand.Read(addrBytes)
 	return common.BytesToAddress(addrBytes)
 }
+
+func TestMaintenanceSkipsSealing(t *testing.T) {
+	engine := New(params.ChapelChainConfig, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	engine.SetMaintenance(true)
+	if !engine.InMaintenance() {
+		t.Fatal("maintenance mode not enabled")
+	}
+	var (
+		block   = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Extra: make([]byte, extraVanity+extraSeal)})
+		results = make(chan *types.Block, 1)
+	)
+	// The chain isn't consulted in maintenance, sealing the turn is skipped
+	if err := engine.Seal(nil, block, results, make(chan struct{})); err != nil {
+		t.Fatalf("sealing failed in maintenance: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatal("block sealed in maintenance")
+	}
+	for _, enter := range []bool{true, false} {
+		to, data, err := engine.MaintenanceCall(enter)
+		if err != nil {
+			t.Fatalf("failed to pack maintenance call: %v", err)
+		}
+		method, err := engine.maintenanceABI.MethodById(data)
+		if err != nil {
+			t.Fatalf("failed to unpack maintenance call: %v", err)
+		}
+		if want := map[bool]string{true: "enterMaintenance", false: "exitMaintenance"}[enter]; method.Name != want {
+			t.Errorf("maintenance call mismatch: have %s, want %s", method.Name, want)
+		}
+		if to != common.HexToAddress("0x0000000000000000000000000000000000001000") {
+			t.Errorf("maintenance call to wrong contract %x", to)
+		}
+	}
+}
+
+// Tests that the seal guard only records the blocks actually delivered, so that
+// a sealing task replaced by the miner doesn't block the next candidate of the
+// same height.
+func TestSealGuardRecordsDeliveredBlocks(t *testing.T) {
+	config, parliaConfig := *params.ChapelChainConfig, *params.ChapelChainConfig.Parlia
+	parliaConfig.Epoch = 200
+	parliaConfig.PeriodForks = nil
+	config.Parlia = &parliaConfig
+
+	engine := New(&config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+	chain, validators := newSealedChain(t, &config, 3, 1000)
+
+	var (
+		val    = validators[1] // In turn at block 4
+		lock   sync.Mutex
+		sealed = make(map[uint64]common.Hash)
+	)
+	engine.Authorize(val, func(accounts.Account, string, []byte) ([]byte, error) {
+		return make([]byte, extraSeal), nil
+	}, nil)
+	engine.SetSealGuard(func(validator common.Address, number uint64, sealHash common.Hash) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if have, ok := sealed[number]; ok && have != sealHash {
+			return errors.New("double seal")
+		}
+		sealed[number] = sealHash
+		return nil
+	})
+	recorded := func() (common.Hash, bool) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		hash, ok := sealed[4]
+		return hash, ok
+	}
+	seal := func(at time.Time, salt byte) (common.Hash, chan *types.Block, chan struct{}) {
+		header := &types.Header{
+			ParentHash: chain.headers[3].Hash(),
+			Number:     big.NewInt(4),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Coinbase:   val,
+			Time:       uint64(at.Unix()),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		header.Extra[0] = salt
+
+		results, stop := make(chan *types.Block, 1), make(chan struct{})
+		if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, stop); err != nil {
+			t.Fatalf("failed to seal candidate %d: %v", salt, err)
+		}
+		return engine.SealHash(header), results, stop
+	}
+	// Interrupt a candidate waiting for its slot, it must not be recorded
+	_, _, stop := seal(time.Now().Add(time.Hour), 1)
+	close(stop)
+	if _, ok := recorded(); ok {
+		t.Fatal("interrupted candidate recorded")
+	}
+	// Seal another candidate of the same height, which is delivered and recorded
+	hash, results, _ := seal(time.Now(), 2)
+	select {
+	case block := <-results:
+		if have := engine.SealHash(block.Header()); have != hash {
+			t.Fatalf("delivered block mismatch: have %x, want %x", have, hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("replacing candidate not delivered")
+	}
+	if have, _ := recorded(); have != hash {
+		t.Fatalf("recorded seal hash mismatch: have %x, want %x", have, hash)
+	}
+	// A conflicting block of the delivered height is refused
+	_, results, _ = seal(time.Now(), 3)
+	select {
+	case <-results:
+		t.Fatal("conflicting block delivered")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if have, _ := recorded(); have != hash {
+		t.Fatalf("recorded seal hash overwritten: have %x, want %x", have, hash)
+	}
+}
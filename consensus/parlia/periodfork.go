@@ -0,0 +1,48 @@
+package parlia
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Once a period fork is active, the milliseconds of the block timestamp are
+// carried big endian in the last bytes of the otherwise empty mix digest.
+const milliTimeOffset = common.HashLength - 8
+
+// headerMilliTime returns the timestamp of a header in milliseconds.
+func (p *Parlia) headerMilliTime(header *types.Header) uint64 {
+	if !p.config.IsMilliPeriod(header.Number) {
+		return header.Time * 1000
+	}
+	return header.Time*1000 + binary.BigEndian.Uint64(header.MixDigest[milliTimeOffset:])
+}
+
+// setBlockTime sets the timestamp of a header given in milliseconds, truncating
+// it to seconds before the first period fork.
+func (p *Parlia) setBlockTime(header *types.Header, milliTime uint64) {
+	header.Time = milliTime / 1000
+	header.MixDigest = common.Hash{}
+	if p.config.IsMilliPeriod(header.Number) {
+		binary.BigEndian.PutUint64(header.MixDigest[milliTimeOffset:], milliTime%1000)
+	}
+}
+
+// verifyMixDigest checks that the mix digest is empty, apart from the
+// milliseconds of the timestamp after the first period fork.
+func (p *Parlia) verifyMixDigest(header *types.Header) error {
+	if !p.config.IsMilliPeriod(header.Number) {
+		if header.MixDigest != (common.Hash{}) {
+			return errInvalidMixDigest
+		}
+		return nil
+	}
+	if common.BytesToHash(header.MixDigest[:milliTimeOffset]) != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	if binary.BigEndian.Uint64(header.MixDigest[milliTimeOffset:]) >= 1000 {
+		return errInvalidMixDigest
+	}
+	return nil
+}
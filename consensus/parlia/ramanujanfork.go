@@ -14,7 +14,7 @@ const (
 )
 
 func (p *Parlia) delayForRamanujanFork(snap *Snapshot, header *types.Header) time.Duration {
-	delay := time.Until(time.Unix(int64(header.Time), 0)) // nolint: gosimple
+	delay := time.Unix(0, int64(p.headerMilliTime(header))*int64(time.Millisecond)).Sub(p.now())
 	if p.chainConfig.IsRamanujan(header.Number) {
 		return delay
 	}
@@ -26,17 +26,19 @@ func (p *Parlia) delayForRamanujanFork(snap *Snapshot, header *types.Header) tim
 	return delay
 }
 
+// blockTimeForRamanujanFork returns the earliest timestamp of a locally sealed
+// header in milliseconds.
 func (p *Parlia) blockTimeForRamanujanFork(snap *Snapshot, header, parent *types.Header) uint64 {
-	blockTime := parent.Time + p.config.Period
+	blockTime := p.headerMilliTime(parent) + p.config.PeriodMs(header.Number)
 	if p.chainConfig.IsRamanujan(header.Number) {
-		blockTime = blockTime + backOffTime(snap, p.val)
+		blockTime = blockTime + p.backOffTime(snap, header, p.val)
 	}
 	return blockTime
 }
 
 func (p *Parlia) blockTimeVerifyForRamanujanFork(snap *Snapshot, header, parent *types.Header) error {
 	if p.chainConfig.IsRamanujan(header.Number) {
-		if header.Time < parent.Time+p.config.Period+backOffTime(snap, header.Coinbase) {
+		if p.headerMilliTime(header) < p.headerMilliTime(parent)+p.config.PeriodMs(header.Number)+p.backOffTime(snap, header, header.Coinbase) {
 			return consensus.ErrFutureBlock
 		}
 	}
@@ -168,9 +168,7 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 	for _, header := range headers {
 		number := header.Number.Uint64()
 		// Delete the oldest validator from the recent list to allow it signing again
-		if limit := uint64(len(snap.Validators)/2 + 1); number >= limit {
-			delete(snap.Recents, number-limit)
-		}
+		snap.pruneRecents(number)
 		if limit := uint64(len(snap.Validators)); number >= limit {
 			delete(snap.RecentForkHashes, number-limit)
 		}
@@ -182,10 +180,8 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 		if _, ok := snap.Validators[validator]; !ok {
 			return nil, errUnauthorizedValidator
 		}
-		for _, recent := range snap.Recents {
-			if recent == validator {
-				return nil, errRecentlySigned
-			}
+		if snap.countRecents(validator) >= snap.turnLength(number) {
+			return nil, errRecentlySigned
 		}
 		snap.Recents[number] = validator
 		// change validator set
@@ -205,21 +201,17 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 			for _, val := range newValArr {
 				newVals[val] = struct{}{}
 			}
-			oldLimit := len(snap.Validators)/2 + 1
-			newLimit := len(newVals)/2 + 1
-			if newLimit < oldLimit {
-				for i := 0; i < oldLimit-newLimit; i++ {
-					delete(snap.Recents, number-uint64(newLimit)-uint64(i))
-				}
-			}
-			oldLimit = len(snap.Validators)
-			newLimit = len(newVals)
+			oldLimit := len(snap.Validators)
+			newLimit := len(newVals)
 			if newLimit < oldLimit {
 				for i := 0; i < oldLimit-newLimit; i++ {
 					delete(snap.RecentForkHashes, number-uint64(newLimit)-uint64(i))
 				}
 			}
 			snap.Validators = newVals
+
+			// A smaller validator set shortens the history of recent validators
+			snap.pruneRecents(number)
 		}
 		snap.RecentForkHashes[number] = hex.EncodeToString(header.Extra[extraVanity-nextForkHashSize : extraVanity])
 	}
@@ -238,17 +230,69 @@ func (s *Snapshot) validators() []common.Address {
 	return validators
 }
 
+// turnLength returns the number of consecutive blocks sealed by the in-turn
+// validator at the given block height.
+func (s *Snapshot) turnLength(number uint64) uint64 {
+	return s.config.TurnLength(new(big.Int).SetUint64(number))
+}
+
+// minerHistoryCheckLen returns the number of blocks preceding the given block
+// height in which a validator may only seal a single turn.
+func (s *Snapshot) minerHistoryCheckLen(number uint64) uint64 {
+	return uint64(len(s.Validators)/2+1)*s.turnLength(number) - 1
+}
+
+// pruneRecents deletes the validators from the recent list which sealed too long
+// ago to be banned from sealing the given block height.
+func (s *Snapshot) pruneRecents(number uint64) {
+	limit := s.minerHistoryCheckLen(number) + 1
+	for seen := range s.Recents {
+		if seen+limit <= number {
+			delete(s.Recents, seen)
+		}
+	}
+}
+
+// countRecents returns the number of recent blocks sealed by a validator.
+func (s *Snapshot) countRecents(validator common.Address) uint64 {
+	var count uint64
+	for _, recent := range s.Recents {
+		if recent == validator {
+			count++
+		}
+	}
+	return count
+}
+
+// signRecently returns whether a validator already sealed its full turn within
+// the history preceding the given block height, banning it from sealing it.
+func (s *Snapshot) signRecently(validator common.Address, number uint64) bool {
+	limit := s.minerHistoryCheckLen(number)
+	var count uint64
+	for seen, recent := range s.Recents {
+		// Only count the blocks which aren't shifted out by the given one
+		if recent == validator && seen+limit >= number {
+			count++
+		}
+	}
+	return count >= s.turnLength(number)
+}
+
+// inturnOffset returns the index of the in-turn validator of the next block.
+func (s *Snapshot) inturnOffset() uint64 {
+	number := s.Number + 1
+	return number / s.turnLength(number) % uint64(len(s.Validators))
+}
+
 // inturn returns if a validator at a given block height is in-turn or not.
 func (s *Snapshot) inturn(validator common.Address) bool {
 	validators := s.validators()
-	offset := (s.Number + 1) % uint64(len(validators))
-	return validators[offset] == validator
+	return validators[s.inturnOffset()] == validator
 }
 
 func (s *Snapshot) blockProducer() common.Address {
 	validators := s.validators()
-	offset := (s.Number + 1) % uint64(len(validators))
-	return validators[offset]
+	return validators[s.inturnOffset()]
 }
 
 func (s *Snapshot) enoughDistance(validator common.Address, header *types.Header) bool {
@@ -263,7 +307,7 @@ func (s *Snapshot) enoughDistance(validator common.Address, header *types.Header
 	if validator == header.Coinbase {
 		return false
 	}
-	offset := (int64(s.Number) + 1) % validatorNum
+	offset := int64(s.inturnOffset())
 	if int64(idx) >= offset {
 		return int64(idx)-offset >= validatorNum-2
 	} else {
@@ -283,8 +327,7 @@ func (s *Snapshot) indexOfVal(validator common.Address) int {
 
 func (s *Snapshot) supposeValidator() common.Address {
 	validators := s.validators()
-	index := (s.Number + 1) % uint64(len(validators))
-	return validators[index]
+	return validators[s.inturnOffset()]
 }
 
 func ParseValidators(validatorsBytes []byte) ([]common.Address, error) {
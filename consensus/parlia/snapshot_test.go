@@ -2,12 +2,14 @@ package parlia
 
 import (
 	"bytes"
+	"math/big"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestValidatorSetSort(t *testing.T) {
@@ -21,3 +23,43 @@ func TestValidatorSetSort(t *testing.T) {
 		assert.True(t, bytes.Compare(validators[i][:], validators[i+1][:]) < 0)
 	}
 }
+
+func TestSnapshotTurnLength(t *testing.T) {
+	config := &params.ParliaConfig{
+		Period: 3,
+		Epoch:  200,
+		PeriodForks: []*params.ParliaPeriodFork{
+			{Block: big.NewInt(200), PeriodMs: 1500, TurnLength: 4},
+		},
+	}
+	validators := make([]common.Address, 5)
+	for i := range validators {
+		validators[i] = randomAddress()
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	// Seal a few blocks before and after the fork by the in-turn validators
+	snap := newSnapshot(config, nil, 0, common.Hash{}, validators, nil)
+	for number := uint64(1); number < 240; number++ {
+		snap.pruneRecents(number)
+		producer := snap.blockProducer()
+		assert.False(t, snap.signRecently(producer, number), "block %d", number)
+		snap.Recents[number] = producer
+		snap.Number = number
+	}
+	// Before the fork every validator seals a single block at a time
+	assert.Equal(t, uint64(2), snap.minerHistoryCheckLen(199))
+	assert.Equal(t, uint64(11), snap.minerHistoryCheckLen(200))
+
+	// The in-turn validator of a turn may seal all blocks of it, but no more
+	snap.Number = 239
+	producer := snap.blockProducer()
+	assert.Equal(t, validators[(240/4)%5], producer)
+	for number := uint64(240); number < 244; number++ {
+		snap.pruneRecents(number)
+		assert.False(t, snap.signRecently(producer, number), "block %d", number)
+		snap.Recents[number] = producer
+	}
+	assert.True(t, snap.signRecently(producer, 244))
+	assert.Equal(t, uint64(12), uint64(len(snap.Recents)))
+}
@@ -0,0 +1,192 @@
+package parlia
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+)
+
+const (
+	// inMemoryArrivals is the number of recent block arrival times to keep in
+	// memory for measuring the propagation latency.
+	inMemoryArrivals = 4096
+
+	// epochMetricsSource is the name the epoch stats are reported under as
+	// labeled metrics.
+	epochMetricsSource = "parlia/epoch"
+)
+
+// ValidatorStats is the block production record of a validator over a range of
+// blocks.
+type ValidatorStats struct {
+	InTurn         uint64 `json:"inTurn"`         // Blocks sealed in turn
+	OutOfTurn      uint64 `json:"outOfTurn"`      // Blocks sealed out of turn, picking up a missed turn
+	Missed         uint64 `json:"missed"`         // In-turn blocks sealed by another validator
+	LatencySamples uint64 `json:"latencySamples"` // Blocks whose arrival was observed locally
+	AverageLatency uint64 `json:"averageLatency"` // Average delay between sealing and local arrival in milliseconds
+
+	latency uint64 // Sum of the latencies of the samples
+}
+
+// ProductionStats is the block production record of the validators over a range
+// of blocks.
+type ProductionStats struct {
+	From       uint64                             `json:"from"`
+	To         uint64                             `json:"to"`
+	Validators map[common.Address]*ValidatorStats `json:"validators"`
+}
+
+// EpochStats is the block production record of the validators over an epoch,
+// spanning the blocks from the epoch checkpoint up to the next one.
+type EpochStats struct {
+	Epoch uint64 `json:"epoch"`
+	*ProductionStats
+}
+
+// recordArrival remembers when a header was first seen, to measure how long it
+// took to propagate.
+func (p *Parlia) recordArrival(header *types.Header) {
+	if hash := header.Hash(); !p.arrivals.Contains(hash) {
+		p.arrivals.Add(hash, uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	}
+}
+
+// productionStats computes the block production record of the validators over
+// the given canonical blocks.
+func (p *Parlia) productionStats(chain consensus.ChainHeaderReader, from, to uint64) (*ProductionStats, error) {
+	stats := &ProductionStats{
+		From:       from,
+		To:         to,
+		Validators: make(map[common.Address]*ValidatorStats),
+	}
+	get := func(validator common.Address) *ValidatorStats {
+		if stats.Validators[validator] == nil {
+			stats.Validators[validator] = new(ValidatorStats)
+		}
+		return stats.Validators[validator]
+	}
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		snap, err := p.snapshot(chain, number-1, header.ParentHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		sealer := get(header.Coinbase)
+		if expected := snap.blockProducer(); expected == header.Coinbase {
+			sealer.InTurn++
+		} else {
+			sealer.OutOfTurn++
+			get(expected).Missed++
+		}
+		if arrival, ok := p.arrivals.Get(header.Hash()); ok {
+			var latency uint64
+			if sealed := p.headerMilliTime(header); arrival.(uint64) > sealed {
+				latency = arrival.(uint64) - sealed
+			}
+			sealer.LatencySamples++
+			sealer.latency += latency
+		}
+	}
+	for _, validator := range stats.Validators {
+		if validator.LatencySamples > 0 {
+			validator.AverageLatency = validator.latency / validator.LatencySamples
+		}
+	}
+	return stats, nil
+}
+
+// latestEpoch returns the most recent epoch completed by the given chain, or
+// false if the chain hasn't completed any yet.
+func (p *Parlia) latestEpoch(chain consensus.ChainHeaderReader) (uint64, bool) {
+	head := chain.CurrentHeader().Number.Uint64()
+	if head+1 < p.config.Epoch {
+		return 0, false
+	}
+	return (head+1)/p.config.Epoch - 1, true
+}
+
+// epochStats computes the block production record of the validators over the
+// given complete epoch. The record of the last requested epoch is cached until
+// the epoch gets reorged.
+func (p *Parlia) epochStats(chain consensus.ChainHeaderReader, epoch uint64) (*EpochStats, error) {
+	if latest, ok := p.latestEpoch(chain); !ok || epoch > latest {
+		return nil, fmt.Errorf("epoch %d is not complete", epoch)
+	}
+	from, to := epoch*p.config.Epoch, (epoch+1)*p.config.Epoch-1
+	if from == 0 {
+		from = 1 // Genesis isn't sealed
+	}
+	last := chain.GetHeaderByNumber(to)
+	if last == nil {
+		return nil, errUnknownBlock
+	}
+	p.epochLock.Lock()
+	defer p.epochLock.Unlock()
+
+	if p.lastEpoch != nil && p.lastEpoch.Epoch == epoch && p.lastEpochEnd == last.Hash() {
+		return p.lastEpoch, nil
+	}
+	stats, err := p.productionStats(chain, from, to)
+	if err != nil {
+		return nil, err
+	}
+	p.lastEpoch, p.lastEpochEnd = &EpochStats{Epoch: epoch, ProductionStats: stats}, last.Hash()
+	return p.lastEpoch, nil
+}
+
+// EnableEpochMetrics reports the block production record of the validators over
+// the latest complete epoch of the given chain as labeled Prometheus metrics.
+func (p *Parlia) EnableEpochMetrics(chain consensus.ChainHeaderReader) {
+	p.epochMetrics = true
+	prometheus.RegisterLabeledGauges(epochMetricsSource, func() map[string][]prometheus.LabeledValue {
+		return p.epochGauges(chain)
+	})
+}
+
+// epochGauges converts the stats of the latest complete epoch into labeled gauges,
+// one sample per validator.
+func (p *Parlia) epochGauges(chain consensus.ChainHeaderReader) map[string][]prometheus.LabeledValue {
+	epoch, ok := p.latestEpoch(chain)
+	if !ok {
+		return nil
+	}
+	stats, err := p.epochStats(chain, epoch)
+	if err != nil {
+		log.Debug("Failed to compute epoch stats", "epoch", epoch, "err", err)
+		return nil
+	}
+	validators := make([]common.Address, 0, len(stats.Validators))
+	for validator := range stats.Validators {
+		validators = append(validators, validator)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return bytes.Compare(validators[i][:], validators[j][:]) < 0
+	})
+	gauges := map[string][]prometheus.LabeledValue{
+		"parlia/epoch/number": {{Value: stats.Epoch}},
+	}
+	for _, validator := range validators {
+		var (
+			record = stats.Validators[validator]
+			labels = map[string]string{"validator": validator.Hex()}
+		)
+		gauges["parlia/epoch/sealed"] = append(gauges["parlia/epoch/sealed"], prometheus.LabeledValue{Labels: labels, Value: record.InTurn + record.OutOfTurn})
+		gauges["parlia/epoch/inturn"] = append(gauges["parlia/epoch/inturn"], prometheus.LabeledValue{Labels: labels, Value: record.InTurn})
+		gauges["parlia/epoch/outofturn"] = append(gauges["parlia/epoch/outofturn"], prometheus.LabeledValue{Labels: labels, Value: record.OutOfTurn})
+		gauges["parlia/epoch/missed"] = append(gauges["parlia/epoch/missed"], prometheus.LabeledValue{Labels: labels, Value: record.Missed})
+		if record.LatencySamples > 0 {
+			gauges["parlia/epoch/latency"] = append(gauges["parlia/epoch/latency"], prometheus.LabeledValue{Labels: labels, Value: record.AverageLatency})
+		}
+	}
+	return gauges
+}
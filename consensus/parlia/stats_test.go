@@ -0,0 +1,158 @@
+package parlia
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testHeaderChain is a canonical chain of headers served by number.
+type testHeaderChain struct {
+	headers []*types.Header
+}
+
+func (c *testHeaderChain) Config() *params.ChainConfig  { return params.ChapelChainConfig }
+func (c *testHeaderChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.GetHeaderByHash(hash)
+}
+func (c *testHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+func (c *testHeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+func (c *testHeaderChain) GetHighestVerifiedHeader() *types.Header { return c.CurrentHeader() }
+
+var statsValidators = []common.Address{{1}, {2}, {3}}
+
+// newStatsChain creates a chain of the given length sealed by three validators
+// in turn, apart from block 4 picked up by validator 3.
+func newStatsChain(engine *Parlia, length uint64) *testHeaderChain {
+	var (
+		validators = statsValidators
+		chain      = &testHeaderChain{headers: []*types.Header{{Number: big.NewInt(0)}}}
+	)
+	for number := uint64(0); number < length; number++ {
+		parent := chain.headers[number]
+		snap := &Snapshot{config: engine.config, Number: number, Hash: parent.Hash(), Validators: make(map[common.Address]struct{})}
+		for _, val := range validators {
+			snap.Validators[val] = struct{}{}
+		}
+		engine.recentSnaps.Add(snap.Hash, snap)
+
+		sealer := validators[(number+1)%3]
+		if number+1 == 4 {
+			sealer = validators[2]
+		}
+		chain.headers = append(chain.headers, &types.Header{
+			Number:     new(big.Int).SetUint64(number + 1),
+			ParentHash: parent.Hash(),
+			Coinbase:   sealer,
+			Time:       uint64(time.Now().Unix()) - 1,
+		})
+	}
+	return chain
+}
+
+func TestProductionStats(t *testing.T) {
+	var (
+		engine     = New(params.ChapelChainConfig, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+		validators = statsValidators
+		chain      = newStatsChain(engine, 7)
+	)
+	engine.recordArrival(chain.headers[1])
+
+	stats, err := engine.productionStats(chain, 1, 6)
+	if err != nil {
+		t.Fatalf("failed to compute stats: %v", err)
+	}
+	want := map[common.Address][3]uint64{
+		validators[0]: {2, 0, 0},
+		validators[1]: {1, 0, 1},
+		validators[2]: {2, 1, 0},
+	}
+	for val, counts := range want {
+		have := stats.Validators[val]
+		if have == nil || have.InTurn != counts[0] || have.OutOfTurn != counts[1] || have.Missed != counts[2] {
+			t.Errorf("validator %x: stats mismatch: have %+v, want in-turn/out-of-turn/missed %v", val, have, counts)
+		}
+	}
+	if stats := stats.Validators[validators[1]]; stats.LatencySamples != 1 || stats.AverageLatency < 1000 {
+		t.Errorf("latency mismatch: have %d samples averaging %dms", stats.LatencySamples, stats.AverageLatency)
+	}
+}
+
+func TestEpochStats(t *testing.T) {
+	config, parliaConfig := *params.ChapelChainConfig, *params.ChapelChainConfig.Parlia
+	parliaConfig.Epoch = 3
+	config.Parlia = &parliaConfig
+
+	var (
+		engine     = New(&config, rawdb.NewMemoryDatabase(), nil, common.Hash{})
+		validators = statsValidators
+		chain      = newStatsChain(engine, 7)
+	)
+	// Blocks 3 to 5 make up the latest complete epoch, block 6 starts the next
+	if epoch, ok := engine.latestEpoch(chain); !ok || epoch != 1 {
+		t.Fatalf("latest epoch mismatch: have %d (%v), want 1", epoch, ok)
+	}
+	if _, err := engine.epochStats(chain, 2); err == nil {
+		t.Fatalf("stats of incomplete epoch computed")
+	}
+	engine.recordArrival(chain.headers[5])
+
+	stats, err := engine.epochStats(chain, 1)
+	if err != nil {
+		t.Fatalf("failed to compute stats: %v", err)
+	}
+	if stats.Epoch != 1 || stats.From != 3 || stats.To != 5 {
+		t.Fatalf("epoch range mismatch: have epoch %d blocks %d-%d, want epoch 1 blocks 3-5", stats.Epoch, stats.From, stats.To)
+	}
+	want := map[common.Address][3]uint64{
+		validators[0]: {1, 0, 0},
+		validators[1]: {0, 0, 1},
+		validators[2]: {1, 1, 0},
+	}
+	for val, counts := range want {
+		have := stats.Validators[val]
+		if have == nil || have.InTurn != counts[0] || have.OutOfTurn != counts[1] || have.Missed != counts[2] {
+			t.Errorf("validator %x: stats mismatch: have %+v, want in-turn/out-of-turn/missed %v", val, have, counts)
+		}
+	}
+	if cached, _ := engine.epochStats(chain, 1); cached != stats {
+		t.Errorf("epoch stats not cached")
+	}
+	gauges := engine.epochGauges(chain)
+	if number := gauges["parlia/epoch/number"]; len(number) != 1 || number[0].Value != uint64(1) {
+		t.Errorf("epoch number gauge mismatch: have %v", number)
+	}
+	sealed := gauges["parlia/epoch/sealed"]
+	if len(sealed) != 3 {
+		t.Fatalf("sealed gauge sample count mismatch: have %d, want 3", len(sealed))
+	}
+	for i, sample := range sealed {
+		if label := sample.Labels["validator"]; label != validators[i].Hex() {
+			t.Errorf("sample %d: validator label mismatch: have %s, want %s", i, label, validators[i].Hex())
+		}
+		if have, want := sample.Value, want[validators[i]][0]+want[validators[i]][1]; have != want {
+			t.Errorf("sample %d: sealed count mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	if latency := gauges["parlia/epoch/latency"]; len(latency) != 1 || latency[0].Labels["validator"] != validators[2].Hex() {
+		t.Errorf("latency gauge mismatch: have %v", latency)
+	}
+}
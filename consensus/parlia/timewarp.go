@@ -0,0 +1,68 @@
+package parlia
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNegativeTimeWarp = errors.New("time can't be warped backwards")
+
+// now returns the time of the engine's clock, the system time unless warped on
+// a developer chain.
+func (p *Parlia) now() time.Time {
+	return time.Now().Add(time.Duration(atomic.LoadInt64(&p.timeOffset)) * time.Millisecond)
+}
+
+// EnableTimeWarp exposes the RPC methods warping the clock of the engine, which
+// the timestamps of the sealed blocks follow. Meant for developer chains running
+// contract test suites, it must be called before the APIs are registered.
+func (p *Parlia) EnableTimeWarp() {
+	p.timeWarp = true
+}
+
+// timeWarpAPIs returns the RPC services warping the clock of the engine.
+func timeWarpAPIs(p *Parlia) []rpc.API {
+	return []rpc.API{{
+		Namespace: "debug",
+		Version:   "1.0",
+		Service:   &TimeWarpDebugAPI{parlia: p},
+		Public:    false,
+	}, {
+		Namespace: "evm",
+		Version:   "1.0",
+		Service:   &TimeWarpEvmAPI{parlia: p},
+		Public:    true,
+	}}
+}
+
+// TimeWarpDebugAPI sets the clock of the engine to an absolute time.
+type TimeWarpDebugAPI struct {
+	parlia *Parlia
+}
+
+// SetTime sets the clock of the engine to the given unix timestamp, returning the
+// offset to the system time in seconds. The timestamps of the sealed blocks never
+// go back behind their parents.
+func (api *TimeWarpDebugAPI) SetTime(timestamp int64) int64 {
+	offset := time.Until(time.Unix(timestamp, 0)).Milliseconds()
+	atomic.StoreInt64(&api.parlia.timeOffset, offset)
+	return offset / 1000
+}
+
+// TimeWarpEvmAPI advances the clock of the engine, in the style of the methods
+// of development chains contract test suites expect.
+type TimeWarpEvmAPI struct {
+	parlia *Parlia
+}
+
+// IncreaseTime advances the clock of the engine by the given number of seconds,
+// returning the total offset to the system time in seconds.
+func (api *TimeWarpEvmAPI) IncreaseTime(seconds int64) (int64, error) {
+	if seconds < 0 {
+		return 0, errNegativeTimeWarp
+	}
+	return atomic.AddInt64(&api.parlia.timeOffset, seconds*1000) / 1000, nil
+}
@@ -0,0 +1,36 @@
+package parlia
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWarp(t *testing.T) {
+	p := &Parlia{}
+	if apis := p.APIs(nil); len(apis) != 2 {
+		t.Fatalf("time warp exposed without being enabled: %d APIs", len(apis))
+	}
+	p.EnableTimeWarp()
+	if apis := p.APIs(nil); len(apis) != 4 {
+		t.Fatalf("time warp APIs missing: %d APIs", len(apis))
+	}
+	evm := &TimeWarpEvmAPI{parlia: p}
+	if offset, err := evm.IncreaseTime(3600); err != nil || offset != 3600 {
+		t.Fatalf("increase time: offset %d, err %v", offset, err)
+	}
+	if offset, _ := evm.IncreaseTime(60); offset != 3660 {
+		t.Fatalf("offset not accumulated: %d", offset)
+	}
+	if _, err := evm.IncreaseTime(-1); err != errNegativeTimeWarp {
+		t.Fatalf("negative warp: have %v, want %v", err, errNegativeTimeWarp)
+	}
+	if drift := time.Until(p.now()) - 3660*time.Second; drift > time.Second || drift < -time.Second {
+		t.Errorf("clock not warped: drift %v", drift)
+	}
+	debug := &TimeWarpDebugAPI{parlia: p}
+	target := time.Now().Add(-time.Hour).Unix()
+	debug.SetTime(target)
+	if now := p.now().Unix(); now < target || now > target+1 {
+		t.Errorf("clock not set: have %d, want %d", now, target)
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package backup implements incremental backups of the chain database.
+//
+// A backup is a sequence of increments kept in a directory or an object
+// storage. Every increment holds the canonical blocks since the previous one
+// together with the trie nodes and contract codes by which the state at its
+// last block differs from the state of the previous increment. The manifest
+// listing the increments is only rewritten after all their files have been
+// stored, so an interrupted backup never damages the existing ones.
+//
+// Published with a signed manifest, e.g. over HTTP, a backup lets new nodes
+// bootstrap from it instead of syncing the chain from the network.
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// manifestName is the name of the file listing the increments of a backup.
+const manifestName = "manifest.json"
+
+var (
+	// ErrNotFound is returned by a Store if the requested file does not exist.
+	ErrNotFound = errors.New("backup file not found")
+
+	errGenesisMismatch = errors.New("backup belongs to a different chain")
+	errReadOnlyStore   = errors.New("backup store is read-only")
+)
+
+// Manifest describes the increments of a backup.
+type Manifest struct {
+	Genesis    common.Hash         `json:"genesis"`
+	Config     *params.ChainConfig `json:"config"`
+	Increments []*Increment        `json:"increments"`
+}
+
+// Increment is a part of a backup holding the chain segment First..Number and
+// the state at block Number.
+type Increment struct {
+	Number  uint64      `json:"number"`
+	Hash    common.Hash `json:"hash"`
+	Root    common.Hash `json:"root"`
+	First   uint64      `json:"first"`
+	Created time.Time   `json:"created"`
+	Nodes   uint64      `json:"nodes"`
+	Codes   uint64      `json:"codes"`
+	Files   []*File     `json:"files"`
+}
+
+// File is a compressed file of an increment holding chain or state records.
+type File struct {
+	Name   string      `json:"name"`
+	Kind   string      `json:"kind"`
+	Size   int64       `json:"size"`
+	SHA256 common.Hash `json:"sha256"`
+}
+
+// chainRecord is a block of the backup in its raw database encoding.
+type chainRecord struct {
+	Header   rlp.RawValue
+	Body     rlp.RawValue
+	Receipts rlp.RawValue
+	Td       rlp.RawValue
+}
+
+// stateRecord is a trie node or a contract code of the backup. Both are keyed
+// by the hash of the blob in the database.
+type stateRecord struct {
+	Code bool
+	Blob []byte
+}
+
+// Store is the storage holding the files of a backup.
+type Store interface {
+	// Get retrieves the content of the file or ErrNotFound.
+	Get(name string) (io.ReadCloser, error)
+
+	// Put stores the content of the reader as the file, replacing any
+	// previous version of it.
+	Put(name string, r io.ReadSeeker, size int64) error
+}
+
+// OpenStore opens the backup kept at the given location, which is either a
+// local directory, an object storage URL of the form s3://bucket/prefix or
+// gs://bucket/prefix, or a read-only http:// or https:// URL.
+func OpenStore(location string) (Store, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewHTTPStore(location)
+	}
+	if rawdb.IsRemoteAncient(location) {
+		store, err := rawdb.NewObjectStore(location)
+		if err != nil {
+			return nil, err
+		}
+		return &objectStore{store}, nil
+	}
+	return NewDirStore(location)
+}
+
+// dirStore is a Store keeping the files in a local directory.
+type dirStore struct {
+	dir string
+}
+
+// NewDirStore creates a Store keeping the files in the given directory.
+func NewDirStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &dirStore{dir: dir}, nil
+}
+
+// Get implements Store.
+func (s *dirStore) Get(name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Put implements Store, replacing the file atomically.
+func (s *dirStore) Put(name string, r io.ReadSeeker, size int64) error {
+	path := filepath.Join(s.dir, name)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// objectStore is a Store keeping the files in an object storage.
+type objectStore struct {
+	store rawdb.ObjectStore
+}
+
+// Get implements Store.
+func (s *objectStore) Get(name string) (io.ReadCloser, error) {
+	r, err := s.store.Get(name)
+	if err == rawdb.ErrObjectNotFound {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+// Put implements Store.
+func (s *objectStore) Put(name string, r io.ReadSeeker, size int64) error {
+	return s.store.Put(name, r, size)
+}
+
+// httpStore is a read-only Store downloading the files from a web server.
+type httpStore struct {
+	base   *url.URL
+	client *http.Client
+}
+
+// NewHTTPStore creates a read-only Store downloading the files from below the
+// given URL.
+func NewHTTPStore(rawurl string) (Store, error) {
+	base, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup url: %v", err)
+	}
+	return &httpStore{base: base, client: new(http.Client)}, nil
+}
+
+// Get implements Store.
+func (s *httpStore) Get(name string) (io.ReadCloser, error) {
+	u := *s.base
+	u.Path = path.Join("/", u.Path, name)
+
+	res, err := s.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", u.Redacted(), res.Status)
+	}
+	return res.Body, nil
+}
+
+// Put implements Store.
+func (s *httpStore) Put(name string, r io.ReadSeeker, size int64) error {
+	return errReadOnlyStore
+}
+
+// readFile retrieves the whole content of a small file of the backup.
+func readFile(store Store, name string) ([]byte, error) {
+	r, err := store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// ReadManifest retrieves the manifest of the backup in the store. An empty
+// manifest is returned if the store holds no backup yet.
+func ReadManifest(store Store) (*Manifest, error) {
+	blob, err := readFile(store, manifestName)
+	if err == ErrNotFound {
+		return new(Manifest), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseManifest(blob)
+}
+
+// parseManifest decodes the manifest of a backup.
+func parseManifest(blob []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(blob, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+// writeManifest stores the manifest of the backup.
+func writeManifest(store Store, manifest *Manifest) error {
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(manifestName, bytes.NewReader(blob), int64(len(blob)))
+}
+
+// find returns the increments needed to restore the chain up to the given
+// block, or up to the latest increment if number is nil.
+func (m *Manifest) find(number *uint64) ([]*Increment, error) {
+	if len(m.Increments) == 0 {
+		return nil, errors.New("backup holds no increments")
+	}
+	if number == nil {
+		return m.Increments, nil
+	}
+	for i, inc := range m.Increments {
+		if inc.Number == *number {
+			return m.Increments[:i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("backup holds no increment ending at block #%d", *number)
+}
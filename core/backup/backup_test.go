@@ -0,0 +1,255 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContract = common.Address{0xff}
+
+	// storeCode stores the block number into the slot of the block number.
+	storeCode = common.FromHex("434355")
+
+	// deployCode deploys a contract with storeCode as its code.
+	deployCode = common.FromHex("624343556000526003601df3")
+)
+
+// newTestDatabase creates a database with a freezer in a temporary directory.
+func newTestDatabase(t *testing.T) ethdb.Database {
+	db, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false, false, false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestChain creates an archive chain of the given length whose blocks
+// fund a new account, write a new storage slot and deploy a contract.
+func newTestChain(t *testing.T, n int) (ethdb.Database, []*types.Block) {
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddr:     {Balance: big.NewInt(1000000000000000000)},
+			testContract: {Balance: big.NewInt(1), Code: storeCode},
+		},
+	}
+	var (
+		db      = newTestDatabase(t)
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(params.TestChainConfig)
+		price   = big.NewInt(1)
+	)
+	gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), gendb, n, func(i int, gen *core.BlockGen) {
+		txs := []*types.Transaction{
+			types.NewTransaction(gen.TxNonce(testAddr), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, price, nil),
+			types.NewTransaction(gen.TxNonce(testAddr)+1, testContract, nil, 100000, price, nil),
+		}
+		if i%4 == 0 {
+			txs = append(txs, types.NewContractCreation(gen.TxNonce(testAddr)+2, nil, 100000, price, deployCode))
+		}
+		for _, tx := range txs {
+			signed, err := types.SignTx(tx, signer, testKey)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			gen.AddTx(signed)
+		}
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return db, blocks
+}
+
+// checkRestored verifies that the restored database holds the chain up to the
+// given block with its complete state.
+func checkRestored(t *testing.T, db ethdb.Database, want *types.Block) {
+	t.Helper()
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to open restored chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock(); head.Hash() != want.Hash() {
+		t.Fatalf("head mismatch: have #%d %x, want #%d %x", head.NumberU64(), head.Hash(), want.NumberU64(), want.Hash())
+	}
+	for number := uint64(0); number <= want.NumberU64(); number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			t.Fatalf("block #%d missing", number)
+		}
+		if receipts := chain.GetReceiptsByHash(block.Hash()); len(receipts) != len(block.Transactions()) {
+			t.Fatalf("block #%d: receipt count mismatch: have %d, want %d", number, len(receipts), len(block.Transactions()))
+		}
+	}
+	if tx := want.Transactions()[0]; rawdb.ReadTxLookupEntry(db, tx.Hash()) == nil {
+		t.Fatalf("transaction %x not indexed", tx.Hash())
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open restored state: %v", err)
+	}
+	number := want.NumberU64()
+	if have := statedb.GetState(testContract, common.BigToHash(want.Number())); have != common.BigToHash(want.Number()) {
+		t.Fatalf("storage slot of block #%d mismatch: have %x", number, have)
+	}
+	if have := statedb.GetBalance(common.Address{byte(number - 1)}); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("balance of account #%d mismatch: have %v", number-1, have)
+	}
+	if have := statedb.GetCode(crypto.CreateAddress(testAddr, 2)); string(have) != string(storeCode) {
+		t.Fatalf("deployed code mismatch: have %x", have)
+	}
+	res, err := state.VerifyState(db, want.Root(), 2, nil)
+	if err != nil || !res.Healthy() {
+		t.Fatalf("restored state damaged: %v %+v", err, res)
+	}
+}
+
+// Tests that incremental backups are restored up to any of their increments.
+func TestBackupRestore(t *testing.T) {
+	db, blocks := newTestChain(t, 24)
+
+	store, err := NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	number := uint64(10)
+	if inc, err := Create(db, store, &number, nil); err != nil {
+		t.Fatalf("failed to create first increment: %v", err)
+	} else if inc.Number != 10 || inc.First != 0 || inc.Hash != blocks[9].Hash() {
+		t.Fatalf("first increment mismatch: have #%d-#%d %x", inc.First, inc.Number, inc.Hash)
+	}
+	if _, err := Create(db, store, &number, nil); err == nil {
+		t.Fatalf("backed up block #%d twice", number)
+	}
+	inc, err := Create(db, store, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create second increment: %v", err)
+	}
+	if inc.Number != 24 || inc.First != 11 || inc.Hash != blocks[23].Hash() {
+		t.Fatalf("second increment mismatch: have #%d-#%d %x", inc.First, inc.Number, inc.Hash)
+	}
+	// The second increment only holds the state changed since the first one
+	manifest, err := ReadManifest(store)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if full := countNodes(t, db, blocks[23].Root()); inc.Nodes >= full {
+		t.Errorf("increment not incremental: %d nodes, %d in the full state", inc.Nodes, full)
+	}
+	if len(manifest.Increments) != 2 || manifest.Genesis != rawdb.ReadCanonicalHash(db, 0) {
+		t.Fatalf("manifest mismatch: %+v", manifest)
+	}
+	// Restore both the latest and the first increment, partly into the freezer
+	defer func(threshold uint64) { immutabilityThreshold = threshold }(immutabilityThreshold)
+	immutabilityThreshold = 8
+
+	restored := newTestDatabase(t)
+	if _, err := Restore(restored, store, nil, 2); err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	if frozen, _ := restored.Ancients(); frozen != 16 {
+		t.Errorf("frozen blocks mismatch: have %d, want %d", frozen, 16)
+	}
+	checkRestored(t, restored, blocks[23])
+
+	if _, err := Restore(restored, store, nil, 2); err != errNotEmpty {
+		t.Errorf("restore into a used database: have %v, want %v", err, errNotEmpty)
+	}
+	restored = newTestDatabase(t)
+	if _, err := Restore(restored, store, &number, 2); err != nil {
+		t.Fatalf("failed to restore first increment: %v", err)
+	}
+	checkRestored(t, restored, blocks[9])
+}
+
+// countNodes returns the number of trie nodes of a state.
+func countNodes(t *testing.T, db ethdb.Database, root common.Hash) uint64 {
+	res, err := state.VerifyState(db, root, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to verify state: %v", err)
+	}
+	return res.Nodes
+}
+
+// Tests that damaged backup files are detected before anything is restored.
+func TestRestoreCorrupted(t *testing.T) {
+	db, _ := newTestChain(t, 4)
+
+	dir := t.TempDir()
+	store, err := NewDirStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	inc, err := Create(db, store, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	path := filepath.Join(dir, inc.Files[0].Name)
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read backup file: %v", err)
+	}
+	blob[len(blob)/2] ^= 0xff
+	if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+		t.Fatalf("failed to damage backup file: %v", err)
+	}
+	restored := newTestDatabase(t)
+	if _, err := Restore(restored, store, nil, 1); err == nil {
+		t.Fatal("restored damaged backup")
+	}
+	if hash := rawdb.ReadHeadBlockHash(restored); hash != (common.Hash{}) {
+		t.Fatalf("damaged backup set head %x", hash)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove backup file: %v", err)
+	}
+	if _, err := Restore(newTestDatabase(t), store, nil, 1); err == nil {
+		t.Fatal("restored incomplete backup")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// signatureName is the name of the file holding the signature of the manifest.
+const signatureName = "manifest.sig"
+
+var (
+	errNoTrust         = errors.New("no trusted signers or checkpoint to verify the backup against")
+	errUntrustedBackup = errors.New("backup not signed by a trusted signer")
+)
+
+// Checkpoint is a finalized block a downloaded backup must end at.
+type Checkpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// ParseCheckpoint parses a checkpoint of the form <number>:<hash>.
+func ParseCheckpoint(s string) (*Checkpoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid checkpoint %q, want <number>:<hash>", s)
+	}
+	number, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint number %q", parts[0])
+	}
+	hash, err := hexutil.Decode(parts[1])
+	if err != nil || len(hash) != common.HashLength {
+		return nil, fmt.Errorf("invalid checkpoint hash %q", parts[1])
+	}
+	return &Checkpoint{Number: number, Hash: common.BytesToHash(hash)}, nil
+}
+
+// Trust is what a downloaded backup is verified against before restoring it.
+// A backup is accepted if its manifest is signed by one of the signers, or if
+// it ends at the checkpoint. If both are given, both must hold.
+type Trust struct {
+	Signers    []common.Address
+	Checkpoint *Checkpoint
+}
+
+// Sign signs the manifest of the backup in the store with the given key, for
+// the nodes bootstrapping from it to trust it. The backup must be signed again
+// whenever an increment is added.
+func Sign(store Store, key *ecdsa.PrivateKey) error {
+	blob, err := readFile(store, manifestName)
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(blob), key)
+	if err != nil {
+		return err
+	}
+	enc := []byte(hexutil.Encode(sig))
+	if err := store.Put(signatureName, bytes.NewReader(enc), int64(len(enc))); err != nil {
+		return err
+	}
+	log.Info("Signed backup manifest", "signer", crypto.PubkeyToAddress(key.PublicKey))
+	return nil
+}
+
+// signer recovers the address which signed the manifest.
+func signer(store Store, blob []byte) (common.Address, error) {
+	enc, err := readFile(store, signatureName)
+	if err == ErrNotFound {
+		return common.Address{}, errUntrustedBackup
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+	sig, err := hexutil.Decode(string(bytes.TrimSpace(enc)))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid manifest signature: %v", err)
+	}
+	pub, err := crypto.SigToPub(crypto.Keccak256(blob), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid manifest signature: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Bootstrap restores a downloaded backup into an empty database after having
+// verified it against the trusted signers or checkpoint. Without a checkpoint,
+// the backup is restored up to its latest increment.
+func Bootstrap(db ethdb.Database, store Store, trust Trust, threads int) (*Increment, error) {
+	if len(trust.Signers) == 0 && trust.Checkpoint == nil {
+		return nil, errNoTrust
+	}
+	blob, err := readFile(store, manifestName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve backup manifest: %v", err)
+	}
+	if len(trust.Signers) > 0 {
+		addr, err := signer(store, blob)
+		if err != nil {
+			return nil, err
+		}
+		trusted := false
+		for _, signer := range trust.Signers {
+			if signer == addr {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			return nil, fmt.Errorf("%w: signed by %v", errUntrustedBackup, addr)
+		}
+		log.Info("Verified backup signature", "signer", addr)
+	}
+	manifest, err := parseManifest(blob)
+	if err != nil {
+		return nil, err
+	}
+	var number *uint64
+	if trust.Checkpoint != nil {
+		number = &trust.Checkpoint.Number
+	}
+	incs, err := manifest.find(number)
+	if err != nil {
+		return nil, err
+	}
+	if cp := trust.Checkpoint; cp != nil && incs[len(incs)-1].Hash != cp.Hash {
+		return nil, fmt.Errorf("backup block #%d mismatches checkpoint: have %x, want %x", cp.Number, incs[len(incs)-1].Hash, cp.Hash)
+	}
+	return restore(db, store, manifest, incs, threads)
+}
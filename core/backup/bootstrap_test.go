@@ -0,0 +1,126 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that nodes only bootstrap from backups matching their trust anchors.
+func TestBootstrap(t *testing.T) {
+	db, blocks := newTestChain(t, 12)
+
+	dir := t.TempDir()
+	store, err := NewDirStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	number := uint64(6)
+	if _, err := Create(db, store, &number, nil); err != nil {
+		t.Fatalf("failed to create first increment: %v", err)
+	}
+	if _, err := Create(db, store, nil, nil); err != nil {
+		t.Fatalf("failed to create second increment: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	if err := Sign(store, key); err != nil {
+		t.Fatalf("failed to sign backup: %v", err)
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	remote, err := OpenStore(server.URL)
+	if err != nil {
+		t.Fatalf("failed to open http store: %v", err)
+	}
+	var (
+		signer   = crypto.PubkeyToAddress(key.PublicKey)
+		stranger = common.Address{0x01}
+	)
+	tests := []struct {
+		trust Trust
+		want  *Increment
+		err   error
+	}{
+		{trust: Trust{}, err: errNoTrust},
+		{trust: Trust{Signers: []common.Address{stranger}}, err: errUntrustedBackup},
+		{trust: Trust{Checkpoint: &Checkpoint{Number: 6, Hash: blocks[4].Hash()}}, err: errors.New("mismatch")},
+		{trust: Trust{Checkpoint: &Checkpoint{Number: 5, Hash: blocks[4].Hash()}}, err: errors.New("no increment")},
+		{trust: Trust{Signers: []common.Address{stranger, signer}}, want: &Increment{Number: 12, Hash: blocks[11].Hash()}},
+		{trust: Trust{Checkpoint: &Checkpoint{Number: 6, Hash: blocks[5].Hash()}}, want: &Increment{Number: 6, Hash: blocks[5].Hash()}},
+	}
+	for i, tt := range tests {
+		restored := newTestDatabase(t)
+		inc, err := Bootstrap(restored, remote, tt.trust, 2)
+		if tt.err != nil {
+			if err == nil {
+				t.Errorf("test %d: bootstrapped untrusted backup", i)
+			} else if errors.Is(tt.err, errNoTrust) || errors.Is(tt.err, errUntrustedBackup) {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to bootstrap: %v", i, err)
+			continue
+		}
+		if inc.Number != tt.want.Number || inc.Hash != tt.want.Hash {
+			t.Errorf("test %d: increment mismatch: have #%d %x, want #%d %x", i, inc.Number, inc.Hash, tt.want.Number, tt.want.Hash)
+		}
+		checkRestored(t, restored, blocks[tt.want.Number-1])
+	}
+	// Rewriting the manifest invalidates the signature
+	manifest, err := ReadManifest(store)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	manifest.Increments = manifest.Increments[:1]
+	if err := writeManifest(store, manifest); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	if _, err := Bootstrap(newTestDatabase(t), remote, Trust{Signers: []common.Address{signer}}, 2); !errors.Is(err, errUntrustedBackup) {
+		t.Errorf("stale signature: have %v, want %v", err, errUntrustedBackup)
+	}
+	if err := remote.Put(manifestName, nil, 0); err != errReadOnlyStore {
+		t.Errorf("http store write: have %v, want %v", err, errReadOnlyStore)
+	}
+}
+
+// Tests the parsing of bootstrap checkpoints.
+func TestParseCheckpoint(t *testing.T) {
+	hash := common.HexToHash("0x4800141b2be6fd0ba49a69306ade2d40524e7841c1d5dcc54ccac69560a6a27e")
+	cp, err := ParseCheckpoint("1234:" + hash.Hex())
+	if err != nil {
+		t.Fatalf("failed to parse checkpoint: %v", err)
+	}
+	if cp.Number != 1234 || cp.Hash != hash {
+		t.Errorf("checkpoint mismatch: have %d:%x", cp.Number, cp.Hash)
+	}
+	for _, s := range []string{"", "1234", "x:" + hash.Hex(), "1234:0x1234", "1:2:3"} {
+		if _, err := ParseCheckpoint(s); err == nil {
+			t.Errorf("parsed invalid checkpoint %q", s)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256(nil)
+)
+
+// retryReader is a reader over the database of a running node, catching up
+// with the node once before reporting an item as missing.
+type retryReader struct {
+	ethdb.KeyValueStore
+	refresh func() error
+}
+
+// Get implements ethdb.KeyValueReader.
+func (r *retryReader) Get(key []byte) ([]byte, error) {
+	blob, err := r.KeyValueStore.Get(key)
+	if err != nil && r.refresh != nil {
+		if err := r.refresh(); err != nil {
+			return nil, err
+		}
+		blob, err = r.KeyValueStore.Get(key)
+	}
+	return blob, err
+}
+
+// Create appends an increment to the backup in the store, holding the chain
+// since the previous increment up to the given block and the state at that
+// block. If number is nil, the most recent block whose state is on disk is
+// used. The database may belong to a running node, in which case refresh is
+// invoked to catch up with it whenever data moved while being read.
+func Create(db ethdb.Database, store Store, number *uint64, refresh func() error) (*Increment, error) {
+	manifest, err := ReadManifest(store)
+	if err != nil {
+		return nil, err
+	}
+	if err := catchUp(refresh); err != nil {
+		return nil, err
+	}
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("database holds no genesis block")
+	}
+	if len(manifest.Increments) == 0 {
+		manifest.Genesis, manifest.Config = genesis, rawdb.ReadChainConfig(db, genesis)
+	} else if manifest.Genesis != genesis {
+		return nil, errGenesisMismatch
+	}
+	var (
+		inc      = &Increment{Created: time.Now().UTC()}
+		prevHash common.Hash
+		prevRoot = emptyRoot
+		start    = time.Now()
+	)
+	if n := len(manifest.Increments); n > 0 {
+		prev := manifest.Increments[n-1]
+		if rawdb.ReadCanonicalHash(db, prev.Number) != prev.Hash {
+			return nil, fmt.Errorf("block #%d of the previous increment is no longer canonical", prev.Number)
+		}
+		inc.First, prevHash, prevRoot = prev.Number+1, prev.Hash, prev.Root
+	}
+	header, err := backupTarget(db, number, inc.First)
+	if err != nil {
+		return nil, err
+	}
+	inc.Number, inc.Hash, inc.Root = header.Number.Uint64(), header.Hash(), header.Root
+
+	log.Info("Creating backup increment", "first", inc.First, "number", inc.Number, "hash", inc.Hash, "root", inc.Root)
+	seq := len(manifest.Increments)
+	if err := writeChain(db, store, inc, seq, prevHash, refresh); err != nil {
+		return nil, err
+	}
+	if err := writeState(db, store, inc, seq, prevRoot, refresh); err != nil {
+		return nil, err
+	}
+	// The blocks were read one by one, make sure they are still canonical
+	if err := catchUp(refresh); err != nil {
+		return nil, err
+	}
+	if rawdb.ReadCanonicalHash(db, inc.Number) != inc.Hash {
+		return nil, fmt.Errorf("chain reorganised below block #%d during the backup", inc.Number)
+	}
+	manifest.Increments = append(manifest.Increments, inc)
+	if err := writeManifest(store, manifest); err != nil {
+		return nil, err
+	}
+	log.Info("Created backup increment", "number", inc.Number, "hash", inc.Hash, "files", len(inc.Files),
+		"nodes", inc.Nodes, "codes", inc.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return inc, nil
+}
+
+// catchUp invokes the refresh callback, if any.
+func catchUp(refresh func() error) error {
+	if refresh == nil {
+		return nil
+	}
+	return refresh()
+}
+
+// hasState returns whether the root node of the state is on disk.
+func hasState(db ethdb.KeyValueReader, root common.Hash) bool {
+	return root == emptyRoot || len(rawdb.ReadTrieNode(db, root)) > 0
+}
+
+// backupTarget returns the header of the block to back up. Without an explicit
+// number, it is the most recent block since first whose state is on disk.
+func backupTarget(db ethdb.Database, number *uint64, first uint64) (*types.Header, error) {
+	if number != nil {
+		if *number < first {
+			return nil, fmt.Errorf("block #%d is already backed up", *number)
+		}
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, *number), *number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", *number)
+		}
+		if !hasState(db, header.Root) {
+			return nil, fmt.Errorf("state of block #%d is not available", *number)
+		}
+		return header, nil
+	}
+	header := rawdb.ReadHeadHeader(db)
+	if block := rawdb.ReadHeadBlock(db); block != nil {
+		header = block.Header()
+	}
+	for header != nil && header.Number.Uint64() >= first {
+		if hasState(db, header.Root) {
+			return header, nil
+		}
+		if header.Number.Uint64() == 0 {
+			break
+		}
+		header = rawdb.ReadHeader(db, header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil, errors.New("no block with its state on disk since the previous increment")
+}
+
+// readBlock retrieves a canonical block in its raw database encoding.
+func readBlock(db ethdb.Database, number uint64, refresh func() error) (*chainRecord, *types.Header, error) {
+	for attempt := 0; ; attempt++ {
+		var (
+			hash = rawdb.ReadCanonicalHash(db, number)
+			rec  = &chainRecord{
+				Header:   rawdb.ReadHeaderRLP(db, hash, number),
+				Body:     rawdb.ReadBodyRLP(db, hash, number),
+				Receipts: rawdb.ReadReceiptsRLP(db, hash, number),
+				Td:       rawdb.ReadTdRLP(db, hash, number),
+			}
+		)
+		if hash != (common.Hash{}) && len(rec.Header) > 0 && len(rec.Body) > 0 && len(rec.Receipts) > 0 && len(rec.Td) > 0 {
+			header := new(types.Header)
+			if err := rlp.DecodeBytes(rec.Header, header); err != nil {
+				return nil, nil, fmt.Errorf("invalid header of block #%d: %v", number, err)
+			}
+			return rec, header, nil
+		}
+		// Parts of the block may have moved into the freezer meanwhile
+		if attempt > 0 || refresh == nil {
+			return nil, nil, fmt.Errorf("block #%d is not available", number)
+		}
+		if err := refresh(); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// writeChain backs up the canonical blocks of the increment.
+func writeChain(db ethdb.Database, store Store, inc *Increment, seq int, parent common.Hash, refresh func() error) error {
+	w := newFileWriter(store, inc, seq, "chain")
+	defer w.discard()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := inc.First; number <= inc.Number; number++ {
+		rec, header, err := readBlock(db, number, refresh)
+		if err != nil {
+			return err
+		}
+		if number > 0 && header.ParentHash != parent {
+			return fmt.Errorf("chain reorganised at block #%d during the backup", number)
+		}
+		if err := w.write(rec); err != nil {
+			return err
+		}
+		parent = header.Hash()
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Backing up chain", "number", number, "last", inc.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if parent != inc.Hash {
+		return fmt.Errorf("chain reorganised at block #%d during the backup", inc.Number)
+	}
+	return w.flush()
+}
+
+// stateWriter backs up the trie nodes and codes by which a state differs
+// from the state of the previous increment.
+type stateWriter struct {
+	w      *fileWriter
+	inc    *Increment
+	reader ethdb.KeyValueReader
+	triedb *trie.Database
+	codes  map[common.Hash]struct{}
+
+	start  time.Time
+	logged time.Time
+}
+
+// writeState backs up the state of the increment.
+func writeState(db ethdb.Database, store Store, inc *Increment, seq int, prevRoot common.Hash, refresh func() error) error {
+	reader := &retryReader{KeyValueStore: db, refresh: refresh}
+	s := &stateWriter{
+		w:      newFileWriter(store, inc, seq, "state"),
+		inc:    inc,
+		reader: reader,
+		triedb: trie.NewDatabaseWithConfig(reader, &trie.Config{Cache: 16}),
+		codes:  make(map[common.Hash]struct{}),
+		start:  time.Now(),
+		logged: time.Now(),
+	}
+	defer s.w.discard()
+
+	if !hasState(db, prevRoot) {
+		log.Warn("State of the previous increment is missing, backing up the whole state", "root", prevRoot)
+		prevRoot = emptyRoot
+	}
+	if err := s.diff(prevRoot, inc.Root, true); err != nil {
+		return err
+	}
+	return s.w.flush()
+}
+
+// diff writes the nodes of the trie with the given root which are missing
+// from the trie with the previous root. For the account trie, the storage
+// tries and the codes of the changed accounts are written too.
+func (s *stateWriter) diff(prevRoot, root common.Hash, accounts bool) error {
+	prevTrie, err := trie.New(prevRoot, s.triedb)
+	if err != nil {
+		return err
+	}
+	newTrie, err := trie.New(root, s.triedb)
+	if err != nil {
+		return err
+	}
+	it, _ := trie.NewDifferenceIterator(prevTrie.NodeIterator(nil), newTrie.NodeIterator(nil))
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			blob, err := s.triedb.Node(hash)
+			if err != nil {
+				return err
+			}
+			if err := s.w.write(&stateRecord{Blob: blob}); err != nil {
+				return err
+			}
+			s.inc.Nodes++
+		}
+		if !accounts || !it.Leaf() {
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(it.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		prevStorage, prevCode, err := s.prevAccount(prevRoot, it.LeafKey())
+		if err != nil {
+			return err
+		}
+		if acc.Root != prevStorage {
+			if err := s.diff(prevStorage, acc.Root, false); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCode) && !bytes.Equal(acc.CodeHash, prevCode) {
+			if err := s.writeCode(common.BytesToHash(acc.CodeHash)); err != nil {
+				return err
+			}
+		}
+		if time.Since(s.logged) > 8*time.Second {
+			log.Info("Backing up state", "at", common.BytesToHash(it.LeafKey()), "nodes", s.inc.Nodes, "codes", s.inc.Codes,
+				"elapsed", common.PrettyDuration(time.Since(s.start)))
+			s.logged = time.Now()
+		}
+	}
+	return it.Error()
+}
+
+// prevAccount returns the storage root and the code hash of an account in the
+// previous state. A fresh trie is used for every lookup, to not accumulate the
+// resolved nodes in memory.
+func (s *stateWriter) prevAccount(prevRoot common.Hash, key []byte) (common.Hash, []byte, error) {
+	if prevRoot == emptyRoot {
+		return emptyRoot, emptyCode, nil
+	}
+	t, err := trie.New(prevRoot, s.triedb)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	blob, err := t.TryGet(key)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	if len(blob) == 0 {
+		return emptyRoot, emptyCode, nil
+	}
+	var acc state.Account
+	if err := rlp.DecodeBytes(blob, &acc); err != nil {
+		return common.Hash{}, nil, err
+	}
+	return acc.Root, acc.CodeHash, nil
+}
+
+// writeCode writes a contract code, once per increment.
+func (s *stateWriter) writeCode(hash common.Hash) error {
+	if _, ok := s.codes[hash]; ok {
+		return nil
+	}
+	code := rawdb.ReadCode(s.reader, hash)
+	if len(code) == 0 {
+		return fmt.Errorf("missing code %x", hash)
+	}
+	if err := s.w.write(&stateRecord{Code: true, Blob: code}); err != nil {
+		return err
+	}
+	s.codes[hash] = struct{}{}
+	s.inc.Codes++
+	return nil
+}
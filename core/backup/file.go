@@ -0,0 +1,173 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// fileLimit is the compressed size after which the records of an increment
+// continue in a new file, keeping the uploads below the object size limits.
+const fileLimit = 256 * 1024 * 1024
+
+// counter is a writer counting the bytes written into it.
+type counter int64
+
+func (c *counter) Write(p []byte) (int, error) {
+	*c += counter(len(p))
+	return len(p), nil
+}
+
+// fileWriter writes the records of one kind into the files of an increment,
+// staging each file in a temporary one before storing it.
+type fileWriter struct {
+	store Store
+	inc   *Increment
+	seq   int
+	kind  string
+	part  int
+
+	file   *os.File
+	gz     *gzip.Writer
+	hasher hash.Hash
+	size   counter
+}
+
+func newFileWriter(store Store, inc *Increment, seq int, kind string) *fileWriter {
+	return &fileWriter{store: store, inc: inc, seq: seq, kind: kind}
+}
+
+// write appends a record, storing the current file once it is large enough.
+func (w *fileWriter) write(record interface{}) error {
+	if w.file == nil {
+		file, err := ioutil.TempFile("", "geth-backup-")
+		if err != nil {
+			return err
+		}
+		w.file, w.hasher, w.size = file, sha256.New(), 0
+		w.gz = gzip.NewWriter(io.MultiWriter(w.file, w.hasher, &w.size))
+	}
+	if err := rlp.Encode(w.gz, record); err != nil {
+		return err
+	}
+	if w.size >= fileLimit {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush stores the current file, if any, and adds it to the increment.
+func (w *fileWriter) flush() error {
+	if w.file == nil {
+		return nil
+	}
+	defer w.discard()
+
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%06d-%s-%04d.rlp.gz", w.seq, w.kind, w.part)
+	if err := w.store.Put(name, w.file, int64(w.size)); err != nil {
+		return fmt.Errorf("failed to store %s: %v", name, err)
+	}
+	w.inc.Files = append(w.inc.Files, &File{
+		Name:   name,
+		Kind:   w.kind,
+		Size:   int64(w.size),
+		SHA256: common.BytesToHash(w.hasher.Sum(nil)),
+	})
+	w.part++
+	return nil
+}
+
+// discard removes the temporary file, if any.
+func (w *fileWriter) discard() {
+	if w.file != nil {
+		w.file.Close()
+		os.Remove(w.file.Name())
+		w.file = nil
+	}
+}
+
+// fileReader iterates over the records of a file of a backup, which has been
+// downloaded into a temporary file and verified against the manifest.
+type fileReader struct {
+	file   *os.File
+	gz     *gzip.Reader
+	stream *rlp.Stream
+}
+
+func openFile(store Store, file *File) (*fileReader, error) {
+	r, err := store.Get(file.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve %s: %v", file.Name, err)
+	}
+	defer r.Close()
+
+	tmp, err := ioutil.TempFile("", "geth-backup-")
+	if err != nil {
+		return nil, err
+	}
+	reader := &fileReader{file: tmp}
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if err != nil {
+		reader.close()
+		return nil, fmt.Errorf("failed to retrieve %s: %v", file.Name, err)
+	}
+	if size != file.Size {
+		reader.close()
+		return nil, fmt.Errorf("size mismatch of %s: have %d, want %d", file.Name, size, file.Size)
+	}
+	if sum := common.BytesToHash(hasher.Sum(nil)); sum != file.SHA256 {
+		reader.close()
+		return nil, fmt.Errorf("checksum mismatch of %s: have %x, want %x", file.Name, sum, file.SHA256)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		reader.close()
+		return nil, err
+	}
+	if reader.gz, err = gzip.NewReader(tmp); err != nil {
+		reader.close()
+		return nil, fmt.Errorf("failed to decompress %s: %v", file.Name, err)
+	}
+	reader.stream = rlp.NewStream(reader.gz, 0)
+	return reader, nil
+}
+
+// next decodes the next record, returning io.EOF at the end of the file.
+func (r *fileReader) next(record interface{}) error {
+	return r.stream.Decode(record)
+}
+
+// close removes the temporary file.
+func (r *fileReader) close() {
+	r.file.Close()
+	os.Remove(r.file.Name())
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errNotEmpty is returned if a backup is restored into a database already
+// holding a chain.
+var errNotEmpty = errors.New("database is not empty")
+
+// immutabilityThreshold is the depth after which restored blocks are written
+// into the freezer.
+var immutabilityThreshold = uint64(params.FullImmutabilityThreshold)
+
+// Restore imports the backup in the store into an empty database, up to the
+// increment ending at the given block or up to the latest one if number is
+// nil. Every file is verified against the manifest, every block against its
+// header and the whole state is verified before the head of the chain is set.
+func Restore(db ethdb.Database, store Store, number *uint64, threads int) (*Increment, error) {
+	manifest, err := ReadManifest(store)
+	if err != nil {
+		return nil, err
+	}
+	incs, err := manifest.find(number)
+	if err != nil {
+		return nil, err
+	}
+	return restore(db, store, manifest, incs, threads)
+}
+
+// restore imports the given increments of the backup into an empty database.
+func restore(db ethdb.Database, store Store, manifest *Manifest, incs []*Increment, threads int) (*Increment, error) {
+	if err := checkEmpty(db, manifest.Genesis); err != nil {
+		return nil, err
+	}
+	target := incs[len(incs)-1]
+	log.Info("Restoring backup", "increments", len(incs), "number", target.Number, "hash", target.Hash, "root", target.Root)
+
+	imp := &importer{
+		db:      db,
+		batch:   db.NewBatch(),
+		genesis: manifest.Genesis,
+		start:   time.Now(),
+		logged:  time.Now(),
+	}
+	// Blocks past the immutability threshold go right into the freezer
+	if target.Number > immutabilityThreshold {
+		imp.ancientLimit = target.Number - immutabilityThreshold
+	}
+	for _, inc := range incs {
+		if inc.First != imp.next {
+			return nil, fmt.Errorf("increment ending at block #%d does not continue the chain", inc.Number)
+		}
+		for _, kind := range []string{"chain", "state"} {
+			for _, file := range inc.Files {
+				if file.Kind != kind {
+					continue
+				}
+				if err := imp.importFile(store, file); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if imp.hash != inc.Hash {
+			return nil, fmt.Errorf("increment ending at block #%d: hash mismatch: have %x, want %x", inc.Number, imp.hash, inc.Hash)
+		}
+	}
+	if err := imp.batch.Write(); err != nil {
+		return nil, err
+	}
+	if imp.ancientLimit > 0 {
+		if err := db.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	if rawdb.ReadChainConfig(db, manifest.Genesis) == nil && manifest.Config != nil {
+		rawdb.WriteChainConfig(db, manifest.Genesis, manifest.Config)
+	}
+	// Only make the chain visible once the state is known to be complete
+	res, err := state.VerifyState(db, target.Root, threads, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Healthy() {
+		return nil, fmt.Errorf("restored state of block #%d is damaged: %d damaged nodes, %d missing codes",
+			target.Number, len(res.Issues), len(res.MissingCodes))
+	}
+	rawdb.IndexTransactions(db, 0, target.Number+1, nil)
+
+	rawdb.WriteHeadHeaderHash(db, target.Hash)
+	rawdb.WriteHeadFastBlockHash(db, target.Hash)
+	rawdb.WriteHeadBlockHash(db, target.Hash)
+
+	log.Info("Restored backup", "number", target.Number, "hash", target.Hash, "nodes", imp.nodes, "codes", imp.codes,
+		"elapsed", common.PrettyDuration(time.Since(imp.start)))
+	return target, nil
+}
+
+// checkEmpty ensures that the database holds at most the genesis block of the
+// chain in the backup.
+func checkEmpty(db ethdb.Database, genesis common.Hash) error {
+	if frozen, err := db.Ancients(); err == nil && frozen > 0 {
+		return errNotEmpty
+	}
+	if number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db)); number != nil && *number > 0 {
+		return errNotEmpty
+	}
+	if hash := rawdb.ReadCanonicalHash(db, 0); hash != (common.Hash{}) && hash != genesis {
+		return errGenesisMismatch
+	}
+	return nil
+}
+
+// importer writes the verified content of the backup files into the database.
+type importer struct {
+	db           ethdb.Database
+	batch        ethdb.Batch
+	genesis      common.Hash
+	ancientLimit uint64 // Blocks below are written into the freezer
+
+	next uint64      // Number of the next block to import
+	hash common.Hash // Hash of the last imported block
+	td   *big.Int    // Total difficulty of the last imported block
+
+	nodes  uint64
+	codes  uint64
+	start  time.Time
+	logged time.Time
+}
+
+// importFile imports the records of a file of the backup.
+func (imp *importer) importFile(store Store, file *File) error {
+	r, err := openFile(store, file)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	for {
+		switch file.Kind {
+		case "chain":
+			var rec chainRecord
+			if err := r.next(&rec); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("corrupt %s: %v", file.Name, err)
+			}
+			if err := imp.importBlock(&rec); err != nil {
+				return err
+			}
+		case "state":
+			var rec stateRecord
+			if err := r.next(&rec); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("corrupt %s: %v", file.Name, err)
+			}
+			imp.importState(&rec)
+		default:
+			return fmt.Errorf("unknown kind %q of %s", file.Kind, file.Name)
+		}
+		if imp.batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := imp.batch.Write(); err != nil {
+				return err
+			}
+			imp.batch.Reset()
+		}
+		if time.Since(imp.logged) > 8*time.Second {
+			log.Info("Restoring backup", "blocks", imp.next, "nodes", imp.nodes, "codes", imp.codes,
+				"elapsed", common.PrettyDuration(time.Since(imp.start)))
+			imp.logged = time.Now()
+		}
+	}
+}
+
+// importBlock verifies a block against its header and its parent, then
+// writes it into the database.
+func (imp *importer) importBlock(rec *chainRecord) error {
+	number := imp.next
+
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(rec.Header, header); err != nil {
+		return fmt.Errorf("block #%d: invalid header: %v", number, err)
+	}
+	hash := header.Hash()
+	if header.Number.Uint64() != number {
+		return fmt.Errorf("block #%d: unexpected number #%d", number, header.Number)
+	}
+	if number == 0 && hash != imp.genesis {
+		return errGenesisMismatch
+	}
+	if number > 0 && header.ParentHash != imp.hash {
+		return fmt.Errorf("block #%d: does not extend block %x", number, imp.hash)
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(rec.Body, body); err != nil {
+		return fmt.Errorf("block #%d: invalid body: %v", number, err)
+	}
+	if root := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); root != header.TxHash {
+		return fmt.Errorf("block #%d: transaction root mismatch: have %x, want %x", number, root, header.TxHash)
+	}
+	if uncles := types.CalcUncleHash(body.Uncles); uncles != header.UncleHash {
+		return fmt.Errorf("block #%d: uncle hash mismatch: have %x, want %x", number, uncles, header.UncleHash)
+	}
+	var stored []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(rec.Receipts, &stored); err != nil {
+		return fmt.Errorf("block #%d: invalid receipts: %v", number, err)
+	}
+	if len(stored) != len(body.Transactions) {
+		return fmt.Errorf("block #%d: receipt count mismatch: have %d, want %d", number, len(stored), len(body.Transactions))
+	}
+	receipts := make(types.Receipts, len(stored))
+	for i, receipt := range stored {
+		receipts[i] = (*types.Receipt)(receipt)
+		receipts[i].Type = body.Transactions[i].Type()
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		return fmt.Errorf("block #%d: receipt root mismatch: have %x, want %x", number, root, header.ReceiptHash)
+	}
+	td := new(big.Int)
+	if err := rlp.DecodeBytes(rec.Td, td); err != nil {
+		return fmt.Errorf("block #%d: invalid total difficulty: %v", number, err)
+	}
+	// The total difficulty of the genesis is as configured, not derived
+	if number > 0 {
+		if want := new(big.Int).Add(imp.td, header.Difficulty); td.Cmp(want) != 0 {
+			return fmt.Errorf("block #%d: total difficulty mismatch: have %v, want %v", number, td, want)
+		}
+	}
+	// Like the freezer, keep the genesis in the key-value store too
+	if number < imp.ancientLimit {
+		if err := imp.db.AppendAncient(number, hash.Bytes(), rec.Header, rec.Body, rec.Receipts, rec.Td); err != nil {
+			return fmt.Errorf("block #%d: %v", number, err)
+		}
+		rawdb.WriteHeaderNumber(imp.batch, hash, number)
+	}
+	if number >= imp.ancientLimit || number == 0 {
+		rawdb.WriteHeader(imp.batch, header)
+		rawdb.WriteBodyRLP(imp.batch, hash, number, rec.Body)
+		rawdb.WriteReceipts(imp.batch, hash, number, receipts)
+		rawdb.WriteTd(imp.batch, hash, number, td)
+		rawdb.WriteCanonicalHash(imp.batch, hash, number)
+	}
+	imp.next, imp.hash, imp.td = number+1, hash, td
+	return nil
+}
+
+// importState writes a trie node or a code, keyed by the hash of the blob.
+func (imp *importer) importState(rec *stateRecord) {
+	hash := crypto.Keccak256Hash(rec.Blob)
+	if rec.Code {
+		rawdb.WriteCode(imp.batch, hash, rec.Blob)
+		imp.codes++
+	} else {
+		rawdb.WriteTrieNode(imp.batch, hash, rec.Blob)
+		imp.nodes++
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrBlobTxBeforeCancun is returned if a blob transaction is included in a
+	// block, or sent to the pool, before the Cancun fork.
+	ErrBlobTxBeforeCancun = errors.New("blob transaction before cancun")
+
+	// ErrBlobGasLimitReached is returned if the blob gas of the transactions of a
+	// block exceeds the maximum allowed.
+	ErrBlobGasLimitReached = errors.New("blob gas limit reached")
+)
+
+// BlobGasPrice returns the price of a unit of blob gas. Headers don't track the
+// blob gas usage, so there is no blob fee market yet and blobs are always priced
+// at the protocol minimum.
+func BlobGasPrice() *big.Int {
+	return new(big.Int).SetUint64(params.BlobTxMinBlobGasprice)
+}
+
+// ValidateBlobTransactions checks the blob transactions of a block: they are
+// only allowed after the Cancun fork, they must carry valid blob hashes and their
+// total blob gas must not exceed the per-block maximum.
+func ValidateBlobTransactions(config *params.ChainConfig, block *types.Block) error {
+	var blobGas uint64
+	for i, tx := range block.Transactions() {
+		if tx.Type() != types.BlobTxType {
+			continue
+		}
+		if !config.IsCancun(block.Number()) {
+			return fmt.Errorf("%w: tx %d", ErrBlobTxBeforeCancun, i)
+		}
+		if err := tx.ValidateBlobHashes(); err != nil {
+			return fmt.Errorf("invalid blob transaction %d: %w", i, err)
+		}
+		blobGas += tx.BlobGas()
+	}
+	if blobGas > params.MaxBlobGasPerBlock {
+		return fmt.Errorf("%w: have %d, max %d", ErrBlobGasLimitReached, blobGas, params.MaxBlobGasPerBlock)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/importtrace"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
@@ -141,6 +143,9 @@ type CacheConfig struct {
 	SnapshotLimit      int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages          bool          // Whether to store preimage of trie key to the disk
 	TriesInMemory      uint64        // How many tries keeps in memory
+	SnapshotVerifyRate int           // Accounts per second the snapshot self-check verifies, 0 to disable
+	SnapshotRepair     bool          // Whether to regenerate the snapshot when the self-check finds a divergence
+	ReadOnly           bool          // Whether the chain follows the database of another process, never repairing it
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -181,11 +186,12 @@ type BlockChain struct {
 	chainConfig *params.ChainConfig // Chain & network configuration
 	cacheConfig *CacheConfig        // Cache configuration for pruning
 
-	db         ethdb.Database // Low level persistent database to store final content in
-	snaps      *snapshot.Tree // Snapshot tree for fast trie leaf access
-	triegc     *prque.Prque   // Priority queue mapping block numbers to tries to gc
-	gcproc     time.Duration  // Accumulates canonical block processing for trie dumping
-	commitLock sync.Mutex     // CommitLock is used to protect above field from being modified concurrently
+	db         ethdb.Database     // Low level persistent database to store final content in
+	snaps      *snapshot.Tree     // Snapshot tree for fast trie leaf access
+	snapCheck  *snapshot.Verifier // Background self-check of the snapshot, nil if disabled
+	triegc     *prque.Prque       // Priority queue mapping block numbers to tries to gc
+	gcproc     time.Duration      // Accumulates canonical block processing for trie dumping
+	commitLock sync.Mutex         // CommitLock is used to protect above field from being modified concurrently
 
 	// txLookupLimit is the maximum number of blocks from head whose tx indices
 	// are reserved:
@@ -195,13 +201,20 @@ type BlockChain struct {
 	txLookupLimit uint64
 	triesInMemory uint64
 
+	// txLookupAllowlist holds the addresses whose transactions stay indexed
+	// beyond the txLookupLimit window, nil to drop all stale tx indices.
+	txLookupAllowlist map[common.Address]struct{}
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	reorgFeed     event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	stateDiffFeed event.Feed
+	stateDiffSubs int32 // Number of state diff subscribers (atomic)
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -240,12 +253,20 @@ type BlockChain struct {
 	running       int32          // 0 if chain is running, 1 when stopped
 	procInterrupt int32          // interrupt signaler for block processing
 
-	engine     consensus.Engine
-	prefetcher Prefetcher
-	validator  Validator // Block and state validator interface
-	processor  Processor // Block transaction processor interface
-	vmConfig   vm.Config
-	pipeCommit bool
+	engine         consensus.Engine
+	prefetcher     Prefetcher
+	validator      Validator // Block and state validator interface
+	processor      Processor // Block transaction processor interface
+	vmConfig       vm.Config
+	pipeCommit     bool
+	callIndex      bool   // Whether to index the addresses touched by internal calls
+	accountTxIndex bool   // Whether to index the transactions by sender and recipient
+	senderTxIndex  bool   // Whether to index the canonical transactions by sender and nonce
+	traceBlock     bool   // Whether to record the traces of the imported blocks
+	revertBlock    bool   // Whether to record the transaction failures of the imported blocks
+	contractIndex  bool   // Whether to index the contracts deployed by the imported blocks
+	tokenIndex     bool   // Whether to index the token transfers of the imported blocks by account
+	backendChecks  uint64 // Number of imported blocks left to execute with the interpreter too
 
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
@@ -343,9 +364,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
-	// Make sure the state associated with the block is available
+	// Make sure the state associated with the block is available. A read-only
+	// chain can't repair the database, the primary flushes the state eventually.
 	head := bc.CurrentBlock()
-	if _, err := state.New(head.Root(), bc.stateCache, bc.snaps); err != nil {
+	if _, err := state.New(head.Root(), bc.stateCache, bc.snaps); err != nil && bc.cacheConfig.ReadOnly {
+		log.Warn("Head state not yet flushed by the primary", "number", head.Number(), "hash", head.Hash())
+	} else if err != nil {
 		// Head state is missing, before the state recovery, find out the
 		// disk layer point of snapshot(if it's enabled). Make sure the
 		// rewound point is lower than disk layer.
@@ -372,7 +396,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 	}
 	// Ensure that a previous crash in SetHead doesn't leave extra ancients
-	if frozen, err := bc.db.ItemAmountInAncient(); err == nil && frozen > 0 {
+	if frozen, err := bc.db.ItemAmountInAncient(); err == nil && frozen > 0 && !bc.cacheConfig.ReadOnly {
 		frozen, err = bc.db.Ancients()
 		if err != nil {
 			return nil, err
@@ -441,6 +465,14 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		}
 		bc.snaps, _ = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, int(bc.cacheConfig.TriesInMemory), head.Root(), !bc.cacheConfig.SnapshotWait, true, recover)
 	}
+	if bc.snaps != nil && bc.cacheConfig.SnapshotVerifyRate > 0 {
+		var repair func()
+		if bc.cacheConfig.SnapshotRepair {
+			repair = bc.repairSnapshot
+		}
+		bc.snapCheck = snapshot.NewVerifier(bc.snaps, bc.cacheConfig.SnapshotVerifyRate, repair)
+		bc.snapCheck.Start()
+	}
 	// do options before start any routine
 	for _, option := range options {
 		bc = option(bc)
@@ -582,6 +614,48 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// ReloadHead reloads the chain head from the database, catching up with the
+// blocks imported by the process owning it in read-only mode. It returns whether
+// the head moved, announcing the new head if so.
+func (bc *BlockChain) ReloadHead() (bool, error) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	hash := rawdb.ReadHeadBlockHash(bc.db)
+	if hash == (common.Hash{}) || hash == bc.CurrentBlock().Hash() {
+		return false, nil
+	}
+	block := bc.GetBlockByHash(hash)
+	if block == nil {
+		return false, fmt.Errorf("head block %x missing", hash)
+	}
+	// The canonical chain may have been reorged, drop the lookups of the old one
+	bc.txLookupCache.Purge()
+
+	bc.currentBlock.Store(block)
+	headBlockGauge.Update(int64(block.NumberU64()))
+
+	header := block.Header()
+	if head := rawdb.ReadHeadHeaderHash(bc.db); head != (common.Hash{}) {
+		if h := bc.GetHeaderByHash(head); h != nil {
+			header = h
+		}
+	}
+	bc.hc.SetCurrentHeader(header)
+
+	fast := block
+	if head := rawdb.ReadHeadFastBlockHash(bc.db); head != (common.Hash{}) {
+		if b := bc.GetBlockByHash(head); b != nil {
+			fast = b
+		}
+	}
+	bc.currentFastBlock.Store(fast)
+	headFastBlockGauge.Update(int64(fast.NumberU64()))
+
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+	return true, nil
+}
+
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
@@ -749,6 +823,8 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
 		}
+		rawdb.DeleteBlockTraces(db, hash, num)
+		rawdb.DeleteBlockReverts(db, hash, num)
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
 	// If SetHead was only called as a chain reparation method, try to skip
@@ -785,8 +861,12 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	if _, err := trie.NewSecure(block.Root(), bc.stateCache.TrieDB()); err != nil {
 		return err
 	}
-	// If all checks out, manually set the head block
+	// If all checks out, manually set the head block. The blocks below it were
+	// not indexed by sender, the new head is the first one to be.
 	bc.chainmu.Lock()
+	if bc.senderTxIndex {
+		rawdb.WriteSenderTxIndexTail(bc.db, block.NumberU64()+1)
+	}
 	bc.currentBlock.Store(block)
 	headBlockGauge.Update(int64(block.NumberU64()))
 	bc.chainmu.Unlock()
@@ -816,6 +896,23 @@ func (bc *BlockChain) Snapshots() *snapshot.Tree {
 	return bc.snaps
 }
 
+// SnapshotHealth returns the status of the snapshot integrity self-check.
+func (bc *BlockChain) SnapshotHealth() snapshot.Health {
+	if bc.snapCheck == nil {
+		return snapshot.Health{}
+	}
+	return bc.snapCheck.Health()
+}
+
+// repairSnapshot regenerates the snapshot at the current head, holding the chain
+// lock for no block to be imported on top of the discarded layers meanwhile.
+func (bc *BlockChain) repairSnapshot() {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.snaps.Rebuild(bc.CurrentBlock().Root())
+}
+
 // CurrentFastBlock retrieves the current fast-sync head block of the canonical
 // chain. The block is retrieved from the blockchain's internal cache.
 func (bc *BlockChain) CurrentFastBlock() *types.Block {
@@ -928,6 +1025,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	batch := bc.db.NewBatch()
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
 	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	if bc.senderTxIndex {
+		rawdb.WriteSenderTxs(batch, block, types.MakeSigner(bc.chainConfig, block.Number()))
+	}
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// If the block is better than our head or is on a different chain, force update heads
@@ -1229,6 +1329,9 @@ func (bc *BlockChain) Stop() {
 	close(bc.quit)
 	bc.StopInsert()
 	bc.wg.Wait()
+	if bc.snapCheck != nil {
+		bc.snapCheck.Stop()
+	}
 
 	// Ensure that the entirety of the state snapshot is journalled to disk.
 	var snapBase common.Hash
@@ -1467,6 +1570,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 				rawdb.WriteTxLookupEntriesByBlock(batch, block)
 			} else if rawdb.ReadTxIndexTail(bc.db) != nil {
 				rawdb.WriteTxLookupEntriesByBlock(batch, block)
+			} else if bc.txLookupAllowlist != nil {
+				var hashes []common.Hash
+				for _, tx := range block.Transactions() {
+					if bc.allowlistedTx(block.NumberU64(), tx) {
+						hashes = append(hashes, tx.Hash())
+					}
+				}
+				rawdb.WriteTxLookupEntries(batch, block.NumberU64(), hashes)
 			}
 			stats.processed++
 		}
@@ -1596,6 +1707,10 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			} else {
 				rawdb.WriteTxIndexTail(bc.db, ancientLimit-bc.txLookupLimit)
 			}
+			// The allowlisted transactions of the older blocks were indexed too
+			if bc.txLookupAllowlist != nil && rawdb.ReadTxIndexAllowlistTail(bc.db) == nil {
+				rawdb.WriteTxIndexAllowlistTail(bc.db, 0)
+			}
 		}
 	}
 	if len(liveBlocks) > 0 {
@@ -1837,6 +1952,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		if len(logs) > 0 {
 			bc.logsFeed.Send(logs)
 		}
+		bc.sendStateDiff(block, diffLayer)
 		// In theory we should fire a ChainHeadEvent when we inject
 		// a canonical block, but sometimes we can insert a batch of
 		// canonicial blocks. Avoid firing too much ChainHeadEvents,
@@ -1921,6 +2037,30 @@ func (bc *BlockChain) InsertChainWithoutSealVerification(block *types.Block) (in
 	return n, err
 }
 
+// SetCanonical makes a known block, whose state is available, the head of the
+// canonical chain, reorganising the chain onto it. It lets an external consensus
+// driver settle the fork choice, the block must not be a canonical ancestor of
+// the current head as rewinding the chain is left to SetHead.
+func (bc *BlockChain) SetCanonical(head *types.Block) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if head.Hash() == bc.CurrentBlock().Hash() {
+		return nil
+	}
+	if bc.GetCanonicalHash(head.NumberU64()) == head.Hash() {
+		return fmt.Errorf("block #%d [%x…] is a canonical ancestor of the head", head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+	if !bc.HasBlockAndState(head.Hash(), head.NumberU64()) {
+		return fmt.Errorf("block #%d [%x…] or its state missing", head.NumberU64(), head.Hash().Bytes()[:4])
+	}
+	if err := bc.writeKnownBlock(head); err != nil {
+		return err
+	}
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: head})
+	return nil
+}
+
 // insertChain is the internal implementation of InsertChain, which assumes that
 // 1) chains are contiguous, and 2) The chain mutex is held.
 //
@@ -2123,9 +2263,40 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 			statedb.EnablePipeCommit()
 		}
 		statedb.SetExpectedStateRoot(block.Root())
-		statedb, receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		var (
+			vmConfig = bc.vmConfig
+			indexer  *callIndexer
+			recorder *traceRecorder
+			reverts  *revertRecorder
+			deployed *contractIndexer
+			loggers  multiLogger
+		)
+		if bc.callIndex && !vmConfig.Debug {
+			indexer = newCallIndexer()
+			loggers = append(loggers, indexer)
+		}
+		if bc.traceBlock && !vmConfig.Debug {
+			recorder = newTraceRecorder(block, statedb)
+			loggers = append(loggers, recorder)
+		}
+		if bc.revertBlock && !vmConfig.Debug {
+			reverts = newRevertRecorder()
+			loggers = append(loggers, reverts)
+		}
+		if bc.contractIndex && !vmConfig.Debug {
+			deployed = newContractIndexer()
+			loggers = append(loggers, deployed)
+		}
+		if len(loggers) > 0 {
+			vmConfig.Debug, vmConfig.Tracer = true, loggers
+		}
+		statedb, receipts, logs, usedGas, err := bc.processor.Process(block, statedb, vmConfig)
+		if bc.backendChecks > 0 && vmConfig.Backend != vm.InterpreterBackend && !vmConfig.Debug && !statedb.IsLightProcessed() {
+			statedb, receipts, logs, usedGas, err = bc.checkBackend(block, parent, vmConfig, statedb, receipts, logs, usedGas, err)
+		}
 		atomic.StoreUint32(&followupInterrupt, 1)
 		activeState = statedb
+		importtrace.Record(block.Hash(), block.NumberU64(), importtrace.StageExecuted, substart, "txs", len(block.Transactions()), "gas", usedGas, "err", err)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			return it.index, err
@@ -2145,12 +2316,42 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		if !statedb.IsLightProcessed() {
 			if err := bc.validator.ValidateState(block, statedb, receipts, usedGas, bc.pipeCommit); err != nil {
 				log.Error("validate state failed", "error", err)
+				importtrace.Record(block.Hash(), block.NumberU64(), importtrace.StageValidated, substart, "err", err)
 				bc.reportBlock(block, receipts, err)
 				return it.index, err
 			}
 		}
+		importtrace.Record(block.Hash(), block.NumberU64(), importtrace.StageValidated, substart, "light", statedb.IsLightProcessed())
 		bc.cacheReceipts(block.Hash(), receipts)
 		bc.cacheBlock(block.Hash(), block)
+		if indexer != nil && !statedb.IsLightProcessed() {
+			rawdb.WriteInternalCalls(bc.db, block.Hash(), block.NumberU64(), indexer.calls)
+		}
+		if bc.accountTxIndex {
+			signer := types.MakeSigner(bc.chainConfig, block.Number())
+			rawdb.WriteAccountTxs(bc.db, block.Hash(), block.NumberU64(), accountTxs(block, receipts, signer))
+		}
+		if bc.tokenIndex {
+			rawdb.WriteTokenTransfers(bc.db, block.Hash(), block.NumberU64(), tokenTransfers(receipts))
+		}
+		if recorder != nil && !statedb.IsLightProcessed() {
+			rawdb.WriteBlockTraces(bc.db, block.Hash(), block.NumberU64(), recorder.traces(bc.engine, statedb))
+		}
+		if reverts != nil && !statedb.IsLightProcessed() {
+			rawdb.WriteBlockReverts(bc.db, block.Hash(), block.NumberU64(), reverts.blockReverts())
+		}
+		if deployed != nil && !statedb.IsLightProcessed() {
+			for index, creations := range deployed.creations {
+				for _, creation := range creations {
+					rawdb.WriteContractCreation(bc.db, creation.address, rawdb.ContractCreation{
+						BlockNumber: block.NumberU64(),
+						BlockHash:   block.Hash(),
+						TxIndex:     index,
+						Creator:     creation.creator,
+					})
+				}
+			}
+		}
 		proctime := time.Since(start)
 
 		// Update the metrics touched during block validation
@@ -2162,6 +2363,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		// Write the block to the chain and get the status.
 		substart = time.Now()
 		status, err := bc.writeBlockWithState(block, receipts, logs, statedb, false)
+		importtrace.Record(block.Hash(), block.NumberU64(), importtrace.StageCommitted, substart, "err", err)
 		if err != nil {
 			return it.index, err
 		}
@@ -2173,12 +2375,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		blockWriteTimer.Update(time.Since(substart))
 		blockInsertTimer.UpdateSince(start)
 
+		importtrace.Imported(block.Hash(), block.NumberU64(), start, "canonical", status == CanonStatTy)
+
 		switch status {
 		case CanonStatTy:
 			log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(),
 				"uncles", len(block.Uncles()), "txs", len(block.Transactions()), "gas", block.GasUsed(),
 				"elapsed", common.PrettyDuration(time.Since(start)),
-				"root", block.Root())
+				"root", block.Root(), "trace", importtrace.ID(block.Hash()))
 
 			lastCanon = block
 
@@ -2189,7 +2393,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 			log.Debug("Inserted forked block", "number", block.Number(), "hash", block.Hash(),
 				"diff", block.Difficulty(), "elapsed", common.PrettyDuration(time.Since(start)),
 				"txs", len(block.Transactions()), "gas", block.GasUsed(), "uncles", len(block.Uncles()),
-				"root", block.Root())
+				"root", block.Root(), "trace", importtrace.ID(block.Hash()))
 
 		default:
 			// This in theory is impossible, but lets be nice to our future selves and leave
@@ -2482,6 +2686,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 	}
 	// Ensure the user sees large reorgs
+	var record *rawdb.ReorgRecord
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
 		msg := "Chain reorg detected"
@@ -2494,6 +2699,23 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		blockReorgAddMeter.Mark(int64(len(newChain)))
 		blockReorgDropMeter.Mark(int64(len(oldChain)))
 		blockReorgMeter.Mark(1)
+
+		// Record the reorg in the reorg log, both chains in ascending order
+		record = &rawdb.ReorgRecord{
+			Time:           uint64(time.Now().UnixNano()),
+			AncestorNumber: commonBlock.NumberU64(),
+			AncestorHash:   commonBlock.Hash(),
+		}
+		for i := len(oldChain) - 1; i >= 0; i-- {
+			record.OldChain = append(record.OldChain, oldChain[i].Hash())
+		}
+		for i := len(newChain) - 1; i >= 0; i-- {
+			record.NewChain = append(record.NewChain, newChain[i].Hash())
+		}
+	} else if len(newChain) > 0 {
+		// The current head is an ancestor of the new head, as happens when an
+		// external consensus driver moves the head several blocks forward.
+		log.Info("Extend chain", "add", len(newChain), "number", newChain[0].Number(), "hash", newChain[0].Hash())
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
@@ -2514,6 +2736,15 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	indexesBatch := bc.db.NewBatch()
 	for _, tx := range types.TxDifference(deletedTxs, addedTxs) {
 		rawdb.DeleteTxLookupEntry(indexesBatch, tx.Hash())
+
+		// The nonce may have been reused by a transaction of the new chain
+		if bc.senderTxIndex {
+			if sender, err := types.Sender(types.LatestSigner(bc.chainConfig), tx); err == nil {
+				if entry := rawdb.ReadSenderTx(bc.db, sender, tx.Nonce()); entry != nil && entry.TxHash == tx.Hash() {
+					rawdb.DeleteSenderTx(indexesBatch, sender, tx.Nonce())
+				}
+			}
+		}
 	}
 	// Delete any canonical number assignments above the new head
 	number := bc.CurrentBlock().NumberU64()
@@ -2524,9 +2755,15 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 		rawdb.DeleteCanonicalHash(indexesBatch, i)
 	}
+	if record != nil {
+		rawdb.WriteReorg(indexesBatch, record)
+	}
 	if err := indexesBatch.Write(); err != nil {
 		log.Crit("Failed to delete useless indexes", "err", err)
 	}
+	if record != nil {
+		bc.reorgFeed.Send(ReorgEvent{Reorg: record})
+	}
 	// If any logs need to be fired, do it now. In theory we could avoid creating
 	// this goroutine if there are no events to fire, but realistcally that only
 	// ever happens if we're reorging empty blocks, which will only happen on idle
@@ -2849,15 +3086,23 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	indexBlocks := func(tail *uint64, head uint64, done chan struct{}) {
 		defer func() { done <- struct{}{} }()
 
+		// The allowlisted transactions below the window are indexed once the
+		// window itself is up to date
+		defer bc.indexAllowlistedTxs()
+
 		// If the user just upgraded Geth to a new version which supports transaction
 		// index pruning, write the new tail and remove anything older.
 		if tail == nil {
+			// All the blocks were indexed, so are the allowlisted transactions
+			if bc.txLookupAllowlist != nil && rawdb.ReadTxIndexAllowlistTail(bc.db) == nil {
+				rawdb.WriteTxIndexAllowlistTail(bc.db, 0)
+			}
 			if bc.txLookupLimit == 0 || head < bc.txLookupLimit {
 				// Nothing to delete, write the tail and return
 				rawdb.WriteTxIndexTail(bc.db, 0)
 			} else {
 				// Prune all stale tx indices and record the tx index tail
-				rawdb.UnindexTransactions(bc.db, 0, head-bc.txLookupLimit+1, bc.quit)
+				bc.unindexTxs(0, head-bc.txLookupLimit+1)
 			}
 			return
 		}
@@ -2874,7 +3119,7 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 			rawdb.IndexTransactions(bc.db, head-bc.txLookupLimit+1, *tail, bc.quit)
 		} else {
 			// Unindex a part of stale indices and forward index tail to HEAD-limit
-			rawdb.UnindexTransactions(bc.db, *tail, head-bc.txLookupLimit+1, bc.quit)
+			bc.unindexTxs(*tail, head-bc.txLookupLimit+1)
 		}
 	}
 	// Any reindexing done, start listening to chain events and moving the index window
@@ -2907,6 +3152,53 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// unindexTxs removes the tx indices of the given block range, keeping the ones
+// of the allowlisted addresses.
+func (bc *BlockChain) unindexTxs(from, to uint64) {
+	if bc.txLookupAllowlist == nil {
+		rawdb.UnindexTransactions(bc.db, from, to, bc.quit)
+		return
+	}
+	rawdb.UnindexTransactionsExcept(bc.db, from, to, bc.quit, bc.allowlistedTx)
+}
+
+// indexAllowlistedTxs indexes the transactions of the allowlisted addresses
+// below the tx index tail, which is only done once for the whole history as the
+// window keeps them when moving forward.
+func (bc *BlockChain) indexAllowlistedTxs() {
+	if bc.txLookupAllowlist == nil {
+		return
+	}
+	tail := rawdb.ReadTxIndexAllowlistTail(bc.db)
+	if tail == nil {
+		// The allowlist is new, index its transactions below the window
+		if tail = rawdb.ReadTxIndexTail(bc.db); tail == nil {
+			return
+		}
+		rawdb.WriteTxIndexAllowlistTail(bc.db, *tail)
+	}
+	if *tail > 0 {
+		rawdb.IndexAllowlistedTransactions(bc.db, 0, *tail, bc.quit, bc.allowlistedTx)
+	}
+}
+
+// allowlistedTx reports whether the transaction of the given block was sent by
+// or to an allowlisted address, its index to be kept beyond the txLookupLimit
+// window.
+func (bc *BlockChain) allowlistedTx(number uint64, tx *types.Transaction) bool {
+	if to := tx.To(); to != nil {
+		if _, ok := bc.txLookupAllowlist[*to]; ok {
+			return true
+		}
+	}
+	from, err := types.Sender(types.MakeSigner(bc.chainConfig, new(big.Int).SetUint64(number)), tx)
+	if err != nil {
+		return false
+	}
+	_, ok := bc.txLookupAllowlist[from]
+	return ok
+}
+
 func (bc *BlockChain) isCachedBadBlock(block *types.Block) bool {
 	if timeAt, exist := bc.badBlockCache.Get(block.Hash()); exist {
 		putAt := timeAt.(time.Time)
@@ -3072,6 +3364,11 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeReorgEvent registers a subscription of ReorgEvent.
+func (bc *BlockChain) SubscribeReorgEvent(ch chan<- ReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -3089,11 +3386,203 @@ func EnableLightProcessor(bc *BlockChain) *BlockChain {
 	return bc
 }
 
+// EnableParallelProcessor executes the transactions of the imported blocks in
+// parallel on the given number of workers.
+func EnableParallelProcessor(workers int) BlockChainOption {
+	return func(chain *BlockChain) *BlockChain {
+		chain.processor = NewParallelStateProcessor(chain.Config(), chain, chain.engine, workers)
+		return chain
+	}
+}
+
 func EnablePipelineCommit(bc *BlockChain) *BlockChain {
 	bc.pipeCommit = true
 	return bc
 }
 
+// EnableCallIndex records the addresses touched by the internal calls of the
+// transactions of the imported blocks. The blocks imported by diff sync, whose
+// transactions are not executed, are not indexed.
+func EnableCallIndex(bc *BlockChain) *BlockChain {
+	if bc.vmConfig.Debug {
+		log.Warn("Internal call index disabled by the configured EVM tracer")
+		return bc
+	}
+	bc.callIndex = true
+	if rawdb.ReadInternalCallIndexTail(bc.db) == nil {
+		rawdb.WriteInternalCallIndexTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableAccountTxIndex records the senders and the recipients of the transactions
+// of the imported blocks, along with the contracts they deploy.
+func EnableAccountTxIndex(bc *BlockChain) *BlockChain {
+	bc.accountTxIndex = true
+	if rawdb.ReadAccountTxIndexTail(bc.db) == nil {
+		rawdb.WriteAccountTxIndexTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableTxLookupAllowlist keeps the tx indices of the transactions sent by or to
+// the given addresses beyond the txLookupLimit window, the transactions of the
+// addresses added to the allowlist being indexed over the whole history.
+func EnableTxLookupAllowlist(addresses []common.Address) BlockChainOption {
+	return func(bc *BlockChain) *BlockChain {
+		allowlist := make(map[common.Address]struct{})
+		for _, address := range addresses {
+			allowlist[address] = struct{}{}
+		}
+		// Reindex the history if an address was added since the last run
+		stored := make(map[common.Address]struct{})
+		for _, address := range rawdb.ReadTxIndexAllowlist(bc.db) {
+			stored[address] = struct{}{}
+		}
+		for address := range allowlist {
+			if _, ok := stored[address]; !ok {
+				log.Info("Indexing the history of new allowlisted addresses", "addresses", len(allowlist))
+				rawdb.DeleteTxIndexAllowlistTail(bc.db)
+				break
+			}
+		}
+		sorted := make([]common.Address, 0, len(allowlist))
+		for address := range allowlist {
+			sorted = append(sorted, address)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+		})
+		rawdb.WriteTxIndexAllowlist(bc.db, sorted)
+
+		bc.txLookupAllowlist = allowlist
+		return bc
+	}
+}
+
+// EnableSenderTxIndex records the sender and the nonce of the transactions of
+// the canonical blocks, to look them up without scanning the chain.
+func EnableSenderTxIndex(bc *BlockChain) *BlockChain {
+	bc.senderTxIndex = true
+	if rawdb.ReadSenderTxIndexTail(bc.db) == nil {
+		rawdb.WriteSenderTxIndexTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableBlockTraces records the call traces, the state diffs and the rewards of
+// the imported blocks into the database. The blocks imported by diff sync, whose
+// transactions are not executed, are not traced.
+func EnableBlockTraces(bc *BlockChain) *BlockChain {
+	if bc.vmConfig.Debug {
+		log.Warn("Block trace recording disabled by the configured EVM tracer")
+		return bc
+	}
+	bc.traceBlock = true
+	if rawdb.ReadBlockTracesTail(bc.db) == nil {
+		rawdb.WriteBlockTracesTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableBlockReverts records the failures of the transactions of the imported
+// blocks, along with the call frames they originated in, into the database. The
+// blocks imported by diff sync, whose transactions are not executed, are not
+// recorded.
+func EnableBlockReverts(bc *BlockChain) *BlockChain {
+	if bc.vmConfig.Debug {
+		log.Warn("Transaction failure recording disabled by the configured EVM tracer")
+		return bc
+	}
+	bc.revertBlock = true
+	if rawdb.ReadBlockRevertsTail(bc.db) == nil {
+		rawdb.WriteBlockRevertsTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableContractIndex records the contracts deployed by the transactions of the
+// imported blocks, along with the accounts creating them. The blocks imported by
+// diff sync, whose transactions are not executed, are not indexed.
+func EnableContractIndex(bc *BlockChain) *BlockChain {
+	if bc.vmConfig.Debug {
+		log.Warn("Contract creation index disabled by the configured EVM tracer")
+		return bc
+	}
+	bc.contractIndex = true
+	if rawdb.ReadContractCreationIndexTail(bc.db) == nil {
+		rawdb.WriteContractCreationIndexTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableTokenIndex records the ERC-20, ERC-721 and ERC-1155 transfers emitted
+// by the transactions of the imported blocks, under their senders and recipients.
+func EnableTokenIndex(bc *BlockChain) *BlockChain {
+	bc.tokenIndex = true
+	if rawdb.ReadTokenTransferIndexTail(bc.db) == nil {
+		rawdb.WriteTokenTransferIndexTail(bc.db, bc.CurrentBlock().NumberU64()+1)
+	}
+	return bc
+}
+
+// EnableBackendVerification executes the given number of imported blocks with
+// the interpreter too, to cross-check the configured EVM backend against it.
+func EnableBackendVerification(blocks uint64) BlockChainOption {
+	return func(bc *BlockChain) *BlockChain {
+		if bc.vmConfig.Backend != vm.InterpreterBackend {
+			bc.backendChecks = blocks
+		}
+		return bc
+	}
+}
+
+// checkBackend executes the block with the interpreter on top of the parent state
+// again, comparing the outcome with the one of the configured backend. On any
+// difference, the backend is disabled and the outcome of the interpreter used
+// instead.
+func (bc *BlockChain) checkBackend(block *types.Block, parent *types.Header, vmConfig vm.Config, statedb *state.StateDB, receipts types.Receipts, logs []*types.Log, usedGas uint64, err error) (*state.StateDB, types.Receipts, []*types.Log, uint64, error) {
+	backend := vmConfig.Backend
+	bc.backendChecks--
+
+	parentState, stateErr := state.New(parent.Root, bc.stateCache, bc.snaps)
+	if stateErr != nil {
+		log.Warn("Failed to verify EVM backend", "number", block.Number(), "hash", block.Hash(), "err", stateErr)
+		return statedb, receipts, logs, usedGas, err
+	}
+	if bc.pipeCommit {
+		parentState.EnablePipeCommit()
+	}
+	parentState.SetExpectedStateRoot(block.Root())
+
+	vmConfig.Backend = vm.InterpreterBackend
+	wantState, wantReceipts, wantLogs, wantGas, wantErr := bc.processor.Process(block, parentState, vmConfig)
+
+	var diff string
+	switch {
+	case (err == nil) != (wantErr == nil):
+		diff = fmt.Sprintf("error %v, interpreter %v", err, wantErr)
+	case err != nil:
+		// Both rejected the block
+	case usedGas != wantGas:
+		diff = fmt.Sprintf("gas used %d, interpreter %d", usedGas, wantGas)
+	case types.DeriveSha(receipts, trie.NewStackTrie(nil)) != types.DeriveSha(wantReceipts, trie.NewStackTrie(nil)):
+		diff = "receipts"
+	case !bc.pipeCommit && statedb.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number())) != wantState.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number())):
+		diff = "state"
+	}
+	if diff == "" {
+		if bc.backendChecks == 0 {
+			log.Info("Verified EVM backend against the interpreter", "backend", backend)
+		}
+		return statedb, receipts, logs, usedGas, err
+	}
+	log.Error("EVM backend diverged from the interpreter, disabling it", "backend", backend, "number", block.Number(), "hash", block.Hash(), "diff", diff)
+	bc.vmConfig.Backend = vm.InterpreterBackend
+	bc.backendChecks = 0
+	return wantState, wantReceipts, wantLogs, wantGas, wantErr
+}
+
 func EnablePersistDiff(limit uint64) BlockChainOption {
 	return func(chain *BlockChain) *BlockChain {
 		chain.diffLayerFreezerBlockLimit = limit
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -1318,6 +1319,65 @@ done:
 
 }
 
+// Tests that reorgs are recorded in the reorg log and announced.
+func TestReorgLog(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	chain, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	fork, _ := GenerateChain(gspec.Config, chain[0], ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	})
+	reorgCh := make(chan ReorgEvent, 1)
+	blockchain.SubscribeReorgEvent(reorgCh)
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	reorgs, err := rawdb.ReadReorgs(db, 0, 10, 10)
+	if err != nil {
+		t.Fatalf("failed to read reorg log: %v", err)
+	}
+	if len(reorgs) != 1 {
+		t.Fatalf("reorg log length mismatch: have %d, want 1", len(reorgs))
+	}
+	reorg := reorgs[0]
+	if reorg.AncestorNumber != 1 || reorg.AncestorHash != chain[0].Hash() {
+		t.Errorf("common ancestor mismatch: have #%d %x, want #1 %x", reorg.AncestorNumber, reorg.AncestorHash, chain[0].Hash())
+	}
+	if len(reorg.OldChain) != 2 || reorg.OldChain[0] != chain[1].Hash() || reorg.OldChain[1] != chain[2].Hash() {
+		t.Errorf("old chain mismatch: %x", reorg.OldChain)
+	}
+	// The fork becomes canonical at its second or third block depending on how
+	// the tie at equal difficulty is broken.
+	if len(reorg.NewChain) < 2 || len(reorg.NewChain) > 3 {
+		t.Fatalf("new chain length mismatch: have %d, want 2 or 3", len(reorg.NewChain))
+	}
+	for i, hash := range reorg.NewChain {
+		if hash != fork[i].Hash() {
+			t.Errorf("new chain block %d mismatch: have %x, want %x", i, hash, fork[i].Hash())
+		}
+	}
+	if reorgs, _ := rawdb.ReadReorgs(db, 2, 10, 10); len(reorgs) != 0 {
+		t.Errorf("reorg outside of the queried range returned")
+	}
+	select {
+	case ev := <-reorgCh:
+		if ev.Reorg.AncestorHash != chain[0].Hash() {
+			t.Errorf("announced common ancestor mismatch: have %x, want %x", ev.Reorg.AncestorHash, chain[0].Hash())
+		}
+	default:
+		t.Error("reorg not announced")
+	}
+}
+
 // Tests if the canonical block can be fetched from the database during chain insertion.
 func TestCanonicalBlockRetrieval(t *testing.T) {
 	_, blockchain, err := newCanonical(ethash.NewFaker(), 0, true, false)
@@ -2306,6 +2366,94 @@ func TestTransactionIndices(t *testing.T) {
 	}
 }
 
+func TestTransactionIndexAllowlist(t *testing.T) {
+	// Configure and generate a sample block chain with a deposit and a transfer
+	// in every block
+	var (
+		gendb    = rawdb.NewMemoryDatabase()
+		key1, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1    = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2    = crypto.PubkeyToAddress(key2.PublicKey)
+		deposit  = common.Address{0xde}
+		funds    = big.NewInt(1000000000)
+		gspec    = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{addr1: {Balance: funds}, addr2: {Balance: funds}}}
+		genesis  = gspec.MustCommit(gendb)
+		signer   = types.LatestSigner(gspec.Config)
+		limit    = uint64(16)
+		deposits = make(map[common.Hash]bool)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 64, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(addr1), common.Address{0x00}, big.NewInt(1000), params.TxGas, nil, nil), signer, key1)
+		block.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(block.TxNonce(addr2), deposit, big.NewInt(1000), params.TxGas, nil, nil), signer, key2)
+		block.AddTx(tx)
+		deposits[tx.Hash()] = true
+	})
+	blocks2, _ := GenerateChain(gspec.Config, blocks[len(blocks)-1], ethash.NewFaker(), gendb, 2, nil)
+
+	// check verifies that the window is fully indexed and that only the selected
+	// transactions are indexed below it
+	check := func(chain *BlockChain, tail uint64, keep func(tx *types.Transaction) bool) {
+		t.Helper()
+		if stored := rawdb.ReadTxIndexTail(chain.db); stored == nil || *stored != tail {
+			t.Fatalf("Oldest indexed block mismatch, want %d, have %v", tail, stored)
+		}
+		if stored := rawdb.ReadTxIndexAllowlistTail(chain.db); stored == nil || *stored != 0 {
+			t.Fatalf("Oldest allowlisted block mismatch, want 0, have %v", stored)
+		}
+		for i := uint64(1); i <= chain.CurrentBlock().NumberU64(); i++ {
+			block := rawdb.ReadBlock(chain.db, rawdb.ReadCanonicalHash(chain.db, i), i)
+			for _, tx := range block.Transactions() {
+				want := i >= tail || keep(tx)
+				if have := rawdb.ReadTxLookupEntry(chain.db, tx.Hash()) != nil; have != want {
+					t.Fatalf("Transaction index presence mismatch, number %d hash %s: have %v, want %v", i, tx.Hash().Hex(), have, want)
+				}
+			}
+		}
+	}
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	// Import the chain keeping the deposits indexed beyond the window
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &limit, EnableTxLookupAllowlist([]common.Address{deposit}))
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	time.Sleep(50 * time.Millisecond) // Wait for indices maintenance
+	check(chain, 64-limit+1, func(tx *types.Transaction) bool { return deposits[tx.Hash()] })
+	chain.Stop()
+
+	// Allowlist the sender of the transfers, their history has to be reindexed
+	chain, err = NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &limit, EnableTxLookupAllowlist([]common.Address{deposit, addr1}))
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if rawdb.ReadTxIndexAllowlistTail(db) != nil {
+		t.Fatalf("Allowlist tail not reset by a new address")
+	}
+	chain.InsertChain(blocks2[:1])    // Feed chain a higher block to trigger indices updater.
+	time.Sleep(50 * time.Millisecond) // Wait for indices maintenance
+	check(chain, 65-limit+1, func(tx *types.Transaction) bool { return true })
+	chain.Stop()
+
+	// Removing an address keeps its indices, no reindexing is needed
+	chain, err = NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, &limit, EnableTxLookupAllowlist([]common.Address{deposit}))
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if tail := rawdb.ReadTxIndexAllowlistTail(db); tail == nil || *tail != 0 {
+		t.Fatalf("Allowlist tail mismatch, want 0, have %v", tail)
+	}
+	if stored := rawdb.ReadTxIndexAllowlist(db); len(stored) != 1 || stored[0] != deposit {
+		t.Fatalf("Stored allowlist mismatch, have %v", stored)
+	}
+	chain.Stop()
+}
+
 func TestSkipStaleTxIndicesInFastSync(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -3218,3 +3366,171 @@ func TestEIP2718Transition(t *testing.T) {
 
 	}
 }
+
+// TestEIP7702 deploys two delegation designations and calls them. It writes one
+// value to storage which is verified after.
+func TestEIP7702(t *testing.T) {
+	var (
+		aa = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		bb = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2   = crypto.PubkeyToAddress(key2.PublicKey)
+		funds   = big.NewInt(1000000000)
+		config  = *params.TestChainConfig
+	)
+	config.CancunBlock = big.NewInt(0)
+	config.PragueBlock = big.NewInt(0)
+
+	gspec := &Genesis{
+		Config: &config,
+		Alloc: GenesisAlloc{
+			addr1: {Balance: funds},
+			addr2: {Balance: funds},
+			// The address 0xAAAA calls into addr2
+			aa: {
+				Code: []byte{
+					byte(vm.PUSH1), 0x00, // retSize
+					byte(vm.PUSH1), 0x00, // retOffset
+					byte(vm.PUSH1), 0x00, // argsSize
+					byte(vm.PUSH1), 0x00, // argsOffset
+					byte(vm.PUSH1), 0x00, // value
+					byte(vm.PUSH20),
+				},
+				Balance: big.NewInt(0),
+			},
+			// The address 0xBBBB sstores 42 into slot 42
+			bb: {
+				Code: []byte{
+					byte(vm.PUSH1), 0x2a,
+					byte(vm.PUSH1), 0x2a,
+					byte(vm.SSTORE),
+				},
+				Balance: big.NewInt(0),
+			},
+		},
+	}
+	code := gspec.Alloc[aa].Code
+	code = append(code, addr2.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL))
+	gspec.Alloc[aa] = GenesisAccount{Code: code, Balance: big.NewInt(0)}
+	genesis := gspec.MustCommit(db)
+
+	// Sign authorizations delegating addr1 to 0xAAAA and addr2 to 0xBBBB. As
+	// addr1 sends the transaction, its nonce is bumped before the authorization
+	// is applied.
+	auth1, _ := types.SignSetCode(key1, types.SetCodeAuthorization{
+		ChainID: config.ChainID,
+		Address: aa,
+		Nonce:   1,
+	})
+	auth2, _ := types.SignSetCode(key2, types.SetCodeAuthorization{
+		ChainID: new(big.Int),
+		Address: bb,
+		Nonce:   0,
+	})
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+
+		tx, _ := types.SignNewTx(key1, types.LatestSigner(gspec.Config), &types.SetCodeTx{
+			ChainID:  gspec.Config.ChainID,
+			Nonce:    0,
+			To:       addr1,
+			Gas:      500000,
+			GasPrice: big.NewInt(1),
+			AuthList: []types.SetCodeAuthorization{auth1, auth2},
+		})
+		b.AddTx(tx)
+	})
+	diskdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// Verify delegation designations were deployed
+	state, _ := chain.State()
+	if code, want := state.GetCode(addr1), types.AddressToDelegation(aa); !bytes.Equal(code, want) {
+		t.Fatalf("addr1 code incorrect: got %x, want %x", code, want)
+	}
+	if code, want := state.GetCode(addr2), types.AddressToDelegation(bb); !bytes.Equal(code, want) {
+		t.Fatalf("addr2 code incorrect: got %x, want %x", code, want)
+	}
+	// Verify delegation executed the correct code
+	var (
+		fortyTwo = common.BytesToHash([]byte{0x2a})
+		actual   = state.GetState(addr2, fortyTwo)
+	)
+	if actual != fortyTwo {
+		t.Fatalf("addr2 storage wrong: expected %d, got %d", fortyTwo, actual)
+	}
+}
+
+// Tests that a read-only chain following the database of another chain catches
+// up with its head, including reorgs, when reloading it.
+func TestReloadHead(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		engine  = ethash.NewFaker()
+	)
+	primary, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	defer primary.Stop()
+
+	config := *defaultCacheConfig
+	config.SnapshotLimit = 0
+	config.ReadOnly = true
+	follower, err := NewBlockChain(db, &config, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create follower: %v", err)
+	}
+	defer follower.Stop()
+
+	headCh := make(chan ChainHeadEvent, 1)
+	sub := follower.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	if moved, err := follower.ReloadHead(); moved || err != nil {
+		t.Fatalf("unchanged head reloaded: moved %v, err %v", moved, err)
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, engine, db, 4, nil)
+	fork, _ := GenerateChain(gspec.Config, genesis, engine, db, 5, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+
+	for _, chain := range [][]*types.Block{blocks, fork} {
+		if _, err := primary.InsertChain(chain); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		if moved, err := follower.ReloadHead(); !moved || err != nil {
+			t.Fatalf("head not reloaded: moved %v, err %v", moved, err)
+		}
+		head := chain[len(chain)-1]
+		if have := follower.CurrentBlock().Hash(); have != head.Hash() {
+			t.Fatalf("head block mismatch: have %x, want %x", have, head.Hash())
+		}
+		if have := follower.CurrentHeader().Hash(); have != head.Hash() {
+			t.Fatalf("head header mismatch: have %x, want %x", have, head.Hash())
+		}
+		if have := follower.GetBlockByNumber(2).Hash(); have != chain[1].Hash() {
+			t.Fatalf("canonical block mismatch: have %x, want %x", have, chain[1].Hash())
+		}
+		select {
+		case ev := <-headCh:
+			if ev.Block.Hash() != head.Hash() {
+				t.Fatalf("head event mismatch: have %x, want %x", ev.Block.Hash(), head.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no head event")
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// callIndexer is an EVM logger collecting the addresses touched by the internal
+// calls of the transactions of a block, i.e. the sender and the recipient of
+// every call frame below the top level one.
+type callIndexer struct {
+	env   *vm.EVM
+	calls map[uint32][]common.Address
+	seen  map[uint32]map[common.Address]struct{}
+}
+
+func newCallIndexer() *callIndexer {
+	return &callIndexer{
+		calls: make(map[uint32][]common.Address),
+		seen:  make(map[uint32]map[common.Address]struct{}),
+	}
+}
+
+// txIndex returns the index of the transaction being executed.
+func (c *callIndexer) txIndex() (uint32, bool) {
+	if c.env == nil {
+		return 0, false
+	}
+	state, ok := c.env.StateDB.(interface{ TxIndex() int })
+	if !ok {
+		return 0, false
+	}
+	return uint32(state.TxIndex()), true
+}
+
+func (c *callIndexer) add(index uint32, address common.Address) {
+	seen, ok := c.seen[index]
+	if !ok {
+		seen = make(map[common.Address]struct{})
+		c.seen[index] = seen
+	}
+	if _, ok := seen[address]; ok {
+		return
+	}
+	seen[address] = struct{}{}
+	c.calls[index] = append(c.calls[index], address)
+}
+
+func (c *callIndexer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	c.env = env
+}
+
+func (c *callIndexer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if index, ok := c.txIndex(); ok {
+		c.add(index, from)
+		c.add(index, to)
+	}
+}
+
+func (c *callIndexer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (c *callIndexer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (c *callIndexer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (c *callIndexer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {}
+
+func (c *callIndexer) CaptureTxStart(gasLimit uint64) {}
+
+func (c *callIndexer) CaptureTxEnd(restGas uint64) {}
+
+// accountTxs returns the accounts sending, receiving or created by each
+// transaction of a block, keyed by transaction index.
+func accountTxs(block *types.Block, receipts types.Receipts, signer types.Signer) map[uint32][]common.Address {
+	txs := make(map[uint32][]common.Address)
+	for i, tx := range block.Transactions() {
+		var addresses []common.Address
+		if from, err := types.Sender(signer, tx); err == nil {
+			addresses = append(addresses, from)
+		}
+		if to := tx.To(); to != nil {
+			addresses = append(addresses, *to)
+		} else if i < len(receipts) && receipts[i].ContractAddress != (common.Address{}) {
+			addresses = append(addresses, receipts[i].ContractAddress)
+		}
+		if len(addresses) == 2 && addresses[0] == addresses[1] {
+			addresses = addresses[:1]
+		}
+		txs[uint32(i)] = addresses
+	}
+	return txs
+}
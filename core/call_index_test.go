@@ -0,0 +1,206 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the addresses touched by internal calls are indexed on import.
+func TestCallIndex(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		caller = common.HexToAddress("0xaaaa")
+		callee = common.HexToAddress("0xbbbb")
+		db     = rawdb.NewMemoryDatabase()
+		signer = types.LatestSigner(params.TestChainConfig)
+		// PUSH1 0 (x5) PUSH20 callee GAS CALL STOP
+		contract = append(append(common.FromHex("0x6000600060006000600073"), callee.Bytes()...), byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			caller: {Balance: common.Big0, Code: contract},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		// A plain transfer, followed by a call to the contract in every other block
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), callee, common.Big1, params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+		if i%2 == 0 {
+			tx, _ = types.SignTx(types.NewTransaction(gen.TxNonce(sender), caller, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableCallIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadInternalCallIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("index tail mismatch: have %v, want 1", tail)
+	}
+	for _, address := range []common.Address{caller, callee} {
+		var calls []rawdb.InternalCall
+		rawdb.IterateInternalCalls(db, address, 0, 3, func(call rawdb.InternalCall) bool {
+			calls = append(calls, call)
+			return true
+		})
+		if len(calls) != 2 {
+			t.Fatalf("%x: call count mismatch: have %d, want 2", address, len(calls))
+		}
+		for i, call := range calls {
+			block := blocks[2*i]
+			if call.BlockNumber != block.NumberU64() || call.BlockHash != block.Hash() || call.TxIndex != 1 {
+				t.Fatalf("%x: call %d mismatch: have #%d [%x] tx %d", address, i, call.BlockNumber, call.BlockHash, call.TxIndex)
+			}
+		}
+	}
+	// The sender only made top level calls
+	rawdb.IterateInternalCalls(db, sender, 0, 3, func(call rawdb.InternalCall) bool {
+		t.Fatalf("sender indexed in block #%d", call.BlockNumber)
+		return false
+	})
+}
+
+// Tests that the senders, recipients and deployed contracts of the transactions
+// are indexed on import.
+func TestAccountTxIndex(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.HexToAddress("0xbbbb")
+		contract  = crypto.CreateAddress(sender, 1)
+		db        = rawdb.NewMemoryDatabase()
+		signer    = types.LatestSigner(params.TestChainConfig)
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		// A plain transfer in the first block, a contract creation in the second
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), recipient, common.Big1, params.TxGas, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		} else {
+			tx, _ := types.SignTx(types.NewContractCreation(gen.TxNonce(sender), common.Big0, 100000, big.NewInt(1), common.FromHex("0x00")), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableAccountTxIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadAccountTxIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("index tail mismatch: have %v, want 1", tail)
+	}
+	for address, want := range map[common.Address][]uint64{sender: {1, 2}, recipient: {1}, contract: {2}} {
+		var have []uint64
+		rawdb.IterateAccountTxs(db, address, 0, 2, func(tx rawdb.AccountTx) bool {
+			if tx.BlockHash != blocks[tx.BlockNumber-1].Hash() || tx.TxIndex != 0 {
+				t.Errorf("%x: entry mismatch: have #%d [%x] tx %d", address, tx.BlockNumber, tx.BlockHash, tx.TxIndex)
+			}
+			have = append(have, tx.BlockNumber)
+			return true
+		})
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("%x: indexed blocks mismatch: have %v, want %v", address, have, want)
+		}
+	}
+}
+
+// Tests that the transactions are indexed by sender and nonce when they become
+// canonical, and that the entries of the transactions dropped by a reorg are
+// replaced or deleted.
+func TestSenderTxIndex(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+		signer = types.LatestSigner(params.TestChainConfig)
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	genesis := gspec.MustCommit(db)
+
+	// A chain sending two transactions, and a longer one replacing the first and
+	// dropping the second
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), common.HexToAddress("0xaaaa"), common.Big1, params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	forks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), common.HexToAddress("0xbbbb"), common.Big1, params.TxGas, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableSenderTxIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadSenderTxIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("index tail mismatch: have %v, want 1", tail)
+	}
+	for nonce, block := range blocks {
+		want := &rawdb.SenderTx{BlockNumber: block.NumberU64(), TxHash: block.Transactions()[0].Hash()}
+		if have := rawdb.ReadSenderTx(db, sender, uint64(nonce)); !reflect.DeepEqual(have, want) {
+			t.Errorf("nonce %d: entry mismatch: have %+v, want %+v", nonce, have, want)
+		}
+	}
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	want := &rawdb.SenderTx{BlockNumber: 1, TxHash: forks[0].Transactions()[0].Hash()}
+	if have := rawdb.ReadSenderTx(db, sender, 0); !reflect.DeepEqual(have, want) {
+		t.Errorf("replaced entry mismatch: have %+v, want %+v", have, want)
+	}
+	if have := rawdb.ReadSenderTx(db, sender, 1); have != nil {
+		t.Errorf("dropped entry not deleted: %+v", have)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// contractCreation is a contract deployed by a transaction and the account
+// creating it.
+type contractCreation struct {
+	address common.Address
+	creator common.Address
+}
+
+// contractIndexer is an EVM logger collecting the contracts deployed by the
+// transactions of a block. The contracts created by failing call frames, whose
+// changes are reverted, are dropped.
+type contractIndexer struct {
+	env       *vm.EVM
+	creations map[uint32][]contractCreation
+
+	// Contracts created by the frames entered but not exited yet, the ones of
+	// each frame are committed to its caller when it succeeds
+	frames [][]contractCreation
+}
+
+func newContractIndexer() *contractIndexer {
+	return &contractIndexer{
+		creations: make(map[uint32][]contractCreation),
+	}
+}
+
+func (c *contractIndexer) enter(create bool, from common.Address, to common.Address) {
+	var created []contractCreation
+	if create {
+		created = append(created, contractCreation{address: to, creator: from})
+	}
+	c.frames = append(c.frames, created)
+}
+
+func (c *contractIndexer) exit(err error) []contractCreation {
+	if len(c.frames) == 0 {
+		return nil
+	}
+	created := c.frames[len(c.frames)-1]
+	c.frames = c.frames[:len(c.frames)-1]
+
+	if err != nil {
+		return nil
+	}
+	if len(c.frames) > 0 {
+		c.frames[len(c.frames)-1] = append(c.frames[len(c.frames)-1], created...)
+	}
+	return created
+}
+
+func (c *contractIndexer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	c.env, c.frames = env, nil
+	c.enter(create, from, to)
+}
+
+func (c *contractIndexer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	c.enter(typ == vm.CREATE || typ == vm.CREATE2, from, to)
+}
+
+func (c *contractIndexer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (c *contractIndexer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	c.exit(err)
+}
+
+func (c *contractIndexer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (c *contractIndexer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	created := c.exit(err)
+	if len(created) == 0 || c.env == nil {
+		return
+	}
+	state, ok := c.env.StateDB.(interface{ TxIndex() int })
+	if !ok {
+		return
+	}
+	index := uint32(state.TxIndex())
+	c.creations[index] = append(c.creations[index], created...)
+}
+
+func (c *contractIndexer) CaptureTxStart(gasLimit uint64) {}
+
+func (c *contractIndexer) CaptureTxEnd(restGas uint64) {}
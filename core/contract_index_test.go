@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the contracts deployed by transactions are indexed on import, along
+// with their creators, while the creations reverted are not.
+func TestContractIndex(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		factory = common.HexToAddress("0xaaaa")
+		db      = rawdb.NewMemoryDatabase()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		// PUSH1 0 PUSH1 0 PUSH1 0 CREATE, deploying an empty contract
+		create = common.FromHex("0x600060006000f0")
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			// Creates a contract, then reverts: POP PUSH1 0 PUSH1 0 REVERT
+			factory: {Balance: common.Big0, Code: append(create, common.FromHex("0x5060006000fd")...)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, gen *BlockGen) {
+		// A contract creating another one in its constructor, then a reverted creation
+		tx, _ := types.SignTx(types.NewContractCreation(gen.TxNonce(sender), common.Big0, 200000, big.NewInt(1), append(create, byte(vm.STOP))), signer, key)
+		gen.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(gen.TxNonce(sender), factory, common.Big0, 200000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableContractIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadContractCreationIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("contract index tail mismatch: have %v, want 1", tail)
+	}
+	var (
+		parent = crypto.CreateAddress(sender, 0)
+		child  = crypto.CreateAddress(parent, 1)
+	)
+	for address, creator := range map[common.Address]common.Address{parent: sender, child: parent} {
+		creations, err := rawdb.ReadContractCreations(db, address)
+		if err != nil {
+			t.Fatalf("failed to read creations of %x: %v", address, err)
+		}
+		want := rawdb.ContractCreation{BlockNumber: 1, BlockHash: blocks[0].Hash(), TxIndex: 0, Creator: creator}
+		if len(creations) != 1 || creations[0] != want {
+			t.Errorf("creations of %x mismatch: have %+v, want %+v", address, creations, want)
+		}
+	}
+	if creations, _ := rawdb.ReadContractCreations(db, crypto.CreateAddress(factory, 0)); len(creations) != 0 {
+		t.Errorf("reverted creation indexed: %+v", creations)
+	}
+}
@@ -77,4 +77,26 @@ var (
 	// ErrTxTypeNotSupported is returned if a transaction is not supported in the
 	// current network configuration.
 	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
+
+	// ErrBlobFeeCapTooLow is returned if the blob fee cap of a transaction is
+	// lower than the blob gas price.
+	ErrBlobFeeCapTooLow = errors.New("max fee per blob gas less than blob gas price")
+
+	// ErrEmptyAuthList is returned if a set-code transaction has an empty
+	// authorization list.
+	ErrEmptyAuthList = errors.New("EIP-7702 transaction with empty auth list")
+
+	// ErrFeeCapTooLow is returned if the gas price of a transaction is lower than
+	// the base fee of the block.
+	ErrFeeCapTooLow = errors.New("gas price less than block base fee")
+)
+
+// EIP-7702 authorization errors. An invalid authorization doesn't invalidate the
+// transaction carrying it, it's skipped.
+var (
+	ErrAuthorizationWrongChainID       = errors.New("EIP-7702 authorization chain ID mismatch")
+	ErrAuthorizationNonceOverflow      = errors.New("EIP-7702 authorization nonce > 64 bit")
+	ErrAuthorizationInvalidSignature   = errors.New("EIP-7702 authorization has invalid signature")
+	ErrAuthorizationDestinationHasCode = errors.New("EIP-7702 authorization destination is a contract")
+	ErrAuthorizationNonceMismatch      = errors.New("EIP-7702 authorization nonce does not match current account nonce")
 )
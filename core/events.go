@@ -18,6 +18,7 @@ package core
 
 import (
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -44,3 +45,10 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ReorgEvent is posted when the canonical chain is reorganised.
+type ReorgEvent struct{ Reorg *rawdb.ReorgRecord }
+
+// StateDiffEvent is posted with the state changes of a block when it is written
+// as the new canonical head.
+type StateDiffEvent struct{ Diff *StateDiff }
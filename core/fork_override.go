@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	errNoOverrideSigners  = errors.New("no trusted signers configured for the fork overrides")
+	errUnauthorizedSigner = errors.New("fork overrides not signed by a trusted signer")
+)
+
+// ForkOverrides is an operator supplied schedule of hard forks, moving the
+// activation blocks of the chain configuration without a new release. Forks
+// which are already active can't be moved, the chain configuration check
+// refuses such overrides.
+type ForkOverrides struct {
+	ChainID *big.Int            `json:"chainId"` // Chain the overrides are meant for
+	Forks   map[string]*big.Int `json:"forks"`   // Activation blocks, keyed by the JSON name of the fork in the chain configuration
+}
+
+// SignedForkOverrides is the manifest distributed to the operators, holding the
+// fork overrides along with the signature of a trusted signer.
+type SignedForkOverrides struct {
+	Overrides json.RawMessage `json:"overrides"` // Encoded ForkOverrides
+	Signature hexutil.Bytes   `json:"signature"` // Signature of the keccak256 hash of the compacted overrides
+}
+
+// SignForkOverrides encodes the overrides into a manifest signed with the given key.
+func SignForkOverrides(overrides *ForkOverrides, key *ecdsa.PrivateKey) ([]byte, error) {
+	blob, err := json.Marshal(overrides)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(blob), key)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&SignedForkOverrides{Overrides: blob, Signature: sig}, "", "  ")
+}
+
+// LoadForkOverrides reads a signed fork override manifest from disk, checking it
+// was signed by one of the trusted signers.
+func LoadForkOverrides(path string, signers []common.Address) (*ForkOverrides, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeForkOverrides(blob, signers)
+}
+
+func decodeForkOverrides(blob []byte, signers []common.Address) (*ForkOverrides, error) {
+	if len(signers) == 0 {
+		return nil, errNoOverrideSigners
+	}
+	var manifest SignedForkOverrides
+	if err := json.Unmarshal(blob, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid fork override manifest: %v", err)
+	}
+	if len(manifest.Signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("invalid fork override signature length %d", len(manifest.Signature))
+	}
+	// Whitespace isn't signed, the manifest may be reformatted
+	signed := new(bytes.Buffer)
+	if err := json.Compact(signed, manifest.Overrides); err != nil {
+		return nil, fmt.Errorf("invalid fork overrides: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(signed.Bytes()), manifest.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fork override signature: %v", err)
+	}
+	signer := crypto.PubkeyToAddress(*pubkey)
+	trusted := false
+	for _, addr := range signers {
+		if addr == signer {
+			trusted = true
+			break
+		}
+	}
+	if !trusted {
+		return nil, fmt.Errorf("%w: %v", errUnauthorizedSigner, signer)
+	}
+	dec := json.NewDecoder(bytes.NewReader(manifest.Overrides))
+	dec.DisallowUnknownFields()
+
+	overrides := new(ForkOverrides)
+	if err := dec.Decode(overrides); err != nil {
+		return nil, fmt.Errorf("invalid fork overrides: %v", err)
+	}
+	if overrides.ChainID == nil {
+		return nil, errors.New("fork overrides without chain id")
+	}
+	if err := validateForks(overrides.Forks); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// apply returns a copy of the chain configuration with the fork blocks overridden.
+func (o *ForkOverrides) apply(config *params.ChainConfig) (*params.ChainConfig, error) {
+	if config.ChainID == nil || config.ChainID.Cmp(o.ChainID) != 0 {
+		return nil, fmt.Errorf("fork overrides for chain %v, have chain %v", o.ChainID, config.ChainID)
+	}
+	return setForks(config, o.Forks), nil
+}
+
+// SetForks returns a copy of the chain configuration with the activation blocks
+// of the given forks, keyed by their JSON name in the configuration, replaced.
+func SetForks(config *params.ChainConfig, forks map[string]*big.Int) (*params.ChainConfig, error) {
+	if err := validateForks(forks); err != nil {
+		return nil, err
+	}
+	return setForks(config, forks), nil
+}
+
+// validateForks checks that the activation blocks are keyed by the JSON names
+// of forks of the chain configuration.
+func validateForks(forks map[string]*big.Int) error {
+	for name, number := range forks {
+		if _, ok := forkField(name); !ok {
+			return fmt.Errorf("unknown fork %q", name)
+		}
+		if number == nil || number.Sign() < 0 {
+			return fmt.Errorf("invalid activation block of fork %q", name)
+		}
+	}
+	return nil
+}
+
+// setForks returns a copy of the chain configuration with the given, validated,
+// activation blocks.
+func setForks(config *params.ChainConfig, forks map[string]*big.Int) *params.ChainConfig {
+	cpy := *config
+	value := reflect.ValueOf(&cpy).Elem()
+	for name, number := range forks {
+		index, _ := forkField(name)
+		value.Field(index).Set(reflect.ValueOf(new(big.Int).Set(number)))
+	}
+	return &cpy
+}
+
+// forkField returns the index of the chain configuration field holding the
+// activation block of the fork with the given JSON name.
+func forkField(name string) (int, bool) {
+	typ := reflect.TypeOf(params.ChainConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Type != reflect.TypeOf((*big.Int)(nil)) {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == name && strings.HasSuffix(tag, "Block") {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestForkOverridesValidation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	overrides := &ForkOverrides{ChainID: big.NewInt(1), Forks: map[string]*big.Int{"berlinBlock": big.NewInt(50)}}
+	manifest, err := SignForkOverrides(overrides, key)
+	if err != nil {
+		t.Fatalf("failed to sign overrides: %v", err)
+	}
+	if _, err := decodeForkOverrides(manifest, nil); !errors.Is(err, errNoOverrideSigners) {
+		t.Errorf("manifest accepted without signers: %v", err)
+	}
+	if _, err := decodeForkOverrides(manifest, []common.Address{crypto.PubkeyToAddress(other.PublicKey)}); !errors.Is(err, errUnauthorizedSigner) {
+		t.Errorf("manifest accepted from untrusted signer: %v", err)
+	}
+	have, err := decodeForkOverrides(manifest, []common.Address{signer})
+	if err != nil {
+		t.Fatalf("failed to decode manifest: %v", err)
+	}
+	if have.Forks["berlinBlock"].Uint64() != 50 {
+		t.Errorf("berlin override mismatch: have %v, want 50", have.Forks["berlinBlock"])
+	}
+	// Tampering with the signed overrides must invalidate the signature
+	tampered := []byte(string(manifest))
+	for i := range tampered {
+		if tampered[i] == '5' {
+			tampered[i] = '6'
+			break
+		}
+	}
+	if _, err := decodeForkOverrides(tampered, []common.Address{signer}); err == nil {
+		t.Errorf("tampered manifest accepted")
+	}
+	// Only fork blocks of the chain config can be overridden
+	for _, name := range []string{"chainId", "parlia", "fooBlock"} {
+		bad, _ := SignForkOverrides(&ForkOverrides{ChainID: big.NewInt(1), Forks: map[string]*big.Int{name: big.NewInt(1)}}, key)
+		if _, err := decodeForkOverrides(bad, []common.Address{signer}); err == nil {
+			t.Errorf("override of %q accepted", name)
+		}
+	}
+}
+
+func TestSetupGenesisForkOverrides(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BerlinBlock = big.NewInt(100)
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&Genesis{Config: &config}).MustCommit(db)
+
+	// Reschedule the upcoming fork of the custom chain
+	overrides := &ForkOverrides{ChainID: config.ChainID, Forks: map[string]*big.Int{"berlinBlock": big.NewInt(50)}}
+	have, _, err := SetupGenesisBlockWithOverride(db, nil, nil, overrides)
+	if err != nil {
+		t.Fatalf("failed to apply overrides: %v", err)
+	}
+	if have.BerlinBlock.Uint64() != 50 {
+		t.Errorf("berlin block mismatch: have %v, want 50", have.BerlinBlock)
+	}
+	if stored := rawdb.ReadChainConfig(db, genesis.Hash()); stored.BerlinBlock.Uint64() != 50 {
+		t.Errorf("stored berlin block mismatch: have %v, want 50", stored.BerlinBlock)
+	}
+	if config.BerlinBlock.Uint64() != 100 {
+		t.Errorf("overrides modified the original config")
+	}
+	// Overrides for another chain are refused
+	wrong := &ForkOverrides{ChainID: big.NewInt(2), Forks: overrides.Forks}
+	if _, _, err := SetupGenesisBlockWithOverride(db, nil, nil, wrong); err == nil {
+		t.Errorf("overrides of another chain accepted")
+	}
+	// Once the fork is active it can't be moved anymore
+	head := common.Hash{0x01}
+	rawdb.WriteHeaderNumber(db, head, 60)
+	rawdb.WriteHeadHeaderHash(db, head)
+
+	overrides.Forks["berlinBlock"] = big.NewInt(80)
+	_, _, err = SetupGenesisBlockWithOverride(db, nil, nil, overrides)
+	if _, ok := err.(*params.ConfigCompatError); !ok {
+		t.Errorf("override of an active fork accepted: %v", err)
+	}
+}
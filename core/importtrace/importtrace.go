@@ -0,0 +1,159 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package importtrace traces the import of blocks through the node, from their
+// arrival over the network up to their commit to the chain.
+//
+// Every stage of the import is logged as a structured event carrying the trace
+// ID of the block. The ID is derived from the block hash, so the stages don't
+// need to pass it along. The stages can also be exported as OpenTelemetry spans.
+package importtrace
+
+import (
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/internal/otlp"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// arrivalLimit is the number of recent block arrivals to keep for the span
+// covering the whole import of the blocks.
+const arrivalLimit = 1024
+
+// The stages of the import of a block.
+const (
+	StageAnnounced = "announced" // Block hash announced by a peer
+	StageReceived  = "received"  // Block received from a peer
+	StageQueued    = "queued"    // Block waited in the fetcher queue
+	StageVerified  = "verified"  // Header verified before propagation
+	StageExecuted  = "executed"  // Transactions executed
+	StageValidated = "validated" // Resulting state validated
+	StageCommitted = "committed" // Block and state written to the chain
+	StageImported  = "imported"  // Whole import, from arrival to commit
+)
+
+var (
+	// arrivals keeps when the recent blocks were first seen.
+	arrivals, _ = lru.New(arrivalLimit)
+
+	exporter     *otlp.Exporter // Span exporter, nil if the spans aren't exported
+	exporterLock sync.RWMutex
+)
+
+// EnableOTLP exports the import stages as OpenTelemetry spans to the given
+// collector endpoint, usually ending in /v1/traces.
+func EnableOTLP(endpoint string) {
+	exporterLock.Lock()
+	defer exporterLock.Unlock()
+
+	if exporter != nil {
+		exporter.Close()
+	}
+	exporter = otlp.NewExporter(endpoint, "importtrace")
+}
+
+// DisableOTLP stops exporting the import stages, sending the queued spans first.
+func DisableOTLP() {
+	exporterLock.Lock()
+	defer exporterLock.Unlock()
+
+	if exporter != nil {
+		exporter.Close()
+		exporter = nil
+	}
+}
+
+// activeExporter returns the span exporter, or nil if the spans aren't exported.
+func activeExporter() *otlp.Exporter {
+	exporterLock.RLock()
+	defer exporterLock.RUnlock()
+
+	return exporter
+}
+
+// ID returns the trace ID of the import of a block.
+func ID(hash common.Hash) otlp.TraceID {
+	var id otlp.TraceID
+	copy(id[:], hash[:16])
+	return id
+}
+
+// rootSpan returns the span ID of the whole import of a block, parenting the
+// spans of its stages.
+func rootSpan(hash common.Hash) otlp.SpanID {
+	var id otlp.SpanID
+	copy(id[:], hash[16:24])
+	return id
+}
+
+// Arrived marks the arrival of a block over the network, starting its trace. The
+// stage is announced or received, and the context holds extra key/value pairs
+// like the peer it arrived from.
+func Arrived(hash common.Hash, number uint64, stage string, ctx ...interface{}) {
+	now := time.Now()
+	arrivals.ContainsOrAdd(hash, now)
+	Record(hash, number, stage, now, ctx...)
+}
+
+// Record logs a stage of the import of a block which started at the given time
+// and just ended, and exports it as a span if enabled. The context holds extra
+// key/value pairs, with an "err" key marking a failed stage.
+func Record(hash common.Hash, number uint64, stage string, start time.Time, ctx ...interface{}) {
+	record(hash, number, stage, otlp.NewSpanID(), rootSpan(hash), start, ctx)
+}
+
+// Imported completes the trace of a block after it was committed, recording the
+// span covering the whole import, from its arrival if it came over the network,
+// or else from the given start of its processing.
+func Imported(hash common.Hash, number uint64, start time.Time, ctx ...interface{}) {
+	if arrival, ok := arrivals.Get(hash); ok {
+		start = arrival.(time.Time)
+		arrivals.Remove(hash)
+	}
+	record(hash, number, StageImported, rootSpan(hash), otlp.SpanID{}, start, ctx)
+}
+
+// record logs a stage of the import of a block and exports it as a span with the
+// given identifier and parent, if enabled.
+func record(hash common.Hash, number uint64, stage string, id, parent otlp.SpanID, start time.Time, ctx []interface{}) {
+	end := time.Now()
+
+	// Drop the unset values, like the errors of the successful stages
+	for i := 0; i+1 < len(ctx); {
+		if ctx[i+1] == nil {
+			ctx = append(ctx[:i:i], ctx[i+2:]...)
+			continue
+		}
+		i += 2
+	}
+	log.Debug("Block import stage", append([]interface{}{"trace", ID(hash), "stage", stage, "number", number, "hash", hash, "elapsed", common.PrettyDuration(end.Sub(start))}, ctx...)...)
+
+	if exp := activeExporter(); exp != nil {
+		exp.Export(&otlp.Span{
+			Trace:  ID(hash),
+			ID:     id,
+			Parent: parent,
+			Name:   stage,
+			Start:  start,
+			End:    end,
+			Attrs:  append([]interface{}{"number", number, "hash", hash}, ctx...),
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package importtrace
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// exportedSpan is the part of an OTLP span checked by the tests.
+type exportedSpan struct {
+	TraceID           string `json:"traceId"`
+	SpanID            string `json:"spanId"`
+	ParentSpanID      string `json:"parentSpanId"`
+	Name              string `json:"name"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	Status            *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+func TestOTLPExport(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		spans []exportedSpan
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, resource := range req.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				spans = append(spans, scope.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	EnableOTLP(server.URL)
+	hash := common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+
+	Arrived(hash, 1, StageReceived, "peer", "test")
+	arrival := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	Record(hash, 1, StageExecuted, time.Now(), "txs", 2, "err", errors.New("bad block"))
+	Imported(hash, 1, time.Now(), "canonical", true)
+	DisableOTLP()
+
+	if len(spans) != 3 {
+		t.Fatalf("exported span count mismatch: have %d, want 3", len(spans))
+	}
+	var (
+		trace = hex.EncodeToString(hash[:16])
+		root  = hex.EncodeToString(hash[16:24])
+	)
+	for i, name := range []string{StageReceived, StageExecuted, StageImported} {
+		span := spans[i]
+		if span.Name != name {
+			t.Errorf("span %d: name mismatch: have %s, want %s", i, span.Name, name)
+		}
+		if span.TraceID != trace {
+			t.Errorf("span %d: trace ID mismatch: have %s, want %s", i, span.TraceID, trace)
+		}
+		if name == StageImported {
+			if span.SpanID != root || span.ParentSpanID != "" {
+				t.Errorf("root span ID mismatch: have %s (parent %s), want %s", span.SpanID, span.ParentSpanID, root)
+			}
+		} else if span.ParentSpanID != root {
+			t.Errorf("span %d: parent mismatch: have %s, want %s", i, span.ParentSpanID, root)
+		}
+	}
+	if status := spans[1].Status; status == nil || status.Message != "bad block" {
+		t.Errorf("failed stage status mismatch: have %+v", status)
+	}
+	// The whole import spans from the arrival of the block
+	start, _ := strconv.ParseInt(spans[2].StartTimeUnixNano, 10, 64)
+	if start > arrival.UnixNano() {
+		t.Errorf("import span starts after the arrival: %d > %d", start, arrival.UnixNano())
+	}
+}
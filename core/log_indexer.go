@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// logIndexEntry is a single address or positional topic of a log.
+type logIndexEntry struct {
+	kind  byte
+	value common.Hash
+}
+
+// LogIndexer implements a core.ChainIndexer, building up a precise index from
+// the addresses and the positional topics of the logs to the blocks containing
+// them. Contrary to the bloombits, looking up an address or a topic yields no
+// false positive blocks.
+type LogIndexer struct {
+	db      ethdb.Database                        // database instance to write index data and metadata into
+	entries map[logIndexEntry]map[uint64]struct{} // entries of the section being processed
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{db: db}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.entries = make(map[logIndexEntry]map[uint64]struct{})
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a block into
+// the index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	if header.Bloom == (types.Bloom{}) {
+		return nil // no logs in the block
+	}
+	number, hash := header.Number.Uint64(), header.Hash()
+
+	receipts := rawdb.ReadRawReceipts(l.db, hash, number)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d [%x..] not found", number, hash[:4])
+	}
+	add := func(kind byte, value common.Hash) {
+		entry := logIndexEntry{kind: kind, value: value}
+		if l.entries[entry] == nil {
+			l.entries[entry] = make(map[uint64]struct{})
+		}
+		l.entries[entry][number] = struct{}{}
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			add(rawdb.LogIndexAddress, common.BytesToHash(log.Address.Bytes()))
+			for i, topic := range log.Topics {
+				add(rawdb.LogIndexTopic0+byte(i), topic)
+			}
+		}
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the entries of the section
+// into the database.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+	for entry, blocks := range l.entries {
+		for number := range blocks {
+			rawdb.WriteLogIndexEntry(batch, entry.kind, entry.value, number)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
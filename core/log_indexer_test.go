@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the log indexer maps the addresses and the positional topics of the
+// logs to exactly the blocks containing them.
+func TestLogIndexer(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		emitter = common.HexToAddress("0xeeee")
+		db      = rawdb.NewMemoryDatabase()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		topic   = common.HexToHash("0x01")
+		// PUSH1 1 PUSH1 0 PUSH1 0 LOG1 STOP
+		contract = common.FromHex("0x600160006000a100")
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender:  {Balance: big.NewInt(params.Ether)},
+			emitter: {Balance: common.Big0, Code: contract},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 5, func(i int, gen *BlockGen) {
+		// Emit a log in every other block
+		if i%2 == 1 {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), emitter, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexer := &LogIndexer{db: db}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for _, block := range blocks {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatalf("failed to process block #%d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit index: %v", err)
+	}
+	tests := []struct {
+		kind  byte
+		value common.Hash
+		from  uint64
+		to    uint64
+		want  []uint64
+	}{
+		{rawdb.LogIndexAddress, common.BytesToHash(emitter.Bytes()), 0, 5, []uint64{2, 4}},
+		{rawdb.LogIndexAddress, common.BytesToHash(emitter.Bytes()), 3, 5, []uint64{4}},
+		{rawdb.LogIndexAddress, common.BytesToHash(sender.Bytes()), 0, 5, nil},
+		{rawdb.LogIndexTopic0, topic, 0, 3, []uint64{2}},
+		{rawdb.LogIndexTopic0 + 1, topic, 0, 5, nil},
+	}
+	for i, tt := range tests {
+		have, err := rawdb.ReadLogIndexBlocks(db, tt.kind, tt.value, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("test %d: failed to read index: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: blocks mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// ParallelStateProcessor is a Processor executing the transactions of a block
+// speculatively in parallel, each of them on top of the state at the start of
+// the block. The transactions are then committed in order: the effects of the
+// ones whose read set wasn't modified by any preceding transaction are replayed
+// on the real state, the others are re-executed serially.
+//
+// ParallelStateProcessor implements Processor.
+type ParallelStateProcessor struct {
+	StateProcessor
+	workers int // Number of transactions executed concurrently
+}
+
+// NewParallelStateProcessor initialises a new ParallelStateProcessor. Passing
+// no workers uses one per CPU.
+func NewParallelStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine, workers int) *ParallelStateProcessor {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &ParallelStateProcessor{
+		StateProcessor: *NewStateProcessor(config, bc, engine),
+		workers:        workers,
+	}
+}
+
+// speculation is the outcome of executing a transaction on a copy of the state
+// at the start of the block.
+type speculation struct {
+	tx     *types.Transaction
+	index  int
+	msg    types.Message
+	result *ExecutionResult
+	rwSet  *state.RWSet
+	err    error
+	done   chan struct{}
+}
+
+// Process processes the state changes according to the Ethereum rules, the same
+// way StateProcessor does, executing the transactions in parallel.
+func (p *ParallelStateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (*state.StateDB, types.Receipts, []*types.Log, uint64, error) {
+	// Tracing needs the transactions executed exactly once and in order, and
+	// pre-Byzantium receipts need the intermediate roots, process those serially
+	if p.workers < 2 || cfg.Debug || !p.config.IsByzantium(block.Number()) || len(block.Transactions()) < 2 {
+		return p.StateProcessor.Process(block, statedb, cfg)
+	}
+	var (
+		usedGas = new(uint64)
+		header  = block.Header()
+		allLogs []*types.Log
+		gp      = new(GasPool).AddGas(block.GasLimit())
+	)
+	signer := types.MakeSigner(p.bc.chainConfig, block.Number())
+	statedb.TryPreload(block, signer)
+	var receipts = make([]*types.Receipt, 0)
+	// Mutate the block and state according to any hard-fork specs
+	if p.config.DAOForkSupport && p.config.DAOForkBlock != nil && p.config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		misc.ApplyDAOHardFork(statedb)
+	}
+	txNum := len(block.Transactions())
+	posa, isPoSA := p.engine.(consensus.PoSA)
+	commonTxs := make([]*types.Transaction, 0, txNum)
+
+	// Split off the system transactions, they are applied by the engine
+	systemTxs := make([]*types.Transaction, 0, 2)
+	specs := make([]*speculation, 0, txNum)
+	for i, tx := range block.Transactions() {
+		if isPoSA {
+			if isSystemTx, err := posa.IsSystemTransaction(tx, block.Header()); err != nil {
+				return statedb, nil, nil, 0, err
+			} else if isSystemTx {
+				systemTxs = append(systemTxs, tx)
+				continue
+			}
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return statedb, nil, nil, 0, err
+		}
+		specs = append(specs, &speculation{tx: tx, index: i, msg: msg, done: make(chan struct{})})
+	}
+	// Start executing the transactions speculatively, each worker on its own
+	// copy of the state at the start of the block
+	var (
+		next int64
+		quit = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	workers := p.workers
+	if workers > len(specs) {
+		workers = len(specs)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(base *state.StateDB) {
+			defer wg.Done()
+
+			evm := vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, base, p.config, cfg)
+			for {
+				n := int(atomic.AddInt64(&next, 1) - 1)
+				if n >= len(specs) {
+					return
+				}
+				select {
+				case <-quit:
+					return
+				default:
+				}
+				p.speculate(evm, base, block, specs[n])
+			}
+		}(statedb.Copy())
+	}
+	defer func() {
+		close(quit)
+		wg.Wait()
+	}()
+
+	// Commit the transactions in order, re-executing the ones which read state
+	// modified by a preceding transaction
+	bloomProcessors := NewAsyncReceiptBloomGenerator(txNum)
+	statedb.MarkFullProcessed()
+
+	vmenv := vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, statedb, p.config, cfg)
+	written := make(map[state.StateKey]struct{})
+	for _, spec := range specs {
+		<-spec.done
+
+		var (
+			receipt *types.Receipt
+			rwSet   *state.RWSet
+			err     error
+		)
+		statedb.Prepare(spec.tx.Hash(), block.Hash(), spec.index)
+		if spec.err != nil || spec.rwSet.Conflicts(written) {
+			parallelConflictMeter.Mark(1)
+
+			statedb.StartRWSet()
+			receipt, err = applyTransaction(spec.msg, p.config, p.bc, nil, gp, statedb, header, spec.tx, usedGas, vmenv, bloomProcessors)
+			rwSet = statedb.StopRWSet()
+		} else {
+			receipt, err = p.commit(statedb, gp, header, spec, usedGas, bloomProcessors)
+			rwSet = spec.rwSet
+		}
+		if err != nil {
+			bloomProcessors.Close()
+			return statedb, nil, nil, 0, fmt.Errorf("could not apply tx %d [%v]: %w", spec.index, spec.tx.Hash().Hex(), err)
+		}
+		for key := range rwSet.Writes {
+			written[key] = struct{}{}
+		}
+		commonTxs = append(commonTxs, spec.tx)
+		receipts = append(receipts, receipt)
+	}
+	bloomProcessors.Close()
+	parallelTxMeter.Mark(int64(len(specs)))
+
+	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	err := p.engine.Finalize(p.bc, header, statedb, &commonTxs, block.Uncles(), &receipts, &systemTxs, usedGas)
+	if err != nil {
+		return statedb, receipts, allLogs, *usedGas, err
+	}
+	for _, receipt := range receipts {
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	return statedb, receipts, allLogs, *usedGas, nil
+}
+
+// speculate executes a transaction on top of the base state, recording the
+// state it accessed, and reverts the base state afterwards.
+func (p *ParallelStateProcessor) speculate(evm *vm.EVM, base *state.StateDB, block *types.Block, spec *speculation) {
+	defer close(spec.done)
+
+	snap := base.Snapshot()
+	base.Prepare(spec.tx.Hash(), block.Hash(), spec.index)
+	base.StartRWSet()
+
+	// The gas pool is checked when the transaction is committed
+	evm.Reset(NewEVMTxContext(spec.msg), base)
+	spec.result, spec.err = ApplyMessage(evm, spec.msg, new(GasPool).AddGas(block.GasLimit()))
+
+	spec.rwSet = base.StopRWSet()
+	base.RevertToSnapshot(snap)
+}
+
+// commit applies the effects of a speculatively executed transaction to the
+// statedb and creates its receipt.
+func (p *ParallelStateProcessor) commit(statedb *state.StateDB, gp *GasPool, header *types.Header, spec *speculation, usedGas *uint64, receiptProcessors ...ReceiptProcessor) (*types.Receipt, error) {
+	if err := gp.SubGas(spec.msg.Gas()); err != nil {
+		return nil, err
+	}
+	gp.AddGas(spec.msg.Gas() - spec.result.UsedGas)
+
+	statedb.ApplyRWSet(spec.rwSet)
+	statedb.Finalise(true)
+	*usedGas += spec.result.UsedGas
+
+	receipt := newReceipt(spec.msg, spec.tx, header, statedb, spec.result, nil, *usedGas)
+	for _, receiptProcessor := range receiptProcessors {
+		receiptProcessor.Apply(receipt)
+	}
+	return receipt, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that importing blocks with the parallel processor yields the same
+// state and receipts as the serial one, for independent transactions as well as
+// for all kinds of conflicts between them.
+func TestParallelStateProcessor(t *testing.T) {
+	var (
+		counter  = common.Address{0xcc} // Increments slot 0
+		reader   = common.Address{0xcd} // Stores the balance of the coinbase
+		suicider = common.Address{0xce} // Self-destructs to the caller
+		coinbase = common.Address{0xc0}
+
+		keys  = make([]*ecdsa.PrivateKey, 8)
+		addrs = make([]common.Address, len(keys))
+		funds = big.NewInt(params.Ether)
+		alloc = GenesisAlloc{
+			counter:  {Code: common.FromHex("600054600101600055"), Balance: new(big.Int)},
+			reader:   {Code: common.FromHex("4131600055"), Balance: new(big.Int)},
+			suicider: {Code: common.FromHex("33ff"), Balance: funds},
+		}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	var (
+		gendb  = rawdb.NewMemoryDatabase()
+		gspec  = &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	genesis := gspec.MustCommit(gendb)
+
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 4, func(n int, b *BlockGen) {
+		b.SetCoinbase(coinbase)
+
+		send := func(key int, to *common.Address, value int64, gas uint64, data []byte) {
+			addr := addrs[key]
+			tx := types.MustSignNewTx(keys[key], signer, &types.LegacyTx{
+				Nonce:    b.TxNonce(addr),
+				To:       to,
+				Value:    big.NewInt(value),
+				Gas:      gas,
+				GasPrice: big.NewInt(params.GWei),
+				Data:     data,
+			})
+			b.AddTx(tx)
+		}
+		// Independent transfers to fresh accounts
+		for i := range keys {
+			send(i, &common.Address{byte(n), byte(i), 0x01}, 1000, params.TxGas, nil)
+		}
+		// Chained transfers, each spending funds received in the block
+		send(0, &addrs[1], 1000, params.TxGas, nil)
+		send(1, &addrs[2], 2000, params.TxGas, nil)
+		send(0, &addrs[2], 3000, params.TxGas, nil)
+
+		// Storage conflicts, coinbase reads and self-destructs
+		for i := 0; i < 3; i++ {
+			send(i, &counter, 0, 100000, nil)
+		}
+		send(3, &reader, 0, 100000, nil)
+		send(4, &suicider, 0, 100000, nil)
+
+		// Touching empty accounts and deploying contracts
+		send(5, &common.Address{byte(n), 0x02}, 0, params.TxGas, nil)
+		send(6, nil, 0, 100000, common.FromHex("6009600c60003960096000f3600054600101600055"))
+		send(6, &common.Address{byte(n), 0x03}, 1, params.TxGas, nil)
+	})
+	serialdb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(serialdb)
+	serial, _ := NewBlockChain(serialdb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer serial.Stop()
+
+	if _, err := serial.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks serially: %v", err)
+	}
+	paralleldb := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(paralleldb)
+	parallel, _ := NewBlockChain(paralleldb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableParallelProcessor(4))
+	defer parallel.Stop()
+
+	if _, err := parallel.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert blocks in parallel: %v", err)
+	}
+	for _, block := range blocks {
+		have := parallel.GetReceiptsByHash(block.Hash())
+		want := serial.GetReceiptsByHash(block.Hash())
+		if len(have) != len(want) {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want %d", block.NumberU64(), len(have), len(want))
+		}
+		for i := range have {
+			if have[i].Status != want[i].Status || have[i].GasUsed != want[i].GasUsed || have[i].ContractAddress != want[i].ContractAddress || len(have[i].Logs) != len(want[i].Logs) {
+				t.Fatalf("block %d: receipt %d mismatch: have %+v, want %+v", block.NumberU64(), i, have[i], want[i])
+			}
+		}
+	}
+}
@@ -252,6 +252,72 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadTxIndexAllowlist retrieves the addresses whose transactions are indexed
+// below the tx index tail, nil if the index is not restricted to an allowlist.
+func ReadTxIndexAllowlist(db ethdb.KeyValueReader) []common.Address {
+	data, _ := db.Get(txIndexAllowlistKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var addresses []common.Address
+	if err := rlp.DecodeBytes(data, &addresses); err != nil {
+		log.Error("Invalid transaction index allowlist", "err", err)
+		return nil
+	}
+	return addresses
+}
+
+// WriteTxIndexAllowlist stores the addresses whose transactions are indexed
+// below the tx index tail.
+func WriteTxIndexAllowlist(db ethdb.KeyValueWriter, addresses []common.Address) {
+	data, err := rlp.EncodeToBytes(addresses)
+	if err != nil {
+		log.Crit("Failed to encode transaction index allowlist", "err", err)
+	}
+	if err := db.Put(txIndexAllowlistKey, data); err != nil {
+		log.Crit("Failed to store transaction index allowlist", "err", err)
+	}
+}
+
+// DeleteTxIndexAllowlist removes the transaction index allowlist along with its
+// indexing progress.
+func DeleteTxIndexAllowlist(db ethdb.KeyValueWriter) {
+	if err := db.Delete(txIndexAllowlistKey); err != nil {
+		log.Crit("Failed to delete transaction index allowlist", "err", err)
+	}
+	if err := db.Delete(txIndexAllowlistTailKey); err != nil {
+		log.Crit("Failed to delete transaction index allowlist tail", "err", err)
+	}
+}
+
+// ReadTxIndexAllowlistTail retrieves the number of the oldest block whose
+// transactions of the allowlisted addresses have been indexed, nil if their
+// indexing below the tx index tail has not started yet.
+func ReadTxIndexAllowlistTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(txIndexAllowlistTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTxIndexAllowlistTail stores the number of the oldest block whose
+// transactions of the allowlisted addresses have been indexed.
+func WriteTxIndexAllowlistTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(txIndexAllowlistTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the transaction index allowlist tail", "err", err)
+	}
+}
+
+// DeleteTxIndexAllowlistTail removes the allowlist tail, restarting the indexing
+// of the transactions of the allowlisted addresses below the tx index tail.
+func DeleteTxIndexAllowlistTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(txIndexAllowlistTailKey); err != nil {
+		log.Crit("Failed to delete transaction index allowlist tail", "err", err)
+	}
+}
+
 // ReadFastTxLookupLimit retrieves the tx lookup limit used in fast sync.
 func ReadFastTxLookupLimit(db ethdb.KeyValueReader) *uint64 {
 	data, _ := db.Get(fastTxLookupLimitKey)
@@ -669,6 +735,111 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadBlockTracesTail retrieves the number of the oldest block whose traces have
+// been recorded, nil if the trace recording was never enabled.
+func ReadBlockTracesTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(blockTracesTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteBlockTracesTail stores the number of the oldest block whose traces have
+// been recorded.
+func WriteBlockTracesTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(blockTracesTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the block traces tail", "err", err)
+	}
+}
+
+// ReadBlockTraces retrieves the traces recorded while importing a block, nil if
+// the block was not traced.
+func ReadBlockTraces(db ethdb.KeyValueReader, hash common.Hash, number uint64) *types.BlockTraces {
+	data, _ := db.Get(blockTracesKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	traces := new(types.BlockTraces)
+	if err := rlp.DecodeBytes(data, traces); err != nil {
+		log.Error("Invalid block traces RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return traces
+}
+
+// WriteBlockTraces stores the traces recorded while importing a block.
+func WriteBlockTraces(db ethdb.KeyValueWriter, hash common.Hash, number uint64, traces *types.BlockTraces) {
+	data, err := rlp.EncodeToBytes(traces)
+	if err != nil {
+		log.Crit("Failed to encode block traces", "err", err)
+	}
+	if err := db.Put(blockTracesKey(number, hash), data); err != nil {
+		log.Crit("Failed to store block traces", "err", err)
+	}
+}
+
+// DeleteBlockTraces removes the traces recorded for a block.
+func DeleteBlockTraces(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockTracesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block traces", "err", err)
+	}
+}
+
+// ReadBlockRevertsTail retrieves the number of the oldest block whose transaction
+// failures have been recorded, nil if the recording was never enabled.
+func ReadBlockRevertsTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(blockRevertsTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteBlockRevertsTail stores the number of the oldest block whose transaction
+// failures have been recorded.
+func WriteBlockRevertsTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(blockRevertsTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the block reverts tail", "err", err)
+	}
+}
+
+// ReadBlockReverts retrieves the transaction failures recorded while importing
+// a block, nil if the block was not recorded.
+func ReadBlockReverts(db ethdb.KeyValueReader, hash common.Hash, number uint64) *types.BlockReverts {
+	data, _ := db.Get(blockRevertsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	reverts := new(types.BlockReverts)
+	if err := rlp.DecodeBytes(data, reverts); err != nil {
+		log.Error("Invalid block reverts RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return reverts
+}
+
+// WriteBlockReverts stores the transaction failures recorded while importing a
+// block.
+func WriteBlockReverts(db ethdb.KeyValueWriter, hash common.Hash, number uint64, reverts *types.BlockReverts) {
+	data, err := rlp.EncodeToBytes(reverts)
+	if err != nil {
+		log.Crit("Failed to encode block reverts", "err", err)
+	}
+	if err := db.Put(blockRevertsKey(number, hash), data); err != nil {
+		log.Crit("Failed to store block reverts", "err", err)
+	}
+}
+
+// DeleteBlockReverts removes the transaction failures recorded for a block.
+func DeleteBlockReverts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockRevertsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block reverts", "err", err)
+	}
+}
+
 // ReadBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -837,6 +1008,52 @@ func DeleteBadBlocks(db ethdb.KeyValueWriter) {
 	}
 }
 
+// ReorgRecord describes a reorganization of the canonical chain.
+type ReorgRecord struct {
+	Time           uint64        // Unix time of the reorg in nanoseconds
+	AncestorNumber uint64        // Number of the common ancestor of both chains
+	AncestorHash   common.Hash   // Hash of the common ancestor of both chains
+	OldChain       []common.Hash // Dropped blocks, in ascending order
+	NewChain       []common.Hash // Blocks replacing them, in ascending order
+}
+
+// WriteReorg stores a reorg record in the reorg log.
+func WriteReorg(db ethdb.KeyValueWriter, reorg *ReorgRecord) {
+	data, err := rlp.EncodeToBytes(reorg)
+	if err != nil {
+		log.Crit("Failed to encode reorg record", "err", err)
+	}
+	if err := db.Put(reorgKey(reorg.AncestorNumber, reorg.Time), data); err != nil {
+		log.Crit("Failed to store reorg record", "err", err)
+	}
+}
+
+// ReadReorgs retrieves the reorg records whose common ancestor is in the [from, to]
+// block range, ordered by ancestor number and time. At most limit records are
+// returned.
+func ReadReorgs(db ethdb.Iteratee, from, to uint64, limit int) ([]*ReorgRecord, error) {
+	it := db.NewIterator(reorgPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var reorgs []*ReorgRecord
+	for len(reorgs) < limit && it.Next() {
+		key := it.Key()
+		if len(key) != len(reorgPrefix)+16 {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(reorgPrefix):]) > to {
+			break
+		}
+		reorg := new(ReorgRecord)
+		if err := rlp.DecodeBytes(it.Value(), reorg); err != nil {
+			log.Error("Invalid reorg record in database", "err", err)
+			continue
+		}
+		reorgs = append(reorgs, reorg)
+	}
+	return reorgs, it.Error()
+}
+
 // FindCommonAncestor returns the last common ancestor of two block headers
 func FindCommonAncestor(db ethdb.Reader, a, b *types.Header) *types.Header {
 	for bn := b.Number.Uint64(); a.Number.Uint64() > bn; {
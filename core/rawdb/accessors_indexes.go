@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,431 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// InternalCall is an entry of the internal call index: a transaction of the
+// given block made an internal call from or to the indexed address.
+type InternalCall struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint32
+}
+
+// ReadInternalCallIndexTail retrieves the number of the oldest block whose
+// internal calls have been indexed, nil if the index was never enabled.
+func ReadInternalCallIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(internalCallIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteInternalCallIndexTail stores the number of the oldest block whose
+// internal calls have been indexed.
+func WriteInternalCallIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(internalCallIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the internal call index tail", "err", err)
+	}
+}
+
+// WriteInternalCalls stores the addresses touched by the internal calls of each
+// transaction of a block, keyed by transaction index.
+func WriteInternalCalls(db ethdb.KeyValueWriter, hash common.Hash, number uint64, calls map[uint32][]common.Address) {
+	for index, addresses := range calls {
+		for _, address := range addresses {
+			if err := db.Put(internalCallKey(address, number, index), hash.Bytes()); err != nil {
+				log.Crit("Failed to store internal call index", "err", err)
+			}
+		}
+	}
+}
+
+// IterateInternalCalls invokes the callback on the internal call index entries
+// of an address in the [from, to] block range, in ascending order, until the
+// callback returns false. The entries of reorged blocks are kept in the index,
+// callers are expected to filter them out by checking the block hash against
+// the canonical chain.
+func IterateInternalCalls(db ethdb.Iteratee, address common.Address, from, to uint64, fn func(InternalCall) bool) error {
+	return iterateAddressTxs(db, internalCallPrefix, address, from, to, func(number uint64, hash common.Hash, index uint32) bool {
+		return fn(InternalCall{BlockNumber: number, BlockHash: hash, TxIndex: index})
+	})
+}
+
+// AccountTx is an entry of the account transaction index: a transaction of the
+// given block was sent by the indexed address, sent to it or created it.
+type AccountTx struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint32
+}
+
+// ReadAccountTxIndexTail retrieves the number of the oldest block whose
+// transactions have been indexed by account, nil if the index was never enabled.
+func ReadAccountTxIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(accountTxIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteAccountTxIndexTail stores the number of the oldest block whose
+// transactions have been indexed by account.
+func WriteAccountTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(accountTxIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the account transaction index tail", "err", err)
+	}
+}
+
+// WriteAccountTxs stores the accounts sending, receiving or created by each
+// transaction of a block, keyed by transaction index.
+func WriteAccountTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64, txs map[uint32][]common.Address) {
+	for index, addresses := range txs {
+		for _, address := range addresses {
+			if err := db.Put(accountTxKey(address, number, index), hash.Bytes()); err != nil {
+				log.Crit("Failed to store account transaction index", "err", err)
+			}
+		}
+	}
+}
+
+// IterateAccountTxs invokes the callback on the account transaction index
+// entries of an address in the [from, to] block range, in ascending order, until
+// the callback returns false. As for the internal call index, the entries of
+// reorged blocks are kept in the index.
+func IterateAccountTxs(db ethdb.Iteratee, address common.Address, from, to uint64, fn func(AccountTx) bool) error {
+	return iterateAddressTxs(db, accountTxPrefix, address, from, to, func(number uint64, hash common.Hash, index uint32) bool {
+		return fn(AccountTx{BlockNumber: number, BlockHash: hash, TxIndex: index})
+	})
+}
+
+// ContractCreation is an entry of the contract creation index: the transaction
+// of the given block deployed the indexed contract, created by the given account.
+type ContractCreation struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint32
+	Creator     common.Address // Sender of the transaction or contract running the creation
+}
+
+// ReadContractCreationIndexTail retrieves the number of the oldest block whose
+// contract creations have been indexed, nil if the index was never enabled.
+func ReadContractCreationIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(contractCreationIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteContractCreationIndexTail stores the number of the oldest block whose
+// contract creations have been indexed.
+func WriteContractCreationIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(contractCreationIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the contract creation index tail", "err", err)
+	}
+}
+
+// WriteContractCreation stores a contract creation index entry.
+func WriteContractCreation(db ethdb.KeyValueWriter, address common.Address, creation ContractCreation) {
+	value := append(creation.BlockHash.Bytes(), creation.Creator.Bytes()...)
+	if err := db.Put(contractCreationKey(address, creation.BlockNumber, creation.TxIndex), value); err != nil {
+		log.Crit("Failed to store contract creation index", "err", err)
+	}
+}
+
+// ReadContractCreations retrieves the contract creation index entries of an
+// address, in ascending order. An address has several entries if a contract
+// was deployed again at it, or if the creating block was reorged, callers are
+// expected to filter the entries by checking the block hash against the
+// canonical chain.
+func ReadContractCreations(db ethdb.Iteratee, address common.Address) ([]ContractCreation, error) {
+	prefix := append(append([]byte{}, contractCreationPrefix...), address.Bytes()...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var creations []ContractCreation
+	for it.Next() {
+		key, value := it.Key(), it.Value()
+		if len(key) != len(prefix)+12 || len(value) != common.HashLength+common.AddressLength {
+			continue
+		}
+		creations = append(creations, ContractCreation{
+			BlockNumber: binary.BigEndian.Uint64(key[len(prefix):]),
+			BlockHash:   common.BytesToHash(value[:common.HashLength]),
+			TxIndex:     binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Creator:     common.BytesToAddress(value[common.HashLength:]),
+		})
+	}
+	return creations, it.Error()
+}
+
+// The token standards whose transfers are indexed.
+const (
+	TokenERC20   uint16 = 20
+	TokenERC721  uint16 = 721
+	TokenERC1155 uint16 = 1155
+)
+
+// TokenTransfer is an entry of the token transfer index: a log of the given
+// block moved an amount of a token, or a token id, between two accounts.
+type TokenTransfer struct {
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxIndex     uint32
+	LogIndex    uint32
+	Token       common.Address // Contract emitting the transfer event
+	Standard    uint16
+	From        common.Address // Zero address for mints
+	To          common.Address // Zero address for burns
+	ID          *big.Int       // Token id, nil for ERC-20 transfers
+	Value       *big.Int       // Amount transferred, 1 for ERC-721 transfers
+}
+
+// storedTokenTransfer is the part of a token transfer index entry not held in
+// its key.
+type storedTokenTransfer struct {
+	BlockHash common.Hash
+	TxIndex   uint32
+	Token     common.Address
+	Standard  uint16
+	From      common.Address
+	To        common.Address
+	ID        *big.Int
+	Value     *big.Int
+}
+
+// ReadTokenTransferIndexTail retrieves the number of the oldest block whose
+// token transfers have been indexed, nil if the index was never enabled.
+func ReadTokenTransferIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(tokenTransferIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTokenTransferIndexTail stores the number of the oldest block whose token
+// transfers have been indexed.
+func WriteTokenTransferIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(tokenTransferIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the token transfer index tail", "err", err)
+	}
+}
+
+// WriteTokenTransfers stores the token transfers of a block, in log order, under
+// both the sender and the recipient. The zero address, the counterparty of the
+// mints and the burns, is not indexed.
+func WriteTokenTransfers(db ethdb.KeyValueWriter, hash common.Hash, number uint64, transfers []TokenTransfer) {
+	var item uint32
+	for i, transfer := range transfers {
+		// The items of an ERC-1155 batch share the log index
+		if i > 0 && transfers[i-1].LogIndex == transfer.LogIndex {
+			item++
+		} else {
+			item = 0
+		}
+		data, err := rlp.EncodeToBytes(&storedTokenTransfer{
+			BlockHash: hash,
+			TxIndex:   transfer.TxIndex,
+			Token:     transfer.Token,
+			Standard:  transfer.Standard,
+			From:      transfer.From,
+			To:        transfer.To,
+			ID:        transfer.ID,
+			Value:     transfer.Value,
+		})
+		if err != nil {
+			log.Crit("Failed to encode token transfer", "err", err)
+		}
+		for _, address := range []common.Address{transfer.From, transfer.To} {
+			if address == (common.Address{}) || (address == transfer.To && transfer.From == transfer.To) {
+				continue
+			}
+			if err := db.Put(tokenTransferKey(address, number, transfer.LogIndex, item), data); err != nil {
+				log.Crit("Failed to store token transfer index", "err", err)
+			}
+		}
+	}
+}
+
+// IterateTokenTransfers invokes the callback on the token transfer index entries
+// of an address in the [from, to] block range, in ascending order, until the
+// callback returns false. The entries of reorged blocks are kept in the index,
+// callers are expected to filter them out by checking the block hash against
+// the canonical chain.
+func IterateTokenTransfers(db ethdb.Iteratee, address common.Address, from, to uint64, fn func(TokenTransfer) bool) error {
+	var (
+		prefix = append(append([]byte{}, tokenTransferPrefix...), address.Bytes()...)
+		start  = tokenTransferKey(address, from, 0, 0)[len(prefix):]
+	)
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+16 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		var stored storedTokenTransfer
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			log.Error("Invalid token transfer index entry RLP", "address", address, "number", number, "err", err)
+			continue
+		}
+		transfer := TokenTransfer{
+			BlockNumber: number,
+			BlockHash:   stored.BlockHash,
+			TxIndex:     stored.TxIndex,
+			LogIndex:    binary.BigEndian.Uint32(key[len(prefix)+8:]),
+			Token:       stored.Token,
+			Standard:    stored.Standard,
+			From:        stored.From,
+			To:          stored.To,
+			ID:          stored.ID,
+			Value:       stored.Value,
+		}
+		if transfer.Standard == TokenERC20 {
+			transfer.ID = nil
+		}
+		if !fn(transfer) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// SenderTx is an entry of the sender transaction index: the transaction with the
+// indexed sender and nonce was included in the canonical block of the given
+// number.
+type SenderTx struct {
+	BlockNumber uint64
+	TxHash      common.Hash
+}
+
+// ReadSenderTxIndexTail retrieves the number of the oldest block whose
+// transactions have been indexed by sender, nil if the index was never enabled.
+func ReadSenderTxIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(senderTxIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteSenderTxIndexTail stores the number of the oldest block whose
+// transactions have been indexed by sender.
+func WriteSenderTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(senderTxIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the sender transaction index tail", "err", err)
+	}
+}
+
+// ReadSenderTx retrieves the sender transaction index entry of the transaction
+// sent by an address with the given nonce.
+func ReadSenderTx(db ethdb.KeyValueReader, sender common.Address, nonce uint64) *SenderTx {
+	data, _ := db.Get(senderTxKey(sender, nonce))
+	if len(data) != 8+common.HashLength {
+		return nil
+	}
+	return &SenderTx{
+		BlockNumber: binary.BigEndian.Uint64(data),
+		TxHash:      common.BytesToHash(data[8:]),
+	}
+}
+
+// WriteSenderTxs stores the sender transaction index entries of the transactions
+// of a canonical block.
+func WriteSenderTxs(db ethdb.KeyValueWriter, block *types.Block, signer types.Signer) {
+	for _, tx := range block.Transactions() {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+		hash := tx.Hash()
+		if err := db.Put(senderTxKey(sender, tx.Nonce()), append(encodeBlockNumber(block.NumberU64()), hash[:]...)); err != nil {
+			log.Crit("Failed to store sender transaction index", "err", err)
+		}
+	}
+}
+
+// DeleteSenderTx removes the sender transaction index entry of the transaction
+// sent by an address with the given nonce.
+func DeleteSenderTx(db ethdb.KeyValueWriter, sender common.Address, nonce uint64) {
+	if err := db.Delete(senderTxKey(sender, nonce)); err != nil {
+		log.Crit("Failed to delete sender transaction index", "err", err)
+	}
+}
+
+// iterateAddressTxs invokes the callback on the entries of an index of the
+// transactions by address in the [from, to] block range, in ascending order,
+// until the callback returns false.
+func iterateAddressTxs(db ethdb.Iteratee, indexPrefix []byte, address common.Address, from, to uint64, fn func(number uint64, hash common.Hash, index uint32) bool) error {
+	var (
+		prefix = append(append([]byte{}, indexPrefix...), address.Bytes()...)
+		start  = addressTxKey(indexPrefix, address, from, 0)[len(prefix):]
+	)
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+12 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		if !fn(number, common.BytesToHash(it.Value()), binary.BigEndian.Uint32(key[len(prefix)+8:])) {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// The kinds of the log index entries, the topics being indexed by position.
+const (
+	LogIndexAddress byte = 0
+	LogIndexTopic0  byte = 1
+)
+
+// WriteLogIndexEntry stores a log index entry, recording that the block with the
+// given number has a log with the given address or topic.
+func WriteLogIndexEntry(db ethdb.KeyValueWriter, kind byte, value common.Hash, number uint64) {
+	if err := db.Put(logIndexKey(kind, value, number), nil); err != nil {
+		log.Crit("Failed to store log index entry", "err", err)
+	}
+}
+
+// ReadLogIndexBlocks retrieves the numbers of the blocks in the [from, to] range
+// having a log with the given address or topic, in ascending order.
+func ReadLogIndexBlocks(db ethdb.Iteratee, kind byte, value common.Hash, from, to uint64) ([]uint64, error) {
+	var (
+		prefix = logIndexKey(kind, value, 0)[:len(logIndexPrefix)+1+common.HashLength]
+		start  = logIndexKey(kind, value, from)[len(prefix):]
+		blocks []uint64
+	)
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number > to {
+			break
+		}
+		blocks = append(blocks, number)
+	}
+	return blocks, it.Error()
+}
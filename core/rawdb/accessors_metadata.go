@@ -138,3 +138,112 @@ func PopUncleanShutdownMarker(db ethdb.KeyValueStore) {
 		log.Warn("Failed to clear unclean-shutdown marker", "err", err)
 	}
 }
+
+// blockCheckpoint is the last block processed by a chain follower, such as the
+// chain data exporter.
+type blockCheckpoint struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+// ReadExporterCheckpoint retrieves the hash and number of the last block published
+// by the chain data exporter. The returned bool is false if nothing was published yet.
+func ReadExporterCheckpoint(db ethdb.KeyValueReader) (common.Hash, uint64, bool) {
+	data, _ := db.Get(exporterCheckpointKey)
+	if len(data) == 0 {
+		return common.Hash{}, 0, false
+	}
+	var checkpoint blockCheckpoint
+	if err := rlp.DecodeBytes(data, &checkpoint); err != nil {
+		log.Error("Invalid exporter checkpoint in database", "err", err)
+		return common.Hash{}, 0, false
+	}
+	return checkpoint.Hash, checkpoint.Number, true
+}
+
+// WriteExporterCheckpoint stores the hash and number of the last block published
+// by the chain data exporter.
+func WriteExporterCheckpoint(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	enc, err := rlp.EncodeToBytes(blockCheckpoint{Hash: hash, Number: number})
+	if err != nil {
+		log.Crit("Failed to encode exporter checkpoint", "err", err)
+	}
+	if err := db.Put(exporterCheckpointKey, enc); err != nil {
+		log.Crit("Failed to store exporter checkpoint", "err", err)
+	}
+}
+
+// Webhook is an address watch-list registered with the webhook service, whose
+// activity is notified to the given URL.
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    []byte // Key of the HMAC signing the notifications
+	Addresses []common.Address
+}
+
+// ReadWebhooks retrieves all the webhooks registered with the webhook service.
+func ReadWebhooks(db ethdb.Iteratee) []*Webhook {
+	it := db.NewIterator(webhookPrefix, nil)
+	defer it.Release()
+
+	var hooks []*Webhook
+	for it.Next() {
+		hook := new(Webhook)
+		if err := rlp.DecodeBytes(it.Value(), hook); err != nil {
+			log.Error("Invalid webhook in database", "key", string(it.Key()), "err", err)
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// WriteWebhook stores a webhook registered with the webhook service.
+func WriteWebhook(db ethdb.KeyValueWriter, hook *Webhook) {
+	enc, err := rlp.EncodeToBytes(hook)
+	if err != nil {
+		log.Crit("Failed to encode webhook", "err", err)
+	}
+	if err := db.Put(append(append([]byte{}, webhookPrefix...), hook.ID...), enc); err != nil {
+		log.Crit("Failed to store webhook", "err", err)
+	}
+}
+
+// DeleteWebhook removes a webhook along with its checkpoint.
+func DeleteWebhook(db ethdb.KeyValueWriter, id string) {
+	if err := db.Delete(append(append([]byte{}, webhookPrefix...), id...)); err != nil {
+		log.Crit("Failed to delete webhook", "err", err)
+	}
+	if err := db.Delete(append(append([]byte{}, webhookCheckpointPrefix...), id...)); err != nil {
+		log.Crit("Failed to delete webhook checkpoint", "err", err)
+	}
+}
+
+// ReadWebhookCheckpoint retrieves the hash and number of the last block whose
+// activity was notified to a webhook. The returned bool is false if the webhook
+// has no checkpoint.
+func ReadWebhookCheckpoint(db ethdb.KeyValueReader, id string) (common.Hash, uint64, bool) {
+	data, _ := db.Get(append(append([]byte{}, webhookCheckpointPrefix...), id...))
+	if len(data) == 0 {
+		return common.Hash{}, 0, false
+	}
+	var checkpoint blockCheckpoint
+	if err := rlp.DecodeBytes(data, &checkpoint); err != nil {
+		log.Error("Invalid webhook checkpoint in database", "id", id, "err", err)
+		return common.Hash{}, 0, false
+	}
+	return checkpoint.Hash, checkpoint.Number, true
+}
+
+// WriteWebhookCheckpoint stores the hash and number of the last block whose
+// activity was notified to a webhook.
+func WriteWebhookCheckpoint(db ethdb.KeyValueWriter, id string, hash common.Hash, number uint64) {
+	enc, err := rlp.EncodeToBytes(blockCheckpoint{Hash: hash, Number: number})
+	if err != nil {
+		log.Crit("Failed to encode webhook checkpoint", "err", err)
+	}
+	if err := db.Put(append(append([]byte{}, webhookCheckpointPrefix...), id...), enc); err != nil {
+		log.Crit("Failed to store webhook checkpoint", "err", err)
+	}
+}
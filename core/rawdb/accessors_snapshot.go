@@ -216,3 +216,24 @@ func DeleteSnapshotSyncStatus(db ethdb.KeyValueWriter) {
 		log.Crit("Failed to remove snapshot sync status", "err", err)
 	}
 }
+
+// ReadSnapshotSyncHeal retrieves the serialized heal journal saved at shutdown.
+func ReadSnapshotSyncHeal(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(snapshotSyncHealKey)
+	return data
+}
+
+// WriteSnapshotSyncHeal stores the serialized heal journal to save at shutdown.
+func WriteSnapshotSyncHeal(db ethdb.KeyValueWriter, journal []byte) {
+	if err := db.Put(snapshotSyncHealKey, journal); err != nil {
+		log.Crit("Failed to store snapshot heal journal", "err", err)
+	}
+}
+
+// DeleteSnapshotSyncHeal deletes the serialized heal journal saved at the last
+// shutdown.
+func DeleteSnapshotSyncHeal(db ethdb.KeyValueWriter) {
+	if err := db.Delete(snapshotSyncHealKey); err != nil {
+		log.Crit("Failed to remove snapshot heal journal", "err", err)
+	}
+}
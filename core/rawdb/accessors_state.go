@@ -94,3 +94,25 @@ func DeleteTrieNode(db ethdb.KeyValueWriter, hash common.Hash) {
 		log.Crit("Failed to delete trie node", "err", err)
 	}
 }
+
+// ReadStatePruningProgress retrieves the last state entry key visited by an
+// interrupted state pruning.
+func ReadStatePruningProgress(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(statePruningProgressKey)
+	return data
+}
+
+// WriteStatePruningProgress stores the last state entry key visited by the
+// running state pruning.
+func WriteStatePruningProgress(db ethdb.KeyValueWriter, key []byte) {
+	if err := db.Put(statePruningProgressKey, key); err != nil {
+		log.Crit("Failed to store state pruning progress", "err", err)
+	}
+}
+
+// DeleteStatePruningProgress deletes the state pruning progress marker.
+func DeleteStatePruningProgress(db ethdb.KeyValueWriter) {
+	if err := db.Delete(statePruningProgressKey); err != nil {
+		log.Crit("Failed to remove state pruning progress", "err", err)
+	}
+}
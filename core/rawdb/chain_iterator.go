@@ -85,11 +85,16 @@ type blockTxHashes struct {
 	hashes []common.Hash
 }
 
+// TxFilter selects the transactions of the given block to be processed by the
+// transaction indexer.
+type TxFilter func(number uint64, tx *types.Transaction) bool
+
 // iterateTransactions iterates over all transactions in the (canon) block
-// number(s) given, and yields the hashes on a channel. If there is a signal
-// received from interrupt channel, the iteration will be aborted and result
-// channel will be closed.
-func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, interrupt chan struct{}) chan *blockTxHashes {
+// number(s) given, and yields the hashes on a channel. If a filter is given,
+// only the hashes of the transactions it selects are yielded. If there is a
+// signal received from interrupt channel, the iteration will be aborted and
+// result channel will be closed.
+func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool, filter TxFilter, interrupt chan struct{}) chan *blockTxHashes {
 	// One thread sequentially reads data from db
 	type numberRlp struct {
 		number uint64
@@ -145,6 +150,9 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 			}
 			var hashes []common.Hash
 			for _, tx := range body.Transactions {
+				if filter != nil && !filter(data.number, tx) {
+					continue
+				}
 				hashes = append(hashes, tx.Hash())
 			}
 			result := &blockTxHashes{
@@ -176,13 +184,13 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, filter TxFilter, writeTail func(ethdb.KeyValueWriter, uint64), hook func(uint64) bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, true, interrupt)
+		hashesCh = iterateTransactions(db, from, to, true, filter, interrupt)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -216,7 +224,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 			txs += len(delivery.hashes)
 			// If enough data was accumulated in memory or we're at the last block, dump to disk
 			if batch.ValueSize() > ethdb.IdealBatchSize {
-				WriteTxIndexTail(batch, lastNum) // Also write the tail here
+				writeTail(batch, lastNum) // Also write the tail here
 				if err := batch.Write(); err != nil {
 					log.Crit("Failed writing batch to db", "error", err)
 					return
@@ -233,7 +241,7 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 	// Flush the new indexing tail and the last committed data. It can also happen
 	// that the last batch is empty because nothing to index, but the tail has to
 	// be flushed anyway.
-	WriteTxIndexTail(batch, lastNum)
+	writeTail(batch, lastNum)
 	if err := batch.Write(); err != nil {
 		log.Crit("Failed writing batch to db", "error", err)
 		return
@@ -255,25 +263,33 @@ func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func IndexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	indexTransactions(db, from, to, interrupt, nil)
+	indexTransactions(db, from, to, interrupt, nil, WriteTxIndexTail, nil)
+}
+
+// IndexAllowlistedTransactions creates txlookup indices of the transactions of
+// the specified block range selected by the filter, the ones of the allowlisted
+// addresses, tracking the progress in the allowlist tail instead of the tx index
+// tail.
+func IndexAllowlistedTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, filter TxFilter) {
+	indexTransactions(db, from, to, interrupt, filter, WriteTxIndexAllowlistTail, nil)
 }
 
 // indexTransactionsForTesting is the internal debug version with an additional hook.
 func indexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	indexTransactions(db, from, to, interrupt, hook)
+	indexTransactions(db, from, to, interrupt, nil, WriteTxIndexTail, hook)
 }
 
 // unindexTransactions removes txlookup indices of the specified block range.
 //
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
-func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, filter TxFilter, hook func(uint64) bool) {
 	// short circuit for invalid range
 	if from >= to {
 		return
 	}
 	var (
-		hashesCh = iterateTransactions(db, from, to, false, interrupt)
+		hashesCh = iterateTransactions(db, from, to, false, filter, interrupt)
 		batch    = db.NewBatch()
 		start    = time.Now()
 		logged   = start.Add(-7 * time.Second)
@@ -342,10 +358,18 @@ func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}) {
-	unindexTransactions(db, from, to, interrupt, nil)
+	unindexTransactions(db, from, to, interrupt, nil, nil)
+}
+
+// UnindexTransactionsExcept removes the txlookup indices of the specified block
+// range, keeping the ones of the transactions selected by the filter.
+func UnindexTransactionsExcept(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, keep TxFilter) {
+	unindexTransactions(db, from, to, interrupt, func(number uint64, tx *types.Transaction) bool {
+		return !keep(number, tx)
+	}, nil)
 }
 
 // unindexTransactionsForTesting is the internal debug version with an additional hook.
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
-	unindexTransactions(db, from, to, interrupt, hook)
+	unindexTransactions(db, from, to, interrupt, nil, hook)
 }
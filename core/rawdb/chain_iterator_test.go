@@ -80,7 +80,7 @@ func TestChainIterator(t *testing.T) {
 	}
 	for i, c := range cases {
 		var numbers []int
-		hashCh := iterateTransactions(chainDb, c.from, c.to, c.reverse, nil)
+		hashCh := iterateTransactions(chainDb, c.from, c.to, c.reverse, nil, nil)
 		if hashCh != nil {
 			for h := range hashCh {
 				numbers = append(numbers, int(h.number))
@@ -206,3 +206,66 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestIndexAllowlistedTransactions(t *testing.T) {
+	// Construct test chain db
+	chainDb := NewMemoryDatabase()
+
+	var block *types.Block
+	var txs []*types.Transaction
+	to := common.BytesToAddress([]byte{0x11})
+
+	// Write empty genesis block
+	block = types.NewBlock(&types.Header{Number: big.NewInt(int64(0))}, nil, nil, nil, newHasher())
+	WriteBlock(chainDb, block)
+	WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+
+	for i := uint64(1); i <= 10; i++ {
+		tx := types.NewTx(&types.LegacyTx{
+			Nonce:    i,
+			GasPrice: big.NewInt(11111),
+			Gas:      1111,
+			To:       &to,
+			Value:    big.NewInt(111),
+		})
+		txs = append(txs, tx)
+		block = types.NewBlock(&types.Header{Number: big.NewInt(int64(i))}, []*types.Transaction{tx}, nil, nil, newHasher())
+		WriteBlock(chainDb, block)
+		WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+	}
+	// The transactions with an odd nonce stand for the allowlisted ones
+	allowlisted := func(number uint64, tx *types.Transaction) bool {
+		return tx.Nonce()%2 == 1
+	}
+	// verify checks that only the allowlisted tx indices exist in the range [from, to)
+	verify := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			number := ReadTxLookupEntry(chainDb, txs[i-1].Hash())
+			if want := i%2 == 1; want != (number != nil) {
+				t.Fatalf("Transaction %d index presence mismatch: have %v, want %v", i, number != nil, want)
+			}
+		}
+	}
+	IndexTransactions(chainDb, 0, 11, nil)
+	UnindexTransactionsExcept(chainDb, 0, 6, nil, allowlisted)
+	verify(1, 6)
+	for i := 6; i <= 10; i++ {
+		if ReadTxLookupEntry(chainDb, txs[i-1].Hash()) == nil {
+			t.Fatalf("Transaction %d index missing", i)
+		}
+	}
+	if tail := ReadTxIndexTail(chainDb); tail == nil || *tail != 6 {
+		t.Fatalf("Transaction tail mismatch: have %v, want 6", tail)
+	}
+	// Drop everything and rebuild the allowlisted indices only
+	UnindexTransactions(chainDb, 0, 11, nil)
+	IndexAllowlistedTransactions(chainDb, 0, 11, nil, allowlisted)
+	verify(1, 11)
+	if tail := ReadTxIndexAllowlistTail(chainDb); tail == nil || *tail != 0 {
+		t.Fatalf("Allowlist tail mismatch: have %v, want 0", tail)
+	}
+	if tail := ReadTxIndexTail(chainDb); tail == nil || *tail != 11 {
+		t.Fatalf("Transaction tail mismatch: have %v, want 11", tail)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -323,6 +325,101 @@ func NewLevelDBDatabase(file string, cache int, handles int, namespace string, r
 	return NewDatabase(db), nil
 }
 
+// NewPebbleDBDatabase creates a persistent key-value database based on pebble
+// without a freezer moving immutable chain segments into cold storage.
+func NewPebbleDBDatabase(file string, cache int, handles int, namespace string, readonly bool, config pebble.Config) (ethdb.Database, error) {
+	db, err := newPebbleStore(file, cache, handles, namespace, readonly, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(db), nil
+}
+
+// Database engines backing the key-value store.
+const (
+	DBLeveldb = "leveldb"
+	DBPebble  = "pebble"
+	DBRemote  = "remote" // Read-only database served by another node, see ethdb/remotedb
+)
+
+// PreexistingDatabase checks whether a key-value store already exists in the
+// given directory, and if so, returns the engine it was created with, or an
+// empty string otherwise.
+func PreexistingDatabase(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err != nil {
+		return "" // No pre-existing db
+	}
+	if matches, _ := filepath.Glob(filepath.Join(path, "OPTIONS*")); len(matches) > 0 {
+		return DBPebble
+	}
+	return DBLeveldb
+}
+
+// OpenOptions contains the options to apply when opening a database.
+type OpenOptions struct {
+	Type              string // Requested engine, the pre-existing one or leveldb if empty
+	Directory         string // Directory of the key-value store
+	AncientsDirectory string // Directory of the freezer, no freezer is attached if empty
+	Namespace         string // Prefix of the database metrics
+	Cache             int    // Capacity (in megabytes) of the data caching
+	Handles           int    // Number of files to be open simultaneously
+	ReadOnly          bool
+
+	DisableFreeze bool
+	IsLastOffset  bool
+
+	Secondary bool          // Open the database of another process without locking it, see SecondaryDatabase
+	Refresh   time.Duration // Interval of catching up with the primary of a secondary database
+
+	Pebble pebble.Config // Tuning options of the pebble engine
+}
+
+// openKeyValueStore opens the key-value store with the engine it was created
+// with, failing if it conflicts with the requested one. New stores use the
+// requested engine, leveldb by default.
+func openKeyValueStore(o OpenOptions) (ethdb.KeyValueStore, error) {
+	engine := PreexistingDatabase(o.Directory)
+	if engine != "" && o.Type != "" && o.Type != engine {
+		return nil, fmt.Errorf("db.engine choice was %v but found pre-existing %v database in %s", o.Type, engine, o.Directory)
+	}
+	if engine == "" {
+		engine = o.Type
+	}
+	switch engine {
+	case DBPebble:
+		log.Info("Using pebble as the backing database")
+		return newPebbleStore(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Pebble)
+	case DBLeveldb, "":
+		log.Info("Using leveldb as the backing database")
+		return leveldb.New(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+	case DBRemote:
+		return nil, errors.New("the remote database engine is only supported by the read-only database commands")
+	default:
+		return nil, fmt.Errorf("unknown db.engine %v", engine)
+	}
+}
+
+// Open opens a key-value database with the engine selected by the options, and
+// attaches a freezer to it if an ancients directory is given.
+func Open(o OpenOptions) (ethdb.Database, error) {
+	if o.Secondary {
+		return NewSecondaryDatabase(o)
+	}
+	kvdb, err := openKeyValueStore(o)
+	if err != nil {
+		return nil, err
+	}
+	if o.AncientsDirectory == "" {
+		return NewDatabase(kvdb), nil
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly, o.DisableFreeze, o.IsLastOffset)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 // NewLevelDBDatabaseWithFreezer creates a persistent key-value database with a
 // freezer moving immutable chain segments into cold storage.
 func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer string, namespace string, readonly, disableFreeze, isLastOffset bool) (ethdb.Database, error) {
@@ -412,12 +509,21 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		headers         stat
 		bodies          stat
 		receipts        stat
+		traces          stat
+		reverts         stat
 		tds             stat
 		numHashPairings stat
 		hashNumPairings stat
 		tries           stat
 		codes           stat
 		txLookups       stat
+		internalCalls   stat
+		accountTxs      stat
+		senderTxs       stat
+		creations       stat
+		tokenTransfers  stat
+		logIndex        stat
+		reorgs          stat
 		accountSnaps    stat
 		storageSnaps    stat
 		preimages       stat
@@ -458,6 +564,10 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bodies.Add(size)
 		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
 			receipts.Add(size)
+		case bytes.HasPrefix(key, blockTracesPrefix) && len(key) == (len(blockTracesPrefix)+8+common.HashLength):
+			traces.Add(size)
+		case bytes.HasPrefix(key, blockRevertsPrefix) && len(key) == (len(blockRevertsPrefix)+8+common.HashLength):
+			reverts.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
 			tds.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
@@ -470,6 +580,22 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			codes.Add(size)
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
 			txLookups.Add(size)
+		case bytes.HasPrefix(key, internalCallPrefix) && len(key) == (len(internalCallPrefix)+common.AddressLength+12):
+			internalCalls.Add(size)
+		case bytes.HasPrefix(key, accountTxPrefix) && len(key) == (len(accountTxPrefix)+common.AddressLength+12):
+			accountTxs.Add(size)
+		case bytes.HasPrefix(key, senderTxPrefix) && len(key) == (len(senderTxPrefix)+common.AddressLength+8):
+			senderTxs.Add(size)
+		case bytes.HasPrefix(key, contractCreationPrefix) && len(key) == (len(contractCreationPrefix)+common.AddressLength+12):
+			creations.Add(size)
+		case bytes.HasPrefix(key, tokenTransferPrefix) && len(key) == (len(tokenTransferPrefix)+common.AddressLength+16):
+			tokenTransfers.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && len(key) == (len(logIndexPrefix)+1+common.HashLength+8):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, LogIndexIndexPrefix):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, reorgPrefix) && len(key) == (len(reorgPrefix)+16):
+			reorgs.Add(size)
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
@@ -495,13 +621,17 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomTrieNodes.Add(size)
 		case bytes.Equal(key, uncleanShutdownKey):
 			shutdownInfo.Add(size)
+		case bytes.HasPrefix(key, webhookPrefix) || bytes.HasPrefix(key, webhookCheckpointPrefix):
+			metadata.Add(size)
 		default:
 			var accounted bool
 			for _, meta := range [][]byte{
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
 				fastTrieProgressKey, snapshotDisabledKey, snapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey,
+				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, internalCallIndexTailKey, fastTxLookupLimitKey,
+				accountTxIndexTailKey, senderTxIndexTailKey, blockTracesTailKey, uncleanShutdownKey, badBlockKey, statePruningProgressKey,
+				exporterCheckpointKey, txIndexAllowlistKey, txIndexAllowlistTailKey, blockRevertsTailKey,
+				contractCreationIndexTailKey, tokenTransferIndexTailKey, snapshotSyncStatusKey, snapshotSyncHealKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -538,11 +668,20 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Headers", headers.Size(), headers.Count()},
 		{"Key-Value store", "Bodies", bodies.Size(), bodies.Count()},
 		{"Key-Value store", "Receipt lists", receipts.Size(), receipts.Count()},
+		{"Key-Value store", "Block traces", traces.Size(), traces.Count()},
+		{"Key-Value store", "Transaction failures", reverts.Size(), reverts.Count()},
 		{"Key-Value store", "Difficulties", tds.Size(), tds.Count()},
 		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Internal call index", internalCalls.Size(), internalCalls.Count()},
+		{"Key-Value store", "Account transaction index", accountTxs.Size(), accountTxs.Count()},
+		{"Key-Value store", "Sender transaction index", senderTxs.Size(), senderTxs.Count()},
+		{"Key-Value store", "Contract creation index", creations.Size(), creations.Count()},
+		{"Key-Value store", "Token transfer index", tokenTransfers.Size(), tokenTransfers.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
+		{"Key-Value store", "Reorg log", reorgs.Size(), reorgs.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -15,3 +15,47 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rawdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests that databases are reopened with the engine they were created with and
+// that a conflicting engine choice is rejected.
+func TestOpenEngines(t *testing.T) {
+	if !PebbleEnabled {
+		t.Skip("pebble not available in this build")
+	}
+	for _, engine := range []string{DBLeveldb, DBPebble} {
+		dir := t.TempDir()
+		if have := PreexistingDatabase(dir); have != "" {
+			t.Fatalf("%s: engine detected in empty directory: %s", engine, have)
+		}
+		db, err := Open(OpenOptions{Type: engine, Directory: dir})
+		if err != nil {
+			t.Fatalf("%s: failed to create database: %v", engine, err)
+		}
+		db.Put([]byte("key"), []byte("value"))
+		db.Close()
+
+		if have := PreexistingDatabase(dir); have != engine {
+			t.Fatalf("engine mismatch: have %s, want %s", have, engine)
+		}
+		other := DBPebble
+		if engine == DBPebble {
+			other = DBLeveldb
+		}
+		if _, err := Open(OpenOptions{Type: other, Directory: dir}); err == nil {
+			t.Fatalf("%s: database opened with conflicting engine %s", engine, other)
+		}
+		db, err = Open(OpenOptions{Directory: dir, ReadOnly: true})
+		if err != nil {
+			t.Fatalf("%s: failed to reopen database: %v", engine, err)
+		}
+		if value, err := db.Get([]byte("key")); err != nil || !bytes.Equal(value, []byte("value")) {
+			t.Errorf("%s: value mismatch: have %x, %v", engine, value, err)
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !(pebble && (arm64 || amd64) && !openbsd)
+// +build !pebble !arm64,!amd64 openbsd
+
+package rawdb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+)
+
+// PebbleEnabled reports whether the pebble engine is available in this build. It
+// requires a 64-bit platform and the pebble build tag, keeping pebble and the Go
+// version it needs out of the default builds.
+const PebbleEnabled = false
+
+// errPebbleUnavailable is returned when opening a pebble database in a build
+// without the pebble engine.
+var errPebbleUnavailable = errors.New("pebble is not available in this build, rebuild on a 64-bit platform with the pebble build tag")
+
+// newPebbleStore opens a pebble key-value store.
+func newPebbleStore(file string, cache int, handles int, namespace string, readonly bool, config pebble.Config) (ethdb.KeyValueStore, error) {
+	return nil, errPebbleUnavailable
+}
+
+// newSharedPebbleStore opens a pebble key-value store written by another process
+// for reading only.
+func newSharedPebbleStore(file string, cache int, handles int, namespace string) (ethdb.KeyValueStore, error) {
+	return nil, errPebbleUnavailable
+}
@@ -79,6 +79,7 @@ type freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	remote       *remoteFreezer           // Object storage for the sealed data files, nil if local only
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
 
 	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
@@ -92,12 +93,35 @@ type freezer struct {
 // newFreezer creates a chain freezer that moves ancient chain data into
 // append-only flat file containers.
 func newFreezer(datadir string, namespace string, readonly bool) (*freezer, error) {
+	return openFreezer(datadir, namespace, readonly, false)
+}
+
+// newSharedFreezer opens a chain freezer written by another process for reading
+// only, without locking it or repairing its tables. The view is fixed at the
+// items present when opening, reopen it to catch up with the writer.
+func newSharedFreezer(datadir string, namespace string) (*freezer, error) {
+	return openFreezer(datadir, namespace, true, true)
+}
+
+// openFreezer opens a chain freezer, either owning the data files or sharing
+// them with the process owning them.
+func openFreezer(datadir string, namespace string, readonly bool, shared bool) (*freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
 		writeMeter = metrics.NewRegisteredMeter(namespace+"ancient/write", nil)
 		sizeGauge  = metrics.NewRegisteredGauge(namespace+"ancient/size", nil)
+
+		remote *remoteFreezer
 	)
+	// If the ancient data is kept in an object storage, the local directory
+	// only holds the indexes and the data files being written
+	if IsRemoteAncient(datadir) {
+		var err error
+		if remote, datadir, err = openRemoteFreezer(datadir); err != nil {
+			return nil, err
+		}
+	}
 	// Ensure the datadir is not a symbolic link if it exists.
 	if info, err := os.Lstat(datadir); !os.IsNotExist(err) {
 		if info.Mode()&os.ModeSymlink != 0 {
@@ -107,21 +131,37 @@ func newFreezer(datadir string, namespace string, readonly bool) (*freezer, erro
 	}
 	// Leveldb uses LOCK as the filelock filename. To prevent the
 	// name collision, we use FLOCK as the lock name.
-	lock, _, err := fileutil.Flock(filepath.Join(datadir, "FLOCK"))
-	if err != nil {
-		return nil, err
+	var lock fileutil.Releaser = noopReleaser{}
+	if !shared {
+		var err error
+		if lock, _, err = fileutil.Flock(filepath.Join(datadir, "FLOCK")); err != nil {
+			return nil, err
+		}
 	}
 	// Open all the supported data tables
 	freezer := &freezer{
 		readonly:     readonly,
 		threshold:    params.FullImmutabilityThreshold,
 		tables:       make(map[string]*freezerTable),
+		remote:       remote,
 		instanceLock: lock,
 		trigger:      make(chan chan struct{}),
 		quit:         make(chan struct{}),
 	}
-	for name, disableSnappy := range FreezerNoSnappy {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, disableSnappy)
+	for name := range FreezerNoSnappy {
+		codec, err := openTableCodec(datadir, name, defaultTableCodec(name))
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			lock.Release()
+			return nil, err
+		}
+		open := newRemoteTable
+		if shared {
+			open = newSharedTable
+		}
+		table, err := open(datadir, name, readMeter, writeMeter, sizeGauge, freezerTableSize, codec, remote)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -131,14 +171,22 @@ func newFreezer(datadir string, namespace string, readonly bool) (*freezer, erro
 		}
 		freezer.tables[name] = table
 	}
-	if err := freezer.repair(); err != nil {
+	repair := freezer.repair
+	if shared {
+		repair = freezer.load
+	}
+	if err := repair(); err != nil {
 		for _, table := range freezer.tables {
 			table.Close()
 		}
 		lock.Release()
 		return nil, err
 	}
-	log.Info("Opened ancient database", "database", datadir, "readonly", readonly)
+	if shared {
+		log.Debug("Opened shared ancient database", "database", datadir)
+	} else {
+		log.Info("Opened ancient database", "database", datadir, "readonly", readonly)
+	}
 	return freezer, nil
 }
 
@@ -470,6 +518,15 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 		}
 		log.Info("Deep froze chain segment", context...)
 
+		// Move the sealed data files to the object storage, if configured
+		if f.remote != nil && !f.readonly {
+			for name, table := range f.tables {
+				if err := table.offload(); err != nil {
+					log.Error("Failed to move ancient data to object storage", "table", name, "err", err)
+				}
+			}
+		}
+
 		// Avoid database thrashing with tiny writes
 		if f.frozen-first < freezerBatchLimit {
 			backoff = true
@@ -494,3 +551,23 @@ func (f *freezer) repair() error {
 	atomic.StoreUint64(&f.frozen, min)
 	return nil
 }
+
+// load is the read-only counterpart of repair, it limits the frozen items to
+// the ones present in all tables without truncating them.
+func (f *freezer) load() error {
+	min := uint64(math.MaxUint64)
+	for _, table := range f.tables {
+		items := atomic.LoadUint64(&table.items)
+		if min > items {
+			min = items
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// noopReleaser is the instance lock of a shared freezer, which doesn't lock
+// the data files of the process owning them.
+type noopReleaser struct{}
+
+func (noopReleaser) Release() error { return nil }
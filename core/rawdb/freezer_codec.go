@@ -0,0 +1,334 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/tsdb/fileutil"
+)
+
+// The compression codecs supported by the freezer tables. The codec of a table
+// is determined by the extension of its files, so it is picked when the table
+// is created and can only be changed by recompressing the table.
+const (
+	FreezerCompressionNone   = "none"
+	FreezerCompressionSnappy = "snappy"
+	FreezerCompressionZstd   = "zstd"
+)
+
+// freezerCodec compresses the items of a freezer table.
+type freezerCodec interface {
+	// kind returns the name of the codec.
+	kind() string
+
+	// ext returns the character prefixing the extension of the table files.
+	ext() string
+
+	encode(blob []byte) []byte
+	decode(blob []byte) ([]byte, error)
+	close()
+}
+
+// rawCodec stores the items uncompressed.
+type rawCodec struct{}
+
+func (rawCodec) kind() string                       { return FreezerCompressionNone }
+func (rawCodec) ext() string                        { return "r" }
+func (rawCodec) encode(blob []byte) []byte          { return blob }
+func (rawCodec) decode(blob []byte) ([]byte, error) { return blob, nil }
+func (rawCodec) close()                             {}
+
+// snappyCodec compresses the items with snappy.
+type snappyCodec struct{}
+
+func (snappyCodec) kind() string                       { return FreezerCompressionSnappy }
+func (snappyCodec) ext() string                        { return "c" }
+func (snappyCodec) encode(blob []byte) []byte          { return snappy.Encode(nil, blob) }
+func (snappyCodec) decode(blob []byte) ([]byte, error) { return snappy.Decode(nil, blob) }
+func (snappyCodec) close()                             {}
+
+// zstdCodec compresses the items with zstd, optionally using a dictionary to
+// improve the ratio of the small items.
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func newZstdCodec(dict []byte) (*zstdCodec, error) {
+	var (
+		encOpts = []zstd.EOption{zstd.WithEncoderConcurrency(1)}
+		decOpts = []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+	)
+	if len(dict) > 0 {
+		encOpts = append(encOpts, zstd.WithEncoderDict(dict))
+		decOpts = append(decOpts, zstd.WithDecoderDicts(dict))
+	}
+	enc, err := zstd.NewWriter(nil, encOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zstd dictionary: %v", err)
+	}
+	dec, err := zstd.NewReader(nil, decOpts...)
+	if err != nil {
+		enc.Close()
+		return nil, fmt.Errorf("invalid zstd dictionary: %v", err)
+	}
+	return &zstdCodec{enc: enc, dec: dec}, nil
+}
+
+func (c *zstdCodec) kind() string                       { return FreezerCompressionZstd }
+func (c *zstdCodec) ext() string                        { return "z" }
+func (c *zstdCodec) encode(blob []byte) []byte          { return c.enc.EncodeAll(blob, nil) }
+func (c *zstdCodec) decode(blob []byte) ([]byte, error) { return c.dec.DecodeAll(blob, nil) }
+
+func (c *zstdCodec) close() {
+	c.enc.Close()
+	c.dec.Close()
+}
+
+// newFreezerCodec creates the codec with the given name. The dictionary is
+// only used by zstd.
+func newFreezerCodec(kind string, dict []byte) (freezerCodec, error) {
+	switch kind {
+	case FreezerCompressionNone:
+		return rawCodec{}, nil
+	case FreezerCompressionSnappy:
+		return snappyCodec{}, nil
+	case FreezerCompressionZstd:
+		return newZstdCodec(dict)
+	default:
+		return nil, fmt.Errorf("unknown freezer compression %q", kind)
+	}
+}
+
+// defaultTableCodec returns the codec used for new tables with the given name.
+func defaultTableCodec(name string) string {
+	if FreezerNoSnappy[name] {
+		return FreezerCompressionNone
+	}
+	return FreezerCompressionSnappy
+}
+
+// tableDictPath returns the path of the zstd dictionary of a table.
+func tableDictPath(path, name string) string {
+	return filepath.Join(path, fmt.Sprintf("%s.zdict", name))
+}
+
+// tableIndexPath returns the path of the index file of a table.
+func tableIndexPath(path, name string, codec freezerCodec) string {
+	return filepath.Join(path, fmt.Sprintf("%s.%sidx", name, codec.ext()))
+}
+
+// openTableCodec detects the codec of an existing table from its index file,
+// falling back to the given codec if the table does not exist yet.
+func openTableCodec(path, name, fallback string) (freezerCodec, error) {
+	var found []freezerCodec
+	for _, codec := range []freezerCodec{rawCodec{}, snappyCodec{}, &zstdCodec{}} {
+		if _, err := os.Stat(tableIndexPath(path, name, codec)); err == nil {
+			found = append(found, codec)
+		}
+	}
+	kind := fallback
+	switch len(found) {
+	case 0:
+	case 1:
+		kind = found[0].kind()
+	default:
+		return nil, fmt.Errorf("freezer table %s has multiple index files, interrupted recompression?", name)
+	}
+	var dict []byte
+	if kind == FreezerCompressionZstd {
+		blob, err := ioutil.ReadFile(tableDictPath(path, name))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		dict = blob
+	}
+	return newFreezerCodec(kind, dict)
+}
+
+// removeTableFiles deletes the index and the data files of a table stored with
+// the given codec.
+func removeTableFiles(path, name string, codec freezerCodec) error {
+	if err := os.Remove(tableIndexPath(path, name, codec)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("%s.*.%sdat", name, codec.ext())))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecompressFreezerTable rewrites a table of the freezer in the given directory
+// with another compression codec. The dictionary is only used by zstd and must
+// have been trained beforehand, e.g. with `zstd --train`. The freezer must not
+// be in use.
+//
+// The new table is written next to the old one, and the removal of the old
+// index commits the migration. An interrupted run leaves both indexes on disk,
+// which refuses to open until the command is run again.
+func RecompressFreezerTable(datadir, name, compression string, dict []byte) error {
+	if _, ok := FreezerNoSnappy[name]; !ok {
+		return errUnknownTable
+	}
+	if IsRemoteAncient(datadir) {
+		return fmt.Errorf("recompressing ancients in an object storage is not supported")
+	}
+	if len(dict) > 0 && compression != FreezerCompressionZstd {
+		return fmt.Errorf("dictionaries are only supported by %s", FreezerCompressionZstd)
+	}
+	lock, _, err := fileutil.Flock(filepath.Join(datadir, "FLOCK"))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// Find the existing table, ignoring the target index left over by an
+	// interrupted run
+	var source string
+	for _, codec := range []freezerCodec{rawCodec{}, snappyCodec{}, &zstdCodec{}} {
+		if codec.kind() == compression {
+			continue
+		}
+		if _, err := os.Stat(tableIndexPath(datadir, name, codec)); err == nil {
+			if source != "" {
+				return fmt.Errorf("freezer table %s has multiple source indexes", name)
+			}
+			source = codec.kind()
+		}
+	}
+	if source == "" {
+		return fmt.Errorf("freezer table %s not found or already compressed with %s", name, compression)
+	}
+	var srcDict []byte
+	if source == FreezerCompressionZstd {
+		if srcDict, err = ioutil.ReadFile(tableDictPath(datadir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// Drop the leftovers of an interrupted run and store the new dictionary
+	target, err := newFreezerCodec(compression, dict)
+	if err != nil {
+		return err
+	}
+	if err := removeTableFiles(datadir, name, target); err != nil {
+		target.close()
+		return err
+	}
+	if compression == FreezerCompressionZstd {
+		if len(dict) > 0 {
+			err = ioutil.WriteFile(tableDictPath(datadir, name), dict, 0644)
+		} else if err = os.Remove(tableDictPath(datadir, name)); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			target.close()
+			return err
+		}
+	}
+	// Open both tables, they take ownership of the codecs
+	codec, err := newFreezerCodec(source, srcDict)
+	if err != nil {
+		target.close()
+		return err
+	}
+	src, err := newRemoteTable(datadir, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, codec, nil)
+	if err != nil {
+		target.close()
+		return err
+	}
+	if src.itemOffset != 0 {
+		src.Close()
+		target.close()
+		return fmt.Errorf("freezer table %s was pruned from the tail, can't recompress", name)
+	}
+	dst, err := newRemoteTable(datadir, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, target, nil)
+	if err != nil {
+		src.Close()
+		return err
+	}
+	start := time.Now()
+	srcSize, dstSize, err := copyTable(src, dst)
+	src.Close()
+	dst.Close()
+	if err != nil {
+		return err
+	}
+	// Removing the source index commits the migration
+	if err := removeTableFiles(datadir, name, codec); err != nil {
+		return err
+	}
+	if source == FreezerCompressionZstd {
+		if err := os.Remove(tableDictPath(datadir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	log.Info("Recompressed freezer table", "table", name, "compression", compression, "items", atomic.LoadUint64(&dst.items),
+		"before", common.StorageSize(srcSize), "after", common.StorageSize(dstSize), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// copyTable appends all items of the source table to the empty destination
+// table, returning the size of both tables.
+func copyTable(src, dst *freezerTable) (uint64, uint64, error) {
+	var (
+		items  = atomic.LoadUint64(&src.items)
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for item := uint64(0); item < items; item++ {
+		blob, err := src.Retrieve(item)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := dst.Append(item, blob); err != nil {
+			return 0, 0, err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Recompressing freezer table", "table", src.name, "item", item, "total", items, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := dst.Sync(); err != nil {
+		return 0, 0, err
+	}
+	srcSize, err := src.size()
+	if err != nil {
+		return 0, 0, err
+	}
+	dstSize, err := dst.size()
+	if err != nil {
+		return 0, 0, err
+	}
+	return srcSize, dstSize, nil
+}
@@ -0,0 +1,283 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// remoteChunkSize is the granularity in which the data files kept in the
+	// object storage are read and cached locally.
+	remoteChunkSize = 1024 * 1024
+
+	// remoteCacheDefault is the default size of the local read cache of the
+	// object storage in megabytes.
+	remoteCacheDefault = 256
+)
+
+var (
+	// errRemoteNotFound is returned by a remote store if the requested object
+	// does not exist.
+	errRemoteNotFound = errors.New("object not found")
+
+	remoteCacheHitMeter  = metrics.NewRegisteredMeter("ancient/remote/cache/hit", nil)
+	remoteCacheMissMeter = metrics.NewRegisteredMeter("ancient/remote/cache/miss", nil)
+	remoteUploadMeter    = metrics.NewRegisteredMeter("ancient/remote/upload", nil)
+)
+
+// remoteStore is an object storage holding the sealed data files of the
+// freezer tables. Objects are addressed by the name of the data file.
+type remoteStore interface {
+	// ReadAt reads the object starting at the given offset into p, returning
+	// the number of bytes read. Reads beyond the end of the object are short.
+	ReadAt(name string, p []byte, off int64) (int, error)
+
+	// Get retrieves the whole object.
+	Get(name string) (io.ReadCloser, error)
+
+	// Size returns the size of the object or errRemoteNotFound.
+	Size(name string) (int64, error)
+
+	// Put uploads the content of the reader as the object.
+	Put(name string, r io.ReadSeeker, size int64) error
+
+	// Delete removes the object, if it exists.
+	Delete(name string) error
+}
+
+// remoteChunk identifies a chunk of a data file in the read cache.
+type remoteChunk struct {
+	name  string
+	index int64
+}
+
+// remoteFreezer gives the freezer tables access to the data files offloaded
+// to an object storage, caching recently read chunks in memory.
+type remoteFreezer struct {
+	store remoteStore
+	cache *lru.Cache // Recently read chunks of the remote data files
+}
+
+func newRemoteFreezer(store remoteStore, cacheSize int) *remoteFreezer {
+	cache, _ := lru.New(cacheSize * 1024 * 1024 / remoteChunkSize)
+	return &remoteFreezer{store: store, cache: cache}
+}
+
+// IsRemoteAncient returns whether the ancient directory is the URL of an object
+// storage instead of a local path.
+func IsRemoteAncient(ancient string) bool {
+	return strings.HasPrefix(ancient, "s3://") || strings.HasPrefix(ancient, "gs://")
+}
+
+// ObjectStore is an object storage as used by the remote freezer, for other
+// subsystems keeping their files in the same buckets.
+type ObjectStore interface {
+	remoteStore
+}
+
+// ErrObjectNotFound is returned by an ObjectStore if the requested object does
+// not exist.
+var ErrObjectNotFound = errRemoteNotFound
+
+// NewObjectStore creates an object storage client from an URL of the form
+// s3://bucket/prefix or gs://bucket/prefix.
+func NewObjectStore(rawurl string) (ObjectStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage url: %v", err)
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" {
+		return nil, fmt.Errorf("unsupported object storage url %q", u.Redacted())
+	}
+	return newS3Store(u)
+}
+
+// WithLocalAncientDir sets the local directory keeping the freezer indexes and
+// the data files not yet moved to the object storage, unless the user already
+// specified one in the URL.
+func WithLocalAncientDir(ancient string, dir string) string {
+	u, err := url.Parse(ancient)
+	if err != nil {
+		return ancient // Reported when opening the freezer
+	}
+	query := u.Query()
+	if query.Get("local") == "" {
+		query.Set("local", dir)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// openRemoteFreezer parses an object storage URL of the form
+//
+//	s3://bucket/prefix?local=/path/to/ancient&region=...&endpoint=...&cache=256
+//
+// returning the remote store and the local directory of the freezer.
+func openRemoteFreezer(ancient string) (*remoteFreezer, string, error) {
+	u, err := url.Parse(ancient)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid ancient url: %v", err)
+	}
+	query := u.Query()
+	local := query.Get("local")
+	if local == "" {
+		return nil, "", errors.New("missing local ancient directory")
+	}
+	cache := remoteCacheDefault
+	if s := query.Get("cache"); s != "" {
+		if cache, err = strconv.Atoi(s); err != nil || cache <= 0 {
+			return nil, "", fmt.Errorf("invalid ancient cache size %q", s)
+		}
+	}
+	store, err := newS3Store(u)
+	if err != nil {
+		return nil, "", err
+	}
+	return newRemoteFreezer(store, cache), local, nil
+}
+
+// readAt reads the requested range of a remote data file through the cache.
+func (r *remoteFreezer) readAt(name string, p []byte, off int64) error {
+	for len(p) > 0 {
+		var (
+			index = off / remoteChunkSize
+			start = off % remoteChunkSize
+		)
+		chunk, err := r.chunk(name, index)
+		if err != nil {
+			return err
+		}
+		if int64(len(chunk)) <= start {
+			return io.ErrUnexpectedEOF
+		}
+		n := copy(p, chunk[start:])
+		p, off = p[n:], off+int64(n)
+	}
+	return nil
+}
+
+// chunk retrieves a chunk of a remote data file, fetching it if not cached.
+func (r *remoteFreezer) chunk(name string, index int64) ([]byte, error) {
+	key := remoteChunk{name: name, index: index}
+	if chunk, ok := r.cache.Get(key); ok {
+		remoteCacheHitMeter.Mark(1)
+		return chunk.([]byte), nil
+	}
+	remoteCacheMissMeter.Mark(1)
+
+	chunk := make([]byte, remoteChunkSize)
+	n, err := r.store.ReadAt(name, chunk, index*remoteChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	chunk = chunk[:n]
+	r.cache.Add(key, chunk)
+	return chunk, nil
+}
+
+// upload moves a local data file to the object storage.
+func (r *remoteFreezer) upload(name string, path string) (os.FileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.store.Put(name, file, stat.Size()); err != nil {
+		return nil, err
+	}
+	// Don't trust a partial upload to replace the local copy
+	size, err := r.store.Size(name)
+	if err != nil {
+		return nil, err
+	}
+	if size != stat.Size() {
+		return nil, fmt.Errorf("uploaded size mismatch: have %d, want %d", size, stat.Size())
+	}
+	remoteUploadMeter.Mark(size)
+	return stat, nil
+}
+
+// restore downloads a remote data file back to the local directory, e.g.
+// because the table is truncated into it. It is a noop if the file is local
+// already or does not exist remotely.
+func (r *remoteFreezer) restore(name string, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if _, err := r.store.Size(name); err == errRemoteNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	blob, err := r.store.Get(name)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, blob); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	file.Close()
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	r.evict(name)
+	return r.store.Delete(name)
+}
+
+// evict drops all cached chunks of a data file.
+func (r *remoteFreezer) evict(name string) {
+	for _, key := range r.cache.Keys() {
+		if key.(remoteChunk).name == name {
+			r.cache.Remove(key)
+		}
+	}
+}
+
+// remove deletes a remote data file.
+func (r *remoteFreezer) remove(name string) error {
+	r.evict(name)
+	return r.store.Delete(name)
+}
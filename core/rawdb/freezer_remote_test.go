@@ -0,0 +1,238 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// memRemoteStore is an in-memory remoteStore for testing.
+type memRemoteStore struct {
+	objects map[string][]byte
+	lock    sync.Mutex
+}
+
+func newMemRemoteStore() *memRemoteStore {
+	return &memRemoteStore{objects: make(map[string][]byte)}
+}
+
+func (s *memRemoteStore) ReadAt(name string, p []byte, off int64) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, ok := s.objects[name]
+	if !ok {
+		return 0, errRemoteNotFound
+	}
+	if off >= int64(len(blob)) {
+		return 0, fmt.Errorf("range %d not satisfiable", off)
+	}
+	return copy(p, blob[off:]), nil
+}
+
+func (s *memRemoteStore) Get(name string) (io.ReadCloser, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, ok := s.objects[name]
+	if !ok {
+		return nil, errRemoteNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(blob)), nil
+}
+
+func (s *memRemoteStore) Size(name string) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	blob, ok := s.objects[name]
+	if !ok {
+		return 0, errRemoteNotFound
+	}
+	return int64(len(blob)), nil
+}
+
+func (s *memRemoteStore) Put(name string, r io.ReadSeeker, size int64) error {
+	blob, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.objects[name] = blob
+	return nil
+}
+
+func (s *memRemoteStore) Delete(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.objects, name)
+	return nil
+}
+
+// Tests that sealed data files are moved to the object storage, read back
+// through the cache, and restored when the table is truncated into them.
+func TestFreezerRemoteOffload(t *testing.T) {
+	var (
+		dir    = os.TempDir()
+		fname  = fmt.Sprintf("offload-%d", rand.Uint64())
+		store  = newMemRemoteStore()
+		remote = newRemoteFreezer(store, 1)
+	)
+	open := func() *freezerTable {
+		f, err := newRemoteTable(dir, fname, metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge(), 50, rawCodec{}, remote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	f := open()
+
+	// Write 15 bytes 30 times, resulting in 10 files of 3 items each
+	for x := 0; x < 30; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.offload(); err != nil {
+		t.Fatalf("failed to offload: %v", err)
+	}
+	if len(store.objects) != 9 {
+		t.Fatalf("offloaded file count mismatch: have %d, want %d", len(store.objects), 9)
+	}
+	for num := uint32(0); num < 9; num++ {
+		if _, err := os.Stat(filepath.Join(dir, f.fileName(num))); !os.IsNotExist(err) {
+			t.Fatalf("file %d: local copy not removed", num)
+		}
+	}
+	check := func(f *freezerTable, items int) {
+		for x := 0; x < items; x++ {
+			blob, err := f.Retrieve(uint64(x))
+			if err != nil {
+				t.Fatalf("item %d: failed to retrieve: %v", x, err)
+			}
+			if !bytes.Equal(blob, getChunk(15, x)) {
+				t.Fatalf("item %d: content mismatch: have %x", x, blob)
+			}
+		}
+	}
+	check(f, 30)
+
+	// Reopen the table and ensure the offloaded files are picked up
+	f.Close()
+	f = open()
+	check(f, 30)
+
+	// Truncate into an offloaded file, it must be restored locally
+	if err := f.truncate(10); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, f.fileName(3))); err != nil {
+		t.Fatalf("truncated head not restored: %v", err)
+	}
+	if len(store.objects) != 3 {
+		t.Fatalf("remote file count mismatch after truncation: have %d, want %d", len(store.objects), 3)
+	}
+	check(f, 10)
+	for x := 10; x < 30; x++ {
+		if err := f.Append(uint64(x), getChunk(15, x)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check(f, 30)
+	f.Close()
+}
+
+// Tests the S3 client against a minimal path-style object storage server.
+func TestS3Store(t *testing.T) {
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		blob, ok := objects[r.URL.Path]
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = ioutil.ReadAll(r.Body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(blob))
+		}
+	}))
+	defer srv.Close()
+
+	endpoint, _ := url.Parse(srv.URL)
+	store := &s3Store{
+		endpoint: endpoint,
+		bucket:   "bucket",
+		prefix:   "ancient",
+		region:   "us-east-1",
+		creds:    credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		signer:   v4.NewSigner(),
+		client:   srv.Client(),
+	}
+	if _, err := store.Size("bodies.0000.cdat"); err != errRemoteNotFound {
+		t.Fatalf("missing object error mismatch: have %v, want %v", err, errRemoteNotFound)
+	}
+	data := []byte("0123456789")
+	if err := store.Put("bodies.0000.cdat", bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if _, ok := objects["/bucket/ancient/bodies.0000.cdat"]; !ok {
+		t.Fatalf("object stored at unexpected path: %v", objects)
+	}
+	if size, err := store.Size("bodies.0000.cdat"); err != nil || size != int64(len(data)) {
+		t.Fatalf("size mismatch: have %d (%v), want %d", size, err, len(data))
+	}
+	// Reads past the end of the object must be short
+	buf := make([]byte, 4)
+	if n, err := store.ReadAt("bodies.0000.cdat", buf, 8); err != nil || !bytes.Equal(buf[:n], data[8:]) {
+		t.Fatalf("range read mismatch: have %q (%v), want %q", buf[:n], err, data[8:])
+	}
+	if err := store.Delete("bodies.0000.cdat"); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if err := store.Delete("bodies.0000.cdat"); err != nil {
+		t.Fatalf("failed to delete missing object: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// s3RequestTimeout is the maximum time allowed for a single request to the
+// object storage, apart from uploads and downloads of whole data files.
+const s3RequestTimeout = 30 * time.Second
+
+// unsignedPayload is the payload hash used to skip hashing the uploaded data
+// files when signing the requests. Integrity is checked by the uploaded size.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// s3Store is a remoteStore backed by an S3 compatible object storage, which
+// includes Google Cloud Storage through its interoperability API.
+type s3Store struct {
+	endpoint *url.URL // Endpoint of the storage service
+	bucket   string   // Bucket holding the data files
+	prefix   string   // Key prefix of the data files within the bucket
+	region   string   // Region used for signing the requests
+
+	creds  aws.CredentialsProvider
+	signer *v4.Signer
+	client *http.Client
+}
+
+// newS3Store creates an object storage client from an URL of the form
+// s3://bucket/prefix or gs://bucket/prefix. The region and the endpoint may be
+// overridden with the "region" and "endpoint" query parameters, credentials
+// are loaded from the standard AWS environment and configuration files.
+func newS3Store(u *url.URL) (*s3Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in ancient url %q", u.Redacted())
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load object storage credentials: %v", err)
+	}
+	var (
+		query    = u.Query()
+		region   = query.Get("region")
+		endpoint = query.Get("endpoint")
+	)
+	if region == "" {
+		region = cfg.Region
+	}
+	switch u.Scheme {
+	case "s3":
+		if region == "" {
+			region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+	case "gs":
+		if region == "" {
+			region = "auto"
+		}
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported object storage %q", u.Scheme)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %v", err)
+	}
+	return &s3Store{
+		endpoint: base,
+		bucket:   u.Host,
+		prefix:   strings.Trim(u.Path, "/"),
+		region:   region,
+		creds:    cfg.Credentials,
+		signer:   v4.NewSigner(),
+		client:   new(http.Client),
+	}, nil
+}
+
+// url returns the path-style URL of an object.
+func (s *s3Store) url(name string) string {
+	u := *s.endpoint
+	u.Path = path.Join("/", s.bucket, s.prefix, name)
+	return u.String()
+}
+
+// do signs and sends a request for the given object.
+func (s *s3Store) do(ctx context.Context, method, name string, body io.ReadSeeker, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.url(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Body = ioutil.NopCloser(body)
+		req.ContentLength = size
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := body.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(body), nil
+		}
+	}
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	creds, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.signer.SignHTTP(ctx, creds, req, unsignedPayload, "s3", s.region, time.Now()); err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, errRemoteNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s: %s", method, name, res.Status, msg)
+	}
+	return res, nil
+}
+
+// ReadAt implements remoteStore, reading a range of the object.
+func (s *s3Store) ReadAt(name string, p []byte, off int64) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	header := make(http.Header)
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	res, err := s.do(ctx, http.MethodGet, name, nil, 0, header)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("range request of %s not honoured: %s", name, res.Status)
+	}
+	n, err := io.ReadFull(res.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Get implements remoteStore, retrieving the whole object.
+func (s *s3Store) Get(name string) (io.ReadCloser, error) {
+	res, err := s.do(context.Background(), http.MethodGet, name, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Size implements remoteStore, returning the size of the object.
+func (s *s3Store) Size(name string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	res, err := s.do(ctx, http.MethodHead, name, nil, 0, nil)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	return res.ContentLength, nil
+}
+
+// Put implements remoteStore, uploading the object.
+func (s *s3Store) Put(name string, r io.ReadSeeker, size int64) error {
+	res, err := s.do(context.Background(), http.MethodPut, name, r, size, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Delete implements remoteStore, removing the object.
+func (s *s3Store) Delete(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+
+	res, err := s.do(ctx, http.MethodDelete, name, nil, 0, nil)
+	if err == errRemoteNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
@@ -23,13 +23,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -79,10 +79,10 @@ type freezerTable struct {
 	// so take advantage of that (https://golang.org/pkg/sync/atomic/#pkg-note-BUG).
 	items uint64 // Number of items stored in the table (including items removed from tail)
 
-	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	codec       freezerCodec // Compression codec of the items. Note: does not work retroactively
+	maxFileSize uint32       // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	files  map[uint32]*os.File // open files
@@ -90,6 +90,10 @@ type freezerTable struct {
 	tailId uint32              // number of the earliest file
 	index  *os.File            // File descriptor for the indexEntry file of the table
 
+	remote    *remoteFreezer      // Object storage holding the sealed data files, nil if local only
+	offloaded map[uint32]struct{} // Data files moved to the object storage
+	shared    bool                // Whether the table is opened for reading while another process writes it
+
 	// In the case that old items are deleted (from the tail), we use itemOffset
 	// to count how many historic items have gone missing.
 	itemOffset uint32 // Offset (number of discarded items)
@@ -103,15 +107,22 @@ type freezerTable struct {
 	lock   sync.RWMutex // Mutex protecting the data file descriptors
 }
 
-// NewFreezerTable opens the given path as a freezer table.
+// NewFreezerTable opens the given path as a freezer table. The compression of an
+// existing table is detected from its files, the flag only applies to new ones.
 func NewFreezerTable(path, name string, disableSnappy bool) (*freezerTable, error) {
-	return newTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, disableSnappy)
+	fallback := FreezerCompressionSnappy
+	if disableSnappy {
+		fallback = FreezerCompressionNone
+	}
+	codec, err := openTableCodec(path, name, fallback)
+	if err != nil {
+		return nil, err
+	}
+	return newRemoteTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, codec, nil)
 }
 
-// newTable opens a freezer table with default settings - 2G files
-func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, disableSnappy bool) (*freezerTable, error) {
-	return newCustomTable(path, name, readMeter, writeMeter, sizeGauge, 2*1000*1000*1000, disableSnappy)
-}
+// freezerTableSize defines the maximum size of freezer data files.
+const freezerTableSize = 2 * 1000 * 1000 * 1000
 
 // openFreezerFileForAppend opens a freezer table file and seeks to the end
 func openFreezerFileForAppend(filename string) (*os.File, error) {
@@ -155,34 +166,41 @@ func truncateFreezerFile(file *os.File, size int64) error {
 // non existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
 func newCustomTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression bool) (*freezerTable, error) {
+	var codec freezerCodec = snappyCodec{}
+	if noCompression {
+		codec = rawCodec{}
+	}
+	return newRemoteTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, codec, nil)
+}
+
+// newRemoteTable opens a freezer table whose sealed data files may be moved to
+// the given object storage. Only the index and the head file are always kept
+// in the local directory.
+func newRemoteTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, codec freezerCodec, remote *remoteFreezer) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
+		codec.close()
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		// Raw idx
-		idxName = fmt.Sprintf("%s.ridx", name)
-	} else {
-		// Compressed idx
-		idxName = fmt.Sprintf("%s.cidx", name)
-	}
-	offsets, err := openFreezerFileForAppend(filepath.Join(path, idxName))
+	offsets, err := openFreezerFileForAppend(tableIndexPath(path, name, codec))
 	if err != nil {
+		codec.close()
 		return nil, err
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         offsets,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		maxFileSize:   maxFilesize,
+		index:       offsets,
+		files:       make(map[uint32]*os.File),
+		remote:      remote,
+		offloaded:   make(map[uint32]struct{}),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		codec:       codec,
+		maxFileSize: maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -199,6 +217,89 @@ func newCustomTable(path string, name string, readMeter metrics.Meter, writeMete
 	return tab, nil
 }
 
+// newSharedTable opens a freezer table written by another process for reading
+// only. Instead of repairing the table, the items not completely written yet
+// are ignored.
+func newSharedTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, codec freezerCodec, remote *remoteFreezer) (*freezerTable, error) {
+	offsets, err := openFreezerFileForReadOnly(tableIndexPath(path, name, codec))
+	if err != nil {
+		codec.close()
+		return nil, err
+	}
+	tab := &freezerTable{
+		index:       offsets,
+		files:       make(map[uint32]*os.File),
+		remote:      remote,
+		offloaded:   make(map[uint32]struct{}),
+		shared:      true,
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		codec:       codec,
+		maxFileSize: maxFilesize,
+	}
+	if err := tab.load(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	size, err := tab.sizeNolock()
+	if err != nil {
+		tab.Close()
+		return nil, err
+	}
+	tab.sizeGauge.Inc(int64(size))
+
+	return tab, nil
+}
+
+// load is the read-only counterpart of repair, it determines the items of the
+// table skipping any trailing index entries pointing beyond their data files.
+func (t *freezerTable) load() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	entries := stat.Size() / indexEntrySize
+	if entries == 0 {
+		return fmt.Errorf("freezer table %s is not initialized", t.name)
+	}
+	buffer := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, 0); err != nil {
+		return err
+	}
+	var firstIndex indexEntry
+	firstIndex.unmarshalBinary(buffer)
+
+	t.tailId = firstIndex.filenum
+	t.itemOffset = firstIndex.offset
+
+	lastIndex := indexEntry{filenum: t.tailId}
+	for ; entries > 1; entries-- {
+		if _, err := t.index.ReadAt(buffer, (entries-1)*indexEntrySize); err != nil {
+			return err
+		}
+		var entry indexEntry
+		entry.unmarshalBinary(buffer)
+
+		if stat, err := os.Stat(filepath.Join(t.path, t.fileName(entry.filenum))); err == nil && stat.Size() >= int64(entry.offset) {
+			lastIndex = entry
+			break
+		}
+	}
+	t.items = uint64(t.itemOffset) + uint64(entries-1)
+	t.headBytes = lastIndex.offset
+	t.headId = lastIndex.filenum
+
+	if err := t.preopen(); err != nil {
+		return err
+	}
+	t.logger.Debug("Shared freezer table opened", "items", t.items, "size", common.StorageSize(t.headBytes))
+	return nil
+}
+
 // repair cross checks the head and the index file and truncates them to
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
@@ -320,12 +421,26 @@ func (t *freezerTable) preopen() (err error) {
 	t.releaseFilesAfter(0, false)
 	// Open all except head in RDONLY
 	for i := t.tailId; i < t.headId; i++ {
+		if t.remote != nil {
+			// Data files missing locally are read from the object storage
+			if _, err := os.Stat(filepath.Join(t.path, t.fileName(i))); os.IsNotExist(err) {
+				if _, err := t.remote.store.Size(t.fileName(i)); err != nil {
+					return fmt.Errorf("missing data file %d: %v", i, err)
+				}
+				t.offloaded[i] = struct{}{}
+				continue
+			}
+		}
 		if _, err = t.openFile(i, openFreezerFileForReadOnly); err != nil {
 			return err
 		}
 	}
-	// Open head in read/write
-	t.head, err = t.openFile(t.headId, openFreezerFileForAppend)
+	// Open head in read/write, unless another process is writing it
+	if t.shared {
+		t.head, err = t.openFile(t.headId, openFreezerFileForReadOnly)
+	} else {
+		t.head, err = t.openFile(t.headId, openFreezerFileForAppend)
+	}
 	return err
 }
 
@@ -410,6 +525,7 @@ func (t *freezerTable) Close() error {
 		}
 	}
 	t.head = nil
+	t.codec.close()
 
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
@@ -421,11 +537,13 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
+		name := t.fileName(num)
+		if t.remote != nil {
+			// Writing into an offloaded data file, bring it back first
+			if err := t.remote.restore(name, filepath.Join(t.path, name)); err != nil {
+				return nil, err
+			}
+			delete(t.offloaded, num)
 		}
 		f, err = opener(filepath.Join(t.path, name))
 		if err != nil {
@@ -436,6 +554,11 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// fileName returns the name of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	return fmt.Sprintf("%s.%04d.%sdat", t.name, num, t.codec.ext())
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
@@ -456,6 +579,60 @@ func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 			}
 		}
 	}
+	for fnum := range t.offloaded {
+		if fnum > num {
+			delete(t.offloaded, fnum)
+			if remove {
+				if err := t.remote.remove(t.fileName(fnum)); err != nil {
+					t.logger.Error("Failed to delete remote data file", "file", fnum, "err", err)
+				}
+			}
+		}
+	}
+}
+
+// offload moves the sealed data files of the table to the object storage,
+// dropping their local copies. Reads are served from the object storage from
+// then on.
+func (t *freezerTable) offload() error {
+	t.lock.RLock()
+	var sealed []uint32
+	for num := range t.files {
+		if num < t.headId {
+			sealed = append(sealed, num)
+		}
+	}
+	t.lock.RUnlock()
+
+	sort.Slice(sealed, func(i, j int) bool { return sealed[i] < sealed[j] })
+	for _, num := range sealed {
+		// Upload without holding the lock, the sealed files are immutable
+		var (
+			name = t.fileName(num)
+			path = filepath.Join(t.path, name)
+		)
+		uploaded, err := t.remote.upload(name, path)
+		if err != nil {
+			return err
+		}
+		t.lock.Lock()
+		// The table might have been truncated into the file in the meantime
+		stat, err := os.Stat(path)
+		if num >= t.headId || err != nil || stat.Size() != uploaded.Size() || !stat.ModTime().Equal(uploaded.ModTime()) {
+			t.lock.Unlock()
+			continue
+		}
+		t.releaseFile(num)
+		t.offloaded[num] = struct{}{}
+		err = os.Remove(path)
+		t.lock.Unlock()
+
+		if err != nil {
+			return err
+		}
+		t.logger.Info("Moved data file to object storage", "file", name, "size", common.StorageSize(uploaded.Size()))
+	}
+	return nil
 }
 
 // Append injects a binary blob at the end of the freezer table. The item number
@@ -466,9 +643,7 @@ func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 // fsync before irreversibly deleting data from the database.
 func (t *freezerTable) Append(item uint64, blob []byte) error {
 	// Encode the blob before the lock portion
-	if !t.noCompression {
-		blob = snappy.Encode(nil, blob)
-	}
+	blob = t.codec.encode(blob)
 	// Read lock prevents competition with truncate
 	retry, err := t.append(item, blob, false)
 	if err != nil {
@@ -586,10 +761,7 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if t.noCompression {
-		return blob, nil
-	}
-	return snappy.Decode(nil, blob)
+	return t.codec.decode(blob)
 }
 
 // retrieve looks up the data offset of an item with the given number and retrieves
@@ -613,14 +785,18 @@ func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	dataFile, exist := t.files[filenum]
-	if !exist {
-		return nil, fmt.Errorf("missing data file %d", filenum)
-	}
 	// Retrieve the data itself, decompress and return
 	blob := make([]byte, endOffset-startOffset)
-	if _, err := dataFile.ReadAt(blob, int64(startOffset)); err != nil {
-		return nil, err
+	if dataFile, exist := t.files[filenum]; exist {
+		if _, err := dataFile.ReadAt(blob, int64(startOffset)); err != nil {
+			return nil, err
+		}
+	} else if _, offloaded := t.offloaded[filenum]; offloaded {
+		if err := t.remote.readAt(t.fileName(filenum), blob, int64(startOffset)); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("missing data file %d", filenum)
 	}
 	t.readMeter.Mark(int64(len(blob) + 2*indexEntrySize))
 	return blob, nil
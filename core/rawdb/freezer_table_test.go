@@ -19,6 +19,7 @@ package rawdb
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -638,3 +639,48 @@ func TestOffset(t *testing.T) {
 // However, all 'normal' failure modes arising due to failing to sync() or save a file should be
 // handled already, and the case described above can only (?) happen if an external process/user
 // deletes files from the filesystem.
+
+// TestFreezerRecompress tests that a table can be rewritten with another codec
+// and is opened with the new codec afterwards.
+func TestFreezerRecompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer-recompress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := NewFreezerTable(dir, freezerReceiptTable, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 255; x++ {
+		f.Append(uint64(x), getChunk(15, x))
+	}
+	f.Close()
+
+	for _, compression := range []string{FreezerCompressionZstd, FreezerCompressionNone, FreezerCompressionSnappy} {
+		if err := RecompressFreezerTable(dir, freezerReceiptTable, compression, nil); err != nil {
+			t.Fatalf("failed to recompress with %s: %v", compression, err)
+		}
+		if err := RecompressFreezerTable(dir, freezerReceiptTable, compression, nil); err == nil {
+			t.Fatalf("recompressed with the current codec %s", compression)
+		}
+		f, err := NewFreezerTable(dir, freezerReceiptTable, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind := f.codec.kind(); kind != compression {
+			t.Fatalf("codec mismatch: have %s, want %s", kind, compression)
+		}
+		for x := 0; x < 255; x++ {
+			blob, err := f.Retrieve(uint64(x))
+			if err != nil {
+				t.Fatalf("%s: item %d: failed to retrieve: %v", compression, x, err)
+			}
+			if !bytes.Equal(blob, getChunk(15, x)) {
+				t.Fatalf("%s: item %d: content mismatch: have %x", compression, x, blob)
+			}
+		}
+		f.Close()
+	}
+}
@@ -63,12 +63,64 @@ var (
 	// snapshotSyncStatusKey tracks the snapshot sync status across restarts.
 	snapshotSyncStatusKey = []byte("SnapshotSyncStatus")
 
+	// snapshotSyncHealKey tracks the trie nodes retrieved by the snapshot healer
+	// but not yet committed across restarts.
+	snapshotSyncHealKey = []byte("SnapshotSyncHeal")
+
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// txIndexAllowlistKey tracks the addresses whose transactions stay indexed
+	// below the tx index tail.
+	txIndexAllowlistKey = []byte("TransactionIndexAllowlist")
+
+	// txIndexAllowlistTailKey tracks the oldest block whose transactions of the
+	// allowlisted addresses have been indexed.
+	txIndexAllowlistTailKey = []byte("TransactionIndexAllowlistTail")
+
+	// internalCallIndexTailKey tracks the oldest block whose internal calls have
+	// been indexed.
+	internalCallIndexTailKey = []byte("InternalCallIndexTail")
+
+	// accountTxIndexTailKey tracks the oldest block whose transactions have been
+	// indexed by sender and recipient.
+	accountTxIndexTailKey = []byte("AccountTxIndexTail")
+
+	// senderTxIndexTailKey tracks the oldest block whose transactions have been
+	// indexed by sender and nonce.
+	senderTxIndexTailKey = []byte("SenderTxIndexTail")
+
+	// contractCreationIndexTailKey tracks the oldest block whose contract creations
+	// have been indexed.
+	contractCreationIndexTailKey = []byte("ContractCreationIndexTail")
+
+	// tokenTransferIndexTailKey tracks the oldest block whose token transfers
+	// have been indexed.
+	tokenTransferIndexTailKey = []byte("TokenTransferIndexTail")
+
+	// blockTracesTailKey tracks the oldest block whose traces have been recorded.
+	blockTracesTailKey = []byte("BlockTracesTail")
+
+	// blockRevertsTailKey tracks the oldest block whose transaction failures have
+	// been recorded.
+	blockRevertsTailKey = []byte("BlockRevertsTail")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
+	// statePruningProgressKey tracks the last state entry visited by an offline
+	// state pruning to resume it across restarts.
+	statePruningProgressKey = []byte("StatePruningProgress")
+
+	// exporterCheckpointKey tracks the last block published by the chain data
+	// exporter to resume it across restarts.
+	exporterCheckpointKey = []byte("ExporterCheckpoint")
+
+	// webhookPrefix and webhookCheckpointPrefix hold the address watch-lists
+	// registered with the webhook service, and the last block notified to each.
+	webhookPrefix           = []byte("Webhook-")           // webhookPrefix + id -> webhook
+	webhookCheckpointPrefix = []byte("WebhookCheckpoint-") // webhookCheckpointPrefix + id -> last notified block
+
 	//offSet of new updated ancientDB.
 	offSetOfCurrentAncientFreezer = []byte("offSetOfCurrentAncientFreezer")
 
@@ -89,12 +141,21 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
-
-	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
-	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
-	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
-	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
-	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
+	blockTracesPrefix   = []byte("T") // blockTracesPrefix + num (uint64 big endian) + hash -> block traces
+	blockRevertsPrefix  = []byte("E") // blockRevertsPrefix + num (uint64 big endian) + hash -> block transaction failures
+
+	txLookupPrefix         = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	bloomBitsPrefix        = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	SnapshotAccountPrefix  = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
+	SnapshotStoragePrefix  = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
+	CodePrefix             = []byte("c") // CodePrefix + code hash -> account code
+	internalCallPrefix     = []byte("I") // internalCallPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> block hash
+	accountTxPrefix        = []byte("X") // accountTxPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> block hash
+	senderTxPrefix         = []byte("N") // senderTxPrefix + address + nonce (uint64 big endian) -> num (uint64 big endian) + tx hash
+	contractCreationPrefix = []byte("C") // contractCreationPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) -> block hash + creator
+	tokenTransferPrefix    = []byte("K") // tokenTransferPrefix + address + num (uint64 big endian) + log index (uint32 big endian) + item (uint32 big endian) -> token transfer RLP
+	logIndexPrefix         = []byte("L") // logIndexPrefix + kind + address/topic + num (uint64 big endian) -> nil
+	reorgPrefix            = []byte("R") // reorgPrefix + ancestor num (uint64 big endian) + time (uint64 big endian) -> reorg record
 
 	// difflayer database
 	diffLayerPrefix = []byte("d") // diffLayerPrefix + hash  -> diffLayer
@@ -104,6 +165,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexIndexPrefix  = []byte("iL") // LogIndexIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -186,6 +248,16 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockTracesKey = blockTracesPrefix + num (uint64 big endian) + hash
+func blockTracesKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockTracesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// blockRevertsKey = blockRevertsPrefix + num (uint64 big endian) + hash
+func blockRevertsKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockRevertsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // diffLayerKey = diffLayerKeyPrefix + hash
 func diffLayerKey(hash common.Hash) []byte {
 	return append(append(diffLayerPrefix, hash.Bytes()...))
@@ -196,6 +268,70 @@ func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
 }
 
+// addressTxKey = prefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func addressTxKey(prefix []byte, address common.Address, number uint64, index uint32) []byte {
+	key := make([]byte, len(prefix)+common.AddressLength+8+4)
+	copy(key, prefix)
+	copy(key[len(prefix):], address.Bytes())
+	binary.BigEndian.PutUint64(key[len(prefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(prefix)+common.AddressLength+8:], index)
+	return key
+}
+
+// internalCallKey = internalCallPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func internalCallKey(address common.Address, number uint64, index uint32) []byte {
+	return addressTxKey(internalCallPrefix, address, number, index)
+}
+
+// accountTxKey = accountTxPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func accountTxKey(address common.Address, number uint64, index uint32) []byte {
+	return addressTxKey(accountTxPrefix, address, number, index)
+}
+
+// contractCreationKey = contractCreationPrefix + address + num (uint64 big endian) + tx index (uint32 big endian)
+func contractCreationKey(address common.Address, number uint64, index uint32) []byte {
+	return addressTxKey(contractCreationPrefix, address, number, index)
+}
+
+// tokenTransferKey = tokenTransferPrefix + address + num (uint64 big endian) + log index (uint32 big endian) + item (uint32 big endian)
+func tokenTransferKey(address common.Address, number uint64, logIndex uint32, item uint32) []byte {
+	key := make([]byte, len(tokenTransferPrefix)+common.AddressLength+8+4+4)
+	copy(key, tokenTransferPrefix)
+	copy(key[len(tokenTransferPrefix):], address.Bytes())
+	binary.BigEndian.PutUint64(key[len(tokenTransferPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[len(tokenTransferPrefix)+common.AddressLength+8:], logIndex)
+	binary.BigEndian.PutUint32(key[len(tokenTransferPrefix)+common.AddressLength+12:], item)
+	return key
+}
+
+// senderTxKey = senderTxPrefix + address + nonce (uint64 big endian)
+func senderTxKey(address common.Address, nonce uint64) []byte {
+	key := make([]byte, len(senderTxPrefix)+common.AddressLength+8)
+	copy(key, senderTxPrefix)
+	copy(key[len(senderTxPrefix):], address.Bytes())
+	binary.BigEndian.PutUint64(key[len(senderTxPrefix)+common.AddressLength:], nonce)
+	return key
+}
+
+// reorgKey = reorgPrefix + ancestor num (uint64 big endian) + time (uint64 big endian)
+func reorgKey(number uint64, time uint64) []byte {
+	key := make([]byte, len(reorgPrefix)+16)
+	copy(key, reorgPrefix)
+	binary.BigEndian.PutUint64(key[len(reorgPrefix):], number)
+	binary.BigEndian.PutUint64(key[len(reorgPrefix)+8:], time)
+	return key
+}
+
+// logIndexKey = logIndexPrefix + kind + address/topic + num (uint64 big endian)
+func logIndexKey(kind byte, value common.Hash, number uint64) []byte {
+	key := make([]byte, len(logIndexPrefix)+1+common.HashLength+8)
+	copy(key, logIndexPrefix)
+	key[len(logIndexPrefix)] = kind
+	copy(key[len(logIndexPrefix)+1:], value.Bytes())
+	binary.BigEndian.PutUint64(key[len(logIndexPrefix)+1+common.HashLength:], number)
+	return key
+}
+
 // accountSnapshotKey = SnapshotAccountPrefix + hash
 func accountSnapshotKey(hash common.Hash) []byte {
 	return append(SnapshotAccountPrefix, hash.Bytes()...)
@@ -0,0 +1,551 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errSecondaryNotFound is returned if a key is requested that is not found in
+// the secondary database, or was deleted from it locally.
+var errSecondaryNotFound = errors.New("not found")
+
+// secondaryBase is one opening of the database of the primary process. It is
+// reference counted, so that the iterators still walking it keep it open after
+// the secondary database moved on to a newer opening.
+type secondaryBase struct {
+	ethdb.Database
+	refs int32
+}
+
+// retain acquires a reference to the opening.
+func (b *secondaryBase) retain() *secondaryBase {
+	atomic.AddInt32(&b.refs, 1)
+	return b
+}
+
+// release drops a reference to the opening, closing it after the last one.
+func (b *secondaryBase) release() {
+	if atomic.AddInt32(&b.refs, -1) == 0 {
+		if err := b.Close(); err != nil {
+			log.Warn("Failed to close secondary database", "err", err)
+		}
+	}
+}
+
+// SecondaryDatabase is a read-only view of a database owned by another process,
+// the primary. It opens the files of the primary without locking them and
+// catches up with its writes by reopening them periodically.
+//
+// Writes are kept in memory only, visible to the local process until the next
+// catch-up discards them. They only serve the bookkeeping of the services run
+// on top of the database, the data files are never modified.
+//
+// Opening the files of a live database is optimistic: data the primary moves
+// around between two catch-ups, e.g. by compacting its tables, may fail to be
+// read until the next one.
+type SecondaryDatabase struct {
+	opts OpenOptions
+
+	base    *secondaryBase    // Latest opening of the primary database
+	overlay map[string][]byte // Local writes since the latest opening, nil marking deletions
+	diff    ethdb.KeyValueStore
+	lock    sync.RWMutex // Mutex protecting the opening and the local writes
+
+	quit chan struct{}
+}
+
+// NewSecondaryDatabase opens the database of another process as a secondary. The
+// database is caught up with the primary every refresh interval of the options,
+// or only on an explicit Refresh if the interval is zero.
+func NewSecondaryDatabase(o OpenOptions) (*SecondaryDatabase, error) {
+	base, err := openSecondaryBase(o)
+	if err != nil {
+		return nil, err
+	}
+	db := &SecondaryDatabase{
+		opts:    o,
+		base:    base,
+		overlay: make(map[string][]byte),
+		quit:    make(chan struct{}),
+	}
+	if o.Refresh > 0 {
+		go db.loop(o.Refresh)
+	}
+	log.Info("Opened secondary database", "path", o.Directory, "refresh", o.Refresh)
+	return db, nil
+}
+
+// openSecondaryBase opens the key-value store and the freezer of the primary
+// with the engine they were created with.
+func openSecondaryBase(o OpenOptions) (*secondaryBase, error) {
+	var (
+		kvdb ethdb.KeyValueStore
+		err  error
+	)
+	switch engine := PreexistingDatabase(o.Directory); engine {
+	case DBPebble:
+		kvdb, err = newSharedPebbleStore(o.Directory, o.Cache, o.Handles, o.Namespace)
+	case DBLeveldb:
+		kvdb, err = leveldb.NewShared(o.Directory, o.Cache, o.Handles, o.Namespace)
+	default:
+		return nil, fmt.Errorf("no database found in %s", o.Directory)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.AncientsDirectory == "" {
+		return &secondaryBase{Database: NewDatabase(kvdb), refs: 1}, nil
+	}
+	// The freezer is opened after the key-value store, so that the blocks frozen
+	// in the meantime are found in either of them
+	frdb, err := newSharedFreezer(o.AncientsDirectory, o.Namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	if o.IsLastOffset {
+		frdb.offset = ReadOffSetOfLastAncientFreezer(kvdb)
+	} else {
+		frdb.offset = ReadOffSetOfCurrentAncientFreezer(kvdb)
+	}
+	frdb.frozen += frdb.offset
+
+	return &secondaryBase{Database: &freezerdb{KeyValueStore: kvdb, AncientStore: frdb}, refs: 1}, nil
+}
+
+// loop catches up with the primary every refresh interval.
+func (db *SecondaryDatabase) loop(refresh time.Duration) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := db.Refresh(); err != nil {
+				log.Warn("Failed to catch up with primary database", "path", db.opts.Directory, "err", err)
+			}
+		case <-db.quit:
+			return
+		}
+	}
+}
+
+// Refresh reopens the database of the primary to observe its writes since the
+// last opening, discarding the local writes. Iterators created before keep
+// walking the previous opening.
+func (db *SecondaryDatabase) Refresh() error {
+	base, err := openSecondaryBase(db.opts)
+	if err != nil {
+		return err
+	}
+	db.lock.Lock()
+	if db.base == nil {
+		db.lock.Unlock()
+		base.release()
+		return errClosed
+	}
+	old := db.base
+	db.base, db.overlay = base, make(map[string][]byte)
+	db.lock.Unlock()
+
+	old.release()
+	return nil
+}
+
+// Close stops catching up with the primary and closes the database once all
+// the iterators are released.
+func (db *SecondaryDatabase) Close() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.base == nil {
+		return nil
+	}
+	close(db.quit)
+	db.base.release()
+	db.base, db.overlay = nil, nil
+	if db.diff != nil {
+		return db.diff.Close()
+	}
+	return nil
+}
+
+// Has retrieves if a key is present in the database.
+func (db *SecondaryDatabase) Has(key []byte) (bool, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return false, errClosed
+	}
+	if value, ok := db.overlay[string(key)]; ok {
+		return value != nil, nil
+	}
+	return db.base.Has(key)
+}
+
+// Get retrieves the given key if it's present in the database.
+func (db *SecondaryDatabase) Get(key []byte) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return nil, errClosed
+	}
+	if value, ok := db.overlay[string(key)]; ok {
+		if value == nil {
+			return nil, errSecondaryNotFound
+		}
+		return common.CopyBytes(value), nil
+	}
+	return db.base.Get(key)
+}
+
+// Put inserts the given value into the local writes.
+func (db *SecondaryDatabase) Put(key []byte, value []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.base == nil {
+		return errClosed
+	}
+	db.overlay[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete marks the key deleted in the local writes.
+func (db *SecondaryDatabase) Delete(key []byte) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.base == nil {
+		return errClosed
+	}
+	db.overlay[string(key)] = nil
+	return nil
+}
+
+// NewBatch creates a write-only batch committing into the local writes.
+func (db *SecondaryDatabase) NewBatch() ethdb.Batch {
+	return &secondaryBatch{db: db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset of database
+// content with a particular key prefix, starting at a particular initial key,
+// merging the local writes into the opening of the primary.
+func (db *SecondaryDatabase) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return &secondaryIterator{}
+	}
+	var (
+		pr     = string(prefix)
+		st     = pr + string(start)
+		keys   = make([]string, 0, len(db.overlay))
+		values = make([][]byte, 0, len(db.overlay))
+	)
+	for key := range db.overlay {
+		if strings.HasPrefix(key, pr) && key >= st {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values = append(values, db.overlay[key])
+	}
+	base := db.base.retain()
+	return &secondaryIterator{
+		base:    base.NewIterator(prefix, start),
+		keys:    keys,
+		values:  values,
+		advance: true,
+		release: base.release,
+	}
+}
+
+// Stat returns a particular internal stat of the opening of the primary.
+func (db *SecondaryDatabase) Stat(property string) (string, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return "", errClosed
+	}
+	return db.base.Stat(property)
+}
+
+// Compact is not supported, the data files belong to the primary.
+func (db *SecondaryDatabase) Compact(start []byte, limit []byte) error {
+	return errReadOnly
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (db *SecondaryDatabase) HasAncient(kind string, number uint64) (bool, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return false, errClosed
+	}
+	return db.base.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (db *SecondaryDatabase) Ancient(kind string, number uint64) ([]byte, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return nil, errClosed
+	}
+	return db.base.Ancient(kind, number)
+}
+
+// Ancients returns the length of the frozen items.
+func (db *SecondaryDatabase) Ancients() (uint64, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return 0, errClosed
+	}
+	return db.base.Ancients()
+}
+
+// ItemAmountInAncient returns the actual length of the ancient database.
+func (db *SecondaryDatabase) ItemAmountInAncient() (uint64, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return 0, errClosed
+	}
+	return db.base.ItemAmountInAncient()
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (db *SecondaryDatabase) AncientSize(kind string) (uint64, error) {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return 0, errClosed
+	}
+	return db.base.AncientSize(kind)
+}
+
+// AncientOffSet returns the offset of the ancient database.
+func (db *SecondaryDatabase) AncientOffSet() uint64 {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if db.base == nil {
+		return 0
+	}
+	return db.base.AncientOffSet()
+}
+
+// AppendAncient is not supported, the data files belong to the primary.
+func (db *SecondaryDatabase) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	return errReadOnly
+}
+
+// TruncateAncients is not supported, the data files belong to the primary.
+func (db *SecondaryDatabase) TruncateAncients(items uint64) error {
+	return errReadOnly
+}
+
+// Sync does nothing, as nothing is ever written to disk.
+func (db *SecondaryDatabase) Sync() error {
+	return nil
+}
+
+// DiffStore returns the attached diff store, if any.
+func (db *SecondaryDatabase) DiffStore() ethdb.KeyValueStore {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return db.diff
+}
+
+// SetDiffStore attaches a diff store, closed together with the database.
+func (db *SecondaryDatabase) SetDiffStore(diff ethdb.KeyValueStore) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.diff != nil {
+		db.diff.Close()
+	}
+	db.diff = diff
+}
+
+// secondaryBatch is a write-only batch that commits changes into the local
+// writes of a secondary database when Write is called.
+type secondaryBatch struct {
+	db     *SecondaryDatabase
+	keys   [][]byte
+	values [][]byte // nil marking deletions
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *secondaryBatch) Put(key, value []byte) error {
+	b.keys = append(b.keys, common.CopyBytes(key))
+	b.values = append(b.values, append([]byte{}, value...))
+	b.size += len(value)
+	return nil
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *secondaryBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, common.CopyBytes(key))
+	b.values = append(b.values, nil)
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *secondaryBatch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to the local writes.
+func (b *secondaryBatch) Write() error {
+	b.db.lock.Lock()
+	defer b.db.lock.Unlock()
+
+	if b.db.base == nil {
+		return errClosed
+	}
+	for i, key := range b.keys {
+		b.db.overlay[string(key)] = b.values[i]
+	}
+	return nil
+}
+
+// Reset resets the batch for reuse.
+func (b *secondaryBatch) Reset() {
+	b.keys, b.values = b.keys[:0], b.values[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *secondaryBatch) Replay(w ethdb.KeyValueWriter) error {
+	for i, key := range b.keys {
+		if b.values[i] == nil {
+			if err := w.Delete(key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(key, b.values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secondaryIterator walks the opening of the primary database and the local
+// writes taken at its creation together, the local writes taking precedence.
+type secondaryIterator struct {
+	base    ethdb.Iterator
+	baseOk  bool // Whether the base iterator is positioned at an item
+	advance bool // Whether the base iterator needs to be moved before use
+
+	keys   []string // Sorted local writes not iterated yet
+	values [][]byte
+
+	key     []byte
+	value   []byte
+	release func()
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *secondaryIterator) Next() bool {
+	if it.base == nil {
+		return false
+	}
+	for {
+		if it.advance {
+			it.baseOk, it.advance = it.base.Next(), false
+		}
+		if len(it.keys) == 0 && !it.baseOk {
+			it.key, it.value = nil, nil
+			return false
+		}
+		if len(it.keys) > 0 && (!it.baseOk || it.keys[0] <= string(it.base.Key())) {
+			// The local write shadows the primary's item with the same key
+			if it.baseOk && it.keys[0] == string(it.base.Key()) {
+				it.advance = true
+			}
+			key, value := it.keys[0], it.values[0]
+			it.keys, it.values = it.keys[1:], it.values[1:]
+			if value == nil {
+				continue
+			}
+			it.key, it.value = []byte(key), value
+			return true
+		}
+		it.key, it.value = common.CopyBytes(it.base.Key()), common.CopyBytes(it.base.Value())
+		it.advance = true
+		return true
+	}
+}
+
+// Error returns any accumulated error of the walked opening.
+func (it *secondaryIterator) Error() error {
+	if it.base == nil {
+		return nil
+	}
+	return it.base.Error()
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *secondaryIterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *secondaryIterator) Value() []byte {
+	return it.value
+}
+
+// Release releases associated resources, allowing the walked opening to be
+// closed once the secondary database moved on.
+func (it *secondaryIterator) Release() {
+	if it.base == nil {
+		return
+	}
+	it.base.Release()
+	it.base, it.keys, it.values = nil, nil, nil
+	it.release()
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+)
+
+func TestSecondaryDatabaseSuite(t *testing.T) {
+	dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+		dir := t.TempDir()
+		primary, err := Open(OpenOptions{Directory: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { primary.Close() })
+
+		db, err := Open(OpenOptions{Directory: dir, Secondary: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// Tests that a secondary database reads the data of a live primary, keeps the
+// local writes apart and catches up with the primary when refreshed.
+func TestSecondaryCatchUp(t *testing.T) {
+	engines := []string{DBLeveldb}
+	if PebbleEnabled {
+		engines = append(engines, DBPebble)
+	}
+	for _, engine := range engines {
+		t.Run(engine, func(t *testing.T) {
+			testSecondaryCatchUp(t, engine)
+		})
+	}
+}
+
+func testSecondaryCatchUp(t *testing.T, engine string) {
+	var (
+		dir     = t.TempDir()
+		ancient = filepath.Join(dir, "ancient")
+	)
+	primary, err := Open(OpenOptions{Type: engine, Directory: dir, AncientsDirectory: ancient, DisableFreeze: true})
+	if err != nil {
+		t.Fatalf("failed to open primary: %v", err)
+	}
+	defer primary.Close()
+
+	for i := byte(0); i < 4; i++ {
+		primary.Put([]byte{'k', i}, []byte{i})
+	}
+	for i := uint64(0); i < 2; i++ {
+		if err := primary.AppendAncient(i, []byte{'h'}, []byte{'h'}, []byte{'b'}, []byte{'r'}, []byte{'t'}); err != nil {
+			t.Fatalf("failed to append ancient %d: %v", i, err)
+		}
+	}
+	if err := primary.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewSecondaryDatabase(OpenOptions{Directory: dir, AncientsDirectory: ancient})
+	if err != nil {
+		t.Fatalf("failed to open secondary: %v", err)
+	}
+	defer db.Close()
+
+	if frozen, _ := db.Ancients(); frozen != 2 {
+		t.Fatalf("ancients mismatch: have %d, want 2", frozen)
+	}
+	if blob, err := db.Ancient(freezerBodiesTable, 1); err != nil || !bytes.Equal(blob, []byte{'b'}) {
+		t.Fatalf("ancient body mismatch: have %x, %v", blob, err)
+	}
+	if err := db.AppendAncient(2, nil, nil, nil, nil, nil); err == nil {
+		t.Fatal("ancient appended to secondary")
+	}
+	// Shadow the primary with local writes and check the merged view
+	db.Put([]byte{'k', 1}, []byte{0x11})
+	db.Delete([]byte{'k', 2})
+	db.Put([]byte{'k', 9}, []byte{0x99})
+
+	want := map[string][]byte{"k\x00": {0}, "k\x01": {0x11}, "k\x03": {3}, "k\x09": {0x99}}
+	checkSecondary(t, db, want)
+
+	held := db.NewIterator([]byte{'k'}, nil)
+	defer held.Release()
+
+	// Write into the primary and catch up, discarding the local writes
+	primary.Put([]byte{'k', 4}, []byte{4})
+	if err := primary.AppendAncient(2, []byte{'h'}, []byte{'h'}, []byte{'b'}, []byte{'r'}, []byte{'t'}); err != nil {
+		t.Fatalf("failed to append ancient: %v", err)
+	}
+	if err := db.Refresh(); err != nil {
+		t.Fatalf("failed to refresh secondary: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 3 {
+		t.Fatalf("ancients mismatch after refresh: have %d, want 3", frozen)
+	}
+	checkSecondary(t, db, map[string][]byte{"k\x00": {0}, "k\x01": {1}, "k\x02": {2}, "k\x03": {3}, "k\x04": {4}})
+
+	// The iterator created before the refresh still walks the old view
+	var keys int
+	for held.Next() {
+		if !bytes.Equal(held.Value(), want[string(held.Key())]) {
+			t.Errorf("held iterator item %x mismatch: have %x, want %x", held.Key(), held.Value(), want[string(held.Key())])
+		}
+		keys++
+	}
+	if err := held.Error(); err != nil || keys != len(want) {
+		t.Errorf("held iterator mismatch: have %d items, want %d, err %v", keys, len(want), err)
+	}
+}
+
+// checkSecondary checks that both the point reads and the iteration of the
+// database match the expected content.
+func checkSecondary(t *testing.T, db ethdb.KeyValueStore, want map[string][]byte) {
+	t.Helper()
+
+	for key, value := range want {
+		if have, err := db.Get([]byte(key)); err != nil || !bytes.Equal(have, value) {
+			t.Errorf("item %x mismatch: have %x, want %x, err %v", key, have, value, err)
+		}
+	}
+	it := db.NewIterator([]byte{'k'}, nil)
+	defer it.Release()
+
+	var keys int
+	for it.Next() {
+		if value, ok := want[string(it.Key())]; !ok || !bytes.Equal(it.Value(), value) {
+			t.Errorf("iterated item %x mismatch: have %x, want %x", it.Key(), it.Value(), value)
+		}
+		keys++
+	}
+	if keys != len(want) {
+		t.Errorf("iterated item count mismatch: have %d, want %d", keys, len(want))
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// revertFrame is a call frame entered but not exited yet, along with the last
+// opcode it ran.
+type revertFrame struct {
+	address common.Address
+	op      vm.OpCode
+	pc      uint64
+	run     bool // Whether the frame ran any opcode
+}
+
+// revertRecorder is an EVM logger recording the failures of the transactions of
+// a block while it's imported, so that they can be served without executing the
+// transactions again.
+type revertRecorder struct {
+	env     *vm.EVM
+	reverts []types.RevertTrace
+
+	// Failure of the transaction being executed
+	frames  []revertFrame
+	failure *types.RevertTrace // Failure of the frame the current revert originated in
+}
+
+func newRevertRecorder() *revertRecorder {
+	return &revertRecorder{}
+}
+
+func (r *revertRecorder) enter(address common.Address) {
+	// A failure below the entered frame was handled by its caller
+	if r.failure != nil && r.failure.Depth >= uint64(len(r.frames)) {
+		r.failure = nil
+	}
+	r.frames = append(r.frames, revertFrame{address: address})
+}
+
+func (r *revertRecorder) exit(output []byte, err error) {
+	if len(r.frames) == 0 {
+		return
+	}
+	var (
+		depth = uint64(len(r.frames) - 1)
+		frame = r.frames[depth]
+	)
+	r.frames = r.frames[:depth]
+
+	if err == nil {
+		r.failure = nil
+		return
+	}
+	// Keep the originating frame of a revert passed through unchanged
+	if r.failure != nil && r.failure.Depth > depth && errors.Is(err, vm.ErrExecutionReverted) && bytes.Equal(output, r.failure.Output) {
+		return
+	}
+	r.failure = &types.RevertTrace{
+		Error:   err.Error(),
+		Output:  common.CopyBytes(output),
+		Address: frame.address,
+		Depth:   depth,
+	}
+	if frame.run {
+		r.failure.Op, r.failure.Pc = frame.op.String(), frame.pc
+	}
+}
+
+func (r *revertRecorder) step(pc uint64, op vm.OpCode) {
+	if len(r.frames) == 0 {
+		return
+	}
+	frame := &r.frames[len(r.frames)-1]
+	frame.op, frame.pc, frame.run = op, pc, true
+}
+
+func (r *revertRecorder) CaptureTxStart(gasLimit uint64) {
+	r.frames, r.failure = nil, nil
+}
+
+func (r *revertRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	r.env = env
+	r.enter(to)
+}
+
+func (r *revertRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	r.enter(to)
+}
+
+func (r *revertRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	r.step(pc, op)
+}
+
+func (r *revertRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	r.step(pc, op)
+}
+
+func (r *revertRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {
+	r.exit(output, err)
+}
+
+func (r *revertRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	r.exit(output, err)
+	if err == nil || r.failure == nil || r.env == nil {
+		return
+	}
+	state, ok := r.env.StateDB.(interface{ TxIndex() int })
+	if !ok {
+		return
+	}
+	failure := *r.failure
+	failure.TxIndex = uint64(state.TxIndex())
+	r.reverts = append(r.reverts, failure)
+}
+
+func (r *revertRecorder) CaptureTxEnd(restGas uint64) {}
+
+// blockReverts returns the failures recorded for the block, in transaction order.
+func (r *revertRecorder) blockReverts() *types.BlockReverts {
+	reverts := &types.BlockReverts{Txs: r.reverts}
+	sort.SliceStable(reverts.Txs, func(i, j int) bool { return reverts.Txs[i].TxIndex < reverts.Txs[j].TxIndex })
+	return reverts
+}
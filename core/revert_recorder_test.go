@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the failures of the transactions are recorded on import, along
+// with the call frames they originated in.
+func TestBlockReverts(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		caller  = common.HexToAddress("0xaaaa")
+		callee  = common.HexToAddress("0xbbbb")
+		catcher = common.HexToAddress("0xcccc")
+		db      = rawdb.NewMemoryDatabase()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		invalid = vm.OpCode(0xfe)
+		// Error("nope")
+		reason = common.FromHex("0x08c379a0000000000000000000000000000000000000000000000000000000000000002000000000000000000000000000000000000000000000000000000000000000046e6f706500000000000000000000000000000000000000000000000000000000")
+		// PUSH1 100 PUSH1 12 PUSH1 0 CODECOPY PUSH1 100 PUSH1 0 REVERT, followed by the reason
+		reverter = append(common.FromHex("0x6064600c60003960646000fd"), reason...)
+		// PUSH1 0 (x5) PUSH20 callee GAS CALL POP, followed by the given code
+		call = func(code ...byte) []byte {
+			return append(append(append(common.FromHex("0x6000600060006000600073"), callee.Bytes()...), byte(vm.GAS), byte(vm.CALL), byte(vm.POP)), code...)
+		}
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			callee: {Balance: common.Big0, Code: reverter},
+			// Bubbles the revert up: RETURNDATASIZE PUSH1 0 PUSH1 0 RETURNDATACOPY RETURNDATASIZE PUSH1 0 REVERT
+			caller: {Balance: common.Big0, Code: call(common.FromHex("0x3d600060003e3d6000fd")...)},
+			// Handles the revert, then fails on its own
+			catcher: {Balance: common.Big0, Code: call(byte(invalid))},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		recipients := []common.Address{common.HexToAddress("0xdddd")}
+		if i == 0 {
+			recipients = []common.Address{caller, common.HexToAddress("0xdddd"), catcher}
+		}
+		for _, to := range recipients {
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), to, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+			gen.AddTx(tx)
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableBlockReverts)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadBlockRevertsTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("reverts tail mismatch: have %v, want 1", tail)
+	}
+	reverts := rawdb.ReadBlockReverts(db, blocks[0].Hash(), 1)
+	if reverts == nil || len(reverts.Txs) != 2 {
+		t.Fatalf("failures mismatch: have %+v, want 2", reverts)
+	}
+	// The bubbled revert is attributed to the callee raising it
+	if have := reverts.Txs[0]; have.TxIndex != 0 || have.Address != callee || have.Depth != 1 || have.Op != "REVERT" || have.Pc != 11 ||
+		have.Error != vm.ErrExecutionReverted.Error() || !bytes.Equal(have.Output, reason) {
+		t.Errorf("bubbled revert mismatch: have %+v", have)
+	}
+	// The handled revert is superseded by the failure of the catcher
+	if have := reverts.Txs[1]; have.TxIndex != 2 || have.Address != catcher || have.Depth != 0 || have.Op != invalid.String() || have.Pc != 34 || len(have.Output) != 0 {
+		t.Errorf("handled revert mismatch: have %+v", have)
+	}
+	// Blocks without failures are recorded too
+	if reverts := rawdb.ReadBlockReverts(db, blocks[1].Hash(), 2); reverts == nil || len(reverts.Txs) != 0 {
+		t.Errorf("empty failures mismatch: have %+v", reverts)
+	}
+}
@@ -79,6 +79,10 @@ var (
 // the whole pruning work. It's recommended to run this offline tool
 // periodically in order to release the disk usage and improve the
 // disk read performance to some extent.
+//
+// If a number of blocks to keep is configured, the states of all those
+// recent blocks are retained instead of a single one, which allows to
+// trim the history of an archive node down to a configurable depth.
 type Pruner struct {
 	db            ethdb.Database
 	stateBloom    *stateBloom
@@ -87,6 +91,7 @@ type Pruner struct {
 	headHeader    *types.Header
 	snaptree      *snapshot.Tree
 	triesInMemory uint64
+	keepBlocks    uint64 // Number of recent blocks whose states are retained, zero for a single state
 }
 
 type BlockPruner struct {
@@ -98,7 +103,7 @@ type BlockPruner struct {
 }
 
 // NewPruner creates the pruner instance.
-func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize, triesInMemory uint64) (*Pruner, error) {
+func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize, triesInMemory, keepBlocks uint64) (*Pruner, error) {
 	headBlock := rawdb.ReadHeadBlock(db)
 	if headBlock == nil {
 		return nil, errors.New("Failed to load head block")
@@ -123,6 +128,7 @@ func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize, trie
 		datadir:       datadir,
 		trieCachePath: trieCachePath,
 		triesInMemory: triesInMemory,
+		keepBlocks:    keepBlocks,
 		headHeader:    headBlock.Header(),
 		snaptree:      snaptree,
 	}, nil
@@ -152,8 +158,12 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 		pstart = time.Now()
 		logged = time.Now()
 		batch  = maindb.NewBatch()
-		iter   = maindb.NewIterator(nil, nil)
+		resume = rawdb.ReadStatePruningProgress(maindb)
+		iter   = maindb.NewIterator(nil, resume)
 	)
+	if len(resume) > 0 {
+		log.Info("Resuming state pruning", "from", common.Bytes2Hex(resume))
+	}
 	for iter.Next() {
 		key := iter.Key()
 
@@ -196,6 +206,7 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 			// Recreate the iterator after every batch commit in order
 			// to allow the underlying compactor to delete the entries.
 			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				rawdb.WriteStatePruningProgress(batch, key)
 				batch.Write()
 				batch.Reset()
 
@@ -209,6 +220,7 @@ func prune(snaptree *snapshot.Tree, root common.Hash, maindb ethdb.Database, sta
 		batch.Reset()
 	}
 	iter.Release()
+	rawdb.DeleteStatePruningProgress(maindb)
 	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)))
 
 	// Pruning is done, now drop the "useless" layers from the snapshot.
@@ -459,6 +471,16 @@ func (p *Pruner) Prune(root common.Hash) error {
 	if stateBloomRoot != (common.Hash{}) {
 		return RecoverPruning(p.datadir, p.db, p.trieCachePath, p.triesInMemory)
 	}
+	// Drop any progress left behind by a pruning interrupted before its bloom
+	// filter was committed, the new run has to visit the entire database.
+	rawdb.DeleteStatePruningProgress(p.db)
+
+	if p.keepBlocks > 0 {
+		if root != (common.Hash{}) {
+			return errors.New("pruning target root can't be combined with kept blocks")
+		}
+		return p.pruneHistory()
+	}
 	// If the target state root is not specified, use the HEAD-(n-1) as the
 	// target. The reason for picking it is:
 	// - in most of the normal cases, the related state is available
@@ -558,6 +580,84 @@ func (p *Pruner) Prune(root common.Hash) error {
 	return prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start)
 }
 
+// pruneHistory deletes all historical state nodes except the ones belonging to
+// the states of the most recent blocks. As the head state is retained, the
+// snapshot is flattened into a single disk layer at the head.
+func (p *Pruner) pruneHistory() error {
+	head := p.headHeader.Number.Uint64()
+	if p.keepBlocks > head {
+		return fmt.Errorf("chain not long enough: have %d blocks, keep %d", head, p.keepBlocks)
+	}
+	// The head state must be persisted, it's the only one the node can resume
+	// from without the pruned history.
+	if blob := rawdb.ReadTrieNode(p.db, p.headHeader.Root); len(blob) == 0 {
+		return fmt.Errorf("head state %x is not persisted, kept blocks require an archive node", p.headHeader.Root)
+	}
+	boundary := head + 1 - p.keepBlocks
+	header := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, boundary), boundary)
+	if header == nil {
+		return fmt.Errorf("missing boundary header #%d", boundary)
+	}
+	if blob := rawdb.ReadTrieNode(p.db, header.Root); len(blob) == 0 {
+		return fmt.Errorf("boundary state #%d [%x] is not present", boundary, header.Root)
+	}
+	log.Info("Selecting recent states as the pruning target", "boundary", boundary, "head", head)
+
+	// Before start the pruning, delete the clean trie cache first.
+	// It's necessary otherwise in the next restart we will hit the
+	// deleted state root in the "clean cache" so that the incomplete
+	// state is picked for usage.
+	deleteCleanTrieCache(p.trieCachePath)
+
+	// Commit the entire boundary state into the bloom filter, then only the
+	// changes of every later block. States not persisted are skipped, they
+	// aren't available before the pruning either.
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		parent = header.Root
+	)
+	if err := commitStateDiff(p.db, common.Hash{}, parent, p.stateBloom); err != nil {
+		return err
+	}
+	for number := boundary + 1; number <= head; number++ {
+		header := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, number), number)
+		if header == nil {
+			return fmt.Errorf("missing header #%d", number)
+		}
+		if header.Root == parent {
+			continue
+		}
+		if blob := rawdb.ReadTrieNode(p.db, header.Root); len(blob) == 0 {
+			continue
+		}
+		if err := commitStateDiff(p.db, parent, header.Root, p.stateBloom); err != nil {
+			return err
+		}
+		parent = header.Root
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Collecting recent states", "number", number, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// Traverse the genesis, put all genesis state entries into the
+	// bloom filter too.
+	if err := extractGenesis(p.db, p.stateBloom); err != nil {
+		return err
+	}
+	// The bloom filter is named after the head state to resume the pruning
+	// against the head snapshot layer.
+	filterName := bloomFilterName(p.datadir, p.headHeader.Root)
+
+	log.Info("Writing state bloom to disk", "name", filterName)
+	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return err
+	}
+	log.Info("State bloom filter committed", "name", filterName)
+	return prune(p.snaptree, p.headHeader.Root, p.db, p.stateBloom, filterName, nil, start)
+}
+
 // RecoverPruning will resume the pruning procedure during the system restart.
 // This function is used in this case: user tries to prune state data, but the
 // system was interrupted midway because of crash or manual-kill. In this case
@@ -633,11 +733,28 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 	if genesis == nil {
 		return errors.New("missing genesis block")
 	}
-	t, err := trie.NewSecure(genesis.Root(), trie.NewDatabase(db))
+	return commitStateDiff(db, common.Hash{}, genesis.Root(), stateBloom)
+}
+
+// commitStateDiff commits the trie nodes and contract codes of the state with
+// the given root which are not part of the parent state into the bloom filter.
+// An empty parent root commits the entire state.
+func commitStateDiff(db ethdb.Database, parent, root common.Hash, stateBloom *stateBloom) error {
+	triedb := trie.NewDatabase(db)
+	t, err := trie.New(root, triedb)
 	if err != nil {
 		return err
 	}
-	accIter := t.NodeIterator(nil)
+	var (
+		parentTrie *trie.Trie
+		accIter    = t.NodeIterator(nil)
+	)
+	if parent != (common.Hash{}) {
+		if parentTrie, err = trie.New(parent, triedb); err != nil {
+			return err
+		}
+		accIter, _ = trie.NewDifferenceIterator(parentTrie.NodeIterator(nil), accIter)
+	}
 	for accIter.Next(true) {
 		hash := accIter.Hash()
 
@@ -652,12 +769,33 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 			if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
 				return err
 			}
-			if acc.Root != emptyRoot {
-				storageTrie, err := trie.NewSecure(acc.Root, trie.NewDatabase(db))
+			parentStorage := emptyRoot
+			if parentTrie != nil {
+				blob, err := parentTrie.TryGet(accIter.LeafKey())
+				if err != nil {
+					return err
+				}
+				if len(blob) > 0 {
+					var parentAcc state.Account
+					if err := rlp.DecodeBytes(blob, &parentAcc); err != nil {
+						return err
+					}
+					parentStorage = parentAcc.Root
+				}
+			}
+			if acc.Root != emptyRoot && acc.Root != parentStorage {
+				storageTrie, err := trie.New(acc.Root, triedb)
 				if err != nil {
 					return err
 				}
 				storageIter := storageTrie.NodeIterator(nil)
+				if parentStorage != emptyRoot {
+					parentStorageTrie, err := trie.New(parentStorage, triedb)
+					if err != nil {
+						return err
+					}
+					storageIter, _ = trie.NewDifferenceIterator(parentStorageTrie.NodeIterator(nil), storageIter)
+				}
 				for storageIter.Next(true) {
 					hash := storageIter.Hash()
 					if hash != (common.Hash{}) {
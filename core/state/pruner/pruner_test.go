@@ -0,0 +1,127 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// commitTestState applies the given modifications on top of the parent state
+// and persists the result.
+func commitTestState(t *testing.T, db state.Database, parent common.Hash, modify func(*state.StateDB)) common.Hash {
+	statedb, err := state.New(parent, db, nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	modify(statedb)
+
+	statedb.Finalise(false)
+	statedb.AccountsIntermediateRoot()
+	root, _, err := statedb.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to persist state: %v", err)
+	}
+	return root
+}
+
+// collectState returns the hashes of all the trie nodes and codes of a state.
+func collectState(t *testing.T, db ethdb.Database, root common.Hash) map[common.Hash]struct{} {
+	statedb, err := state.New(root, state.NewDatabase(db), nil)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	hashes := make(map[common.Hash]struct{})
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			hashes[it.Hash] = struct{}{}
+		}
+	}
+	if it.Error != nil {
+		t.Fatalf("failed to iterate state: %v", it.Error)
+	}
+	return hashes
+}
+
+// Tests that committing the difference of two states into the bloom filter on
+// top of the parent state covers the entire child state.
+func TestCommitStateDiff(t *testing.T) {
+	var (
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = state.NewDatabase(diskdb)
+	)
+	parent := commitTestState(t, db, common.Hash{}, func(statedb *state.StateDB) {
+		for i := byte(0); i < 64; i++ {
+			addr := common.Address{i}
+			statedb.AddBalance(addr, big.NewInt(int64(i)+1))
+			statedb.SetState(addr, common.Hash{i}, common.Hash{i})
+			if i%8 == 0 {
+				statedb.SetCode(addr, []byte{i, 0xfe})
+			}
+		}
+	})
+	child := commitTestState(t, db, parent, func(statedb *state.StateDB) {
+		for i := byte(0); i < 64; i += 4 {
+			addr := common.Address{i}
+			statedb.AddBalance(addr, big.NewInt(1))
+			statedb.SetState(addr, common.Hash{0xff, i}, common.Hash{i})
+		}
+		statedb.SetCode(common.Address{0xff}, []byte{0xff, 0xfe})
+	})
+	bloom, err := newStateBloomWithSize(1)
+	if err != nil {
+		t.Fatalf("failed to create bloom: %v", err)
+	}
+	if err := commitStateDiff(diskdb, common.Hash{}, parent, bloom); err != nil {
+		t.Fatalf("failed to commit parent state: %v", err)
+	}
+	if err := commitStateDiff(diskdb, parent, child, bloom); err != nil {
+		t.Fatalf("failed to commit state diff: %v", err)
+	}
+	for _, root := range []common.Hash{parent, child} {
+		for hash := range collectState(t, diskdb, root) {
+			if ok, _ := bloom.Contain(hash.Bytes()); !ok {
+				t.Errorf("state %x: entry %x missing from bloom", root, hash)
+			}
+		}
+	}
+	// The difference alone must not contain the unchanged part of the parent
+	diff, err := newStateBloomWithSize(1)
+	if err != nil {
+		t.Fatalf("failed to create bloom: %v", err)
+	}
+	if err := commitStateDiff(diskdb, parent, child, diff); err != nil {
+		t.Fatalf("failed to commit state diff: %v", err)
+	}
+	statedb, err := state.New(parent, db, nil)
+	if err != nil {
+		t.Fatalf("failed to open parent state: %v", err)
+	}
+	storageRoot := statedb.StorageTrie(common.Address{1}).Hash()
+	if ok, _ := diff.Contain(storageRoot.Bytes()); ok {
+		t.Errorf("unchanged storage trie committed by the state diff")
+	}
+}
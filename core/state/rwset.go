@@ -0,0 +1,255 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StateField is the part of an account a state access refers to.
+type StateField uint8
+
+const (
+	AccountField StateField = iota // Existence and emptiness of the account
+	BalanceField
+	NonceField
+	CodeField
+	StorageField
+)
+
+// StateKey identifies a single item of the state.
+type StateKey struct {
+	Address common.Address
+	Field   StateField
+	Slot    common.Hash // Storage slot, only set for StorageField
+}
+
+// RWSet is the set of state items read and written by a single transaction,
+// along with the values it wrote. It allows executing a transaction on top of
+// a stale state and replaying its effects on the real one, as long as none of
+// the items it read were modified in between.
+//
+// Balances only ever increased without being read (e.g. fee recipients) are
+// not part of the read set, their changes are replayed as deltas instead.
+type RWSet struct {
+	Reads  map[StateKey]struct{}
+	Writes map[StateKey]struct{}
+
+	start        int                         // Journal index the tracking started at
+	created      map[common.Address]struct{} // Accounts (re)created by the transaction
+	suicided     map[common.Address]struct{} // Accounts self-destructed by the transaction
+	touched      map[common.Address]struct{} // Empty accounts touched by the transaction
+	origBalances map[common.Address]*big.Int // Balances before the first change
+	balances     map[common.Address]*big.Int // Balances after the transaction
+	nonces       map[common.Address]uint64   // Nonces after the transaction
+	codes        map[common.Address][]byte   // Codes after the transaction
+	storage      map[StateKey]common.Hash    // Storage slots after the transaction
+	logs         []*types.Log                // Logs emitted by the transaction
+	preimages    map[common.Hash][]byte      // Preimages recorded by the transaction
+}
+
+func newRWSet(start int) *RWSet {
+	return &RWSet{
+		Reads:        make(map[StateKey]struct{}),
+		Writes:       make(map[StateKey]struct{}),
+		start:        start,
+		created:      make(map[common.Address]struct{}),
+		suicided:     make(map[common.Address]struct{}),
+		touched:      make(map[common.Address]struct{}),
+		origBalances: make(map[common.Address]*big.Int),
+		balances:     make(map[common.Address]*big.Int),
+		nonces:       make(map[common.Address]uint64),
+		codes:        make(map[common.Address][]byte),
+		storage:      make(map[StateKey]common.Hash),
+		preimages:    make(map[common.Hash][]byte),
+	}
+}
+
+// Conflicts reports whether the transaction read any of the given items.
+func (set *RWSet) Conflicts(writes map[StateKey]struct{}) bool {
+	if len(writes) == 0 {
+		return false
+	}
+	for key := range set.Reads {
+		if _, ok := writes[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// StartRWSet starts tracking the state items accessed through the statedb.
+func (s *StateDB) StartRWSet() {
+	s.rwSet = newRWSet(s.journal.length())
+}
+
+// StopRWSet stops tracking the accessed state items and returns the set of
+// items accessed since StartRWSet, along with the values written.
+func (s *StateDB) StopRWSet() *RWSet {
+	set := s.rwSet
+	if set == nil {
+		return nil
+	}
+	s.collectWrites()
+	s.rwSet = nil
+
+	for key := range set.Writes {
+		obj := s.stateObjects[key.Address]
+		if obj == nil {
+			continue
+		}
+		switch key.Field {
+		case BalanceField:
+			set.balances[key.Address] = new(big.Int).Set(obj.Balance())
+		case NonceField:
+			set.nonces[key.Address] = obj.Nonce()
+		case CodeField:
+			set.codes[key.Address] = obj.Code(s.db)
+		case StorageField:
+			set.storage[key] = obj.GetState(s.db, key.Slot)
+		}
+	}
+	set.logs = append([]*types.Log(nil), s.logs[s.thash]...)
+	return set
+}
+
+// collectWrites adds the items modified by the journal entries since the last
+// collection to the write set.
+func (s *StateDB) collectWrites() {
+	set := s.rwSet
+	dirties := make(map[common.Address]struct{})
+	for _, entry := range s.journal.entries[set.start:] {
+		switch ch := entry.(type) {
+		case createObjectChange:
+			set.created[*ch.account] = struct{}{}
+			set.Writes[StateKey{Address: *ch.account, Field: AccountField}] = struct{}{}
+		case resetObjectChange:
+			set.created[ch.prev.address] = struct{}{}
+			set.Writes[StateKey{Address: ch.prev.address, Field: AccountField}] = struct{}{}
+		case suicideChange:
+			set.suicided[*ch.account] = struct{}{}
+			set.Writes[StateKey{Address: *ch.account, Field: AccountField}] = struct{}{}
+			set.Writes[StateKey{Address: *ch.account, Field: BalanceField}] = struct{}{}
+		case touchChange:
+			set.touched[*ch.account] = struct{}{}
+			set.Writes[StateKey{Address: *ch.account, Field: AccountField}] = struct{}{}
+		case balanceChange:
+			set.Writes[StateKey{Address: *ch.account, Field: BalanceField}] = struct{}{}
+		case nonceChange:
+			set.Writes[StateKey{Address: *ch.account, Field: NonceField}] = struct{}{}
+		case codeChange:
+			set.Writes[StateKey{Address: *ch.account, Field: CodeField}] = struct{}{}
+		case storageChange:
+			set.Writes[StateKey{Address: *ch.account, Field: StorageField, Slot: ch.key}] = struct{}{}
+		case addPreimageChange:
+			set.preimages[ch.hash] = s.preimages[ch.hash]
+		}
+		if addr := entry.dirtied(); addr != nil {
+			dirties[*addr] = struct{}{}
+		}
+	}
+	// Accounts left empty are deleted on finalisation, which changes their
+	// existence even if only the balance was written.
+	for addr := range dirties {
+		if obj := s.stateObjects[addr]; obj != nil && (obj.suicided || obj.empty()) {
+			set.Writes[StateKey{Address: addr, Field: AccountField}] = struct{}{}
+		}
+	}
+	set.start = s.journal.length()
+}
+
+// trackRead records the read of an account field, along with the existence of
+// the account every access depends on.
+func (s *StateDB) trackRead(addr common.Address, field StateField) {
+	if s.rwSet == nil {
+		return
+	}
+	s.rwSet.Reads[StateKey{Address: addr, Field: AccountField}] = struct{}{}
+	s.rwSet.Reads[StateKey{Address: addr, Field: field}] = struct{}{}
+}
+
+// trackStorageRead records the read of a storage slot.
+func (s *StateDB) trackStorageRead(addr common.Address, slot common.Hash) {
+	if s.rwSet == nil {
+		return
+	}
+	s.rwSet.Reads[StateKey{Address: addr, Field: AccountField}] = struct{}{}
+	s.rwSet.Reads[StateKey{Address: addr, Field: StorageField, Slot: slot}] = struct{}{}
+}
+
+// trackBalance remembers the balance of an account before it is first changed,
+// the change is replayed as a delta on top of the balance of the target state.
+func (s *StateDB) trackBalance(addr common.Address) {
+	if s.rwSet == nil {
+		return
+	}
+	if _, ok := s.rwSet.origBalances[addr]; ok {
+		return
+	}
+	balance := new(big.Int)
+	if obj := s.getStateObject(addr); obj != nil {
+		balance.Set(obj.Balance())
+	}
+	s.rwSet.origBalances[addr] = balance
+}
+
+// ApplyRWSet replays the writes of a transaction executed on another state on
+// top of this one. The caller is responsible for making sure none of the items
+// read by the transaction differ between the two states, and for preparing the
+// statedb for the transaction to attach the logs to it.
+func (s *StateDB) ApplyRWSet(set *RWSet) {
+	for addr := range set.created {
+		s.CreateAccount(addr)
+	}
+	for addr, balance := range set.balances {
+		pre := set.origBalances[addr]
+		if pre == nil {
+			pre = new(big.Int)
+		}
+		switch delta := new(big.Int).Sub(balance, pre); delta.Sign() {
+		case 1:
+			s.AddBalance(addr, delta)
+		case -1:
+			s.SubBalance(addr, delta.Neg(delta))
+		}
+	}
+	for addr, nonce := range set.nonces {
+		s.SetNonce(addr, nonce)
+	}
+	for addr, code := range set.codes {
+		s.SetCode(addr, code)
+	}
+	for key, value := range set.storage {
+		s.SetState(key.Address, key.Slot, value)
+	}
+	for addr := range set.touched {
+		s.AddBalance(addr, common.Big0)
+	}
+	for addr := range set.suicided {
+		s.Suicide(addr)
+	}
+	for _, l := range set.logs {
+		cpy := *l
+		s.AddLog(&cpy)
+	}
+	for hash, preimage := range set.preimages {
+		s.AddPreimage(hash, preimage)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// verifyInterval is the time between two batches of the snapshot self-check.
+const verifyInterval = time.Second
+
+// Divergence is an inconsistency found between the snapshot disk layer and the
+// state trie it was built from.
+type Divergence struct {
+	Root    common.Hash `json:"root"`    // Root of the disk layer the divergence was found in
+	Account common.Hash `json:"account"` // Hash of the diverging account
+	Reason  string      `json:"reason"`
+	Time    uint64      `json:"time"` // Unix time the divergence was found
+}
+
+// Health is the status of the snapshot integrity self-check.
+type Health struct {
+	Running     bool        `json:"running"`     // Whether the self-check is enabled
+	Rate        int         `json:"rate"`        // Accounts verified per second
+	Root        common.Hash `json:"root"`        // Root of the disk layer being verified
+	Cursor      common.Hash `json:"cursor"`      // Hash of the next account to verify
+	Passes      uint64      `json:"passes"`      // Complete passes over the accounts
+	Accounts    uint64      `json:"accounts"`    // Accounts verified in total
+	Divergences uint64      `json:"divergences"` // Divergences found in total
+	Repairs     uint64      `json:"repairs"`     // Snapshot regenerations triggered
+
+	LastDivergence *Divergence `json:"lastDivergence,omitempty"`
+}
+
+// Verifier incrementally cross-checks the accounts and storage roots of the
+// snapshot disk layer against the state trie in the background, at a limited
+// rate. Divergences are flagged and, if a repair callback is configured, fixed
+// by regenerating the snapshot.
+type Verifier struct {
+	tree   *Tree
+	rate   int    // Accounts to verify per second
+	repair func() // Callback regenerating the snapshot, nil to only flag divergences
+
+	health Health
+	lock   sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewVerifier creates a snapshot self-check verifying rate accounts per second.
+func NewVerifier(tree *Tree, rate int, repair func()) *Verifier {
+	return &Verifier{
+		tree:   tree,
+		rate:   rate,
+		repair: repair,
+		health: Health{Rate: rate},
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start spins up the background self-check.
+func (v *Verifier) Start() {
+	v.lock.Lock()
+	v.health.Running = true
+	v.lock.Unlock()
+
+	v.wg.Add(1)
+	go v.loop()
+}
+
+// Stop terminates the background self-check.
+func (v *Verifier) Stop() {
+	close(v.quit)
+	v.wg.Wait()
+
+	v.lock.Lock()
+	v.health.Running = false
+	v.lock.Unlock()
+}
+
+// Health returns the current status of the self-check.
+func (v *Verifier) Health() Health {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	health := v.health
+	if health.LastDivergence != nil {
+		last := *health.LastDivergence
+		health.LastDivergence = &last
+	}
+	return health
+}
+
+func (v *Verifier) loop() {
+	defer v.wg.Done()
+
+	ticker := time.NewTicker(verifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := v.verify(v.rate); err != nil {
+				log.Debug("Snapshot self-check skipped", "err", err)
+			}
+		case <-v.quit:
+			return
+		}
+	}
+}
+
+// verify checks the next limit accounts of the disk layer, starting at the
+// cursor, and records the divergences found. The batch is discarded if the
+// disk layer was updated meanwhile, as it's then an inconsistent view.
+func (v *Verifier) verify(limit int) error {
+	v.tree.lock.RLock()
+	dl := v.tree.disklayer()
+	v.tree.lock.RUnlock()
+
+	if dl == nil {
+		return ErrSnapshotStale
+	}
+	dl.lock.RLock()
+	stale, generating := dl.stale, dl.genMarker != nil
+	dl.lock.RUnlock()
+
+	if stale {
+		return ErrSnapshotStale
+	}
+	if generating {
+		return ErrNotConstructed
+	}
+	v.lock.RLock()
+	cursor := v.health.Cursor
+	v.lock.RUnlock()
+
+	next, checked, divergences, err := verifyAccounts(dl, cursor, limit)
+	if err != nil {
+		return err
+	}
+	if dl.Stale() {
+		return ErrSnapshotStale
+	}
+	v.lock.Lock()
+	v.health.Root = dl.root
+	v.health.Accounts += uint64(checked)
+	v.health.Divergences += uint64(len(divergences))
+	if next == nil {
+		v.health.Cursor = common.Hash{}
+		v.health.Passes++
+	} else {
+		v.health.Cursor = *next
+	}
+	if len(divergences) > 0 {
+		v.health.LastDivergence = &divergences[len(divergences)-1]
+	}
+	v.lock.Unlock()
+
+	for _, div := range divergences {
+		log.Error("Snapshot diverges from the state trie", "root", div.Root, "account", div.Account, "reason", div.Reason)
+	}
+	if len(divergences) > 0 && v.repair != nil {
+		log.Warn("Regenerating diverged state snapshot", "root", dl.root)
+		v.repair()
+
+		v.lock.Lock()
+		v.health.Repairs++
+		v.health.Cursor = common.Hash{}
+		v.lock.Unlock()
+	}
+	return nil
+}
+
+// verifyAccounts walks the accounts of the disk layer snapshot and of its state
+// trie side by side from the cursor, comparing up to limit accounts and the
+// storage roots of the contracts. It returns the account to resume from, nil
+// if the walk completed.
+func verifyAccounts(dl *diskLayer, cursor common.Hash, limit int) (*common.Hash, int, []Divergence, error) {
+	tr, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	var (
+		trieIt = trie.NewIterator(tr.NodeIterator(cursor[:]))
+		snapIt = dl.AccountIterator(cursor)
+
+		trieOk = trieIt.Next()
+		snapOk = snapIt.Next()
+
+		checked     int
+		divergences []Divergence
+	)
+	defer snapIt.Release()
+
+	diverge := func(account common.Hash, format string, args ...interface{}) {
+		divergences = append(divergences, Divergence{
+			Root:    dl.root,
+			Account: account,
+			Reason:  fmt.Sprintf(format, args...),
+			Time:    uint64(time.Now().Unix()),
+		})
+	}
+	for ; checked < limit && (trieOk || snapOk); checked++ {
+		var cmp int
+		switch {
+		case !trieOk:
+			cmp = -1
+		case !snapOk:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(snapIt.Hash().Bytes(), trieIt.Key)
+		}
+		switch {
+		case cmp < 0:
+			diverge(snapIt.Hash(), "account missing from the trie")
+			snapOk = snapIt.Next()
+
+		case cmp > 0:
+			diverge(common.BytesToHash(trieIt.Key), "account missing from the snapshot")
+			trieOk = trieIt.Next()
+
+		default:
+			hash := snapIt.Hash()
+			full, err := FullAccountRLP(snapIt.Account())
+			if err != nil {
+				diverge(hash, "invalid snapshot account: %v", err)
+			} else if !bytes.Equal(full, trieIt.Value) {
+				diverge(hash, "account mismatch")
+			} else if err := verifyStorageRoot(dl, hash, full); err != nil {
+				diverge(hash, "%v", err)
+			}
+			snapOk, trieOk = snapIt.Next(), trieIt.Next()
+		}
+	}
+	if trieIt.Err != nil {
+		return nil, 0, nil, trieIt.Err
+	}
+	if err := snapIt.Error(); err != nil {
+		return nil, 0, nil, err
+	}
+	// Resume from the lowest account not yet compared
+	var next *common.Hash
+	switch {
+	case snapOk && trieOk && bytes.Compare(trieIt.Key, snapIt.Hash().Bytes()) < 0:
+		next = new(common.Hash)
+		*next = common.BytesToHash(trieIt.Key)
+	case snapOk:
+		next = new(common.Hash)
+		*next = snapIt.Hash()
+	case trieOk:
+		next = new(common.Hash)
+		*next = common.BytesToHash(trieIt.Key)
+	}
+	return next, checked, divergences, nil
+}
+
+// verifyStorageRoot checks that the storage snapshot of an account hashes to the
+// storage root of its full RLP encoded trie account.
+func verifyStorageRoot(dl *diskLayer, account common.Hash, full []byte) error {
+	var acc Account
+	if err := rlp.DecodeBytes(full, &acc); err != nil {
+		return err
+	}
+	root := common.BytesToHash(acc.Root)
+	if root == emptyRoot {
+		return nil
+	}
+	it, _ := dl.StorageIterator(account, common.Hash{})
+	defer it.Release()
+
+	got, err := generateTrieRoot(nil, it, account, stackTrieGenerate, nil, newGenerateStats(), false)
+	if err != nil {
+		return err
+	}
+	if got != root {
+		return fmt.Errorf("storage root mismatch: have %x, want %x", got, root)
+	}
+	return nil
+}
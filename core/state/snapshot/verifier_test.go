@@ -0,0 +1,103 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that the self-check walks the whole snapshot in batches and flags the
+// accounts and storages diverging from the trie.
+func TestVerifier(t *testing.T) {
+	helper := newHelper()
+	stRoot := helper.makeStorageTrie([]string{"key-1", "key-2", "key-3"}, []string{"val-1", "val-2", "val-3"})
+
+	helper.addAccount("acc-1", &Account{Balance: big.NewInt(1), Root: stRoot, CodeHash: emptyCode.Bytes()})
+	helper.addSnapStorage("acc-1", []string{"key-1", "key-2", "key-3"}, []string{"val-1", "val-2", "val-3"})
+	helper.addAccount("acc-2", &Account{Balance: big.NewInt(2), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+	helper.addAccount("acc-3", &Account{Balance: big.NewInt(3), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+
+	root, snap := helper.Generate()
+	select {
+	case <-snap.genPending:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Snapshot generation failed")
+	}
+	tree := &Tree{layers: map[common.Hash]snapshot{root: snap}}
+
+	var repairs int
+	verifier := NewVerifier(tree, 2, func() { repairs++ })
+
+	// A consistent snapshot passes in two batches
+	for i := 0; i < 2; i++ {
+		if err := verifier.verify(2); err != nil {
+			t.Fatalf("batch %d: verification failed: %v", i, err)
+		}
+	}
+	health := verifier.Health()
+	if health.Passes != 1 || health.Accounts != 3 || health.Divergences != 0 || health.Root != root {
+		t.Fatalf("health mismatch after clean pass: %+v", health)
+	}
+	// Corrupt an account and a storage slot, both get flagged and repaired
+	val, _ := rlp.EncodeToBytes(&Account{Balance: big.NewInt(20), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+	rawdb.WriteAccountSnapshot(helper.diskdb, hashData([]byte("acc-2")), val)
+	rawdb.WriteStorageSnapshot(helper.diskdb, hashData([]byte("acc-1")), hashData([]byte("key-2")), []byte("val-x"))
+
+	if err := verifier.verify(3); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	health = verifier.Health()
+	if health.Passes != 2 || health.Divergences != 2 || health.Repairs != 1 || repairs != 1 {
+		t.Fatalf("health mismatch after corruption: %+v", health)
+	}
+	if health.LastDivergence == nil || health.LastDivergence.Root != root {
+		t.Fatalf("last divergence mismatch: %+v", health.LastDivergence)
+	}
+	// Accounts missing from the snapshot are flagged too
+	rawdb.DeleteAccountSnapshot(helper.diskdb, hashData([]byte("acc-3")))
+	verifier.repair = nil
+	if err := verifier.verify(3); err != nil {
+		t.Fatalf("verification failed: %v", err)
+	}
+	if health = verifier.Health(); health.Divergences != 5 || health.Repairs != 1 {
+		t.Fatalf("health mismatch after deletion: %+v", health)
+	}
+}
+
+// Tests that the self-check skips snapshots still being generated.
+func TestVerifierSkipsGenerating(t *testing.T) {
+	helper := newHelper()
+	helper.addTrieAccount("acc-1", &Account{Balance: big.NewInt(1), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+
+	root, snap := helper.Generate()
+	<-snap.genPending
+
+	snap.lock.Lock()
+	snap.genMarker = []byte{0x01}
+	snap.lock.Unlock()
+
+	verifier := NewVerifier(&Tree{layers: map[common.Hash]snapshot{root: snap}}, 10, nil)
+	if err := verifier.verify(10); err != ErrNotConstructed {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrNotConstructed)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errVerifyAborted is returned if a state verification is interrupted.
+var errVerifyAborted = errors.New("state verification aborted")
+
+// MissingCode is a contract code referenced by an account but absent from the
+// database.
+type MissingCode struct {
+	Account common.Hash `json:"account"` // Hash of the account address
+	Hash    common.Hash `json:"hash"`    // Hash of the missing code
+}
+
+// VerifyResult is the outcome of a full state verification.
+type VerifyResult struct {
+	Root         common.Hash           `json:"root"`
+	Nodes        uint64                `json:"nodes"`
+	Accounts     uint64                `json:"accounts"`
+	Slots        uint64                `json:"slots"`
+	Codes        uint64                `json:"codes"`
+	Issues       []trie.NodeIssue      `json:"issues"`
+	MissingCodes []MissingCode         `json:"missingCodes"`
+	Elapsed      common.PrettyDuration `json:"elapsed"`
+}
+
+// Healthy returns whether the verification found no damage.
+func (res *VerifyResult) Healthy() bool {
+	return len(res.Issues) == 0 && len(res.MissingCodes) == 0
+}
+
+// storageTask is a storage trie to be verified by a worker.
+type storageTask struct {
+	owner common.Hash
+	root  common.Hash
+}
+
+// VerifyState walks every account and storage trie node of the state with the
+// given root, along with the referenced contract codes, and collects all the
+// missing or corrupt entries. The storage tries are verified by the given
+// number of parallel threads while the account trie is being walked. The walk
+// can be interrupted by closing stop.
+func VerifyState(db ethdb.KeyValueReader, root common.Hash, threads int, stop <-chan struct{}) (*VerifyResult, error) {
+	if threads < 1 {
+		threads = 1
+	}
+	var (
+		res   = &VerifyResult{Root: root}
+		lock  sync.Mutex
+		tasks = make(chan storageTask, 4*threads)
+		wg    sync.WaitGroup
+		start = time.Now()
+
+		slots      uint64
+		nodes      uint64
+		lastReport time.Time
+	)
+	onIssue := func(issue trie.NodeIssue) {
+		lock.Lock()
+		res.Issues = append(res.Issues, issue)
+		lock.Unlock()
+	}
+	aborted := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				n, err := trie.VerifyTrie(db, task.owner, task.root, func(key, value []byte) error {
+					atomic.AddUint64(&slots, 1)
+					return nil
+				}, onIssue)
+				atomic.AddUint64(&nodes, uint64(n))
+				if err != nil {
+					log.Error("Failed to verify storage trie", "owner", task.owner, "root", task.root, "err", err)
+				}
+			}
+		}()
+	}
+	n, err := trie.VerifyTrie(db, common.Hash{}, root, func(key, value []byte) error {
+		if aborted() {
+			return errVerifyAborted
+		}
+		var acc Account
+		if err := rlp.DecodeBytes(value, &acc); err != nil {
+			return err
+		}
+		res.Accounts++
+		owner := common.BytesToHash(key)
+		if acc.Root != emptyRoot {
+			tasks <- storageTask{owner: owner, root: acc.Root}
+		}
+		if !bytes.Equal(acc.CodeHash, emptyCodeHash) {
+			hash := common.BytesToHash(acc.CodeHash)
+			if len(rawdb.ReadCode(db, hash)) == 0 {
+				lock.Lock()
+				res.MissingCodes = append(res.MissingCodes, MissingCode{Account: owner, Hash: hash})
+				lock.Unlock()
+			} else {
+				res.Codes++
+			}
+		}
+		if time.Since(lastReport) > time.Second*8 {
+			log.Info("Verifying state", "accounts", res.Accounts, "slots", atomic.LoadUint64(&slots), "codes", res.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			lastReport = time.Now()
+		}
+		return nil
+	}, onIssue)
+	close(tasks)
+	wg.Wait()
+
+	res.Nodes = atomic.LoadUint64(&nodes) + uint64(n)
+	res.Slots = atomic.LoadUint64(&slots)
+	res.Elapsed = common.PrettyDuration(time.Since(start))
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// ScheduleHeal seeds a state sync scheduler with the damaged entries found by
+// a verification. Damaged account trie nodes are scheduled along with all the
+// missing storage tries and codes below them, while corrupt nodes must have
+// been deleted beforehand for the scheduler not to skip them.
+func ScheduleHeal(sched *trie.Sync, res *VerifyResult) {
+	onAccount := func(paths [][]byte, hexpath []byte, leaf []byte, parent common.Hash) error {
+		var obj Account
+		if err := rlp.Decode(bytes.NewReader(leaf), &obj); err != nil {
+			return err
+		}
+		sched.AddSubTrie(obj.Root, hexpath, parent, nil)
+		sched.AddCodeEntry(common.BytesToHash(obj.CodeHash), hexpath, parent)
+		return nil
+	}
+	for _, issue := range res.Issues {
+		if issue.Owner == (common.Hash{}) {
+			sched.AddSubTrie(issue.Hash, issue.SyncPath(), common.Hash{}, onAccount)
+		} else {
+			sched.AddSubTrie(issue.Hash, issue.SyncPath(), common.Hash{}, nil)
+		}
+	}
+	for _, code := range res.MissingCodes {
+		sched.AddCodeEntry(code.Hash, nil, common.Hash{})
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that a state verification reports damaged trie nodes and codes, and
+// that scheduling them for healing restores the full state.
+func TestVerifyAndHealState(t *testing.T) {
+	srcDb, root, accounts := makeTestState()
+	srcDb.TrieDB().Commit(root, false, nil)
+
+	// Copy the state into a fresh database and make sure it's intact
+	db := rawdb.NewMemoryDatabase()
+	it := srcDb.TrieDB().DiskDB().NewIterator(nil, nil)
+	for it.Next() {
+		db.Put(it.Key(), it.Value())
+	}
+	it.Release()
+
+	res, err := VerifyState(db, root, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to verify state: %v", err)
+	}
+	if !res.Healthy() {
+		t.Fatalf("intact state reported damaged: %v, %v", res.Issues, res.MissingCodes)
+	}
+	if res.Accounts != uint64(len(accounts)) {
+		t.Fatalf("account count mismatch: have %d, want %d", res.Accounts, len(accounts))
+	}
+	want := *res
+
+	// Delete an account trie node, corrupt a storage root and drop a code
+	srcTrie, _ := trie.New(root, srcDb.TrieDB())
+	nodes := srcTrie.NodeIterator(nil)
+	for nodes.Next(true) {
+		if hash := nodes.Hash(); hash != (common.Hash{}) && hash != root {
+			rawdb.DeleteTrieNode(db, hash)
+			break
+		}
+	}
+	var corrupted, dropped bool
+	for accIt := trie.NewIterator(srcTrie.NodeIterator(nil)); accIt.Next() && !(corrupted && dropped); {
+		var acc Account
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			t.Fatalf("failed to decode account: %v", err)
+		}
+		if !corrupted && acc.Root != emptyRoot {
+			rawdb.WriteTrieNode(db, acc.Root, []byte{0xde, 0xad})
+			corrupted = true
+		}
+		if !dropped && common.BytesToHash(acc.CodeHash) != crypto.Keccak256Hash(nil) {
+			rawdb.DeleteCode(db, common.BytesToHash(acc.CodeHash))
+			dropped = true
+		}
+	}
+	// Verify and heal until the state is complete, deeper damage only being
+	// discovered once the nodes above it are restored
+	for pass := 0; ; pass++ {
+		res, err := VerifyState(db, root, 4, nil)
+		if err != nil {
+			t.Fatalf("pass %d: failed to verify state: %v", pass, err)
+		}
+		if res.Healthy() {
+			if res.Accounts != want.Accounts || res.Slots != want.Slots || res.Codes != want.Codes || res.Nodes != want.Nodes {
+				t.Fatalf("healed state mismatch: have %+v, want %+v", res, want)
+			}
+			break
+		}
+		if pass == 2 {
+			t.Fatalf("state not healed after %d passes: %v, %v", pass+1, res.Issues, res.MissingCodes)
+		}
+		for _, issue := range res.Issues {
+			if issue.Corrupt {
+				rawdb.DeleteTrieNode(db, issue.Hash)
+			}
+		}
+		sched := trie.NewSync(root, db, nil, nil)
+		ScheduleHeal(sched, res)
+
+		for sched.Pending() > 0 {
+			nodes, _, codes := sched.Missing(0)
+			for _, hash := range append(nodes, codes...) {
+				data, err := srcDb.TrieDB().Node(hash)
+				if err != nil {
+					data, err = srcDb.ContractCode(common.Hash{}, hash)
+				}
+				if err != nil {
+					t.Fatalf("failed to retrieve node data for hash %x", hash)
+				}
+				if err := sched.Process(trie.SyncResult{Hash: hash, Data: data}); err != nil {
+					t.Fatalf("failed to process result: %v", err)
+				}
+			}
+			batch := db.NewBatch()
+			if err := sched.Commit(batch); err != nil {
+				t.Fatalf("failed to commit data: %v", err)
+			}
+			batch.Write()
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// StateChange is the kind of change made to an account or a storage slot.
+type StateChange string
+
+const (
+	StateCreated StateChange = "created"
+	StateUpdated StateChange = "updated"
+	StateDeleted StateChange = "deleted"
+)
+
+// StateDiff is the set of accounts and storage slots changed by a block.
+type StateDiff struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Accounts    []*AccountDiff // Changed accounts, ordered by address
+}
+
+// AccountDiff is the change made to an account by a block. The account fields
+// are the values after the block, they are unset for deleted accounts. The
+// storage of deleted and re-created accounts is wiped before applying Storage.
+type AccountDiff struct {
+	Address  common.Address
+	Change   StateChange
+	Nonce    uint64
+	Balance  *big.Int
+	CodeHash common.Hash
+	Storage  []*StorageDiff // Changed storage slots, ordered by key
+}
+
+// StorageDiff is the change made to a storage slot by a block.
+type StorageDiff struct {
+	Key    common.Hash
+	Value  common.Hash
+	Change StateChange
+}
+
+// SubscribeStateDiffEvent registers a subscription of StateDiffEvent. State diffs
+// are only computed while there are subscribers.
+func (bc *BlockChain) SubscribeStateDiffEvent(ch chan<- StateDiffEvent) event.Subscription {
+	atomic.AddInt32(&bc.stateDiffSubs, 1)
+	sub := bc.stateDiffFeed.Subscribe(ch)
+
+	return bc.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		defer atomic.AddInt32(&bc.stateDiffSubs, -1)
+		defer sub.Unsubscribe()
+
+		select {
+		case err := <-sub.Err():
+			return err
+		case <-quit:
+			return nil
+		}
+	}))
+}
+
+// sendStateDiff announces the state changes of a block which became canonical,
+// if anyone is listening.
+func (bc *BlockChain) sendStateDiff(block *types.Block, diff *types.DiffLayer) {
+	if diff == nil || atomic.LoadInt32(&bc.stateDiffSubs) == 0 {
+		return
+	}
+	bc.stateDiffFeed.Send(StateDiffEvent{Diff: bc.stateDiff(block, diff)})
+}
+
+// stateDiff converts the diff layer of a block into a state diff. Accounts and
+// slots are classified as created or updated by looking them up in the snapshot
+// of the parent block. If that snapshot is not available, changes which aren't
+// deletions are reported as updates.
+func (bc *BlockChain) stateDiff(block *types.Block, diff *types.DiffLayer) *StateDiff {
+	var parent snapshot.Snapshot
+	if bc.snaps != nil {
+		if header := bc.GetHeader(block.ParentHash(), block.NumberU64()-1); header != nil {
+			parent = bc.snaps.Snapshot(header.Root)
+		}
+	}
+	existed := func(addr common.Address) bool {
+		if parent == nil {
+			return true
+		}
+		account, err := parent.Account(crypto.Keccak256Hash(addr.Bytes()))
+		return err != nil || account != nil
+	}
+	slotExisted := func(addr common.Address, key common.Hash) bool {
+		if parent == nil {
+			return true
+		}
+		value, err := parent.Storage(crypto.Keccak256Hash(addr.Bytes()), crypto.Keccak256Hash(key.Bytes()))
+		return err != nil || len(value) > 0
+	}
+	var (
+		accounts  = make(map[common.Address]*AccountDiff)
+		destructs = make(map[common.Address]bool)
+	)
+	for _, addr := range diff.Destructs {
+		destructs[addr] = true
+		accounts[addr] = &AccountDiff{Address: addr, Change: StateDeleted}
+	}
+	for _, entry := range diff.Accounts {
+		account := &AccountDiff{Address: entry.Account, Change: StateUpdated}
+		if destructs[entry.Account] || !existed(entry.Account) {
+			account.Change = StateCreated
+		}
+		var data snapshot.Account
+		if err := rlp.DecodeBytes(entry.Blob, &data); err == nil {
+			account.Nonce, account.Balance = data.Nonce, data.Balance
+			if len(data.CodeHash) == 0 {
+				account.CodeHash = common.BytesToHash(types.EmptyCodeHash)
+			} else {
+				account.CodeHash = common.BytesToHash(data.CodeHash)
+			}
+		}
+		accounts[entry.Account] = account
+	}
+	for _, entry := range diff.Storages {
+		account := accounts[entry.Account]
+		if account == nil {
+			account = &AccountDiff{Address: entry.Account, Change: StateUpdated}
+			accounts[entry.Account] = account
+		}
+		for i, key := range entry.Keys {
+			slot := &StorageDiff{Key: common.BytesToHash([]byte(key))}
+			switch {
+			case len(entry.Vals[i]) == 0:
+				slot.Change = StateDeleted
+			case account.Change == StateCreated || !slotExisted(entry.Account, slot.Key):
+				slot.Change = StateCreated
+			default:
+				slot.Change = StateUpdated
+			}
+			if len(entry.Vals[i]) > 0 {
+				_, content, _, err := rlp.Split(entry.Vals[i])
+				if err == nil {
+					slot.Value = common.BytesToHash(content)
+				}
+			}
+			account.Storage = append(account.Storage, slot)
+		}
+		sort.Slice(account.Storage, func(i, j int) bool {
+			return bytes.Compare(account.Storage[i].Key[:], account.Storage[j].Key[:]) < 0
+		})
+	}
+	result := &StateDiff{
+		BlockHash:   block.Hash(),
+		BlockNumber: block.NumberU64(),
+		Accounts:    make([]*AccountDiff, 0, len(accounts)),
+	}
+	for _, account := range accounts {
+		result.Accounts = append(result.Accounts, account)
+	}
+	sort.Slice(result.Accounts, func(i, j int) bool {
+		return bytes.Compare(result.Accounts[i].Address[:], result.Accounts[j].Address[:]) < 0
+	})
+	return result
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestStateDiffEvents(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		to      = common.Address{0xaa}
+		signer  = types.HomesteadSigner{}
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}}}
+		genesis = gspec.MustCommit(db)
+
+		// PUSH1 1 PUSH1 0 SSTORE: stores 1 in slot 0 and deploys empty code
+		initcode = common.FromHex("6001600055")
+		contract = crypto.CreateAddress(sender, 1)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	chain, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		var tx *types.Transaction
+		if i == 0 {
+			tx, _ = types.SignTx(types.NewTransaction(gen.TxNonce(sender), to, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		} else {
+			tx, _ = types.SignTx(types.NewContractCreation(gen.TxNonce(sender), new(big.Int), 100000, nil, initcode), signer, key)
+		}
+		gen.AddTx(tx)
+	})
+	diffs := make(chan StateDiffEvent, 2)
+	sub := blockchain.SubscribeStateDiffEvent(diffs)
+	defer sub.Unsubscribe()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i, block := range chain {
+		var diff *StateDiff
+		select {
+		case ev := <-diffs:
+			diff = ev.Diff
+		case <-time.After(time.Second):
+			t.Fatalf("block %d: no state diff event", i+1)
+		}
+		if diff.BlockHash != block.Hash() || diff.BlockNumber != block.NumberU64() {
+			t.Fatalf("block %d: state diff for wrong block #%d %x", i+1, diff.BlockNumber, diff.BlockHash)
+		}
+		accounts := make(map[common.Address]*AccountDiff)
+		for _, account := range diff.Accounts {
+			accounts[account.Address] = account
+		}
+		if account := accounts[sender]; account == nil || account.Change != StateUpdated || account.Nonce != uint64(i+1) {
+			t.Errorf("block %d: sender change mismatch: %+v", i+1, account)
+		}
+		if i == 0 {
+			account := accounts[to]
+			if account == nil || account.Change != StateCreated || account.Balance.Int64() != 1000 {
+				t.Errorf("block 1: recipient change mismatch: %+v", account)
+			}
+			continue
+		}
+		account := accounts[contract]
+		if account == nil || account.Change != StateCreated || account.CodeHash != common.BytesToHash(types.EmptyCodeHash) {
+			t.Fatalf("block 2: contract change mismatch: %+v", account)
+		}
+		if len(account.Storage) != 1 {
+			t.Fatalf("block 2: contract storage changes mismatch: have %d, want 1", len(account.Storage))
+		}
+		slot := account.Storage[0]
+		if slot.Key != (common.Hash{}) || slot.Value != common.BigToHash(big.NewInt(1)) || slot.Change != StateCreated {
+			t.Errorf("block 2: storage change mismatch: %+v", slot)
+		}
+	}
+}
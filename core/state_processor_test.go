@@ -17,12 +17,14 @@
 package core
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -150,3 +152,78 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
 }
+
+// TestStateProcessorBaseFee tests that past the base fee fork the miner is only
+// paid the gas price above the base fee, the base fee going to the configured
+// recipient, and that transactions not paying the base fee are rejected.
+func TestStateProcessorBaseFee(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		recipient = common.HexToAddress("0x1002")
+		config    = *params.TestChainConfig
+		db        = rawdb.NewMemoryDatabase()
+	)
+	config.BaseFeeBlock = big.NewInt(1)
+	config.BaseFee = &params.BaseFeeConfig{Recipient: &recipient}
+
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}}}
+	genesis := gspec.MustCommit(db)
+	if genesis.BaseFee() != nil {
+		t.Fatalf("genesis has a base fee before the fork: %v", genesis.BaseFee())
+	}
+	price := big.NewInt(2 * params.GWei)
+	blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), db, 3, func(i int, b *BlockGen) {
+		// The first block is empty, to measure the block reward
+		b.SetCoinbase(common.Address{byte(i + 1)})
+		if i > 0 {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{}, common.Big0, params.TxGas, price, nil), types.HomesteadSigner{}, key)
+			b.AddTx(tx)
+		}
+	})
+	blockchain, _ := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	statedb, _ := blockchain.State()
+	var (
+		reward = statedb.GetBalance(common.Address{1})
+		burnt  = new(big.Int)
+	)
+	for i, block := range blocks {
+		want := new(big.Int).SetUint64(params.InitialBaseFee)
+		if i > 0 {
+			want = misc.CalcBaseFee(&config, blocks[i-1].Header())
+		}
+		if block.BaseFee() == nil || block.BaseFee().Cmp(want) != 0 {
+			t.Fatalf("block #%d: base fee mismatch: have %v, want %v", block.NumberU64(), block.BaseFee(), want)
+		}
+		if i == 0 {
+			continue
+		}
+		gas := new(big.Int).SetUint64(params.TxGas)
+		tip := new(big.Int).Mul(new(big.Int).Sub(price, block.BaseFee()), gas)
+		if have := new(big.Int).Sub(statedb.GetBalance(block.Coinbase()), reward); have.Cmp(tip) != 0 {
+			t.Errorf("block #%d: miner tip mismatch: have %v, want %v", block.NumberU64(), have, tip)
+		}
+		burnt.Add(burnt, new(big.Int).Mul(block.BaseFee(), gas))
+	}
+	if have := statedb.GetBalance(recipient); have.Cmp(burnt) != 0 {
+		t.Errorf("base fee recipient balance mismatch: have %v, want %v", have, burnt)
+	}
+	// A transaction paying less than the base fee is rejected
+	head := blockchain.CurrentBlock().Header()
+	msg := types.NewMessage(addr, &common.Address{}, 2, common.Big0, params.TxGas, big.NewInt(1), nil, nil, true)
+	evm := vm.NewEVM(NewEVMBlockContext(head, blockchain, nil), NewEVMTxContext(msg), statedb, &config, vm.Config{})
+	if _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(head.GasLimit)); !errors.Is(err, ErrFeeCapTooLow) {
+		t.Errorf("underpriced transaction error mismatch: have %v, want %v", err, ErrFeeCapTooLow)
+	}
+	// Unless the base fee check is disabled for zero priced calls
+	msg = types.NewMessage(addr, &common.Address{}, 2, common.Big0, params.TxGas, common.Big0, nil, nil, true)
+	evm = vm.NewEVM(NewEVMBlockContext(head, blockchain, nil), NewEVMTxContext(msg), statedb, &config, vm.Config{NoBaseFee: true})
+	if _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(head.GasLimit)); err != nil {
+		t.Errorf("zero priced call with the base fee check disabled failed: %v", err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -70,14 +71,20 @@ type Message interface {
 	CheckNonce() bool
 	Data() []byte
 	AccessList() types.AccessList
+
+	BlobHashes() []common.Hash
+	BlobGasFeeCap() *big.Int
+
+	SetCodeAuthorizations() []types.SetCodeAuthorization
 }
 
 // ExecutionResult includes all output after executing given evm
 // message no matter the execution itself is successful or not.
 type ExecutionResult struct {
-	UsedGas    uint64 // Total used gas but include the refunded gas
-	Err        error  // Any error encountered during the execution(listed in core/vm/errors.go)
-	ReturnData []byte // Returned data from evm(function result or data supplied with revert opcode)
+	UsedGas     uint64 // Total used gas but include the refunded gas
+	RefundedGas uint64 // Total gas refunded after execution
+	Err         error  // Any error encountered during the execution(listed in core/vm/errors.go)
+	ReturnData  []byte // Returned data from evm(function result or data supplied with revert opcode)
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -108,7 +115,7 @@ func (result *ExecutionResult) Revert() []byte {
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool, isHomestead, isEIP2028 bool) (uint64, error) {
+func IntrinsicGas(data []byte, accessList types.AccessList, authList []types.SetCodeAuthorization, isContractCreation bool, isHomestead, isEIP2028 bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if isContractCreation && isHomestead {
@@ -145,6 +152,9 @@ func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation b
 		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
 		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
 	}
+	if authList != nil {
+		gas += uint64(len(authList)) * params.TxAuthEmptyAccountGas
+	}
 	return gas, nil
 }
 
@@ -180,9 +190,22 @@ func (st *StateTransition) to() common.Address {
 	return *st.msg.To()
 }
 
+// blobGas returns the blob gas consumed by the message.
+func (st *StateTransition) blobGas() uint64 {
+	return uint64(len(st.msg.BlobHashes())) * params.BlobTxBlobGasPerBlob
+}
+
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if have, want := st.state.GetBalance(st.msg.From()), mgval; have.Cmp(want) < 0 {
+	balanceCheck := mgval
+	if blobGas := st.blobGas(); blobGas > 0 {
+		// The balance has to cover the blob fee cap, the blob gas is bought at
+		// the blob gas price and burnt
+		blobGasVal := new(big.Int).SetUint64(blobGas)
+		balanceCheck = new(big.Int).Add(mgval, new(big.Int).Mul(blobGasVal, st.msg.BlobGasFeeCap()))
+		mgval = new(big.Int).Add(mgval, blobGasVal.Mul(blobGasVal, BlobGasPrice()))
+	}
+	if have, want := st.state.GetBalance(st.msg.From()), balanceCheck; have.Cmp(want) < 0 {
 		return fmt.Errorf("%w: address %v have %v want %v", ErrInsufficientFunds, st.msg.From().Hex(), have, want)
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -207,6 +230,26 @@ func (st *StateTransition) preCheck() error {
 				st.msg.From().Hex(), msgNonce, stNonce)
 		}
 	}
+	// Make sure set-code transactions carry at least one authorization
+	if st.msg.SetCodeAuthorizations() != nil && len(st.msg.SetCodeAuthorizations()) == 0 {
+		return fmt.Errorf("%w: address %v", ErrEmptyAuthList, st.msg.From().Hex())
+	}
+	// Make sure the gas price covers the base fee, unless it's a call with a zero
+	// gas price and the base fee check is disabled
+	if baseFee := st.evm.Context.BaseFee; baseFee != nil {
+		skip := st.evm.Config().NoBaseFee && st.gasPrice.Sign() == 0
+		if !skip && st.gasPrice.Cmp(baseFee) < 0 {
+			return fmt.Errorf("%w: address %v, gasPrice: %v, baseFee: %v", ErrFeeCapTooLow,
+				st.msg.From().Hex(), st.gasPrice, baseFee)
+		}
+	}
+	// Make sure the blob fee cap covers the blob gas price
+	if st.blobGas() > 0 {
+		if have, want := st.msg.BlobGasFeeCap(), BlobGasPrice(); have.Cmp(want) < 0 {
+			return fmt.Errorf("%w: address %v, maxFeePerBlobGas: %v, blobGasPrice: %v", ErrBlobFeeCapTooLow,
+				st.msg.From().Hex(), have, want)
+		}
+	}
 	return st.buyGas()
 }
 
@@ -238,6 +281,12 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	if err := st.preCheck(); err != nil {
 		return nil, err
 	}
+	if config := st.evm.Config(); config.Debug {
+		config.Tracer.CaptureTxStart(st.initialGas)
+		defer func() {
+			config.Tracer.CaptureTxEnd(st.gas)
+		}()
+	}
 	msg := st.msg
 	sender := vm.AccountRef(msg.From())
 	homestead := st.evm.ChainConfig().IsHomestead(st.evm.Context.BlockNumber)
@@ -245,7 +294,7 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	contractCreation := msg.To() == nil
 
 	// Check clauses 4-5, subtract intrinsic gas if everything is correct
-	gas, err := IntrinsicGas(st.data, st.msg.AccessList(), contractCreation, homestead, istanbul)
+	gas, err := IntrinsicGas(st.data, st.msg.AccessList(), st.msg.SetCodeAuthorizations(), contractCreation, homestead, istanbul)
 	if err != nil {
 		return nil, err
 	}
@@ -272,25 +321,106 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	} else {
 		// Increment the nonce for the next transaction
 		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
+
+		// Apply EIP-7702 authorizations, invalid ones are skipped
+		for _, auth := range msg.SetCodeAuthorizations() {
+			if err := st.applyAuthorization(&auth); err != nil {
+				log.Trace("Skipped EIP-7702 authorization", "from", msg.From(), "err", err)
+			}
+		}
+		// Perform convenience warming of the delegation target of the recipient
+		if st.evm.ChainConfig().IsPrague(st.evm.Context.BlockNumber) {
+			if addr, ok := types.ParseDelegation(st.state.GetCode(st.to())); ok {
+				st.state.AddAddressToAccessList(addr)
+			}
+		}
 		ret, st.gas, vmerr = st.evm.Call(sender, st.to(), st.data, st.gas, st.value)
 	}
-	st.refundGas()
-
+	refunded := st.refundGas()
+
+	// Past the base fee fork, the miner only gets the tip above the base fee. The
+	// base fee is paid to the configured recipient, or burnt.
+	tip := st.gasPrice
+	if baseFee := st.evm.Context.BaseFee; baseFee != nil {
+		if baseFee.Cmp(tip) > 0 {
+			baseFee = tip // Zero priced calls with the base fee check disabled
+		}
+		tip = new(big.Int).Sub(tip, baseFee)
+		if recipient := st.evm.ChainConfig().BaseFeeRecipient(); recipient != nil {
+			st.state.AddBalance(*recipient, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), baseFee))
+		}
+	}
 	// consensus engine is parlia
 	if st.evm.ChainConfig().Parlia != nil {
-		st.state.AddBalance(consensus.SystemAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+		st.state.AddBalance(consensus.SystemAddress, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), tip))
 	} else {
-		st.state.AddBalance(st.evm.Context.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+		st.state.AddBalance(st.evm.Context.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), tip))
 	}
 
 	return &ExecutionResult{
-		UsedGas:    st.gasUsed(),
-		Err:        vmerr,
-		ReturnData: ret,
+		UsedGas:     st.gasUsed(),
+		RefundedGas: refunded,
+		Err:         vmerr,
+		ReturnData:  ret,
 	}, nil
 }
 
-func (st *StateTransition) refundGas() {
+// validateAuthorization checks an EIP-7702 authorization against the state and
+// returns the authority on success.
+func (st *StateTransition) validateAuthorization(auth *types.SetCodeAuthorization) (common.Address, error) {
+	// Verify the chain ID is either zero or the one of the current chain
+	if auth.ChainID.Sign() != 0 && auth.ChainID.Cmp(st.evm.ChainConfig().ChainID) != 0 {
+		return common.Address{}, ErrAuthorizationWrongChainID
+	}
+	// Limit the nonce to 2^64-1 per EIP-2681
+	if auth.Nonce+1 < auth.Nonce {
+		return common.Address{}, ErrAuthorizationNonceOverflow
+	}
+	authority, err := auth.Authority()
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrAuthorizationInvalidSignature, err)
+	}
+	// The authority is added to the access list, even if the authorization is
+	// rejected later on
+	st.state.AddAddressToAccessList(authority)
+
+	// The authority must not have any code other than a delegation
+	if code := st.state.GetCode(authority); len(code) != 0 {
+		if _, ok := types.ParseDelegation(code); !ok {
+			return common.Address{}, ErrAuthorizationDestinationHasCode
+		}
+	}
+	if have := st.state.GetNonce(authority); have != auth.Nonce {
+		return common.Address{}, ErrAuthorizationNonceMismatch
+	}
+	return authority, nil
+}
+
+// applyAuthorization applies an EIP-7702 code delegation to the state.
+func (st *StateTransition) applyAuthorization(auth *types.SetCodeAuthorization) error {
+	authority, err := st.validateAuthorization(auth)
+	if err != nil {
+		return err
+	}
+	// If the account already exists in state, refund the new account cost
+	// charged in the intrinsic calculation
+	if st.state.Exist(authority) {
+		st.state.AddRefund(params.TxAuthEmptyAccountGas - params.TxAuthTupleGas)
+	}
+	st.state.SetNonce(authority, auth.Nonce+1)
+
+	// Delegating to the zero address clears the delegation
+	if auth.Address == (common.Address{}) {
+		st.state.SetCode(authority, nil)
+		return nil
+	}
+	st.state.SetCode(authority, types.AddressToDelegation(auth.Address))
+	return nil
+}
+
+// refundGas returns the unused and the refunded gas to the sender, returning the
+// amount of gas refunded.
+func (st *StateTransition) refundGas() uint64 {
 	// Apply refund counter, capped to half of the used gas.
 	refund := st.gasUsed() / 2
 	if refund > st.state.GetRefund() {
@@ -305,6 +435,8 @@ func (st *StateTransition) refundGas() {
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
 	st.gp.AddGas(st.gas)
+
+	return refund
 }
 
 // gasUsed returns the amount of gas used up by the state transition.
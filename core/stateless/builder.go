@@ -0,0 +1,166 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errGenesis is returned if the witness of the genesis block is requested, which
+// is not executed.
+var errGenesis = errors.New("genesis block has no witness")
+
+// Build re-executes the block on top of the state of its parent, recording the
+// witness of the execution. The state of the parent must be available.
+func Build(chain *core.BlockChain, block *types.Block) (*Witness, error) {
+	if block.NumberU64() == 0 {
+		return nil, errGenesis
+	}
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block #%d not found", block.NumberU64())
+	}
+	var (
+		witness = newWitness(parent)
+		nodes   = &recordingStore{
+			KeyValueStore: chain.StateCache().TrieDB().DiskDB(),
+			triedb:        chain.StateCache().TrieDB(),
+			witness:       witness,
+		}
+		database = &recordingDatabase{
+			Database: state.NewDatabase(rawdb.NewDatabase(nodes)),
+			codes:    chain.StateCache(),
+			witness:  witness,
+		}
+		hashes = &blockHashRecorder{number: block.NumberU64(), oldest: parent.Number.Uint64()}
+	)
+	statedb, err := state.New(parent.Root, database, nil)
+	if err != nil {
+		return nil, err
+	}
+	statedb, _, _, _, err = chain.Processor().Process(block, statedb, vm.Config{Debug: true, Tracer: hashes})
+	if err != nil {
+		return nil, fmt.Errorf("processing block #%d failed: %v", block.NumberU64(), err)
+	}
+	// Hashing the post state resolves the nodes needed to collapse the tries
+	if root := statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number())); root != block.Root() {
+		return nil, fmt.Errorf("state root mismatch of block #%d: have %x, want %x", block.NumberU64(), root, block.Root())
+	}
+	for header := parent; header.Number.Uint64() > hashes.oldest; {
+		if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, fmt.Errorf("ancestor of block #%d not found", block.NumberU64())
+		}
+		witness.Headers = append(witness.Headers, header)
+	}
+	return witness, nil
+}
+
+// recordingStore serves the trie nodes from the trie database of the chain, which
+// holds the recent states in memory, recording them into the witness.
+type recordingStore struct {
+	ethdb.KeyValueStore
+	triedb  *trie.Database
+	witness *Witness
+}
+
+func (s *recordingStore) Get(key []byte) ([]byte, error) {
+	if len(key) != common.HashLength {
+		return s.KeyValueStore.Get(key)
+	}
+	node, err := s.triedb.Node(common.BytesToHash(key))
+	if err != nil {
+		return nil, err
+	}
+	s.witness.addState(node)
+	return node, nil
+}
+
+// recordingDatabase is the state database the block is executed on, serving the
+// contract codes from the database of the chain and recording them into the
+// witness.
+type recordingDatabase struct {
+	state.Database
+	codes   state.Database
+	witness *Witness
+}
+
+func (db *recordingDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.codes.ContractCode(addrHash, codeHash)
+	if err == nil {
+		db.witness.addCode(code)
+	}
+	return code, err
+}
+
+// ContractCodeSize records the code too, the stateless clients can only tell
+// its size from the code itself.
+func (db *recordingDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// blockHashRecorder is a tracer tracking the oldest block hashed by BLOCKHASH.
+type blockHashRecorder struct {
+	number uint64 // Number of the block executed
+	oldest uint64 // Oldest ancestor hashed
+	lock   sync.Mutex
+}
+
+func (r *blockHashRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op != vm.BLOCKHASH || err != nil {
+		return
+	}
+	// Out of range numbers are answered without headers
+	arg := scope.Stack.Back(0)
+	if !arg.IsUint64() {
+		return
+	}
+	n := arg.Uint64()
+	if n >= r.number || n+256 < r.number {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if n < r.oldest {
+		r.oldest = n
+	}
+}
+
+func (r *blockHashRecorder) CaptureTxStart(gasLimit uint64) {}
+func (r *blockHashRecorder) CaptureTxEnd(restGas uint64)    {}
+func (r *blockHashRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (r *blockHashRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (r *blockHashRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (r *blockHashRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (r *blockHashRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {}
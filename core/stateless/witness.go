@@ -0,0 +1,175 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stateless implements the building of execution witnesses, the data
+// needed to execute a block without having the state.
+package stateless
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Witness contains the data accessed while executing a block: the headers of the
+// ancestors down to the oldest one hashed by BLOCKHASH, the codes of the contracts
+// run and the trie nodes of the parent state read.
+type Witness struct {
+	Headers []*types.Header // Ancestors of the block, parent first
+
+	codes map[string]struct{}
+	state map[string]struct{}
+	lock  sync.Mutex
+}
+
+// newWitness creates an empty witness of the block with the given parent.
+func newWitness(parent *types.Header) *Witness {
+	return &Witness{
+		Headers: []*types.Header{parent},
+		codes:   make(map[string]struct{}),
+		state:   make(map[string]struct{}),
+	}
+}
+
+// addCode records a contract code, safe for concurrent use.
+func (w *Witness) addCode(code []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.codes[string(code)] = struct{}{}
+}
+
+// addState records a trie node, safe for concurrent use.
+func (w *Witness) addState(node []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.state[string(node)] = struct{}{}
+}
+
+// Codes returns the contract codes in the witness, sorted.
+func (w *Witness) Codes() [][]byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return sortedBlobs(w.codes)
+}
+
+// State returns the trie nodes in the witness, sorted.
+func (w *Witness) State() [][]byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return sortedBlobs(w.state)
+}
+
+func sortedBlobs(set map[string]struct{}) [][]byte {
+	blobs := make([][]byte, 0, len(set))
+	for blob := range set {
+		blobs = append(blobs, []byte(blob))
+	}
+	sort.Slice(blobs, func(i, j int) bool { return bytes.Compare(blobs[i], blobs[j]) < 0 })
+	return blobs
+}
+
+// Database creates a database holding the contents of the witness, enough to
+// execute the block on top of the state of its parent.
+func (w *Witness) Database() ethdb.Database {
+	db := rawdb.NewDatabase(memorydb.New())
+	for _, code := range w.Codes() {
+		rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+	}
+	for _, node := range w.State() {
+		rawdb.WriteTrieNode(db, crypto.Keccak256Hash(node), node)
+	}
+	return db
+}
+
+// extWitness is the external representation of a witness, used both for its
+// RLP and JSON encodings.
+type extWitness struct {
+	Headers []*types.Header `json:"headers"`
+	Codes   []hexutil.Bytes `json:"codes"`
+	State   []hexutil.Bytes `json:"state"`
+}
+
+func (w *Witness) toExt() *extWitness {
+	codes, state := w.Codes(), w.State()
+	ext := &extWitness{
+		Headers: w.Headers,
+		Codes:   make([]hexutil.Bytes, len(codes)),
+		State:   make([]hexutil.Bytes, len(state)),
+	}
+	for i, code := range codes {
+		ext.Codes[i] = code
+	}
+	for i, node := range state {
+		ext.State[i] = node
+	}
+	return ext
+}
+
+func (w *Witness) fromExt(ext *extWitness) {
+	w.Headers = ext.Headers
+	w.codes = make(map[string]struct{}, len(ext.Codes))
+	for _, code := range ext.Codes {
+		w.codes[string(code)] = struct{}{}
+	}
+	w.state = make(map[string]struct{}, len(ext.State))
+	for _, node := range ext.State {
+		w.state[string(node)] = struct{}{}
+	}
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (w *Witness) EncodeRLP(ew io.Writer) error {
+	return rlp.Encode(ew, w.toExt())
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (w *Witness) DecodeRLP(s *rlp.Stream) error {
+	var ext extWitness
+	if err := s.Decode(&ext); err != nil {
+		return err
+	}
+	w.fromExt(&ext)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (w *Witness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(w.toExt())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *Witness) UnmarshalJSON(input []byte) error {
+	var ext extWitness
+	if err := json.Unmarshal(input, &ext); err != nil {
+		return err
+	}
+	w.fromExt(&ext)
+	return nil
+}
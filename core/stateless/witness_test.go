@@ -0,0 +1,207 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testFunds  = big.NewInt(1000000000000000000)
+
+	// hasherAddr runs sstore(number, blockhash(number - 3))
+	hasherAddr = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+	hasherCode = []byte{byte(vm.PUSH1), 3, byte(vm.NUMBER), byte(vm.SUB), byte(vm.BLOCKHASH), byte(vm.NUMBER), byte(vm.SSTORE), byte(vm.STOP)}
+)
+
+// newTestChain creates a chain calling the hasher contract and creating a new
+// account in every block.
+func newTestChain(t *testing.T, n int) (*core.BlockChain, []*types.Block) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+		genesis = (&core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testAddr:   {Balance: testFunds},
+				hasherAddr: {Balance: common.Big0, Code: hasherCode},
+			},
+		}).MustCommit(db)
+		signer = types.LatestSigner(params.TestChainConfig)
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	// Insert the blocks one by one, so BLOCKHASH finds the ancestors
+	var blocks []*types.Block
+	for i, parent := 0, genesis; i < n; i, parent = i+1, blocks[len(blocks)-1] {
+		next, _ := core.GenerateChain(params.TestChainConfig, parent, engine, db, 1, func(_ int, gen *core.BlockGen) {
+			call, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), hasherAddr, common.Big0, 100000, big.NewInt(1), nil), signer, testKey)
+			gen.AddTxWithChain(chain, call)
+			transfer, _ := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), common.Address{byte(i + 1)}, common.Big1, params.TxGas, big.NewInt(1), nil), signer, testKey)
+			gen.AddTxWithChain(chain, transfer)
+		})
+		if _, err := chain.InsertChain(next); err != nil {
+			t.Fatalf("failed to insert block #%d: %v", i+1, err)
+		}
+		blocks = append(blocks, next...)
+	}
+	return chain, blocks
+}
+
+func TestBuildWitness(t *testing.T) {
+	chain, blocks := newTestChain(t, 8)
+	defer chain.Stop()
+
+	if _, err := Build(chain, chain.Genesis()); err != errGenesis {
+		t.Errorf("genesis witness error mismatch: have %v, want %v", err, errGenesis)
+	}
+	for _, block := range blocks {
+		witness, err := Build(chain, block)
+		if err != nil {
+			t.Fatalf("failed to build witness of block #%d: %v", block.NumberU64(), err)
+		}
+		// The block hashing the ancestor 3 blocks before needs it and its descendants
+		want := uint64(1)
+		if block.NumberU64() >= 3 {
+			want = 3
+		}
+		if len(witness.Headers) != int(want) {
+			t.Errorf("block #%d: header count mismatch: have %d, want %d", block.NumberU64(), len(witness.Headers), want)
+		}
+		if codes := witness.Codes(); len(codes) != 1 || string(codes[0]) != string(hasherCode) {
+			t.Errorf("block #%d: wrong codes: %x", block.NumberU64(), codes)
+		}
+		if root := executeStateless(t, witness, block); root != block.Root() {
+			t.Errorf("block #%d: stateless state root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+		}
+	}
+}
+
+// executeStateless executes the block with only the data in the witness, returning
+// the resulting state root.
+func executeStateless(t *testing.T, witness *Witness, block *types.Block) common.Hash {
+	chain := &witnessChain{engine: ethash.NewFaker(), headers: make(map[common.Hash]*types.Header)}
+	for _, header := range witness.Headers {
+		chain.headers[header.Hash()] = header
+	}
+	statedb, err := state.New(witness.Headers[0].Root, state.NewDatabase(witness.Database()), nil)
+	if err != nil {
+		t.Fatalf("block #%d: failed to open parent state: %v", block.NumberU64(), err)
+	}
+	var (
+		header   = block.Header()
+		gp       = new(core.GasPool).AddGas(block.GasLimit())
+		usedGas  = new(uint64)
+		txs      = []*types.Transaction(block.Transactions())
+		receipts []*types.Receipt
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, err := core.ApplyTransaction(chain.Config(), chain, nil, gp, statedb, header, tx, usedGas, vm.Config{})
+		if err != nil {
+			t.Fatalf("block #%d: failed to apply tx %d: %v", block.NumberU64(), i, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	if err := chain.engine.Finalize(chain, header, statedb, &txs, nil, &receipts, nil, usedGas); err != nil {
+		t.Fatalf("block #%d: failed to finalize: %v", block.NumberU64(), err)
+	}
+	return statedb.IntermediateRoot(true)
+}
+
+// witnessChain serves the headers of a witness.
+type witnessChain struct {
+	engine  consensus.Engine
+	headers map[common.Hash]*types.Header
+}
+
+func (c *witnessChain) Engine() consensus.Engine                { return c.engine }
+func (c *witnessChain) Config() *params.ChainConfig             { return params.TestChainConfig }
+func (c *witnessChain) CurrentHeader() *types.Header            { return nil }
+func (c *witnessChain) GetHighestVerifiedHeader() *types.Header { return nil }
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return c.headers[hash]
+}
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	for _, header := range c.headers {
+		if header.Number.Uint64() == number {
+			return header
+		}
+	}
+	return nil
+}
+
+func TestWitnessEncoding(t *testing.T) {
+	chain, blocks := newTestChain(t, 4)
+	defer chain.Stop()
+
+	witness, err := Build(chain, blocks[3])
+	if err != nil {
+		t.Fatalf("failed to build witness: %v", err)
+	}
+	check := func(name string, have *Witness) {
+		if len(have.Headers) != len(witness.Headers) || have.Headers[0].Hash() != witness.Headers[0].Hash() {
+			t.Errorf("%s: headers mismatch", name)
+		}
+		if !reflect.DeepEqual(have.Codes(), witness.Codes()) {
+			t.Errorf("%s: codes mismatch", name)
+		}
+		if !reflect.DeepEqual(have.State(), witness.State()) {
+			t.Errorf("%s: state mismatch", name)
+		}
+	}
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to RLP encode: %v", err)
+	}
+	dec := new(Witness)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatalf("failed to RLP decode: %v", err)
+	}
+	check("rlp", dec)
+
+	if enc, err = json.Marshal(witness); err != nil {
+		t.Fatalf("failed to JSON encode: %v", err)
+	}
+	dec = new(Witness)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("failed to JSON decode: %v", err)
+	}
+	check("json", dec)
+}
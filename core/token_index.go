@@ -0,0 +1,111 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// erc20TransferTopic is the signature of the ERC-20 and ERC-721 Transfer
+	// events, telling them apart by the token id being indexed by the latter.
+	erc20TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// erc1155SingleTopic and erc1155BatchTopic are the signatures of the ERC-1155
+	// TransferSingle and TransferBatch events.
+	erc1155SingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	erc1155BatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// tokenTransfers decodes the standard token transfer events emitted by the
+// transactions of a block, in log order. The events not matching the layout of
+// the standards, as emitted by non-compliant tokens, are skipped.
+func tokenTransfers(receipts types.Receipts) []rawdb.TokenTransfer {
+	var transfers []rawdb.TokenTransfer
+	for i, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if len(log.Topics) == 0 {
+				continue
+			}
+			transfer := rawdb.TokenTransfer{
+				TxIndex:  uint32(i),
+				LogIndex: uint32(log.Index),
+				Token:    log.Address,
+			}
+			switch {
+			case log.Topics[0] == erc20TransferTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+				transfer.Standard = rawdb.TokenERC20
+				transfer.From, transfer.To = common.BytesToAddress(log.Topics[1][:]), common.BytesToAddress(log.Topics[2][:])
+				transfer.Value = new(big.Int).SetBytes(log.Data)
+				transfers = append(transfers, transfer)
+
+			case log.Topics[0] == erc20TransferTopic && len(log.Topics) == 4 && len(log.Data) == 0:
+				transfer.Standard = rawdb.TokenERC721
+				transfer.From, transfer.To = common.BytesToAddress(log.Topics[1][:]), common.BytesToAddress(log.Topics[2][:])
+				transfer.ID, transfer.Value = log.Topics[3].Big(), big.NewInt(1)
+				transfers = append(transfers, transfer)
+
+			case log.Topics[0] == erc1155SingleTopic && len(log.Topics) == 4 && len(log.Data) == 64:
+				transfer.Standard = rawdb.TokenERC1155
+				transfer.From, transfer.To = common.BytesToAddress(log.Topics[2][:]), common.BytesToAddress(log.Topics[3][:])
+				transfer.ID, transfer.Value = new(big.Int).SetBytes(log.Data[:32]), new(big.Int).SetBytes(log.Data[32:])
+				transfers = append(transfers, transfer)
+
+			case log.Topics[0] == erc1155BatchTopic && len(log.Topics) == 4:
+				ids, values := decodeUint256Array(log.Data, 0), decodeUint256Array(log.Data, 32)
+				if ids == nil || len(ids) != len(values) {
+					continue
+				}
+				transfer.Standard = rawdb.TokenERC1155
+				transfer.From, transfer.To = common.BytesToAddress(log.Topics[2][:]), common.BytesToAddress(log.Topics[3][:])
+				for j := range ids {
+					transfer.ID, transfer.Value = ids[j], values[j]
+					transfers = append(transfers, transfer)
+				}
+			}
+		}
+	}
+	return transfers
+}
+
+// decodeUint256Array decodes the ABI encoded uint256[] whose offset is stored
+// at the given position of the data, nil if the encoding is invalid.
+func decodeUint256Array(data []byte, pos int) []*big.Int {
+	if len(data) < pos+32 {
+		return nil
+	}
+	offset := new(big.Int).SetBytes(data[pos : pos+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil
+	}
+	start := int(offset.Uint64())
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data)-start-32)/32 {
+		return nil
+	}
+	items := make([]*big.Int, length.Uint64())
+	for i := range items {
+		begin := start + 32 + i*32
+		items[i] = new(big.Int).SetBytes(data[begin : begin+32])
+	}
+	return items
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the token transfers emitted by the imported blocks are indexed under
+// both their sender and their recipient.
+func TestTokenIndex(t *testing.T) {
+	var (
+		key, _    = crypto.GenerateKey()
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		token     = common.HexToAddress("0xaaaa")
+		recipient = common.HexToAddress("0xbeef")
+		db        = rawdb.NewMemoryDatabase()
+		signer    = types.LatestSigner(params.TestChainConfig)
+	)
+	// Emits Transfer(caller, 0xbeef, 5): PUSH1 5 PUSH1 0 MSTORE PUSH2 0xbeef
+	// CALLER PUSH32 topic PUSH1 32 PUSH1 0 LOG3 STOP
+	code := common.FromHex("0x6005600052" + "61beef" + "33" + "7f" + erc20TransferTopic.Hex()[2:] + "60206000a300")
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			token:  {Balance: common.Big0, Code: code},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), token, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableTokenIndex)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadTokenTransferIndexTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("token index tail mismatch: have %v, want 1", tail)
+	}
+	for _, address := range []common.Address{sender, recipient} {
+		var transfers []rawdb.TokenTransfer
+		rawdb.IterateTokenTransfers(db, address, 2, 2, func(transfer rawdb.TokenTransfer) bool {
+			transfers = append(transfers, transfer)
+			return true
+		})
+		if len(transfers) != 1 {
+			t.Fatalf("transfers of %x: have %d, want 1", address, len(transfers))
+		}
+		transfer := transfers[0]
+		if transfer.BlockHash != blocks[1].Hash() || transfer.Token != token || transfer.Standard != rawdb.TokenERC20 ||
+			transfer.From != sender || transfer.To != recipient || transfer.ID != nil || transfer.Value.Uint64() != 5 {
+			t.Errorf("transfer of %x mismatch: %+v", address, transfer)
+		}
+	}
+}
+
+// Tests that the ERC-721 and ERC-1155 transfer events are decoded, and that the
+// events not matching the standards are skipped.
+func TestTokenTransferDecoding(t *testing.T) {
+	var (
+		token    = common.HexToAddress("0xaaaa")
+		operator = common.HexToHash("0x01")
+		from     = common.HexToHash("0x02")
+		to       = common.HexToHash("0x03")
+	)
+	word := func(n int64) []byte { return common.BigToHash(big.NewInt(n)).Bytes() }
+
+	var batch []byte
+	for _, n := range []int64{64, 160, 2, 7, 8, 2, 1, 10} {
+		batch = append(batch, word(n)...)
+	}
+	receipts := types.Receipts{{Logs: []*types.Log{
+		{Address: token, Topics: []common.Hash{erc20TransferTopic, from, to, common.HexToHash("0x2a")}, Index: 0},
+		{Address: token, Topics: []common.Hash{erc1155SingleTopic, operator, from, to}, Data: append(word(5), word(6)...), Index: 1},
+		{Address: token, Topics: []common.Hash{erc1155BatchTopic, operator, from, to}, Data: batch, Index: 2},
+		{Address: token, Topics: []common.Hash{erc20TransferTopic, from}, Data: word(1), Index: 3},
+		{Address: token, Topics: []common.Hash{erc1155BatchTopic, operator, from, to}, Data: word(1 << 40), Index: 4},
+	}}}
+	want := []struct {
+		standard  uint16
+		logIndex  uint32
+		id, value int64
+	}{
+		{rawdb.TokenERC721, 0, 42, 1},
+		{rawdb.TokenERC1155, 1, 5, 6},
+		{rawdb.TokenERC1155, 2, 7, 1},
+		{rawdb.TokenERC1155, 2, 8, 10},
+	}
+	transfers := tokenTransfers(receipts)
+	if len(transfers) != len(want) {
+		t.Fatalf("transfer count mismatch: have %d, want %d", len(transfers), len(want))
+	}
+	for i, transfer := range transfers {
+		if transfer.Standard != want[i].standard || transfer.LogIndex != want[i].logIndex ||
+			transfer.From != common.BytesToAddress(from[:]) || transfer.To != common.BytesToAddress(to[:]) ||
+			transfer.ID.Int64() != want[i].id || transfer.Value.Int64() != want[i].value {
+			t.Errorf("transfer %d mismatch: %+v", i, transfer)
+		}
+	}
+	// Both items of the batch must be stored under distinct keys
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteTokenTransfers(db, common.Hash{1}, 1, transfers)
+
+	var count int
+	rawdb.IterateTokenTransfers(db, common.BytesToAddress(to[:]), 0, 1, func(rawdb.TokenTransfer) bool {
+		count++
+		return true
+	})
+	if count != len(want) {
+		t.Errorf("stored transfer count mismatch: have %d, want %d", count, len(want))
+	}
+}
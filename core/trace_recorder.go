@@ -0,0 +1,394 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// tracedAccount is the state of an account touched by a transaction, as it was
+// before the transaction.
+type tracedAccount struct {
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// exists reports whether the account is non-empty.
+func (a *tracedAccount) exists() bool {
+	return a.nonce > 0 || a.balance.Sign() != 0 || len(a.code) > 0
+}
+
+// traceRecorder is an EVM logger recording the traces of the transactions of a
+// block while it's imported: the flattened call frames and the state diff of
+// every transaction, and the rewards credited by the consensus engine.
+type traceRecorder struct {
+	block *types.Block
+	env   *vm.EVM
+	txs   map[int]types.TxTraces
+
+	// Traces of the transaction being executed
+	calls    []types.CallTrace
+	frames   []int // Indices of the call frames entered but not exited yet
+	pre      map[common.Address]*tracedAccount
+	gasLimit uint64
+
+	// Balances of the reward recipients before the finalization of the block
+	rewardBase map[common.Address]*big.Int
+}
+
+func newTraceRecorder(block *types.Block, statedb *state.StateDB) *traceRecorder {
+	r := &traceRecorder{
+		block:      block,
+		txs:        make(map[int]types.TxTraces),
+		rewardBase: make(map[common.Address]*big.Int),
+	}
+	r.snapshotRewards(statedb)
+	return r
+}
+
+// snapshotRewards records the current balances of the accounts credited when
+// finalizing the block.
+func (r *traceRecorder) snapshotRewards(statedb vm.StateDB) {
+	recipients := []common.Address{r.block.Coinbase(), consensus.SystemAddress}
+	for _, uncle := range r.block.Uncles() {
+		recipients = append(recipients, uncle.Coinbase)
+	}
+	for _, addr := range recipients {
+		r.rewardBase[addr] = new(big.Int).Set(statedb.GetBalance(addr))
+	}
+}
+
+// lookupAccount adds the current state of an account to the pre state of the
+// transaction, unless it was already touched.
+func (r *traceRecorder) lookupAccount(addr common.Address) {
+	if _, ok := r.pre[addr]; ok {
+		return
+	}
+	r.pre[addr] = &tracedAccount{
+		balance: new(big.Int).Set(r.env.StateDB.GetBalance(addr)),
+		nonce:   r.env.StateDB.GetNonce(addr),
+		code:    common.CopyBytes(r.env.StateDB.GetCode(addr)),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage adds the current value of a storage slot to the pre state of
+// the transaction, unless it was already touched.
+func (r *traceRecorder) lookupStorage(addr common.Address, key common.Hash) {
+	r.lookupAccount(addr)
+	if _, ok := r.pre[addr].storage[key]; ok {
+		return
+	}
+	r.pre[addr].storage[key] = r.env.StateDB.GetState(addr, key)
+}
+
+// traceError converts an execution error into its trace form.
+func traceError(err error) string {
+	var invalidOp *vm.ErrInvalidOpCode
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, vm.ErrExecutionReverted):
+		return "Reverted"
+	case errors.Is(err, vm.ErrOutOfGas):
+		return "Out of gas"
+	case errors.Is(err, vm.ErrInvalidJump):
+		return "Bad jump destination"
+	case errors.As(err, &invalidOp):
+		return "Bad instruction"
+	default:
+		return err.Error()
+	}
+}
+
+func (r *traceRecorder) CaptureTxStart(gasLimit uint64) {
+	r.calls, r.frames = nil, nil
+	r.pre = make(map[common.Address]*tracedAccount)
+	r.gasLimit = gasLimit
+}
+
+func (r *traceRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	r.env = env
+	if r.pre == nil {
+		return
+	}
+	r.lookupAccount(from)
+	r.lookupAccount(to)
+	r.lookupAccount(env.Context.Coinbase)
+	if env.ChainConfig().Parlia != nil {
+		r.lookupAccount(consensus.SystemAddress)
+	}
+	// Revert the changes made before the execution: the value transfer, the gas
+	// purchase and the nonce increment of the sender, the contract setup.
+	r.pre[to].balance.Sub(r.pre[to].balance, value)
+	fee := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(r.gasLimit))
+	r.pre[from].balance.Add(r.pre[from].balance, fee.Add(fee, value))
+	r.pre[from].nonce--
+
+	frame := types.CallTrace{
+		Type:         types.CallTraceCall,
+		CallType:     "call",
+		From:         from,
+		To:           to,
+		Value:        new(big.Int).Set(value),
+		Gas:          gas,
+		Input:        common.CopyBytes(input),
+		TraceAddress: []uint64{},
+	}
+	if create {
+		r.pre[to].nonce, r.pre[to].code = 0, nil
+		frame.Type, frame.CallType = types.CallTraceCreate, ""
+	}
+	r.calls, r.frames = append(r.calls, frame), []int{0}
+}
+
+func (r *traceRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if len(r.frames) == 0 {
+		return
+	}
+	parent := &r.calls[r.frames[len(r.frames)-1]]
+	frame := types.CallTrace{
+		Type:         types.CallTraceCall,
+		From:         from,
+		To:           to,
+		Value:        new(big.Int),
+		Gas:          gas,
+		Input:        common.CopyBytes(input),
+		TraceAddress: append(append([]uint64{}, parent.TraceAddress...), parent.Subtraces),
+	}
+	parent.Subtraces++
+
+	if value != nil {
+		frame.Value.Set(value)
+	}
+	switch typ {
+	case vm.CREATE, vm.CREATE2:
+		frame.Type = types.CallTraceCreate
+	case vm.SELFDESTRUCT:
+		frame.Type = types.CallTraceSuicide
+	default:
+		frame.CallType = strings.ToLower(typ.String())
+	}
+	r.calls = append(r.calls, frame)
+	r.frames = append(r.frames, len(r.calls)-1)
+}
+
+func (r *traceRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(r.frames) < 2 {
+		return
+	}
+	frame := &r.calls[r.frames[len(r.frames)-1]]
+	frame.Output, frame.GasUsed, frame.Error = common.CopyBytes(output), gasUsed, traceError(err)
+	r.frames = r.frames[:len(r.frames)-1]
+}
+
+func (r *traceRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	if len(r.frames) == 0 {
+		return
+	}
+	frame := &r.calls[0]
+	frame.Output, frame.GasUsed, frame.Error = common.CopyBytes(output), gasUsed, traceError(err)
+	r.frames = nil
+}
+
+func (r *traceRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || r.pre == nil {
+		return
+	}
+	var (
+		stack  = scope.Stack
+		size   = len(stack.Data())
+		caller = scope.Contract.Address()
+	)
+	switch {
+	case size >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		r.lookupStorage(caller, common.Hash(stack.Back(0).Bytes32()))
+	case size >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		r.lookupAccount(common.Address(stack.Back(0).Bytes20()))
+	case size >= 2 && (op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL):
+		r.lookupAccount(common.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		r.lookupAccount(crypto.CreateAddress(caller, r.env.StateDB.GetNonce(caller)))
+	case size >= 4 && op == vm.CREATE2:
+		init := scope.Memory.GetCopy(int64(stack.Back(1).Uint64()), int64(stack.Back(2).Uint64()))
+		r.lookupAccount(crypto.CreateAddress2(caller, stack.Back(3).Bytes32(), crypto.Keccak256(init)))
+	}
+}
+
+func (r *traceRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (r *traceRecorder) CaptureTxEnd(restGas uint64) {
+	if r.env == nil || r.pre == nil {
+		return
+	}
+	state, ok := r.env.StateDB.(interface{ TxIndex() int })
+	if !ok {
+		return
+	}
+	addrs := make([]common.Address, 0, len(r.pre))
+	for addr := range r.pre {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var diffs []types.AccountDiff
+	for _, addr := range addrs {
+		var (
+			pre  = r.pre[addr]
+			db   = r.env.StateDB
+			diff = types.AccountDiff{
+				Address:     addr,
+				Existed:     pre.exists(),
+				Exists:      !db.HasSuicided(addr) && !db.Empty(addr),
+				BalanceFrom: pre.balance,
+				BalanceTo:   new(big.Int).Set(db.GetBalance(addr)),
+				NonceFrom:   pre.nonce,
+				NonceTo:     db.GetNonce(addr),
+				CodeFrom:    pre.code,
+				CodeTo:      common.CopyBytes(db.GetCode(addr)),
+			}
+		)
+		keys := make([]common.Hash, 0, len(pre.storage))
+		for key := range pre.storage {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+		for _, key := range keys {
+			if from, to := pre.storage[key], db.GetState(addr, key); from != to {
+				diff.Storage = append(diff.Storage, types.StorageDiff{Key: key, From: from, To: to})
+			}
+		}
+		if diff.Existed == diff.Exists && diff.BalanceFrom.Cmp(diff.BalanceTo) == 0 && diff.NonceFrom == diff.NonceTo &&
+			bytes.Equal(diff.CodeFrom, diff.CodeTo) && len(diff.Storage) == 0 {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	r.txs[state.TxIndex()] = types.TxTraces{Calls: r.calls, StateDiff: diffs}
+	r.snapshotRewards(r.env.StateDB)
+}
+
+// traces returns the traces recorded for the block, the rewards being derived
+// from the given state of the processed block.
+func (r *traceRecorder) traces(engine consensus.Engine, statedb *state.StateDB) *types.BlockTraces {
+	var (
+		txs      = r.block.Transactions()
+		coinbase = r.block.Coinbase()
+		traces   = &types.BlockTraces{Txs: make([]types.TxTraces, len(txs))}
+	)
+	for index, tx := range r.txs {
+		if index < len(txs) {
+			traces.Txs[index] = tx
+		}
+	}
+	if posa, ok := engine.(consensus.PoSA); ok {
+		// The fees collected by the system address are distributed to the
+		// validator, along with the block rewards if enabled.
+		reward := new(big.Int).Set(r.rewardBase[consensus.SystemAddress])
+		if blockRewards := posa.BlockRewards(r.block.Number()); blockRewards != nil {
+			reward.Add(reward, blockRewards)
+		}
+		if reward.Sign() > 0 {
+			traces.Rewards = append(traces.Rewards, types.RewardTrace{Author: coinbase, Value: reward, Type: types.RewardTraceBlock})
+		}
+		return traces
+	}
+	// Otherwise the rewards are credited to the recipients when finalizing
+	credited := func(addr common.Address) *big.Int {
+		return new(big.Int).Sub(statedb.GetBalance(addr), r.rewardBase[addr])
+	}
+	if reward := credited(coinbase); reward.Sign() > 0 {
+		traces.Rewards = append(traces.Rewards, types.RewardTrace{Author: coinbase, Value: reward, Type: types.RewardTraceBlock})
+	}
+	seen := map[common.Address]bool{coinbase: true}
+	for _, uncle := range r.block.Uncles() {
+		if seen[uncle.Coinbase] {
+			continue
+		}
+		seen[uncle.Coinbase] = true
+		if reward := credited(uncle.Coinbase); reward.Sign() > 0 {
+			traces.Rewards = append(traces.Rewards, types.RewardTrace{Author: uncle.Coinbase, Value: reward, Type: types.RewardTraceUncle})
+		}
+	}
+	return traces
+}
+
+// multiLogger is an EVM logger dispatching the events to multiple loggers.
+type multiLogger []vm.EVMLogger
+
+func (l multiLogger) CaptureTxStart(gasLimit uint64) {
+	for _, logger := range l {
+		logger.CaptureTxStart(gasLimit)
+	}
+}
+
+func (l multiLogger) CaptureTxEnd(restGas uint64) {
+	for _, logger := range l {
+		logger.CaptureTxEnd(restGas)
+	}
+}
+
+func (l multiLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	for _, logger := range l {
+		logger.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (l multiLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	for _, logger := range l {
+		logger.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (l multiLogger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	for _, logger := range l {
+		logger.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (l multiLogger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	for _, logger := range l {
+		logger.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (l multiLogger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	for _, logger := range l {
+		logger.CaptureFault(pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+func (l multiLogger) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	for _, logger := range l {
+		logger.CaptureEnd(output, gasUsed, t, err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the call traces, state diffs and rewards of the blocks are recorded
+// on import.
+func TestBlockTraces(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		caller = common.HexToAddress("0xaaaa")
+		callee = common.HexToAddress("0xbbbb")
+		uncle  = common.HexToAddress("0xcccc")
+		db     = rawdb.NewMemoryDatabase()
+		signer = types.LatestSigner(params.TestChainConfig)
+		// PUSH1 1 PUSH1 0 SSTORE PUSH1 0 (x5) PUSH20 callee GAS CALL STOP
+		contract = append(append(common.FromHex("0x60016000556000600060006000600073"), callee.Bytes()...), byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			caller: {Balance: common.Big0, Code: contract},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	forks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, gen *BlockGen) {
+		gen.SetCoinbase(uncle)
+	})
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), caller, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+		if i == 1 {
+			gen.AddUncle(forks[0].Header())
+		}
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil, EnableBlockTraces)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if tail := rawdb.ReadBlockTracesTail(db); tail == nil || *tail != 1 {
+		t.Fatalf("traces tail mismatch: have %v, want 1", tail)
+	}
+	for i, block := range blocks {
+		traces := rawdb.ReadBlockTraces(db, block.Hash(), block.NumberU64())
+		if traces == nil || len(traces.Txs) != 1 {
+			t.Fatalf("block %d: traces missing", i)
+		}
+		calls := traces.Txs[0].Calls
+		if len(calls) != 2 {
+			t.Fatalf("block %d: call count mismatch: have %d, want 2", i, len(calls))
+		}
+		if calls[0].From != sender || calls[0].To != caller || calls[0].Subtraces != 1 || len(calls[0].TraceAddress) != 0 {
+			t.Errorf("block %d: top call mismatch: have %+v", i, calls[0])
+		}
+		if calls[1].CallType != "call" || calls[1].From != caller || calls[1].To != callee || !reflect.DeepEqual(calls[1].TraceAddress, []uint64{0}) {
+			t.Errorf("block %d: internal call mismatch: have %+v", i, calls[1])
+		}
+		// The slot is only changed by the first transaction
+		diffs := make(map[common.Address]types.AccountDiff)
+		for _, diff := range traces.Txs[0].StateDiff {
+			diffs[diff.Address] = diff
+		}
+		if diff := diffs[sender]; diff.NonceFrom != uint64(i) || diff.NonceTo != uint64(i+1) {
+			t.Errorf("block %d: sender nonce mismatch: have %d -> %d", i, diff.NonceFrom, diff.NonceTo)
+		}
+		if _, ok := diffs[callee]; ok {
+			t.Errorf("block %d: untouched callee in state diff", i)
+		}
+		if have := len(diffs[caller].Storage); (i == 0 && have != 1) || (i == 1 && have != 0) {
+			t.Errorf("block %d: storage diff mismatch: have %v", i, diffs[caller].Storage)
+		}
+		// Check the rewards of the block and of the uncle
+		wantRewards := 1
+		if i == 1 {
+			wantRewards = 2
+		}
+		if len(traces.Rewards) != wantRewards {
+			t.Fatalf("block %d: reward count mismatch: have %d, want %d", i, len(traces.Rewards), wantRewards)
+		}
+		if reward := traces.Rewards[0]; reward.Author != block.Coinbase() || reward.Type != types.RewardTraceBlock || reward.Value.Cmp(ethash.ConstantinopleBlockReward) < 0 {
+			t.Errorf("block %d: block reward mismatch: have %+v", i, reward)
+		}
+		if i == 1 {
+			if reward := traces.Rewards[1]; reward.Author != uncle || reward.Type != types.RewardTraceUncle || reward.Value.Sign() <= 0 {
+				t.Errorf("block %d: uncle reward mismatch: have %+v", i, reward)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// ErrBlobPoolFull is returned if a blob transaction is added to the pool when
+	// the blob sub-pool already holds the maximum number of blob transactions.
+	ErrBlobPoolFull = errors.New("blob pool full")
+
+	// ErrBlobAccountLimit is returned if a sender already has the maximum number
+	// of blob transactions in the pool.
+	ErrBlobAccountLimit = errors.New("blob transaction account limit reached")
+
+	// ErrBlobUnderpriced is returned if the blob fee cap of a transaction is lower
+	// than the minimum configured in the pool.
+	ErrBlobUnderpriced = errors.New("blob transaction underpriced")
+
+	// ErrBlobReplaceType is returned if a blob transaction would replace a regular
+	// one or vice versa.
+	ErrBlobReplaceType = errors.New("blob and regular transactions can't replace each other")
+)
+
+var blobPoolDiscardMeter = metrics.NewRegisteredMeter("txpool/blob/discard", nil)
+
+// The blob sub-pool shares the pending and queued lists of the pool, so that the
+// nonce ordering of a sender holds across transaction types, but it is sized
+// separately: blob transactions are large, costly to propagate and don't compete
+// with the regular transactions for slots.
+
+// validateBlobTx checks a blob transaction against the rules of the blob
+// sub-pool. It's a no-op for any other transaction type.
+func (pool *TxPool) validateBlobTx(tx *types.Transaction, local bool) error {
+	if tx.Type() != types.BlobTxType {
+		return nil
+	}
+	if err := tx.ValidateBlobHashes(); err != nil {
+		return err
+	}
+	if tx.BlobGas() > params.MaxBlobGasPerBlock {
+		return ErrBlobGasLimitReached
+	}
+	if tx.BlobGasFeeCap().Cmp(BlobGasPrice()) < 0 {
+		return ErrBlobFeeCapTooLow
+	}
+	if !local && tx.BlobGasFeeCap().Cmp(new(big.Int).SetUint64(pool.config.BlobPriceLimit)) < 0 {
+		return ErrBlobUnderpriced
+	}
+	return nil
+}
+
+// checkBlobSlots checks whether the blob sub-pool has room for a transaction,
+// and that a replaced transaction is of the same kind and, for blob ones, that
+// the blob fee cap is bumped as well.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkBlobSlots(from common.Address, tx *types.Transaction, local bool) error {
+	var old *types.Transaction
+	if list := pool.pending[from]; list != nil {
+		old = list.txs.Get(tx.Nonce())
+	}
+	if list := pool.queue[from]; old == nil && list != nil {
+		old = list.txs.Get(tx.Nonce())
+	}
+	isBlob := tx.Type() == types.BlobTxType
+	if old != nil {
+		if (old.Type() == types.BlobTxType) != isBlob {
+			blobPoolDiscardMeter.Mark(1)
+			return ErrBlobReplaceType
+		}
+		if !isBlob {
+			return nil
+		}
+		// threshold = oldBlobFeeCap * (100 + priceBump) / 100
+		threshold := new(big.Int).Mul(old.BlobGasFeeCap(), big.NewInt(100+int64(pool.config.BlobPriceBump)))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.BlobGasFeeCap().Cmp(threshold) < 0 {
+			blobPoolDiscardMeter.Mark(1)
+			return ErrReplaceUnderpriced
+		}
+		return nil
+	}
+	if !isBlob || local {
+		return nil
+	}
+	if uint64(pool.all.BlobCount()) >= pool.config.BlobSlots {
+		blobPoolDiscardMeter.Mark(1)
+		return ErrBlobPoolFull
+	}
+	var count uint64
+	for _, list := range []*txList{pool.pending[from], pool.queue[from]} {
+		if list == nil {
+			continue
+		}
+		for _, ptx := range list.txs.items {
+			if ptx.Type() == types.BlobTxType {
+				count++
+			}
+		}
+	}
+	if count >= pool.config.BlobAccountSlots {
+		blobPoolDiscardMeter.Mark(1)
+		return ErrBlobAccountLimit
+	}
+	return nil
+}
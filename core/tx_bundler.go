@@ -0,0 +1,110 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	bundleMeter        = metrics.NewRegisteredMeter("txpool/bundler/bundles", nil)
+	bundleReplaceMeter = metrics.NewRegisteredMeter("txpool/bundler/replace", nil)
+)
+
+// bundleSelectors are the selectors of the EIP-4337 entry point methods taking
+// user operations, of the v0.6 and v0.7 entry points.
+var bundleSelectors = func() map[[4]byte]struct{} {
+	const (
+		userOp       = "(address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)"
+		packedUserOp = "(address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)"
+	)
+	selectors := make(map[[4]byte]struct{})
+	for _, method := range []string{
+		"handleOps(" + userOp + "[],address)",
+		"handleAggregatedOps((" + userOp + "[],address,bytes)[],address)",
+		"handleOps(" + packedUserOp + "[],address)",
+		"handleAggregatedOps((" + packedUserOp + "[],address,bytes)[],address)",
+	} {
+		var selector [4]byte
+		copy(selector[:], crypto.Keccak256([]byte(method)))
+		selectors[selector] = struct{}{}
+	}
+	return selectors
+}()
+
+// txBundlers relaxes the replacement rules for the EIP-4337 bundlers. A bundler
+// resubmits its bundle whenever user operations come and go, with a different
+// calldata but the same nonce, so the usual price bump would price it out after
+// a few rounds.
+//
+// Only the bundles of the configured bundlers, i.e. their calls to the handleOps
+// methods of an entry point, are replaceable with the smaller bump. The new one
+// still has to pay more than the old one.
+type txBundlers struct {
+	bundlers    map[common.Address]struct{}
+	defaultBump uint64
+	bundlerBump uint64
+}
+
+// newTxBundlers creates the bundler replacement rules of a pool from its
+// configuration.
+func newTxBundlers(config TxPoolConfig) *txBundlers {
+	bundlers := &txBundlers{
+		bundlers:    make(map[common.Address]struct{}),
+		defaultBump: config.PriceBump,
+		bundlerBump: config.BundlerPriceBump,
+	}
+	for _, addr := range config.Bundlers {
+		bundlers.bundlers[addr] = struct{}{}
+	}
+	return bundlers
+}
+
+// isBundle returns whether the transaction is a bundle of user operations sent
+// by one of the configured bundlers.
+func (b *txBundlers) isBundle(from common.Address, tx *types.Transaction) bool {
+	if len(b.bundlers) == 0 || tx.To() == nil || len(tx.Data()) < 4 {
+		return false
+	}
+	if _, ok := b.bundlers[from]; !ok {
+		return false
+	}
+	var selector [4]byte
+	copy(selector[:], tx.Data())
+	_, ok := bundleSelectors[selector]
+	return ok
+}
+
+// priceBump returns the price bump percentage the transaction needs to replace
+// a pooled one with the same nonce.
+func (b *txBundlers) priceBump(from common.Address, tx *types.Transaction) uint64 {
+	if b.isBundle(from, tx) {
+		bundleMeter.Mark(1)
+		return b.bundlerBump
+	}
+	return b.defaultBump
+}
+
+// replaced accounts the transaction replacing a pooled one.
+func (b *txBundlers) replaced(from common.Address, tx *types.Transaction) {
+	if b.isBundle(from, tx) {
+		bundleReplaceMeter.Mark(1)
+	}
+}
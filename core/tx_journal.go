@@ -20,6 +20,8 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -31,6 +33,10 @@ import (
 // into the journal, but no such file is currently open.
 var errNoActiveJournal = errors.New("no active journal")
 
+// errJournalLimit is returned for the journaled transactions not restored due
+// to the configured limit.
+var errJournalLimit = errors.New("journal restore limit reached")
+
 // devNull is a WriteCloser that just discards anything written into it. Its
 // goal is to allow the transaction journal to write into a fake journal when
 // loading transactions on startup without printing warnings due to no file
@@ -43,17 +49,26 @@ func (*devNull) Close() error                      { return nil }
 // txJournal is a rotating log of transactions with the aim of storing locally
 // created transactions to allow non-executed ones to survive node restarts.
 type txJournal struct {
+	kind   string         // Kind of the journaled transactions, for logging
 	path   string         // Filesystem path to store the transactions at
 	writer io.WriteCloser // Output stream to write new transactions into
 }
 
 // newTxJournal creates a new transaction journal to
-func newTxJournal(path string) *txJournal {
+func newTxJournal(path string, kind string) *txJournal {
 	return &txJournal{
+		kind: kind,
 		path: path,
 	}
 }
 
+// remoteJournalPath derives the path of the remote transaction journal from
+// the local one, e.g. transactions.rlp becomes transactions.remote.rlp.
+func remoteJournalPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".remote" + ext
+}
+
 // load parses a transaction journal dump from disk, loading its contents into
 // the specified pool.
 func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
@@ -111,7 +126,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	log.Info("Loaded transaction journal", "kind", journal.kind, "transactions", total, "dropped", dropped)
 
 	return failure
 }
@@ -163,7 +178,7 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 		return err
 	}
 	journal.writer = sink
-	log.Info("Regenerated local transaction journal", "transactions", journaled, "accounts", len(all))
+	log.Info("Regenerated transaction journal", "kind", journal.kind, "transactions", journaled, "accounts", len(all))
 
 	return nil
 }
@@ -26,6 +26,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/prque"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
@@ -119,6 +120,7 @@ var (
 	queuedGauge  = metrics.NewRegisteredGauge("txpool/queued", nil)
 	localGauge   = metrics.NewRegisteredGauge("txpool/local", nil)
 	slotsGauge   = metrics.NewRegisteredGauge("txpool/slots", nil)
+	blobGauge    = metrics.NewRegisteredGauge("txpool/blobs", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -148,6 +150,9 @@ type TxPoolConfig struct {
 	Journal   string           // Journal of local transactions to survive node restarts
 	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
 
+	JournalRemotes bool   // Whether to also journal remote transactions, pending and queued
+	JournalLimit   uint64 // Maximum number of remote transactions restored from the journal (0 = all)
+
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
 
@@ -156,6 +161,19 @@ type TxPoolConfig struct {
 	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
+	BlobSlots        uint64 // Maximum number of blob transactions in the blob sub-pool
+	BlobAccountSlots uint64 // Maximum number of blob transactions permitted per account
+	BlobPriceLimit   uint64 // Minimum blob fee cap to enforce for acceptance into the blob sub-pool
+	BlobPriceBump    uint64 // Minimum blob fee cap bump percentage to replace a blob transaction
+
+	SenderQuota        uint64           // Maximum number of pending and queued transactions per remote sender (0 = unlimited)
+	OriginQuota        uint64           // Maximum number of pending and queued transactions per network origin (0 = unlimited)
+	QuotaExemptSenders []common.Address // Senders not subject to the quotas, e.g. known relayers
+	QuotaExemptOrigins []string         // Peer IDs or IP addresses not subject to the origin quota
+
+	Bundlers         []common.Address // Account abstraction bundlers whose bundles are replaceable with a smaller price bump
+	BundlerPriceBump uint64           // Minimum price bump percentage to replace a bundle of a bundler (0 = any increase)
+
 	Lifetime       time.Duration // Maximum amount of time non-executable transaction are queued
 	ReannounceTime time.Duration // Duration for announcing local pending transactions again
 }
@@ -174,6 +192,13 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 64,
 	GlobalQueue:  1024,
 
+	BlobSlots:        256,
+	BlobAccountSlots: 16,
+	BlobPriceLimit:   1,
+	BlobPriceBump:    100,
+
+	BundlerPriceBump: 1,
+
 	Lifetime:       3 * time.Hour,
 	ReannounceTime: 10 * 365 * 24 * time.Hour,
 }
@@ -210,6 +235,26 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 		log.Warn("Sanitizing invalid txpool global queue", "provided", conf.GlobalQueue, "updated", DefaultTxPoolConfig.GlobalQueue)
 		conf.GlobalQueue = DefaultTxPoolConfig.GlobalQueue
 	}
+	if conf.BlobSlots < 1 {
+		log.Warn("Sanitizing invalid txpool blob slots", "provided", conf.BlobSlots, "updated", DefaultTxPoolConfig.BlobSlots)
+		conf.BlobSlots = DefaultTxPoolConfig.BlobSlots
+	}
+	if conf.BlobAccountSlots < 1 {
+		log.Warn("Sanitizing invalid txpool blob account slots", "provided", conf.BlobAccountSlots, "updated", DefaultTxPoolConfig.BlobAccountSlots)
+		conf.BlobAccountSlots = DefaultTxPoolConfig.BlobAccountSlots
+	}
+	if conf.BlobPriceLimit < params.BlobTxMinBlobGasprice {
+		log.Warn("Sanitizing invalid txpool blob price limit", "provided", conf.BlobPriceLimit, "updated", DefaultTxPoolConfig.BlobPriceLimit)
+		conf.BlobPriceLimit = DefaultTxPoolConfig.BlobPriceLimit
+	}
+	if conf.BlobPriceBump < 1 {
+		log.Warn("Sanitizing invalid txpool blob price bump", "provided", conf.BlobPriceBump, "updated", DefaultTxPoolConfig.BlobPriceBump)
+		conf.BlobPriceBump = DefaultTxPoolConfig.BlobPriceBump
+	}
+	if conf.BundlerPriceBump > conf.PriceBump {
+		log.Warn("Sanitizing invalid txpool bundler price bump", "provided", conf.BundlerPriceBump, "updated", conf.PriceBump)
+		conf.BundlerPriceBump = conf.PriceBump
+	}
 	if conf.Lifetime < 1 {
 		log.Warn("Sanitizing invalid txpool lifetime", "provided", conf.Lifetime, "updated", DefaultTxPoolConfig.Lifetime)
 		conf.Lifetime = DefaultTxPoolConfig.Lifetime
@@ -241,13 +286,19 @@ type TxPool struct {
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
+	eip4844  bool // Fork indicator whether we are accepting EIP-4844 blob transactions.
+	eip7702  bool // Fork indicator whether we are accepting EIP-7702 set-code transactions.
 
-	currentState  *state.StateDB // Current state in the blockchain head
-	pendingNonces *txNoncer      // Pending state tracking virtual nonces
-	currentMaxGas uint64         // Current gas limit for transaction caps
+	currentState   *state.StateDB // Current state in the blockchain head
+	pendingNonces  *txNoncer      // Pending state tracking virtual nonces
+	currentMaxGas  uint64         // Current gas limit for transaction caps
+	pendingBaseFee *big.Int       // Base fee of the pending block, nil before the base fee fork
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals        *accountSet // Set of local transaction to exempt from eviction rules
+	journal       *txJournal  // Journal of local transaction to back up to disk
+	remoteJournal *txJournal  // Journal of remote transactions to back up to disk
+	quota         *txQuota    // Per-sender and per-origin limits of remote transactions
+	bundlers      *txBundlers // Relaxed replacement rules of the account abstraction bundlers
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -294,6 +345,8 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	pool.quota = newTxQuota(pool, config)
+	pool.bundlers = newTxBundlers(config)
 	for _, addr := range config.Locals {
 		log.Info("Setting new local account", "address", addr)
 		pool.locals.add(addr)
@@ -307,7 +360,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 
 	// If local transactions and journaling is enabled, load from disk
 	if !config.NoLocals && config.Journal != "" {
-		pool.journal = newTxJournal(config.Journal)
+		pool.journal = newTxJournal(config.Journal, "local")
 
 		if err := pool.journal.load(pool.AddLocals); err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
@@ -316,6 +369,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote journaling is enabled, restore the remote transactions too
+	if config.JournalRemotes && config.Journal != "" {
+		pool.remoteJournal = newTxJournal(remoteJournalPath(config.Journal), "remote")
+
+		if err := pool.remoteJournal.load(pool.restoreRemotes(config.JournalLimit)); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -427,6 +491,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -443,6 +514,15 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	// Remote transactions aren't journaled on arrival, dump the final pool
+	if pool.remoteJournal != nil {
+		pool.mu.Lock()
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote tx journal", "err", err)
+		}
+		pool.mu.Unlock()
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -479,6 +559,41 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// SetLimits updates the pricing and slot limits of the transaction pool to those
+// of the given configuration, ignoring any other fields. Transactions exceeding
+// the new limits are dropped by the next pool reorganisation.
+func (pool *TxPool) SetLimits(config TxPoolConfig) {
+	pool.mu.Lock()
+	conf, limit := pool.config, pool.config.PriceLimit
+	conf.PriceLimit, conf.PriceBump = config.PriceLimit, config.PriceBump
+	conf.AccountSlots, conf.GlobalSlots = config.AccountSlots, config.GlobalSlots
+	conf.AccountQueue, conf.GlobalQueue = config.AccountQueue, config.GlobalQueue
+	conf.Lifetime = config.Lifetime
+	conf = conf.sanitize()
+
+	pool.config.PriceLimit, pool.config.PriceBump = conf.PriceLimit, conf.PriceBump
+	pool.config.AccountSlots, pool.config.GlobalSlots = conf.AccountSlots, conf.GlobalSlots
+	pool.config.AccountQueue, pool.config.GlobalQueue = conf.AccountQueue, conf.GlobalQueue
+	pool.config.BundlerPriceBump, pool.config.Lifetime = conf.BundlerPriceBump, conf.Lifetime
+	pool.bundlers.defaultBump, pool.bundlers.bundlerBump = conf.PriceBump, conf.BundlerPriceBump
+
+	if conf.PriceLimit != limit {
+		price := new(big.Int).SetUint64(conf.PriceLimit)
+		pool.gasPrice = price
+		for _, tx := range pool.priced.Cap(price) {
+			pool.removeTx(tx.Hash(), false)
+		}
+	}
+	pool.mu.Unlock()
+
+	log.Info("Transaction pool limits updated", "pricelimit", conf.PriceLimit, "pricebump", conf.PriceBump,
+		"accountslots", conf.AccountSlots, "globalslots", conf.GlobalSlots, "accountqueue", conf.AccountQueue,
+		"globalqueue", conf.GlobalQueue, "lifetime", conf.Lifetime)
+
+	// Truncate the pool to the new limits right away
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer))
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (pool *TxPool) Nonce(addr common.Address) uint64 {
@@ -566,6 +681,42 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
+// restoreRemotes returns a method adding journaled remote transactions to the
+// pool, dropping everything above the given limit.
+func (pool *TxPool) restoreRemotes(limit uint64) func([]*types.Transaction) []error {
+	var restored uint64
+	return func(txs []*types.Transaction) []error {
+		var errs []error
+		if limit > 0 && restored+uint64(len(txs)) > limit {
+			keep := limit - restored
+			for range txs[keep:] {
+				errs = append(errs, errJournalLimit)
+			}
+			txs = txs[:keep]
+		}
+		restored += uint64(len(txs))
+		return append(pool.AddRemotesSync(txs), errs...)
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -573,6 +724,14 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
 		return ErrTxTypeNotSupported
 	}
+	// Accept blob transactions only once EIP-4844 activates.
+	if !pool.eip4844 && tx.Type() == types.BlobTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Accept set-code transactions only once EIP-7702 activates.
+	if !pool.eip7702 && tx.Type() == types.SetCodeTxType {
+		return ErrTxTypeNotSupported
+	}
 	// Reject transactions over defined size to prevent DOS attacks
 	if uint64(tx.Size()) > txMaxSize {
 		return ErrOversizedData
@@ -595,6 +754,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !local && tx.GasPriceIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
 	}
+	// Drop non-local transactions which can't pay the base fee of the pending block
+	if !local && pool.pendingBaseFee != nil && tx.GasPriceIntCmp(pool.pendingBaseFee) < 0 {
+		return ErrFeeCapTooLow
+	}
 	// Ensure the transaction adheres to nonce ordering
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
@@ -605,14 +768,17 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInsufficientFunds
 	}
 	// Ensure the transaction has more gas than the basic tx fee.
-	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, true, pool.istanbul)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.SetCodeAuthorizations(), tx.To() == nil, true, pool.istanbul)
 	if err != nil {
 		return err
 	}
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
-	return nil
+	if err := pool.validateSetCodeTx(tx); err != nil {
+		return err
+	}
+	return pool.validateBlobTx(tx, local)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for later
@@ -640,6 +806,13 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		invalidTxMeter.Mark(1)
 		return false, err
 	}
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	if err := pool.checkBlobSlots(from, tx, isLocal); err != nil {
+		return false, err
+	}
+	if err := pool.checkDelegations(from, tx); err != nil {
+		return false, err
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -667,10 +840,9 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 		}
 	}
 	// Try to replace an existing transaction in the pending pool
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
-		inserted, old := list.Add(tx, pool.config.PriceBump)
+		inserted, old := list.Add(tx, pool.bundlers.priceBump(from, tx))
 		if !inserted {
 			pendingDiscardMeter.Mark(1)
 			return false, ErrReplaceUnderpriced
@@ -680,6 +852,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.bundlers.replaced(from, tx)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
@@ -720,7 +893,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
 	}
-	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
+	inserted, old := pool.queue[from].Add(tx, pool.bundlers.priceBump(from, tx))
 	if !inserted {
 		// An older transaction was better, discard this
 		queuedDiscardMeter.Mark(1)
@@ -731,6 +904,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction, local boo
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.bundlers.replaced(from, tx)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -774,7 +948,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	}
 	list := pool.pending[addr]
 
-	inserted, old := list.Add(tx, pool.config.PriceBump)
+	inserted, old := list.Add(tx, pool.bundlers.priceBump(addr, tx))
 	if !inserted {
 		// An older transaction was better, discard this
 		pool.all.Remove(hash)
@@ -805,7 +979,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 // This method is used to add transactions from the RPC API and performs synchronous pool
 // reorganization and event propagation.
 func (pool *TxPool) AddLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals, true)
+	return pool.addTxs(txs, "", !pool.config.NoLocals, true)
 }
 
 // AddLocal enqueues a single local transaction into the pool if it is valid. This is
@@ -821,12 +995,19 @@ func (pool *TxPool) AddLocal(tx *types.Transaction) error {
 // This method is used to add transactions from the p2p network and does not wait for pool
 // reorganization and internal event propagation.
 func (pool *TxPool) AddRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, false)
+	return pool.addTxs(txs, "", false, false)
+}
+
+// AddRemotesFrom is like AddRemotes, but additionally accounts the transactions
+// to the network origin (peer ID or IP address) they were received from, which is
+// subject to the per-origin quota.
+func (pool *TxPool) AddRemotesFrom(origin string, txs []*types.Transaction) []error {
+	return pool.addTxs(txs, origin, false, false)
 }
 
 // This is like AddRemotes, but waits for pool reorganization. Tests use this method.
 func (pool *TxPool) AddRemotesSync(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, true)
+	return pool.addTxs(txs, "", false, true)
 }
 
 // This is like AddRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
@@ -844,8 +1025,9 @@ func (pool *TxPool) AddRemote(tx *types.Transaction) error {
 	return errs[0]
 }
 
-// addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
+// addTxs attempts to queue a batch of transactions if they are valid. The origin
+// is the network source of remote transactions, empty if unknown.
+func (pool *TxPool) addTxs(txs []*types.Transaction, origin string, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
 		errs = make([]error, len(txs))
@@ -864,6 +1046,9 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 		_, err := types.Sender(pool.signer, tx)
 		if err != nil {
 			errs[i] = ErrInvalidSender
+			if errors.Is(err, types.ErrTxTypeNotSupported) {
+				errs[i] = ErrTxTypeNotSupported
+			}
 			invalidTxMeter.Mark(1)
 			continue
 		}
@@ -876,7 +1061,7 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, origin, local)
 	pool.mu.Unlock()
 
 	var nilSlot = 0
@@ -897,12 +1082,19 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 
 // addTxsLocked attempts to queue a batch of transactions if they are valid.
 // The transaction pool lock must be held.
-func (pool *TxPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *TxPool) addTxsLocked(txs []*types.Transaction, origin string, local bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
+		if err := pool.quota.check(tx, origin, local); err != nil {
+			errs[i] = err
+			continue
+		}
 		replaced, err := pool.add(tx, local)
 		errs[i] = err
+		if err == nil && origin != "" {
+			pool.all.SetOrigin(tx.Hash(), origin)
+		}
 		if err == nil && !replaced {
 			dirty.addTx(tx)
 		}
@@ -1241,16 +1433,22 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.pendingBaseFee = nil
+	if pool.chainconfig.IsBaseFee(new(big.Int).Add(newHead.Number, common.Big1)) {
+		pool.pendingBaseFee = misc.CalcBaseFee(pool.chainconfig, newHead)
+	}
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	senderCacher.recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, "", false)
 
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
+	pool.eip4844 = pool.chainconfig.IsCancun(next)
+	pool.eip7702 = pool.chainconfig.IsPrague(next)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1608,16 +1806,22 @@ func (as *accountSet) merge(other *accountSet) {
 // to build upper-level structure.
 type txLookup struct {
 	slots   int
+	blobs   int
 	lock    sync.RWMutex
 	locals  map[common.Hash]*types.Transaction
 	remotes map[common.Hash]*types.Transaction
+
+	origins     map[common.Hash]string // Network origin (peer or IP) of the remote transactions
+	originSlots map[string]int         // Number of transactions currently tracked per origin
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		locals:  make(map[common.Hash]*types.Transaction),
-		remotes: make(map[common.Hash]*types.Transaction),
+		locals:      make(map[common.Hash]*types.Transaction),
+		remotes:     make(map[common.Hash]*types.Transaction),
+		origins:     make(map[common.Hash]string),
+		originSlots: make(map[string]int),
 	}
 }
 
@@ -1695,6 +1899,14 @@ func (t *txLookup) RemoteCount() int {
 	return len(t.remotes)
 }
 
+// BlobCount returns the current number of blob transactions in the lookup.
+func (t *txLookup) BlobCount() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.blobs
+}
+
 // Slots returns the current number of slots used in the lookup.
 func (t *txLookup) Slots() int {
 	t.lock.RLock()
@@ -1710,6 +1922,10 @@ func (t *txLookup) Add(tx *types.Transaction, local bool) {
 
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
+	if tx.Type() == types.BlobTxType {
+		t.blobs++
+		blobGauge.Update(int64(t.blobs))
+	}
 
 	if local {
 		t.locals[tx.Hash()] = tx
@@ -1733,9 +1949,43 @@ func (t *txLookup) Remove(hash common.Hash) {
 	}
 	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
+	if tx.Type() == types.BlobTxType {
+		t.blobs--
+		blobGauge.Update(int64(t.blobs))
+	}
 
 	delete(t.locals, hash)
 	delete(t.remotes, hash)
+
+	if origin, ok := t.origins[hash]; ok {
+		delete(t.origins, hash)
+		if t.originSlots[origin]--; t.originSlots[origin] <= 0 {
+			delete(t.originSlots, origin)
+		}
+	}
+}
+
+// SetOrigin records the network origin a tracked transaction was received from.
+func (t *txLookup) SetOrigin(hash common.Hash, origin string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.origins[hash]; ok {
+		return
+	}
+	if t.locals[hash] == nil && t.remotes[hash] == nil {
+		return
+	}
+	t.origins[hash] = origin
+	t.originSlots[origin]++
+}
+
+// OriginCount returns the number of transactions tracked from the given origin.
+func (t *txLookup) OriginCount(origin string) int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.originSlots[origin]
 }
 
 // RemoteToLocals migrates the transactions belongs to the given locals to locals
@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -1109,6 +1110,46 @@ func TestTransactionPendingGlobalLimiting(t *testing.T) {
 	}
 }
 
+// Tests that lowering the pool limits at runtime truncates the pending and queued
+// transactions to the new allowances.
+func TestTransactionPoolSetLimits(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	// Fill the pool with executable and gapped transactions of a single account
+	txs := types.Transactions{}
+	for i := uint64(0); i < 8; i++ {
+		txs = append(txs, transaction(i, 100000, key))
+	}
+	for i := uint64(10); i < 18; i++ {
+		txs = append(txs, transaction(i, 100000, key))
+	}
+	pool.AddRemotesSync(txs)
+	if pending, queued := pool.Stats(); pending != 8 || queued != 8 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 8/8", pending, queued)
+	}
+	// Lower the limits and check that the pool was truncated
+	config := testTxPoolConfig
+	config.GlobalSlots, config.AccountSlots = 4, 4
+	config.GlobalQueue, config.AccountQueue = 2, 2
+	pool.SetLimits(config)
+
+	if pending, queued := pool.Stats(); pending != 4 || queued != 2 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 4/2", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Test the limit on transaction size is enforced correctly.
 // This test verifies every transaction having allowed size
 // is added to the pool, and longer transactions are rejected.
@@ -1859,6 +1900,355 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions, both pending and queued behind a nonce gap,
+// survive restarts if remote journaling is enabled.
+func TestTransactionJournalingRemotes(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary directory for the journals
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+	config.JournalRemotes = true
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, big.NewInt(1), remote)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	pool.Stop()
+
+	// Restart the pool and ensure the pending and queued transactions are restored
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("restored transactions mismatched: have %d/%d, want %d/%d", pending, queued, 2, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.Stop()
+
+	// Restart the pool with a restore limit and ensure only that many are restored
+	config.JournalLimit = 1
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("restored transactions mismatched: have %d/%d, want %d/%d", pending, queued, 1, 0)
+	}
+	pool.Stop()
+}
+
+// Tests that remote transactions are limited per sender and per network origin,
+// except for replacements, local transactions and allowlisted senders and origins.
+func TestTransactionQuotas(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	keys := make([]*ecdsa.PrivateKey, 4)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	config := testTxPoolConfig
+	config.SenderQuota = 2
+	config.OriginQuota = 3
+	config.QuotaExemptSenders = []common.Address{crypto.PubkeyToAddress(keys[2].PublicKey)}
+	config.QuotaExemptOrigins = []string{"10.0.0.1"}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	add := func(origin string, tx *types.Transaction) error {
+		return pool.addTxs([]*types.Transaction{tx}, origin, false, true)[0]
+	}
+	// Fill up the quota of a sender, replacements are still accepted
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := add("1.1.1.1", pricedTransaction(nonce, 100000, big.NewInt(1), keys[0])); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	if err := add("1.1.1.1", pricedTransaction(2, 100000, big.NewInt(1), keys[0])); err != ErrSenderQuota {
+		t.Fatalf("sender quota error mismatch: have %v, want %v", err, ErrSenderQuota)
+	}
+	if err := add("1.1.1.1", pricedTransaction(1, 100000, big.NewInt(2), keys[0])); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	// Fill up the quota of the origin with another sender
+	if err := add("1.1.1.1", pricedTransaction(0, 100000, big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := add("1.1.1.1", pricedTransaction(1, 100000, big.NewInt(1), keys[1])); err != ErrOriginQuota {
+		t.Fatalf("origin quota error mismatch: have %v, want %v", err, ErrOriginQuota)
+	}
+	if err := add("2.2.2.2", pricedTransaction(1, 100000, big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add transaction from another origin: %v", err)
+	}
+	// Allowlisted senders and origins are exempt
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := add("1.1.1.1", pricedTransaction(nonce, 100000, big.NewInt(1), keys[2])); err != nil {
+			t.Fatalf("failed to add exempt sender transaction %d: %v", nonce, err)
+		}
+	}
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := add("10.0.0.1", pricedTransaction(nonce, 100000, big.NewInt(1), keys[3])); err != nil {
+			t.Fatalf("failed to add exempt origin transaction %d: %v", nonce, err)
+		}
+	}
+	// Local transactions are never limited
+	if err := pool.AddLocal(pricedTransaction(2, 100000, big.NewInt(1), keys[0])); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	// Dropped transactions release the quota of their origin
+	if count := pool.all.OriginCount("1.1.1.1"); count != 6 {
+		t.Fatalf("origin transaction count mismatch: have %d, want %d", count, 6)
+	}
+	pool.mu.Lock()
+	pool.removeTx(pricedTransaction(1, 100000, big.NewInt(1), keys[1]).Hash(), true)
+	pool.removeTx(pricedTransaction(0, 100000, big.NewInt(1), keys[1]).Hash(), true)
+	pool.mu.Unlock()
+	if count := pool.all.OriginCount("1.1.1.1"); count != 5 {
+		t.Fatalf("origin transaction count mismatch: have %d, want %d", count, 5)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the bundles of the configured bundlers are replaceable with the
+// smaller bundler price bump, and that other transactions aren't.
+func TestTransactionBundlerReplacement(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	config := testTxPoolConfig
+	config.Bundlers = []common.Address{crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)}
+	config.BundlerPriceBump = 1
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range keys {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	var handleOps []byte
+	for selector := range bundleSelectors {
+		handleOps = append(selector[:], 0xbb)
+		break
+	}
+	entryPoint := common.HexToAddress("0x5ff137d4b0fdcd49dca30c7cf57e578a026d2789")
+	transaction := func(nonce uint64, price int64, data []byte, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, entryPoint, common.Big0, 100000, big.NewInt(price), data), types.HomesteadSigner{}, key)
+		return tx
+	}
+	for i, tt := range []struct {
+		key  *ecdsa.PrivateKey
+		data []byte
+		bump bool // Whether the replacement with a 5% bump is accepted
+	}{
+		{keys[0], handleOps, true},                       // Bundle of a bundler
+		{keys[1], []byte{0xde, 0xad, 0xbe, 0xef}, false}, // Other call of a bundler
+		{keys[2], handleOps, false},                      // Bundle of someone else
+	} {
+		// Both pending (nonce 0) and queued (nonce 2) transactions are replaceable
+		for _, nonce := range []uint64{0, 2} {
+			if err := pool.addRemoteSync(transaction(nonce, 100, tt.data, tt.key)); err != nil {
+				t.Fatalf("test %d, nonce %d: failed to add transaction: %v", i, nonce, err)
+			}
+			err := pool.addRemoteSync(transaction(nonce, 105, append(tt.data, 1), tt.key))
+			if tt.bump && err != nil {
+				t.Errorf("test %d, nonce %d: failed to replace transaction: %v", i, nonce, err)
+			}
+			if !tt.bump && err != ErrReplaceUnderpriced {
+				t.Errorf("test %d, nonce %d: replacement error mismatch: have %v, want %v", i, nonce, err, ErrReplaceUnderpriced)
+			}
+		}
+	}
+	// Bundles still have to pay more than the ones they replace
+	if err := pool.addRemoteSync(transaction(0, 105, append(handleOps, 2), keys[0])); err != ErrReplaceUnderpriced {
+		t.Errorf("same price replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that blob transactions are only accepted after the Cancun fork, are
+// limited in their own sub-pool and can't replace regular transactions.
+func TestTransactionBlobLimits(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.BlobSlots = 3
+	config.BlobAccountSlots = 2
+
+	chainconfig := *params.TestChainConfig
+	chainconfig.CancunBlock = big.NewInt(0)
+
+	pool := NewTxPool(config, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	blobTx := func(nonce uint64, gasPrice, blobFeeCap int64, key *ecdsa.PrivateKey) *types.Transaction {
+		return types.MustSignNewTx(key, pool.signer, &types.BlobTx{
+			ChainID:    chainconfig.ChainID,
+			Nonce:      nonce,
+			GasPrice:   big.NewInt(gasPrice),
+			Gas:        100000,
+			Value:      big.NewInt(0),
+			BlobFeeCap: big.NewInt(blobFeeCap),
+			BlobHashes: []common.Hash{{params.BlobTxHashVersion}},
+		})
+	}
+	// Fill up the blob slots of a sender, replacements need a blob fee bump
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := pool.AddRemote(blobTx(nonce, 1, 1, keys[0])); err != nil {
+			t.Fatalf("failed to add blob transaction %d: %v", nonce, err)
+		}
+	}
+	if err := pool.AddRemote(blobTx(2, 1, 1, keys[0])); err != ErrBlobAccountLimit {
+		t.Fatalf("blob account limit error mismatch: have %v, want %v", err, ErrBlobAccountLimit)
+	}
+	if err := pool.AddRemote(blobTx(1, 2, 1, keys[0])); err != ErrReplaceUnderpriced {
+		t.Fatalf("blob replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	}
+	if err := pool.AddRemote(blobTx(1, 2, 2, keys[0])); err != nil {
+		t.Fatalf("failed to replace blob transaction: %v", err)
+	}
+	// Blob and regular transactions can't replace each other
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(10), keys[0])); err != ErrBlobReplaceType {
+		t.Fatalf("replacement type error mismatch: have %v, want %v", err, ErrBlobReplaceType)
+	}
+	// Fill up the blob sub-pool, regular transactions are still accepted
+	if err := pool.AddRemote(blobTx(0, 1, 1, keys[1])); err != nil {
+		t.Fatalf("failed to add blob transaction: %v", err)
+	}
+	if err := pool.AddRemote(blobTx(1, 1, 1, keys[1])); err != ErrBlobPoolFull {
+		t.Fatalf("blob pool full error mismatch: have %v, want %v", err, ErrBlobPoolFull)
+	}
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add regular transaction: %v", err)
+	}
+	if count := pool.all.BlobCount(); count != 3 {
+		t.Fatalf("blob transaction count mismatch: have %d, want %d", count, 3)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Blob transactions are rejected before the fork
+	legacy := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer legacy.Stop()
+
+	if err := legacy.AddRemote(blobTx(0, 1, 1, keys[1])); err != ErrTxTypeNotSupported {
+		t.Fatalf("pre-fork blob error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that set-code transactions are only accepted after the Prague fork, and
+// that they don't conflict with the pooled transactions of their authorities.
+func TestTransactionSetCodeLimits(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	chainconfig := *params.TestChainConfig
+	chainconfig.CancunBlock = big.NewInt(0)
+	chainconfig.PragueBlock = big.NewInt(0)
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+		statedb.AddBalance(crypto.PubkeyToAddress(keys[i].PublicKey), big.NewInt(1000000000))
+	}
+	// The last account is already delegated
+	delegated := crypto.PubkeyToAddress(keys[2].PublicKey)
+	statedb.SetCode(delegated, types.AddressToDelegation(common.Address{0xaa}))
+
+	pool := NewTxPool(testTxPoolConfig, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	setCodeTx := func(nonce uint64, key *ecdsa.PrivateKey, authorities ...*ecdsa.PrivateKey) *types.Transaction {
+		var auths []types.SetCodeAuthorization
+		for _, authority := range authorities {
+			auth, _ := types.SignSetCode(authority, types.SetCodeAuthorization{
+				ChainID: chainconfig.ChainID,
+				Address: common.Address{0xaa},
+			})
+			auths = append(auths, auth)
+		}
+		return types.MustSignNewTx(key, pool.signer, &types.SetCodeTx{
+			ChainID:  chainconfig.ChainID,
+			Nonce:    nonce,
+			GasPrice: big.NewInt(1),
+			Gas:      100000,
+			Value:    big.NewInt(0),
+			AuthList: auths,
+		})
+	}
+	if err := pool.AddRemote(setCodeTx(0, keys[0])); err != ErrEmptyAuthList {
+		t.Fatalf("empty auth list error mismatch: have %v, want %v", err, ErrEmptyAuthList)
+	}
+	// An authority with pooled transactions can't be delegated by someone else
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[1])); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.AddRemote(setCodeTx(0, keys[0], keys[1])); err != ErrAuthorityReserved {
+		t.Fatalf("reserved authority error mismatch: have %v, want %v", err, ErrAuthorityReserved)
+	}
+	if err := pool.AddRemote(setCodeTx(0, keys[0], keys[0])); err != nil {
+		t.Fatalf("failed to add self-sponsored set-code transaction: %v", err)
+	}
+	// Delegated accounts are limited to a single in-flight transaction
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), keys[2])); err != nil {
+		t.Fatalf("failed to add delegated account transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), keys[2])); err != ErrInflightTxLimitReached {
+		t.Fatalf("in-flight limit error mismatch: have %v, want %v", err, ErrInflightTxLimitReached)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), keys[2])); err != nil {
+		t.Fatalf("failed to replace delegated account transaction: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Set-code transactions are rejected before the fork
+	legacy := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer legacy.Stop()
+
+	if err := legacy.AddRemote(setCodeTx(0, keys[1], keys[1])); err != ErrTxTypeNotSupported {
+		t.Fatalf("pre-fork set-code error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -0,0 +1,221 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	txPrefetchTxsMeter  = metrics.NewRegisteredMeter("txpool/prefetch/txs", nil)
+	txPrefetchHitMeter  = metrics.NewRegisteredMeter("txpool/prefetch/hit", nil)
+	txPrefetchMissMeter = metrics.NewRegisteredMeter("txpool/prefetch/miss", nil)
+	txPrefetchTimer     = metrics.NewRegisteredTimer("txpool/prefetch/time", nil)
+)
+
+// TxPrefetcher warms the snapshot and trie caches for the next block. On every
+// new chain head it executes the pending transactions most likely to be picked
+// by the next block producer on top of it, while that block is being sealed and
+// propagated, so its import mostly hits memory.
+//
+// The share of the transactions of every imported block which were prefetched
+// is reported through the txpool/prefetch/hit and txpool/prefetch/miss meters.
+type TxPrefetcher struct {
+	bc   *BlockChain
+	pool *TxPool
+
+	headCh  chan ChainHeadEvent
+	headSub event.Subscription
+
+	prefetched map[common.Hash]struct{} // Transactions prefetched for the current head
+	interrupt  *uint32                  // Interrupt flag of the running prefetch
+
+	wg sync.WaitGroup
+}
+
+// NewTxPrefetcher creates a prefetcher for the pending transactions of the pool
+// and starts following the chain head.
+func NewTxPrefetcher(bc *BlockChain, pool *TxPool) *TxPrefetcher {
+	p := &TxPrefetcher{
+		bc:     bc,
+		pool:   pool,
+		headCh: make(chan ChainHeadEvent, chainHeadChanSize),
+	}
+	p.headSub = bc.SubscribeChainHeadEvent(p.headCh)
+
+	p.wg.Add(1)
+	go p.loop()
+	return p
+}
+
+// Stop terminates the prefetcher, interrupting any running prefetch.
+func (p *TxPrefetcher) Stop() {
+	p.headSub.Unsubscribe()
+	p.wg.Wait()
+}
+
+func (p *TxPrefetcher) loop() {
+	defer p.wg.Done()
+	defer p.abort()
+
+	for {
+		select {
+		case ev := <-p.headCh:
+			p.abort()
+			p.report(ev.Block)
+
+			// Skip stale heads queued up during a sync
+			if len(p.headCh) > 0 {
+				continue
+			}
+			txs := p.pending(ev.Block)
+			if len(txs) == 0 {
+				continue
+			}
+			p.prefetched = make(map[common.Hash]struct{}, len(txs))
+			for _, tx := range txs {
+				p.prefetched[tx.Hash()] = struct{}{}
+			}
+			p.interrupt = new(uint32)
+
+			p.wg.Add(1)
+			go func(head *types.Header, interrupt *uint32) {
+				defer p.wg.Done()
+				p.prefetch(head, txs, interrupt)
+			}(ev.Block.Header(), p.interrupt)
+
+		case <-p.headSub.Err():
+			return
+		}
+	}
+}
+
+// abort interrupts the running prefetch, if any.
+func (p *TxPrefetcher) abort() {
+	if p.interrupt != nil {
+		atomic.StoreUint32(p.interrupt, 1)
+		p.interrupt = nil
+	}
+}
+
+// report updates the hit rate metrics with the transactions of a new head.
+func (p *TxPrefetcher) report(block *types.Block) {
+	if p.prefetched == nil {
+		return
+	}
+	var hits int64
+	for _, tx := range block.Transactions() {
+		if _, ok := p.prefetched[tx.Hash()]; ok {
+			hits++
+		}
+	}
+	txPrefetchHitMeter.Mark(hits)
+	txPrefetchMissMeter.Mark(int64(len(block.Transactions())) - hits)
+	p.prefetched = nil
+}
+
+// pending returns the most profitable pending transactions of the pool,
+// limited to the gas limit of the next block. The pool might not have caught
+// up with the head yet, so the transactions included in it are skipped.
+func (p *TxPrefetcher) pending(head *types.Block) types.Transactions {
+	pending, err := p.pool.Pending()
+	if err != nil || len(pending) == 0 {
+		return nil
+	}
+	included := make(map[common.Hash]struct{}, len(head.Transactions()))
+	for _, tx := range head.Transactions() {
+		included[tx.Hash()] = struct{}{}
+	}
+	var (
+		signer = types.MakeSigner(p.bc.Config(), new(big.Int).Add(head.Number(), common.Big1))
+		sorted = types.NewTransactionsByPriceAndNonce(signer, pending)
+		gas    = head.GasLimit()
+		txs    types.Transactions
+	)
+	for tx := sorted.Peek(); tx != nil; tx = sorted.Peek() {
+		if _, ok := included[tx.Hash()]; ok {
+			sorted.Shift()
+			continue
+		}
+		if tx.Gas() > gas {
+			sorted.Pop()
+			continue
+		}
+		gas -= tx.Gas()
+		txs = append(txs, tx)
+		sorted.Shift()
+	}
+	return txs
+}
+
+// prefetch executes the transactions on top of the head, discarding the results.
+// The only goal is to pull the accessed accounts, storage slots and trie nodes
+// into the caches.
+func (p *TxPrefetcher) prefetch(head *types.Header, txs types.Transactions, interrupt *uint32) {
+	start := time.Now()
+
+	statedb, err := p.bc.StateAt(head.Root)
+	if err != nil {
+		log.Debug("Failed to open state for prefetching", "number", head.Number, "err", err)
+		return
+	}
+	var (
+		config = p.bc.Config()
+		header = &types.Header{
+			ParentHash: head.Hash(),
+			Number:     new(big.Int).Add(head.Number, common.Big1),
+			GasLimit:   head.GasLimit,
+			Time:       head.Time + 1,
+			Coinbase:   head.Coinbase,
+			Difficulty: head.Difficulty,
+		}
+		signer  = types.MakeSigner(config, header.Number)
+		gaspool = new(GasPool).AddGas(header.GasLimit)
+		evm     = vm.NewEVM(NewEVMBlockContext(header, p.bc, &header.Coinbase), vm.TxContext{}, statedb, config, *p.bc.GetVMConfig())
+	)
+	statedb.StartPrefetcher("txpool")
+	defer statedb.StopPrefetcher()
+
+	var done int64
+	for i, tx := range txs {
+		if atomic.LoadUint32(interrupt) == 1 {
+			break
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			continue
+		}
+		statedb.Prepare(tx.Hash(), header.Hash(), i)
+		precacheTransaction(msg, config, gaspool, statedb, header, evm)
+
+		// Hand the touched accounts to the trie prefetcher
+		statedb.Finalise(true)
+		done++
+	}
+	txPrefetchTxsMeter.Mark(done)
+	txPrefetchTimer.UpdateSince(start)
+}
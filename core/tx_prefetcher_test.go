@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the prefetcher picks the pending transactions fitting into the
+// next block and prefetches them on every new head.
+func TestTxPrefetcher(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(params.Ether)}},
+		}
+	)
+	gspec.MustCommit(db)
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	pool := NewTxPool(testTxPoolConfig, gspec.Config, chain)
+	defer pool.Stop()
+
+	var txs types.Transactions
+	for i := uint64(0); i < 5; i++ {
+		txs = append(txs, transaction(i, params.TxGas, key))
+	}
+	if errs := pool.AddLocals(txs); errs[0] != nil {
+		t.Fatalf("failed to add transactions: %v", errs[0])
+	}
+	prefetcher := NewTxPrefetcher(chain, pool)
+	defer prefetcher.Stop()
+
+	// Only the transactions fitting into the next block should be picked
+	head := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), GasLimit: 3*params.TxGas + 1})
+	if picked := prefetcher.pending(head); len(picked) != 3 {
+		t.Fatalf("picked transaction count mismatch: have %d, want %d", len(picked), 3)
+	}
+	// A new head should trigger prefetching the pending transactions
+	gendb := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 1, func(i int, b *BlockGen) {
+		b.AddTx(txs[0])
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	prefetcher.Stop()
+
+	if len(prefetcher.prefetched) != len(txs)-1 {
+		t.Fatalf("prefetched transaction count mismatch: have %d, want %d", len(prefetcher.prefetched), len(txs)-1)
+	}
+	if _, ok := prefetcher.prefetched[txs[0].Hash()]; ok {
+		t.Fatalf("included transaction prefetched")
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// ErrSenderQuota is returned if a remote transaction would exceed the number
+	// of transactions its sender is allowed to have in the pool.
+	ErrSenderQuota = errors.New("sender quota exceeded")
+
+	// ErrOriginQuota is returned if a remote transaction would exceed the number
+	// of transactions the peer it was received from is allowed to have in the pool.
+	ErrOriginQuota = errors.New("origin quota exceeded")
+)
+
+var (
+	senderQuotaMeter = metrics.NewRegisteredMeter("txpool/quota/sender", nil)
+	originQuotaMeter = metrics.NewRegisteredMeter("txpool/quota/origin", nil)
+)
+
+// txQuota caps the number of pending and queued transactions a single remote
+// sender, or a single network origin, may occupy in the pool. Without it, one
+// spammer filling the pool evicts the cheaper transactions of everyone else.
+//
+// Local transactions and replacements of already pooled ones are never limited,
+// neither are the senders and origins on the allowlist (e.g. known relayers).
+type txQuota struct {
+	pool *TxPool
+
+	senderQuota uint64
+	originQuota uint64
+
+	exemptSenders map[common.Address]struct{}
+	exemptOrigins map[string]struct{}
+}
+
+// newTxQuota creates the quota policy of a pool from its configuration.
+func newTxQuota(pool *TxPool, config TxPoolConfig) *txQuota {
+	quota := &txQuota{
+		pool:          pool,
+		senderQuota:   config.SenderQuota,
+		originQuota:   config.OriginQuota,
+		exemptSenders: make(map[common.Address]struct{}),
+		exemptOrigins: make(map[string]struct{}),
+	}
+	for _, addr := range config.QuotaExemptSenders {
+		quota.exemptSenders[addr] = struct{}{}
+	}
+	for _, origin := range config.QuotaExemptOrigins {
+		quota.exemptOrigins[origin] = struct{}{}
+	}
+	return quota
+}
+
+// check returns an error if accepting the transaction would exceed the quota of
+// its sender or of the origin it was received from.
+//
+// Note, this method assumes the pool lock is held!
+func (q *txQuota) check(tx *types.Transaction, origin string, local bool) error {
+	if q.senderQuota == 0 && q.originQuota == 0 {
+		return nil
+	}
+	pool := q.pool
+	if local || pool.all.Get(tx.Hash()) != nil {
+		return nil
+	}
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil || pool.locals.contains(from) {
+		return nil // The pool itself rejects invalid senders
+	}
+	if _, ok := q.exemptSenders[from]; ok {
+		return nil
+	}
+	// Replacing a transaction doesn't take up any more room
+	var count uint64
+	if list := pool.pending[from]; list != nil {
+		if list.Overlaps(tx) {
+			return nil
+		}
+		count += uint64(list.Len())
+	}
+	if list := pool.queue[from]; list != nil {
+		if list.Overlaps(tx) {
+			return nil
+		}
+		count += uint64(list.Len())
+	}
+	if q.senderQuota > 0 && count >= q.senderQuota {
+		senderQuotaMeter.Mark(1)
+		return ErrSenderQuota
+	}
+	if q.originQuota > 0 && origin != "" {
+		if _, ok := q.exemptOrigins[origin]; !ok && uint64(pool.all.OriginCount(origin)) >= q.originQuota {
+			originQuotaMeter.Mark(1)
+			return ErrOriginQuota
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrInflightTxLimitReached is returned if a delegated account already has a
+	// transaction in the pool. The code of a delegated account can move its funds
+	// at any time, so the pool can't reason about its future nonces and balances.
+	ErrInflightTxLimitReached = errors.New("in-flight transaction limit reached for delegated accounts")
+
+	// ErrAuthorityReserved is returned if a set-code transaction carries an
+	// authorization of an account having transactions in the pool, which would
+	// be invalidated by the nonce bump of the authorization.
+	ErrAuthorityReserved = errors.New("authority already reserved")
+)
+
+// validateSetCodeTx checks a set-code transaction against the pool rules. It's
+// a no-op for any other transaction type.
+func (pool *TxPool) validateSetCodeTx(tx *types.Transaction) error {
+	if tx.Type() != types.SetCodeTxType {
+		return nil
+	}
+	if len(tx.SetCodeAuthorizations()) == 0 {
+		return ErrEmptyAuthList
+	}
+	return nil
+}
+
+// checkDelegations checks that a transaction doesn't conflict with the code
+// delegations present in the state or carried by the transaction itself:
+//
+//   - delegated senders are limited to a single in-flight transaction
+//   - authorities of a set-code transaction must not have transactions in the
+//     pool, unless the authority is the sender itself
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkDelegations(from common.Address, tx *types.Transaction) error {
+	if !pool.eip7702 {
+		return nil
+	}
+	if _, ok := types.ParseDelegation(pool.currentState.GetCode(from)); ok {
+		if count := pool.accountTxCount(from); count > 1 || (count == 1 && !pool.hasNonce(from, tx.Nonce())) {
+			return ErrInflightTxLimitReached
+		}
+	}
+	for _, auth := range tx.SetCodeAuthorizations() {
+		authority, err := auth.Authority()
+		if err != nil || authority == from {
+			continue
+		}
+		if pool.accountTxCount(authority) > 0 {
+			return ErrAuthorityReserved
+		}
+	}
+	return nil
+}
+
+// accountTxCount returns the number of pending and queued transactions of an
+// account.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) accountTxCount(addr common.Address) int {
+	var count int
+	if list := pool.pending[addr]; list != nil {
+		count += list.Len()
+	}
+	if list := pool.queue[addr]; list != nil {
+		count += list.Len()
+	}
+	return count
+}
+
+// hasNonce reports whether the pool holds a transaction of an account with the
+// given nonce.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) hasNonce(addr common.Address, nonce uint64) bool {
+	if list := pool.pending[addr]; list != nil && list.txs.Get(nonce) != nil {
+		return true
+	}
+	if list := pool.queue[addr]; list != nil && list.txs.Get(nonce) != nil {
+		return true
+	}
+	return false
+}
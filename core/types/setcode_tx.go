@@ -0,0 +1,218 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DelegationPrefix is used by code to denote the account is delegating to
+// another account.
+var DelegationPrefix = []byte{0xef, 0x01, 0x00}
+
+// ParseDelegation tries to parse the address from a delegation slice.
+func ParseDelegation(b []byte) (common.Address, bool) {
+	if len(b) != 23 || !bytes.HasPrefix(b, DelegationPrefix) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(b[len(DelegationPrefix):]), true
+}
+
+// AddressToDelegation adds the delegation prefix to the specified address.
+func AddressToDelegation(addr common.Address) []byte {
+	return append(common.CopyBytes(DelegationPrefix), addr.Bytes()...)
+}
+
+// SetCodeTx implements the EIP-7702 transaction type which temporarily installs
+// the code at the signer's address. As the chain has no EIP-1559 fee market, the
+// gas is paid at a plain gas price like the other typed transactions.
+type SetCodeTx struct {
+	ChainID    *big.Int               // destination chain ID
+	Nonce      uint64                 // nonce of sender account
+	GasPrice   *big.Int               // wei per gas
+	Gas        uint64                 // gas limit
+	To         common.Address         // set-code transactions can't create contracts
+	Value      *big.Int               // wei amount
+	Data       []byte                 // contract invocation input data
+	AccessList AccessList             // EIP-2930 access list
+	AuthList   []SetCodeAuthorization // authorizations to delegate the code of the authorities
+	V, R, S    *big.Int               // signature values
+}
+
+// SetCodeAuthorization is an authorization from an account to deploy code at
+// its address.
+type SetCodeAuthorization struct {
+	ChainID *big.Int       // chain the authorization is valid on, zero for any
+	Address common.Address // address to delegate the code of the authority to
+	Nonce   uint64         // nonce of the authority when the authorization is applied
+	V       uint8          // signature values
+	R       *big.Int
+	S       *big.Int
+}
+
+// authorizationJSON is the JSON representation of a SetCodeAuthorization.
+type authorizationJSON struct {
+	ChainID *hexutil.Big   `json:"chainId"`
+	Address common.Address `json:"address"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+	V       hexutil.Uint64 `json:"yParity"`
+	R       *hexutil.Big   `json:"r"`
+	S       *hexutil.Big   `json:"s"`
+}
+
+// MarshalJSON marshals as JSON.
+func (a SetCodeAuthorization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&authorizationJSON{
+		ChainID: (*hexutil.Big)(a.ChainID),
+		Address: a.Address,
+		Nonce:   hexutil.Uint64(a.Nonce),
+		V:       hexutil.Uint64(a.V),
+		R:       (*hexutil.Big)(a.R),
+		S:       (*hexutil.Big)(a.S),
+	})
+}
+
+// UnmarshalJSON unmarshals from JSON.
+func (a *SetCodeAuthorization) UnmarshalJSON(input []byte) error {
+	var dec authorizationJSON
+	if err := json.Unmarshal(input, &dec); err != nil {
+		return err
+	}
+	if dec.ChainID == nil {
+		return errors.New("missing required field 'chainId' for SetCodeAuthorization")
+	}
+	if dec.R == nil {
+		return errors.New("missing required field 'r' for SetCodeAuthorization")
+	}
+	if dec.S == nil {
+		return errors.New("missing required field 's' for SetCodeAuthorization")
+	}
+	if dec.V > 1 {
+		return errors.New("invalid field 'yParity' for SetCodeAuthorization")
+	}
+	a.ChainID = (*big.Int)(dec.ChainID)
+	a.Address = dec.Address
+	a.Nonce = uint64(dec.Nonce)
+	a.V = uint8(dec.V)
+	a.R = (*big.Int)(dec.R)
+	a.S = (*big.Int)(dec.S)
+	return nil
+}
+
+// SignSetCode signs a SetCode authorization with the given private key.
+func SignSetCode(prv *ecdsa.PrivateKey, auth SetCodeAuthorization) (SetCodeAuthorization, error) {
+	h := auth.sigHash()
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return SetCodeAuthorization{}, err
+	}
+	r, s, _ := decodeSignature(sig)
+	return SetCodeAuthorization{
+		ChainID: auth.ChainID,
+		Address: auth.Address,
+		Nonce:   auth.Nonce,
+		V:       sig[64],
+		R:       r,
+		S:       s,
+	}, nil
+}
+
+// sigHash returns the hash signed by the authority, keccak256(0x05 || rlp([chain_id, address, nonce])).
+func (a *SetCodeAuthorization) sigHash() common.Hash {
+	return prefixedRlpHash(0x05, []interface{}{
+		a.ChainID,
+		a.Address,
+		a.Nonce,
+	})
+}
+
+// Authority recovers the authorizing account of an authorization.
+func (a *SetCodeAuthorization) Authority() (common.Address, error) {
+	if a.R == nil || a.S == nil {
+		return common.Address{}, ErrInvalidSig
+	}
+	return recoverPlain(a.sigHash(), a.R, a.S, new(big.Int).SetUint64(uint64(a.V)+27), true)
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *SetCodeTx) copy() TxData {
+	cpy := &SetCodeTx{
+		Nonce: tx.Nonce,
+		To:    tx.To,
+		Data:  common.CopyBytes(tx.Data),
+		Gas:   tx.Gas,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		AuthList:   make([]SetCodeAuthorization, len(tx.AuthList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasPrice:   new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	copy(cpy.AuthList, tx.AuthList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasPrice != nil {
+		cpy.GasPrice.Set(tx.GasPrice)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+
+func (tx *SetCodeTx) txType() byte           { return SetCodeTxType }
+func (tx *SetCodeTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *SetCodeTx) protected() bool        { return true }
+func (tx *SetCodeTx) accessList() AccessList { return tx.AccessList }
+func (tx *SetCodeTx) data() []byte           { return tx.Data }
+func (tx *SetCodeTx) gas() uint64            { return tx.Gas }
+func (tx *SetCodeTx) gasPrice() *big.Int     { return tx.GasPrice }
+func (tx *SetCodeTx) value() *big.Int        { return tx.Value }
+func (tx *SetCodeTx) nonce() uint64          { return tx.Nonce }
+func (tx *SetCodeTx) to() *common.Address    { return &tx.To }
+
+func (tx *SetCodeTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *SetCodeTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
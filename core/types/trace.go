@@ -0,0 +1,116 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The kinds of call traces.
+const (
+	CallTraceCall    = "call"
+	CallTraceCreate  = "create"
+	CallTraceSuicide = "suicide"
+)
+
+// CallTrace is a single call frame of a transaction. The frames of a transaction
+// are stored flattened, in the order they were entered, each one locating itself
+// in the call tree by its trace address.
+type CallTrace struct {
+	Type         string         // Kind of the frame: call, create or suicide
+	CallType     string         // Opcode of call frames, e.g. call or delegatecall
+	From         common.Address // Caller, or the self destructed contract
+	To           common.Address // Callee, created contract or the beneficiary of a self destruct
+	Value        *big.Int       // Value transferred by the frame
+	Gas          uint64         // Gas made available to the frame
+	GasUsed      uint64         // Gas used by the frame
+	Input        []byte         // Call data or contract init code
+	Output       []byte         // Returned data or the deployed contract code
+	Error        string         // Failure of the frame, empty if it succeeded
+	TraceAddress []uint64       // Position of the frame in the call tree
+	Subtraces    uint64         // Number of frames directly entered from this one
+}
+
+// StorageDiff is a storage slot changed by a transaction.
+type StorageDiff struct {
+	Key  common.Hash
+	From common.Hash
+	To   common.Hash
+}
+
+// AccountDiff is an account touched by a transaction, with its state before and
+// after the execution.
+type AccountDiff struct {
+	Address     common.Address
+	Existed     bool // Whether the account existed before the transaction
+	Exists      bool // Whether the account exists after the transaction
+	BalanceFrom *big.Int
+	BalanceTo   *big.Int
+	NonceFrom   uint64
+	NonceTo     uint64
+	CodeFrom    []byte
+	CodeTo      []byte
+	Storage     []StorageDiff
+}
+
+// TxTraces are the call traces and the state diff of a transaction.
+type TxTraces struct {
+	Calls     []CallTrace
+	StateDiff []AccountDiff
+}
+
+// The kinds of reward traces.
+const (
+	RewardTraceBlock = "block"
+	RewardTraceUncle = "uncle"
+)
+
+// RewardTrace is a reward credited to an account by the consensus engine when
+// finalizing a block.
+type RewardTrace struct {
+	Author common.Address
+	Value  *big.Int
+	Type   string // Kind of the reward: block or uncle
+}
+
+// BlockTraces are the traces recorded while importing a block: the traces of
+// each transaction, by transaction index, and the rewards of the block.
+type BlockTraces struct {
+	Txs     []TxTraces
+	Rewards []RewardTrace
+}
+
+// RevertTrace is the failure of a transaction, along with the call frame the
+// failure originated in. A revert bubbling up unchanged through the callers is
+// attributed to the innermost frame raising it.
+type RevertTrace struct {
+	TxIndex uint64
+	Error   string         // Failure of the originating frame
+	Output  []byte         // Data returned by the transaction, the revert reason if any
+	Address common.Address // Contract executed by the originating frame
+	Depth   uint64         // Depth of the originating frame, 0 for the top level one
+	Op      string         // Opcode failing the originating frame, empty if none was run
+	Pc      uint64         // Program counter of the failing opcode
+}
+
+// BlockReverts are the failures of the transactions of a block recorded while
+// importing it, in transaction order.
+type BlockReverts struct {
+	Txs []RevertTrace
+}
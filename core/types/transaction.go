@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -35,6 +36,8 @@ var (
 	ErrUnexpectedProtection = errors.New("transaction type does not supported EIP-155 protected signatures")
 	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
+	ErrMissingBlobHashes    = errors.New("blob transaction without blob hashes")
+	ErrInvalidBlobHash      = errors.New("blob hash with invalid version")
 	errEmptyTypedTx         = errors.New("empty typed transaction bytes")
 )
 
@@ -42,6 +45,8 @@ var (
 const (
 	LegacyTxType = iota
 	AccessListTxType
+	BlobTxType    = 0x03
+	SetCodeTxType = 0x04
 )
 
 // Transaction is an Ethereum transaction.
@@ -64,7 +69,7 @@ func NewTx(inner TxData) *Transaction {
 
 // TxData is the underlying data of a transaction.
 //
-// This is implemented by LegacyTx and AccessListTx.
+// This is implemented by LegacyTx, AccessListTx, BlobTx and SetCodeTx.
 type TxData interface {
 	txType() byte // returns the type ID
 	copy() TxData // creates a deep copy and initializes all fields
@@ -182,6 +187,14 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner AccessListTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case BlobTxType:
+		var inner BlobTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case SetCodeTxType:
+		var inner SetCodeTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -286,10 +299,61 @@ func (tx *Transaction) To() *common.Address {
 	return &cpy
 }
 
-// Cost returns gas * gasPrice + value.
+// BlobHashes returns the versioned hashes of the blobs carried by the transaction,
+// nil for any other than blob transactions.
+func (tx *Transaction) BlobHashes() []common.Hash {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return blobtx.BlobHashes
+	}
+	return nil
+}
+
+// BlobGas returns the blob gas consumed by the transaction.
+func (tx *Transaction) BlobGas() uint64 {
+	return uint64(len(tx.BlobHashes())) * params.BlobTxBlobGasPerBlob
+}
+
+// BlobGasFeeCap returns the maximum price per blob gas of the transaction, nil
+// for any other than blob transactions.
+func (tx *Transaction) BlobGasFeeCap() *big.Int {
+	if blobtx, ok := tx.inner.(*BlobTx); ok {
+		return new(big.Int).Set(blobtx.BlobFeeCap)
+	}
+	return nil
+}
+
+// ValidateBlobHashes checks that a blob transaction carries at least one blob
+// and that all the blob hashes have a known version.
+func (tx *Transaction) ValidateBlobHashes() error {
+	hashes := tx.BlobHashes()
+	if len(hashes) == 0 {
+		return ErrMissingBlobHashes
+	}
+	for _, hash := range hashes {
+		if hash[0] != params.BlobTxHashVersion {
+			return ErrInvalidBlobHash
+		}
+	}
+	return nil
+}
+
+// SetCodeAuthorizations returns the authorizations list of the transaction, nil
+// for any other than set-code transactions.
+func (tx *Transaction) SetCodeAuthorizations() []SetCodeAuthorization {
+	if setcodetx, ok := tx.inner.(*SetCodeTx); ok {
+		return setcodetx.AuthList
+	}
+	return nil
+}
+
+// Cost returns gas * gasPrice + value, plus blobGas * blobGasFeeCap for blob
+// transactions.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
 	total.Add(total, tx.Value())
+	if tx.Type() == BlobTxType {
+		total.Add(total, new(big.Int).Mul(tx.BlobGasFeeCap(), new(big.Int).SetUint64(tx.BlobGas())))
+	}
 	return total
 }
 
@@ -501,6 +565,10 @@ type Message struct {
 	data       []byte
 	accessList AccessList
 	checkNonce bool
+
+	blobHashes []common.Hash
+	blobFeeCap *big.Int
+	authList   []SetCodeAuthorization
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, accessList AccessList, checkNonce bool) Message {
@@ -517,6 +585,13 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 	}
 }
 
+// WithSetCodeAuthorizations returns a copy of the message carrying the given
+// EIP-7702 authorizations.
+func (m Message) WithSetCodeAuthorizations(authList []SetCodeAuthorization) Message {
+	m.authList = authList
+	return m
+}
+
 // AsMessage returns the transaction as a core.Message.
 func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 	msg := Message{
@@ -528,6 +603,9 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		data:       tx.Data(),
 		accessList: tx.AccessList(),
 		checkNonce: true,
+		blobHashes: tx.BlobHashes(),
+		blobFeeCap: tx.BlobGasFeeCap(),
+		authList:   tx.SetCodeAuthorizations(),
 	}
 
 	var err error
@@ -544,3 +622,8 @@ func (m Message) Nonce() uint64          { return m.nonce }
 func (m Message) Data() []byte           { return m.data }
 func (m Message) AccessList() AccessList { return m.accessList }
 func (m Message) CheckNonce() bool       { return m.checkNonce }
+
+func (m Message) BlobHashes() []common.Hash { return m.blobHashes }
+func (m Message) BlobGasFeeCap() *big.Int   { return m.blobFeeCap }
+
+func (m Message) SetCodeAuthorizations() []SetCodeAuthorization { return m.authList }
@@ -441,6 +441,60 @@ func TestTransactionCoding(t *testing.T) {
 	}
 }
 
+// Tests that set-code transactions and their authorizations survive the RLP and
+// JSON encodings, and that senders and authorities are recovered.
+func TestSetCodeTransactionCoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	authKey, _ := crypto.GenerateKey()
+
+	var (
+		signer    = NewPragueSigner(common.Big1)
+		recipient = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	)
+	auth, err := SignSetCode(authKey, SetCodeAuthorization{
+		ChainID: big.NewInt(1),
+		Address: common.HexToAddress("0x000000000000000000000000000000000000aaaa"),
+		Nonce:   3,
+	})
+	if err != nil {
+		t.Fatalf("could not sign authorization: %v", err)
+	}
+	tx, err := SignNewTx(key, signer, &SetCodeTx{
+		ChainID:  big.NewInt(1),
+		Nonce:    1,
+		To:       recipient,
+		Gas:      123457,
+		GasPrice: big.NewInt(10),
+		AuthList: []SetCodeAuthorization{auth},
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	for _, coding := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsedTx, err := coding(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := assertEqual(parsedTx, tx); err != nil {
+			t.Fatal(err)
+		}
+		if from, err := Sender(signer, parsedTx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Fatalf("sender mismatch: have %x, want %x, err %v", from, crypto.PubkeyToAddress(key.PublicKey), err)
+		}
+		auths := parsedTx.SetCodeAuthorizations()
+		if len(auths) != 1 {
+			t.Fatalf("authorization count mismatch: have %d, want 1", len(auths))
+		}
+		if authority, err := auths[0].Authority(); err != nil || authority != crypto.PubkeyToAddress(authKey.PublicKey) {
+			t.Fatalf("authority mismatch: have %x, want %x, err %v", authority, crypto.PubkeyToAddress(authKey.PublicKey), err)
+		}
+	}
+	// Older signers don't know about set-code transactions
+	if _, err := Sender(NewCancunSigner(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("cancun signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
 func encodeDecodeJSON(tx *Transaction) (*Transaction, error) {
 	data, err := json.Marshal(tx)
 	if err != nil {
@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	BLSPublicKeyLength = 48
+	BLSSignatureLength = 96
+)
+
+// BLSPublicKey is the compressed BLS12-381 public key of a voting validator.
+type BLSPublicKey [BLSPublicKeyLength]byte
+
+// BLSSignature is the compressed BLS12-381 signature of a vote.
+type BLSSignature [BLSSignatureLength]byte
+
+// Bytes returns the raw bytes of the public key.
+func (p BLSPublicKey) Bytes() []byte { return p[:] }
+
+// MarshalText implements encoding.TextMarshaler.
+func (p BLSPublicKey) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(p[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *BLSPublicKey) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("BLSPublicKey", input, p[:])
+}
+
+// Bytes returns the raw bytes of the signature.
+func (s BLSSignature) Bytes() []byte { return s[:] }
+
+// MarshalText implements encoding.TextMarshaler.
+func (s BLSSignature) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(s[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *BLSSignature) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("BLSSignature", input, s[:])
+}
+
+// VoteData represents the vote range that a validator votes for.
+type VoteData struct {
+	SourceNumber uint64      // The source block number should be the latest justified block number.
+	SourceHash   common.Hash // The block hash of the source block.
+	TargetNumber uint64      // The target block number which validator wants to vote for.
+	TargetHash   common.Hash // The block hash of the target block.
+}
+
+// Hash returns the hash of the vote data, which is also the signing root.
+func (d *VoteData) Hash() common.Hash { return rlpHash(d) }
+
+// VoteEnvelope represents the vote of a single validator.
+type VoteEnvelope struct {
+	VoteAddress BLSPublicKey // The BLS public key of the validator.
+	Signature   BLSSignature // Validator's signature for the vote data.
+	Data        *VoteData    // The vote data for fast finality.
+
+	// caches
+	hash atomic.Value
+}
+
+// Hash returns the vote's hash.
+func (v *VoteEnvelope) Hash() common.Hash {
+	if hash := v.hash.Load(); hash != nil {
+		return hash.(common.Hash)
+	}
+	h := v.calcVoteHash()
+	v.hash.Store(h)
+	return h
+}
+
+func (v *VoteEnvelope) calcVoteHash() common.Hash {
+	vote := struct {
+		VoteAddress BLSPublicKey
+		Signature   BLSSignature
+		Data        *VoteData
+	}{v.VoteAddress, v.Signature, v.Data}
+	return rlpHash(vote)
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 // accessList is an accumulator for the set of accounts and storage slots an EVM
@@ -105,11 +106,32 @@ func (al accessList) accessList() types.AccessList {
 	return acl
 }
 
+// SlotAccess describes how an execution accessed a storage slot.
+type SlotAccess struct {
+	Read       bool // Whether the slot was loaded
+	Written    bool // Whether the slot was stored
+	FirstWrite bool // Whether the slot was stored before ever being loaded
+}
+
+// GasSaved returns the gas saved by having the slot in the access list, that is
+// the cold access surcharge of its first access minus the cost of the entry.
+func (s SlotAccess) GasSaved() uint64 {
+	switch {
+	case s.FirstWrite:
+		return ColdSloadCostEIP2929 - params.TxAccessListStorageKeyGas
+	case s.Read:
+		return ColdSloadCostEIP2929 - WarmStorageReadCostEIP2929 - params.TxAccessListStorageKeyGas
+	default:
+		return 0
+	}
+}
+
 // AccessListTracer is a tracer that accumulates touched accounts and storage
 // slots into an internal set.
 type AccessListTracer struct {
-	excl map[common.Address]struct{} // Set of account to exclude from the list
-	list accessList                  // Set of accounts and storage slots touched
+	excl  map[common.Address]struct{}                   // Set of account to exclude from the list
+	list  accessList                                    // Set of accounts and storage slots touched
+	slots map[common.Address]map[common.Hash]SlotAccess // How the touched storage slots were accessed
 }
 
 // NewAccessListTracer creates a new tracer that can generate AccessLists.
@@ -132,8 +154,9 @@ func NewAccessListTracer(acl types.AccessList, from, to common.Address, precompi
 		}
 	}
 	return &AccessListTracer{
-		excl: excl,
-		list: list,
+		excl:  excl,
+		list:  list,
+		slots: make(map[common.Address]map[common.Hash]SlotAccess),
 	}
 }
 
@@ -146,6 +169,7 @@ func (a *AccessListTracer) CaptureState(pc uint64, op OpCode, gas, cost uint64,
 	if (op == SLOAD || op == SSTORE) && stack.len() >= 1 {
 		slot := common.Hash(stack.data[stack.len()-1].Bytes32())
 		a.list.addSlot(scope.Contract.Address(), slot)
+		a.addSlotAccess(scope.Contract.Address(), slot, op == SSTORE)
 	}
 	if (op == EXTCODECOPY || op == EXTCODEHASH || op == EXTCODESIZE || op == BALANCE || op == SELFDESTRUCT) && stack.len() >= 1 {
 		addr := common.Address(stack.data[stack.len()-1].Bytes20())
@@ -161,6 +185,23 @@ func (a *AccessListTracer) CaptureState(pc uint64, op OpCode, gas, cost uint64,
 	}
 }
 
+// addSlotAccess records a load or a store of a storage slot.
+func (a *AccessListTracer) addSlotAccess(address common.Address, slot common.Hash, write bool) {
+	slots, ok := a.slots[address]
+	if !ok {
+		slots = make(map[common.Hash]SlotAccess)
+		a.slots[address] = slots
+	}
+	access, seen := slots[slot]
+	if write {
+		access.Written = true
+		access.FirstWrite = access.FirstWrite || !seen
+	} else {
+		access.Read = true
+	}
+	slots[slot] = access
+}
+
 func (*AccessListTracer) CaptureFault(pc uint64, op OpCode, gas, cost uint64, scope *ScopeContext, depth int, err error) {
 }
 
@@ -171,11 +212,20 @@ func (*AccessListTracer) CaptureEnter(typ OpCode, from common.Address, to common
 
 func (*AccessListTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
 
+func (*AccessListTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (*AccessListTracer) CaptureTxEnd(restGas uint64) {}
+
 // AccessList returns the current accesslist maintained by the tracer.
 func (a *AccessListTracer) AccessList() types.AccessList {
 	return a.list.accessList()
 }
 
+// SlotAccess returns how the execution accessed the given storage slot.
+func (a *AccessListTracer) SlotAccess(address common.Address, slot common.Hash) SlotAccess {
+	return a.slots[address][slot]
+}
+
 // Equal returns if the content of two access list traces are equal.
 func (a *AccessListTracer) Equal(other *AccessListTracer) bool {
 	return a.list.equal(other.list)
@@ -0,0 +1,68 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the access list tracer classifies the storage slot accesses and
+// the gas saved by listing them.
+func TestAccessListTracerSlotAccess(t *testing.T) {
+	address := common.BytesToAddress([]byte("contract"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	// SLOAD(1), SSTORE(2, 1), SSTORE(3, SLOAD(3))
+	statedb.SetCode(address, hexutil.MustDecode("0x600154506001600255600354600355"))
+
+	tracer := NewAccessListTracer(nil, common.Address{}, address, nil)
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	vmenv := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{Debug: true, Tracer: tracer})
+	if _, _, err := vmenv.Call(AccountRef(common.Address{}), address, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("failed to execute contract: %v", err)
+	}
+	tests := []struct {
+		slot   byte
+		access SlotAccess
+		saved  uint64
+	}{
+		{1, SlotAccess{Read: true}, 100},
+		{2, SlotAccess{Written: true, FirstWrite: true}, 200},
+		{3, SlotAccess{Read: true, Written: true}, 100},
+		{4, SlotAccess{}, 0},
+	}
+	for _, tt := range tests {
+		access := tracer.SlotAccess(address, common.BytesToHash([]byte{tt.slot}))
+		if access != tt.access {
+			t.Errorf("slot %d: access mismatch: have %+v, want %+v", tt.slot, access, tt.access)
+		}
+		if saved := access.GasSaved(); saved != tt.saved {
+			t.Errorf("slot %d: gas saved mismatch: have %d, want %d", tt.slot, saved, tt.saved)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ethereum/go-ethereum/common"
+	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const compiledCodeCacheSize = 1024
+
+var (
+	compiledCodeCache, _ = lru.New(compiledCodeCacheSize)
+
+	compiledCodeHitMeter  = metrics.NewRegisteredMeter("vm/compiled/code/hit", nil)
+	compiledCodeMissMeter = metrics.NewRegisteredMeter("vm/compiled/code/miss", nil)
+)
+
+// compiledKey identifies a compiled code, the blocks depending on the instruction
+// set too.
+type compiledKey struct {
+	hash common.Hash
+	stop *operation // STOP operation of the instruction set, unique to each
+}
+
+// compiledCode is a contract code split into blocks of opcodes which can't fail
+// once their stack requirements and gas are checked for.
+type compiledCode struct {
+	blocks []codeBlock
+	starts []uint16 // Index+1 of the block starting at each position, 0 if none
+}
+
+// codeBlock is a run of opcodes with constant gas costs, no memory expansion and
+// no control flow, checked and charged for at once.
+type codeBlock struct {
+	gas      uint64 // Constant gas of the opcodes
+	minStack int    // Stack items needed by the opcodes
+	maxStack int    // Stack items allowed for the opcodes not to overflow
+	end      uint64 // Position following the last opcode
+}
+
+// compilable returns whether the opcode can be part of a code block.
+func compilable(op OpCode, operation *operation) bool {
+	if operation == nil || operation.dynamicGas != nil || operation.memorySize != nil {
+		return false
+	}
+	if operation.writes || operation.returns || operation.reverts || operation.halts || operation.jumps {
+		return false
+	}
+	// GAS reads the gas left, which would include the rest of its block
+	return op != GAS
+}
+
+// compile splits the code into blocks of at least two opcodes. Blocks start after
+// any opcode which can't be part of them, and at every JUMPDEST, so all jumps and
+// returns from calls land at the start of a block.
+func compile(code []byte, jt *JumpTable) *compiledCode {
+	compiled := &compiledCode{starts: make([]uint16, len(code))}
+	for pc := uint64(0); pc < uint64(len(code)); {
+		var (
+			block  = codeBlock{maxStack: int(params.StackLimit)}
+			next   = pc
+			height int // Stack height relative to the start of the block
+			ops    int
+		)
+		for next < uint64(len(code)) {
+			op := OpCode(code[next])
+			operation := jt[op]
+			if !compilable(op, operation) || (op == JUMPDEST && ops > 0) {
+				break
+			}
+			if need := operation.minStack - height; need > block.minStack {
+				block.minStack = need
+			}
+			if allow := operation.maxStack - height; allow < block.maxStack {
+				block.maxStack = allow
+			}
+			height += int(params.StackLimit) - operation.maxStack
+			block.gas += operation.constantGas
+			ops++
+
+			next++
+			if op.IsPush() {
+				next += uint64(op - PUSH1 + 1)
+			}
+		}
+		switch {
+		case ops == 0:
+			// Skip the opcode not fitting in a block, it's never a PUSH
+			pc++
+			continue
+		case ops > 1 && len(compiled.blocks) < math.MaxUint16:
+			block.end = next
+			compiled.blocks = append(compiled.blocks, block)
+			compiled.starts[pc] = uint16(len(compiled.blocks))
+		}
+		pc = next
+	}
+	return compiled
+}
+
+// compiled returns the code of the contract compiled, cached by code hash if the
+// code has one and the instruction set is a standard one.
+func (in *EVMInterpreter) compiled(contract *Contract) *compiledCode {
+	if contract.CodeHash == (common.Hash{}) || len(in.cfg.ExtraEips) > 0 {
+		return compile(contract.Code, (*JumpTable)(&in.cfg.JumpTable))
+	}
+	key := compiledKey{hash: contract.CodeHash, stop: in.cfg.JumpTable[STOP]}
+	if cached, ok := compiledCodeCache.Get(key); ok {
+		compiledCodeHitMeter.Mark(1)
+		return cached.(*compiledCode)
+	}
+	compiledCodeMissMeter.Mark(1)
+	code := compile(contract.Code, (*JumpTable)(&in.cfg.JumpTable))
+	compiledCodeCache.Add(key, code)
+	return code
+}
+
+// runCompiled executes the contract with the compiled backend. The blocks of the
+// code are run without per opcode checks whenever the stack and gas suffice for
+// the whole block. Otherwise the opcodes are stepped through one by one like the
+// interpreter does, so failures happen at the same opcodes with the same errors.
+func (in *EVMInterpreter) runCompiled(contract *Contract, input []byte) (ret []byte, err error) {
+	var (
+		code  = in.compiled(contract)
+		jt    = &in.cfg.JumpTable
+		mem   = NewMemory()
+		stack = newstack()
+		scope = &ScopeContext{
+			Memory:   mem,
+			Stack:    stack,
+			Contract: contract,
+		}
+		pc  = uint64(0)
+		res []byte
+	)
+	defer returnStack(stack)
+	contract.Input = input
+
+	steps := 0
+	for {
+		steps++
+		if steps%1000 == 0 && atomic.LoadInt32(&in.evm.abort) != 0 {
+			break
+		}
+		if pc < uint64(len(code.starts)) && code.starts[pc] != 0 {
+			block := &code.blocks[code.starts[pc]-1]
+			if sLen := stack.len(); sLen >= block.minStack && sLen <= block.maxStack && contract.Gas >= block.gas {
+				contract.Gas -= block.gas
+				for pc < block.end {
+					if _, err = jt[contract.GetOp(pc)].execute(&pc, in, scope); err != nil {
+						return nil, err
+					}
+					pc++
+				}
+				continue
+			}
+		}
+		op := contract.GetOp(pc)
+		operation := jt[op]
+		if operation == nil {
+			return nil, &ErrInvalidOpCode{opcode: op}
+		}
+		if sLen := stack.len(); sLen < operation.minStack {
+			return nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
+		} else if sLen > operation.maxStack {
+			return nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
+		}
+		if in.readOnly && in.evm.chainRules.IsByzantium {
+			if operation.writes || (op == CALL && stack.Back(2).Sign() != 0) {
+				return nil, ErrWriteProtection
+			}
+		}
+		if !contract.UseGas(operation.constantGas) {
+			return nil, ErrOutOfGas
+		}
+		var memorySize uint64
+		if operation.memorySize != nil {
+			memSize, overflow := operation.memorySize(stack)
+			if overflow {
+				return nil, ErrGasUintOverflow
+			}
+			if memorySize, overflow = cmath.SafeMul(toWordSize(memSize), 32); overflow {
+				return nil, ErrGasUintOverflow
+			}
+		}
+		if operation.dynamicGas != nil {
+			dynamicCost, err := operation.dynamicGas(in.evm, contract, stack, mem, memorySize)
+			if err != nil || !contract.UseGas(dynamicCost) {
+				return nil, ErrOutOfGas
+			}
+		}
+		if memorySize > 0 {
+			mem.Resize(memorySize)
+		}
+		res, err = operation.execute(&pc, in, scope)
+		if operation.returns {
+			in.returnData = res
+		}
+		switch {
+		case err != nil:
+			return nil, err
+		case operation.reverts:
+			return res, ErrExecutionReverted
+		case operation.halts:
+			return res, nil
+		case !operation.jumps:
+			pc++
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCompile(t *testing.T) {
+	code := []byte{
+		byte(PUSH1), 1, byte(PUSH2), 2, 3, byte(ADD), // Block 0-5
+		byte(JUMPDEST), byte(DUP1), byte(POP), // Block 6-8
+		byte(SLOAD),               // Dynamic gas
+		byte(PUSH1), 0, byte(GAS), // Single opcodes
+		byte(JUMPDEST), byte(PUSH1), // Block 13-15 with truncated push data
+	}
+	compiled := compile(code, &berlinInstructionSet)
+
+	want := []codeBlock{
+		{gas: 3 * GasFastestStep, minStack: 0, maxStack: int(params.StackLimit) - 2, end: 6},
+		{gas: params.JumpdestGas + GasFastestStep + GasQuickStep, minStack: 1, maxStack: int(params.StackLimit) - 1, end: 9},
+		{gas: params.JumpdestGas + GasFastestStep, minStack: 0, maxStack: int(params.StackLimit) - 1, end: 16},
+	}
+	if len(compiled.blocks) != len(want) {
+		t.Fatalf("block count mismatch: have %d, want %d", len(compiled.blocks), len(want))
+	}
+	for i, block := range want {
+		if compiled.blocks[i] != block {
+			t.Errorf("block %d mismatch: have %+v, want %+v", i, compiled.blocks[i], block)
+		}
+	}
+	for pc, start := range compiled.starts {
+		want := uint16(0)
+		switch pc {
+		case 0:
+			want = 1
+		case 6:
+			want = 2
+		case 13:
+			want = 3
+		}
+		if start != want {
+			t.Errorf("pc %d: block start mismatch: have %d, want %d", pc, start, want)
+		}
+	}
+}
+
+// compiledTestOps are the opcodes random test programs are made of, the ones not
+// needing a block context.
+var compiledTestOps = []OpCode{
+	STOP, ADD, MUL, SUB, DIV, SDIV, MOD, EXP, LT, GT, EQ, ISZERO, AND, OR, XOR, NOT, BYTE, SHL, SHR, SAR,
+	SHA3, ADDRESS, CALLER, CALLVALUE, CALLDATALOAD, CALLDATASIZE, CODESIZE, CODECOPY, RETURNDATASIZE,
+	POP, MLOAD, MSTORE, MSTORE8, SLOAD, SSTORE, JUMP, JUMPI, PC, MSIZE, GAS, JUMPDEST,
+	PUSH1, PUSH1, PUSH1, PUSH2, PUSH32, DUP1, DUP2, DUP4, SWAP1, SWAP3, RETURN, REVERT, 0xfe,
+}
+
+// randomProgram creates a program of mostly valid opcodes, jumping back and
+// forth between its JUMPDESTs.
+func randomProgram(rng *rand.Rand, size int) []byte {
+	var code []byte
+	for len(code) < size {
+		op := compiledTestOps[rng.Intn(len(compiledTestOps))]
+		switch {
+		case op == JUMP || op == JUMPI:
+			code = append(code, byte(PUSH1), byte(rng.Intn(size)), byte(op))
+		case op.IsPush():
+			code = append(code, byte(op))
+			for i := 0; i < int(op-PUSH1)+1; i++ {
+				code = append(code, byte(rng.Intn(64)))
+			}
+		default:
+			code = append(code, byte(op))
+		}
+	}
+	return code
+}
+
+// runBackend executes the code with the given backend, returning a description
+// of the outcome.
+func runBackend(code []byte, gas uint64, backend Backend) string {
+	address := common.BytesToAddress([]byte("contract"))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.CreateAccount(address)
+	statedb.SetCode(address, code)
+	statedb.Finalise(true)
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+	}
+	vmenv := NewEVM(vmctx, TxContext{}, statedb, params.AllEthashProtocolChanges, Config{Backend: backend})
+
+	ret, left, err := vmenv.Call(AccountRef(common.Address{}), address, []byte{1, 2, 3}, gas, new(big.Int))
+	return fmt.Sprintf("ret %x, gas %d, err %v, root %x", ret, left, err, statedb.IntermediateRoot(true))
+}
+
+func TestCompiledBackend(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		code := randomProgram(rng, 16+rng.Intn(128))
+		for _, gas := range []uint64{20, 100, 1000, 30000} {
+			want := runBackend(code, gas, InterpreterBackend)
+			if have := runBackend(code, gas, CompiledBackend); have != want {
+				t.Fatalf("program %d (%x), gas %d: outcome mismatch:\nhave %s\nwant %s", i, code, gas, have, want)
+			}
+		}
+	}
+}
+
+func TestCompiledBackendLoop(t *testing.T) {
+	// Sums the numbers counting down from 1000, storing the result
+	code := []byte{
+		byte(PUSH2), 0x03, 0xe8, byte(PUSH1), 0, // counter, sum
+		byte(JUMPDEST), // 5
+		byte(DUP2), byte(ADD), byte(SWAP1), byte(PUSH1), 1, byte(SWAP1), byte(SUB), byte(SWAP1),
+		byte(DUP2), byte(PUSH1), 5, byte(JUMPI),
+		byte(PUSH1), 0, byte(MSTORE), byte(PUSH1), 32, byte(PUSH1), 0, byte(RETURN),
+	}
+	for _, gas := range []uint64{1000, 50000, 100000} {
+		want := runBackend(code, gas, InterpreterBackend)
+		if have := runBackend(code, gas, CompiledBackend); have != want {
+			t.Errorf("gas %d: outcome mismatch:\nhave %s\nwant %s", gas, have, want)
+		}
+	}
+	if have := runBackend(code, 100000, CompiledBackend); !bytes.HasPrefix([]byte(have), []byte(fmt.Sprintf("ret %064x", 500500))) {
+		t.Errorf("wrong sum: %s", have)
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	for _, backend := range []Backend{InterpreterBackend, CompiledBackend} {
+		if have, err := ParseBackend(backend.String()); err != nil || have != backend {
+			t.Errorf("backend %v: parse mismatch: have %v, %v", backend, have, err)
+		}
+	}
+	if _, err := ParseBackend("jit"); err == nil {
+		t.Error("unknown backend parsed")
+	}
+}
@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
@@ -106,6 +108,10 @@ var PrecompiledContractsBLS = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{18}): &bls12381MapG2{},
 }
 
+// p256VerifyAddress is the address of the secp256r1 signature verification
+// precompile of RIP-7212, active from the block set in the chain config.
+var p256VerifyAddress = common.BytesToAddress([]byte{1, 0})
+
 var (
 	PrecompiledAddressesBerlin    []common.Address
 	PrecompiledAddressesIstanbul  []common.Address
@@ -130,6 +136,24 @@ func init() {
 
 // ActivePrecompiles returns the precompiles enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
+	standard := standardPrecompiles(rules)
+	if !rules.IsP256Verify && len(rules.Precompiles) == 0 {
+		return standard
+	}
+	active := make([]common.Address, len(standard), len(standard)+1+len(rules.Precompiles))
+	copy(active, standard)
+	if rules.IsP256Verify {
+		active = append(active, p256VerifyAddress)
+	}
+	for addr := range rules.Precompiles {
+		active = append(active, addr)
+	}
+	return active
+}
+
+// standardPrecompiles returns the standard precompiles enabled with the current
+// configuration.
+func standardPrecompiles(rules params.Rules) []common.Address {
 	switch {
 	case rules.IsBerlin:
 		return PrecompiledAddressesBerlin
@@ -1049,3 +1073,35 @@ func (c *bls12381MapG2) Run(input []byte) ([]byte, error) {
 	// Encode the G2 point to 256 bytes
 	return g.EncodePoint(r), nil
 }
+
+// p256Verify implements the secp256r1 (P-256) signature verification precompile
+// of RIP-7212. The input is the message hash, the r and s signature values and
+// the x and y public key coordinates, 32 bytes each. It returns 1 as a 32 byte
+// word if the signature is valid, and nothing otherwise.
+type p256Verify struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *p256Verify) RequiredGas(input []byte) uint64 {
+	return params.P256VerifyGas
+}
+
+func (c *p256Verify) Run(input []byte) ([]byte, error) {
+	if len(input) != 160 {
+		return nil, nil
+	}
+	var (
+		hash = input[:32]
+		r    = new(big.Int).SetBytes(input[32:64])
+		s    = new(big.Int).SetBytes(input[64:96])
+		x    = new(big.Int).SetBytes(input[96:128])
+		y    = new(big.Int).SetBytes(input[128:160])
+	)
+	curve := elliptic.P256()
+	if !curve.IsOnCurve(x, y) {
+		return nil, nil
+	}
+	if !ecdsa.Verify(&ecdsa.PublicKey{Curve: curve, X: x, Y: y}, hash, r, s) {
+		return nil, nil
+	}
+	return common.LeftPadBytes([]byte{1}, 32), nil
+}
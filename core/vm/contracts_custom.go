@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	cmath "github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// customPrecompiles are the implementations of the custom precompiled contracts
+// by name, which chain configs activate at addresses and blocks of their choice.
+var customPrecompiles = make(map[string]PrecompiledContract)
+
+func init() {
+	RegisterPrecompile("bls12381G1Add", &bls12381G1Add{})
+	RegisterPrecompile("bls12381G1Mul", &bls12381G1Mul{})
+	RegisterPrecompile("bls12381G1MultiExp", &bls12381G1MultiExp{})
+	RegisterPrecompile("bls12381G2Add", &bls12381G2Add{})
+	RegisterPrecompile("bls12381G2Mul", &bls12381G2Mul{})
+	RegisterPrecompile("bls12381G2MultiExp", &bls12381G2MultiExp{})
+	RegisterPrecompile("bls12381Pairing", &bls12381Pairing{})
+	RegisterPrecompile("bls12381MapG1", &bls12381MapG1{})
+	RegisterPrecompile("bls12381MapG2", &bls12381MapG2{})
+	RegisterPrecompile("p256Verify", &p256Verify{})
+}
+
+// RegisterPrecompile registers the implementation of a custom precompiled contract
+// under the given name, for chain configs to activate. Registrations have to be
+// done before any EVM runs, usually from init functions.
+func RegisterPrecompile(name string, contract PrecompiledContract) {
+	if _, exist := customPrecompiles[name]; exist {
+		panic(fmt.Sprintf("precompile %q registered twice", name))
+	}
+	customPrecompiles[name] = contract
+}
+
+// CheckPrecompiles checks that the custom precompiled contracts of the chain
+// config are registered, and don't shadow the standard ones.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	for _, precompile := range config.Precompiles {
+		if _, exist := customPrecompiles[precompile.Implementation]; !exist {
+			return fmt.Errorf("precompile at %v: unknown implementation %q", precompile.Address, precompile.Implementation)
+		}
+		if _, exist := PrecompiledContractsBerlin[precompile.Address]; exist {
+			return fmt.Errorf("precompile at %v: address taken by a standard precompile", precompile.Address)
+		}
+		if precompile.Address == p256VerifyAddress && config.P256VerifyBlock != nil {
+			return fmt.Errorf("precompile at %v: address taken by the secp256r1 verification precompile", precompile.Address)
+		}
+	}
+	return nil
+}
+
+// customPrecompile returns the implementation of the custom precompiled contract
+// with the given config, with the gas schedule of the config if any.
+func customPrecompile(config *params.PrecompileConfig) (PrecompiledContract, bool) {
+	if config == nil {
+		return nil, false
+	}
+	contract, exist := customPrecompiles[config.Implementation]
+	if !exist {
+		return nil, false
+	}
+	if config.Gas != nil {
+		return &scheduledPrecompile{PrecompiledContract: contract, gas: config.Gas}, true
+	}
+	return contract, true
+}
+
+// scheduledPrecompile is a precompiled contract charging the gas of a schedule
+// instead of its own.
+type scheduledPrecompile struct {
+	PrecompiledContract
+	gas *params.PrecompileGas
+}
+
+func (c *scheduledPrecompile) RequiredGas(input []byte) uint64 {
+	words := (uint64(len(input)) + 31) / 32
+	gas, overflow := cmath.SafeMul(words, c.gas.PerWord)
+	if overflow {
+		return math.MaxUint64
+	}
+	if gas, overflow = cmath.SafeAdd(gas, c.gas.Base); overflow {
+		return math.MaxUint64
+	}
+	return gas
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestCustomPrecompiles(t *testing.T) {
+	var (
+		address = common.HexToAddress("0x0100")
+		config  = *params.AllEthashProtocolChanges
+	)
+	config.Precompiles = []*params.PrecompileConfig{{
+		Block:          big.NewInt(5),
+		Address:        address,
+		Implementation: "bls12381MapG1",
+		Gas:            &params.PrecompileGas{Base: 100, PerWord: 10},
+	}}
+	if err := CheckPrecompiles(&config); err != nil {
+		t.Fatalf("valid precompiles rejected: %v", err)
+	}
+	// A valid field element, mapped into G1
+	input := common.LeftPadBytes([]byte{1}, 64)
+	want, _ := (&bls12381MapG1{}).Run(input)
+
+	for _, tt := range []struct {
+		number uint64
+		active bool
+	}{{4, false}, {5, true}} {
+		rules := config.Rules(new(big.Int).SetUint64(tt.number))
+		var listed bool
+		for _, addr := range ActivePrecompiles(rules) {
+			listed = listed || addr == address
+		}
+		if listed != tt.active {
+			t.Errorf("block #%d: precompile listed mismatch: have %v, want %v", tt.number, listed, tt.active)
+		}
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int).SetUint64(tt.number),
+		}
+		evm := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+		ret, left, err := evm.Call(AccountRef(common.Address{}), address, input, 1000, new(big.Int))
+		if err != nil {
+			t.Fatalf("block #%d: call failed: %v", tt.number, err)
+		}
+		if !tt.active {
+			if len(ret) != 0 || left != 1000 {
+				t.Errorf("block #%d: inactive precompile executed: %x, gas left %d", tt.number, ret, left)
+			}
+			continue
+		}
+		if !bytes.Equal(ret, want) {
+			t.Errorf("block #%d: output mismatch: have %x, want %x", tt.number, ret, want)
+		}
+		if used := 1000 - left; used != 100+2*10 {
+			t.Errorf("block #%d: gas used mismatch: have %d, want %d", tt.number, used, 100+2*10)
+		}
+	}
+	config.P256VerifyBlock = big.NewInt(10)
+	for _, invalid := range []*params.PrecompileConfig{
+		{Block: common.Big0, Address: address, Implementation: "unknown"},
+		{Block: common.Big0, Address: common.BytesToAddress([]byte{1}), Implementation: "p256Verify"},
+		{Block: common.Big0, Address: p256VerifyAddress, Implementation: "p256Verify"},
+	} {
+		config.Precompiles = []*params.PrecompileConfig{invalid}
+		if err := CheckPrecompiles(&config); err == nil {
+			t.Errorf("invalid precompile %+v accepted", invalid)
+		}
+	}
+}
+
+func TestP256VerifyActivation(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.P256VerifyBlock = big.NewInt(10)
+
+	tests, err := loadJson("p256Verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := common.Hex2Bytes(tests[0].Input)
+	for _, tt := range []struct {
+		number uint64
+		active bool
+	}{{9, false}, {10, true}} {
+		rules := config.Rules(new(big.Int).SetUint64(tt.number))
+		var listed bool
+		for _, addr := range ActivePrecompiles(rules) {
+			listed = listed || addr == p256VerifyAddress
+		}
+		if listed != tt.active {
+			t.Errorf("block #%d: precompile listed mismatch: have %v, want %v", tt.number, listed, tt.active)
+		}
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		vmctx := BlockContext{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: new(big.Int).SetUint64(tt.number),
+		}
+		evm := NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+		ret, left, err := evm.Call(AccountRef(common.Address{}), p256VerifyAddress, input, 10000, new(big.Int))
+		if err != nil {
+			t.Fatalf("block #%d: call failed: %v", tt.number, err)
+		}
+		switch {
+		case !tt.active && (len(ret) != 0 || left != 10000):
+			t.Errorf("block #%d: inactive precompile executed: %x, gas left %d", tt.number, ret, left)
+		case tt.active && (common.Bytes2Hex(ret) != tests[0].Expected || left != 10000-params.P256VerifyGas):
+			t.Errorf("block #%d: wrong result: %x, gas left %d", tt.number, ret, left)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"hash"
 	"sync"
 	"sync/atomic"
@@ -38,6 +39,7 @@ type Config struct {
 	Tracer                  EVMLogger // Opcode logger
 	NoRecursion             bool      // Disables call, callcode, delegate call and create
 	EnablePreimageRecording bool      // Enables recording of SHA3/keccak preimages
+	NoBaseFee               bool      // Lets calls with a zero gas price pass the base fee check
 
 	JumpTable [256]*operation // EVM instruction table, automatically populated if unset
 
@@ -45,6 +47,42 @@ type Config struct {
 	EVMInterpreter   string // External EVM interpreter options
 
 	ExtraEips []int // Additional EIPS that are to be enabled
+
+	Backend Backend // Execution backend of the EVM code
+}
+
+// Backend is an engine executing the EVM code.
+type Backend uint8
+
+const (
+	// InterpreterBackend executes the code one opcode at a time, checking the
+	// stack and charging the gas of each.
+	InterpreterBackend Backend = iota
+
+	// CompiledBackend splits the code into blocks of opcodes with constant gas
+	// costs, which are cached and checked and charged for at once.
+	CompiledBackend
+)
+
+// ParseBackend returns the backend with the given name.
+func ParseBackend(name string) (Backend, error) {
+	switch name {
+	case "", "interpreter":
+		return InterpreterBackend, nil
+	case "compiled":
+		return CompiledBackend, nil
+	}
+	return InterpreterBackend, fmt.Errorf("unknown EVM backend %q", name)
+}
+
+func (b Backend) String() string {
+	switch b {
+	case InterpreterBackend:
+		return "interpreter"
+	case CompiledBackend:
+		return "compiled"
+	}
+	return fmt.Sprintf("Backend(%d)", uint8(b))
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
@@ -105,6 +143,10 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 	if cfg.JumpTable[STOP] == nil {
 		var jt JumpTable
 		switch {
+		case evm.chainRules.IsPrague:
+			jt = pragueInstructionSet
+		case evm.chainRules.IsCancun:
+			jt = cancunInstructionSet
 		case evm.chainRules.IsBerlin:
 			jt = berlinInstructionSet
 		case evm.chainRules.IsIstanbul:
@@ -168,6 +210,10 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	//	return nil, nil
 	//}
 
+	// Tracing needs every opcode stepped through separately
+	if in.cfg.Backend == CompiledBackend && !in.cfg.Debug {
+		return in.runCompiled(contract, input)
+	}
 	var (
 		op          OpCode        // current opcode
 		mem         = NewMemory() // bound memory
@@ -57,11 +57,29 @@ var (
 	constantinopleInstructionSet   = newConstantinopleInstructionSet()
 	istanbulInstructionSet         = newIstanbulInstructionSet()
 	berlinInstructionSet           = newBerlinInstructionSet()
+	cancunInstructionSet           = newCancunInstructionSet()
+	pragueInstructionSet           = newPragueInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// newPragueInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul, petersburg, berlin, cancun and prague instructions.
+func newPragueInstructionSet() JumpTable {
+	instructionSet := newCancunInstructionSet()
+	enable7702(&instructionSet) // EIP-7702 Setcode transaction type https://eips.ethereum.org/EIPS/eip-7702
+	return instructionSet
+}
+
+// newCancunInstructionSet returns the frontier, homestead, byzantium,
+// contantinople, istanbul, petersburg, berlin and cancun instructions.
+func newCancunInstructionSet() JumpTable {
+	instructionSet := newBerlinInstructionSet()
+	enable4844(&instructionSet) // BLOBHASH opcode https://eips.ethereum.org/EIPS/eip-4844
+	return instructionSet
+}
+
 // newBerlinInstructionSet returns the frontier, homestead, byzantium,
 // contantinople, istanbul, petersburg and berlin instructions.
 func newBerlinInstructionSet() JumpTable {
@@ -0,0 +1,78 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the blocks imported with the compiled EVM backend are cross-checked
+// against the interpreter, and that the backend is kept when they agree.
+func TestBackendVerification(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0xaaaa")
+		db      = rawdb.NewMemoryDatabase()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		// sstore(0, sload(0) + 1)
+		code = []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+	)
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			sender:  {Balance: big.NewInt(params.Ether)},
+			counter: {Balance: common.Big0, Code: code},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 4, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), counter, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{Backend: vm.CompiledBackend}, nil, nil, EnableBackendVerification(3))
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if chain.backendChecks != 3 {
+		t.Fatalf("verified block count mismatch: have %d, want 3", chain.backendChecks)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if chain.backendChecks != 0 {
+		t.Errorf("blocks left to verify: %d", chain.backendChecks)
+	}
+	if backend := chain.GetVMConfig().Backend; backend != vm.CompiledBackend {
+		t.Errorf("backend mismatch: have %v, want %v", backend, vm.CompiledBackend)
+	}
+	statedb, _ := chain.State()
+	if have := statedb.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(4)) {
+		t.Errorf("counter mismatch: have %x, want 4", have)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// interchangeVersion is the supported version of the EIP-3076 slashing
+// protection interchange format.
+const interchangeVersion = "5"
+
+// Interchange is the EIP-3076 slashing protection interchange document. Vote
+// source and target block numbers are stored in the epoch fields.
+type Interchange struct {
+	Metadata InterchangeMetadata `json:"metadata"`
+	Data     []InterchangeRecord `json:"data"`
+}
+
+// InterchangeMetadata identifies the format and the chain of an interchange
+// document. The genesis hash of the chain takes the place of the validators
+// root.
+type InterchangeMetadata struct {
+	Version     string      `json:"interchange_format_version"`
+	GenesisRoot common.Hash `json:"genesis_validators_root"`
+}
+
+// InterchangeRecord contains the votes signed by a single key.
+type InterchangeRecord struct {
+	PublicKey    types.BLSPublicKey      `json:"pubkey"`
+	SignedBlocks []json.RawMessage       `json:"signed_blocks"`
+	SignedVotes  []InterchangeSignedVote `json:"signed_attestations"`
+}
+
+// InterchangeSignedVote is a single signed vote of an interchange record.
+type InterchangeSignedVote struct {
+	SourceNumber decimal      `json:"source_epoch"`
+	TargetNumber decimal      `json:"target_epoch"`
+	SigningRoot  *common.Hash `json:"signing_root,omitempty"`
+}
+
+// decimal is an uint64 marshalled as a decimal string, as mandated by EIP-3076.
+type decimal uint64
+
+// MarshalText implements encoding.TextMarshaler.
+func (d decimal) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(d), 10)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *decimal) UnmarshalText(input []byte) error {
+	n, err := strconv.ParseUint(string(input), 10, 64)
+	if err != nil {
+		return err
+	}
+	*d = decimal(n)
+	return nil
+}
+
+// Export writes the content of the protection database as an interchange
+// document for the chain with the given genesis hash.
+func (p *ProtectionDB) Export(w io.Writer, genesis common.Hash) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	doc := &Interchange{
+		Metadata: InterchangeMetadata{Version: interchangeVersion, GenesisRoot: genesis},
+		Data:     []InterchangeRecord{},
+	}
+	it := p.db.NewIterator(protectionPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(protectionPrefix)+types.BLSPublicKeyLength {
+			continue
+		}
+		var vote SignedVote
+		if err := rlp.DecodeBytes(it.Value(), &vote); err != nil {
+			return err
+		}
+		record := InterchangeRecord{
+			SignedBlocks: []json.RawMessage{},
+			SignedVotes: []InterchangeSignedVote{{
+				SourceNumber: decimal(vote.SourceNumber),
+				TargetNumber: decimal(vote.TargetNumber),
+				SigningRoot:  &vote.SigningRoot,
+			}},
+		}
+		copy(record.PublicKey[:], it.Key()[len(protectionPrefix):])
+		doc.Data = append(doc.Data, record)
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Import merges an interchange document of the chain with the given genesis
+// hash into the protection database. The resulting watermark of each key is
+// the highest of the local and the imported votes, so importing can never
+// lower the protection. It returns the number of keys imported.
+func (p *ProtectionDB) Import(r io.Reader, genesis common.Hash) (int, error) {
+	var doc Interchange
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("invalid interchange document: %v", err)
+	}
+	if doc.Metadata.Version != interchangeVersion {
+		return 0, fmt.Errorf("unsupported interchange version %q, want %q", doc.Metadata.Version, interchangeVersion)
+	}
+	if doc.Metadata.GenesisRoot != genesis {
+		return 0, fmt.Errorf("interchange genesis mismatch: have %x, want %x", doc.Metadata.GenesisRoot, genesis)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, record := range doc.Data {
+		merged, err := p.SignedVote(record.PublicKey)
+		if err != nil {
+			return 0, err
+		}
+		for _, vote := range record.SignedVotes {
+			if vote.SourceNumber > vote.TargetNumber {
+				return 0, fmt.Errorf("key %x: source number %d above target number %d", record.PublicKey, vote.SourceNumber, vote.TargetNumber)
+			}
+			if merged == nil {
+				merged = new(SignedVote)
+			}
+			if uint64(vote.SourceNumber) > merged.SourceNumber {
+				merged.SourceNumber = uint64(vote.SourceNumber)
+			}
+			if uint64(vote.TargetNumber) > merged.TargetNumber {
+				merged.TargetNumber = uint64(vote.TargetNumber)
+
+				// Without a signing root nothing may be signed at this target
+				merged.SigningRoot = common.Hash{}
+				if vote.SigningRoot != nil {
+					merged.SigningRoot = *vote.SigningRoot
+				}
+			}
+		}
+		if merged != nil {
+			if err := p.writeSignedVote(record.PublicKey, merged); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(doc.Data), nil
+}
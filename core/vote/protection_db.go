@@ -0,0 +1,286 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ProtectionDatabaseName is the name of the slashing protection database
+// within the node's data directory.
+const ProtectionDatabaseName = "voteprotection"
+
+// protectionPrefix + pubkey -> signed vote watermark
+var protectionPrefix = []byte("vote-protection-")
+
+// blockProtectionPrefix + validator address -> sealed block watermark
+var blockProtectionPrefix = []byte("block-protection-")
+
+var (
+	// ErrDoubleVote is returned if a vote for an already voted target number
+	// is requested with a different signing root.
+	ErrDoubleVote = errors.New("double vote")
+
+	// ErrSurroundVote is returned if a vote surrounds, or is surrounded by, a
+	// previously signed vote.
+	ErrSurroundVote = errors.New("surround vote")
+
+	// ErrDoubleSeal is returned if sealing a block at or below the height of an
+	// already sealed, or possibly sealed, different block is requested.
+	ErrDoubleSeal = errors.New("double seal")
+
+	// ErrStandby is returned if a vote is requested from a signer on standby.
+	ErrStandby = errors.New("vote signer on standby")
+
+	refusedVoteMeter = metrics.NewRegisteredMeter("vote/protection/refused", nil)
+	refusedSealMeter = metrics.NewRegisteredMeter("vote/protection/refusedseal", nil)
+)
+
+// SignedVote is the watermark of the votes signed by a single key. Only the
+// highest source and target numbers are kept, which is enough to reject any
+// slashable vote following the minimal strategy of EIP-3076.
+type SignedVote struct {
+	SourceNumber uint64      // Highest source number ever signed
+	TargetNumber uint64      // Highest target number ever signed
+	SigningRoot  common.Hash // Signing root of the vote at the highest target
+}
+
+// ProtectionDB is a local slashing protection database recording the votes
+// signed by the validator keys of the node. It has to be kept separate from
+// the chain database so it survives resyncs and snapshot restores.
+type ProtectionDB struct {
+	db   ethdb.KeyValueStore
+	lock sync.Mutex // Serialises check-and-record cycles
+}
+
+// NewProtectionDB creates a slashing protection database on top of the given
+// key-value store.
+func NewProtectionDB(db ethdb.KeyValueStore) *ProtectionDB {
+	return &ProtectionDB{db: db}
+}
+
+func protectionKey(pubKey types.BLSPublicKey) []byte {
+	return append(append([]byte{}, protectionPrefix...), pubKey[:]...)
+}
+
+// SignedVote returns the watermark of the votes signed by the given key, or
+// nil if the key never signed anything.
+func (p *ProtectionDB) SignedVote(pubKey types.BLSPublicKey) (*SignedVote, error) {
+	key := protectionKey(pubKey)
+	if has, err := p.db.Has(key); err != nil || !has {
+		return nil, err
+	}
+	blob, err := p.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	vote := new(SignedVote)
+	if err := rlp.DecodeBytes(blob, vote); err != nil {
+		return nil, err
+	}
+	return vote, nil
+}
+
+// writeSignedVote stores the watermark of the votes signed by the given key.
+func (p *ProtectionDB) writeSignedVote(pubKey types.BLSPublicKey, vote *SignedVote) error {
+	blob, err := rlp.EncodeToBytes(vote)
+	if err != nil {
+		return err
+	}
+	return p.db.Put(protectionKey(pubKey), blob)
+}
+
+// CheckAndRecord verifies that signing the given vote data with the key can't
+// be slashed and records it as signed. Once this method returned without an
+// error, the vote is considered signed even if the signing itself fails.
+func (p *ProtectionDB) CheckAndRecord(pubKey types.BLSPublicKey, data *types.VoteData) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if data.SourceNumber > data.TargetNumber {
+		return fmt.Errorf("source number %d above target number %d", data.SourceNumber, data.TargetNumber)
+	}
+	last, err := p.SignedVote(pubKey)
+	if err != nil {
+		return err
+	}
+	root := data.Hash()
+	if last != nil {
+		if err := checkVote(last, data, root); err != nil {
+			refusedVoteMeter.Mark(1)
+			log.Error("Refusing to sign slashable vote", "source", data.SourceNumber, "target", data.TargetNumber,
+				"lastSource", last.SourceNumber, "lastTarget", last.TargetNumber, "err", err)
+			return err
+		}
+		if data.TargetNumber == last.TargetNumber {
+			return nil // Same vote signed again, nothing to record
+		}
+	}
+	return p.writeSignedVote(pubKey, &SignedVote{
+		SourceNumber: data.SourceNumber,
+		TargetNumber: data.TargetNumber,
+		SigningRoot:  root,
+	})
+}
+
+// checkVote checks a vote against the watermark of the previously signed ones.
+func checkVote(last *SignedVote, data *types.VoteData, root common.Hash) error {
+	switch {
+	case data.TargetNumber == last.TargetNumber && root != last.SigningRoot:
+		return ErrDoubleVote
+	case data.TargetNumber < last.TargetNumber:
+		// Anything below the watermark either conflicts with a vote we can't
+		// see anymore or is surrounded by the last one, refuse it either way.
+		return ErrSurroundVote
+	case data.SourceNumber < last.SourceNumber:
+		return ErrSurroundVote
+	}
+	return nil
+}
+
+// SealedBlock is the watermark of the blocks sealed by a validator. A zero seal
+// hash marks a height raised externally, where any block is refused.
+type SealedBlock struct {
+	Number   uint64      // Highest block number ever sealed
+	SealHash common.Hash // Seal hash of the block at the highest number
+}
+
+func blockProtectionKey(validator common.Address) []byte {
+	return append(append([]byte{}, blockProtectionPrefix...), validator[:]...)
+}
+
+// SealedBlock returns the watermark of the blocks sealed by the given validator,
+// or nil if the validator never sealed anything.
+func (p *ProtectionDB) SealedBlock(validator common.Address) (*SealedBlock, error) {
+	key := blockProtectionKey(validator)
+	if has, err := p.db.Has(key); err != nil || !has {
+		return nil, err
+	}
+	blob, err := p.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	block := new(SealedBlock)
+	if err := rlp.DecodeBytes(blob, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (p *ProtectionDB) writeSealedBlock(validator common.Address, block *SealedBlock) error {
+	blob, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return err
+	}
+	return p.db.Put(blockProtectionKey(validator), blob)
+}
+
+// CheckAndRecordSeal verifies that sealing the block with the given seal hash at
+// the given height can't be slashed and records it as sealed.
+func (p *ProtectionDB) CheckAndRecordSeal(validator common.Address, number uint64, sealHash common.Hash) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	last, err := p.SealedBlock(validator)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		if number < last.Number || (number == last.Number && sealHash != last.SealHash) {
+			refusedSealMeter.Mark(1)
+			log.Error("Refusing to seal slashable block", "number", number, "lastNumber", last.Number)
+			return ErrDoubleSeal
+		}
+		if number == last.Number {
+			return nil // Same block sealed again, nothing to record
+		}
+	}
+	return p.writeSealedBlock(validator, &SealedBlock{Number: number, SealHash: sealHash})
+}
+
+// RaiseSealWatermark refuses the sealing of any block up to the given height,
+// which another node holding the same key may have sealed.
+func (p *ProtectionDB) RaiseSealWatermark(validator common.Address, number uint64) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	last, err := p.SealedBlock(validator)
+	if err != nil {
+		return err
+	}
+	if last != nil && last.Number >= number {
+		return nil
+	}
+	return p.writeSealedBlock(validator, &SealedBlock{Number: number})
+}
+
+// ProtectedSigner is a vote signer which consults the slashing protection
+// database before handing a vote to the wrapped signer.
+type ProtectedSigner struct {
+	signer  Signer
+	db      *ProtectionDB
+	standby int32 // Whether votes are refused as another node signs them (atomic)
+}
+
+// NewProtectedSigner wraps a vote signer with slashing protection.
+func NewProtectedSigner(signer Signer, db *ProtectionDB) *ProtectedSigner {
+	return &ProtectedSigner{signer: signer, db: db}
+}
+
+// PublicKey implements Signer, returning the key of the wrapped signer.
+func (s *ProtectedSigner) PublicKey() types.BLSPublicKey {
+	return s.signer.PublicKey()
+}
+
+// SignVote implements Signer, refusing to sign any slashable vote.
+func (s *ProtectedSigner) SignVote(vote *types.VoteEnvelope) error {
+	if vote.Data == nil {
+		return errors.New("missing vote data")
+	}
+	if atomic.LoadInt32(&s.standby) == 1 {
+		return ErrStandby
+	}
+	if err := s.db.CheckAndRecord(s.signer.PublicKey(), vote.Data); err != nil {
+		return err
+	}
+	return s.signer.SignVote(vote)
+}
+
+// SetStandby enables or disables the refusal of all votes, used while another
+// node holding the same key is signing.
+func (s *ProtectedSigner) SetStandby(standby bool) {
+	var flag int32
+	if standby {
+		flag = 1
+	}
+	atomic.StoreInt32(&s.standby, flag)
+}
+
+// Signer returns the signer wrapped by the protection.
+func (s *ProtectedSigner) Signer() Signer {
+	return s.signer
+}
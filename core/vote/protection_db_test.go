@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testSigner is a vote signer counting the signed votes.
+type testSigner struct {
+	signed int
+}
+
+func (s *testSigner) PublicKey() types.BLSPublicKey { return testPubKey }
+
+func (s *testSigner) SignVote(vote *types.VoteEnvelope) error {
+	s.signed++
+	vote.VoteAddress = testPubKey
+	vote.Signature = testSig
+	return nil
+}
+
+func newVote(source, target uint64, hash byte) *types.VoteEnvelope {
+	return &types.VoteEnvelope{Data: &types.VoteData{
+		SourceNumber: source,
+		TargetNumber: target,
+		TargetHash:   common.Hash{hash},
+	}}
+}
+
+func TestProtectedSigner(t *testing.T) {
+	backend := new(testSigner)
+	signer := NewProtectedSigner(backend, NewProtectionDB(rawdb.NewMemoryDatabase()))
+
+	tests := []struct {
+		vote *types.VoteEnvelope
+		err  error
+	}{
+		{newVote(1, 2, 0), nil},
+		{newVote(1, 2, 0), nil},             // identical vote may be re-signed
+		{newVote(1, 2, 1), ErrDoubleVote},   // same target, different block
+		{newVote(2, 4, 0), nil},             // regular progression
+		{newVote(2, 3, 0), ErrSurroundVote}, // below the target watermark
+		{newVote(1, 6, 0), ErrSurroundVote}, // surrounds the previous vote
+		{newVote(4, 6, 0), nil},
+	}
+	signed := 0
+	for i, tt := range tests {
+		if err := signer.SignVote(tt.vote); err != tt.err {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if tt.err == nil {
+			signed++
+		}
+		if backend.signed != signed {
+			t.Fatalf("test %d: signed votes mismatch: have %d, want %d", i, backend.signed, signed)
+		}
+	}
+}
+
+func TestProtectionInterchange(t *testing.T) {
+	var (
+		genesis = common.Hash{0x01}
+		src     = NewProtectionDB(rawdb.NewMemoryDatabase())
+		dst     = NewProtectionDB(rawdb.NewMemoryDatabase())
+	)
+	if err := src.CheckAndRecord(testPubKey, newVote(5, 10, 0).Data); err != nil {
+		t.Fatalf("failed to record vote: %v", err)
+	}
+	// Record a lower vote locally which the import has to lift
+	if err := dst.CheckAndRecord(testPubKey, newVote(1, 3, 0).Data); err != nil {
+		t.Fatalf("failed to record vote: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := src.Export(&buf, genesis); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if _, err := dst.Import(bytes.NewReader(buf.Bytes()), common.Hash{0x02}); err == nil {
+		t.Fatalf("import of foreign chain succeeded")
+	}
+	if n, err := dst.Import(bytes.NewReader(buf.Bytes()), genesis); err != nil || n != 1 {
+		t.Fatalf("failed to import: %d, %v", n, err)
+	}
+	have, err := dst.SignedVote(testPubKey)
+	if err != nil {
+		t.Fatalf("failed to read watermark: %v", err)
+	}
+	want, _ := src.SignedVote(testPubKey)
+	if *have != *want {
+		t.Fatalf("watermark mismatch: have %+v, want %+v", have, want)
+	}
+	// Anything below the imported watermark must be refused
+	if err := dst.CheckAndRecord(testPubKey, newVote(5, 9, 0).Data); err != ErrSurroundVote {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrSurroundVote)
+	}
+}
+
+func TestSealProtection(t *testing.T) {
+	var (
+		db        = NewProtectionDB(rawdb.NewMemoryDatabase())
+		validator = common.Address{1}
+	)
+	tests := []struct {
+		number uint64
+		hash   byte
+		err    error
+	}{
+		{10, 1, nil},
+		{10, 1, nil},           // identical block may be resealed
+		{10, 2, ErrDoubleSeal}, // same height, different block
+		{9, 3, ErrDoubleSeal},  // below the watermark
+		{11, 4, nil},
+	}
+	for i, tt := range tests {
+		if err := db.CheckAndRecordSeal(validator, tt.number, common.Hash{tt.hash}); err != tt.err {
+			t.Fatalf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// A raised watermark refuses any block up to it
+	if err := db.RaiseSealWatermark(validator, 20); err != nil {
+		t.Fatalf("failed to raise watermark: %v", err)
+	}
+	if err := db.CheckAndRecordSeal(validator, 20, common.Hash{5}); err != ErrDoubleSeal {
+		t.Fatalf("sealing at the watermark: have %v, want %v", err, ErrDoubleSeal)
+	}
+	if err := db.CheckAndRecordSeal(validator, 21, common.Hash{5}); err != nil {
+		t.Fatalf("sealing above the watermark failed: %v", err)
+	}
+	// Lowering the watermark is a noop
+	if err := db.RaiseSealWatermark(validator, 15); err != nil {
+		t.Fatalf("failed to raise watermark: %v", err)
+	}
+	if block, _ := db.SealedBlock(validator); block.Number != 21 {
+		t.Fatalf("watermark mismatch: have %d, want 21", block.Number)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// signPath is the Web3Signer endpoint used to sign a signing root with the
+	// key identified by the public key appended to it.
+	signPath = "/api/v1/eth2/sign/"
+
+	// publicKeysPath is the Web3Signer endpoint listing all loaded keys.
+	publicKeysPath = "/api/v1/eth2/publicKeys"
+
+	// upcheckPath is the Web3Signer liveness endpoint.
+	upcheckPath = "/upcheck"
+
+	// voteSigningType is the artifact type announced to the remote signer.
+	voteSigningType = "VOTE"
+
+	// maxResponseSize is the maximum accepted size of a remote signer response.
+	maxResponseSize = 64 * 1024
+)
+
+var (
+	remoteSignTimer = metrics.NewRegisteredTimer("vote/signer/remote/sign", nil)
+	remoteSignFails = metrics.NewRegisteredMeter("vote/signer/remote/fail", nil)
+
+	// errUnknownPublicKey is returned if the remote signer does not hold the
+	// configured key.
+	errUnknownPublicKey = errors.New("public key not loaded by remote signer")
+)
+
+// signRequest is the body of a Web3Signer signing request.
+type signRequest struct {
+	Type        string        `json:"type"`
+	SigningRoot hexutil.Bytes `json:"signingRoot"`
+}
+
+// signResponse is the JSON body of a Web3Signer signing response.
+type signResponse struct {
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// RemoteSigner delegates the BLS signing of votes to an external signer
+// speaking the Web3Signer HTTP API, so the validator key never has to touch
+// the disk of the node.
+type RemoteSigner struct {
+	endpoint string             // Base URL of the remote signer
+	pubKey   types.BLSPublicKey // Public key the votes are signed with
+	client   *http.Client       // HTTP client with the configured timeout
+}
+
+// NewRemoteSigner creates a vote signer backed by a Web3Signer endpoint.
+func NewRemoteSigner(config Config) (*RemoteSigner, error) {
+	u, err := url.Parse(config.RemoteSignerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote signer url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported remote signer scheme %q", u.Scheme)
+	}
+	var pubKey types.BLSPublicKey
+	if err := pubKey.UnmarshalText([]byte(config.RemotePublicKey)); err != nil {
+		return nil, fmt.Errorf("invalid remote signer public key: %v", err)
+	}
+	timeout := config.RemoteTimeout
+	if timeout <= 0 {
+		timeout = DefaultConfig.RemoteTimeout
+	}
+	return &RemoteSigner{
+		endpoint: strings.TrimRight(u.String(), "/"),
+		pubKey:   pubKey,
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// PublicKey implements Signer, returning the key held by the remote signer.
+func (s *RemoteSigner) PublicKey() types.BLSPublicKey {
+	return s.pubKey
+}
+
+// SignVote implements Signer, requesting a signature over the vote data hash
+// from the remote signer.
+func (s *RemoteSigner) SignVote(vote *types.VoteEnvelope) error {
+	if vote.Data == nil {
+		return errors.New("missing vote data")
+	}
+	start := time.Now()
+	sig, err := s.sign(vote.Data.Hash().Bytes())
+	if err != nil {
+		remoteSignFails.Mark(1)
+		log.Warn("Remote vote signing failed", "endpoint", s.endpoint, "err", err)
+		return err
+	}
+	remoteSignTimer.UpdateSince(start)
+
+	vote.VoteAddress = s.pubKey
+	copy(vote.Signature[:], sig)
+	return nil
+}
+
+// Check verifies that the remote signer is alive and holds the configured key.
+func (s *RemoteSigner) Check(ctx context.Context) error {
+	if _, err := s.do(ctx, http.MethodGet, upcheckPath, nil); err != nil {
+		return err
+	}
+	blob, err := s.do(ctx, http.MethodGet, publicKeysPath, nil)
+	if err != nil {
+		return err
+	}
+	var keys []types.BLSPublicKey
+	if err := json.Unmarshal(blob, &keys); err != nil {
+		return fmt.Errorf("invalid public key list: %v", err)
+	}
+	for _, key := range keys {
+		if key == s.pubKey {
+			return nil
+		}
+	}
+	return errUnknownPublicKey
+}
+
+// sign requests a signature over the given signing root.
+func (s *RemoteSigner) sign(root []byte) ([]byte, error) {
+	body, err := json.Marshal(&signRequest{Type: voteSigningType, SigningRoot: root})
+	if err != nil {
+		return nil, err
+	}
+	blob, err := s.do(context.Background(), http.MethodPost, signPath+hexutil.Encode(s.pubKey[:]), body)
+	if err != nil {
+		return nil, err
+	}
+	// Web3Signer answers with a JSON object if asked to, but older versions
+	// only ever return the plain hex encoded signature.
+	var sig []byte
+	var res signResponse
+	if err := json.Unmarshal(blob, &res); err == nil {
+		sig = res.Signature
+	} else if sig, err = hexutil.Decode(strings.TrimSpace(string(blob))); err != nil {
+		return nil, fmt.Errorf("invalid signature response: %v", err)
+	}
+	if len(sig) != types.BLSSignatureLength {
+		return nil, fmt.Errorf("invalid signature length: have %d, want %d", len(sig), types.BLSSignatureLength)
+	}
+	return sig, nil
+}
+
+// do executes a single HTTP request against the remote signer.
+func (s *RemoteSigner) do(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	blob, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return blob, nil
+	case http.StatusNotFound:
+		if strings.HasPrefix(path, signPath) {
+			return nil, errUnknownPublicKey
+		}
+	}
+	return nil, fmt.Errorf("remote signer returned %s: %s", res.Status, strings.TrimSpace(string(blob)))
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	testPubKey = types.BLSPublicKey{0x01, 0x02, 0x03}
+	testSig    = types.BLSSignature{0xaa, 0xbb, 0xcc}
+)
+
+// newTestWeb3Signer starts a fake Web3Signer holding testPubKey, optionally
+// answering sign requests with a plain text body.
+func newTestWeb3Signer(t *testing.T, plain bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == upcheckPath:
+			w.Write([]byte("OK"))
+		case r.URL.Path == publicKeysPath:
+			json.NewEncoder(w).Encode([]types.BLSPublicKey{testPubKey})
+		case r.URL.Path == signPath+hexutil.Encode(testPubKey[:]):
+			var req signRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Type != voteSigningType || len(req.SigningRoot) != common.HashLength {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			if plain {
+				w.Write([]byte(hexutil.Encode(testSig[:])))
+			} else {
+				json.NewEncoder(w).Encode(&signResponse{Signature: testSig[:]})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRemoteSignerSignVote(t *testing.T) {
+	for _, plain := range []bool{false, true} {
+		server := newTestWeb3Signer(t, plain)
+
+		signer, err := NewRemoteSigner(Config{
+			RemoteSignerURL: server.URL + "/",
+			RemotePublicKey: hexutil.Encode(testPubKey[:]),
+		})
+		if err != nil {
+			t.Fatalf("failed to create remote signer: %v", err)
+		}
+		if err := signer.Check(context.Background()); err != nil {
+			t.Fatalf("remote signer check failed: %v", err)
+		}
+		vote := &types.VoteEnvelope{Data: &types.VoteData{SourceNumber: 1, TargetNumber: 2}}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("plain %v: failed to sign vote: %v", plain, err)
+		}
+		if vote.VoteAddress != testPubKey {
+			t.Errorf("plain %v: vote address mismatch: have %x, want %x", plain, vote.VoteAddress, testPubKey)
+		}
+		if vote.Signature != testSig {
+			t.Errorf("plain %v: signature mismatch: have %x, want %x", plain, vote.Signature, testSig)
+		}
+		server.Close()
+	}
+}
+
+func TestRemoteSignerUnknownKey(t *testing.T) {
+	server := newTestWeb3Signer(t, false)
+	defer server.Close()
+
+	other := types.BLSPublicKey{0xff}
+	signer, err := NewRemoteSigner(Config{
+		RemoteSignerURL: server.URL,
+		RemotePublicKey: hexutil.Encode(other[:]),
+	})
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	if err := signer.Check(context.Background()); err != errUnknownPublicKey {
+		t.Errorf("check error mismatch: have %v, want %v", err, errUnknownPublicKey)
+	}
+	if err := signer.SignVote(&types.VoteEnvelope{Data: &types.VoteData{}}); err != errUnknownPublicKey {
+		t.Errorf("sign error mismatch: have %v, want %v", err, errUnknownPublicKey)
+	}
+}
+
+func TestRemoteSignerInvalidConfig(t *testing.T) {
+	tests := []Config{
+		{RemoteSignerURL: "ftp://localhost", RemotePublicKey: hexutil.Encode(testPubKey[:])},
+		{RemoteSignerURL: "http://localhost", RemotePublicKey: "0x1234"},
+		{RemoteSignerURL: "http://localhost"},
+	}
+	for i, config := range tests {
+		if _, err := NewRemoteSigner(config); err == nil {
+			t.Errorf("test %d: expected error", i)
+		} else if !strings.Contains(err.Error(), "remote signer") {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package vote implements the signing of validator votes.
+package vote
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errNoSigner is returned if a vote signer is requested without any signing
+// backend being configured.
+var errNoSigner = errors.New("no vote signer configured")
+
+// Config contains the settings of the vote signer.
+type Config struct {
+	RemoteSignerURL string        `toml:",omitempty"` // Web3Signer-compatible endpoint the BLS signing is delegated to
+	RemotePublicKey string        `toml:",omitempty"` // Hex encoded BLS public key held by the remote signer
+	RemoteTimeout   time.Duration `toml:",omitempty"` // Timeout of a single remote signing request
+}
+
+// DefaultConfig contains the default settings of the vote signer.
+var DefaultConfig = Config{
+	RemoteTimeout: 5 * time.Second,
+}
+
+// Enabled returns whether any vote signing backend is configured.
+func (c *Config) Enabled() bool {
+	return c.RemoteSignerURL != ""
+}
+
+// Signer signs votes with the BLS key of the local validator.
+type Signer interface {
+	// PublicKey returns the BLS public key the votes are signed with.
+	PublicKey() types.BLSPublicKey
+
+	// SignVote signs the vote data of the envelope, filling in the vote
+	// address and the signature on success.
+	SignVote(vote *types.VoteEnvelope) error
+}
+
+// NewSigner creates the vote signer selected by the configuration.
+func NewSigner(config Config) (Signer, error) {
+	if config.RemoteSignerURL != "" {
+		return NewRemoteSigner(config)
+	}
+	return nil, errNoSigner
+}
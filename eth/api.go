@@ -30,11 +30,18 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
@@ -66,6 +73,12 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().Hashrate())
 }
 
+// SendBundle submits an ordered list of transactions to be included atomically
+// at the top of the target block, returning the hash of the bundle.
+func (api *PublicEthereumAPI) SendBundle(bundle miner.SendBundleArgs) (common.Hash, error) {
+	return sendBundle(api.e, &bundle)
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -82,6 +95,44 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// PublicMevAPI provides an API for external block builders to submit bids and
+// for searchers to submit transaction bundles for the blocks sealed by this node.
+type PublicMevAPI struct {
+	e *Ethereum
+}
+
+// NewPublicMevAPI creates a new RPC service accepting block builder bids.
+func NewPublicMevAPI(e *Ethereum) *PublicMevAPI {
+	return &PublicMevAPI{e: e}
+}
+
+// SendBid submits a signed block candidate of an allowed builder, returning the
+// hash of the bid. The bid is included instead of the locally built block if it
+// pays more, as verified by executing it on top of the current head. The bid must
+// be signed for the chain id and the etherbase of the node.
+func (api *PublicMevAPI) SendBid(bid miner.BidArgs) (common.Hash, error) {
+	if !api.e.IsMining() {
+		return common.Hash{}, errors.New("node is not mining")
+	}
+	return api.e.Miner().SendBid(&bid)
+}
+
+// SendBundle submits an ordered list of transactions to be included atomically
+// at the top of the target block, returning the hash of the bundle. It is an
+// alias of eth_sendBundle.
+func (api *PublicMevAPI) SendBundle(bundle miner.SendBundleArgs) (common.Hash, error) {
+	return sendBundle(api.e, &bundle)
+}
+
+// sendBundle queues a bundle in the miner, which simulates it on top of the
+// target block's parent and includes it if it pays the validator.
+func sendBundle(e *Ethereum, bundle *miner.SendBundleArgs) (common.Hash, error) {
+	if !e.IsMining() {
+		return common.Hash{}, errors.New("node is not mining")
+	}
+	return e.Miner().SendBundle(bundle)
+}
+
 // PrivateMinerAPI provides private RPC methods to control the miner.
 // These methods can be abused by external users and must be considered insecure for use by untrusted users.
 type PrivateMinerAPI struct {
@@ -140,6 +191,95 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// BuildStats returns the time spent in every phase of building the recently
+// sealed local blocks, along with the transactions dropped for lack of gas or
+// time.
+func (api *PrivateMinerAPI) BuildStats() []miner.BuildStats {
+	return api.e.Miner().BuildStats()
+}
+
+// errNotParlia is returned by the validator APIs if the node doesn't run parlia.
+var errNotParlia = errors.New("validator operations require the parlia engine")
+
+// PrivateValidatorAPI provides private RPC methods to operate the local parlia
+// validator.
+type PrivateValidatorAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateValidatorAPI creates a new RPC service which operates the local
+// validator of this node.
+func NewPrivateValidatorAPI(e *Ethereum) *PrivateValidatorAPI {
+	return &PrivateValidatorAPI{e: e}
+}
+
+// MaintenanceResult is the outcome of a maintenance mode change.
+type MaintenanceResult struct {
+	Maintenance bool         `json:"maintenance"`           // Whether the validator skips its sealing turns
+	Transaction *common.Hash `json:"transaction,omitempty"` // Transaction signalling the change on-chain, if supported
+}
+
+// EnterMaintenance makes the local validator skip its sealing turns until
+// ExitMaintenance is called, and signals it to the validator set contract if
+// the contract supports maintenance.
+func (api *PrivateValidatorAPI) EnterMaintenance(ctx context.Context) (*MaintenanceResult, error) {
+	return api.setMaintenance(ctx, true)
+}
+
+// ExitMaintenance makes the local validator resume sealing its turns.
+func (api *PrivateValidatorAPI) ExitMaintenance(ctx context.Context) (*MaintenanceResult, error) {
+	return api.setMaintenance(ctx, false)
+}
+
+// Maintenance returns whether the local validator skips its sealing turns.
+func (api *PrivateValidatorAPI) Maintenance() (bool, error) {
+	engine, ok := api.e.engine.(*parlia.Parlia)
+	if !ok {
+		return false, errNotParlia
+	}
+	return engine.InMaintenance(), nil
+}
+
+func (api *PrivateValidatorAPI) setMaintenance(ctx context.Context, enter bool) (*MaintenanceResult, error) {
+	engine, ok := api.e.engine.(*parlia.Parlia)
+	if !ok {
+		return nil, errNotParlia
+	}
+	if engine.InMaintenance() == enter {
+		return &MaintenanceResult{Maintenance: enter}, nil
+	}
+	validator, err := api.e.Etherbase()
+	if err != nil {
+		return nil, err
+	}
+	to, data, err := engine.MaintenanceCall(enter)
+	if err != nil {
+		return nil, err
+	}
+	result := &MaintenanceResult{Maintenance: enter}
+
+	// Only signal the change if the validator set contract accepts it
+	var (
+		gas   = hexutil.Uint64(maintenanceGasLimit)
+		input = hexutil.Bytes(data)
+		args  = ethapi.CallArgs{From: &validator, To: &to, Gas: &gas, Data: &input}
+	)
+	call, err := ethapi.DoCall(ctx, api.e.APIBackend, args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil, vm.Config{NoBaseFee: true}, 5*time.Second, api.e.config.RPCGasCap)
+	if err == nil && !call.Failed() {
+		tx, err := api.e.sendSystemTx(validator, to, data, maintenanceGasLimit)
+		if err != nil {
+			return nil, err
+		}
+		hash := tx.Hash()
+		result.Transaction = &hash
+	} else {
+		log.Warn("Validator set contract doesn't support maintenance, changing locally only", "enter", enter)
+	}
+	engine.SetMaintenance(enter)
+	log.Info("Changed validator maintenance mode", "validator", validator, "maintenance", enter)
+	return result, nil
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -299,6 +439,88 @@ func NewPrivateDebugAPI(eth *Ethereum) *PrivateDebugAPI {
 	return &PrivateDebugAPI{eth: eth}
 }
 
+// BlockPropagation returns the traced path of a recently imported block from its
+// miner through the peers tracing the block propagation.
+func (api *PrivateDebugAPI) BlockPropagation(hash common.Hash) (*BlockPropagation, error) {
+	tracer := api.eth.handler.tracer
+	if tracer == nil {
+		return nil, errPropagationTracingDisabled
+	}
+	if prop := tracer.block(hash); prop != nil {
+		return prop, nil
+	}
+	return nil, errUnknownPropagation
+}
+
+// PropagationReport aggregates the propagation of the recently imported blocks
+// per validator, the ones whose blocks arrive the latest first.
+func (api *PrivateDebugAPI) PropagationReport() ([]*ValidatorPropagation, error) {
+	tracer := api.eth.handler.tracer
+	if tracer == nil {
+		return nil, errPropagationTracingDisabled
+	}
+	return tracer.validators(), nil
+}
+
+// SnapshotHealth returns the status of the background self-check verifying the
+// state snapshot against the trie.
+func (api *PrivateDebugAPI) SnapshotHealth() snapshot.Health {
+	return api.eth.blockchain.SnapshotHealth()
+}
+
+// stateReader serves state verification reads from disk, falling back to the
+// trie database for the recent nodes not flushed yet.
+type stateReader struct {
+	ethdb.KeyValueReader
+	triedb *trie.Database
+}
+
+func (r *stateReader) Get(key []byte) ([]byte, error) {
+	blob, err := r.KeyValueReader.Get(key)
+	if len(blob) == 0 && len(key) == common.HashLength {
+		return r.triedb.Node(common.BytesToHash(key))
+	}
+	return blob, err
+}
+
+// maxHealPasses is the number of verify and heal rounds debug_healState runs,
+// damage below a missing node only being discovered once it's restored.
+const maxHealPasses = 4
+
+// HealState verifies the state with the given root, or the head state if none
+// is given, and re-downloads the missing or corrupt trie nodes and codes from
+// the connected peers. It returns the result of the last verification.
+func (api *PrivateDebugAPI) HealState(root *common.Hash) (*state.VerifyResult, error) {
+	if root == nil {
+		head := api.eth.blockchain.CurrentBlock().Root()
+		root = &head
+	}
+	var (
+		db     = api.eth.ChainDb()
+		reader = &stateReader{KeyValueReader: db, triedb: api.eth.blockchain.StateCache().TrieDB()}
+	)
+	for pass := 0; ; pass++ {
+		res, err := state.VerifyState(reader, *root, runtime.NumCPU(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if res.Healthy() || pass == maxHealPasses {
+			return res, nil
+		}
+		log.Info("Healing damaged state", "root", *root, "pass", pass, "nodes", len(res.Issues), "codes", len(res.MissingCodes))
+		for _, issue := range res.Issues {
+			if issue.Corrupt {
+				rawdb.DeleteTrieNode(db, issue.Hash)
+			}
+		}
+		if err := api.eth.Downloader().HealState(*root, func(sched *trie.Sync) {
+			state.ScheduleHeal(sched, res)
+		}); err != nil {
+			return res, err
+		}
+	}
+}
+
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.eth.ChainDb(), hash); preimage != nil {
@@ -307,6 +529,25 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// ExecutionWitness re-executes the given block, returning the ancestor headers,
+// contract codes and trie nodes needed to execute it without the state. The state
+// of the parent block must be available.
+func (api *PrivateDebugAPI) ExecutionWitness(blockNr rpc.BlockNumber) (*stateless.Witness, error) {
+	var block *types.Block
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("pending block has no witness")
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	return stateless.Build(api.eth.blockchain, block)
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
@@ -344,6 +585,86 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// maxReorgResults is the maximum number of reorgs returned by GetReorgs.
+const maxReorgResults = 1024
+
+// ReorgArgs represents a chain reorganization returned by GetReorgs and the reorgs
+// subscription.
+type ReorgArgs struct {
+	Time           hexutil.Uint64 `json:"time"`
+	Depth          hexutil.Uint64 `json:"depth"`
+	AncestorNumber hexutil.Uint64 `json:"ancestorNumber"`
+	AncestorHash   common.Hash    `json:"ancestorHash"`
+	OldChain       []common.Hash  `json:"oldChain"`
+	NewChain       []common.Hash  `json:"newChain"`
+}
+
+func newReorgArgs(reorg *rawdb.ReorgRecord) *ReorgArgs {
+	return &ReorgArgs{
+		Time:           hexutil.Uint64(time.Duration(reorg.Time) / time.Second),
+		Depth:          hexutil.Uint64(len(reorg.OldChain)),
+		AncestorNumber: hexutil.Uint64(reorg.AncestorNumber),
+		AncestorHash:   reorg.AncestorHash,
+		OldChain:       reorg.OldChain,
+		NewChain:       reorg.NewChain,
+	}
+}
+
+// GetReorgs returns the chain reorganizations recorded by the node whose common
+// ancestor is in the given block range, ordered by ancestor number.
+func (api *PrivateDebugAPI) GetReorgs(fromBlock, toBlock rpc.BlockNumber) ([]*ReorgArgs, error) {
+	resolve := func(number rpc.BlockNumber) uint64 {
+		switch number {
+		case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+			return api.eth.blockchain.CurrentBlock().NumberU64()
+		case rpc.EarliestBlockNumber:
+			return 0
+		default:
+			return uint64(number)
+		}
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	reorgs, err := rawdb.ReadReorgs(api.eth.ChainDb(), from, to, maxReorgResults)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*ReorgArgs, len(reorgs))
+	for i, reorg := range reorgs {
+		results[i] = newReorgArgs(reorg)
+	}
+	return results, nil
+}
+
+// Reorgs creates a subscription that is notified of every chain reorganization.
+func (api *PrivateDebugAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ReorgEvent, 16)
+		sub := api.eth.blockchain.SubscribeReorgEvent(reorgs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, newReorgArgs(ev.Reorg))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // AccountRangeMaxResults is the maximum number of results to be returned per call
 const AccountRangeMaxResults = 256
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -226,10 +227,35 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 	return b.eth.BlockChain().SubscribeLogsEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeStateDiffEvent(ch)
+}
+
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if atomic.LoadUint32(&b.eth.handler.txsPaused) == 1 {
+		return errTxsPaused
+	}
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	if atomic.LoadUint32(&b.eth.handler.txsPaused) == 1 {
+		return errTxsPaused
+	}
+	if len(b.eth.handler.privateTxPeers) == 0 {
+		return errors.New("no private transaction peers configured")
+	}
+	// Mark the transaction before it hits the pool to keep it from being gossiped
+	marked := b.eth.handler.privateTxs.add(signedTx.Hash())
+	if err := b.eth.txPool.AddLocal(signedTx); err != nil {
+		if marked {
+			b.eth.handler.privateTxs.remove(signedTx.Hash())
+		}
+		return err
+	}
+	return nil
+}
+
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.eth.txPool.Pending()
 	if err != nil {
@@ -279,6 +305,19 @@ func (b *EthAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthAPIBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	return b.gpo.FeeHistory(ctx, blocks, lastBlock, rewardPercentiles)
+}
+
+func (b *EthAPIBackend) Congestion(ctx context.Context) (*gasprice.Congestion, error) {
+	return b.gpo.Congestion(ctx)
+}
+
+// SetGasPriceOracle replaces the sampling parameters of the gas price oracle.
+func (b *EthAPIBackend) SetGasPriceOracle(config gasprice.Config) {
+	b.gpo.SetConfig(config)
+}
+
 func (b *EthAPIBackend) Chain() *core.BlockChain {
 	return b.eth.BlockChain()
 }
@@ -316,6 +355,16 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+// LogIndexStatus returns the number of blocks covered by the log index, zero if
+// it's disabled.
+func (b *EthAPIBackend) LogIndexStatus() uint64 {
+	if b.eth.logIndexer == nil {
+		return 0
+	}
+	sections, _, _ := b.eth.logIndexer.Sections()
+	return sections * params.BloomBitsBlocks
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
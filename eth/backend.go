@@ -18,6 +18,7 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -38,17 +39,22 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vote"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/governor"
+	"github.com/ethereum/go-ethereum/eth/ha"
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/protocols/trace"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -59,6 +65,14 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+const (
+	// evidenceGasLimit is the gas allowance of the double sign evidence submissions.
+	evidenceGasLimit = 1000000
+
+	// maintenanceGasLimit is the gas allowance of the maintenance signalling calls.
+	maintenanceGasLimit = 500000
+)
+
 // Config contains the configuration options of the ETH protocol.
 // Deprecated: use ethconfig.Config instead.
 type Config = ethconfig.Config
@@ -69,6 +83,7 @@ type Ethereum struct {
 
 	// Handlers
 	txPool             *core.TxPool
+	txPrefetcher       *core.TxPrefetcher
 	blockchain         *core.BlockChain
 	handler            *handler
 	ethDialCandidates  enode.Iterator
@@ -83,20 +98,30 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer        *core.ChainIndexer             // Log indexer operating during block imports, nil if disabled
 	closeBloomHandler chan struct{}
 
+	follow      time.Duration // Interval of reloading the head of a read-only data directory, 0 if owned
+	closeFollow chan struct{}
+
 	APIBackend *EthAPIBackend
 
-	miner     *miner.Miner
-	gasPrice  *big.Int
-	etherbase common.Address
+	miner        *miner.Miner
+	voteSigner   vote.Signer
+	protectionDB *vote.ProtectionDB // Slashing protection, nil unless signing votes or failing over
+	failover     *ha.Coordinator    // Validator failover, nil if disabled
+	gasPrice     *big.Int
+	etherbase    common.Address
 
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
-	p2pServer *p2p.Server
+	p2pServer        *p2p.Server
+	sentryValidators []*enode.Node // Hidden validators this node is a sentry for
+	sentries         []*enode.Node // Sentries of this hidden validator
 
-	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
+	lock         sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
+	systemTxLock sync.Mutex   // Serializes the nonce assignment of the local system contract calls
 }
 
 // New creates a new Ethereum object (including the
@@ -134,14 +159,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
-	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideBerlin)
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideBerlin, config.OverrideForks)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
-	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal), config.TriesInMemory); err != nil {
-		log.Error("Failed to recover state", "error", err)
+	// An interrupted pruning is recovered by the node owning the data directory
+	if !stack.Config().DataDirReadOnly {
+		if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal), config.TriesInMemory); err != nil {
+			log.Error("Failed to recover state", "error", err)
+		}
 	}
 	eth := &Ethereum{
 		config:            config,
@@ -149,6 +177,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eventMux:          stack.EventMux(),
 		accountManager:    stack.AccountManager(),
 		closeBloomHandler: make(chan struct{}),
+		closeFollow:       make(chan struct{}),
 		networkID:         config.NetworkId,
 		gasPrice:          config.Miner.GasPrice,
 		etherbase:         config.Miner.Etherbase,
@@ -181,11 +210,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 		}
 	}
+	backend, err := vm.ParseBackend(config.VMBackend)
+	if err != nil {
+		return nil, err
+	}
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
+			Backend:                 backend,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:     config.TrieCleanCache,
@@ -195,10 +229,19 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieDirtyDisabled:  config.NoPruning,
 			TrieTimeLimit:      config.TrieTimeout,
 			SnapshotLimit:      config.SnapshotCache,
+			SnapshotVerifyRate: config.SnapshotVerifyRate,
+			SnapshotRepair:     config.SnapshotRepair,
 			TriesInMemory:      config.TriesInMemory,
 			Preimages:          config.Preimages,
+			ReadOnly:           stack.Config().DataDirReadOnly,
 		}
 	)
+	if config.TrieCleanCacheJournal == "" {
+		cacheConfig.TrieCleanJournal = ""
+	}
+	if stack.Config().DataDirReadOnly {
+		eth.follow = stack.Config().DataDirRefresh
+	}
 	bcOps := make([]core.BlockChainOption, 0)
 	if config.DiffSync {
 		bcOps = append(bcOps, core.EnableLightProcessor)
@@ -206,9 +249,49 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.PipeCommit {
 		bcOps = append(bcOps, core.EnablePipelineCommit)
 	}
+	if config.ParallelTxMode {
+		if config.DiffSync {
+			log.Warn("Parallel transaction execution replaces diff sync processing")
+		}
+		bcOps = append(bcOps, core.EnableParallelProcessor(config.ParallelTxNum))
+	}
 	if config.PersistDiff {
 		bcOps = append(bcOps, core.EnablePersistDiff(config.DiffBlock))
 	}
+	if config.CallIndex || config.Otterscan {
+		bcOps = append(bcOps, core.EnableCallIndex)
+	}
+	if config.Otterscan {
+		bcOps = append(bcOps, core.EnableAccountTxIndex)
+	}
+	if config.SenderIndex {
+		bcOps = append(bcOps, core.EnableSenderTxIndex)
+	}
+	if config.ContractIndex {
+		bcOps = append(bcOps, core.EnableContractIndex)
+	}
+	if config.TokenIndex {
+		bcOps = append(bcOps, core.EnableTokenIndex)
+	}
+	if config.TraceDB {
+		bcOps = append(bcOps, core.EnableBlockTraces)
+	}
+	if config.RevertReasons {
+		bcOps = append(bcOps, core.EnableBlockReverts)
+	}
+	if config.VMBackendVerify > 0 {
+		bcOps = append(bcOps, core.EnableBackendVerification(config.VMBackendVerify))
+	}
+	// The read-only mode serves the index maintained by the running node
+	if !stack.Config().DataDirReadOnly {
+		if len(config.TxLookupAllowlist) > 0 && config.TxLookupLimit > 0 {
+			bcOps = append(bcOps, core.EnableTxLookupAllowlist(config.TxLookupAllowlist))
+		} else if rawdb.ReadTxIndexAllowlist(chainDb) != nil {
+			// The indices kept below the tail stay, but they are no longer maintained
+			log.Info("Transaction index allowlist disabled")
+			rawdb.DeleteTxIndexAllowlist(chainDb)
+		}
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit, bcOps...)
 	if err != nil {
 		return nil, err
@@ -220,11 +303,29 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = core.NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		eth.logIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
+	if config.TxPoolPrefetch {
+		eth.txPrefetcher = core.NewTxPrefetcher(eth.blockchain, eth.txPool)
+	}
+	if p, ok := eth.engine.(*parlia.Parlia); ok && config.DoubleSignReporter != (common.Address{}) {
+		p.SetEvidenceSubmitter(eth.evidenceSubmitter(config.DoubleSignReporter))
+		log.Info("Enabled double sign evidence submission", "reporter", config.DoubleSignReporter)
+	}
+	if p, ok := eth.engine.(*parlia.Parlia); ok && metrics.Enabled {
+		p.EnableEpochMetrics(eth.blockchain)
+	}
+	if p, ok := eth.engine.(*parlia.Parlia); ok && config.TimeWarp {
+		p.EnableTimeWarp()
+		log.Warn("Enabled time warping of the parlia clock")
+	}
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -233,6 +334,31 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		checkpoint = params.TrustedCheckpoints[genesisHash]
 	}
 
+	privateTxPeers := make([]enode.ID, 0, len(config.PrivateTxPeers))
+	for _, url := range config.PrivateTxPeers {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private transaction peer %q: %v", url, err)
+		}
+		privateTxPeers = append(privateTxPeers, node.ID())
+	}
+	var sentryValidators, sentries []enode.ID
+	for _, url := range config.SentryValidators {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sentry validator %q: %v", url, err)
+		}
+		eth.sentryValidators = append(eth.sentryValidators, node)
+		sentryValidators = append(sentryValidators, node.ID())
+	}
+	for _, url := range config.Sentries {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sentry %q: %v", url, err)
+		}
+		eth.sentries = append(eth.sentries, node)
+		sentries = append(sentries, node.ID())
+	}
 	if eth.handler, err = newHandler(&handlerConfig{
 		Database:               chainDb,
 		Chain:                  eth.blockchain,
@@ -243,9 +369,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		EventMux:               eth.eventMux,
 		Checkpoint:             checkpoint,
 		Whitelist:              config.Whitelist,
+		SyncCheckpoint:         config.SyncCheckpoint,
 		DirectBroadcast:        config.DirectBroadcast,
 		DiffSync:               config.DiffSync,
 		DisablePeerTxBroadcast: config.DisablePeerTxBroadcast,
+		PrivateTxPeers:         privateTxPeers,
+		PeerGroups:             config.PeerGroups,
+		SentryValidators:       sentryValidators,
+		Sentries:               sentries,
+		Capabilities:           localCapabilities(config, chainDb),
+		ServeWitness:           config.ServeWitness,
+		TracePropagation:       config.TracePropagation,
 	}); err != nil {
 		return nil, err
 	}
@@ -253,6 +387,42 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
+	if config.VoteSigner.Enabled() || config.HA.Enabled() {
+		// Never sign a vote or, with failover, seal a block without consulting
+		// the slashing protection first
+		protectionDb, err := stack.OpenDatabase(vote.ProtectionDatabaseName, 0, 0, "eth/db/voteprotection/", false)
+		if err != nil {
+			return nil, err
+		}
+		eth.protectionDB = vote.NewProtectionDB(protectionDb)
+	}
+	if config.VoteSigner.Enabled() {
+		signer, err := vote.NewSigner(config.VoteSigner)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("Delegating vote signing to remote signer", "url", config.VoteSigner.RemoteSignerURL, "pubkey", config.VoteSigner.RemotePublicKey)
+		eth.voteSigner = vote.NewProtectedSigner(signer, eth.protectionDB)
+	}
+	if config.HA.Enabled() {
+		engine, ok := eth.engine.(*parlia.Parlia)
+		if !ok {
+			return nil, errors.New("validator failover requires the parlia engine")
+		}
+		engine.SetSealGuard(eth.protectionDB.CheckAndRecordSeal)
+		if eth.failover, err = ha.New(config.HA, &failoverBackend{eth}); err != nil {
+			return nil, err
+		}
+		stack.RegisterLifecycle(eth.failover)
+	}
+	if config.Governor.Enabled() {
+		gov, err := governor.New(config.Governor, stack.InstanceDir(), &governorBackend{eth, stack})
+		if err != nil {
+			return nil, err
+		}
+		stack.RegisterLifecycle(gov)
+	}
+
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -318,6 +488,9 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	if s.failover != nil {
+		apis = append(apis, s.failover.APIs()...)
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -340,10 +513,20 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateMinerAPI(s),
 			Public:    false,
+		}, {
+			Namespace: "validator",
+			Version:   "1.0",
+			Service:   NewPrivateValidatorAPI(s),
+			Public:    false,
+		}, {
+			Namespace: "mev",
+			Version:   "1.0",
+			Service:   NewPublicMevAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.RangeLimit),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.LogsLimits()),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -451,6 +634,34 @@ func (s *Ethereum) shouldPreserve(block *types.Block) bool {
 	return s.isLocalBlock(block)
 }
 
+// evidenceSubmitter returns a parlia evidence submitter sending the evidence in
+// transactions signed by the given unlocked account.
+func (s *Ethereum) evidenceSubmitter(reporter common.Address) parlia.EvidenceSubmitter {
+	return func(to common.Address, data []byte) error {
+		_, err := s.sendSystemTx(reporter, to, data, evidenceGasLimit)
+		return err
+	}
+}
+
+// sendSystemTx signs a contract call with an unlocked local account and adds it
+// to the transaction pool.
+func (s *Ethereum) sendSystemTx(from, to common.Address, data []byte, gas uint64) (*types.Transaction, error) {
+	account := accounts.Account{Address: from}
+	wallet, err := s.accountManager.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	s.systemTxLock.Lock()
+	defer s.systemTxLock.Unlock()
+
+	tx := types.NewTransaction(s.txPool.Nonce(from), to, common.Big0, gas, s.txPool.GasPrice(), data)
+	signed, err := wallet.SignTx(account, tx, s.blockchain.Config().ChainID)
+	if err != nil {
+		return nil, err
+	}
+	return signed, s.txPool.AddLocal(signed)
+}
+
 // SetEtherbase sets the mining reward address.
 func (s *Ethereum) SetEtherbase(etherbase common.Address) {
 	s.lock.Lock()
@@ -529,8 +740,9 @@ func (s *Ethereum) StopMining() {
 	s.miner.Stop()
 }
 
-func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
-func (s *Ethereum) Miner() *miner.Miner { return s.miner }
+func (s *Ethereum) IsMining() bool          { return s.miner.Mining() }
+func (s *Ethereum) Miner() *miner.Miner     { return s.miner }
+func (s *Ethereum) VoteSigner() vote.Signer { return s.voteSigner }
 
 func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
 func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
@@ -544,6 +756,25 @@ func (s *Ethereum) Synced() bool                       { return atomic.LoadUint3
 func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
 func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
 
+// localCapabilities returns the services the node advertises to its peers.
+func localCapabilities(config *ethconfig.Config, db ethdb.Database) eth.Capabilities {
+	var caps eth.Capabilities
+	if !config.DisableSnapProtocol && config.SnapshotCache > 0 {
+		caps |= eth.CapSnapServing
+	}
+	if !config.DisablePeerTxBroadcast {
+		caps |= eth.CapTxRelay
+	}
+	// Pruned nodes have lost the ancient part of the chain
+	if rawdb.ReadOffSetOfCurrentAncientFreezer(db) == 0 {
+		caps |= eth.CapAncientHeaders
+	}
+	if config.ServeWitness {
+		caps |= eth.CapWitness
+	}
+	return caps
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -553,30 +784,113 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	}
 	// diff protocol can still open without snap protocol
 	protos = append(protos, diff.MakeProtocols((*diffHandler)(s.handler), s.snapDialCandidates)...)
+	if s.handler.tracer != nil {
+		protos = append(protos, trace.MakeProtocols((*traceHandler)(s.handler))...)
+	}
 	return protos
 }
 
+// ethPeerLimit returns the maximum number of eth peers out of the given total peer
+// count, reserving the light client slots if serving light clients.
+func (s *Ethereum) ethPeerLimit(total int) (int, error) {
+	if s.config.LightServ > 0 {
+		if s.config.LightPeers >= total {
+			return 0, fmt.Errorf("invalid peer config: light peer count (%d) >= total peer count (%d)", s.config.LightPeers, total)
+		}
+		return total - s.config.LightPeers, nil
+	}
+	return total, nil
+}
+
+// SetMaxPeers changes the maximum number of peers of the node. Peers above the
+// new limit stay connected, but no new ones are accepted until the count drops.
+func (s *Ethereum) SetMaxPeers(total int) error {
+	maxPeers, err := s.ethPeerLimit(total)
+	if err != nil {
+		return err
+	}
+	s.p2pServer.SetMaxPeers(total)
+	atomic.StoreInt32(&s.handler.maxPeers, int32(maxPeers))
+	log.Info("Updated maximum peer count", "eth", maxPeers, "total", total)
+	return nil
+}
+
 // Start implements node.Lifecycle, starting all internal goroutines needed by the
 // Ethereum protocol implementation.
 func (s *Ethereum) Start() error {
 	eth.StartENRUpdater(s.blockchain, s.p2pServer.LocalNode())
+	if s.handler.tracer != nil {
+		s.handler.tracer.setSelf(s.p2pServer.LocalNode().ID())
+	}
 
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
-	// Figure out a max peers count based on the server limits
-	maxPeers := s.p2pServer.MaxPeers
-	if s.config.LightServ > 0 {
-		if s.config.LightPeers >= s.p2pServer.MaxPeers {
-			return fmt.Errorf("invalid peer config: light peer count (%d) >= total peer count (%d)", s.config.LightPeers, s.p2pServer.MaxPeers)
+	// Follow the node owning a read-only data directory
+	if s.follow > 0 {
+		go s.followHead()
+	}
+
+	// Make sure a configured remote vote signer is reachable, but don't refuse
+	// to start as the signer may simply come online later.
+	if signer, ok := s.voteSigner.(*vote.ProtectedSigner); ok {
+		if remote, ok := signer.Signer().(*vote.RemoteSigner); ok {
+			if err := remote.Check(context.Background()); err != nil {
+				log.Warn("Remote vote signer unavailable", "url", s.config.VoteSigner.RemoteSignerURL, "err", err)
+			}
 		}
-		maxPeers -= s.config.LightPeers
+	}
+
+	// Figure out a max peers count based on the server limits
+	maxPeers, err := s.ethPeerLimit(s.p2pServer.MaxPeers)
+	if err != nil {
+		return err
+	}
+	// Keep the members of the protected peer groups connected regardless of the
+	// peer limits
+	for _, node := range s.handler.groups.protectedNodes() {
+		s.p2pServer.AddTrustedPeer(node)
+	}
+	// Accept the hidden validators behind this sentry regardless of the peer
+	// limits, they dial in themselves. A hidden validator keeps its sentries
+	// connected instead.
+	for _, node := range s.sentryValidators {
+		s.p2pServer.AddTrustedPeer(node)
+	}
+	for _, node := range s.sentries {
+		s.p2pServer.AddTrustedPeer(node)
+		s.p2pServer.AddPeer(node)
+	}
+	if len(s.sentries) > 0 && (!s.p2pServer.NoDiscovery || s.p2pServer.DiscoveryV5) {
+		log.Warn("Hidden validator with discovery enabled, its address may leak", "sentries", len(s.sentries))
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
 	return nil
 }
 
+// followHead reloads the chain head imported by the node owning a read-only data
+// directory, after the database caught up with it.
+func (s *Ethereum) followHead() {
+	ticker := time.NewTicker(s.follow)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			moved, err := s.blockchain.ReloadHead()
+			if err != nil {
+				log.Warn("Failed to reload chain head", "err", err)
+			} else if moved {
+				head := s.blockchain.CurrentBlock()
+				log.Debug("Reloaded chain head", "number", head.Number(), "hash", head.Hash())
+			}
+		case <-s.closeFollow:
+			return
+		}
+	}
+}
+
 // Stop implements node.Lifecycle, terminating all internal goroutines used by the
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
@@ -587,7 +901,14 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	close(s.closeBloomHandler)
+	close(s.closeFollow)
+	if s.txPrefetcher != nil {
+		s.txPrefetcher.Stop()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.miner.Close()
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -52,6 +53,12 @@ func Register(stack *node.Node, backend *eth.Ethereum) error {
 			Service:   newConsensusAPI(backend),
 			Public:    true,
 		},
+		{
+			Namespace: "engine",
+			Version:   "1.0",
+			Service:   newEngineAPI(backend),
+			Public:    true,
+		},
 	})
 	return nil
 }
@@ -88,13 +95,13 @@ func (env *blockExecutionEnv) commitTransaction(tx *types.Transaction, coinbase
 	return nil
 }
 
-func (api *consensusAPI) makeEnv(parent *types.Block, header *types.Header) (*blockExecutionEnv, error) {
-	state, err := api.eth.BlockChain().StateAt(parent.Root())
+func makeEnv(chain *core.BlockChain, parent *types.Block, header *types.Header) (*blockExecutionEnv, error) {
+	state, err := chain.StateAt(parent.Root())
 	if err != nil {
 		return nil, err
 	}
 	env := &blockExecutionEnv{
-		chain:   api.eth.BlockChain(),
+		chain:   chain,
 		state:   state,
 		header:  header,
 		gasPool: new(core.GasPool).AddGas(header.GasLimit),
@@ -114,8 +121,6 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 		return nil, fmt.Errorf("cannot assemble block with unknown parent %s", params.ParentHash)
 	}
 
-	pool := api.eth.TxPool()
-
 	if parent.Time() >= params.Timestamp {
 		return nil, fmt.Errorf("child timestamp lower than parent's: %d >= %d", parent.Time(), params.Timestamp)
 	}
@@ -125,15 +130,39 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 		time.Sleep(wait)
 	}
 
-	pending, err := pool.Pending()
+	coinbase, err := api.eth.Etherbase()
 	if err != nil {
 		return nil, err
 	}
+	block, err := buildBlock(api.eth, parent, params.Timestamp, coinbase, common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	return &executableData{
+		BlockHash:    block.Hash(),
+		ParentHash:   block.ParentHash(),
+		Miner:        block.Coinbase(),
+		StateRoot:    block.Root(),
+		Number:       block.NumberU64(),
+		GasLimit:     block.GasLimit(),
+		GasUsed:      block.GasUsed(),
+		Timestamp:    block.Time(),
+		ReceiptRoot:  block.ReceiptHash(),
+		LogsBloom:    block.Bloom().Bytes(),
+		Transactions: encodeTransactions(block.Transactions()),
+	}, nil
+}
 
-	coinbase, err := api.eth.Etherbase()
+// buildBlock creates a new block on top of the given parent out of the pending
+// transactions of the pool. Consensus engines requiring a specific mix digest
+// override the given random value.
+func buildBlock(backend *eth.Ethereum, parent *types.Block, timestamp uint64, coinbase common.Address, random common.Hash) (*types.Block, error) {
+	bc := backend.BlockChain()
+	pending, err := backend.TxPool().Pending()
 	if err != nil {
 		return nil, err
 	}
+
 	num := parent.Number()
 	header := &types.Header{
 		ParentHash: parent.Hash(),
@@ -141,14 +170,19 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 		Coinbase:   coinbase,
 		GasLimit:   parent.GasLimit(), // Keep the gas limit constant in this prototype
 		Extra:      []byte{},
-		Time:       params.Timestamp,
+		Time:       timestamp,
+		MixDigest:  random,
+	}
+	// Set the base fee once the fork activated it
+	if bc.Config().IsBaseFee(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(bc.Config(), parent.Header())
 	}
-	err = api.eth.Engine().Prepare(bc, header)
+	err = backend.Engine().Prepare(bc, header)
 	if err != nil {
 		return nil, err
 	}
 
-	env, err := api.makeEnv(parent, header)
+	env, err := makeEnv(bc, parent, header)
 	if err != nil {
 		return nil, err
 	}
@@ -205,23 +239,8 @@ func (api *consensusAPI) AssembleBlock(params assembleBlockParams) (*executableD
 	}
 
 	// Create the block.
-	block, _, err := api.eth.Engine().FinalizeAndAssemble(bc, header, env.state, transactions, nil /* uncles */, env.receipts)
-	if err != nil {
-		return nil, err
-	}
-	return &executableData{
-		BlockHash:    block.Hash(),
-		ParentHash:   block.ParentHash(),
-		Miner:        block.Coinbase(),
-		StateRoot:    block.Root(),
-		Number:       block.NumberU64(),
-		GasLimit:     block.GasLimit(),
-		GasUsed:      block.GasUsed(),
-		Timestamp:    block.Time(),
-		ReceiptRoot:  block.ReceiptHash(),
-		LogsBloom:    block.Bloom().Bytes(),
-		Transactions: encodeTransactions(block.Transactions()),
-	}, nil
+	block, _, err := backend.Engine().FinalizeAndAssemble(bc, header, env.state, transactions, nil /* uncles */, env.receipts)
+	return block, err
 }
 
 func encodeTransactions(txs []*types.Transaction) [][]byte {
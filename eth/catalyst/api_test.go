@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -219,6 +221,125 @@ func TestEth2NewBlock(t *testing.T) {
 	}
 }
 
+func TestEngineBuildAndImportPayload(t *testing.T) {
+	genesis, blocks, _ := generateTestChainWithFork(10, 4)
+	n, ethservice := startEthService(t, genesis, blocks[1:9])
+	defer n.Close()
+
+	api := newEngineAPI(ethservice)
+	signer := types.NewEIP155Signer(ethservice.BlockChain().Config().ChainID)
+	tx, err := types.SignTx(types.NewTransaction(0, blocks[8].Coinbase(), big.NewInt(1000), params.TxGas, nil, nil), signer, testKey)
+	if err != nil {
+		t.Fatalf("error signing transaction, err=%v", err)
+	}
+	ethservice.TxPool().AddLocal(tx)
+
+	head := blocks[8]
+	state := forkchoiceStateV1{HeadBlockHash: head.Hash()}
+	attrs := &payloadAttributesV1{Timestamp: hexutil.Uint64(head.Time() + 5), Random: common.Hash{0x01}}
+	res, err := api.ForkchoiceUpdatedV1(state, attrs)
+	if err != nil {
+		t.Fatalf("failed to start building payload: %v", err)
+	}
+	if res.PayloadStatus.Status != statusValid || res.PayloadID == nil {
+		t.Fatalf("unexpected forkchoice response: %+v", res)
+	}
+	payload, err := api.GetPayloadV1(*res.PayloadID)
+	if err != nil {
+		t.Fatalf("failed to retrieve payload: %v", err)
+	}
+	if len(payload.Transactions) != 1 {
+		t.Fatalf("invalid number of transactions: have %d, want 1", len(payload.Transactions))
+	}
+	if payload.Random != attrs.Random {
+		t.Fatalf("invalid random: have %x, want %x", payload.Random, attrs.Random)
+	}
+	if _, err := api.GetPayloadV1(payloadID{0xff}); err != errUnknownPayload {
+		t.Fatalf("unexpected error for unknown payload: %v", err)
+	}
+	// Payloads with a tampered block hash are rejected
+	tampered := *payload
+	tampered.BlockHash = common.Hash{0x01}
+	if status, _ := api.NewPayloadV1(tampered); status.Status != statusInvalidBlockHash {
+		t.Fatalf("unexpected status for tampered payload: %s", status.Status)
+	}
+	// Payloads with an unknown parent can't be validated
+	orphan := blockToExecutableData(blocks[10])
+	if status, _ := api.NewPayloadV1(*orphan); status.Status != statusSyncing {
+		t.Fatalf("unexpected status for orphan payload: %s", status.Status)
+	}
+	status, err := api.NewPayloadV1(*payload)
+	if err != nil || status.Status != statusValid {
+		t.Fatalf("failed to import payload: %v %+v", err, status)
+	}
+	if *status.LatestValidHash != payload.BlockHash {
+		t.Fatalf("invalid latest valid hash: have %x, want %x", *status.LatestValidHash, payload.BlockHash)
+	}
+	res, err = api.ForkchoiceUpdatedV1(forkchoiceStateV1{HeadBlockHash: payload.BlockHash, FinalizedBlockHash: head.Hash()}, nil)
+	if err != nil || res.PayloadStatus.Status != statusValid {
+		t.Fatalf("failed to update forkchoice: %v %+v", err, res)
+	}
+	if current := ethservice.BlockChain().CurrentBlock(); current.Hash() != payload.BlockHash {
+		t.Fatalf("wrong head: have %x, want %x", current.Hash(), payload.BlockHash)
+	}
+	// Stale payload attributes are rejected
+	attrs.Timestamp = hexutil.Uint64(head.Time())
+	if _, err := api.ForkchoiceUpdatedV1(forkchoiceStateV1{HeadBlockHash: payload.BlockHash}, attrs); err != errInvalidPayloadAttributes {
+		t.Fatalf("unexpected error for stale attributes: %v", err)
+	}
+}
+
+func TestEngineForkchoiceReorg(t *testing.T) {
+	genesis, blocks, forkedBlocks := generateTestChainWithFork(10, 4)
+	n, ethservice := startEthService(t, genesis, blocks[1:])
+	defer n.Close()
+
+	api := newEngineAPI(ethservice)
+	for i, block := range forkedBlocks[:5] {
+		status, err := api.NewPayloadV1(*blockToExecutableData(block))
+		if err != nil || status.Status != statusValid {
+			t.Fatalf("failed to import forked payload %d: %v %+v", i, err, status)
+		}
+	}
+	// The fork doesn't outweigh the canonical chain, so the head stays put
+	chain := ethservice.BlockChain()
+	if current := chain.CurrentBlock(); current.Hash() != blocks[10].Hash() {
+		t.Fatalf("wrong head after importing fork: have %x, want %x", current.Hash(), blocks[10].Hash())
+	}
+	// A finalized block off the new head is an invalid forkchoice state
+	fork := forkedBlocks[4]
+	state := forkchoiceStateV1{HeadBlockHash: fork.Hash(), FinalizedBlockHash: blocks[6].Hash()}
+	if _, err := api.ForkchoiceUpdatedV1(state, nil); err != errInvalidForkchoiceState {
+		t.Fatalf("unexpected error for invalid finalized block: %v", err)
+	}
+	state.FinalizedBlockHash = blocks[4].Hash()
+	res, err := api.ForkchoiceUpdatedV1(state, nil)
+	if err != nil || res.PayloadStatus.Status != statusValid {
+		t.Fatalf("failed to update forkchoice: %v %+v", err, res)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != fork.Hash() {
+		t.Fatalf("wrong head after forkchoice update: have %x, want %x", current.Hash(), fork.Hash())
+	}
+	for i := 5; i <= 9; i++ {
+		if hash := chain.GetCanonicalHash(uint64(i)); hash != forkedBlocks[i-5].Hash() {
+			t.Fatalf("block %d not reorged: have %x, want %x", i, hash, forkedBlocks[i-5].Hash())
+		}
+	}
+	// Moving back to a canonical ancestor is ignored
+	res, err = api.ForkchoiceUpdatedV1(forkchoiceStateV1{HeadBlockHash: blocks[4].Hash()}, nil)
+	if err != nil || res.PayloadStatus.Status != statusValid {
+		t.Fatalf("failed to update forkchoice: %v %+v", err, res)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != fork.Hash() {
+		t.Fatalf("head moved back to ancestor: have %x, want %x", current.Hash(), fork.Hash())
+	}
+	// Unknown heads report syncing
+	res, err = api.ForkchoiceUpdatedV1(forkchoiceStateV1{HeadBlockHash: common.Hash{0x01}}, nil)
+	if err != nil || res.PayloadStatus.Status != statusSyncing {
+		t.Fatalf("unexpected response for unknown head: %v %+v", err, res)
+	}
+}
+
 // startEthService creates a full node instance for testing.
 func startEthService(t *testing.T, genesis *core.Genesis, blocks []*types.Block) (*node.Node, *eth.Ethereum) {
 	t.Helper()
@@ -68,3 +68,66 @@ type newBlockResponse struct {
 type genericResponse struct {
 	Success bool `json:"success"`
 }
+
+// The statuses of a payload, as reported by the engine API.
+const (
+	statusValid            = "VALID"
+	statusInvalid          = "INVALID"
+	statusSyncing          = "SYNCING"
+	statusInvalidBlockHash = "INVALID_BLOCK_HASH"
+)
+
+// Structure described at https://github.com/ethereum/execution-apis/blob/main/src/engine/paris.md#forkchoicestatev1
+type forkchoiceStateV1 struct {
+	HeadBlockHash      common.Hash `json:"headBlockHash"`
+	SafeBlockHash      common.Hash `json:"safeBlockHash"`
+	FinalizedBlockHash common.Hash `json:"finalizedBlockHash"`
+}
+
+// Structure described at https://github.com/ethereum/execution-apis/blob/main/src/engine/paris.md#payloadattributesv1
+type payloadAttributesV1 struct {
+	Timestamp             hexutil.Uint64 `json:"timestamp"`
+	Random                common.Hash    `json:"prevRandao"`
+	SuggestedFeeRecipient common.Address `json:"suggestedFeeRecipient"`
+}
+
+// Structure described at https://github.com/ethereum/execution-apis/blob/main/src/engine/paris.md#executionpayloadv1
+type executableDataV1 struct {
+	ParentHash    common.Hash     `json:"parentHash"`
+	FeeRecipient  common.Address  `json:"feeRecipient"`
+	StateRoot     common.Hash     `json:"stateRoot"`
+	ReceiptsRoot  common.Hash     `json:"receiptsRoot"`
+	LogsBloom     hexutil.Bytes   `json:"logsBloom"`
+	Random        common.Hash     `json:"prevRandao"`
+	Number        hexutil.Uint64  `json:"blockNumber"`
+	GasLimit      hexutil.Uint64  `json:"gasLimit"`
+	GasUsed       hexutil.Uint64  `json:"gasUsed"`
+	Timestamp     hexutil.Uint64  `json:"timestamp"`
+	ExtraData     hexutil.Bytes   `json:"extraData"`
+	BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas"`
+	BlockHash     common.Hash     `json:"blockHash"`
+	Transactions  []hexutil.Bytes `json:"transactions"`
+}
+
+// Structure described at https://github.com/ethereum/execution-apis/blob/main/src/engine/paris.md#payloadstatusv1
+type payloadStatusV1 struct {
+	Status          string       `json:"status"`
+	LatestValidHash *common.Hash `json:"latestValidHash"`
+	ValidationError *string      `json:"validationError"`
+}
+
+type forkchoiceResponse struct {
+	PayloadStatus payloadStatusV1 `json:"payloadStatus"`
+	PayloadID     *payloadID      `json:"payloadId"`
+}
+
+// payloadID identifies a payload being built by the engine API.
+type payloadID [8]byte
+
+func (id payloadID) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(id[:]).MarshalText()
+}
+
+func (id *payloadID) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("payloadID", input, id[:])
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// maxTrackedPayloads is the number of built payloads kept for engine_getPayloadV1.
+const maxTrackedPayloads = 10
+
+// engineError is an error of the engine API, carrying its JSON-RPC error code.
+type engineError struct {
+	code    int
+	message string
+}
+
+func (e *engineError) Error() string  { return e.message }
+func (e *engineError) ErrorCode() int { return e.code }
+
+var (
+	errUnknownPayload           = &engineError{code: -38001, message: "Unknown payload"}
+	errInvalidForkchoiceState   = &engineError{code: -38002, message: "Invalid forkchoice state"}
+	errInvalidPayloadAttributes = &engineError{code: -38003, message: "Invalid payload attributes"}
+)
+
+// engineAPI implements the V1 methods of the engine API, letting an external
+// consensus driver insert blocks, settle the head of the chain and build blocks
+// on top of it.
+//
+// The payloads are imported with the regular fork choice rule, so the ones
+// extending the head become canonical on import; engine_forkchoiceUpdatedV1
+// then moves the head to the block chosen by the driver. The safe and finalized
+// blocks are checked against the canonical chain, but not tracked.
+type engineAPI struct {
+	eth *eth.Ethereum
+
+	lock     sync.Mutex
+	payloads []*trackedPayload // Built payloads, oldest first
+}
+
+// trackedPayload is a payload built by engine_forkchoiceUpdatedV1.
+type trackedPayload struct {
+	id    payloadID
+	block *types.Block
+}
+
+func newEngineAPI(eth *eth.Ethereum) *engineAPI {
+	return &engineAPI{eth: eth}
+}
+
+// NewPayloadV1 executes a payload and inserts it into the chain, reporting
+// whether it's valid.
+func (api *engineAPI) NewPayloadV1(params executableDataV1) (payloadStatusV1, error) {
+	block, err := executableDataToBlock(params)
+	if err != nil {
+		return invalidStatus(nil, err), nil
+	}
+	if block.Hash() != params.BlockHash {
+		log.Warn("Invalid payload block hash", "have", block.Hash(), "want", params.BlockHash)
+		return payloadStatusV1{Status: statusInvalidBlockHash}, nil
+	}
+	bc := api.eth.BlockChain()
+	if bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+		return validStatus(block.Hash()), nil
+	}
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		log.Debug("Payload with unknown parent", "number", block.NumberU64(), "hash", block.Hash(), "parent", block.ParentHash())
+		return payloadStatusV1{Status: statusSyncing}, nil
+	}
+	if _, err := bc.InsertChainWithoutSealVerification(block); err != nil {
+		log.Warn("Invalid payload", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+		hash := parent.Hash()
+		return invalidStatus(&hash, err), nil
+	}
+	return validStatus(block.Hash()), nil
+}
+
+// ForkchoiceUpdatedV1 makes the given head block the head of the canonical chain
+// and, if payload attributes are given, starts building a payload on top of it.
+func (api *engineAPI) ForkchoiceUpdatedV1(state forkchoiceStateV1, attrs *payloadAttributesV1) (forkchoiceResponse, error) {
+	if state.HeadBlockHash == (common.Hash{}) {
+		return forkchoiceResponse{PayloadStatus: invalidStatus(nil, errors.New("zero head block hash"))}, nil
+	}
+	bc := api.eth.BlockChain()
+	head := bc.GetBlockByHash(state.HeadBlockHash)
+	if head == nil {
+		log.Debug("Forkchoice update to unknown head", "hash", state.HeadBlockHash)
+		return forkchoiceResponse{PayloadStatus: payloadStatusV1{Status: statusSyncing}}, nil
+	}
+	// Moving the head back to a canonical ancestor is skipped, along with the
+	// payload building, as allowed by the specification.
+	stale := false
+	if current := bc.CurrentBlock(); current.Hash() != head.Hash() {
+		if bc.GetCanonicalHash(head.NumberU64()) == head.Hash() {
+			log.Info("Ignoring forkchoice update to an old head", "number", head.NumberU64(), "hash", head.Hash())
+			stale = true
+		} else if err := bc.SetCanonical(head); err != nil {
+			return forkchoiceResponse{}, err
+		}
+	}
+	// The safe and finalized blocks must be canonical ancestors of the head
+	for _, hash := range []common.Hash{state.SafeBlockHash, state.FinalizedBlockHash} {
+		if hash == (common.Hash{}) {
+			continue
+		}
+		header := bc.GetHeaderByHash(hash)
+		if header == nil || header.Number.Cmp(head.Number()) > 0 || bc.GetCanonicalHash(header.Number.Uint64()) != hash {
+			return forkchoiceResponse{}, errInvalidForkchoiceState
+		}
+	}
+	res := forkchoiceResponse{PayloadStatus: validStatus(head.Hash())}
+	if attrs == nil || stale {
+		return res, nil
+	}
+	if uint64(attrs.Timestamp) <= head.Time() {
+		return forkchoiceResponse{}, errInvalidPayloadAttributes
+	}
+	block, err := buildBlock(api.eth, head, uint64(attrs.Timestamp), attrs.SuggestedFeeRecipient, attrs.Random)
+	if err != nil {
+		log.Error("Failed to build payload", "parent", head.Hash(), "err", err)
+		return forkchoiceResponse{}, err
+	}
+	id := computePayloadID(head.Hash(), attrs)
+	api.track(id, block)
+	res.PayloadID = &id
+
+	log.Info("Built payload", "id", hexutil.Bytes(id[:]), "number", block.NumberU64(), "hash", block.Hash(), "txs", len(block.Transactions()))
+	return res, nil
+}
+
+// GetPayloadV1 returns a payload built by engine_forkchoiceUpdatedV1.
+func (api *engineAPI) GetPayloadV1(id payloadID) (*executableDataV1, error) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	for _, payload := range api.payloads {
+		if payload.id == id {
+			return blockToExecutableData(payload.block), nil
+		}
+	}
+	return nil, errUnknownPayload
+}
+
+// track stores a built payload, evicting the oldest one past maxTrackedPayloads.
+func (api *engineAPI) track(id payloadID, block *types.Block) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	for i, payload := range api.payloads {
+		if payload.id == id {
+			api.payloads = append(api.payloads[:i], api.payloads[i+1:]...)
+			break
+		}
+	}
+	api.payloads = append(api.payloads, &trackedPayload{id: id, block: block})
+	if len(api.payloads) > maxTrackedPayloads {
+		api.payloads = api.payloads[1:]
+	}
+}
+
+// computePayloadID derives the id of a payload from its parent and attributes.
+func computePayloadID(parent common.Hash, attrs *payloadAttributesV1) payloadID {
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(timestamp[:], uint64(attrs.Timestamp))
+
+	var id payloadID
+	copy(id[:], crypto.Keccak256(parent[:], timestamp[:], attrs.Random[:], attrs.SuggestedFeeRecipient[:]))
+	return id
+}
+
+func validStatus(hash common.Hash) payloadStatusV1 {
+	return payloadStatusV1{Status: statusValid, LatestValidHash: &hash}
+}
+
+func invalidStatus(latestValid *common.Hash, err error) payloadStatusV1 {
+	msg := err.Error()
+	return payloadStatusV1{Status: statusInvalid, LatestValidHash: latestValid, ValidationError: &msg}
+}
+
+// executableDataToBlock assembles the block described by an engine API payload.
+func executableDataToBlock(params executableDataV1) (*types.Block, error) {
+	txs := make([]*types.Transaction, len(params.Transactions))
+	for i, enc := range params.Transactions {
+		var tx types.Transaction
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		txs[i] = &tx
+	}
+	if len(params.LogsBloom) != types.BloomByteLength {
+		return nil, fmt.Errorf("invalid logsBloom length: %d", len(params.LogsBloom))
+	}
+	header := &types.Header{
+		ParentHash:  params.ParentHash,
+		UncleHash:   types.EmptyUncleHash,
+		Coinbase:    params.FeeRecipient,
+		Root:        params.StateRoot,
+		TxHash:      types.DeriveSha(types.Transactions(txs), trie.NewStackTrie(nil)),
+		ReceiptHash: params.ReceiptsRoot,
+		Bloom:       types.BytesToBloom(params.LogsBloom),
+		Difficulty:  big.NewInt(1),
+		Number:      new(big.Int).SetUint64(uint64(params.Number)),
+		GasLimit:    uint64(params.GasLimit),
+		GasUsed:     uint64(params.GasUsed),
+		Time:        uint64(params.Timestamp),
+		Extra:       params.ExtraData,
+		MixDigest:   params.Random,
+		BaseFee:     (*big.Int)(params.BaseFeePerGas),
+	}
+	return types.NewBlockWithHeader(header).WithBody(txs, nil /* uncles */), nil
+}
+
+// blockToExecutableData describes a block as an engine API payload.
+func blockToExecutableData(block *types.Block) *executableDataV1 {
+	txs := make([]hexutil.Bytes, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		txs[i], _ = tx.MarshalBinary()
+	}
+	return &executableDataV1{
+		ParentHash:    block.ParentHash(),
+		FeeRecipient:  block.Coinbase(),
+		StateRoot:     block.Root(),
+		ReceiptsRoot:  block.ReceiptHash(),
+		LogsBloom:     block.Bloom().Bytes(),
+		Random:        block.MixDigest(),
+		Number:        hexutil.Uint64(block.NumberU64()),
+		GasLimit:      hexutil.Uint64(block.GasLimit()),
+		GasUsed:       hexutil.Uint64(block.GasUsed()),
+		Timestamp:     hexutil.Uint64(block.Time()),
+		ExtraData:     block.Extra(),
+		BaseFeePerGas: (*hexutil.Big)(block.BaseFee()),
+		BlockHash:     block.Hash(),
+		Transactions:  txs,
+	}
+}
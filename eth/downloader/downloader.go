@@ -163,6 +163,11 @@ type Downloader struct {
 	quitCh   chan struct{} // Quit channel to signal termination
 	quitLock sync.Mutex    // Lock to prevent double closes
 
+	blockInterval func(number uint64) time.Duration // Block interval of the chain at a given height, if known
+
+	syncCheckpoint       common.Hash // Trusted block fast sync only pivots to chains containing, if set
+	syncCheckpointNumber uint64      // Number of the trusted block, as reported by the sync peer
+
 	// Testing hooks
 	syncInitHook     func(uint64, uint64)                // Method to call upon initiating a new sync run
 	bodyFetchHook    func([]*types.Header)               // Method to call upon starting a block body fetch
@@ -268,6 +273,24 @@ func EnableDiffFetchOp(peers IPeerSet) DownloadOption {
 	}
 }
 
+// EnableBlockIntervalOp makes the downloader pace its waits for new headers by
+// the block interval of the chain instead of the default one.
+func EnableBlockIntervalOp(interval func(number uint64) time.Duration) DownloadOption {
+	return func(dl *Downloader) *Downloader {
+		dl.blockInterval = interval
+		return dl
+	}
+}
+
+// EnableSyncCheckpoint pins fast sync to chains containing the given trusted
+// block, dropping the peers whose canonical chain lacks it.
+func EnableSyncCheckpoint(hash common.Hash) DownloadOption {
+	return func(dl *Downloader) *Downloader {
+		dl.syncCheckpoint = hash
+		return dl
+	}
+}
+
 // New creates a new downloader to fetch hashes and blocks from remote peers.
 func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn, options ...DownloadOption) *Downloader {
 	if lightchain == nil {
@@ -310,6 +333,17 @@ func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom,
 	return dl
 }
 
+// headerContCheck returns the interval to check for header continuations at the
+// given height, which is the block interval of the chain if it is known.
+func (d *Downloader) headerContCheck(number uint64) time.Duration {
+	if d.blockInterval != nil {
+		if interval := d.blockInterval(number); interval > 0 {
+			return interval
+		}
+	}
+	return fsHeaderContCheck
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -547,6 +581,11 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
+	if mode == FastSync && d.syncCheckpoint != (common.Hash{}) {
+		if d.syncCheckpointNumber, err = d.checkSyncCheckpoint(p, latest); err != nil {
+			return err
+		}
+	}
 	origin, err := d.findAncestor(p, latest)
 	if err != nil {
 		return err
@@ -774,6 +813,72 @@ func (d *Downloader) fetchHead(p *peerConnection) (head *types.Header, pivot *ty
 	}
 }
 
+// checkSyncCheckpoint ensures that the canonical chain of the remote peer
+// contains the trusted block fast sync is pinned to, returning its number.
+func (d *Downloader) checkSyncCheckpoint(p *peerConnection, head *types.Header) (uint64, error) {
+	go p.peer.RequestHeadersByHash(d.syncCheckpoint, 1, 0, false)
+
+	header, err := d.fetchSingleHeader(p)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil || header.Hash() != d.syncCheckpoint {
+		return 0, fmt.Errorf("%w: remote chain lacks sync checkpoint %x", errUnsyncedPeer, d.syncCheckpoint)
+	}
+	number := header.Number.Uint64()
+	if head.Number.Uint64() < number {
+		return 0, fmt.Errorf("%w: remote head %d below sync checkpoint %d", errUnsyncedPeer, head.Number, number)
+	}
+	// The peer might know the block from a side chain only, it must be canonical
+	go p.peer.RequestHeadersByNumber(number, 1, 0, false)
+
+	header, err = d.fetchSingleHeader(p)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil || header.Hash() != d.syncCheckpoint {
+		return 0, fmt.Errorf("%w: sync checkpoint %x not canonical in remote chain", errInvalidChain, d.syncCheckpoint)
+	}
+	p.log.Debug("Remote chain contains sync checkpoint", "number", number, "hash", d.syncCheckpoint)
+	return number, nil
+}
+
+// fetchSingleHeader waits for the reply of the peer to a request of at most
+// one header, returning nil if the peer doesn't know the requested header.
+func (d *Downloader) fetchSingleHeader(p *peerConnection) (*types.Header, error) {
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCanceled
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			if len(headers) > 1 {
+				return nil, fmt.Errorf("%w: returned headers %d != requested 1", errBadPeer, len(headers))
+			}
+			if len(headers) == 0 {
+				return nil, nil
+			}
+			return headers[0], nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for header timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // calculateRequestSpan calculates what headers to request from a peer when trying to determine the
 // common ancestor.
 // It returns parameters to be used for peer.RequestHeadersByNumber:
@@ -1186,7 +1291,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 				if atomic.LoadInt32(&d.committed) == 0 && pivot <= from {
 					p.log.Debug("No headers, waiting for pivot commit")
 					select {
-					case <-time.After(fsHeaderContCheck):
+					case <-time.After(d.headerContCheck(from)):
 						getHeaders(from)
 						continue
 					case <-d.cancelCh:
@@ -1265,7 +1370,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64) error {
 				// No headers delivered, or all of them being delayed, sleep a bit and retry
 				p.log.Trace("All headers delayed, waiting")
 				select {
-				case <-time.After(fsHeaderContCheck):
+				case <-time.After(d.headerContCheck(from)):
 					getHeaders(from)
 					continue
 				case <-d.cancelCh:
@@ -1689,6 +1794,14 @@ func (d *Downloader) processHeaders(origin uint64, td *big.Int) error {
 				}
 				chunk := headers[:limit]
 
+				// Refuse any chain replacing the block fast sync is pinned to
+				if number := d.syncCheckpointNumber; mode == FastSync && d.syncCheckpoint != (common.Hash{}) {
+					first, last := chunk[0].Number.Uint64(), chunk[len(chunk)-1].Number.Uint64()
+					if first <= number && number <= last && chunk[number-first].Hash() != d.syncCheckpoint {
+						rollbackErr = errInvalidChain
+						return fmt.Errorf("%w: block %d mismatches sync checkpoint %x", errInvalidChain, number, d.syncCheckpoint)
+					}
+				}
 				// In case of header only syncing, validate the chunk immediately
 				if mode == FastSync || mode == LightSync {
 					// If we're importing pure headers, verify based on their recentness
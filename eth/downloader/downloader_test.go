@@ -1711,3 +1711,41 @@ func testCheckpointEnforcement(t *testing.T, protocol uint, mode SyncMode) {
 		assertOwnChain(t, tester, chain.len())
 	}
 }
+
+// Tests that fast sync pinned to a trusted block only syncs from peers whose
+// canonical chain contains that block.
+func TestSyncCheckpoint65(t *testing.T) { testSyncCheckpoint(t, eth.ETH65) }
+func TestSyncCheckpoint66(t *testing.T) { testSyncCheckpoint(t, eth.ETH66) }
+
+func testSyncCheckpoint(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	chainA := testChainForkLightA.shorten(testChainBase.len() + 80)
+	chainB := testChainForkLightB.shorten(testChainBase.len() + 80)
+	checkpoint := chainA.chain[testChainBase.len()+40]
+
+	// A peer on another fork must be refused
+	tester := newTester()
+	defer tester.terminate()
+
+	EnableSyncCheckpoint(checkpoint)(tester.downloader)
+	tester.newPeer("fork B", protocol, chainB)
+	if err := tester.sync("fork B", nil, FastSync); !errors.Is(err, errUnsyncedPeer) {
+		t.Fatalf("fork sync error mismatch: have %v, want %v", err, errUnsyncedPeer)
+	}
+	assertOwnChain(t, tester, 1)
+
+	// A peer not having reached the block yet must be refused too
+	tester.newPeer("short", protocol, chainA.shorten(testChainBase.len()+20))
+	if err := tester.sync("short", nil, FastSync); !errors.Is(err, errUnsyncedPeer) {
+		t.Fatalf("short sync error mismatch: have %v, want %v", err, errUnsyncedPeer)
+	}
+	assertOwnChain(t, tester, 1)
+
+	// A peer containing the block must be synced from
+	tester.newPeer("fork A", protocol, chainA)
+	if err := tester.sync("fork A", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, chainA.len())
+}
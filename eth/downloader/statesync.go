@@ -19,6 +19,7 @@ package downloader
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -76,6 +77,31 @@ func (d *Downloader) syncState(root common.Hash) *stateSync {
 	return s
 }
 
+// HealState re-downloads the damaged parts of the state with the given root from
+// the connected peers via GetNodeData, even if snap sync is enabled. The seed
+// callback schedules the missing entries, as the scheduler only descends into
+// subtries absent from the database. It fails if a sync is already running.
+func (d *Downloader) HealState(root common.Hash, seed func(sched *trie.Sync)) error {
+	if !atomic.CompareAndSwapInt32(&d.synchronising, 0, 1) {
+		return errBusy
+	}
+	defer atomic.StoreInt32(&d.synchronising, 0)
+
+	// Check the database for every node, the bloom is unreliable after sync
+	s := newStateSync(d, root)
+	s.heal = true
+	s.sched = state.NewStateSync(root, d.stateDB, nil, nil)
+	seed(s.sched)
+
+	select {
+	case d.stateSyncStart <- s:
+		<-s.started
+	case <-d.quitCh:
+		return errCancelStateFetch
+	}
+	return s.Wait()
+}
+
 // stateFetcher manages the active state sync and accepts requests
 // on its behalf.
 func (d *Downloader) stateFetcher() {
@@ -261,6 +287,7 @@ type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
 	root   common.Hash        // State root currently being synced
+	heal   bool               // Whether the sync heals a damaged local state
 	sched  *trie.Sync         // State trie sync scheduler defining the tasks
 	keccak crypto.KeccakState // Keccak256 hasher to verify deliveries with
 
@@ -314,7 +341,7 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 // finish.
 func (s *stateSync) run() {
 	close(s.started)
-	if s.d.snapSync {
+	if s.d.snapSync && !s.heal {
 		s.err = s.d.SnapSyncer.Sync(s.root, s.cancel)
 	} else {
 		s.err = s.loop()
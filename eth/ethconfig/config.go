@@ -31,8 +31,12 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/consensus/parlia"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vote"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/governor"
+	"github.com/ethereum/go-ethereum/eth/ha"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
@@ -87,8 +91,12 @@ var Defaults = Config{
 		GasPrice:      big.NewInt(params.GWei),
 		Recommit:      3 * time.Second,
 		DelayLeftOver: 50 * time.Millisecond,
+		BuildRounds:   miner.DefaultBuildRounds,
 	},
 	TxPool:      core.DefaultTxPoolConfig,
+	VoteSigner:  vote.DefaultConfig,
+	HA:          ha.DefaultConfig,
+	Governor:    governor.DefaultConfig,
 	RPCGasCap:   25000000,
 	GPO:         FullNodeGPO,
 	RPCTxFeeCap: 1, // 1 ether
@@ -135,16 +143,45 @@ type Config struct {
 
 	NoPruning           bool // Whether to disable pruning and flush everything to disk
 	DirectBroadcast     bool
-	DisableSnapProtocol bool //Whether disable snap protocol
-	DiffSync            bool // Whether support diff sync
+	PrivateTxPeers      []string     `toml:",omitempty"` // Enode URLs of the trusted peers to exchange private transactions with
+	PeerGroups          []*PeerGroup `toml:",omitempty"` // Named groups of peers with their propagation policies
+	SentryValidators    []string     `toml:",omitempty"` // Enode URLs of the hidden validators to act as a sentry for
+	Sentries            []string     `toml:",omitempty"` // Enode URLs of the sentries of this hidden validator, the only peers accepted
+	ServeWitness        bool         `toml:",omitempty"` // Whether to serve the execution witnesses of the recent blocks to the peers
+	TracePropagation    bool         `toml:",omitempty"` // Whether to trace the block propagation with the peers opting in too
+	DisableSnapProtocol bool         //Whether disable snap protocol
+	DiffSync            bool         // Whether support diff sync
 	PipeCommit          bool
+	ParallelTxMode      bool // Whether to execute the transactions of imported blocks in parallel
+	ParallelTxNum       int  // Number of transactions executed concurrently, 0 for one per CPU
 	RangeLimit          bool
-
-	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	LogsMaxBlockRange   uint64 `toml:",omitempty"` // Maximum block range of the log queries, 0 for unlimited
+	LogsMaxResults      int    `toml:",omitempty"` // Maximum number of logs returned by a log query, 0 for unlimited
+
+	TxLookupLimit     uint64           `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	TxLookupAllowlist []common.Address `toml:",omitempty"` // Addresses whose transactions stay indexed beyond the TxLookupLimit window
+	CallIndex         bool             `toml:",omitempty"` // Whether to index the addresses touched by internal calls
+	SenderIndex       bool             `toml:",omitempty"` // Whether to index the transactions by sender and nonce
+	ContractIndex     bool             `toml:",omitempty"` // Whether to index the creations of the deployed contracts
+	TokenIndex        bool             `toml:",omitempty"` // Whether to index the token transfers by account
+	LogIndex          bool             `toml:",omitempty"` // Whether to maintain a precise log index for eth_getLogs
+	TraceDB           bool             `toml:",omitempty"` // Whether to record the traces of the imported blocks for the trace APIs
+	RevertReasons     bool             `toml:",omitempty"` // Whether to record the transaction failures of the imported blocks
+	Otterscan         bool             `toml:",omitempty"` // Whether to index the transactions by address for the Otterscan APIs
+
+	// Account submitting the double signs detected by parlia to the slash contract,
+	// the submission is disabled if unset
+	DoubleSignReporter common.Address `toml:",omitempty"`
+
+	// Whether the clock of parlia can be warped over RPC, for developer chains only
+	TimeWarp bool `toml:",omitempty"`
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Hash of a trusted block which fast and snap sync only pivot to chains containing
+	SyncCheckpoint common.Hash `toml:",omitempty"`
+
 	// Light client options
 	LightServ          int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress       int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -174,6 +211,8 @@ type Config struct {
 	TrieDirtyCache          int
 	TrieTimeout             time.Duration
 	SnapshotCache           int
+	SnapshotVerifyRate      int  `toml:",omitempty"` // Accounts per second the snapshot self-check verifies, 0 to disable
+	SnapshotRepair          bool `toml:",omitempty"` // Whether to regenerate the snapshot on a self-check divergence
 	TriesInMemory           uint64
 	Preimages               bool
 
@@ -186,9 +225,21 @@ type Config struct {
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
+	// Whether to prefetch the state accessed by the pending transactions
+	TxPoolPrefetch bool
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Vote signer options
+	VoteSigner vote.Config
+
+	// Validator failover options
+	HA ha.Config
+
+	// Resource governor options
+	Governor governor.Config
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
@@ -201,6 +252,11 @@ type Config struct {
 	// Type of the EVM interpreter ("" for default)
 	EVMInterpreter string
 
+	// Execution backend of the EVM ("" for the interpreter), and the number of
+	// imported blocks also executed with the interpreter to cross-check it
+	VMBackend       string `toml:",omitempty"`
+	VMBackendVerify uint64 `toml:",omitempty"`
+
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap uint64
 
@@ -216,6 +272,22 @@ type Config struct {
 
 	// Berlin block override (TODO: remove after the fork)
 	OverrideBerlin *big.Int `toml:",omitempty"`
+
+	// Hard fork schedule overrides, loaded from a signed manifest
+	OverrideForks *core.ForkOverrides `toml:"-"`
+}
+
+// LogsLimits returns the server side limits of the log queries, the legacy range
+// limit switch applying if no explicit maximum block range is configured.
+func (c *Config) LogsLimits() filters.LogsLimits {
+	limits := filters.LogsLimits{
+		MaxBlockRange: c.LogsMaxBlockRange,
+		MaxResults:    c.LogsMaxResults,
+	}
+	if c.RangeLimit && limits.MaxBlockRange == 0 {
+		limits.MaxBlockRange = filters.MaxFilterBlockRange
+	}
+	return limits
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
@@ -251,3 +323,20 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 	engine.SetThreads(-1) // Disable CPU mining
 	return engine
 }
+
+// Block broadcast policies of the peer groups.
+const (
+	BlocksFull     = "full"     // Always send the full blocks
+	BlocksAnnounce = "announce" // Only announce the block hashes
+)
+
+// PeerGroup is a named group of peers, e.g. validators, sentries or public, with
+// the policy applied to them. A group without nodes holds the peers not listed in
+// any group. Peers outside any group get full blocks from a square root of the
+// peers and announcements from the rest.
+type PeerGroup struct {
+	Name      string
+	Nodes     []string `toml:",omitempty"` // Enode URLs of the members
+	Blocks    string   `toml:",omitempty"` // Block broadcast policy, BlocksFull or BlocksAnnounce, the default split if empty
+	Protected bool     `toml:",omitempty"` // Whether the members are exempt from the peer limits, like trusted peers
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vote"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -37,6 +38,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		UltraLightServers       []string               `toml:",omitempty"`
 		UltraLightFraction      int                    `toml:",omitempty"`
 		UltraLightOnlyAnnounce  bool                   `toml:",omitempty"`
+		PeerGroups              []*PeerGroup           `toml:",omitempty"`
+		SentryValidators        []string               `toml:",omitempty"`
+		Sentries                []string               `toml:",omitempty"`
+		ServeWitness            bool                   `toml:",omitempty"`
+		TracePropagation        bool                   `toml:",omitempty"`
 		SkipBcVersionCheck      bool                   `toml:"-"`
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
@@ -49,13 +55,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieTimeout             time.Duration
 		TriesInMemory           uint64 `toml:",omitempty"`
 		SnapshotCache           int
+		SnapshotVerifyRate      int  `toml:",omitempty"`
+		SnapshotRepair          bool `toml:",omitempty"`
 		Preimages               bool
 		PersistDiff             bool
 		DiffBlock               uint64 `toml:",omitempty"`
 		Miner                   miner.Config
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
+		TxPoolPrefetch          bool
 		GPO                     gasprice.Config
+		VoteSigner              vote.Config
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
@@ -85,6 +95,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.UltraLightServers = c.UltraLightServers
 	enc.UltraLightFraction = c.UltraLightFraction
 	enc.UltraLightOnlyAnnounce = c.UltraLightOnlyAnnounce
+	enc.PeerGroups = c.PeerGroups
+	enc.SentryValidators = c.SentryValidators
+	enc.Sentries = c.Sentries
+	enc.ServeWitness = c.ServeWitness
+	enc.TracePropagation = c.TracePropagation
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -97,13 +112,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieTimeout = c.TrieTimeout
 	enc.TriesInMemory = c.TriesInMemory
 	enc.SnapshotCache = c.SnapshotCache
+	enc.SnapshotVerifyRate = c.SnapshotVerifyRate
+	enc.SnapshotRepair = c.SnapshotRepair
 	enc.Preimages = c.Preimages
 	enc.PersistDiff = c.PersistDiff
 	enc.DiffBlock = c.DiffBlock
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
+	enc.TxPoolPrefetch = c.TxPoolPrefetch
 	enc.GPO = c.GPO
+	enc.VoteSigner = c.VoteSigner
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
 	enc.EWASMInterpreter = c.EWASMInterpreter
@@ -138,6 +157,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		UltraLightServers       []string               `toml:",omitempty"`
 		UltraLightFraction      *int                   `toml:",omitempty"`
 		UltraLightOnlyAnnounce  *bool                  `toml:",omitempty"`
+		PeerGroups              []*PeerGroup           `toml:",omitempty"`
+		SentryValidators        []string               `toml:",omitempty"`
+		Sentries                []string               `toml:",omitempty"`
+		ServeWitness            *bool                  `toml:",omitempty"`
+		TracePropagation        *bool                  `toml:",omitempty"`
 		SkipBcVersionCheck      *bool                  `toml:"-"`
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
@@ -152,11 +176,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieTimeout             *time.Duration
 		TriesInMemory           *uint64 `toml:",omitempty"`
 		SnapshotCache           *int
+		SnapshotVerifyRate      *int  `toml:",omitempty"`
+		SnapshotRepair          *bool `toml:",omitempty"`
 		Preimages               *bool
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
+		TxPoolPrefetch          *bool
 		GPO                     *gasprice.Config
+		VoteSigner              *vote.Config
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
@@ -227,6 +255,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.UltraLightOnlyAnnounce != nil {
 		c.UltraLightOnlyAnnounce = *dec.UltraLightOnlyAnnounce
 	}
+	if dec.PeerGroups != nil {
+		c.PeerGroups = dec.PeerGroups
+	}
+	if dec.SentryValidators != nil {
+		c.SentryValidators = dec.SentryValidators
+	}
+	if dec.Sentries != nil {
+		c.Sentries = dec.Sentries
+	}
+	if dec.ServeWitness != nil {
+		c.ServeWitness = *dec.ServeWitness
+	}
+	if dec.TracePropagation != nil {
+		c.TracePropagation = *dec.TracePropagation
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -269,6 +312,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SnapshotCache != nil {
 		c.SnapshotCache = *dec.SnapshotCache
 	}
+	if dec.SnapshotVerifyRate != nil {
+		c.SnapshotVerifyRate = *dec.SnapshotVerifyRate
+	}
+	if dec.SnapshotRepair != nil {
+		c.SnapshotRepair = *dec.SnapshotRepair
+	}
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
@@ -281,9 +330,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.TxPoolPrefetch != nil {
+		c.TxPoolPrefetch = *dec.TxPoolPrefetch
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.VoteSigner != nil {
+		c.VoteSigner = *dec.VoteSigner
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
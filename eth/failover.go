@@ -0,0 +1,48 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vote"
+)
+
+// failoverBackend exposes the validator of the node to the failover coordinator.
+type failoverBackend struct {
+	eth *Ethereum
+}
+
+func (b *failoverBackend) CurrentHeader() *types.Header {
+	return b.eth.blockchain.CurrentHeader()
+}
+
+func (b *failoverBackend) Etherbase() (common.Address, error) {
+	return b.eth.Etherbase()
+}
+
+func (b *failoverBackend) SetStandby(standby bool) {
+	b.eth.engine.(*parlia.Parlia).SetStandby(standby)
+	if signer, ok := b.eth.voteSigner.(*vote.ProtectedSigner); ok {
+		signer.SetStandby(standby)
+	}
+}
+
+func (b *failoverBackend) RaiseSealWatermark(validator common.Address, number uint64) error {
+	return b.eth.protectionDB.RaiseSealWatermark(validator, number)
+}
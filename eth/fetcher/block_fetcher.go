@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/gopool"
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/importtrace"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -825,7 +826,10 @@ func (f *BlockFetcher) importBlocks(op *blockOrHeaderInject) {
 	hash := block.Hash()
 
 	// Run the import on a new thread
-	log.Debug("Importing propagated block", "peer", peer, "number", block.Number(), "hash", hash)
+	log.Debug("Importing propagated block", "peer", peer, "number", block.Number(), "hash", hash, "trace", importtrace.ID(hash))
+	if !block.ReceivedAt.IsZero() {
+		importtrace.Record(hash, block.NumberU64(), importtrace.StageQueued, block.ReceivedAt, "peer", peer)
+	}
 	go func() {
 		defer func() { f.done <- hash }()
 
@@ -838,7 +842,11 @@ func (f *BlockFetcher) importBlocks(op *blockOrHeaderInject) {
 			return
 		}
 		// Quickly validate the header and propagate the block if it passes
-		switch err := f.verifyHeader(block.Header()); err {
+		start := time.Now()
+		err := f.verifyHeader(block.Header())
+		importtrace.Record(hash, block.NumberU64(), importtrace.StageVerified, start, "peer", peer, "err", err)
+
+		switch err {
 		case nil:
 			// All ok, quickly propagate to our peers
 			blockBroadcastOutTimer.UpdateSince(block.ReceivedAt)
@@ -29,12 +29,28 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/gopool"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+const (
+	// defaultLogsPageSize is the number of logs returned by a paginated log query
+	// if neither the client nor the server caps it.
+	defaultLogsPageSize = 10000
+
+	// logsChunkSize is the number of blocks scanned at once by the log queries
+	// capped in the number of results.
+	logsChunkSize = 4096
+)
+
+// errPendingRangeStart is returned by the range queries starting at the pending
+// block, whose logs are only available through the pending logs subscription.
+var errPendingRangeStart = errors.New("log range cannot start at the pending block")
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -49,26 +65,33 @@ type filter struct {
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
-	backend    Backend
-	mux        *event.TypeMux
-	quit       chan struct{}
-	chainDb    ethdb.Database
-	events     *EventSystem
-	filtersMu  sync.Mutex
-	filters    map[rpc.ID]*filter
-	timeout    time.Duration
-	rangeLimit bool
+	backend   Backend
+	mux       *event.TypeMux
+	quit      chan struct{}
+	chainDb   ethdb.Database
+	events    *EventSystem
+	filtersMu sync.Mutex
+	filters   map[rpc.ID]*filter
+	timeout   time.Duration
+	limits    LogsLimits
+}
+
+// LogsLimits are the server side limits enforced on the log queries, protecting
+// the node from requests scanning millions of blocks.
+type LogsLimits struct {
+	MaxBlockRange uint64 // Maximum number of blocks a query may span (0 = unlimited)
+	MaxResults    int    // Maximum number of logs a query may return (0 = unlimited)
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, rangeLimit bool) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, limits LogsLimits) *PublicFilterAPI {
 	api := &PublicFilterAPI{
-		backend:    backend,
-		chainDb:    backend.ChainDb(),
-		events:     NewEventSystem(backend, lightMode),
-		filters:    make(map[rpc.ID]*filter),
-		timeout:    timeout,
-		rangeLimit: rangeLimit,
+		backend: backend,
+		chainDb: backend.ChainDb(),
+		events:  NewEventSystem(backend, lightMode),
+		filters: make(map[rpc.ID]*filter),
+		timeout: timeout,
+		limits:  limits,
 	}
 	go api.timeoutLoop(timeout)
 
@@ -114,7 +137,7 @@ func (api *PublicFilterAPI) timeoutLoop(timeout time.Duration) {
 // https://eth.wiki/json-rpc/API#eth_newpendingtransactionfilter
 func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	var (
-		pendingTxs   = make(chan []common.Hash)
+		pendingTxs   = make(chan []*types.Transaction)
 		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
 	)
 	api.filtersMu.Lock()
@@ -124,10 +147,12 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	gopool.Submit(func() {
 		for {
 			select {
-			case ph := <-pendingTxs:
+			case pTx := <-pendingTxs:
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph...)
+					for _, tx := range pTx {
+						f.hashes = append(f.hashes, tx.Hash())
+					}
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
@@ -142,9 +167,54 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	return pendingTxSub.ID
 }
 
+// PendingTxCriteria is the server side filter of the pending transaction
+// subscriptions. Empty fields match every transaction.
+type PendingTxCriteria struct {
+	From        []common.Address `json:"from"`        // Senders to match
+	To          []common.Address `json:"to"`          // Recipients to match
+	MinGasPrice *hexutil.Big     `json:"minGasPrice"` // Minimum gas price to match
+	Types       []hexutil.Uint64 `json:"types"`       // Transaction types to match
+}
+
+// matches reports whether the transaction satisfies the criteria.
+func (crit *PendingTxCriteria) matches(tx *types.Transaction) bool {
+	if crit.MinGasPrice != nil && tx.GasPrice().Cmp(crit.MinGasPrice.ToInt()) < 0 {
+		return false
+	}
+	if len(crit.Types) > 0 {
+		var found bool
+		for _, typ := range crit.Types {
+			if uint64(typ) == uint64(tx.Type()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+		return false
+	}
+	if len(crit.From) > 0 {
+		// Pool transactions have their sender cached, recovering it is cheap
+		var signer types.Signer = types.HomesteadSigner{}
+		if tx.Protected() {
+			signer = types.LatestSignerForChainID(tx.ChainId())
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil || !includes(crit.From, from) {
+			return false
+		}
+	}
+	return true
+}
+
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
-// enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// enters the transaction pool. If fullTx is true, the full transactions are sent
+// instead of their hashes. If criteria are given, only the matching transactions
+// are sent.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -153,16 +223,23 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	gopool.Submit(func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
+		pendingTxs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribePendingTxs(pendingTxs)
 
 		for {
 			select {
-			case hashes := <-txHashes:
+			case txs := <-pendingTxs:
 				// To keep the original behaviour, send a single tx hash in one notification.
 				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+				for _, tx := range txs {
+					if crit != nil && !crit.matches(tx) {
+						continue
+					}
+					if fullTx != nil && *fullTx {
+						notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
 				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
@@ -242,6 +319,83 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// StateDiffs send a notification with the account and storage changes of each
+// block which becomes the new canonical head. The changes are taken from the
+// snapshot diff layers, so no notifications are sent if snapshots are disabled.
+// Blocks which become canonical through a reorg aren't announced.
+func (api *PublicFilterAPI) StateDiffs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	gopool.Submit(func() {
+		diffs := make(chan core.StateDiffEvent, chainEvChanSize)
+		diffsSub := api.backend.SubscribeStateDiffEvent(diffs)
+		defer diffsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-diffs:
+				notifier.Notify(rpcSub.ID, newRPCStateDiff(ev.Diff))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	})
+
+	return rpcSub, nil
+}
+
+// RPCStateDiff is the JSON representation of the state changes of a block.
+type RPCStateDiff struct {
+	BlockHash   common.Hash       `json:"blockHash"`
+	BlockNumber hexutil.Uint64    `json:"blockNumber"`
+	Accounts    []*RPCAccountDiff `json:"accounts"`
+}
+
+// RPCAccountDiff is the JSON representation of an account change. The account
+// fields are omitted for deleted accounts.
+type RPCAccountDiff struct {
+	Address  common.Address    `json:"address"`
+	Change   core.StateChange  `json:"change"`
+	Nonce    *hexutil.Uint64   `json:"nonce,omitempty"`
+	Balance  *hexutil.Big      `json:"balance,omitempty"`
+	CodeHash *common.Hash      `json:"codeHash,omitempty"`
+	Storage  []*RPCStorageDiff `json:"storage,omitempty"`
+}
+
+// RPCStorageDiff is the JSON representation of a storage slot change.
+type RPCStorageDiff struct {
+	Key    common.Hash      `json:"key"`
+	Value  common.Hash      `json:"value"`
+	Change core.StateChange `json:"change"`
+}
+
+func newRPCStateDiff(diff *core.StateDiff) *RPCStateDiff {
+	result := &RPCStateDiff{
+		BlockHash:   diff.BlockHash,
+		BlockNumber: hexutil.Uint64(diff.BlockNumber),
+		Accounts:    make([]*RPCAccountDiff, 0, len(diff.Accounts)),
+	}
+	for _, account := range diff.Accounts {
+		enc := &RPCAccountDiff{Address: account.Address, Change: account.Change}
+		if account.Change != core.StateDeleted && account.Balance != nil {
+			nonce, codeHash := hexutil.Uint64(account.Nonce), account.CodeHash
+			enc.Nonce, enc.Balance, enc.CodeHash = &nonce, (*hexutil.Big)(account.Balance), &codeHash
+		}
+		for _, slot := range account.Storage {
+			enc.Storage = append(enc.Storage, &RPCStorageDiff{Key: slot.Key, Value: slot.Value, Change: slot.Change})
+		}
+		result.Accounts = append(result.Accounts, enc)
+	}
+	return result
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -333,29 +487,168 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 //
 // https://eth.wiki/json-rpc/API#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	var filter *Filter
+	return api.logs(ctx, crit)
+}
+
+// LogsCursor is the position a paginated log query resumes from.
+type LogsCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// LogsPage is a page of the results of a paginated log query.
+type LogsPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *LogsCursor  `json:"cursor"` // Position of the next page, nil if the query is exhausted
+}
+
+// GetLogsPage returns a page of the logs matching the given criteria, starting
+// from the cursor if one is given. At most limit logs are returned, capped by the
+// server side maximum, and at most the maximum block range is scanned per page.
+// The returned cursor resumes the query where the page left off.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *LogsCursor, limit *hexutil.Uint) (*LogsPage, error) {
+	if crit.BlockHash != nil {
+		return nil, errors.New("block hash queries cannot be paginated")
+	}
+	begin, end, err := api.resolveRange(ctx, crit.FromBlock, crit.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	var skip uint
+	if cursor != nil {
+		if uint64(cursor.BlockNumber) < begin {
+			return nil, errors.New("cursor before the start of the range")
+		}
+		begin, skip = uint64(cursor.BlockNumber), uint(cursor.LogIndex)
+	}
+	page := &LogsPage{Logs: []*types.Log{}}
+	if begin > end {
+		return page, nil
+	}
+	// Cap the size and the block range of the page
+	size := defaultLogsPageSize
+	if api.limits.MaxResults > 0 && api.limits.MaxResults < size {
+		size = api.limits.MaxResults
+	}
+	if limit != nil && *limit > 0 && int(*limit) < size {
+		size = int(*limit)
+	}
+	last := end
+	if max := api.limits.MaxBlockRange; max > 0 && end-begin > max {
+		last = begin + max
+	}
+	logs, next, err := api.rangeLogs(ctx, begin, last, crit.Addresses, crit.Topics, size+int(skip))
+	if err != nil {
+		return nil, err
+	}
+	// Drop the logs already returned by the previous page
+	for len(logs) > 0 && logs[0].BlockNumber == begin && logs[0].Index < skip {
+		logs = logs[1:]
+	}
+	switch {
+	case len(logs) > size:
+		page.Logs = logs[:size]
+		page.Cursor = &LogsCursor{BlockNumber: hexutil.Uint64(logs[size].BlockNumber), LogIndex: hexutil.Uint(logs[size].Index)}
+	case next <= end:
+		page.Logs = returnLogs(logs)
+		page.Cursor = &LogsCursor{BlockNumber: hexutil.Uint64(next)}
+	default:
+		page.Logs = returnLogs(logs)
+	}
+	return page, nil
+}
+
+// logs returns the logs matching the given criteria, enforcing the server side
+// limits of the log queries.
+func (api *PublicFilterAPI) logs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	var (
+		logs []*types.Log
+		err  error
+	)
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
+		logs, err = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics).Logs(ctx)
 	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if crit.FromBlock != nil {
-			begin = crit.FromBlock.Int64()
+		var begin, end uint64
+		if begin, end, err = api.resolveRange(ctx, crit.FromBlock, crit.ToBlock); err != nil {
+			return nil, err
 		}
-		end := rpc.LatestBlockNumber.Int64()
-		if crit.ToBlock != nil {
-			end = crit.ToBlock.Int64()
+		if max := api.limits.MaxBlockRange; max > 0 && end > begin && end-begin > max {
+			return nil, fmt.Errorf("exceed maximum block range: %d", max)
 		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics, api.rangeLimit)
+		logs, _, err = api.rangeLogs(ctx, begin, end, crit.Addresses, crit.Topics, api.limits.MaxResults)
 	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	if max := api.limits.MaxResults; max > 0 && len(logs) > max {
+		return nil, fmt.Errorf("query returned more than %d results", max)
+	}
+	return returnLogs(logs), nil
+}
+
+// resolveRange converts the RPC block numbers of a range query into absolute
+// ones, the missing ones and latest referring to the current head. The logs of
+// the pending block aren't served by range queries, so a range ending at pending
+// stops at the head, whereas one starting there is rejected.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, from, to *big.Int) (uint64, uint64, error) {
+	header, _ := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil {
+		return 0, 0, errors.New("latest header not found")
+	}
+	resolve := func(number *big.Int, end bool) (uint64, error) {
+		switch {
+		case number == nil:
+			return header.Number.Uint64(), nil
+		case number.IsUint64():
+			return number.Uint64(), nil
+		case !number.IsInt64():
+			return 0, fmt.Errorf("invalid block number %v", number)
+		}
+		switch rpc.BlockNumber(number.Int64()) {
+		case rpc.LatestBlockNumber:
+			return header.Number.Uint64(), nil
+		case rpc.PendingBlockNumber:
+			if end {
+				return header.Number.Uint64(), nil
+			}
+			return 0, errPendingRangeStart
+		default:
+			return 0, fmt.Errorf("unsupported block number %v", number)
+		}
+	}
+	begin, err := resolve(from, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := resolve(to, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	return begin, end, nil
+}
+
+// rangeLogs returns the logs matching the given criteria in the [begin, end]
+// block range. If a limit is given, the range is scanned in chunks and the scan
+// stops as soon as more logs than the limit are found. Beside the logs, the
+// number of the first block not scanned is returned.
+func (api *PublicFilterAPI) rangeLogs(ctx context.Context, begin, end uint64, addresses []common.Address, topics [][]common.Hash, limit int) ([]*types.Log, uint64, error) {
+	var logs []*types.Log
+	for begin <= end {
+		last := end
+		if limit > 0 && end-begin >= logsChunkSize {
+			last = begin + logsChunkSize - 1
+		}
+		found, err := NewRangeFilter(api.backend, int64(begin), int64(last), addresses, topics, false).Logs(ctx)
+		if err != nil {
+			return nil, begin, err
+		}
+		logs, begin = append(logs, found...), last+1
+		if limit > 0 && len(logs) > limit {
+			break
+		}
+	}
+	return logs, begin, nil
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -388,29 +681,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		return nil, fmt.Errorf("filter not found")
 	}
 
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics, api.rangeLimit)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), nil
+	return api.logs(ctx, f.crit)
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
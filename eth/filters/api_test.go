@@ -19,9 +19,13 @@ package filters
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -183,3 +187,36 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+func TestPendingTxCriteria(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		signer   = types.LatestSignerForChainID(big.NewInt(1))
+	)
+	legacy, _ := types.SignTx(types.NewTransaction(0, receiver, new(big.Int), 21000, big.NewInt(10), nil), signer, key)
+	accessList, _ := types.SignNewTx(key, signer, &types.AccessListTx{ChainID: big.NewInt(1), Nonce: 1, GasPrice: big.NewInt(5), Gas: 53000})
+
+	tests := []struct {
+		crit       PendingTxCriteria
+		legacy     bool
+		accessList bool
+	}{
+		{PendingTxCriteria{}, true, true},
+		{PendingTxCriteria{From: []common.Address{sender}}, true, true},
+		{PendingTxCriteria{From: []common.Address{receiver}}, false, false},
+		{PendingTxCriteria{To: []common.Address{receiver}}, true, false},
+		{PendingTxCriteria{MinGasPrice: (*hexutil.Big)(big.NewInt(6))}, true, false},
+		{PendingTxCriteria{Types: []hexutil.Uint64{types.AccessListTxType}}, false, true},
+		{PendingTxCriteria{From: []common.Address{sender}, Types: []hexutil.Uint64{types.LegacyTxType, types.AccessListTxType}}, true, true},
+	}
+	for i, tt := range tests {
+		if have := tt.crit.matches(legacy); have != tt.legacy {
+			t.Errorf("test %d: legacy tx match mismatch: have %v, want %v", i, have, tt.legacy)
+		}
+		if have := tt.crit.matches(accessList); have != tt.accessList {
+			t.Errorf("test %d: access list tx match mismatch: have %v, want %v", i, have, tt.accessList)
+		}
+	}
+}
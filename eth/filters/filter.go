@@ -25,13 +25,15 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-const maxFilterBlockRange = 5000
+// MaxFilterBlockRange is the maximum block range of the range limited filters.
+const MaxFilterBlockRange = 5000
 
 type Backend interface {
 	ChainDb() ethdb.Database
@@ -45,11 +47,20 @@ type Backend interface {
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeStateDiffEvent(ch chan<- core.StateDiffEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndexBackend is implemented by the backends maintaining a precise log
+// index, see core.NewLogIndexer.
+type LogIndexBackend interface {
+	// LogIndexStatus returns the number of blocks covered by the log index,
+	// starting from genesis.
+	LogIndexStatus() uint64
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -148,21 +159,36 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	if f.rangeLimit && (int64(end)-f.begin) > maxFilterBlockRange {
-		return nil, fmt.Errorf("exceed maximum block range: %d", maxFilterBlockRange)
+	if f.rangeLimit && (int64(end)-f.begin) > MaxFilterBlockRange {
+		return nil, fmt.Errorf("exceed maximum block range: %d", MaxFilterBlockRange)
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	// Gather all precisely indexed logs, then the bloom indexed ones, and finish
+	// with non indexed ones
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(LogIndexBackend); ok && f.hasCriteria() {
+		if indexed := backend.LogIndexStatus(); indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.logIndexedLogs(ctx, end)
+			} else {
+				logs, err = f.logIndexedLogs(ctx, indexed-1)
+			}
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
-	if indexed := sections * size; indexed > uint64(f.begin) {
+	if indexed := sections * size; indexed > uint64(f.begin) && uint64(f.begin) <= end {
+		var found []*types.Log
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
 		}
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -219,6 +245,124 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
+// hasCriteria reports whether the filter has any address or topic clause, the
+// log index being useless for the filters matching every log.
+func (f *Filter) hasCriteria() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexedLogs returns the logs matching the filter criteria based on the
+// precise log index, only retrieving the blocks that contain matching logs.
+func (f *Filter) logIndexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+	blocks, err := f.logIndexMatches(uint64(f.begin), end)
+	if err != nil {
+		return nil, err
+	}
+	var logs []*types.Log
+	for _, number := range blocks {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		f.begin = int64(number) + 1
+
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return logs, err
+		}
+		found, err := f.checkMatches(ctx, header)
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, found...)
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
+}
+
+// logIndexMatches returns the numbers of the blocks in the [begin, end] range
+// matching every clause of the filter according to the log index.
+func (f *Filter) logIndexMatches(begin, end uint64) ([]uint64, error) {
+	var (
+		matches []uint64
+		first   = true
+	)
+	clause := func(kind byte, values []common.Hash) error {
+		var blocks []uint64
+		for _, value := range values {
+			found, err := rawdb.ReadLogIndexBlocks(f.db, kind, value, begin, end)
+			if err != nil {
+				return err
+			}
+			blocks = unionBlocks(blocks, found)
+		}
+		if first {
+			matches, first = blocks, false
+		} else {
+			matches = intersectBlocks(matches, blocks)
+		}
+		return nil
+	}
+	if len(f.addresses) > 0 {
+		values := make([]common.Hash, len(f.addresses))
+		for i, address := range f.addresses {
+			values[i] = common.BytesToHash(address.Bytes())
+		}
+		if err := clause(rawdb.LogIndexAddress, values); err != nil {
+			return nil, err
+		}
+	}
+	for i, topics := range f.topics {
+		if len(topics) == 0 {
+			continue // wildcard
+		}
+		if err := clause(rawdb.LogIndexTopic0+byte(i), topics); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// unionBlocks merges two ascending lists of block numbers.
+func unionBlocks(a, b []uint64) []uint64 {
+	merged := make([]uint64, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			merged, a = append(merged, a[0]), a[1:]
+		case a[0] > b[0]:
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// intersectBlocks returns the block numbers present in both ascending lists.
+func intersectBlocks(a, b []uint64) []uint64 {
+	var both []uint64
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0] < b[0]:
+			a = a[1:]
+		case a[0] > b[0]:
+			b = b[1:]
+		default:
+			both, a, b = append(both, a[0]), a[1:], b[1:]
+		}
+	}
+	return both
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -21,15 +21,18 @@ import (
 	"io/ioutil"
 	"math/big"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -223,3 +226,136 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// Tests that the log queries enforce the server side limits, and that paginating
+// over a range yields every matching log exactly once.
+func TestGetLogsPage(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		addr    = common.HexToAddress("0x1111")
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		// Three logs in every odd block
+		if i%2 == 0 {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{{Address: addr}, {Address: addr}, {Address: addr}}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x2222"), big.NewInt(1), 1, big.NewInt(1), nil))
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	crit := FilterCriteria{FromBlock: big.NewInt(0), Addresses: []common.Address{addr}}
+
+	// Check that the unpaginated query fails if it exceeds any limit
+	if logs, err := NewPublicFilterAPI(backend, false, deadline, LogsLimits{}).GetLogs(context.Background(), crit); err != nil || len(logs) != 15 {
+		t.Fatalf("unlimited query mismatch: have %d logs, %v; want 15 logs", len(logs), err)
+	}
+	if _, err := NewPublicFilterAPI(backend, false, deadline, LogsLimits{MaxResults: 14}).GetLogs(context.Background(), crit); err == nil {
+		t.Fatalf("result limit not enforced")
+	}
+	if _, err := NewPublicFilterAPI(backend, false, deadline, LogsLimits{MaxBlockRange: 5}).GetLogs(context.Background(), crit); err == nil {
+		t.Fatalf("block range limit not enforced")
+	}
+	// Check that paginating yields all the logs in order, whatever the limits
+	for i, tt := range []struct {
+		limits LogsLimits
+		limit  uint
+		pages  int
+	}{
+		{LogsLimits{}, 0, 1},
+		{LogsLimits{}, 4, 4},
+		{LogsLimits{MaxResults: 2}, 4, 8},
+		{LogsLimits{MaxBlockRange: 3}, 0, 3},
+		{LogsLimits{MaxBlockRange: 3, MaxResults: 5}, 0, 4},
+	} {
+		var (
+			api    = NewPublicFilterAPI(backend, false, deadline, tt.limits)
+			limit  = hexutil.Uint(tt.limit)
+			cursor *LogsCursor
+			logs   []*types.Log
+			pages  int
+		)
+		for {
+			page, err := api.GetLogsPage(context.Background(), crit, cursor, &limit)
+			if err != nil {
+				t.Fatalf("test %d: failed to retrieve page %d: %v", i, pages, err)
+			}
+			logs, cursor, pages = append(logs, page.Logs...), page.Cursor, pages+1
+			if cursor == nil {
+				break
+			}
+		}
+		if pages != tt.pages {
+			t.Errorf("test %d: page count mismatch: have %d, want %d", i, pages, tt.pages)
+		}
+		if len(logs) != 15 {
+			t.Fatalf("test %d: log count mismatch: have %d, want 15", i, len(logs))
+		}
+		for j, log := range logs {
+			if log.BlockNumber != uint64(j/3*2+1) || log.Index != uint(j%3) {
+				t.Fatalf("test %d: log %d mismatch: have #%d/%d", i, j, log.BlockNumber, log.Index)
+			}
+		}
+	}
+}
+
+// Tests that the block tags of the range queries resolve against the head, and
+// that the unsupported ones are rejected rather than mapped to the head.
+func TestResolveRange(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, LogsLimits{})
+	)
+	genesis := core.GenesisBlockForTesting(db, common.Address{}, big.NewInt(1000000))
+	chain, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, nil)
+	for _, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+	}
+	var (
+		latest   = big.NewInt(rpc.LatestBlockNumber.Int64())
+		pending  = big.NewInt(rpc.PendingBlockNumber.Int64())
+		earliest = big.NewInt(rpc.EarliestBlockNumber.Int64())
+	)
+	for i, tt := range []struct {
+		from, to   *big.Int
+		begin, end uint64
+		err        string
+	}{
+		{from: nil, to: nil, begin: 10, end: 10},
+		{from: earliest, to: latest, begin: 0, end: 10},
+		{from: earliest, to: pending, begin: 0, end: 10},
+		{from: big.NewInt(3), to: big.NewInt(7), begin: 3, end: 7},
+		{from: latest, to: nil, begin: 10, end: 10},
+		{from: latest, to: pending, begin: 10, end: 10},
+		{from: pending, to: pending, err: errPendingRangeStart.Error()},
+		{from: pending, to: nil, err: errPendingRangeStart.Error()},
+		{from: big.NewInt(-3), to: nil, err: "unsupported block number -3"},
+		{from: earliest, to: big.NewInt(-3), err: "unsupported block number -3"},
+		{from: new(big.Int).Lsh(big.NewInt(1), 64), to: nil, err: "invalid block number"},
+	} {
+		begin, end, err := api.resolveRange(context.Background(), tt.from, tt.to)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to resolve range: %v", i, err)
+			continue
+		}
+		if begin != tt.begin || end != tt.end {
+			t.Errorf("test %d: range mismatch: have [%d, %d], want [%d, %d]", i, begin, end, tt.begin, tt.end)
+		}
+	}
+}
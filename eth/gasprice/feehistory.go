@@ -0,0 +1,207 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxFeeHistory is the maximum number of blocks of a fee history.
+	maxFeeHistory = 1024
+
+	// defaultCongestionBlocks is the number of blocks the congestion is measured
+	// over on chains without epochs.
+	defaultCongestionBlocks = 200
+)
+
+var errInvalidPercentile = errors.New("invalid reward percentile")
+
+// FeeHistory returns the gas used ratio of up to maxFeeHistory blocks ending with
+// lastBlock, and the given percentiles of the rewards paid in each of them, i.e.
+// the gas prices above the base fee, weighted by the gas used by the transactions.
+// Returns the number of the oldest block and the base fees of the blocks too, plus
+// the one of the block following lastBlock. Blocks before the base fee fork have
+// a zero base fee, the whole gas price being the reward of the miner.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	if blocks < 1 {
+		return common.Big0, nil, nil, nil, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if head == nil {
+		if err == nil {
+			err = fmt.Errorf("block %d not found", lastBlock)
+		}
+		return common.Big0, nil, nil, nil, err
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	var (
+		oldest       = last + 1 - uint64(blocks)
+		reward       [][]*big.Int
+		baseFee      = make([]*big.Int, blocks+1)
+		gasUsedRatio = make([]float64, blocks)
+	)
+	if len(rewardPercentiles) > 0 {
+		reward = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		fees, err := gpo.blockFees(ctx, oldest+uint64(i), len(rewardPercentiles) > 0)
+		if err != nil {
+			return common.Big0, nil, nil, nil, err
+		}
+		if fees.gasLimit > 0 {
+			gasUsedRatio[i] = float64(fees.gasUsed) / float64(fees.gasLimit)
+		}
+		if reward != nil {
+			reward[i] = fees.percentiles(rewardPercentiles)
+		}
+		baseFee[i] = new(big.Int)
+		if fees.baseFee != nil {
+			baseFee[i].Set(fees.baseFee)
+		}
+	}
+	baseFee[blocks] = new(big.Int)
+	if config := gpo.backend.ChainConfig(); config.IsBaseFee(new(big.Int).SetUint64(last + 1)) {
+		baseFee[blocks] = misc.CalcBaseFee(config, head)
+	}
+	return new(big.Int).SetUint64(oldest), reward, baseFee, gasUsedRatio, nil
+}
+
+// percentiles returns the given percentiles of the rewards paid in the block,
+// weighted by gas used. The rewards have to be loaded.
+func (fees *blockFees) percentiles(percentiles []float64) []*big.Int {
+	reward := make([]*big.Int, len(percentiles))
+	if len(fees.rewards) == 0 {
+		for i := range reward {
+			reward[i] = new(big.Int)
+		}
+		return reward
+	}
+	var (
+		index      int
+		sumGasUsed = fees.rewards[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(fees.gasUsed) * p / 100)
+		for sumGasUsed < threshold && index < len(fees.rewards)-1 {
+			index++
+			sumGasUsed += fees.rewards[index].gasUsed
+		}
+		reward[i] = fees.rewards[index].price
+	}
+	return reward
+}
+
+// Congestion is how full the recent blocks are, a hint whether transactions are
+// competing for the block space. Chains without a base fee don't price it in.
+type Congestion struct {
+	OldestBlock  uint64
+	NewestBlock  uint64
+	GasTarget    uint64  // Half the gas limit of the newest block
+	GasUsedRatio float64 // Mean ratio of the gas used to the gas limit
+	BusyBlocks   uint64  // Number of blocks using more than the gas target
+	Congested    bool    // Whether the blocks use more than the gas target on average
+}
+
+// Congestion measures the fullness of the blocks over the last epoch of the
+// consensus engine, or the last defaultCongestionBlocks blocks if it has no
+// epochs. It is measured once per head.
+func (gpo *Oracle) Congestion(ctx context.Context) (*Congestion, error) {
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		if err == nil {
+			err = errors.New("head block not found")
+		}
+		return nil, err
+	}
+	gpo.cacheLock.RLock()
+	lastHead, congestion := gpo.congestionHead, gpo.congestion
+	gpo.cacheLock.RUnlock()
+	if lastHead == head.Hash() {
+		return congestion, nil
+	}
+	blocks := uint64(defaultCongestionBlocks)
+	switch config := gpo.backend.ChainConfig(); {
+	case config.Parlia != nil && config.Parlia.Epoch > 0:
+		blocks = config.Parlia.Epoch
+	case config.Clique != nil && config.Clique.Epoch > 0:
+		blocks = config.Clique.Epoch
+	}
+	if number := head.Number.Uint64(); blocks > number {
+		blocks = number // The genesis block is never full
+	}
+	congestion = &Congestion{
+		OldestBlock: head.Number.Uint64() + 1 - blocks,
+		NewestBlock: head.Number.Uint64(),
+		GasTarget:   head.GasLimit / 2,
+	}
+	if blocks == 0 {
+		congestion.OldestBlock = congestion.NewestBlock
+	}
+	var ratios float64
+	for number := congestion.OldestBlock; blocks > 0 && number <= congestion.NewestBlock; number++ {
+		header := head
+		if number != head.Number.Uint64() {
+			if header, err = gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(number)); header == nil {
+				if err == nil {
+					err = fmt.Errorf("block #%d not found", number)
+				}
+				return nil, err
+			}
+		}
+		if header.GasLimit == 0 {
+			continue
+		}
+		ratios += float64(header.GasUsed) / float64(header.GasLimit)
+		if header.GasUsed > header.GasLimit/2 {
+			congestion.BusyBlocks++
+		}
+	}
+	if blocks > 0 {
+		congestion.GasUsedRatio = ratios / float64(blocks)
+	}
+	congestion.Congested = congestion.GasUsedRatio > 0.5
+
+	gpo.cacheLock.Lock()
+	gpo.congestionHead, gpo.congestion = head.Hash(), congestion
+	gpo.cacheLock.Unlock()
+	return congestion, nil
+}
@@ -18,18 +18,29 @@ package gasprice
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
+	lru "github.com/hashicorp/golang-lru"
+
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-const sampleNumber = 3 // Number of transactions sampled in a block
+const (
+	sampleNumber = 3 // Number of transactions sampled in a block
+
+	// feeCacheSize is the number of blocks whose fees are kept around, enough for
+	// the longest fee history.
+	feeCacheSize = maxFeeHistory
+)
 
 var DefaultMaxPrice = big.NewInt(20000 * params.GWei)
 
@@ -45,11 +56,16 @@ type Config struct {
 type OracleBackend interface {
 	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
+	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	ChainConfig() *params.ChainConfig
 }
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
+//
+// The fees paid in each block are processed once and cached by block hash, the
+// price suggestions, fee histories and congestion hints all being derived from
+// the cached blocks.
 type Oracle struct {
 	backend   OracleBackend
 	lastHead  common.Hash
@@ -58,6 +74,11 @@ type Oracle struct {
 	cacheLock sync.RWMutex
 	fetchLock sync.Mutex
 
+	feeCache *lru.Cache // Fees paid in the recent blocks, by block hash
+
+	congestionHead common.Hash
+	congestion     *Congestion
+
 	defaultPrice      *big.Int
 	sampleTxThreshold int
 
@@ -68,6 +89,31 @@ type Oracle struct {
 // NewOracle returns a new gasprice oracle which can recommend suitable
 // gasprice for newly created transaction.
 func NewOracle(backend OracleBackend, params Config) *Oracle {
+	feeCache, _ := lru.New(feeCacheSize)
+	oracle := &Oracle{
+		backend:   backend,
+		lastPrice: params.Default,
+		feeCache:  feeCache,
+	}
+	oracle.configure(params)
+	return oracle
+}
+
+// SetConfig replaces the sampling parameters of the oracle. The price suggested
+// for the current head is recalculated with the new parameters.
+func (gpo *Oracle) SetConfig(params Config) {
+	gpo.fetchLock.Lock()
+	defer gpo.fetchLock.Unlock()
+
+	gpo.configure(params)
+
+	gpo.cacheLock.Lock()
+	gpo.lastHead = common.Hash{}
+	gpo.cacheLock.Unlock()
+}
+
+// configure sanitizes the given parameters and applies them to the oracle.
+func (gpo *Oracle) configure(params Config) {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -87,19 +133,19 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxPrice = DefaultMaxPrice
 		log.Warn("Sanitizing invalid gasprice oracle price cap", "provided", params.MaxPrice, "updated", maxPrice)
 	}
-	return &Oracle{
-		backend:           backend,
-		lastPrice:         params.Default,
-		maxPrice:          maxPrice,
-		checkBlocks:       blocks,
-		percentile:        percent,
-		defaultPrice:      params.Default,
-		sampleTxThreshold: params.OracleThreshold,
-	}
+	gpo.maxPrice = maxPrice
+	gpo.checkBlocks = blocks
+	gpo.percentile = percent
+	gpo.defaultPrice = params.Default
+	gpo.sampleTxThreshold = params.OracleThreshold
 }
 
 // SuggestPrice returns a gasprice so that newly created transaction can
 // have a very high chance to be included in the following blocks.
+//
+// The suggestion is a percentile of the lowest prices paid in the recent blocks.
+// Blocks with a single price paid or none don't tell much, so more blocks are
+// checked for them, up to twice as many.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
@@ -123,46 +169,30 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	}
 
 	var (
-		sent, exp      int
 		number         = head.Number.Uint64()
-		result         = make(chan getBlockPricesResult, gpo.checkBlocks)
-		quit           = make(chan struct{})
 		txPrices       []*big.Int
 		totalTxSamples int
 	)
-	for sent < gpo.checkBlocks && number > 0 {
-		go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(number))), number, sampleNumber, result, quit)
-		sent++
-		exp++
-		number--
-	}
-	for exp > 0 {
-		res := <-result
-		if res.err != nil {
-			close(quit)
-			return lastPrice, res.err
+	for checked, sampled := 0, 0; sampled < gpo.checkBlocks && checked < gpo.checkBlocks*2 && number > 0; checked++ {
+		fees, err := gpo.blockFees(ctx, number, false)
+		if err != nil {
+			return lastPrice, err
 		}
-		exp--
+		number--
 
-		// Nothing returned. There are two special cases here:
+		// Nothing paid. There are two special cases here:
 		// - The block is empty
 		// - All the transactions included are sent by the miner itself.
 		// In these cases, use the latest calculated price for samping.
-		if len(res.prices) == 0 {
-			res.prices = []*big.Int{lastPrice}
-		} else {
-			totalTxSamples = totalTxSamples + res.number
+		if len(fees.prices) == 0 {
+			txPrices = append(txPrices, lastPrice)
+			continue
 		}
-		// Besides, in order to collect enough data for sampling, if nothing
-		// meaningful returned, try to query more blocks. But the maximum
-		// is 2*checkBlocks.
-		if len(res.prices) == 1 && len(txPrices)+1+exp < gpo.checkBlocks*2 && number > 0 {
-			go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(number))), number, sampleNumber, result, quit)
-			sent++
-			exp++
-			number--
+		totalTxSamples += len(fees.prices)
+		txPrices = append(txPrices, fees.prices...)
+		if len(fees.prices) > 1 {
+			sampled++
 		}
-		txPrices = append(txPrices, res.prices...)
 	}
 	price := lastPrice
 	if len(txPrices) > 0 && totalTxSamples > gpo.sampleTxThreshold {
@@ -174,6 +204,12 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	if price.Cmp(gpo.maxPrice) > 0 {
 		price = new(big.Int).Set(gpo.maxPrice)
 	}
+	// Transactions paying less than the base fee of the next block won't make it
+	if config := gpo.backend.ChainConfig(); config.IsBaseFee(new(big.Int).Add(head.Number, common.Big1)) {
+		if baseFee := misc.CalcBaseFee(config, head); price.Cmp(baseFee) < 0 {
+			price = baseFee
+		}
+	}
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
 	gpo.lastPrice = price
@@ -181,10 +217,64 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return price, nil
 }
 
-type getBlockPricesResult struct {
-	number int
-	prices []*big.Int
-	err    error
+// txReward is the gas price paid by a transaction above the base fee, i.e. the
+// reward of the miner, with the gas it used.
+type txReward struct {
+	price   *big.Int
+	gasUsed uint64
+}
+
+// blockFees are the fees paid in a block.
+type blockFees struct {
+	gasUsed  uint64
+	gasLimit uint64
+	baseFee  *big.Int   // Base fee of the block, nil before the base fee fork
+	prices   []*big.Int // Lowest prices paid by the senders other than the miner
+	rewards  []txReward // Rewards paid by all transactions sorted, nil until the receipts are loaded
+}
+
+// blockFees returns the fees paid in the block with the given number, from the
+// cache if possible. The rewards of the transactions are loaded if requested,
+// needing the receipts of the block.
+//
+// Cached fees are never modified, the rewards being added to a copy.
+func (gpo *Oracle) blockFees(ctx context.Context, number uint64, rewards bool) (*blockFees, error) {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		return nil, err
+	}
+	var fees *blockFees
+	if cached, ok := gpo.feeCache.Get(block.Hash()); ok {
+		fees = cached.(*blockFees)
+	} else {
+		fees = newBlockFees(block, types.MakeSigner(gpo.backend.ChainConfig(), block.Number()), sampleNumber)
+		gpo.feeCache.Add(block.Hash(), fees)
+	}
+	if !rewards || fees.rewards != nil || len(block.Transactions()) == 0 {
+		return fees, nil
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("block #%d: receipt count mismatch: have %d, want %d", number, len(receipts), len(block.Transactions()))
+	}
+	loaded := *fees
+	loaded.rewards = make([]txReward, len(receipts))
+	for i, tx := range block.Transactions() {
+		reward := tx.GasPrice()
+		if fees.baseFee != nil {
+			reward = math.BigMax(reward.Sub(reward, fees.baseFee), common.Big0)
+		}
+		loaded.rewards[i] = txReward{price: reward, gasUsed: receipts[i].GasUsed}
+	}
+	sort.SliceStable(loaded.rewards, func(i, j int) bool { return loaded.rewards[i].price.Cmp(loaded.rewards[j].price) < 0 })
+	gpo.feeCache.Add(block.Hash(), &loaded)
+	return &loaded, nil
 }
 
 type transactionsByGasPrice []*types.Transaction
@@ -193,41 +283,31 @@ func (t transactionsByGasPrice) Len() int           { return len(t) }
 func (t transactionsByGasPrice) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
 func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPriceCmp(t[j]) < 0 }
 
-// getBlockPrices calculates the lowest transaction gas price in a given block
-// and sends it to the result channel. If the block is empty or all transactions
-// are sent by the miner itself(it doesn't make any sense to include this kind of
-// transaction prices for sampling), nil gasprice is returned.
-func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, limit int, result chan getBlockPricesResult, quit chan struct{}) {
-	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
-	if block == nil {
-		select {
-		case result <- getBlockPricesResult{0, nil, err}:
-		case <-quit:
-		}
-		return
-	}
+// newBlockFees processes the fees paid in a block, sampling up to limit of its
+// lowest transaction gas prices. The transactions sent by the miner itself (it
+// doesn't make any sense to include this kind of transaction prices for
+// sampling) are skipped.
+func newBlockFees(block *types.Block, signer types.Signer, limit int) *blockFees {
+	fees := &blockFees{gasUsed: block.GasUsed(), gasLimit: block.GasLimit(), baseFee: block.BaseFee()}
+
 	blockTxs := block.Transactions()
 	txs := make([]*types.Transaction, len(blockTxs))
 	copy(txs, blockTxs)
 	sort.Sort(transactionsByGasPrice(txs))
 
-	var prices []*big.Int
 	for _, tx := range txs {
 		if tx.GasPriceIntCmp(common.Big1) <= 0 {
 			continue
 		}
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
-			prices = append(prices, tx.GasPrice())
-			if len(prices) >= limit {
+			fees.prices = append(fees.prices, tx.GasPrice())
+			if len(fees.prices) >= limit {
 				break
 			}
 		}
 	}
-	select {
-	case result <- getBlockPricesResult{len(prices), prices, nil}:
-	case <-quit:
-	}
+	return fees
 }
 
 type bigIntArray []*big.Int
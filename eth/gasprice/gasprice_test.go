@@ -51,16 +51,20 @@ func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber)
 	return b.chain.GetBlockByNumber(uint64(number)), nil
 }
 
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
 
-func newTestBackend(t *testing.T) *testBackend {
+func newTestBackend(t *testing.T, config *params.ChainConfig) *testBackend {
 	var (
 		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 		addr   = crypto.PubkeyToAddress(key.PublicKey)
 		gspec  = &core.Genesis{
-			Config: params.TestChainConfig,
+			Config: config,
 			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(math.MaxInt64)}},
 		}
 		signer = types.LatestSigner(gspec.Config)
@@ -70,7 +74,7 @@ func newTestBackend(t *testing.T) *testBackend {
 	genesis, _ := gspec.Commit(db)
 
 	// Generate testing blocks
-	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 32, func(i int, b *core.BlockGen) {
+	blocks, _ := core.GenerateChain(config, genesis, engine, db, 32, func(i int, b *core.BlockGen) {
 		b.SetCoinbase(common.Address{1})
 		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.HexToAddress("deadbeef"), big.NewInt(100), 21000, big.NewInt(int64(i+1)*params.GWei), nil), signer, key)
 		if err != nil {
@@ -81,7 +85,7 @@ func newTestBackend(t *testing.T) *testBackend {
 	// Construct testing chain
 	diskdb := rawdb.NewMemoryDatabase()
 	gspec.Commit(diskdb)
-	chain, err := core.NewBlockChain(diskdb, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	chain, err := core.NewBlockChain(diskdb, nil, config, engine, vm.Config{}, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to create local chain, %v", err)
 	}
@@ -103,7 +107,7 @@ func TestSuggestPrice(t *testing.T) {
 		Percentile: 60,
 		Default:    big.NewInt(params.GWei),
 	}
-	backend := newTestBackend(t)
+	backend := newTestBackend(t, params.TestChainConfig)
 	oracle := NewOracle(backend, config)
 
 	// The gas price sampled is: 32G, 31G, 30G, 29G, 28G, 27G
@@ -116,3 +120,139 @@ func TestSuggestPrice(t *testing.T) {
 		t.Fatalf("Gas price mismatch, want %d, got %d", expect, got)
 	}
 }
+
+func TestSuggestPriceReconfigure(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	}
+	backend := newTestBackend(t, params.TestChainConfig)
+	oracle := NewOracle(backend, config)
+
+	if _, err := oracle.SuggestPrice(context.Background()); err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	// Lowering the price cap must apply to the current head too
+	config.MaxPrice = big.NewInt(params.GWei * int64(20))
+	oracle.SetConfig(config)
+
+	got, err := oracle.SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve recommended gas price: %v", err)
+	}
+	if got.Cmp(config.MaxPrice) != 0 {
+		t.Fatalf("Gas price mismatch, want %d, got %d", config.MaxPrice, got)
+	}
+}
+
+func TestFeeHistory(t *testing.T) {
+	backend := newTestBackend(t, params.TestChainConfig)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	// Every block has a single transaction paying (number) GWei
+	oldest, reward, baseFee, ratios, err := oracle.FeeHistory(context.Background(), 3, rpc.LatestBlockNumber, []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Uint64() != 30 || len(reward) != 3 || len(baseFee) != 4 || len(ratios) != 3 {
+		t.Fatalf("history mismatch: oldest %d, %d rewards, %d base fees, %d ratios", oldest, len(reward), len(baseFee), len(ratios))
+	}
+	for i, fee := range baseFee {
+		if fee.Sign() != 0 {
+			t.Errorf("base fee %d mismatch: have %v, want 0", i, fee)
+		}
+	}
+	for i := range reward {
+		number := uint64(30 + i)
+		price := big.NewInt(int64(number) * params.GWei)
+		for j, r := range reward[i] {
+			if r.Cmp(price) != 0 {
+				t.Errorf("block #%d: reward %d mismatch: have %v, want %v", number, j, r, price)
+			}
+		}
+		block := backend.chain.GetBlockByNumber(number)
+		if want := float64(block.GasUsed()) / float64(block.GasLimit()); ratios[i] != want {
+			t.Errorf("block #%d: gas used ratio mismatch: have %f, want %f", number, ratios[i], want)
+		}
+	}
+	// The history is cut at the genesis, rewards are only returned if requested
+	if oldest, reward, _, ratios, err = oracle.FeeHistory(context.Background(), 10, 4, nil); err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Uint64() != 0 || reward != nil || len(ratios) != 5 {
+		t.Errorf("history mismatch: oldest %d, %d rewards, %d ratios", oldest, len(reward), len(ratios))
+	}
+	for _, percentiles := range [][]float64{{-1}, {101}, {50, 10}} {
+		if _, _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, percentiles); err == nil {
+			t.Errorf("invalid percentiles %v accepted", percentiles)
+		}
+	}
+}
+
+func TestFeeHistoryBaseFee(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BaseFeeBlock = big.NewInt(16)
+	backend := newTestBackend(t, &config)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	// The rewards are the prices paid above the base fee once it's activated
+	_, reward, baseFee, _, err := oracle.FeeHistory(context.Background(), 18, rpc.LatestBlockNumber, []float64{50})
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	for i := range reward {
+		var (
+			number = uint64(15 + i)
+			header = backend.chain.GetHeaderByNumber(number)
+			want   = big.NewInt(int64(number) * params.GWei)
+		)
+		switch {
+		case number < 16 && (header.BaseFee != nil || baseFee[i].Sign() != 0):
+			t.Errorf("block #%d: base fee before fork: header %v, history %v", number, header.BaseFee, baseFee[i])
+			continue
+		case number == 16 && header.BaseFee.Uint64() != params.InitialBaseFee:
+			t.Errorf("block #%d: initial base fee mismatch: have %v, want %d", number, header.BaseFee, params.InitialBaseFee)
+		case number > 16 && header.BaseFee.Cmp(backend.chain.GetHeaderByNumber(number-1).BaseFee) >= 0:
+			t.Errorf("block #%d: base fee of an almost empty block not decreased: %v", number, header.BaseFee)
+		}
+		if header.BaseFee != nil {
+			if baseFee[i].Cmp(header.BaseFee) != 0 {
+				t.Errorf("block #%d: base fee mismatch: have %v, want %v", number, baseFee[i], header.BaseFee)
+			}
+			want.Sub(want, header.BaseFee)
+		}
+		if reward[i][0].Cmp(want) != 0 {
+			t.Errorf("block #%d: reward mismatch: have %v, want %v", number, reward[i][0], want)
+		}
+	}
+	head := backend.chain.CurrentBlock().Header()
+	if next := baseFee[len(baseFee)-1]; next.Sign() <= 0 || next.Cmp(head.BaseFee) >= 0 {
+		t.Errorf("next base fee mismatch: have %v, head %v", next, head.BaseFee)
+	}
+	// The suggested price is still the one paid in the latest blocks
+	if price, _ := oracle.SuggestPrice(context.Background()); price.Cmp(big.NewInt(30*params.GWei)) != 0 {
+		t.Errorf("suggested price mismatch: have %v, want %v", price, 30*params.GWei)
+	}
+}
+
+func TestCongestion(t *testing.T) {
+	backend := newTestBackend(t, params.TestChainConfig)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	congestion, err := oracle.Congestion(context.Background())
+	if err != nil {
+		t.Fatalf("failed to measure congestion: %v", err)
+	}
+	// The chain has no epochs and is shorter than the default window
+	head := backend.chain.CurrentBlock()
+	if congestion.OldestBlock != 1 || congestion.NewestBlock != 32 || congestion.GasTarget != head.GasLimit()/2 {
+		t.Errorf("window mismatch: %+v", congestion)
+	}
+	if congestion.Congested || congestion.BusyBlocks != 0 || congestion.GasUsedRatio <= 0 {
+		t.Errorf("congestion mismatch: %+v", congestion)
+	}
+	if cached, _ := oracle.Congestion(context.Background()); cached != congestion {
+		t.Error("congestion measured again for the same head")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// errTxsPaused is returned for the transactions submitted while the resource
+// governor keeps the node in safety mode.
+var errTxsPaused = errors.New("transaction intake paused, the node is low on resources")
+
+// governorBackend exposes the node to the resource governor.
+type governorBackend struct {
+	eth   *Ethereum
+	stack *node.Node
+}
+
+func (b *governorBackend) PauseTxs(paused bool) {
+	atomic.StoreUint32(&b.eth.handler.txsPaused, boolToUint32(paused))
+}
+
+func (b *governorBackend) PauseSnap(paused bool) {
+	atomic.StoreUint32(&b.eth.handler.snapPaused, boolToUint32(paused))
+}
+
+func (b *governorBackend) FlushCaches() {
+	if err := b.eth.blockchain.StateCache().TrieDB().Cap(0); err != nil {
+		log.Error("Failed to flush the trie cache", "err", err)
+	}
+}
+
+func (b *governorBackend) Shutdown() {
+	go b.stack.Close()
+}
+
+func boolToUint32(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
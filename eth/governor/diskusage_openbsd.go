@@ -16,7 +16,7 @@
 
 // +build openbsd
 
-package utils
+package governor
 
 import (
 	"fmt"
@@ -24,7 +24,8 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func getFreeDiskSpace(path string) (uint64, error) {
+// FreeDiskSpace returns the disk space available to the node at the given path.
+func FreeDiskSpace(path string) (uint64, error) {
 	var stat unix.Statfs_t
 	if err := unix.Statfs(path, &stat); err != nil {
 		return 0, fmt.Errorf("failed to call Statfs: %v", err)
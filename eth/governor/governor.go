@@ -0,0 +1,232 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package governor protects the database of the node from running out of disk
+// space or memory.
+//
+// The governor polls the free disk space of the data directory and the heap in
+// use. Once either crosses its low watermark, the node enters safety mode: it
+// stops accepting new transactions and serving snap state requests, and on heap
+// pressure flushes its caches at every poll. The node leaves safety mode when
+// the usage recovers with some margin. Crossing a critical threshold shuts the
+// node down cleanly, before the OS kills it mid-write.
+package governor
+
+import (
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// recoveryMargin is the fraction of a low watermark the usage has to recover by
+// before the node leaves safety mode, so it doesn't flap around the watermark.
+const recoveryMargin = 0.1
+
+var (
+	safeModeGauge = metrics.NewRegisteredGauge("governor/safemode", nil)
+	freeDiskGauge = metrics.NewRegisteredGauge("governor/disk/free", nil)
+	heapGauge     = metrics.NewRegisteredGauge("governor/heap", nil)
+)
+
+// Config contains the thresholds of the resource governor, in MiB. A zero value
+// disables the corresponding check.
+type Config struct {
+	DiskLow      uint64        `toml:",omitempty"` // Free disk space below which the node enters safety mode
+	DiskCritical uint64        `toml:",omitempty"` // Free disk space below which the node shuts down
+	HeapLow      uint64        `toml:",omitempty"` // Heap in use above which the node enters safety mode
+	HeapCritical uint64        `toml:",omitempty"` // Heap in use above which the node shuts down
+	Interval     time.Duration `toml:",omitempty"` // Interval between two usage polls
+}
+
+// DefaultConfig contains the default settings of the resource governor.
+var DefaultConfig = Config{
+	Interval: 10 * time.Second,
+}
+
+// Enabled returns whether any threshold is configured.
+func (c *Config) Enabled() bool {
+	return c.DiskLow != 0 || c.DiskCritical != 0 || c.HeapLow != 0 || c.HeapCritical != 0
+}
+
+// Backend is the node protected by the governor.
+type Backend interface {
+	// PauseTxs disables or enables accepting new transactions from the network
+	// and the RPC API.
+	PauseTxs(paused bool)
+
+	// PauseSnap disables or enables serving snap state requests.
+	PauseSnap(paused bool)
+
+	// FlushCaches releases the memory held by the caches of the node.
+	FlushCaches()
+
+	// Shutdown stops the node cleanly, without waiting for it.
+	Shutdown()
+}
+
+// Governor monitors the resource usage of the node.
+type Governor struct {
+	config  Config
+	backend Backend
+
+	freeDisk func() (uint64, error) // Free disk space in bytes
+	heap     func() uint64          // Heap in use in bytes
+
+	safe bool // Whether the node is in safety mode
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a governor watching the disk holding the given directory.
+func New(config Config, dir string, backend Backend) (*Governor, error) {
+	if config.DiskLow != 0 && config.DiskLow <= config.DiskCritical {
+		return nil, errors.New("low free disk watermark must be above the critical one")
+	}
+	if config.HeapLow != 0 && config.HeapCritical != 0 && config.HeapLow >= config.HeapCritical {
+		return nil, errors.New("low heap watermark must be below the critical one")
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultConfig.Interval
+	}
+	freeDisk := func() (uint64, error) { return FreeDiskSpace(dir) }
+	return newGovernor(config, backend, freeDisk, heapInUse), nil
+}
+
+func newGovernor(config Config, backend Backend, freeDisk func() (uint64, error), heap func() uint64) *Governor {
+	return &Governor{
+		config:   config,
+		backend:  backend,
+		freeDisk: freeDisk,
+		heap:     heap,
+		quit:     make(chan struct{}),
+	}
+}
+
+// heapInUse returns the bytes in the in-use spans of the heap.
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+// Start implements node.Lifecycle, starting the usage polls.
+func (g *Governor) Start() error {
+	g.wg.Add(1)
+	go g.loop()
+	log.Info("Started resource governor", "disklow", g.config.DiskLow, "diskcritical", g.config.DiskCritical,
+		"heaplow", g.config.HeapLow, "heapcritical", g.config.HeapCritical)
+	return nil
+}
+
+// Stop implements node.Lifecycle.
+func (g *Governor) Stop() error {
+	close(g.quit)
+	g.wg.Wait()
+	return nil
+}
+
+func (g *Governor) loop() {
+	defer g.wg.Done()
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if !g.step() {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-g.quit:
+			return
+		}
+	}
+}
+
+// step polls the resource usage and reacts to it, returning false once the node
+// was shut down.
+func (g *Governor) step() bool {
+	var (
+		mib      = uint64(1024 * 1024)
+		heap     = g.heap()
+		diskLow  bool
+		diskGood = true
+	)
+	heapGauge.Update(int64(heap))
+	if g.config.DiskLow != 0 || g.config.DiskCritical != 0 {
+		free, err := g.freeDisk()
+		if err != nil {
+			log.Warn("Failed to get free disk space", "err", err)
+			diskGood = false // Don't leave safety mode blindly
+		} else {
+			freeDiskGauge.Update(int64(free))
+			if g.config.DiskCritical != 0 && free < g.config.DiskCritical*mib {
+				log.Error("Free disk space critical, shutting down to protect the database", "available", common.StorageSize(free))
+				return g.shutDown()
+			}
+			diskLow = g.config.DiskLow != 0 && free < g.config.DiskLow*mib
+			diskGood = g.config.DiskLow == 0 || float64(free) >= float64(g.config.DiskLow*mib)*(1+recoveryMargin)
+		}
+	}
+	if g.config.HeapCritical != 0 && heap > g.config.HeapCritical*mib {
+		log.Error("Heap usage critical, shutting down to protect the database", "heap", common.StorageSize(heap))
+		return g.shutDown()
+	}
+	heapLow := g.config.HeapLow != 0 && heap > g.config.HeapLow*mib
+	heapGood := g.config.HeapLow == 0 || float64(heap) <= float64(g.config.HeapLow*mib)*(1-recoveryMargin)
+
+	if heapLow {
+		g.backend.FlushCaches()
+		debug.FreeOSMemory()
+	}
+	switch {
+	case !g.safe && (diskLow || heapLow):
+		log.Warn("Resources running low, entering safety mode", "disklow", diskLow, "heaplow", heapLow, "heap", common.StorageSize(heap))
+		g.setSafe(true)
+	case g.safe && diskGood && heapGood:
+		log.Info("Resources recovered, leaving safety mode", "heap", common.StorageSize(heap))
+		g.setSafe(false)
+	}
+	return true
+}
+
+// setSafe enters or leaves safety mode.
+func (g *Governor) setSafe(safe bool) {
+	g.safe = safe
+	g.backend.PauseTxs(safe)
+	g.backend.PauseSnap(safe)
+	if safe {
+		safeModeGauge.Update(1)
+	} else {
+		safeModeGauge.Update(0)
+	}
+}
+
+// shutDown stops the node. The governor polls no more.
+func (g *Governor) shutDown() bool {
+	if !g.safe {
+		g.setSafe(true)
+	}
+	g.backend.Shutdown()
+	return false
+}
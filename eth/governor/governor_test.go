@@ -0,0 +1,125 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package governor
+
+import (
+	"errors"
+	"testing"
+)
+
+const mib = 1024 * 1024
+
+type testBackend struct {
+	txsPaused  bool
+	snapPaused bool
+	flushes    int
+	shutdown   bool
+}
+
+func (b *testBackend) PauseTxs(paused bool)  { b.txsPaused = paused }
+func (b *testBackend) PauseSnap(paused bool) { b.snapPaused = paused }
+func (b *testBackend) FlushCaches()          { b.flushes++ }
+func (b *testBackend) Shutdown()             { b.shutdown = true }
+
+// testUsage is the resource usage reported to the governor.
+type testUsage struct {
+	free uint64
+	heap uint64
+	err  error
+}
+
+func newTestGovernor(config Config) (*Governor, *testBackend, *testUsage) {
+	backend, usage := new(testBackend), new(testUsage)
+	freeDisk := func() (uint64, error) { return usage.free, usage.err }
+	heap := func() uint64 { return usage.heap }
+	return newGovernor(config, backend, freeDisk, heap), backend, usage
+}
+
+func TestGovernorHeap(t *testing.T) {
+	gov, backend, usage := newTestGovernor(Config{HeapLow: 100, HeapCritical: 200})
+
+	usage.heap = 50 * mib
+	gov.step()
+	if backend.txsPaused || backend.snapPaused || backend.flushes != 0 {
+		t.Fatalf("governor reacted below the watermark: %+v", backend)
+	}
+	// Crossing the low watermark enters safety mode and flushes the caches
+	usage.heap = 150 * mib
+	gov.step()
+	if !backend.txsPaused || !backend.snapPaused || backend.flushes != 1 {
+		t.Fatalf("governor didn't enter safety mode: %+v", backend)
+	}
+	// Safety mode is kept until the usage recovers by the margin
+	usage.heap = 95 * mib
+	gov.step()
+	if !backend.txsPaused || !backend.snapPaused {
+		t.Fatalf("governor left safety mode within the margin: %+v", backend)
+	}
+	usage.heap = 80 * mib
+	gov.step()
+	if backend.txsPaused || backend.snapPaused {
+		t.Fatalf("governor didn't leave safety mode: %+v", backend)
+	}
+	// Crossing the critical threshold shuts the node down
+	usage.heap = 250 * mib
+	if gov.step() {
+		t.Fatalf("governor kept polling after the shutdown")
+	}
+	if !backend.shutdown || !backend.txsPaused {
+		t.Fatalf("governor didn't shut the node down: %+v", backend)
+	}
+}
+
+func TestGovernorDisk(t *testing.T) {
+	gov, backend, usage := newTestGovernor(Config{DiskLow: 1000, DiskCritical: 100})
+
+	usage.free = 500 * mib
+	gov.step()
+	if !backend.txsPaused || !backend.snapPaused {
+		t.Fatalf("governor didn't enter safety mode: %+v", backend)
+	}
+	if backend.flushes != 0 {
+		t.Errorf("caches flushed on low disk space")
+	}
+	// Failing to measure the disk keeps the safety mode
+	usage.free, usage.err = 2000*mib, errors.New("statfs failed")
+	gov.step()
+	if !backend.txsPaused {
+		t.Fatalf("governor left safety mode without disk usage")
+	}
+	usage.err = nil
+	gov.step()
+	if backend.txsPaused {
+		t.Fatalf("governor didn't leave safety mode: %+v", backend)
+	}
+	usage.free = 50 * mib
+	if gov.step() || !backend.shutdown {
+		t.Fatalf("governor didn't shut the node down: %+v", backend)
+	}
+}
+
+func TestGovernorConfig(t *testing.T) {
+	if _, err := New(Config{DiskLow: 100, DiskCritical: 200}, ".", new(testBackend)); err == nil {
+		t.Errorf("disk watermarks in the wrong order accepted")
+	}
+	if _, err := New(Config{HeapLow: 200, HeapCritical: 100}, ".", new(testBackend)); err == nil {
+		t.Errorf("heap watermarks in the wrong order accepted")
+	}
+	if _, err := New(Config{DiskCritical: 100, HeapLow: 100}, ".", new(testBackend)); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+}
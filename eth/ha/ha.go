@@ -0,0 +1,351 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ha implements the failover between a primary and a backup validator
+// node holding the same consensus keys.
+//
+// Only the active node seals blocks and signs votes, the other one stays on
+// standby. The nodes poll each other's status over RPC: a standby node takes
+// over if its partner can't be reached, stops following the chain, or is on
+// standby itself (immediately for the primary, after the failover timeout for
+// the backup). If both nodes end up active, the one behind, or the backup on a
+// tie, steps down. Before sealing again, a node raises the watermark of its
+// slashing protection database to the highest block its partner may have sealed.
+//
+// Failover can't distinguish a crashed partner from a network partition, so the
+// nodes should be connected by a reliable link.
+package ha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Roles of the nodes of a failover pair.
+const (
+	RolePrimary = "primary"
+	RoleBackup  = "backup"
+)
+
+var (
+	activeGauge    = metrics.NewRegisteredGauge("ha/active", nil)
+	failoverMeter  = metrics.NewRegisteredMeter("ha/failover", nil)
+	peerErrorMeter = metrics.NewRegisteredMeter("ha/peer/errors", nil)
+)
+
+// Config contains the settings of the failover coordinator.
+type Config struct {
+	Role            string        `toml:",omitempty"` // Role of the node, failover is disabled if empty
+	Peer            string        `toml:",omitempty"` // RPC endpoint of the partner node, exposing the ha API
+	Interval        time.Duration `toml:",omitempty"` // Interval between two status polls of the partner
+	FailoverTimeout time.Duration `toml:",omitempty"` // Time after which an unresponsive partner is replaced
+}
+
+// DefaultConfig contains the default settings of the failover coordinator.
+var DefaultConfig = Config{
+	Interval:        time.Second,
+	FailoverTimeout: 15 * time.Second,
+}
+
+// Enabled returns whether the failover is configured.
+func (c *Config) Enabled() bool {
+	return c.Role != ""
+}
+
+// Backend is the node controlled by the coordinator.
+type Backend interface {
+	// CurrentHeader returns the head of the local chain.
+	CurrentHeader() *types.Header
+
+	// Etherbase returns the address of the local validator.
+	Etherbase() (common.Address, error)
+
+	// SetStandby disables or enables the sealing of blocks and signing of votes.
+	SetStandby(standby bool)
+
+	// RaiseSealWatermark makes the slashing protection refuse the sealing of any
+	// block up to the given height.
+	RaiseSealWatermark(validator common.Address, number uint64) error
+}
+
+// Status is the failover state of a node, polled by its partner.
+type Status struct {
+	Role   string `json:"role"`
+	Active bool   `json:"active"` // Whether the node seals blocks and signs votes
+	Held   bool   `json:"held"`   // Whether the node was put on standby by the operator
+	Head   uint64 `json:"head"`   // Number of the head block of the node
+}
+
+// peer retrieves the status of the partner node.
+type peer interface {
+	Status(ctx context.Context) (*Status, error)
+}
+
+// rpcPeer is a partner node reached over RPC.
+type rpcPeer struct {
+	url    string
+	client *rpc.Client
+}
+
+func (p *rpcPeer) Status(ctx context.Context) (*Status, error) {
+	if p.client == nil {
+		client, err := rpc.DialContext(ctx, p.url)
+		if err != nil {
+			return nil, err
+		}
+		p.client = client
+	}
+	status := new(Status)
+	if err := p.client.CallContext(ctx, status, "ha_status"); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Coordinator is a node lifecycle activating the local validator if its partner
+// fails.
+type Coordinator struct {
+	config  Config
+	backend Backend
+	peer    peer
+
+	lock         sync.Mutex
+	active       bool      // Whether the local validator seals blocks and signs votes
+	held         bool      // Whether the operator put the node on standby
+	lastContact  time.Time // Last time the partner answered
+	lastProgress time.Time // Last time the head of the partner advanced
+	lastActive   time.Time // Last time the partner was seen active
+	peerHead     uint64    // Highest head reported by the partner
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a failover coordinator, putting the local validator on standby
+// until the partner node was consulted.
+func New(config Config, backend Backend) (*Coordinator, error) {
+	if config.Role != RolePrimary && config.Role != RoleBackup {
+		return nil, fmt.Errorf("invalid failover role %q, want %s or %s", config.Role, RolePrimary, RoleBackup)
+	}
+	if config.Peer == "" {
+		return nil, errors.New("failover partner endpoint missing")
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultConfig.Interval
+	}
+	if config.FailoverTimeout < config.Interval {
+		return nil, fmt.Errorf("failover timeout %v below the poll interval %v", config.FailoverTimeout, config.Interval)
+	}
+	return newCoordinator(config, backend, &rpcPeer{url: config.Peer}), nil
+}
+
+func newCoordinator(config Config, backend Backend, peer peer) *Coordinator {
+	backend.SetStandby(true)
+	activeGauge.Update(0)
+
+	now := time.Now()
+	return &Coordinator{
+		config:       config,
+		backend:      backend,
+		peer:         peer,
+		lastContact:  now,
+		lastProgress: now,
+		lastActive:   now,
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start implements node.Lifecycle, starting the polling of the partner.
+func (c *Coordinator) Start() error {
+	c.wg.Add(1)
+	go c.loop()
+	log.Info("Started validator failover", "role", c.config.Role, "peer", c.config.Peer)
+	return nil
+}
+
+// Stop implements node.Lifecycle, putting the validator on standby.
+func (c *Coordinator) Stop() error {
+	close(c.quit)
+	c.wg.Wait()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.setActive(false, "shutdown")
+	return nil
+}
+
+func (c *Coordinator) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), c.config.Interval)
+		c.step(ctx, time.Now())
+		cancel()
+
+		select {
+		case <-ticker.C:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// step polls the partner and activates or deactivates the local validator.
+func (c *Coordinator) step(ctx context.Context, now time.Time) {
+	status, err := c.peer.Status(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err != nil {
+		peerErrorMeter.Mark(1)
+		log.Debug("Failover partner unreachable", "err", err)
+		if !c.active && now.Sub(c.lastContact) >= c.config.FailoverTimeout {
+			c.setActive(true, "partner unreachable")
+		}
+		return
+	}
+	c.lastContact = now
+	if status.Head > c.peerHead {
+		c.peerHead, c.lastProgress = status.Head, now
+	}
+	if status.Active {
+		c.lastActive = now
+	}
+	if status.Role == c.config.Role {
+		log.Error("Failover partner has the same role", "role", status.Role)
+	}
+	var (
+		head    = c.backend.CurrentHeader().Number.Uint64()
+		stalled = now.Sub(c.lastProgress) >= c.config.FailoverTimeout
+	)
+	switch {
+	case c.active && status.Active && !stalled && (status.Head > head || c.config.Role == RoleBackup):
+		c.setActive(false, "partner active")
+	case !c.active && status.Active && stalled:
+		c.setActive(true, "partner stalled")
+	case !c.active && !status.Active && (c.config.Role == RolePrimary || now.Sub(c.lastActive) >= c.config.FailoverTimeout):
+		c.setActive(true, "partner on standby")
+	}
+}
+
+// setActive switches the local validator between active and standby, unless it
+// was put on standby by the operator. The lock must be held.
+func (c *Coordinator) setActive(active bool, reason string) {
+	if active == c.active || (active && c.held) {
+		return
+	}
+	if active {
+		validator, err := c.backend.Etherbase()
+		if err != nil {
+			log.Error("Failed to activate validator", "err", err)
+			return
+		}
+		// Never reseal a height the partner may have sealed already
+		watermark := c.backend.CurrentHeader().Number.Uint64()
+		if c.peerHead > watermark {
+			watermark = c.peerHead
+		}
+		if err := c.backend.RaiseSealWatermark(validator, watermark); err != nil {
+			log.Error("Failed to raise the seal watermark", "number", watermark, "err", err)
+			return
+		}
+		failoverMeter.Mark(1)
+		activeGauge.Update(1)
+		log.Warn("Activated validator", "reason", reason, "watermark", watermark)
+	} else {
+		activeGauge.Update(0)
+		log.Warn("Put validator on standby", "reason", reason)
+	}
+	c.active = active
+	c.backend.SetStandby(!active)
+}
+
+// Status returns the failover state of the local node.
+func (c *Coordinator) Status() *Status {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return &Status{
+		Role:   c.config.Role,
+		Active: c.active,
+		Held:   c.held,
+		Head:   c.backend.CurrentHeader().Number.Uint64(),
+	}
+}
+
+// Hold puts the local validator on standby until Release is called, letting the
+// partner take over.
+func (c *Coordinator) Hold() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.setActive(false, "held by operator")
+	c.held = true
+}
+
+// Release allows the local validator to become active again.
+func (c *Coordinator) Release() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.held = false
+}
+
+// APIs returns the RPC API of the coordinator, polled by the partner node.
+func (c *Coordinator) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "ha",
+		Version:   "1.0",
+		Service:   &API{c: c},
+		Public:    false,
+	}}
+}
+
+// API is the RPC API of the failover coordinator.
+type API struct {
+	c *Coordinator
+}
+
+// Status returns the failover state of the node.
+func (api *API) Status() *Status {
+	return api.c.Status()
+}
+
+// Hold puts the validator on standby until released, letting the partner node
+// take over.
+func (api *API) Hold() *Status {
+	api.c.Hold()
+	return api.c.Status()
+}
+
+// Release allows the validator to become active again.
+func (api *API) Release() *Status {
+	api.c.Release()
+	return api.c.Status()
+}
@@ -0,0 +1,143 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ha
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type testBackend struct {
+	head      uint64
+	standby   bool
+	watermark uint64
+}
+
+func (b *testBackend) CurrentHeader() *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(b.head)}
+}
+
+func (b *testBackend) Etherbase() (common.Address, error) { return common.Address{1}, nil }
+
+func (b *testBackend) SetStandby(standby bool) { b.standby = standby }
+
+func (b *testBackend) RaiseSealWatermark(validator common.Address, number uint64) error {
+	b.watermark = number
+	return nil
+}
+
+// testPeer returns a fixed status, or fails if it's nil.
+type testPeer struct {
+	status *Status
+}
+
+func (p *testPeer) Status(ctx context.Context) (*Status, error) {
+	if p.status == nil {
+		return nil, errors.New("unreachable")
+	}
+	return p.status, nil
+}
+
+var testConfig = Config{Interval: time.Second, FailoverTimeout: 10 * time.Second}
+
+func newTestCoordinator(role string) (*Coordinator, *testBackend, *testPeer, time.Time) {
+	config := testConfig
+	config.Role = role
+
+	backend, peer := &testBackend{head: 100}, new(testPeer)
+	c := newCoordinator(config, backend, peer)
+	return c, backend, peer, c.lastContact
+}
+
+func TestBackupTakeover(t *testing.T) {
+	c, backend, peer, start := newTestCoordinator(RoleBackup)
+	if !backend.standby {
+		t.Fatal("coordinator not starting on standby")
+	}
+	// An active primary following the chain keeps the backup on standby
+	peer.status = &Status{Role: RolePrimary, Active: true, Head: 100}
+	c.step(context.Background(), start.Add(time.Second))
+	peer.status = &Status{Role: RolePrimary, Active: true, Head: 101}
+	c.step(context.Background(), start.Add(5*time.Second))
+	if !backend.standby {
+		t.Fatal("backup activated with a healthy primary")
+	}
+	// The backup takes over once the primary is unreachable for the timeout
+	peer.status = nil
+	c.step(context.Background(), start.Add(10*time.Second))
+	if !backend.standby {
+		t.Fatal("backup activated before the failover timeout")
+	}
+	backend.head = 102
+	c.step(context.Background(), start.Add(15*time.Second))
+	if backend.standby {
+		t.Fatal("backup not activated with an unreachable primary")
+	}
+	if backend.watermark != 102 {
+		t.Fatalf("seal watermark mismatch: have %d, want 102", backend.watermark)
+	}
+	// A recovered primary ahead of the backup makes it step down
+	peer.status = &Status{Role: RolePrimary, Active: true, Head: 110}
+	c.step(context.Background(), start.Add(16*time.Second))
+	if !backend.standby {
+		t.Fatal("backup not stepping down for an active primary")
+	}
+}
+
+func TestPrimaryStalled(t *testing.T) {
+	c, backend, peer, start := newTestCoordinator(RolePrimary)
+
+	// The backup is active and the primary doesn't take over...
+	peer.status = &Status{Role: RoleBackup, Active: true, Head: 100}
+	c.step(context.Background(), start.Add(time.Second))
+	if !backend.standby {
+		t.Fatal("primary activated while the backup is active")
+	}
+	// ...unless the backup stops following the chain
+	c.step(context.Background(), start.Add(11*time.Second))
+	if backend.standby {
+		t.Fatal("primary not activated with a stalled backup")
+	}
+}
+
+func TestPrimaryHold(t *testing.T) {
+	c, backend, peer, start := newTestCoordinator(RolePrimary)
+
+	// The primary activates as soon as the backup is seen on standby
+	peer.status = &Status{Role: RoleBackup, Active: false, Head: 100}
+	c.step(context.Background(), start.Add(time.Second))
+	if backend.standby {
+		t.Fatal("primary not activated with a backup on standby")
+	}
+	// Held by the operator, the primary doesn't reactivate until released
+	c.Hold()
+	c.step(context.Background(), start.Add(2*time.Second))
+	if !backend.standby || !c.Status().Held {
+		t.Fatal("held primary reactivated")
+	}
+	c.Release()
+	c.step(context.Background(), start.Add(3*time.Second))
+	if backend.standby {
+		t.Fatal("released primary not reactivated")
+	}
+}
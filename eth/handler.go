@@ -20,6 +20,7 @@ import (
 	"errors"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/fetcher"
 	"github.com/ethereum/go-ethereum/eth/protocols/diff"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
@@ -37,6 +39,8 @@ import (
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie"
@@ -66,6 +70,10 @@ type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// AddRemotesFrom should add the given transactions received from the
+	// given network origin to the pool.
+	AddRemotesFrom(string, []*types.Transaction) []error
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -92,28 +100,44 @@ type handlerConfig struct {
 	EventMux               *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint             *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
 	Whitelist              map[uint64]common.Hash    // Hard coded whitelist for sync challenged
+	SyncCheckpoint         common.Hash               // Trusted block the fast and snap synced chain must contain
 	DirectBroadcast        bool
 	DisablePeerTxBroadcast bool
+	PrivateTxPeers         []enode.ID             // Trusted peers to relay private transactions to
+	PeerGroups             []*ethconfig.PeerGroup // Named groups of peers with their policies
+	SentryValidators       []enode.ID             // Hidden validators relayed to as their sentry
+	Sentries               []enode.ID             // Sentries of the hidden validator, the only peers accepted
+	Capabilities           eth.Capabilities       // Services advertised to the peers
+	ServeWitness           bool                   // Whether to serve the execution witnesses to the peers
+	TracePropagation       bool                   // Whether to trace the block propagation with the consenting peers
 }
 
 type handler struct {
 	networkID              uint64
 	forkFilter             forkid.Filter // Fork ID filter, constant across the lifetime of the node
 	disablePeerTxBroadcast bool
+	capabilities           eth.Capabilities // Services advertised to the peers in the handshake
 
 	fastSync        uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
 	snapSync        uint32 // Flag whether fast sync should operate on top of the snap protocol
 	acceptTxs       uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	txsPaused       uint32 // Flag whether the resource governor paused the transaction intake
+	snapPaused      uint32 // Flag whether the resource governor paused serving snap requests
 	directBroadcast bool
 	diffSync        bool // Flag whether diff sync should operate on top of the diff protocol
 
+	serveWitness bool       // Whether the execution witnesses are served to the peers
+	witnessLock  sync.Mutex // Lock building one witness at a time, it's expensive
+
+	tracer *propagationTracer // Tracer of the block propagation, nil if disabled
+
 	checkpointNumber uint64      // Block number for the sync progress validator to cross reference
 	checkpointHash   common.Hash // Block hash for the sync progress validator to cross reference
 
 	database ethdb.Database
 	txpool   txPool
 	chain    *core.BlockChain
-	maxPeers int
+	maxPeers int32 // Accessed atomically, changed by config reloads
 
 	downloader   *downloader.Downloader
 	stateBloom   *trie.SyncBloom
@@ -130,6 +154,12 @@ type handler struct {
 
 	whitelist map[uint64]common.Hash
 
+	privateTxPeers map[string]struct{} // Trusted peers to relay private transactions to
+	privateTxs     *privateTxSet       // Transactions never gossiped to the public network
+	groups         *peerGroups         // Named groups of peers with their policies
+	proxyPeers     map[string]struct{} // Sentries or hidden validators relaying everything to each other
+	hidden         bool                // Whether only the sentries are accepted as peers
+
 	// channels for fetcher, syncer, txsyncLoop
 	txsyncCh chan *txsync
 	quitSync chan struct{}
@@ -149,6 +179,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		networkID:              config.Network,
 		forkFilter:             forkid.NewFilter(config.Chain),
 		disablePeerTxBroadcast: config.DisablePeerTxBroadcast,
+		capabilities:           config.Capabilities,
 		eventMux:               config.EventMux,
 		database:               config.Database,
 		txpool:                 config.TxPool,
@@ -157,9 +188,28 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		whitelist:              config.Whitelist,
 		directBroadcast:        config.DirectBroadcast,
 		diffSync:               config.DiffSync,
+		serveWitness:           config.ServeWitness,
+		privateTxPeers:         make(map[string]struct{}),
+		proxyPeers:             make(map[string]struct{}),
+		hidden:                 len(config.Sentries) > 0,
 		txsyncCh:               make(chan *txsync),
 		quitSync:               make(chan struct{}),
 	}
+	if config.TracePropagation {
+		h.tracer = newPropagationTracer(config.Chain.Config())
+	}
+	for _, id := range config.PrivateTxPeers {
+		h.privateTxPeers[id.String()] = struct{}{}
+	}
+	for _, id := range append(config.SentryValidators, config.Sentries...) {
+		h.proxyPeers[id.String()] = struct{}{}
+	}
+	groups, err := newPeerGroups(config.PeerGroups)
+	if err != nil {
+		return nil, err
+	}
+	h.groups = groups
+	h.privateTxs = newPrivateTxSet(func(hash common.Hash) bool { return h.txpool.Has(hash) })
 	if config.Sync == downloader.FullSync {
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
@@ -205,6 +255,14 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	if h.diffSync {
 		downloadOptions = append(downloadOptions, downloader.EnableDiffFetchOp(h.peers))
 	}
+	if config.SyncCheckpoint != (common.Hash{}) {
+		downloadOptions = append(downloadOptions, downloader.EnableSyncCheckpoint(config.SyncCheckpoint))
+	}
+	if parlia := h.chain.Config().Parlia; parlia != nil {
+		downloadOptions = append(downloadOptions, downloader.EnableBlockIntervalOp(func(number uint64) time.Duration {
+			return time.Duration(parlia.PeriodMs(new(big.Int).SetUint64(number))) * time.Millisecond
+		}))
+	}
 	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.stateBloom, h.eventMux, h.chain, nil, h.removePeer, downloadOptions...)
 
 	// Construct the fetcher (short sync)
@@ -238,6 +296,15 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		if err == nil {
 			atomic.StoreUint32(&h.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		}
+		if h.tracer != nil {
+			imported := blocks
+			if err != nil {
+				imported = blocks[:n]
+			}
+			for _, block := range imported {
+				h.tracer.imported(block, h.chain.GetCanonicalHash(block.NumberU64()) == block.Hash())
+			}
+		}
 		return n, err
 	}
 	h.blockFetcher = fetcher.NewBlockFetcher(false, nil, h.chain.GetBlockByHash, validator, h.BroadcastBlock, heighter, nil, inserter, h.removePeer)
@@ -249,11 +316,26 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		}
 		return p.RequestTxs(hashes)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, h.txpool.AddRemotes, fetchTx)
+	addTxs := func(peer string, txs []*types.Transaction) []error {
+		return h.txpool.AddRemotesFrom(h.txOrigin(peer), txs)
+	}
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx)
 	h.chainSync = newChainSyncer(h)
 	return h, nil
 }
 
+// txOrigin returns the network origin the transactions of a peer are accounted
+// to by the pool quotas: its IP address, so that reconnecting under a new node
+// ID doesn't reset it, or the peer ID if the address is unknown.
+func (h *handler) txOrigin(peer string) string {
+	if p := h.peers.peer(peer); p != nil {
+		if ip := netutil.AddrIP(p.RemoteAddr()); ip != nil {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
 // runEthPeer registers an eth peer into the joint eth/snap peerset, adds it to
 // various subsistems and starts handling messages.
 func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
@@ -269,6 +351,11 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		peer.Log().Error("Diff extension barrier failed", "err", err)
 		return err
 	}
+	// A hidden validator doesn't reveal itself to anyone but its sentries
+	if h.hidden && !h.isProxyPeer(peer.ID()) {
+		peer.Log().Debug("Rejected peer of hidden validator")
+		return p2p.DiscUselessPeer
+	}
 	// TODO(karalabe): Not sure why this is needed
 	if !h.chainSync.handlePeerEvent(peer) {
 		return p2p.DiscQuitting
@@ -285,7 +372,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		td      = h.chain.GetTd(hash, number)
 	)
 	forkID := forkid.NewID(h.chain.Config(), h.chain.Genesis().Hash(), h.chain.CurrentHeader().Number.Uint64())
-	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter, &eth.UpgradeStatusExtension{DisablePeerTxBroadcast: h.disablePeerTxBroadcast}); err != nil {
+	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter, &eth.UpgradeStatusExtension{DisablePeerTxBroadcast: h.disablePeerTxBroadcast}, h.capabilities); err != nil {
 		peer.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -302,7 +389,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 	}
 	// Ignore maxPeers if this is a trusted peer
 	if !peer.Peer.Info().Network.Trusted {
-		if reject || h.peers.len() >= h.maxPeers {
+		if reject || h.peers.len() >= int(atomic.LoadInt32(&h.maxPeers)) {
 			return p2p.DiscTooManyPeers
 		}
 	}
@@ -430,7 +517,7 @@ func (h *handler) removePeer(id string) {
 }
 
 func (h *handler) Start(maxPeers int) {
-	h.maxPeers = maxPeers
+	atomic.StoreInt32(&h.maxPeers, int32(maxPeers))
 
 	// broadcast transactions
 	h.wg.Add(1)
@@ -491,12 +578,27 @@ func (h *handler) BroadcastBlock(block *types.Block, propagate bool) {
 			log.Error("Propagating dangling block", "number", block.Number(), "hash", hash)
 			return
 		}
-		// Send the block to a subset of our peers
-		var transfer []*ethPeer
+		// Send the block to the proxy peers and the peers of the groups getting full
+		// blocks, and to a subset of the peers without a policy. The rest get the
+		// announcement.
+		var transfer, split []*ethPeer
+		for _, peer := range peers {
+			if h.isProxyPeer(peer.ID()) {
+				transfer = append(transfer, peer)
+				continue
+			}
+			switch h.groups.blocks(peer.ID()) {
+			case ethconfig.BlocksFull:
+				transfer = append(transfer, peer)
+			case ethconfig.BlocksAnnounce:
+			default:
+				split = append(split, peer)
+			}
+		}
 		if h.directBroadcast {
-			transfer = peers[:]
+			transfer = append(transfer, split...)
 		} else {
-			transfer = peers[:int(math.Sqrt(float64(len(peers))))]
+			transfer = append(transfer, split[:int(math.Sqrt(float64(len(split))))]...)
 		}
 		diff := h.chain.GetDiffLayerRLP(block.Hash())
 		for _, peer := range transfer {
@@ -530,20 +632,46 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		directCount int // Count of the txs sent directly to peers
 		directPeers int // Count of the peers that were sent transactions directly
 
-		txset = make(map[*ethPeer][]common.Hash) // Set peer->hash to transfer directly
-		annos = make(map[*ethPeer][]common.Hash) // Set peer->hash to announce
+		txset   = make(map[*ethPeer][]common.Hash)      // Set peer->hash to transfer directly
+		annos   = make(map[*ethPeer][]common.Hash)      // Set peer->hash to announce
+		private = make(map[*ethPeer]types.Transactions) // Set peer->txs to relay privately
 
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
-		// Send the tx unconditionally to a subset of our peers
-		numDirect := int(math.Sqrt(float64(len(peers))))
-		for _, peer := range peers[:numDirect] {
+		// Relay private transactions to the trusted peers only
+		if h.privateTxs.contains(tx.Hash()) {
+			for _, peer := range peers {
+				if h.isPrivateTxPeer(peer.ID()) && peer.Version() >= eth.ETH69 {
+					private[peer] = append(private[peer], tx)
+				}
+			}
+			continue
+		}
+		// Send the tx unconditionally to the proxy peers and a subset of the others,
+		// unless it's a blob transaction which is only ever announced
+		var public []*ethPeer
+		for _, peer := range peers {
+			if h.isProxyPeer(peer.ID()) && tx.Type() != types.BlobTxType {
+				txset[peer] = append(txset[peer], tx.Hash())
+			} else {
+				public = append(public, peer)
+			}
+		}
+		// Prefer sending the transaction to the peers relaying it further
+		sort.SliceStable(public, func(i, j int) bool {
+			return public[i].Capabilities().Has(eth.CapTxRelay) && !public[j].Capabilities().Has(eth.CapTxRelay)
+		})
+		numDirect := int(math.Sqrt(float64(len(public))))
+		if tx.Type() == types.BlobTxType {
+			numDirect = 0
+		}
+		for _, peer := range public[:numDirect] {
 			txset[peer] = append(txset[peer], tx.Hash())
 		}
 		// For the remaining peers, send announcement only
-		for _, peer := range peers[numDirect:] {
+		for _, peer := range public[numDirect:] {
 			annos[peer] = append(annos[peer], tx.Hash())
 		}
 	}
@@ -557,9 +685,32 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annoCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
+	for peer, txs := range private {
+		go h.relayPrivateTransactions(peer.Peer, txs)
+	}
 	log.Debug("Transaction broadcast", "txs", len(txs),
 		"announce packs", annoPeers, "announced hashes", annoCount,
-		"tx packs", directPeers, "broadcast txs", directCount)
+		"tx packs", directPeers, "broadcast txs", directCount, "private peers", len(private))
+}
+
+// isProxyPeer returns whether the peer is a sentry of this hidden validator, or a
+// hidden validator this node is a sentry for.
+func (h *handler) isProxyPeer(id string) bool {
+	_, ok := h.proxyPeers[id]
+	return ok
+}
+
+// isPrivateTxPeer returns whether private transactions are relayed to the peer.
+func (h *handler) isPrivateTxPeer(id string) bool {
+	_, ok := h.privateTxPeers[id]
+	return ok
+}
+
+// relayPrivateTransactions sends a batch of private transactions to a trusted peer.
+func (h *handler) relayPrivateTransactions(peer *eth.Peer, txs types.Transactions) {
+	if err := peer.SendPrivateTransactions(txs); err != nil {
+		peer.Log().Debug("Failed to relay private transactions", "txs", len(txs), "err", err)
+	}
 }
 
 // ReannounceTransactions will announce a batch of local pending transactions
@@ -567,8 +718,14 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 func (h *handler) ReannounceTransactions(txs types.Transactions) {
 	hashes := make([]common.Hash, 0, txs.Len())
 	for _, tx := range txs {
+		if h.privateTxs.contains(tx.Hash()) {
+			continue
+		}
 		hashes = append(hashes, tx.Hash())
 	}
+	if len(hashes) == 0 {
+		return
+	}
 
 	// Announce transactions hash to a batch of peers
 	peersCount := uint(math.Sqrt(float64(h.peers.len())))
@@ -588,6 +745,9 @@ func (h *handler) minedBroadcastLoop() {
 		if ev, ok := obj.Data.(core.NewMinedBlockEvent); ok {
 			h.BroadcastBlock(ev.Block, true)  // First propagate block to peers
 			h.BroadcastBlock(ev.Block, false) // Only then announce to the rest
+			if h.tracer != nil {
+				h.tracer.mined(ev.Block)
+			}
 		}
 	}
 }
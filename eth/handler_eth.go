@@ -25,6 +25,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/importtrace"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/fetcher"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
@@ -49,7 +51,11 @@ func (h *ethHandler) RunPeer(peer *eth.Peer, hand eth.Handler) error {
 // PeerInfo retrieves all known `eth` information about a peer.
 func (h *ethHandler) PeerInfo(id enode.ID) interface{} {
 	if p := h.peers.peer(id.String()); p != nil {
-		return p.info()
+		info := p.info()
+		if group := h.groups.group(p.ID()); group != nil {
+			info.Group = group.name
+		}
+		return info
 	}
 	return nil
 }
@@ -57,7 +63,28 @@ func (h *ethHandler) PeerInfo(id enode.ID) interface{} {
 // AcceptTxs retrieves whether transaction processing is enabled on the node
 // or if inbound transactions should simply be dropped.
 func (h *ethHandler) AcceptTxs() bool {
-	return atomic.LoadUint32(&h.acceptTxs) == 1
+	return atomic.LoadUint32(&h.acceptTxs) == 1 && atomic.LoadUint32(&h.txsPaused) == 0
+}
+
+// Witness builds the execution witness of a block to serve it to a peer, if the
+// node serves witnesses and still has the state of the block's parent.
+func (h *ethHandler) Witness(hash common.Hash) *stateless.Witness {
+	if !h.serveWitness {
+		return nil
+	}
+	block := h.chain.GetBlockByHash(hash)
+	if block == nil {
+		return nil
+	}
+	h.witnessLock.Lock()
+	defer h.witnessLock.Unlock()
+
+	witness, err := stateless.Build(h.chain, block)
+	if err != nil {
+		log.Debug("Failed to build execution witness", "number", block.Number(), "hash", hash, "err", err)
+		return nil
+	}
+	return witness
 }
 
 // Handle is invoked from a peer's message handler when it receives a new remote
@@ -94,11 +121,32 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 	case *eth.NewPooledTransactionHashesPacket:
 		return h.txFetcher.Notify(peer.ID(), *packet)
 
+	case *eth.NewPooledTransactionHashesPacket68:
+		return h.txFetcher.Notify(peer.ID(), packet.Hashes)
+
 	case *eth.TransactionsPacket:
 		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
 
 	case *eth.PooledTransactionsPacket:
 		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
+
+	case *eth.PrivateTransactionsPacket:
+		// Only trusted peers may hide transactions from the public network, anyone
+		// else could use it to censor the gossip of arbitrary transactions
+		if !(*handler)(h).isPrivateTxPeer(peer.ID()) && !peer.Peer.Info().Network.Trusted {
+			return errors.New("private transactions from untrusted peer")
+		}
+		// Keep the relayed transactions away from the public network
+		for _, tx := range *packet {
+			h.privateTxs.add(tx.Hash())
+		}
+		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
+
+	case *eth.WitnessPacket:
+		// The node never requests witnesses, they are for external verifiers
+		peer.Log().Trace("Dropping unrequested execution witness")
+		return nil
+
 	default:
 		return fmt.Errorf("unexpected eth packet type: %T", packet)
 	}
@@ -201,6 +249,10 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 	}
 
 	for i := 0; i < len(unknownHashes); i++ {
+		importtrace.Arrived(unknownHashes[i], unknownNumbers[i], importtrace.StageAnnounced, "peer", peer.ID())
+		if h.tracer != nil {
+			h.tracer.received(unknownHashes[i], unknownNumbers[i])
+		}
 		h.blockFetcher.Notify(peer.ID(), unknownHashes[i], unknownNumbers[i], time.Now(), peer.RequestOneHeader, peer.RequestBodies, diffFetcher)
 	}
 	return nil
@@ -210,6 +262,10 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 // block broadcast for the local node to process.
 func (h *ethHandler) handleBlockBroadcast(peer *eth.Peer, block *types.Block, td *big.Int) error {
 	// Schedule the block for import
+	importtrace.Arrived(block.Hash(), block.NumberU64(), importtrace.StageReceived, "peer", peer.ID())
+	if h.tracer != nil {
+		h.tracer.received(block.Hash(), block.NumberU64())
+	}
 	h.blockFetcher.Enqueue(peer.ID(), block)
 
 	// Assuming the block is importable by the peer, but possibly not yet done so,
@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -48,12 +49,13 @@ type testEthHandler struct {
 	txBroadcasts    event.Feed
 }
 
-func (h *testEthHandler) Chain() *core.BlockChain              { panic("no backing chain") }
-func (h *testEthHandler) StateBloom() *trie.SyncBloom          { panic("no backing state bloom") }
-func (h *testEthHandler) TxPool() eth.TxPool                   { panic("no backing tx pool") }
-func (h *testEthHandler) AcceptTxs() bool                      { return true }
-func (h *testEthHandler) RunPeer(*eth.Peer, eth.Handler) error { panic("not used in tests") }
-func (h *testEthHandler) PeerInfo(enode.ID) interface{}        { panic("not used in tests") }
+func (h *testEthHandler) Chain() *core.BlockChain                { panic("no backing chain") }
+func (h *testEthHandler) StateBloom() *trie.SyncBloom            { panic("no backing state bloom") }
+func (h *testEthHandler) TxPool() eth.TxPool                     { panic("no backing tx pool") }
+func (h *testEthHandler) AcceptTxs() bool                        { return true }
+func (h *testEthHandler) RunPeer(*eth.Peer, eth.Handler) error   { panic("not used in tests") }
+func (h *testEthHandler) PeerInfo(enode.ID) interface{}          { panic("not used in tests") }
+func (h *testEthHandler) Witness(common.Hash) *stateless.Witness { return nil }
 
 func (h *testEthHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 	switch packet := packet.(type) {
@@ -341,7 +343,7 @@ func testRecvTransactions(t *testing.T, protocol uint) {
 		head    = handler.chain.CurrentBlock()
 		td      = handler.chain.GetTd(head.Hash(), head.NumberU64())
 	)
-	if err := src.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil); err != nil {
+	if err := src.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil, 0); err != nil {
 		t.Fatalf("failed to run protocol handshake")
 	}
 	// Send the transaction to the sink and verify that it's added to the tx pool
@@ -403,7 +405,7 @@ func testSendTransactions(t *testing.T, protocol uint) {
 		head    = handler.chain.CurrentBlock()
 		td      = handler.chain.GetTd(head.Hash(), head.NumberU64())
 	)
-	if err := sink.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil); err != nil {
+	if err := sink.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil, 0); err != nil {
 		t.Fatalf("failed to run protocol handshake")
 	}
 	// After the handshake completes, the source handler should stream the sink
@@ -520,7 +522,244 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 	}
 }
 
+// Tests that private transactions are only relayed to the trusted peers, which
+// keep them away from the public network too.
+func TestPrivateTransactionPropagation68(t *testing.T) {
+	testPrivateTransactionPropagation(t, eth.ETH68)
+}
+func TestPrivateTransactionPropagation69(t *testing.T) {
+	testPrivateTransactionPropagation(t, eth.ETH69)
+}
+
+func testPrivateTransactionPropagation(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	// Create a source handler relaying private transactions to the first sink only
+	source := newTestHandler()
+	defer source.close()
+
+	source.handler.privateTxPeers[enode.ID{0}.String()] = struct{}{}
+
+	sinks := make([]*testHandler, 3)
+	for i := 0; i < len(sinks); i++ {
+		sinks[i] = newTestHandler()
+		defer sinks[i].close()
+
+		sinks[i].handler.acceptTxs = 1 // mark synced to accept transactions
+		sinks[i].handler.privateTxPeers[enode.ID{0xff}.String()] = struct{}{}
+	}
+	for i, sink := range sinks {
+		sink := sink // Closure for gorotuine below
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(protocol, p2p.NewPeer(enode.ID{byte(i)}, "", nil), sourcePipe, source.txpool)
+		sinkPeer := eth.NewPeer(protocol, p2p.NewPeer(enode.ID{0xff}, "", nil), sinkPipe, sink.txpool)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		go sink.handler.runEthPeer(sinkPeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(sink.handler), peer)
+		})
+	}
+	txChs := make([]chan core.NewTxsEvent, len(sinks))
+	for i := 0; i < len(sinks); i++ {
+		txChs[i] = make(chan core.NewTxsEvent, 1024)
+
+		sub := sinks[i].txpool.SubscribeNewTxsEvent(txChs[i])
+		defer sub.Unsubscribe()
+	}
+	// Wait for the peers to register before submitting the private transactions
+	time.Sleep(250 * time.Millisecond)
+
+	txs := make([]*types.Transaction, 16)
+	for nonce := range txs {
+		tx := types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+		tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+
+		txs[nonce] = tx
+		source.handler.privateTxs.add(tx.Hash())
+	}
+	source.txpool.AddRemotes(txs)
+
+	// Private transactions are never relayed to peers predating eth/69
+	if protocol < eth.ETH69 {
+		for i := 0; i < len(sinks); i++ {
+			select {
+			case event := <-txChs[i]:
+				t.Errorf("sink %d: private transactions relayed over eth/%d: %d", i, protocol, len(event.Txs))
+			case <-time.NewTimer(250 * time.Millisecond).C:
+			}
+		}
+		return
+	}
+	// Ensure the trusted sink got all the transactions and marked them private
+	for arrived := 0; arrived < len(txs); {
+		select {
+		case event := <-txChs[0]:
+			arrived += len(event.Txs)
+		case <-time.NewTimer(time.Second).C:
+			t.Fatalf("private transaction relay timed out: have %d, want %d", arrived, len(txs))
+		}
+	}
+	for _, tx := range txs {
+		if !sinks[0].handler.privateTxs.contains(tx.Hash()) {
+			t.Errorf("relayed transaction %x not marked private", tx.Hash())
+		}
+	}
+	// Ensure none of the other sinks heard about them
+	for i := 1; i < len(sinks); i++ {
+		select {
+		case event := <-txChs[i]:
+			t.Errorf("sink %d: private transactions leaked: %d", i, len(event.Txs))
+		case <-time.NewTimer(250 * time.Millisecond).C:
+		}
+	}
+}
+
+// Tests that private transactions are only accepted from trusted peers, since
+// marking a transaction private stops it from being gossiped publicly.
+func TestPrivateTransactionsFromUntrustedPeer(t *testing.T) {
+	t.Parallel()
+
+	handler := newTestHandler()
+	defer handler.close()
+
+	handler.handler.acceptTxs = 1 // mark synced to accept transactions
+	handler.handler.privateTxPeers[enode.ID{1}.String()] = struct{}{}
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+
+	untrusted := eth.NewPeer(eth.ETH69, p2p.NewPeer(enode.ID{2}, "", nil), nil, handler.txpool)
+	defer untrusted.Close()
+	if err := (*ethHandler)(handler.handler).Handle(untrusted, &eth.PrivateTransactionsPacket{tx}); err == nil {
+		t.Fatalf("private transactions accepted from untrusted peer")
+	}
+	if handler.handler.privateTxs.contains(tx.Hash()) {
+		t.Fatalf("untrusted peer marked transaction private")
+	}
+	trusted := eth.NewPeer(eth.ETH69, p2p.NewPeer(enode.ID{1}, "", nil), nil, handler.txpool)
+	defer trusted.Close()
+	if err := (*ethHandler)(handler.handler).Handle(trusted, &eth.PrivateTransactionsPacket{tx}); err != nil {
+		t.Fatalf("private transactions rejected from trusted peer: %v", err)
+	}
+	if !handler.handler.privateTxs.contains(tx.Hash()) {
+		t.Fatalf("trusted peer's transaction not marked private")
+	}
+}
+
 // Tests that local pending transactions get propagated to peers.
+// Tests that a hidden validator only accepts its sentries as peers.
+func TestHiddenValidatorPeers(t *testing.T) {
+	t.Parallel()
+
+	validator := newTestHandler()
+	defer validator.close()
+
+	validator.handler.hidden = true
+	validator.handler.proxyPeers[enode.ID{1}.String()] = struct{}{}
+
+	for i := 0; i < 2; i++ {
+		node := newTestHandler()
+		defer node.close()
+
+		validatorPipe, nodePipe := p2p.MsgPipe()
+		defer validatorPipe.Close()
+		defer nodePipe.Close()
+
+		validatorPeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{byte(i)}, "", nil), validatorPipe, validator.txpool)
+		nodePeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{0xff}, "", nil), nodePipe, node.txpool)
+		defer validatorPeer.Close()
+		defer nodePeer.Close()
+
+		go func() {
+			validator.handler.runEthPeer(validatorPeer, func(peer *eth.Peer) error {
+				return eth.Handle((*ethHandler)(validator.handler), peer)
+			})
+			validatorPipe.Close()
+		}()
+		go node.handler.runEthPeer(nodePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(node.handler), peer)
+		})
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	if n := validator.handler.peers.len(); n != 1 {
+		t.Fatalf("peer count mismatch: have %d, want %d", n, 1)
+	}
+	if validator.handler.peers.peer(enode.ID{1}.String()) == nil {
+		t.Errorf("sentry not connected")
+	}
+}
+
+// Tests that transactions are always sent in full to the proxy peers, while the
+// others are split as usual.
+func TestSentryTransactionRelay(t *testing.T) {
+	t.Parallel()
+
+	source := newTestHandler()
+	defer source.close()
+
+	source.handler.proxyPeers[enode.ID{0}.String()] = struct{}{}
+	source.handler.proxyPeers[enode.ID{1}.String()] = struct{}{}
+
+	var (
+		genesis = source.chain.Genesis()
+		td      = source.chain.GetTd(genesis.Hash(), genesis.NumberU64())
+		sinks   = make([]*testEthHandler, 4)
+		txChs   = make([]chan []*types.Transaction, len(sinks))
+	)
+	for i := range sinks {
+		sinks[i] = new(testEthHandler)
+		txChs[i] = make(chan []*types.Transaction, 1)
+		sub := sinks[i].txBroadcasts.Subscribe(txChs[i])
+		defer sub.Unsubscribe()
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{byte(i)}, "", nil), sourcePipe, source.txpool)
+		sinkPeer := eth.NewPeer(eth.ETH66, p2p.NewPeer(enode.ID{0}, "", nil), sinkPipe, nil)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		if err := sinkPeer.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil, 0); err != nil {
+			t.Fatalf("failed to run protocol handshake")
+		}
+		go eth.Handle(sinks[i], sinkPeer)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+	source.txpool.AddRemotes([]*types.Transaction{tx})
+
+	received := make([]bool, len(sinks))
+	for i, ch := range txChs {
+		select {
+		case <-ch:
+			received[i] = true
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+	if !received[0] || !received[1] {
+		t.Errorf("proxy peers missed the transaction: %v", received)
+	}
+	if received[2] == received[3] {
+		t.Errorf("default split mismatch: %v", received)
+	}
+}
+
 func TestTransactionPendingReannounce(t *testing.T) {
 	t.Parallel()
 
@@ -655,7 +894,7 @@ func testCheckpointChallenge(t *testing.T, syncmode downloader.SyncMode, checkpo
 		head    = handler.chain.CurrentBlock()
 		td      = handler.chain.GetTd(head.Hash(), head.NumberU64())
 	)
-	if err := remote.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil); err != nil {
+	if err := remote.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(handler.chain), forkid.NewFilter(handler.chain), nil, 0); err != nil {
 		t.Fatalf("failed to run protocol handshake")
 	}
 	// Connect a new peer and check that we receive the checkpoint challenge
@@ -739,7 +978,7 @@ func testBroadcastBlock(t *testing.T, peers, bcasts int) {
 		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
 			return eth.Handle((*ethHandler)(source.handler), peer)
 		})
-		if err := sinkPeer.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil); err != nil {
+		if err := sinkPeer.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil, 0); err != nil {
 			t.Fatalf("failed to run protocol handshake")
 		}
 		go eth.Handle(sink, sinkPeer)
@@ -812,7 +1051,7 @@ func testBroadcastMalformedBlock(t *testing.T, protocol uint) {
 		genesis = source.chain.Genesis()
 		td      = source.chain.GetTd(genesis.Hash(), genesis.NumberU64())
 	)
-	if err := sink.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil); err != nil {
+	if err := sink.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil, 0); err != nil {
 		t.Fatalf("failed to run protocol handshake")
 	}
 	// After the handshake completes, the source handler should stream the sink
@@ -17,6 +17,8 @@
 package eth
 
 import (
+	"sync/atomic"
+
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -28,6 +30,9 @@ type snapHandler handler
 
 func (h *snapHandler) Chain() *core.BlockChain { return h.chain }
 
+// Serving retrieves whether the state requests of the peers are served.
+func (h *snapHandler) Serving() bool { return atomic.LoadUint32(&h.snapPaused) == 0 }
+
 // RunPeer is invoked when a peer joins on the `snap` protocol.
 func (h *snapHandler) RunPeer(peer *snap.Peer, hand snap.Handler) error {
 	return (*handler)(h).runSnapExtension(peer, hand)
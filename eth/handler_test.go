@@ -91,6 +91,12 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddRemotesFrom appends a batch of transactions to the pool, ignoring their
+// origin.
+func (p *testTxPool) AddRemotesFrom(origin string, txs []*types.Transaction) []error {
+	return p.AddRemotes(txs)
+}
+
 // ReannouceTransactions announce the transactions to some peers.
 func (p *testTxPool) ReannouceTransactions(txs []*types.Transaction) []error {
 	p.lock.Lock()
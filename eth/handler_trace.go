@@ -0,0 +1,57 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/protocols/trace"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// traceHandler implements the trace.Backend interface to handle the block
+// traces sent by the peers.
+type traceHandler handler
+
+func (h *traceHandler) Chain() *core.BlockChain { return h.chain }
+
+// RunPeer is invoked when a peer joins on the `trace` protocol.
+func (h *traceHandler) RunPeer(peer *trace.Peer, hand trace.Handler) error {
+	h.tracer.registerPeer(peer)
+	defer h.tracer.unregisterPeer(peer)
+
+	return hand(peer)
+}
+
+// PeerInfo retrieves all known `trace` information about a peer.
+func (h *traceHandler) PeerInfo(id enode.ID) interface{} {
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *traceHandler) Handle(peer *trace.Peer, packet trace.Packet) error {
+	switch packet := packet.(type) {
+	case *trace.BlockTracePacket:
+		h.tracer.handleTrace(packet)
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected trace packet type: %T", packet)
+	}
+}
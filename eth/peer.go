@@ -29,9 +29,11 @@ import (
 // ethPeerInfo represents a short summary of the `eth` sub-protocol metadata known
 // about a connected peer.
 type ethPeerInfo struct {
-	Version    uint     `json:"version"`    // Ethereum protocol version negotiated
-	Difficulty *big.Int `json:"difficulty"` // Total difficulty of the peer's blockchain
-	Head       string   `json:"head"`       // Hex hash of the peer's best owned block
+	Version      uint     `json:"version"`                // Ethereum protocol version negotiated
+	Difficulty   *big.Int `json:"difficulty"`             // Total difficulty of the peer's blockchain
+	Head         string   `json:"head"`                   // Hex hash of the peer's best owned block
+	Group        string   `json:"group,omitempty"`        // Name of the peer group the peer belongs to
+	Capabilities string   `json:"capabilities,omitempty"` // Services advertised by the peer
 }
 
 // ethPeer is a wrapper around eth.Peer to maintain a few extra metadata.
@@ -50,9 +52,10 @@ func (p *ethPeer) info() *ethPeerInfo {
 	hash, td := p.Head()
 
 	return &ethPeerInfo{
-		Version:    p.Version(),
-		Difficulty: td,
-		Head:       hash.Hex(),
+		Version:      p.Version(),
+		Difficulty:   td,
+		Head:         hash.Hex(),
+		Capabilities: p.Capabilities().String(),
 	}
 }
 
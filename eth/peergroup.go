@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// peerGroup is a named group of peers and the policy applied to them.
+type peerGroup struct {
+	name      string
+	blocks    string        // Block broadcast policy, the default split if empty
+	protected bool          // Whether the members are exempt from the peer limits
+	nodes     []*enode.Node // Members of the group, none for the fallback group
+}
+
+// peerGroups classifies the peers into the configured groups.
+type peerGroups struct {
+	groups   []*peerGroup
+	members  map[string]*peerGroup // Groups of the listed peers by ID
+	fallback *peerGroup            // Group of the peers not listed, if configured
+}
+
+// newPeerGroups validates the configured peer groups.
+func newPeerGroups(configs []*ethconfig.PeerGroup) (*peerGroups, error) {
+	pg := &peerGroups{members: make(map[string]*peerGroup)}
+	names := make(map[string]bool)
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("unnamed peer group")
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("duplicate peer group %q", config.Name)
+		}
+		names[config.Name] = true
+
+		switch config.Blocks {
+		case "", ethconfig.BlocksFull, ethconfig.BlocksAnnounce:
+		default:
+			return nil, fmt.Errorf("peer group %q: invalid block broadcast policy %q", config.Name, config.Blocks)
+		}
+		group := &peerGroup{name: config.Name, blocks: config.Blocks, protected: config.Protected}
+		if len(config.Nodes) == 0 {
+			if pg.fallback != nil {
+				return nil, fmt.Errorf("peer groups %q and %q both without nodes", pg.fallback.name, config.Name)
+			}
+			pg.fallback = group
+		}
+		for _, url := range config.Nodes {
+			node, err := enode.Parse(enode.ValidSchemes, url)
+			if err != nil {
+				return nil, fmt.Errorf("peer group %q: invalid node %q: %v", config.Name, url, err)
+			}
+			if other := pg.members[node.ID().String()]; other != nil {
+				return nil, fmt.Errorf("node %v in peer groups %q and %q", node.ID(), other.name, config.Name)
+			}
+			pg.members[node.ID().String()] = group
+			group.nodes = append(group.nodes, node)
+		}
+		pg.groups = append(pg.groups, group)
+	}
+	return pg, nil
+}
+
+// group returns the group of a peer, nil if it belongs to none.
+func (pg *peerGroups) group(id string) *peerGroup {
+	if group := pg.members[id]; group != nil {
+		return group
+	}
+	return pg.fallback
+}
+
+// blocks returns the block broadcast policy of a peer.
+func (pg *peerGroups) blocks(id string) string {
+	if group := pg.group(id); group != nil {
+		return group.blocks
+	}
+	return ""
+}
+
+// protectedNodes returns the members of the protected groups.
+func (pg *peerGroups) protectedNodes() []*enode.Node {
+	var nodes []*enode.Node
+	for _, group := range pg.groups {
+		if group.protected {
+			nodes = append(nodes, group.nodes...)
+		}
+	}
+	return nodes
+}
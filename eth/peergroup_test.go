@@ -0,0 +1,158 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func testNodeURL() (string, enode.ID) {
+	key, _ := crypto.GenerateKey()
+	node := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+	return node.URLv4(), node.ID()
+}
+
+func TestPeerGroups(t *testing.T) {
+	validator, validatorID := testNodeURL()
+	sentry, sentryID := testNodeURL()
+	_, otherID := testNodeURL()
+
+	groups, err := newPeerGroups([]*ethconfig.PeerGroup{
+		{Name: "validators", Nodes: []string{validator}, Blocks: ethconfig.BlocksFull, Protected: true},
+		{Name: "sentries", Nodes: []string{sentry}, Protected: true},
+		{Name: "public", Blocks: ethconfig.BlocksAnnounce},
+	})
+	if err != nil {
+		t.Fatalf("failed to create peer groups: %v", err)
+	}
+	for id, want := range map[enode.ID]string{validatorID: "validators", sentryID: "sentries", otherID: "public"} {
+		if group := groups.group(id.String()); group == nil || group.name != want {
+			t.Errorf("peer %v: group mismatch: have %v, want %s", id, group, want)
+		}
+	}
+	if policy := groups.blocks(otherID.String()); policy != ethconfig.BlocksAnnounce {
+		t.Errorf("fallback policy mismatch: have %q, want %q", policy, ethconfig.BlocksAnnounce)
+	}
+	if nodes := groups.protectedNodes(); len(nodes) != 2 || nodes[0].ID() != validatorID || nodes[1].ID() != sentryID {
+		t.Errorf("protected nodes mismatch: %v", nodes)
+	}
+
+	// Without a group holding the rest, the other peers belong to none
+	groups, _ = newPeerGroups([]*ethconfig.PeerGroup{{Name: "validators", Nodes: []string{validator}}})
+	if group := groups.group(otherID.String()); group != nil {
+		t.Errorf("unlisted peer in group %s", group.name)
+	}
+}
+
+func TestPeerGroupsInvalid(t *testing.T) {
+	node, _ := testNodeURL()
+	tests := [][]*ethconfig.PeerGroup{
+		{{Nodes: []string{node}}},
+		{{Name: "a"}, {Name: "a", Nodes: []string{node}}},
+		{{Name: "a", Blocks: "some"}},
+		{{Name: "a"}, {Name: "b"}},
+		{{Name: "a", Nodes: []string{"enode://invalid"}}},
+		{{Name: "a", Nodes: []string{node}}, {Name: "b", Nodes: []string{node}}},
+	}
+	for i, config := range tests {
+		if _, err := newPeerGroups(config); err == nil {
+			t.Errorf("test %d: invalid peer groups accepted", i)
+		}
+	}
+}
+
+// Tests that block propagation follows the policies of the peer groups: the
+// members of a full group always get the block, the members of an announce group
+// never do, and the rest is split as usual.
+func TestBroadcastBlockPeerGroups(t *testing.T) {
+	t.Parallel()
+
+	source := newTestHandlerWithBlocks(1)
+	defer source.close()
+
+	full, announce := &peerGroup{name: "validators", blocks: ethconfig.BlocksFull}, &peerGroup{name: "public", blocks: ethconfig.BlocksAnnounce}
+	source.handler.groups.members[enode.ID{0}.String()] = full
+	source.handler.groups.members[enode.ID{1}.String()] = full
+	source.handler.groups.members[enode.ID{2}.String()] = announce
+
+	var (
+		genesis  = source.chain.Genesis()
+		td       = source.chain.GetTd(genesis.Hash(), genesis.NumberU64())
+		sinks    = make([]*testEthHandler, 7)
+		blockChs = make([]chan *types.Block, len(sinks))
+	)
+	for i := range sinks {
+		sinks[i] = new(testEthHandler)
+		blockChs[i] = make(chan *types.Block, 1)
+		sub := sinks[i].blockBroadcasts.Subscribe(blockChs[i])
+		defer sub.Unsubscribe()
+
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(eth.ETH65, p2p.NewPeer(enode.ID{byte(i)}, "", nil), sourcePipe, nil)
+		sinkPeer := eth.NewPeer(eth.ETH65, p2p.NewPeer(enode.ID{0}, "", nil), sinkPipe, nil)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		if err := sinkPeer.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil, 0); err != nil {
+			t.Fatalf("failed to run protocol handshake")
+		}
+		go eth.Handle(sinks[i], sinkPeer)
+	}
+	time.Sleep(100 * time.Millisecond)
+	source.handler.BroadcastBlock(source.chain.CurrentBlock(), true)
+
+	// The full group gets the block, and 2 of the 4 peers without a group
+	received := make([]bool, len(sinks))
+	for i, ch := range blockChs {
+		select {
+		case <-ch:
+			received[i] = true
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if !received[0] || !received[1] {
+		t.Errorf("full group missed the block: %v", received)
+	}
+	if received[2] {
+		t.Errorf("announce group got the block")
+	}
+	var split int
+	for _, ok := range received[3:] {
+		if ok {
+			split++
+		}
+	}
+	if split != 2 {
+		t.Errorf("default split mismatch: have %d, want %d", split, 2)
+	}
+}
@@ -359,8 +359,9 @@ func (ps *peerSet) snapLen() int {
 }
 
 // peerWithHighestTD retrieves the known peer with the currently highest total
-// difficulty.
-func (ps *peerSet) peerWithHighestTD() *eth.Peer {
+// difficulty, preferring the ones advertising the given capabilities among the
+// equally good peers.
+func (ps *peerSet) peerWithHighestTD(prefer eth.Capabilities) *eth.Peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
@@ -369,8 +370,13 @@ func (ps *peerSet) peerWithHighestTD() *eth.Peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
-		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
+		_, td := p.Head()
+		if bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p.Peer, td
+			continue
+		}
+		if td.Cmp(bestTd) == 0 && !bestPeer.Capabilities().Has(prefer) && p.Capabilities().Has(prefer) {
+			bestPeer = p.Peer
 		}
 	}
 	return bestPeer
@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// privateTxPruneInterval is the time after which private transactions no longer
+// tracked by the transaction pool are forgotten.
+const privateTxPruneInterval = time.Minute
+
+// privateTxSet tracks the transactions which must only be relayed to the trusted
+// private transaction peers and never be gossiped to the network.
+type privateTxSet struct {
+	txs       map[common.Hash]time.Time   // Private transactions and the time they were marked
+	pooled    func(hash common.Hash) bool // Whether a transaction is still tracked by the pool
+	lastPrune time.Time
+	lock      sync.RWMutex
+}
+
+func newPrivateTxSet(pooled func(hash common.Hash) bool) *privateTxSet {
+	return &privateTxSet{
+		txs:       make(map[common.Hash]time.Time),
+		pooled:    pooled,
+		lastPrune: time.Now(),
+	}
+}
+
+// add marks the given transaction as private, returning whether it was not yet
+// known as such.
+func (s *privateTxSet) add(hash common.Hash) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) > privateTxPruneInterval {
+		for hash, marked := range s.txs {
+			if now.Sub(marked) > privateTxPruneInterval && !s.pooled(hash) {
+				delete(s.txs, hash)
+			}
+		}
+		s.lastPrune = now
+	}
+	if _, ok := s.txs[hash]; ok {
+		return false
+	}
+	s.txs[hash] = now
+	return true
+}
+
+// remove unmarks a private transaction, e.g. because the pool rejected it.
+func (s *privateTxSet) remove(hash common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.txs, hash)
+}
+
+// contains returns whether the given transaction is private.
+func (s *privateTxSet) contains(hash common.Hash) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	_, ok := s.txs[hash]
+	return ok
+}
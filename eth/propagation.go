@@ -0,0 +1,312 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/trace"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// propagationTraceLimit is the number of recent blocks to keep the propagation
+// traces of.
+const propagationTraceLimit = 1024
+
+var (
+	// errPropagationTracingDisabled is returned by the propagation APIs if the
+	// node doesn't trace the block propagation.
+	errPropagationTracingDisabled = errors.New("block propagation tracing disabled")
+
+	// errUnknownPropagation is returned if no trace of a block is known.
+	errUnknownPropagation = errors.New("unknown block propagation")
+
+	// diffNoTurn is the block difficulty of the out-of-turn clique and parlia
+	// signatures.
+	diffNoTurn = big.NewInt(1)
+
+	propagationArrivalHist = metrics.NewRegisteredHistogram("eth/propagation/arrival", nil, metrics.NewExpDecaySample(1028, 0.015))
+	propagationImportTimer = metrics.NewRegisteredTimer("eth/propagation/import", nil)
+	propagationOutOfTurn   = metrics.NewRegisteredMeter("eth/propagation/outofturn", nil)
+	propagationSideMeter   = metrics.NewRegisteredMeter("eth/propagation/side", nil)
+)
+
+// blockPropagation is the locally known propagation of a block.
+type blockPropagation struct {
+	number    uint64
+	miner     common.Address
+	outOfTurn bool
+	upstream  []trace.Hop // Path of the block up to the peer which traced it first
+	received  uint64      // Unix milliseconds the block was first seen, zero if not yet
+	imported  uint64      // Unix milliseconds the block was imported, zero if not yet
+}
+
+// propagationTracer traces the path and timing of the blocks through the peers
+// which opted into the `trace` protocol, stamping the local hop on every block
+// imported and forwarding the extended trace.
+type propagationTracer struct {
+	config *params.ChainConfig
+	self   enode.ID
+	traces *lru.Cache             // Propagations of the recent blocks by hash
+	peers  map[string]*trace.Peer // Peers running the `trace` protocol
+	lock   sync.Mutex
+}
+
+func newPropagationTracer(config *params.ChainConfig) *propagationTracer {
+	traces, _ := lru.New(propagationTraceLimit)
+	return &propagationTracer{
+		config: config,
+		traces: traces,
+		peers:  make(map[string]*trace.Peer),
+	}
+}
+
+// setSelf sets the identifier of the local node stamped on its hops.
+func (t *propagationTracer) setSelf(id enode.ID) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.self = id
+}
+
+func (t *propagationTracer) registerPeer(peer *trace.Peer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.peers[peer.ID()] = peer
+}
+
+func (t *propagationTracer) unregisterPeer(peer *trace.Peer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.peers, peer.ID())
+}
+
+// propagation retrieves the propagation of a block, creating it if unknown. The
+// lock must be held.
+func (t *propagationTracer) propagation(hash common.Hash, number uint64) *blockPropagation {
+	if prop, ok := t.traces.Get(hash); ok {
+		return prop.(*blockPropagation)
+	}
+	prop := &blockPropagation{number: number}
+	t.traces.Add(hash, prop)
+	return prop
+}
+
+// received records the first sighting of a block, announced or broadcast.
+func (t *propagationTracer) received(hash common.Hash, number uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if prop := t.propagation(hash, number); prop.received == 0 {
+		prop.received = uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	}
+}
+
+// handleTrace records the path of a block traced by a peer. Only the first trace
+// is kept, being the one of the fastest path.
+func (t *propagationTracer) handleTrace(packet *trace.BlockTracePacket) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if prop := t.propagation(packet.Hash, packet.Number); prop.upstream == nil {
+		prop.upstream = packet.Hops
+	}
+}
+
+// mined records the sealing of a local block and sends its trace to the peers.
+func (t *propagationTracer) mined(block *types.Block) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	prop := t.propagation(block.Hash(), block.NumberU64())
+	prop.received, prop.imported = now, now
+	t.stamp(block.Header(), prop)
+}
+
+// imported records the import of a block and sends its trace, extended with the
+// local hop, to the peers. Traces arriving after the import are recorded but
+// not forwarded.
+func (t *propagationTracer) imported(block *types.Block, canonical bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	prop := t.propagation(block.Hash(), block.NumberU64())
+	if prop.imported != 0 {
+		return
+	}
+	if prop.received == 0 {
+		prop.received = now
+	}
+	prop.imported = now
+	t.stamp(block.Header(), prop)
+
+	propagationImportTimer.Update(time.Duration(prop.imported-prop.received) * time.Millisecond)
+	if len(prop.upstream) > 0 && prop.received >= prop.upstream[0].Received {
+		propagationArrivalHist.Update(int64(prop.received - prop.upstream[0].Received))
+	}
+	if prop.outOfTurn {
+		propagationOutOfTurn.Mark(1)
+	}
+	if !canonical {
+		propagationSideMeter.Mark(1)
+	}
+}
+
+// stamp fills the header derived fields of a propagation, and sends its trace
+// with the local hop to the peers. The lock must be held.
+func (t *propagationTracer) stamp(header *types.Header, prop *blockPropagation) {
+	prop.miner = header.Coinbase
+	if t.config.Clique != nil || t.config.Parlia != nil {
+		prop.outOfTurn = header.Difficulty.Cmp(diffNoTurn) == 0
+	}
+	packet := &trace.BlockTracePacket{
+		Hash:   header.Hash(),
+		Number: header.Number.Uint64(),
+		Hops:   prop.hops(t.self),
+	}
+	for _, peer := range t.peers {
+		peer.AsyncSendBlockTrace(packet)
+	}
+}
+
+// hops returns the path of the block up to and including the local node. The
+// upstream is cut short to fit the local hop into the maximum path length.
+func (prop *blockPropagation) hops(self enode.ID) []trace.Hop {
+	upstream := prop.upstream
+	if len(upstream) >= trace.MaxHops {
+		upstream = upstream[:trace.MaxHops-1]
+	}
+	hops := make([]trace.Hop, 0, len(upstream)+1)
+	hops = append(hops, upstream...)
+	return append(hops, trace.Hop{Node: self, Received: prop.received, Imported: prop.imported})
+}
+
+// BlockPropagation is the traced path of a block from its miner to the local
+// node, the last hop.
+type BlockPropagation struct {
+	Hash      common.Hash    `json:"hash"`
+	Number    hexutil.Uint64 `json:"number"`
+	Miner     common.Address `json:"miner"`
+	OutOfTurn bool           `json:"outOfTurn"`
+	Hops      []trace.Hop    `json:"hops"`
+	Arrival   *int64         `json:"arrival,omitempty"` // Milliseconds from the first traced hop to the local reception
+}
+
+// block returns the propagation of a block, if known and imported.
+func (t *propagationTracer) block(hash common.Hash) *BlockPropagation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	prop, ok := t.traces.Peek(hash)
+	if !ok || prop.(*blockPropagation).imported == 0 {
+		return nil
+	}
+	return t.report(hash, prop.(*blockPropagation))
+}
+
+// report assembles the public report of a propagation. The lock must be held.
+func (t *propagationTracer) report(hash common.Hash, prop *blockPropagation) *BlockPropagation {
+	report := &BlockPropagation{
+		Hash:      hash,
+		Number:    hexutil.Uint64(prop.number),
+		Miner:     prop.miner,
+		OutOfTurn: prop.outOfTurn,
+		Hops:      prop.hops(t.self),
+	}
+	if len(prop.upstream) > 0 {
+		arrival := int64(prop.received) - int64(prop.upstream[0].Received)
+		report.Arrival = &arrival
+	}
+	return report
+}
+
+// ValidatorPropagation aggregates the propagation of the recent blocks of a
+// validator to the local node, to spot the ones consistently arriving late.
+type ValidatorPropagation struct {
+	Validator  common.Address `json:"validator"`
+	Blocks     int            `json:"blocks"`     // Blocks of the validator imported locally
+	Traced     int            `json:"traced"`     // Blocks with a trace of their path
+	OutOfTurn  int            `json:"outOfTurn"`  // Blocks sealed out of turn
+	AvgArrival float64        `json:"avgArrival"` // Average milliseconds from the first traced hop to the local reception
+	AvgImport  float64        `json:"avgImport"`  // Average milliseconds from the local reception to the import
+	AvgHops    float64        `json:"avgHops"`    // Average length of the traced paths
+}
+
+// validators aggregates the propagation of the recently imported blocks per
+// validator, sorted by the average arrival delay, slowest first.
+func (t *propagationTracer) validators() []*ValidatorPropagation {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := make(map[common.Address]*ValidatorPropagation)
+	for _, key := range t.traces.Keys() {
+		val, ok := t.traces.Peek(key)
+		if !ok {
+			continue
+		}
+		prop := val.(*blockPropagation)
+		if prop.imported == 0 {
+			continue
+		}
+		stat := stats[prop.miner]
+		if stat == nil {
+			stat = &ValidatorPropagation{Validator: prop.miner}
+			stats[prop.miner] = stat
+		}
+		stat.Blocks++
+		stat.AvgImport += float64(prop.imported - prop.received)
+		if prop.outOfTurn {
+			stat.OutOfTurn++
+		}
+		if len(prop.upstream) > 0 {
+			stat.Traced++
+			stat.AvgArrival += float64(int64(prop.received) - int64(prop.upstream[0].Received))
+			stat.AvgHops += float64(len(prop.upstream) + 1)
+		}
+	}
+	list := make([]*ValidatorPropagation, 0, len(stats))
+	for _, stat := range stats {
+		stat.AvgImport /= float64(stat.Blocks)
+		if stat.Traced > 0 {
+			stat.AvgArrival /= float64(stat.Traced)
+			stat.AvgHops /= float64(stat.Traced)
+		}
+		list = append(list, stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].AvgArrival != list[j].AvgArrival {
+			return list[i].AvgArrival > list[j].AvgArrival
+		}
+		return list[i].Validator.Hex() < list[j].Validator.Hex()
+	})
+	return list
+}
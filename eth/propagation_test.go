@@ -0,0 +1,103 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/trace"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that imported blocks are stamped with the local hop, forwarded to the
+// tracing peers and aggregated per validator.
+func TestPropagationTracer(t *testing.T) {
+	tracer := newPropagationTracer(params.AllCliqueProtocolChanges)
+	tracer.setSelf(enode.ID{0x09})
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	peer := trace.NewPeer(trace.Trace1, p2p.NewPeer(enode.ID{0x01}, "peer", nil), net)
+	defer peer.Close()
+	tracer.registerPeer(peer)
+
+	var (
+		validator = common.Address{0xaa}
+		block     = types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Coinbase: validator, Difficulty: big.NewInt(1)})
+		sealed    = uint64(time.Now().Add(-50*time.Millisecond).UnixNano() / int64(time.Millisecond))
+	)
+	tracer.received(block.Hash(), block.NumberU64())
+	tracer.handleTrace(&trace.BlockTracePacket{
+		Hash:   block.Hash(),
+		Number: block.NumberU64(),
+		Hops:   []trace.Hop{{Node: enode.ID{0x02}, Received: sealed, Imported: sealed}},
+	})
+	if prop := tracer.block(block.Hash()); prop != nil {
+		t.Fatalf("propagation reported before import: %+v", prop)
+	}
+	tracer.imported(block, true)
+
+	// The trace extended with the local hop is forwarded to the peer
+	msg, err := app.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read trace: %v", err)
+	}
+	var packet trace.BlockTracePacket
+	if err := msg.Decode(&packet); err != nil {
+		t.Fatalf("failed to decode trace: %v", err)
+	}
+	if packet.Hash != block.Hash() || len(packet.Hops) != 2 || packet.Hops[1].Node != (enode.ID{0x09}) {
+		t.Fatalf("forwarded trace mismatch: %+v", packet)
+	}
+	if !peer.KnownTrace(block.Hash()) {
+		t.Errorf("forwarded trace not marked as known")
+	}
+	// The report carries the path and the arrival delay
+	prop := tracer.block(block.Hash())
+	if prop == nil {
+		t.Fatalf("missing propagation report")
+	}
+	if prop.Miner != validator || !prop.OutOfTurn || len(prop.Hops) != 2 || prop.Arrival == nil || *prop.Arrival < 50 {
+		t.Fatalf("propagation report mismatch: %+v", prop)
+	}
+	stats := tracer.validators()
+	if len(stats) != 1 {
+		t.Fatalf("validator count mismatch: have %d, want 1", len(stats))
+	}
+	if stat := stats[0]; stat.Validator != validator || stat.Blocks != 1 || stat.Traced != 1 || stat.OutOfTurn != 1 || stat.AvgHops != 2 {
+		t.Fatalf("validator stats mismatch: %+v", stat)
+	}
+}
+
+// Tests that the forwarded paths are capped at the maximum number of hops.
+func TestPropagationHopsCapped(t *testing.T) {
+	prop := &blockPropagation{upstream: make([]trace.Hop, trace.MaxHops), received: 1, imported: 2}
+	hops := prop.hops(enode.ID{0x09})
+	if len(hops) != trace.MaxHops {
+		t.Fatalf("hop count mismatch: have %d, want %d", len(hops), trace.MaxHops)
+	}
+	if last := hops[len(hops)-1]; last.Node != (enode.ID{0x09}) || last.Imported != 2 {
+		t.Fatalf("local hop mismatch: %+v", last)
+	}
+}
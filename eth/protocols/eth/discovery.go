@@ -17,6 +17,8 @@
 package eth
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -63,3 +65,23 @@ func currentENREntry(chain *core.BlockChain) *enrEntry {
 		ForkID: forkid.NewID(chain.Config(), chain.Genesis().Hash(), chain.CurrentHeader().Number.Uint64()),
 	}
 }
+
+// TopicName returns the discovery topic the nodes serving the chain advertise
+// themselves under. It's derived from the genesis only, so that the nodes still
+// syncing find the synced ones, the fork ID is checked by NewNodeFilter.
+func TopicName(chain *core.BlockChain) string {
+	return fmt.Sprintf("%s/%x", ProtocolName, chain.Genesis().Hash())
+}
+
+// NewNodeFilter returns a filtering function that returns whether the provided
+// enode advertises a fork ID compatible with the current chain.
+func NewNodeFilter(chain *core.BlockChain) func(*enode.Node) bool {
+	filter := forkid.NewFilter(chain)
+	return func(n *enode.Node) bool {
+		var entry enrEntry
+		if err := n.Load(&entry); err != nil {
+			return false
+		}
+		return filter(entry.ForkID) == nil
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -102,6 +103,15 @@ func (b *testBackend) RunPeer(peer *Peer, handler Handler) error {
 }
 func (b *testBackend) PeerInfo(enode.ID) interface{} { panic("not implemented") }
 
+func (b *testBackend) Witness(hash common.Hash) *stateless.Witness {
+	block := b.chain.GetBlockByHash(hash)
+	if block == nil {
+		return nil
+	}
+	witness, _ := stateless.Build(b.chain, block)
+	return witness
+}
+
 func (b *testBackend) AcceptTxs() bool {
 	panic("data processing tests should be done in the handler package")
 }
@@ -584,3 +594,27 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that execution witnesses can be retrieved over eth/69.
+func TestGetWitness69(t *testing.T) {
+	backend := newTestBackend(4)
+	defer backend.close()
+
+	peer, _ := newTestPeer("peer", ETH69, backend)
+	defer peer.close()
+
+	block := backend.chain.GetBlockByNumber(3)
+	witness, err := stateless.Build(backend.chain, block)
+	if err != nil {
+		t.Fatalf("failed to build witness: %v", err)
+	}
+	p2p.Send(peer.app, GetWitnessMsg, GetWitnessPacket{RequestId: 1, Hash: block.Hash()})
+	if err := p2p.ExpectMsg(peer.app, WitnessMsg, WitnessPacket{RequestId: 1, Witness: witness}); err != nil {
+		t.Errorf("witness mismatch: %v", err)
+	}
+	// Witnesses of unknown blocks are not served
+	p2p.Send(peer.app, GetWitnessMsg, GetWitnessPacket{RequestId: 2, Hash: common.Hash{1}})
+	if err := p2p.ExpectMsg(peer.app, WitnessMsg, WitnessPacket{RequestId: 2}); err != nil {
+		t.Errorf("unknown witness mismatch: %v", err)
+	}
+}
@@ -34,13 +34,17 @@ const (
 )
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, extension *UpgradeStatusExtension) error {
+// network IDs, difficulties, head and genesis blocks. Since eth/69 the local
+// capabilities are advertised too.
+func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, extension *UpgradeStatusExtension, caps Capabilities) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 
 	var status StatusPacket // safe to read after two values have been received from errc
 
+	if p.version < ETH69 {
+		caps = 0
+	}
 	gopool.Submit(func() {
 		errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket{
 			ProtocolVersion: uint32(p.version),
@@ -49,6 +53,7 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			Head:            head,
 			Genesis:         genesis,
 			ForkID:          forkID,
+			Capabilities:    caps,
 		})
 	})
 	gopool.Submit(func() {
@@ -67,6 +72,9 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 		}
 	}
 	p.td, p.head = status.TD, status.Head
+	if p.version >= ETH69 {
+		p.caps = status.Capabilities
+	}
 
 	if p.version >= ETH67 {
 		var upgradeStatus UpgradeStatusPacket // safe to read after two values have been received from errc
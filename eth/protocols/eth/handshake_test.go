@@ -53,19 +53,19 @@ func testHandshake(t *testing.T, protocol uint) {
 			want: errNoStatusMsg,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID, 0},
 			want: errProtocolVersionMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID, 0},
 			want: errNetworkIDMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID, 0},
 			want: errGenesisMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}, 0},
 			want: errForkIDRejected,
 		},
 	}
@@ -81,7 +81,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		// Send the junk test with one peer, check the handshake failure
 		go p2p.Send(app, test.code, test.data)
 
-		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), nil)
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), nil, 0)
 		if err == nil {
 			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
 		} else if !errors.Is(err, test.want) {
@@ -89,3 +89,67 @@ func testHandshake(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that the capabilities are exchanged in the handshake since eth/69.
+func TestHandshakeCapabilities68(t *testing.T) { testHandshakeCapabilities(t, ETH68) }
+func TestHandshakeCapabilities69(t *testing.T) { testHandshakeCapabilities(t, ETH69) }
+
+func testHandshakeCapabilities(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	backend := newTestBackend(3)
+	defer backend.close()
+
+	var (
+		genesis = backend.chain.Genesis()
+		head    = backend.chain.CurrentBlock()
+		td      = backend.chain.GetTd(head.Hash(), head.NumberU64())
+		forkID  = forkid.NewID(backend.chain.Config(), backend.chain.Genesis().Hash(), backend.chain.CurrentHeader().Number.Uint64())
+		filter  = forkid.NewFilter(backend.chain)
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	local := NewPeer(protocol, p2p.NewPeer(enode.ID{1}, "local", nil), app, nil)
+	defer local.Close()
+	remote := NewPeer(protocol, p2p.NewPeer(enode.ID{2}, "remote", nil), net, nil)
+	defer remote.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- remote.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, filter, nil, CapSnapServing|CapAncientHeaders)
+	}()
+	if err := local.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, filter, nil, CapTxRelay); err != nil {
+		t.Fatalf("local handshake failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("remote handshake failed: %v", err)
+	}
+	var wantLocal, wantRemote Capabilities
+	if protocol >= ETH69 {
+		wantLocal, wantRemote = CapSnapServing|CapAncientHeaders, CapTxRelay
+	}
+	if have := local.Capabilities(); have != wantLocal {
+		t.Errorf("capabilities of remote peer mismatch: have %v, want %v", have, wantLocal)
+	}
+	if have := remote.Capabilities(); have != wantRemote {
+		t.Errorf("capabilities of local peer mismatch: have %v, want %v", have, wantRemote)
+	}
+}
+
+func TestCapabilitiesString(t *testing.T) {
+	tests := []struct {
+		caps Capabilities
+		want string
+	}{
+		{0, ""},
+		{CapSnapServing | CapVotes, "snap,votes"},
+		{CapTxRelay | CapAncientHeaders | 1<<8, "txrelay,ancient,0x100"},
+	}
+	for _, test := range tests {
+		if have := test.caps.String(); have != test.want {
+			t.Errorf("capabilities %#x: have %q, want %q", uint64(test.caps), have, test.want)
+		}
+	}
+}
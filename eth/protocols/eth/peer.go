@@ -24,6 +24,7 @@ import (
 	mapset "github.com/deckarep/golang-set"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -73,6 +74,7 @@ type Peer struct {
 	rw              p2p.MsgReadWriter // Input/output streams for snap
 	version         uint              // Protocol version negotiated
 	statusExtension *UpgradeStatusExtension
+	caps            Capabilities // Services advertised by the peer, zero before eth/69
 
 	head common.Hash // Latest advertised head block hash
 	td   *big.Int    // Latest advertised head block total difficulty
@@ -146,6 +148,12 @@ func (p *Peer) Version() uint {
 	return p.version
 }
 
+// Capabilities retrieves the services advertised by the peer in the handshake,
+// always empty for peers older than eth/69.
+func (p *Peer) Capabilities() Capabilities {
+	return p.caps
+}
+
 // Head retrieves the current head hash and total difficulty of the peer.
 func (p *Peer) Head() (hash common.Hash, td *big.Int) {
 	p.lock.RLock()
@@ -214,6 +222,20 @@ func (p *Peer) SendTransactions(txs types.Transactions) error {
 	return p2p.Send(p.rw, TransactionsMsg, txs)
 }
 
+// SendPrivateTransactions relays transactions to a trusted peer, asking it not
+// to propagate them to the rest of the network. The message only exists since
+// eth/69, the caller must not relay to older peers.
+func (p *Peer) SendPrivateTransactions(txs types.Transactions) error {
+	// Mark all the transactions as known, but ensure we don't overflow our limits
+	for p.knownTxs.Cardinality() > max(0, maxKnownTxs-len(txs)) {
+		p.knownTxs.Pop()
+	}
+	for _, tx := range txs {
+		p.knownTxs.Add(tx.Hash())
+	}
+	return p2p.Send(p.rw, PrivateTransactionsMsg, txs)
+}
+
 // AsyncSendTransactions queues a list of transactions (by hash) to eventually
 // propagate to a remote peer. The number of pending sends are capped (new ones
 // will force old sends to be dropped)
@@ -237,12 +259,13 @@ func (p *Peer) AsyncSendTransactions(hashes []common.Hash) {
 }
 
 // sendPooledTransactionHashes sends transaction hashes to the peer and includes
-// them in its transaction hash set for future reference.
+// them in its transaction hash set for future reference. On eth/68 and newer the
+// announcement carries the types and sizes of the transactions as well.
 //
 // This method is a helper used by the async transaction announcer. Don't call it
 // directly as the queueing (memory) and transmission (bandwidth) costs should
 // not be managed directly.
-func (p *Peer) sendPooledTransactionHashes(hashes []common.Hash) error {
+func (p *Peer) sendPooledTransactionHashes(hashes []common.Hash, types []byte, sizes []uint32) error {
 	// Mark all the transactions as known, but ensure we don't overflow our limits
 	for p.knownTxs.Cardinality() > max(0, maxKnownTxs-len(hashes)) {
 		p.knownTxs.Pop()
@@ -250,6 +273,9 @@ func (p *Peer) sendPooledTransactionHashes(hashes []common.Hash) error {
 	for _, hash := range hashes {
 		p.knownTxs.Add(hash)
 	}
+	if p.Version() >= ETH68 {
+		return p2p.Send(p.rw, NewPooledTransactionHashesMsg, &NewPooledTransactionHashesPacket68{Types: types, Sizes: sizes, Hashes: hashes})
+	}
 	return p2p.Send(p.rw, NewPooledTransactionHashesMsg, NewPooledTransactionHashesPacket(hashes))
 }
 
@@ -425,6 +451,15 @@ func (p *Peer) ReplyReceiptsRLP(id uint64, receipts []rlp.RawValue) error {
 	})
 }
 
+// ReplyWitness is the eth/69 response to GetWitness, a nil witness marking it
+// as not served.
+func (p *Peer) ReplyWitness(id uint64, witness *stateless.Witness) error {
+	return p2p.Send(p.rw, WitnessMsg, &WitnessPacket{
+		RequestId: id,
+		Witness:   witness,
+	})
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *Peer) RequestOneHeader(hash common.Hash) error {
@@ -550,6 +585,22 @@ func (p *Peer) RequestReceipts(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetReceiptsMsg, GetReceiptsPacket(hashes))
 }
 
+// RequestWitness fetches the execution witness of a block, available since
+// eth/69.
+func (p *Peer) RequestWitness(hash common.Hash) error {
+	p.Log().Debug("Fetching execution witness", "hash", hash)
+	if p.Version() < ETH69 {
+		return errNotSupported
+	}
+	id := rand.Uint64()
+
+	requestTracker.Track(p.id, p.version, GetWitnessMsg, WitnessMsg, id)
+	return p2p.Send(p.rw, GetWitnessMsg, &GetWitnessPacket{
+		RequestId: id,
+		Hash:      hash,
+	})
+}
+
 // RequestTxs fetches a batch of transactions from a remote node.
 func (p *Peer) RequestTxs(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
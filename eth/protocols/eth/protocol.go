@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -33,6 +35,8 @@ const (
 	ETH65 = 65
 	ETH66 = 66
 	ETH67 = 67
+	ETH68 = 68
+	ETH69 = 69
 )
 
 // ProtocolName is the official short name of the `eth` protocol used during
@@ -41,11 +45,11 @@ const ProtocolName = "eth"
 
 // ProtocolVersions are the supported versions of the `eth` protocol (first
 // is primary).
-var ProtocolVersions = []uint{ETH67, ETH66, ETH65}
+var ProtocolVersions = []uint{ETH69, ETH68, ETH67, ETH66, ETH65}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{ETH67: 18, ETH66: 17, ETH65: 17}
+var protocolLengths = map[uint]uint64{ETH69: 19, ETH68: 18, ETH67: 18, ETH66: 17, ETH65: 17}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -72,6 +76,13 @@ const (
 
 	// Protocol messages overloaded in eth/66
 	UpgradeStatusMsg = 0x0b
+
+	// Protocol messages relaying private transactions between trusted peers in eth/69
+	PrivateTransactionsMsg = 0x0c
+
+	// Protocol messages exchanging execution witnesses in eth/69
+	GetWitnessMsg = 0x11
+	WitnessMsg    = 0x12
 )
 
 var (
@@ -83,6 +94,7 @@ var (
 	errNetworkIDMismatch       = errors.New("network ID mismatch")
 	errGenesisMismatch         = errors.New("genesis mismatch")
 	errForkIDRejected          = errors.New("fork ID rejected")
+	errNotSupported            = errors.New("not supported by the protocol version")
 )
 
 // Packet represents a p2p message in the `eth` protocol.
@@ -99,6 +111,40 @@ type StatusPacket struct {
 	Head            common.Hash
 	Genesis         common.Hash
 	ForkID          forkid.ID
+	Capabilities    Capabilities `rlp:"optional"` // Services provided by the node, since eth/69
+}
+
+// Capabilities is a bitmask of the services a node provides to its peers, letting
+// them pick the useful peers for their tasks.
+type Capabilities uint64
+
+const (
+	CapSnapServing    Capabilities = 1 << iota // Serves state ranges over the `snap` protocol
+	CapTxRelay                                 // Gossips the transactions received from the network
+	CapAncientHeaders                          // Serves the header chain down to genesis
+	CapVotes                                   // Gossips the fast finality votes
+	CapWitness                                 // Serves the execution witnesses of the recent blocks
+)
+
+var capabilityNames = []string{"snap", "txrelay", "ancient", "votes", "witness"}
+
+// Has returns whether all the capabilities in c are set.
+func (caps Capabilities) Has(c Capabilities) bool {
+	return caps&c == c
+}
+
+// String implements fmt.Stringer, listing the names of the set capabilities.
+func (caps Capabilities) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if caps.Has(1 << i) {
+			names = append(names, name)
+		}
+	}
+	if unknown := caps >> len(capabilityNames); unknown != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint64(unknown<<len(capabilityNames))))
+	}
+	return strings.Join(names, ",")
 }
 
 type UpgradeStatusExtension struct {
@@ -153,6 +199,10 @@ func (p *NewBlockHashesPacket) Unpack() ([]common.Hash, []uint64) {
 // TransactionsPacket is the network packet for broadcasting new transactions.
 type TransactionsPacket []*types.Transaction
 
+// PrivateTransactionsPacket is the network packet for relaying transactions to
+// trusted peers which must not gossip them any further.
+type PrivateTransactionsPacket []*types.Transaction
+
 // GetBlockHeadersPacket represents a block header query.
 type GetBlockHeadersPacket struct {
 	Origin  HashOrNumber // Block from which to retrieve headers
@@ -327,6 +377,14 @@ type ReceiptsRLPPacket66 struct {
 // NewPooledTransactionHashesPacket represents a transaction announcement packet.
 type NewPooledTransactionHashesPacket []common.Hash
 
+// NewPooledTransactionHashesPacket68 represents a transaction announcement packet on
+// eth/68 and newer, carrying the type and size of the announced transactions.
+type NewPooledTransactionHashesPacket68 struct {
+	Types  []byte
+	Sizes  []uint32
+	Hashes []common.Hash
+}
+
 // GetPooledTransactionsPacket represents a transaction query.
 type GetPooledTransactionsPacket []common.Hash
 
@@ -354,6 +412,19 @@ type PooledTransactionsRLPPacket66 struct {
 	PooledTransactionsRLPPacket
 }
 
+// GetWitnessPacket represents an execution witness query.
+type GetWitnessPacket struct {
+	RequestId uint64
+	Hash      common.Hash // Hash of the block to retrieve the witness of
+}
+
+// WitnessPacket is the network packet for the execution witness distribution,
+// carrying no witness if it is not served.
+type WitnessPacket struct {
+	RequestId uint64
+	Witness   *stateless.Witness `rlp:"nil"`
+}
+
 func (*StatusPacket) Name() string { return "Status" }
 func (*StatusPacket) Kind() byte   { return StatusMsg }
 
@@ -366,6 +437,9 @@ func (*NewBlockHashesPacket) Kind() byte   { return NewBlockHashesMsg }
 func (*TransactionsPacket) Name() string { return "Transactions" }
 func (*TransactionsPacket) Kind() byte   { return TransactionsMsg }
 
+func (*PrivateTransactionsPacket) Name() string { return "PrivateTransactions" }
+func (*PrivateTransactionsPacket) Kind() byte   { return PrivateTransactionsMsg }
+
 func (*GetBlockHeadersPacket) Name() string { return "GetBlockHeaders" }
 func (*GetBlockHeadersPacket) Kind() byte   { return GetBlockHeadersMsg }
 
@@ -393,9 +467,18 @@ func (*GetReceiptsPacket) Kind() byte   { return GetReceiptsMsg }
 func (*ReceiptsPacket) Name() string { return "Receipts" }
 func (*ReceiptsPacket) Kind() byte   { return ReceiptsMsg }
 
+func (*GetWitnessPacket) Name() string { return "GetWitness" }
+func (*GetWitnessPacket) Kind() byte   { return GetWitnessMsg }
+
+func (*WitnessPacket) Name() string { return "Witness" }
+func (*WitnessPacket) Kind() byte   { return WitnessMsg }
+
 func (*NewPooledTransactionHashesPacket) Name() string { return "NewPooledTransactionHashes" }
 func (*NewPooledTransactionHashesPacket) Kind() byte   { return NewPooledTransactionHashesMsg }
 
+func (*NewPooledTransactionHashesPacket68) Name() string { return "NewPooledTransactionHashes" }
+func (*NewPooledTransactionHashesPacket68) Kind() byte   { return NewPooledTransactionHashesMsg }
+
 func (*GetPooledTransactionsPacket) Name() string { return "GetPooledTransactions" }
 func (*GetPooledTransactionsPacket) Kind() byte   { return GetPooledTransactionsMsg }
 
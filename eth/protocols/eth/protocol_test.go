@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -70,6 +71,56 @@ func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	}
 }
 
+// Tests that the status packet stays wire compatible with peers predating the
+// capabilities field, in both directions.
+func TestStatusPacketCompatibility(t *testing.T) {
+	type legacyStatusPacket struct {
+		ProtocolVersion uint32
+		NetworkID       uint64
+		TD              *big.Int
+		Head            common.Hash
+		Genesis         common.Hash
+		ForkID          forkid.ID
+	}
+	legacy := &legacyStatusPacket{
+		ProtocolVersion: ETH66,
+		NetworkID:       56,
+		TD:              big.NewInt(1000),
+		Head:            common.HexToHash("0x01"),
+		Genesis:         common.HexToHash("0x02"),
+		ForkID:          forkid.ID{Hash: [4]byte{1, 2, 3, 4}, Next: 5},
+	}
+	// A status from an old peer must decode, leaving the capabilities unset
+	blob, err := rlp.EncodeToBytes(legacy)
+	if err != nil {
+		t.Fatalf("failed to encode legacy status: %v", err)
+	}
+	status := new(StatusPacket)
+	if err := rlp.DecodeBytes(blob, status); err != nil {
+		t.Fatalf("failed to decode legacy status: %v", err)
+	}
+	if status.NetworkID != legacy.NetworkID || status.Head != legacy.Head || status.Capabilities != 0 {
+		t.Fatalf("legacy status mismatch: have %+v", status)
+	}
+	// A status without capabilities must encode exactly like an old peer's
+	if enc, _ := rlp.EncodeToBytes(status); !bytes.Equal(enc, blob) {
+		t.Fatalf("status encoding mismatch: have %x, want %x", enc, blob)
+	}
+	// A status with capabilities only carries the extra field
+	status.Capabilities = CapSnapServing | CapTxRelay
+	enc, err := rlp.EncodeToBytes(status)
+	if err != nil {
+		t.Fatalf("failed to encode status: %v", err)
+	}
+	decoded := new(StatusPacket)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if decoded.Capabilities != status.Capabilities {
+		t.Fatalf("capabilities mismatch: have %v, want %v", decoded.Capabilities, status.Capabilities)
+	}
+}
+
 // TestEth66EmptyMessages tests encoding of empty eth66 messages
 func TestEth66EmptyMessages(t *testing.T) {
 	// All empty messages encodes to the same format
@@ -67,6 +67,10 @@ type Backend interface {
 	// Chain retrieves the blockchain object to serve data.
 	Chain() *core.BlockChain
 
+	// Serving retrieves whether the state requests of the peers are served. The
+	// requests are answered empty otherwise.
+	Serving() bool
+
 	// RunPeer is invoked when a peer joins on the `eth` protocol. The handler
 	// should do any peer maintenance work, handshakes and validations. If all
 	// is passed, control should be given back to the `handler` to process the
@@ -85,10 +89,15 @@ type Backend interface {
 // MakeProtocols constructs the P2P protocol definitions for `snap`.
 func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 	// Filter the discovery iterator for nodes advertising snap support.
-	dnsdisc = enode.Filter(dnsdisc, func(n *enode.Node) bool {
+	filter := func(n *enode.Node) bool {
 		var snap enrEntry
 		return n.Load(&snap) == nil
-	})
+	}
+	dnsdisc = enode.Filter(dnsdisc, filter)
+
+	// The snap servers of the chain advertise themselves under a chain specific
+	// topic with discovery v5.
+	topic := fmt.Sprintf("%s/%x", ProtocolName, backend.Chain().Genesis().Hash())
 
 	protocols := make([]p2p.Protocol, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
@@ -111,6 +120,8 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 			},
 			Attributes:     []enr.Entry{&enrEntry{}},
 			DialCandidates: dnsdisc,
+			Topic:          topic,
+			TopicFilter:    filter,
 		}
 	}
 	return protocols
@@ -161,6 +172,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
+		if !backend.Serving() {
+			return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{ID: req.ID})
+		}
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
@@ -243,6 +257,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
+		if !backend.Serving() {
+			return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{ID: req.ID})
+		}
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
@@ -378,6 +395,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
+		if !backend.Serving() {
+			return p2p.Send(peer.rw, ByteCodesMsg, &ByteCodesPacket{ID: req.ID})
+		}
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
@@ -424,6 +444,9 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if err := msg.Decode(&req); err != nil {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
+		if !backend.Serving() {
+			return p2p.Send(peer.rw, TrieNodesMsg, &TrieNodesPacket{ID: req.ID})
+		}
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
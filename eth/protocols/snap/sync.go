@@ -347,6 +347,14 @@ type syncProgress struct {
 	BytecodeHealNops   uint64             // Number of bytecodes not requested
 }
 
+// healJournal is a database entry to allow resuming the healing phase of a
+// snapshot sync without re-downloading the trie nodes that were already
+// retrieved, but could not yet be committed due to missing children.
+type healJournal struct {
+	Root  common.Hash // State root the fetched nodes belong to
+	Nodes [][]byte    // Retrieved but uncommitted trie nodes
+}
+
 // SyncPeer abstracts out the methods required for a peer to be synced against
 // with the goal of allowing the construction of mock peers without the full
 // blown networking.
@@ -548,6 +556,9 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	}
 	// Retrieve the previous sync status from LevelDB and abort if already synced
 	s.loadSyncStatus()
+	if len(s.tasks) == 0 {
+		s.loadHealJournal()
+	}
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
 		log.Debug("Snapshot sync already completed")
 		return nil
@@ -558,6 +569,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		}
 		s.cleanAccountTasks()
 		s.saveSyncStatus()
+		s.saveHealJournal()
 	}()
 
 	log.Debug("Starting snapshot sync cycle", "root", root)
@@ -788,6 +800,84 @@ func (s *Syncer) saveSyncStatus() {
 	rawdb.WriteSnapshotSyncStatus(s.db, status)
 }
 
+// loadHealJournal retrieves the trie nodes fetched by a previously aborted
+// healing phase and feeds them back into the current heal scheduler. Any node
+// the replay cannot satisfy is queued up for retrieval from the network.
+func (s *Syncer) loadHealJournal() {
+	blob := rawdb.ReadSnapshotSyncHeal(s.db)
+	if len(blob) == 0 {
+		return
+	}
+	var journal healJournal
+	if err := rlp.DecodeBytes(blob, &journal); err != nil {
+		log.Error("Failed to decode snap heal journal", "err", err)
+		return
+	}
+	if journal.Root != s.root {
+		log.Debug("Discarding stale snap heal journal", "root", journal.Root, "current", s.root)
+		return
+	}
+	nodes := make(map[common.Hash][]byte, len(journal.Nodes))
+	for _, node := range journal.Nodes {
+		nodes[crypto.Keccak256Hash(node)] = node
+	}
+	// Keep pulling missing items from the scheduler, satisfying them from the
+	// journal until nothing more can be replayed
+	var replayed int
+	for {
+		hashes, paths, codes := s.healer.scheduler.Missing(0)
+		if len(hashes) == 0 && len(codes) == 0 {
+			break
+		}
+		var progress bool
+		for i, hash := range hashes {
+			node, ok := nodes[hash]
+			if !ok {
+				s.healer.trieTasks[hash] = paths[i]
+				continue
+			}
+			delete(nodes, hash)
+			if err := s.healer.scheduler.Process(trie.SyncResult{Hash: hash, Data: node}); err != nil {
+				log.Error("Invalid journaled trienode", "hash", hash, "err", err)
+				s.healer.trieTasks[hash] = paths[i]
+				continue
+			}
+			replayed++
+			progress = true
+		}
+		for _, hash := range codes {
+			s.healer.codeTasks[hash] = struct{}{}
+		}
+		if !progress {
+			break
+		}
+	}
+	batch := s.db.NewBatch()
+	if err := s.healer.scheduler.Commit(batch); err != nil {
+		log.Error("Failed to commit journaled healing data", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to persist journaled healing data", "err", err)
+	}
+	log.Info("Restored snap heal progress", "nodes", replayed, "pending", s.healer.scheduler.Pending())
+}
+
+// saveHealJournal persists the trie nodes fetched by the healer but not yet
+// committed, so an interrupted healing phase can resume where it left off.
+func (s *Syncer) saveHealJournal() {
+	nodes := s.healer.scheduler.Fetched()
+	if len(s.tasks) != 0 || len(nodes) == 0 {
+		rawdb.DeleteSnapshotSyncHeal(s.db)
+		return
+	}
+	blob, err := rlp.EncodeToBytes(&healJournal{Root: s.root, Nodes: nodes})
+	if err != nil {
+		panic(err) // This can only fail during implementation
+	}
+	rawdb.WriteSnapshotSyncHeal(s.db, blob)
+	log.Debug("Persisted snap heal journal", "nodes", len(nodes), "bytes", common.StorageSize(len(blob)))
+}
+
 // cleanAccountTasks removes account range retrieval tasks that have already been
 // completed.
 func (s *Syncer) cleanAccountTasks() {
@@ -0,0 +1,96 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// testBackend is a mock implementation of the block trace handler, collecting
+// the delivered traces.
+type testBackend struct {
+	traces chan *BlockTracePacket
+}
+
+func (b *testBackend) Chain() *core.BlockChain { return nil }
+
+func (b *testBackend) RunPeer(peer *Peer, handler Handler) error {
+	return handler(peer)
+}
+func (b *testBackend) PeerInfo(enode.ID) interface{} { panic("not implemented") }
+
+func (b *testBackend) Handle(peer *Peer, packet Packet) error {
+	b.traces <- packet.(*BlockTracePacket)
+	return nil
+}
+
+func TestBlockTrace(t *testing.T) { testBlockTrace(t, Trace1) }
+
+func testBlockTrace(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	backend := &testBackend{traces: make(chan *BlockTracePacket, 1)}
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	peer := NewPeer(protocol, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+	defer peer.Close()
+
+	errc := make(chan error, 1)
+	go func() { errc <- Handle(backend, peer) }()
+
+	// A valid trace is delivered and known to the peer afterwards
+	sent := &BlockTracePacket{
+		Hash:   common.Hash{0x01},
+		Number: 1,
+		Hops:   []Hop{{Node: enode.ID{2}, Received: 100, Imported: 100}, {Node: enode.ID{3}, Received: 150, Imported: 180}},
+	}
+	if err := p2p.Send(app, BlockTraceMsg, sent); err != nil {
+		t.Fatalf("failed to send trace: %v", err)
+	}
+	select {
+	case got := <-backend.traces:
+		if got.Hash != sent.Hash || len(got.Hops) != 2 || got.Hops[1] != sent.Hops[1] {
+			t.Fatalf("trace mismatch: have %+v, want %+v", got, sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("trace not delivered")
+	}
+	if !peer.KnownTrace(sent.Hash) {
+		t.Errorf("delivered trace not marked as known")
+	}
+	// A trace without hops drops the peer
+	if err := p2p.Send(app, BlockTraceMsg, &BlockTracePacket{Hash: common.Hash{0x02}}); err != nil {
+		t.Fatalf("failed to send trace: %v", err)
+	}
+	select {
+	case err := <-errc:
+		if !errors.Is(err, errInvalidHops) {
+			t.Fatalf("error mismatch: have %v, want %v", err, errInvalidHops)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("invalid trace accepted")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	mapset "github.com/deckarep/golang-set"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+)
+
+const (
+	// maxKnownTraces is the maximum block hashes to keep in the known list
+	// before starting to randomly evict them.
+	maxKnownTraces = 1024
+
+	// maxQueuedTraces is the maximum number of block traces to queue up before
+	// dropping broadcasts.
+	maxQueuedTraces = 16
+)
+
+// Peer is a collection of relevant information we have about a `trace` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                           // The embedded P2P package peer
+	rw           p2p.MsgReadWriter      // Input/output streams for trace
+	version      uint                   // Protocol version negotiated
+	logger       log.Logger             // Contextual logger with the peer id injected
+	knownTraces  mapset.Set             // Set of block hashes whose traces are known to the peer
+	queuedTraces chan *BlockTracePacket // Queue of block traces to broadcast to the peer
+	term         chan struct{}          // Termination channel to stop the broadcaster
+}
+
+// NewPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	peer := &Peer{
+		id:           id,
+		Peer:         p,
+		rw:           rw,
+		version:      version,
+		logger:       log.New("peer", id[:8]),
+		knownTraces:  mapset.NewSet(),
+		queuedTraces: make(chan *BlockTracePacket, maxQueuedTraces),
+		term:         make(chan struct{}),
+	}
+	go peer.broadcastTraces()
+	return peer
+}
+
+func (p *Peer) broadcastTraces() {
+	for {
+		select {
+		case trace := <-p.queuedTraces:
+			if err := p2p.Send(p.rw, BlockTraceMsg, trace); err != nil {
+				p.Log().Debug("Failed to propagate block trace", "err", err)
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negoatiated `trace` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// Close signals the broadcast goroutine to terminate. Only ever call this if
+// you created the peer yourself via NewPeer. Otherwise let whoever created it
+// clean it up!
+func (p *Peer) Close() {
+	close(p.term)
+}
+
+// KnownTrace returns whether the peer is known to have the trace of a block.
+func (p *Peer) KnownTrace(hash common.Hash) bool {
+	return p.knownTraces.Contains(hash)
+}
+
+// markTrace marks the trace of a block as known for the peer, ensuring that it
+// will never be sent to the peer.
+func (p *Peer) markTrace(hash common.Hash) {
+	for p.knownTraces.Cardinality() >= maxKnownTraces {
+		p.knownTraces.Pop()
+	}
+	p.knownTraces.Add(hash)
+}
+
+// AsyncSendBlockTrace queues a block trace for propagation to the peer, unless
+// it already knows it. If the queue is full, the trace is dropped.
+func (p *Peer) AsyncSendBlockTrace(trace *BlockTracePacket) {
+	if p.KnownTrace(trace.Hash) {
+		return
+	}
+	select {
+	case p.queuedTraces <- trace:
+		p.markTrace(trace.Hash)
+	default:
+		p.Log().Debug("Dropping block trace propagation", "hash", trace.Hash)
+	}
+}
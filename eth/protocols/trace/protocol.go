@@ -0,0 +1,85 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	Trace1 = 1
+)
+
+// ProtocolName is the official short name of the `trace` protocol used during
+// devp2p capability negotiation. Only the peers which both opted into block
+// propagation tracing run it.
+const ProtocolName = "trace"
+
+// ProtocolVersions are the supported versions of the `trace` protocol (first
+// is primary).
+var ProtocolVersions = []uint{Trace1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{Trace1: 1}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 64 * 1024
+
+// MaxHops is the maximum number of hops a block trace may carry.
+const MaxHops = 64
+
+const (
+	BlockTraceMsg = 0x00
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+	errInvalidHops    = errors.New("invalid number of hops in block trace")
+)
+
+// Packet represents a p2p message in the `trace` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// Hop is the passage of a block through a node. The timestamps are the unix
+// milliseconds of the clock of the node, so the delays between hops include the
+// clock skew of the nodes.
+type Hop struct {
+	Node     enode.ID `json:"node"`
+	Received uint64   `json:"received"` // Time the node first saw the block, its sealing time on the miner
+	Imported uint64   `json:"imported"` // Time the node imported the block into its chain
+}
+
+// BlockTracePacket is the path a block took from its miner to the sender, the
+// last hop being the sender itself.
+type BlockTracePacket struct {
+	Hash   common.Hash
+	Number uint64
+	Hops   []Hop
+}
+
+func (*BlockTracePacket) Name() string { return "BlockTrace" }
+func (*BlockTracePacket) Kind() byte   { return BlockTraceMsg }
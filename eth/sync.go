@@ -54,10 +54,20 @@ func (h *handler) syncTransactions(p *eth.Peer) {
 	// order, insertions could overflow the non-executable queues and get dropped.
 	//
 	// TODO(karalabe): Figure out if we could get away with random order somehow
-	var txs types.Transactions
+	var txs, private types.Transactions
 	pending, _ := h.txpool.Pending()
 	for _, batch := range pending {
-		txs = append(txs, batch...)
+		for _, tx := range batch {
+			if h.privateTxs.contains(tx.Hash()) {
+				private = append(private, tx)
+			} else {
+				txs = append(txs, tx)
+			}
+		}
+	}
+	// Private transactions are only ever relayed to the trusted peers
+	if len(private) > 0 && h.isPrivateTxPeer(p.ID()) {
+		go h.relayPrivateTransactions(p, private)
 	}
 	if len(txs) == 0 {
 		return
@@ -245,22 +255,27 @@ func (cs *chainSyncer) nextSyncOp() *chainSyncOp {
 	minPeers := defaultMinSyncPeers
 	if cs.forced {
 		minPeers = 1
-	} else if minPeers > cs.handler.maxPeers {
-		minPeers = cs.handler.maxPeers
+	} else if maxPeers := int(atomic.LoadInt32(&cs.handler.maxPeers)); minPeers > maxPeers {
+		minPeers = maxPeers
 	}
 	if cs.handler.peers.len() < minPeers {
 		return nil
 	}
-	// We have enough peers, check TD
-	peer := cs.handler.peers.peerWithHighestTD()
-	if peer == nil {
-		return nil
-	}
 	mode, ourTD := cs.modeAndLocalHead()
 	if mode == downloader.FastSync && atomic.LoadUint32(&cs.handler.snapSync) == 1 {
 		// Fast sync via the snap protocol
 		mode = downloader.SnapSync
 	}
+	// We have enough peers, check TD, preferring the peers serving the whole
+	// chain, and the state too if snap syncing
+	prefer := eth.CapAncientHeaders
+	if mode == downloader.SnapSync {
+		prefer |= eth.CapSnapServing
+	}
+	peer := cs.handler.peers.peerWithHighestTD(prefer)
+	if peer == nil {
+		return nil
+	}
 	op := peerToSyncOp(mode, peer)
 	if op.td.Cmp(ourTD) <= 0 {
 		return nil // We're in sync.
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -70,7 +71,7 @@ func testFastSyncDisabling(t *testing.T, protocol uint) {
 	time.Sleep(250 * time.Millisecond)
 
 	// Check that fast sync was disabled
-	op := peerToSyncOp(downloader.FastSync, empty.handler.peers.peerWithHighestTD())
+	op := peerToSyncOp(downloader.FastSync, empty.handler.peers.peerWithHighestTD(0))
 	if err := empty.handler.doSync(op); err != nil {
 		t.Fatal("sync failed:", err)
 	}
@@ -78,3 +79,59 @@ func testFastSyncDisabling(t *testing.T, protocol uint) {
 		t.Fatalf("fast sync not disabled after successful synchronisation")
 	}
 }
+
+// Tests that among the equally good sync targets the ones advertising the wanted
+// capabilities are picked.
+func TestSyncPeerCapabilities(t *testing.T) {
+	t.Parallel()
+
+	source := newTestHandler()
+	defer source.close()
+
+	var (
+		genesis = source.chain.Genesis()
+		td      = source.chain.GetTd(genesis.Hash(), genesis.NumberU64())
+		caps    = []eth.Capabilities{eth.CapTxRelay, eth.CapSnapServing, eth.CapSnapServing | eth.CapAncientHeaders, 0}
+	)
+	for i, c := range caps {
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(eth.ETH69, p2p.NewPeer(enode.ID{byte(i + 1)}, "", nil), sourcePipe, nil)
+		sinkPeer := eth.NewPeer(eth.ETH69, p2p.NewPeer(enode.ID{0}, "", nil), sinkPipe, nil)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		if err := sinkPeer.Handshake(1, td, genesis.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), nil, c); err != nil {
+			t.Fatalf("failed to run protocol handshake: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	tests := []struct {
+		prefer eth.Capabilities
+		want   []eth.Capabilities
+	}{
+		{eth.CapAncientHeaders, []eth.Capabilities{eth.CapSnapServing | eth.CapAncientHeaders}},
+		{eth.CapSnapServing, []eth.Capabilities{eth.CapSnapServing, eth.CapSnapServing | eth.CapAncientHeaders}},
+		{eth.CapTxRelay, []eth.Capabilities{eth.CapTxRelay}},
+		{eth.CapVotes, caps},
+	}
+	for _, test := range tests {
+		peer := source.handler.peers.peerWithHighestTD(test.prefer)
+		if peer == nil {
+			t.Fatal("no sync peer")
+		}
+		found := false
+		for _, c := range test.want {
+			found = found || peer.Capabilities() == c
+		}
+		if !found {
+			t.Errorf("preferring %v: picked peer with %v, want one of %v", test.prefer, peer.Capabilities(), test.want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -171,6 +172,9 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// TracerConfig is the tracer specific configuration, handed over verbatim
+	// to the tracer chosen by Tracer.
+	TracerConfig json.RawMessage
 }
 
 // TraceCallConfig is the config for traceCall API. It holds one more
@@ -178,6 +182,7 @@ type TraceConfig struct {
 type TraceCallConfig struct {
 	*vm.LogConfig
 	Tracer         *string
+	TracerConfig   json.RawMessage
 	Timeout        *string
 	Reexec         *uint64
 	StateOverrides *ethapi.StateOverride
@@ -192,8 +197,10 @@ type StdTraceConfig struct {
 
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
-	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
-	Error  string      `json:"error,omitempty"`  // Trace failure produced by the tracer
+	Result   interface{}     `json:"result,omitempty"`   // Trace results produced by the tracer
+	Error    string          `json:"error,omitempty"`    // Trace failure produced by the tracer
+	SystemTx bool            `json:"systemTx,omitempty"` // Whether the transaction was injected by the consensus engine
+	GasUsed  *hexutil.Uint64 `json:"gasUsed,omitempty"`  // Gas used by system transactions, their gas limit being arbitrary
 }
 
 // blockTraceTask represents a single block trace task when an entire chain is
@@ -282,7 +289,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 						TxIndex:   i,
 						TxHash:    tx.Hash(),
 					}
-					res, err := api.traceTx(localctx, msg, txctx, blockCtx, task.statedb, config)
+					res, gasUsed, err := api.traceTx(localctx, msg, txctx, blockCtx, task.statedb, config)
 					if err != nil {
 						task.results[i] = &txTraceResult{Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
@@ -290,7 +297,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 					task.statedb.Finalise(api.backend.ChainConfig().IsEIP158(task.block.Number()))
-					task.results[i] = &txTraceResult{Result: res}
+					task.results[i] = api.newTxTraceResult(tx, task.block.Header(), res, gasUsed)
 				}
 				// Stream the result back to the user or abort on teardown
 				select {
@@ -626,12 +633,12 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 					TxIndex:   task.index,
 					TxHash:    txs[task.index].Hash(),
 				}
-				res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
+				res, gasUsed, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
 				if err != nil {
 					results[task.index] = &txTraceResult{Error: err.Error()}
 					continue
 				}
-				results[task.index] = &txTraceResult{Result: res}
+				results[task.index] = api.newTxTraceResult(txs[task.index], block.Header(), res, gasUsed)
 			}
 		})
 	}
@@ -849,7 +856,8 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 		TxIndex:   int(index),
 		TxHash:    hash,
 	}
-	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	res, _, err := api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
+	return res, err
 }
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs
@@ -894,54 +902,101 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHa
 	var traceConfig *TraceConfig
 	if config != nil {
 		traceConfig = &TraceConfig{
-			LogConfig: config.LogConfig,
-			Tracer:    config.Tracer,
-			Timeout:   config.Timeout,
-			Reexec:    config.Reexec,
+			LogConfig:    config.LogConfig,
+			Tracer:       config.Tracer,
+			TracerConfig: config.TracerConfig,
+			Timeout:      config.Timeout,
+			Reexec:       config.Reexec,
 		}
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	res, _, err := api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return res, err
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
-// be tracer dependent.
-func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+// be tracer dependent, along with the gas used by the message.
+func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, uint64, error) {
 	// Assemble the structured logger or the JavaScript tracer
-	var (
-		tracer    vm.EVMLogger
-		err       error
-		txContext = core.NewEVMTxContext(message)
-	)
+	var tracer vm.EVMLogger
 	switch {
 	case config == nil:
 		tracer = vm.NewStructLogger(nil)
 	case config.Tracer != nil:
-		// Define a meaningful timeout of a single transaction trace
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
-		}
-		if t, err := New(*config.Tracer, txctx); err != nil {
-			return nil, err
-		} else {
-			deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-			go func() {
-				<-deadlineCtx.Done()
-				if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
-					t.Stop(errors.New("execution timeout"))
-				}
-			}()
-			defer cancel()
-			tracer = t
+		t, cancel, err := newTimedTracer(ctx, txctx, config)
+		if err != nil {
+			return nil, 0, err
 		}
+		defer cancel()
+		tracer = t
 	default:
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
+	result, err := api.traceEVM(message, txctx, vmctx, statedb, tracer)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Depending on the tracer type, format and return the output.
+	switch tracer := tracer.(type) {
+	case *vm.StructLogger:
+		return formatStructLogResult(result, tracer.StructLogs()), result.UsedGas, nil
+
+	case Tracer:
+		res, err := tracer.GetResult()
+		return res, result.UsedGas, err
+
+	default:
+		panic(fmt.Sprintf("bad tracer type %T", tracer))
+	}
+}
+
+// newTxTraceResult wraps the trace of a transaction of the given block. System
+// transactions of PoSA engines are flagged, along with the gas they actually
+// used, so that they can be told apart from the user transactions.
+func (api *API) newTxTraceResult(tx *types.Transaction, header *types.Header, res interface{}, gasUsed uint64) *txTraceResult {
+	result := &txTraceResult{Result: res}
+	if posa, ok := api.backend.Engine().(consensus.PoSA); ok {
+		if isSystem, _ := posa.IsSystemTransaction(tx, header); isSystem {
+			result.SystemTx = true
+			result.GasUsed = (*hexutil.Uint64)(&gasUsed)
+		}
+	}
+	return result
+}
+
+// newTimedTracer creates the JavaScript or native tracer requested by the config,
+// stopping it once the configured timeout expires. The returned cancel function
+// must be called once the tracing is done.
+func newTimedTracer(ctx context.Context, txctx *Context, config *TraceConfig) (Tracer, context.CancelFunc, error) {
+	// Define a meaningful timeout of a single transaction trace
+	var (
+		timeout = defaultTraceTimeout
+		err     error
+	)
+	if config.Timeout != nil {
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, nil, err
+		}
+	}
+	t, err := New(*config.Tracer, txctx, config.TracerConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
+			t.Stop(errors.New("execution timeout"))
+		}
+	}()
+	return t, cancel, nil
+}
+
+// traceEVM executes the given message in the provided environment with the
+// given tracer attached.
+func (api *API) traceEVM(message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, tracer vm.EVMLogger) (*core.ExecutionResult, error) {
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(message), statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
 
 	if posa, ok := api.backend.Engine().(consensus.PoSA); ok && message.From() == vmctx.Coinbase &&
 		posa.IsSystemContract(message.To()) && message.GasPrice().Cmp(big.NewInt(0)) == 0 {
@@ -963,27 +1018,21 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
+	return result, nil
+}
 
-	// Depending on the tracer type, format and return the output.
-	switch tracer := tracer.(type) {
-	case *vm.StructLogger:
-		// If the result contains a revert reason, return it.
-		returnVal := fmt.Sprintf("%x", result.Return())
-		if len(result.Revert()) > 0 {
-			returnVal = fmt.Sprintf("%x", result.Revert())
-		}
-		return &ethapi.ExecutionResult{
-			Gas:         result.UsedGas,
-			Failed:      result.Failed(),
-			ReturnValue: returnVal,
-			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
-
-	case Tracer:
-		return tracer.GetResult()
-
-	default:
-		panic(fmt.Sprintf("bad tracer type %T", tracer))
+// formatStructLogResult assembles the output of a struct logger trace.
+func formatStructLogResult(result *core.ExecutionResult, logs []vm.StructLog) *ethapi.ExecutionResult {
+	// If the result contains a revert reason, return it.
+	returnVal := fmt.Sprintf("%x", result.Return())
+	if len(result.Revert()) > 0 {
+		returnVal = fmt.Sprintf("%x", result.Revert())
+	}
+	return &ethapi.ExecutionResult{
+		Gas:         result.UsedGas,
+		Failed:      result.Failed(),
+		ReturnValue: returnVal,
+		StructLogs:  ethapi.FormatLogs(logs),
 	}
 }
 
@@ -416,6 +416,55 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+// testPoSA is a PoSA engine treating the transactions to a given address as
+// system transactions.
+type testPoSA struct {
+	consensus.Engine
+	system common.Address
+}
+
+func (e *testPoSA) IsSystemTransaction(tx *types.Transaction, header *types.Header) (bool, error) {
+	return tx.To() != nil && *tx.To() == e.system, nil
+}
+func (e *testPoSA) IsSystemContract(to *common.Address) bool                    { return to != nil && *to == e.system }
+func (e *testPoSA) EnoughDistance(consensus.ChainReader, *types.Header) bool    { return true }
+func (e *testPoSA) IsLocalBlock(*types.Header) bool                             { return false }
+func (e *testPoSA) AllowLightProcess(consensus.ChainReader, *types.Header) bool { return false }
+func (e *testPoSA) BlockRewards(*big.Int) *big.Int                              { return nil }
+func (e *testPoSA) Validators(consensus.ChainHeaderReader, *types.Header) ([]common.Address, error) {
+	return nil, nil
+}
+
+func TestTraceBlockSystemTx(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		// A user transfer followed by a transfer to the system address
+		tx, _ := types.SignTx(types.NewTransaction(0, accounts[1].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(1, accounts[2].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	backend.engine = &testPoSA{Engine: backend.engine, system: accounts[2].addr}
+
+	result, err := NewAPI(backend).TraceBlockByNumber(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	have, _ := json.Marshal(result)
+	want := `[{"result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}},` +
+		`{"result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]},"systemTx":true,"gasUsed":"0x5208"}]`
+	if string(have) != want {
+		t.Errorf("result mismatch, have\n%v\n, want\n%v\n", string(have), want)
+	}
+}
+
 func TestTracingWithOverrides(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -0,0 +1,168 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// Tests that the native prestate tracer reports the touched accounts as they
+// were before the transaction, and only the changes in diff mode.
+func TestPrestateTracer(t *testing.T) {
+	var (
+		to       = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		coinbase = common.HexToAddress("0x00000000000000000000000000000000c014ba5e")
+		balance  = big.NewInt(500000000000000)
+		slot0    = common.Hash{}
+		slot1    = common.BigToHash(common.Big1)
+		slot2    = common.BigToHash(common.Big2)
+	)
+	privkey, err := crypto.HexToECDSA("0000000000000000deadbeef00000000000000000000000000000000deadbeef")
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	tx, err := types.SignNewTx(privkey, signer, &types.LegacyTx{
+		GasPrice: big.NewInt(1),
+		Gas:      100000,
+		To:       &to,
+		Value:    big.NewInt(10),
+	})
+	if err != nil {
+		t.Fatalf("err %v", err)
+	}
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: tx.GasPrice(),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    coinbase,
+		BlockNumber: new(big.Int).SetUint64(8000000),
+		Time:        new(big.Int).SetUint64(5),
+		Difficulty:  big.NewInt(0x30000),
+		GasLimit:    uint64(6000000),
+	}
+	var code = []byte{
+		byte(vm.PUSH1), 0x0, byte(vm.SLOAD), byte(vm.POP), // read slot 0
+		byte(vm.PUSH1), 0x7, byte(vm.PUSH1), 0x1, byte(vm.SSTORE), // write 7 into slot 1
+		byte(vm.PUSH1), 0x2, byte(vm.SLOAD), byte(vm.POP), // read slot 2
+		byte(vm.STOP),
+	}
+	var alloc = core.GenesisAlloc{
+		to: core.GenesisAccount{
+			Nonce: 1,
+			Code:  code,
+			Storage: map[common.Hash]common.Hash{
+				slot0: common.BigToHash(big.NewInt(5)),
+				slot1: common.BigToHash(big.NewInt(3)),
+				slot2: common.BigToHash(big.NewInt(9)),
+			},
+		},
+		origin: core.GenesisAccount{
+			Nonce:   0,
+			Balance: balance,
+		},
+	}
+	trace := func(config string) []byte {
+		_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+		tracer, err := tracers.New("prestateTracer", new(tracers.Context), json.RawMessage(config))
+		if err != nil {
+			t.Fatalf("failed to create prestate tracer: %v", err)
+		}
+		evm := vm.NewEVM(context, txContext, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			t.Fatalf("failed to prepare transaction for tracing: %v", err)
+		}
+		st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+		if _, err = st.TransitionDb(); err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		return res
+	}
+	// Check the prestate in the default mode
+	var pre map[common.Address]*prestateAccount
+	if err := json.Unmarshal(trace(""), &pre); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if len(pre) != 3 {
+		t.Fatalf("account count mismatch: have %d, want 3", len(pre))
+	}
+	if acc := pre[origin]; acc.Balance.ToInt().Cmp(balance) != 0 || acc.Nonce != 0 {
+		t.Errorf("origin prestate mismatch: have balance %v nonce %d, want %v 0", acc.Balance, acc.Nonce, balance)
+	}
+	if acc := pre[to]; acc.Balance.ToInt().Sign() != 0 || acc.Nonce != 1 || !reflect.DeepEqual([]byte(acc.Code), code) || !reflect.DeepEqual(acc.Storage, alloc[to].Storage) {
+		t.Errorf("contract prestate mismatch: have %+v", acc)
+	}
+	// Check that only the modifications are reported in diff mode
+	var diff struct {
+		Pre  map[common.Address]*prestateAccount `json:"pre"`
+		Post map[common.Address]*prestateAccount `json:"post"`
+	}
+	if err := json.Unmarshal(trace(`{"diffMode": true}`), &diff); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if len(diff.Pre) != 3 || len(diff.Post) != 3 {
+		t.Fatalf("account count mismatch: have %d pre %d post, want 3 3", len(diff.Pre), len(diff.Post))
+	}
+	if have, want := diff.Pre[to].Storage, map[common.Hash]common.Hash{slot1: common.BigToHash(big.NewInt(3))}; !reflect.DeepEqual(have, want) {
+		t.Errorf("contract pre storage mismatch: have %v, want %v", have, want)
+	}
+	if have, want := diff.Post[to].Storage, map[common.Hash]common.Hash{slot1: common.BigToHash(big.NewInt(7))}; !reflect.DeepEqual(have, want) {
+		t.Errorf("contract post storage mismatch: have %v, want %v", have, want)
+	}
+	if have := diff.Post[to]; have.Balance.ToInt().Cmp(tx.Value()) != 0 || have.Nonce != 0 || have.Code != nil {
+		t.Errorf("contract post state mismatch: have %+v", have)
+	}
+	if have := diff.Post[origin]; have.Nonce != 1 {
+		t.Errorf("origin post nonce mismatch: have %d, want 1", have.Nonce)
+	}
+	// The fees paid by the origin are received by the coinbase
+	spent := new(big.Int).Sub(diff.Pre[origin].Balance.ToInt(), diff.Post[origin].Balance.ToInt())
+	earned := new(big.Int).Sub(diff.Post[coinbase].Balance.ToInt(), diff.Pre[coinbase].Balance.ToInt())
+	if spent.Sub(spent, tx.Value()).Cmp(earned) != 0 || earned.Sign() <= 0 {
+		t.Errorf("fee mismatch: origin spent %v, coinbase earned %v", spent, earned)
+	}
+}
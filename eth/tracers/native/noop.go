@@ -27,7 +27,7 @@ import (
 )
 
 func init() {
-	register("noopTracerNative", newNoopTracer)
+	Register("noopTracerNative", newNoopTracer)
 }
 
 // noopTracer is a go implementation of the Tracer interface which
@@ -35,8 +35,8 @@ func init() {
 type noopTracer struct{}
 
 // newNoopTracer returns a new noop tracer.
-func newNoopTracer() tracers.Tracer {
-	return &noopTracer{}
+func newNoopTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	return &noopTracer{}, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -64,6 +64,14 @@ func (t *noopTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.
 func (t *noopTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 }
 
+// CaptureTxStart implements the EVMLogger interface and is invoked at the
+// beginning of transaction processing.
+func (*noopTracer) CaptureTxStart(gasLimit uint64) {}
+
+// CaptureTxEnd implements the EVMLogger interface and is invoked at the end of
+// transaction processing.
+func (*noopTracer) CaptureTxEnd(restGas uint64) {}
+
 // GetResult returns an empty json object.
 func (t *noopTracer) GetResult() (json.RawMessage, error) {
 	return json.RawMessage(`{}`), nil
@@ -0,0 +1,306 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	Register("prestateTracer", newPrestateTracer)
+}
+
+type state = map[common.Address]*account
+
+type account struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// exists reports whether the account has any state, i.e. it is not an empty
+// account created (or to be created) by the traced transaction.
+func (a *account) exists() bool {
+	return a.Nonce > 0 || len(a.Code) > 0 || len(a.Storage) > 0 || (a.Balance != nil && a.Balance.ToInt().Sign() != 0)
+}
+
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, report only the changed accounts and slots, before and after the transaction
+}
+
+type prestateTracer struct {
+	env       *vm.EVM
+	pre       state
+	post      state
+	create    bool
+	to        common.Address
+	gasLimit  uint64 // Amount of gas bought for the whole tx
+	config    prestateTracerConfig
+	created   map[common.Address]bool
+	deleted   map[common.Address]bool
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newPrestateTracer returns a native go tracer which collects the accounts and
+// storage slots touched by a tx as they were before its execution. In diff mode,
+// only the changed state is reported, both before and after the execution.
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{
+		pre:     make(state),
+		post:    make(state),
+		config:  config,
+		created: make(map[common.Address]bool),
+		deleted: make(map[common.Address]bool),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.create = create
+	t.to = to
+
+	t.lookupAccount(from)
+	t.lookupAccount(to)
+	t.lookupAccount(env.Context.Coinbase)
+	if env.ChainConfig().Parlia != nil {
+		// Transaction fees are collected by the system address in parlia
+		t.lookupAccount(consensus.SystemAddress)
+	}
+	// The recipient balance includes the value transferred.
+	toBal := new(big.Int).Sub(t.pre[to].Balance.ToInt(), value)
+	t.pre[to].Balance = (*hexutil.Big)(toBal)
+
+	// The sender balance is after reducing: value and gasLimit.
+	// We need to re-add them to get the pre-tx balance.
+	fromBal := new(big.Int).Set(t.pre[from].Balance.ToInt())
+	consumedGas := new(big.Int).Mul(env.TxContext.GasPrice, new(big.Int).SetUint64(t.gasLimit))
+	fromBal.Add(fromBal, new(big.Int).Add(value, consumedGas))
+	t.pre[from].Balance = (*hexutil.Big)(fromBal)
+	t.pre[from].Nonce--
+
+	if create {
+		// The contract account was already set up for the deployment, but
+		// any pre-existing account must have had no nonce and no code.
+		t.pre[to].Nonce = 0
+		t.pre[to].Code = nil
+		t.created[to] = true
+	}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if t.config.DiffMode {
+		return
+	}
+	if t.create {
+		// Keep the account only if it had any state prior to the contract
+		// creation at that address.
+		if s := t.pre[t.to]; s != nil && !s.exists() {
+			delete(t.pre, t.to)
+		}
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil {
+		return
+	}
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		t.env.Cancel()
+		return
+	}
+	var (
+		stack      = scope.Stack
+		stackLen   = len(stack.Data())
+		caller     = scope.Contract.Address()
+		stackAddr  = func(n int) common.Address { return common.Address(stack.Back(n).Bytes20()) }
+		stackSlot  = func(n int) common.Hash { return common.Hash(stack.Back(n).Bytes32()) }
+		stackFits  = func(n int) bool { return stackLen >= n }
+		stackInt64 = func(n int) int64 { return int64(stack.Back(n).Uint64()) }
+	)
+	switch {
+	case stackFits(1) && (op == vm.SLOAD || op == vm.SSTORE):
+		t.lookupAccount(caller)
+		t.lookupStorage(caller, stackSlot(0))
+	case stackFits(1) && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE):
+		t.lookupAccount(stackAddr(0))
+	case stackFits(1) && op == vm.SELFDESTRUCT:
+		t.lookupAccount(stackAddr(0))
+		t.deleted[caller] = true
+	case stackFits(5) && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		t.lookupAccount(stackAddr(1))
+	case op == vm.CREATE:
+		addr := crypto.CreateAddress(caller, t.env.StateDB.GetNonce(caller))
+		t.lookupAccount(addr)
+		t.created[addr] = true
+	case stackFits(4) && op == vm.CREATE2:
+		init := scope.Memory.GetCopy(stackInt64(1), stackInt64(2))
+		addr := crypto.CreateAddress2(caller, stackSlot(3), crypto.Keccak256(init))
+		t.lookupAccount(addr)
+		t.created[addr] = true
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *prestateTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, _ *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+}
+
+// CaptureTxStart implements the EVMLogger interface and is invoked at the
+// beginning of transaction processing.
+func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd implements the EVMLogger interface and is invoked at the end of
+// transaction processing. In diff mode, it collects the post state of the
+// touched accounts and drops everything left unchanged by the transaction.
+func (t *prestateTracer) CaptureTxEnd(restGas uint64) {
+	if !t.config.DiffMode || t.env == nil {
+		return
+	}
+	for addr, pre := range t.pre {
+		// The state of deleted accounts is only reported in the pre state
+		if t.deleted[addr] {
+			continue
+		}
+		var (
+			modified bool
+			post     = &account{Storage: make(map[common.Hash]common.Hash)}
+		)
+		if balance := t.env.StateDB.GetBalance(addr); balance.Cmp(pre.Balance.ToInt()) != 0 {
+			modified = true
+			post.Balance = (*hexutil.Big)(balance)
+		}
+		if nonce := t.env.StateDB.GetNonce(addr); nonce != pre.Nonce {
+			modified = true
+			post.Nonce = nonce
+		}
+		if code := t.env.StateDB.GetCode(addr); !bytes.Equal(code, pre.Code) {
+			modified = true
+			post.Code = code
+		}
+		for key, val := range pre.Storage {
+			newVal := t.env.StateDB.GetState(addr, key)
+			if val == newVal {
+				// Omit unchanged slots
+				delete(pre.Storage, key)
+				continue
+			}
+			modified = true
+			if val == (common.Hash{}) {
+				delete(pre.Storage, key)
+			}
+			if newVal != (common.Hash{}) {
+				post.Storage[key] = newVal
+			}
+		}
+		if modified {
+			t.post[addr] = post
+		} else {
+			delete(t.pre, addr)
+		}
+	}
+	// Contracts created by the transaction had no state before it
+	for addr := range t.created {
+		if s := t.pre[addr]; s != nil && !s.exists() {
+			delete(t.pre, addr)
+		}
+	}
+}
+
+// GetResult returns the json-encoded prestate (or the pre and post state in
+// diff mode) of the accounts touched by the transaction, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	var (
+		res []byte
+		err error
+	)
+	if t.config.DiffMode {
+		res, err = json.Marshal(struct {
+			Post state `json:"post"`
+			Pre  state `json:"pre"`
+		}{t.post, t.pre})
+	} else {
+		res, err = json.Marshal(t.pre)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(res), t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount fetches details of an account and adds it to the prestate
+// if it doesn't exist there.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &account{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.env.StateDB.GetBalance(addr))),
+		Nonce:   t.env.StateDB.GetNonce(addr),
+		Code:    t.env.StateDB.GetCode(addr),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage fetches the requested storage slot and adds
+// it to the prestate of the given contract. It assumes `lookupAccount`
+// has been performed on the contract before.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	if _, ok := t.pre[addr].Storage[key]; ok {
+		return
+	}
+	t.pre[addr].Storage[key] = t.env.StateDB.GetState(addr, key)
+}
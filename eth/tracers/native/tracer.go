@@ -22,20 +22,27 @@ file needs to be added to this folder, containing an implementation of the
 `eth.tracers.Tracer` interface.
 
 Aside from implementing the tracer, it also needs to register itself, using the
-`register` method -- and this needs to be done in the package initialization.
+`Register` method -- and this needs to be done in the package initialization.
 
 Example:
 
 ```golang
 func init() {
-	register("noopTracerNative", newNoopTracer)
+	Register("noopTracerNative", newNoopTracer)
 }
 ```
+
+Tracers living outside of go-ethereum can be compiled into the node the same
+way: the package implementing them registers them through `native.Register` in
+its initialization, and is imported (for side effects) by the main package of
+the custom build.
 */
 package native
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/eth/tracers"
 )
@@ -45,6 +52,11 @@ func init() {
 	tracers.RegisterLookup(false, lookup)
 }
 
+// Constructor creates a native tracer for the transaction described by ctx.
+// The cfg is the tracer specific configuration supplied by the user, empty if
+// none was given.
+type Constructor func(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error)
+
 /*
 ctors is a map of package-local tracer constructors.
 
@@ -57,23 +69,28 @@ The go spec (https://golang.org/ref/spec#Package_initialization) says
 
 Hence, we cannot make the map in init, but must make it upon first use.
 */
-var ctors map[string]func() tracers.Tracer
+var ctors map[string]Constructor
 
-// register is used by native tracers to register their presence.
-func register(name string, ctor func() tracers.Tracer) {
+// Register makes a native tracer available under the given name. It is meant
+// to be called from package initialization and panics if the name is already
+// taken.
+func Register(name string, ctor Constructor) {
 	if ctors == nil {
-		ctors = make(map[string]func() tracers.Tracer)
+		ctors = make(map[string]Constructor)
+	}
+	if _, exist := ctors[name]; exist {
+		panic(fmt.Sprintf("native tracer %q already registered", name))
 	}
 	ctors[name] = ctor
 }
 
 // lookup returns a tracer, if one can be matched to the given name.
-func lookup(name string, ctx *tracers.Context) (tracers.Tracer, error) {
+func lookup(name string, ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
 	if ctors == nil {
-		ctors = make(map[string]func() tracers.Tracer)
+		ctors = make(map[string]Constructor)
 	}
 	if ctor, ok := ctors[name]; ok {
-		return ctor(), nil
+		return ctor(ctx, cfg)
 	}
 	return nil, errors.New("no tracer found")
 }
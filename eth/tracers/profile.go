@@ -0,0 +1,286 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultProfileContracts is the number of contracts reported by a gas profile
+// by default.
+const defaultProfileContracts = 100
+
+// GasProfileConfig holds extra parameters to the gas profiler.
+type GasProfileConfig struct {
+	Reexec    *uint64
+	Contracts *int // Number of contracts reported, the ones using the most gas first
+}
+
+// OpcodeProfile is the gas used by an opcode over a range of blocks.
+type OpcodeProfile struct {
+	Count uint64 `json:"count"` // Number of executions
+	Gas   uint64 `json:"gas"`   // Gas used, excluding the gas used by the frames called
+}
+
+// ContractProfile is the gas used by the code of an account over a range of
+// blocks.
+type ContractProfile struct {
+	Address common.Address `json:"address"`
+	Calls   uint64         `json:"calls"` // Number of frames executed
+	Gas     uint64         `json:"gas"`   // Gas used, excluding the gas used by the frames called
+}
+
+// GasProfile is the gas used over a range of blocks, aggregated per opcode and
+// per contract.
+type GasProfile struct {
+	From         uint64                    `json:"from"`
+	To           uint64                    `json:"to"`
+	Transactions uint64                    `json:"transactions"`
+	GasUsed      uint64                    `json:"gasUsed"` // Gas used by the transactions, intrinsic gas included
+	Opcodes      map[string]*OpcodeProfile `json:"opcodes"`
+	Contracts    []*ContractProfile        `json:"contracts"` // Contracts using the most gas, sorted by gas
+}
+
+// GasProfile re-executes the blocks between start and end (both included),
+// aggregating the gas used per opcode and per contract, to find the hot opcodes
+// and contracts.
+func (api *API) GasProfile(ctx context.Context, start, end rpc.BlockNumber, config *GasProfileConfig) (*GasProfile, error) {
+	from, err := api.blockByNumber(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(ctx, end)
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+	}
+	reexec, contracts := defaultTraceReexec, defaultProfileContracts
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	if config != nil && config.Contracts != nil {
+		contracts = *config.Contracts
+	}
+	var (
+		profiler = newGasProfiler()
+		profile  = &GasProfile{From: from.NumberU64(), To: to.NumberU64()}
+	)
+	for block := from; ; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+		if err != nil {
+			return nil, err
+		}
+		statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+		if err != nil {
+			return nil, err
+		}
+		gasUsed, err := api.profileBlock(ctx, block, statedb, profiler)
+		if err != nil {
+			return nil, err
+		}
+		profile.Transactions += uint64(len(block.Transactions()))
+		profile.GasUsed += gasUsed
+
+		if block.NumberU64() == to.NumberU64() {
+			break
+		}
+		if block, err = api.blockByNumber(ctx, rpc.BlockNumber(block.NumberU64()+1)); err != nil {
+			return nil, err
+		}
+	}
+	profile.Opcodes = make(map[string]*OpcodeProfile, len(profiler.opcodes))
+	for op, stats := range profiler.opcodes {
+		profile.Opcodes[op.String()] = stats
+	}
+	profile.Contracts = make([]*ContractProfile, 0, len(profiler.contracts))
+	for _, stats := range profiler.contracts {
+		profile.Contracts = append(profile.Contracts, stats)
+	}
+	sort.Slice(profile.Contracts, func(i, j int) bool {
+		if profile.Contracts[i].Gas != profile.Contracts[j].Gas {
+			return profile.Contracts[i].Gas > profile.Contracts[j].Gas
+		}
+		return bytes.Compare(profile.Contracts[i].Address[:], profile.Contracts[j].Address[:]) < 0
+	})
+	if len(profile.Contracts) > contracts {
+		profile.Contracts = profile.Contracts[:contracts]
+	}
+	return profile, nil
+}
+
+// profileBlock executes the transactions of the block on top of the state of its
+// parent with the profiler attached, returning the gas they used.
+func (api *API) profileBlock(ctx context.Context, block *types.Block, statedb *state.StateDB, profiler *gasProfiler) (uint64, error) {
+	var (
+		gasUsed  uint64
+		signer   = types.MakeSigner(api.backend.ChainConfig(), block.Number())
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return 0, fmt.Errorf("block #%d tx %d: %v", block.NumberU64(), i, err)
+		}
+		txctx := &Context{BlockHash: block.Hash(), TxIndex: i, TxHash: tx.Hash()}
+		result, err := api.traceEVM(msg, txctx, blockCtx, statedb, profiler)
+		if err != nil {
+			return 0, fmt.Errorf("block #%d tx %d: %v", block.NumberU64(), i, err)
+		}
+		gasUsed += result.UsedGas
+
+		// Finalize the state so any modifications are written to the trie
+		statedb.Finalise(api.backend.ChainConfig().IsEIP158(block.Number()))
+	}
+	return gasUsed, nil
+}
+
+// gasProfiler is a tracer aggregating the gas used per opcode and per contract.
+//
+// The gas used by an opcode is the gas available before it minus the gas left
+// after it, less the gas used by the frame it called, if any. Unlike its reported
+// cost, it doesn't include the gas handed over to the frames called and returned.
+type gasProfiler struct {
+	opcodes   map[vm.OpCode]*OpcodeProfile
+	contracts map[common.Address]*ContractProfile
+	frames    []*profileFrame
+}
+
+// profileFrame tracks the execution of a call frame.
+type profileFrame struct {
+	contract common.Address
+	executed bool   // Whether any opcode was executed
+	inner    uint64 // Gas used by the frames called
+
+	op      vm.OpCode // Opcode waiting for the gas left after it
+	gas     uint64    // Gas available before the pending opcode
+	cost    uint64    // Cost of the pending opcode, used if the frame ends with it
+	called  uint64    // Gas used by the frame called by the pending opcode
+	pending bool
+}
+
+func newGasProfiler() *gasProfiler {
+	return &gasProfiler{
+		opcodes:   make(map[vm.OpCode]*OpcodeProfile),
+		contracts: make(map[common.Address]*ContractProfile),
+	}
+}
+
+// settle accounts the pending opcode of the frame.
+func (p *gasProfiler) settle(frame *profileFrame, gas uint64) {
+	if !frame.pending {
+		return
+	}
+	frame.pending = false
+
+	stats := p.opcodes[frame.op]
+	if stats == nil {
+		stats = new(OpcodeProfile)
+		p.opcodes[frame.op] = stats
+	}
+	stats.Count++
+	if used := frame.gas - gas; gas <= frame.gas && used >= frame.called {
+		stats.Gas += used - frame.called
+	}
+}
+
+func (p *gasProfiler) enter(contract common.Address) {
+	p.frames = append(p.frames, &profileFrame{contract: contract})
+}
+
+// exit ends the current frame, which used the given gas.
+func (p *gasProfiler) exit(gasUsed uint64) {
+	if len(p.frames) == 0 {
+		return
+	}
+	frame := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:len(p.frames)-1]
+
+	if frame.pending {
+		p.settle(frame, frame.gas-frame.cost)
+	}
+	// Skip the plain transfers to accounts without code
+	if frame.executed || gasUsed > frame.inner {
+		stats := p.contracts[frame.contract]
+		if stats == nil {
+			stats = &ContractProfile{Address: frame.contract}
+			p.contracts[frame.contract] = stats
+		}
+		stats.Calls++
+		if gasUsed > frame.inner {
+			stats.Gas += gasUsed - frame.inner
+		}
+	}
+	if len(p.frames) > 0 {
+		parent := p.frames[len(p.frames)-1]
+		parent.inner += gasUsed
+		parent.called += gasUsed
+	}
+}
+
+func (p *gasProfiler) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	p.frames = p.frames[:0]
+	p.enter(to)
+}
+
+func (p *gasProfiler) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if len(p.frames) == 0 {
+		return
+	}
+	frame := p.frames[len(p.frames)-1]
+	p.settle(frame, gas)
+
+	frame.executed = true
+	frame.op, frame.gas, frame.cost, frame.called, frame.pending = op, gas, cost, 0, true
+}
+
+func (p *gasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	p.enter(to)
+}
+
+func (p *gasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) {
+	p.exit(gasUsed)
+}
+
+func (p *gasProfiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	p.exit(gasUsed)
+}
+
+func (p *gasProfiler) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (p *gasProfiler) CaptureTxStart(gasLimit uint64) {}
+func (p *gasProfiler) CaptureTxEnd(restGas uint64)    {}
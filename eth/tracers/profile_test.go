@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestGasProfile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts = newAccounts(2)
+		counter  = common.HexToAddress("0xc0c0")
+		caller   = common.HexToAddress("0xca11")
+	)
+	// The caller calls the counter, running sstore(0, sload(0) + 1)
+	callerCode := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	callerCode = append(callerCode, counter.Bytes()...)
+	callerCode = append(callerCode, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		counter:          {Balance: common.Big0, Code: []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}},
+		caller:           {Balance: common.Big0, Code: callerCode},
+	}}
+	signer := types.HomesteadSigner{}
+	backend := newTestBackend(t, 5, genesis, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(accounts[0].addr), caller, common.Big0, 100000, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(accounts[0].addr), accounts[1].addr, big.NewInt(1000), params.TxGas, big.NewInt(0), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	api := NewAPI(backend)
+
+	if _, err := api.GasProfile(context.Background(), 0, 2, nil); err == nil {
+		t.Error("profiled the genesis block")
+	}
+	if _, err := api.GasProfile(context.Background(), 3, 2, nil); err == nil {
+		t.Error("profiled a reversed range")
+	}
+	profile, err := api.GasProfile(context.Background(), 2, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to profile: %v", err)
+	}
+	if profile.From != 2 || profile.To != 4 || profile.Transactions != 6 {
+		t.Errorf("range mismatch: have #%d-#%d with %d txs, want #2-#4 with 6 txs", profile.From, profile.To, profile.Transactions)
+	}
+	var gasUsed uint64
+	for n := uint64(2); n <= 4; n++ {
+		gasUsed += backend.chain.GetBlockByNumber(n).GasUsed()
+	}
+	if profile.GasUsed != gasUsed {
+		t.Errorf("gas used mismatch: have %d, want %d", profile.GasUsed, gasUsed)
+	}
+	for _, op := range []string{"CALL", "SLOAD", "SSTORE"} {
+		if stats := profile.Opcodes[op]; stats == nil || stats.Count != 3 || stats.Gas == 0 {
+			t.Errorf("%s: unexpected profile %+v", op, stats)
+		}
+	}
+	// The gas of the opcodes adds up to the gas of the contracts, the gas handed
+	// over to the counter not being accounted to the CALL of the caller
+	var opcodesGas, contractsGas uint64
+	for _, stats := range profile.Opcodes {
+		opcodesGas += stats.Gas
+	}
+	if len(profile.Contracts) != 2 {
+		t.Fatalf("contract count mismatch: have %d, want 2", len(profile.Contracts))
+	}
+	for _, stats := range profile.Contracts {
+		if stats.Calls != 3 {
+			t.Errorf("%x: call count mismatch: have %d, want 3", stats.Address, stats.Calls)
+		}
+		contractsGas += stats.Gas
+	}
+	if opcodesGas != contractsGas {
+		t.Errorf("opcodes gas %d doesn't add up to contracts gas %d", opcodesGas, contractsGas)
+	}
+	if profile.Contracts[0].Address != counter {
+		t.Errorf("hottest contract mismatch: have %x, want %x", profile.Contracts[0].Address, counter)
+	}
+	if call := profile.Opcodes["CALL"].Gas; call >= 3*params.SstoreSetGasEIP2200 {
+		t.Errorf("CALL accounted the gas of the counter: %d", call)
+	}
+	// Only the hottest contracts are reported if requested
+	limit := 1
+	if profile, err = api.GasProfile(context.Background(), rpc.BlockNumber(2), rpc.LatestBlockNumber, &GasProfileConfig{Contracts: &limit}); err != nil {
+		t.Fatalf("failed to profile: %v", err)
+	}
+	if len(profile.Contracts) != 1 || profile.Contracts[0].Address != counter || profile.To != 5 {
+		t.Errorf("limited profile mismatch: %+v", profile)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// traceStreamChunk is the number of struct logs sent in a single frame of a
+// streamed trace.
+const traceStreamChunk = 512
+
+// traceFrame is a chunk of a streamed trace. The struct logs of a transaction
+// are streamed over any number of frames, followed by a final frame holding the
+// result of the trace (or its failure).
+type traceFrame struct {
+	TxIndex    int                   `json:"txIndex"`
+	TxHash     common.Hash           `json:"txHash"`
+	StructLogs []ethapi.StructLogRes `json:"structLogs,omitempty"` // Next chunk of struct logs of the transaction
+	Result     interface{}           `json:"result,omitempty"`     // Trace result, set in the final frame of the transaction
+	Error      string                `json:"error,omitempty"`      // Trace failure, set in the final frame of the transaction
+	Last       bool                  `json:"last,omitempty"`       // Whether this is the last frame of the stream
+}
+
+// streamLogger is a struct logger handing its entries over in chunks as the
+// execution progresses instead of accumulating all of them, keeping the memory
+// used by long traces bounded.
+type streamLogger struct {
+	*vm.StructLogger
+	ctx   context.Context
+	env   *vm.EVM
+	flush func([]vm.StructLog) error
+	err   error // Failure to flush the entries, aborting the execution
+}
+
+func (l *streamLogger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	l.env = env
+	l.StructLogger.CaptureStart(env, from, to, create, input, gas, value)
+}
+
+func (l *streamLogger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if l.err != nil {
+		return
+	}
+	l.StructLogger.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+	if len(l.StructLogs()) >= traceStreamChunk {
+		l.err = l.flush(l.FlushStructLogs())
+	}
+	if l.err == nil {
+		l.err = l.ctx.Err()
+	}
+	if l.err != nil && l.env != nil {
+		l.env.Cancel()
+	}
+}
+
+// TraceBlockByNumberStream traces the block with the given number like
+// TraceBlockByNumber does, but streams the traces as subscription notifications
+// instead of returning them at once. The transactions are traced one by one and
+// the struct logs sent in chunks, so that huge block traces don't exhaust the
+// memory of the node.
+func (api *API) TraceBlockByNumberStream(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) (*rpc.Subscription, error) {
+	block, err := api.blockByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return api.streamBlock(ctx, block, config)
+}
+
+// TraceBlockByHashStream traces the block with the given hash like
+// TraceBlockByHash does, but streams the traces as subscription notifications.
+func (api *API) TraceBlockByHashStream(ctx context.Context, hash common.Hash, config *TraceConfig) (*rpc.Subscription, error) {
+	block, err := api.blockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return api.streamBlock(ctx, block, config)
+}
+
+// TraceTransactionStream traces the transaction with the given hash like
+// TraceTransaction does, but streams the trace as subscription notifications.
+func (api *API) TraceTransactionStream(ctx context.Context, hash common.Hash, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	_, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// It shouldn't happen in practice.
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	txctx := &Context{
+		BlockHash: blockHash,
+		TxIndex:   int(index),
+		TxHash:    hash,
+	}
+	sub := notifier.CreateSubscription()
+	streamCtx, send := streamSender(notifier, sub)
+
+	go func() {
+		if err := api.streamTx(streamCtx, msg, txctx, vmctx, statedb, config, true, send); err != nil {
+			send(&traceFrame{TxIndex: txctx.TxIndex, TxHash: hash, Error: err.Error(), Last: true})
+		}
+	}()
+	return sub, nil
+}
+
+// streamBlock traces the transactions of the given block one by one, streaming
+// the traces as subscription notifications.
+func (api *API) streamBlock(ctx context.Context, block *types.Block, config *TraceConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(block.NumberU64()-1), block.ParentHash())
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.backend.StateAtBlock(ctx, parent, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer   = types.MakeSigner(api.backend.ChainConfig(), block.Number())
+		txs      = block.Transactions()
+		blockCtx = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		sub      = notifier.CreateSubscription()
+	)
+	streamCtx, send := streamSender(notifier, sub)
+
+	go func() {
+		if len(txs) == 0 {
+			send(&traceFrame{Last: true})
+			return
+		}
+		for i, tx := range txs {
+			msg, _ := tx.AsMessage(signer)
+			txctx := &Context{
+				BlockHash: block.Hash(),
+				TxIndex:   i,
+				TxHash:    tx.Hash(),
+			}
+			if err := api.streamTx(streamCtx, msg, txctx, blockCtx, statedb, config, i == len(txs)-1, send); err != nil {
+				log.Debug("Streamed block tracing aborted", "number", block.NumberU64(), "tx", i, "err", err)
+				send(&traceFrame{TxIndex: i, TxHash: tx.Hash(), Error: err.Error(), Last: true})
+				return
+			}
+			// Finalize the state so any modifications are written to the trie
+			// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+			statedb.Finalise(api.backend.ChainConfig().IsEIP158(block.Number()))
+		}
+	}()
+	return sub, nil
+}
+
+// streamSender returns a context cancelled once the subscriber goes away, and
+// a function sending the frames of a trace to the subscriber.
+func streamSender(notifier *rpc.Notifier, sub *rpc.Subscription) (context.Context, func(*traceFrame) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-sub.Err():
+		case <-notifier.Closed():
+		}
+		cancel()
+	}()
+	send := func(frame *traceFrame) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return notifier.Notify(sub.ID, frame)
+	}
+	return ctx, send
+}
+
+// streamTx traces the given message like traceTx does, handing the trace over
+// to the send callback in frames. The struct logs are sent in chunks as the
+// execution progresses, and the result of the trace in a final frame. Errors are
+// only returned if the trace can't be completed.
+func (api *API) streamTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig, last bool, send func(*traceFrame) error) error {
+	var tracer vm.EVMLogger
+	if config == nil || config.Tracer == nil {
+		var logConfig *vm.LogConfig
+		if config != nil {
+			logConfig = config.LogConfig
+		}
+		tracer = &streamLogger{
+			StructLogger: vm.NewStructLogger(logConfig),
+			ctx:          ctx,
+			flush: func(logs []vm.StructLog) error {
+				return send(&traceFrame{TxIndex: txctx.TxIndex, TxHash: txctx.TxHash, StructLogs: ethapi.FormatLogs(logs)})
+			},
+		}
+	} else {
+		t, cancel, err := newTimedTracer(ctx, txctx, config)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		tracer = t
+	}
+	result, err := api.traceEVM(message, txctx, vmctx, statedb, tracer)
+	if err != nil {
+		return err
+	}
+	frame := &traceFrame{TxIndex: txctx.TxIndex, TxHash: txctx.TxHash, Last: last}
+	switch tracer := tracer.(type) {
+	case *streamLogger:
+		if tracer.err != nil {
+			return tracer.err
+		}
+		frame.StructLogs = ethapi.FormatLogs(tracer.FlushStructLogs())
+		frame.Result = formatStructLogResult(result, nil)
+
+	case Tracer:
+		res, err := tracer.GetResult()
+		if err != nil {
+			frame.Error = err.Error()
+		} else {
+			frame.Result = res
+		}
+	}
+	return send(frame)
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that streaming a block trace yields the same struct logs and results as
+// the regular block trace, split over multiple frames.
+func TestTraceBlockStream(t *testing.T) {
+	t.Parallel()
+
+	// Initialize a block calling a contract looping 200 times, twice
+	var (
+		accounts = newAccounts(1)
+		looper   = common.HexToAddress("0x1111")
+		signer   = types.HomesteadSigner{}
+	)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		// PUSH1 200 JUMPDEST PUSH1 1 SWAP1 SUB DUP1 PUSH1 2 JUMPI STOP
+		looper: {Balance: common.Big0, Code: common.FromHex("0x60c85b600190038060025700")},
+	}}
+	api := NewAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, looper, common.Big0, 100000, common.Big0, nil), signer, accounts[0].key)
+			b.AddTx(tx)
+		}
+	}))
+	want, err := api.TraceBlockByNumber(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	// Stream the same block trace over an in-process subscription
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	frames := make(chan *traceFrame)
+	sub, err := client.Subscribe(context.Background(), "debug", frames, "traceBlockByNumberStream", "0x1")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		logs    = make([][]ethapi.StructLogRes, len(want))
+		results = make([]*ethapi.ExecutionResult, len(want))
+		count   int
+	)
+	for done := false; !done; {
+		select {
+		case frame := <-frames:
+			count++
+			if len(frame.StructLogs) > traceStreamChunk {
+				t.Fatalf("frame %d: too many struct logs: %d", count, len(frame.StructLogs))
+			}
+			logs[frame.TxIndex] = append(logs[frame.TxIndex], frame.StructLogs...)
+			if frame.Result != nil {
+				blob, _ := json.Marshal(frame.Result)
+				if err := json.Unmarshal(blob, &results[frame.TxIndex]); err != nil {
+					t.Fatalf("frame %d: invalid result: %v", count, err)
+				}
+			}
+			done = frame.Last
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for trace frames")
+		}
+	}
+	if count < 2*len(want) {
+		t.Errorf("trace not streamed in chunks: %d frames", count)
+	}
+	for i, res := range want {
+		result := res.Result.(*ethapi.ExecutionResult)
+		if !reflect.DeepEqual(logs[i], result.StructLogs) {
+			t.Errorf("tx %d: struct logs mismatch: have %d entries, want %d", i, len(logs[i]), len(result.StructLogs))
+		}
+		result.StructLogs = []ethapi.StructLogRes{}
+		if !reflect.DeepEqual(results[i], result) {
+			t.Errorf("tx %d: result mismatch: have %+v, want %+v", i, results[i], result)
+		}
+	}
+}
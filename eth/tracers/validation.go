@@ -0,0 +1,224 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	simulateValidationTimer  = metrics.NewRegisteredTimer("debug/simulatevalidation", nil)
+	validationViolationMeter = metrics.NewRegisteredMeter("debug/simulatevalidation/violations", nil)
+)
+
+// bannedValidationOpcodes are the opcodes the EIP-4337 validation code of the
+// accounts, factories and paymasters isn't allowed to use, as their outcome may
+// differ between the simulation and the inclusion of the bundle.
+var bannedValidationOpcodes = map[vm.OpCode]struct{}{
+	vm.GASPRICE:     {},
+	vm.GASLIMIT:     {},
+	vm.DIFFICULTY:   {},
+	vm.TIMESTAMP:    {},
+	vm.BLOCKHASH:    {},
+	vm.NUMBER:       {},
+	vm.SELFBALANCE:  {},
+	vm.BALANCE:      {},
+	vm.ORIGIN:       {},
+	vm.COINBASE:     {},
+	vm.BLOBHASH:     {},
+	vm.CREATE:       {},
+	vm.SELFDESTRUCT: {},
+}
+
+// SimulateValidationConfig holds extra parameters to the validation simulation.
+type SimulateValidationConfig struct {
+	Reexec         *uint64
+	StateOverrides *ethapi.StateOverride
+}
+
+// OpcodeViolation is a banned opcode executed by the validation code.
+type OpcodeViolation struct {
+	Address common.Address `json:"address"`
+	Depth   int            `json:"depth"`
+	PC      uint64         `json:"pc"`
+	Opcode  string         `json:"opcode"`
+}
+
+// ValidationResult is the outcome of a validation simulation. The entry point
+// reports the validation outcome by reverting, so the return data is returned
+// whether or not the call failed.
+type ValidationResult struct {
+	GasUsed    uint64                           `json:"gasUsed"`
+	Failed     bool                             `json:"failed"`
+	ReturnData hexutil.Bytes                    `json:"returnData"`
+	Violations []*OpcodeViolation               `json:"violations"`
+	Storage    map[common.Address][]common.Hash `json:"storage"` // Slots accessed by the validation code per contract
+}
+
+// SimulateValidation executes the given call, usually a simulateValidation call
+// of an EIP-4337 entry point, on top of the state of the given block, tracing
+// the code it calls into for the opcodes banned during validation and for the
+// storage slots accessed. This lets bundlers check user operations against the
+// node directly, without a JavaScript tracer.
+func (api *API) SimulateValidation(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *SimulateValidationConfig) (*ValidationResult, error) {
+	defer simulateValidationTimer.UpdateSince(time.Now())
+
+	var (
+		err   error
+		block *types.Block
+	)
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block, err = api.blockByHash(ctx, hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		block, err = api.blockByNumber(ctx, number)
+	} else {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		msg    = args.ToMessage(api.backend.RPCGasCap())
+		vmctx  = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+		tracer = newValidationTracer()
+	)
+	result, err := api.traceEVM(msg, new(Context), vmctx, statedb, tracer)
+	if err != nil {
+		return nil, err
+	}
+	validationViolationMeter.Mark(int64(len(tracer.violations)))
+
+	res := &ValidationResult{
+		GasUsed:    result.UsedGas,
+		Failed:     result.Failed(),
+		ReturnData: result.ReturnData,
+		Violations: tracer.violations,
+		Storage:    make(map[common.Address][]common.Hash, len(tracer.storage)),
+	}
+	if res.Violations == nil {
+		res.Violations = []*OpcodeViolation{}
+	}
+	for addr, slots := range tracer.storage {
+		sorted := make([]common.Hash, 0, len(slots))
+		for slot := range slots {
+			sorted = append(sorted, slot)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+		res.Storage[addr] = sorted
+	}
+	return res, nil
+}
+
+// validationTracer is a tracer collecting the banned opcodes executed and the
+// storage slots accessed below the top call frame, i.e. by the code the entry
+// point calls into.
+//
+// GAS is allowed only right before a call, to forward the gas left to it.
+type validationTracer struct {
+	violations []*OpcodeViolation
+	storage    map[common.Address]map[common.Hash]struct{}
+	gas        *OpcodeViolation // GAS waiting to be followed by a call
+}
+
+func newValidationTracer() *validationTracer {
+	return &validationTracer{storage: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+// settleGas reports the pending GAS, unless it's followed by a call in the same
+// frame.
+func (t *validationTracer) settleGas(op vm.OpCode, depth int) {
+	if t.gas == nil {
+		return
+	}
+	call := op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL
+	if !call || t.gas.Depth != depth {
+		t.violations = append(t.violations, t.gas)
+	}
+	t.gas = nil
+}
+
+func (t *validationTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+func (t *validationTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.settleGas(op, depth)
+	if depth < 2 {
+		return
+	}
+	addr := scope.Contract.Address()
+	switch op {
+	case vm.GAS:
+		t.gas = &OpcodeViolation{Address: addr, Depth: depth, PC: pc, Opcode: op.String()}
+
+	case vm.SLOAD, vm.SSTORE:
+		if len(scope.Stack.Data()) == 0 {
+			return
+		}
+		slots := t.storage[addr]
+		if slots == nil {
+			slots = make(map[common.Hash]struct{})
+			t.storage[addr] = slots
+		}
+		slots[common.Hash(scope.Stack.Back(0).Bytes32())] = struct{}{}
+
+	default:
+		if _, banned := bannedValidationOpcodes[op]; banned {
+			t.violations = append(t.violations, &OpcodeViolation{Address: addr, Depth: depth, PC: pc, Opcode: op.String()})
+		}
+	}
+}
+
+func (t *validationTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (t *validationTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *validationTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	t.settleGas(vm.STOP, 0)
+}
+
+func (t *validationTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (t *validationTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *validationTracer) CaptureTxEnd(restGas uint64)    {}
@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestSimulateValidation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts   = newAccounts(1)
+		entryPoint = common.HexToAddress("0xe0e0")
+		account    = common.HexToAddress("0xacc0")
+		target     = common.HexToAddress("0x0ddd")
+	)
+	// call(gas, to, 0, 0, 0, 0, 0) built from the given gas opcodes
+	call := func(to common.Address, gas ...vm.OpCode) []byte {
+		code := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20)}
+		code = append(code, to.Bytes()...)
+		for _, op := range gas {
+			code = append(code, byte(op))
+		}
+		return append(code, byte(vm.CALL), byte(vm.POP))
+	}
+	// The entry point reads the timestamp, which is fine, then calls the account
+	// and reverts like simulateValidation does
+	entryPointCode := append([]byte{byte(vm.TIMESTAMP), byte(vm.POP)}, call(account, vm.GAS)...)
+	entryPointCode = append(entryPointCode, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT))
+
+	// The account reads the timestamp, slot 7 and the gas left, then forwards the
+	// gas left to a call
+	accountCode := []byte{byte(vm.TIMESTAMP), byte(vm.POP), byte(vm.PUSH1), 7, byte(vm.SLOAD), byte(vm.POP), byte(vm.GAS), byte(vm.POP)}
+	accountCode = append(accountCode, call(target, vm.GAS)...)
+	accountCode = append(accountCode, byte(vm.STOP))
+
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		entryPoint:       {Balance: common.Big0, Code: entryPointCode},
+		account:          {Balance: common.Big0, Code: accountCode},
+	}}
+	api := NewAPI(newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {}))
+
+	latest := rpc.LatestBlockNumber
+	res, err := api.SimulateValidation(context.Background(), ethapi.CallArgs{From: &accounts[0].addr, To: &entryPoint}, rpc.BlockNumberOrHash{BlockNumber: &latest}, nil)
+	if err != nil {
+		t.Fatalf("failed to simulate validation: %v", err)
+	}
+	if !res.Failed || res.GasUsed == 0 {
+		t.Errorf("outcome mismatch: failed %v, gas used %d", res.Failed, res.GasUsed)
+	}
+	want := []*OpcodeViolation{
+		{Address: account, Depth: 2, PC: 0, Opcode: "TIMESTAMP"},
+		{Address: account, Depth: 2, PC: 6, Opcode: "GAS"},
+	}
+	if !reflect.DeepEqual(res.Violations, want) {
+		t.Errorf("violations mismatch:\nhave %+v\nwant %+v", res.Violations, want)
+	}
+	if slots := res.Storage[account]; len(res.Storage) != 1 || len(slots) != 1 || slots[0] != common.BigToHash(big.NewInt(7)) {
+		t.Errorf("storage mismatch: %v", res.Storage)
+	}
+}
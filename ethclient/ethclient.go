@@ -310,6 +310,27 @@ func (ec *Client) TransactionRecipientsInBlock(ctx context.Context, number *big.
 	return rs, err
 }
 
+// BlockReceipts returns the receipts of all the transactions of the given block.
+func (ec *Client) BlockReceipts(ctx context.Context, number *big.Int) ([]*types.Receipt, error) {
+	var rs []*types.Receipt
+	err := ec.c.CallContext(ctx, &rs, "eth_getBlockReceipts", toBlockNumArg(number), nil)
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// BlockReceiptsByHash returns the receipts of all the transactions of the block
+// with the given hash.
+func (ec *Client) BlockReceiptsByHash(ctx context.Context, hash common.Hash) ([]*types.Receipt, error) {
+	var rs []*types.Receipt
+	err := ec.c.CallContext(ctx, &rs, "eth_getBlockReceipts", hash, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
 // TransactionDataAndReceipt returns the original data and receipt of a transaction by transaction hash.
 // Note that the receipt is not available for pending transactions.
 func (ec *Client) TransactionDataAndReceipt(ctx context.Context, txHash common.Hash) (*types.OriginalDataAndReceipt, error) {
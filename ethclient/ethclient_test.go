@@ -374,6 +374,9 @@ func TestEthClient(t *testing.T) {
 		"TestDiffAccounts": {
 			func(t *testing.T) { testDiffAccounts(t, client) },
 		},
+		"TestBlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 		// DO not have TestAtFunctions now, because we do not have pending block now
 	}
 
@@ -640,3 +643,46 @@ func testDiffAccounts(t *testing.T, client *rpc.Client) {
 		}
 	}
 }
+
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+	ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Millisecond)
+	defer cancel()
+
+	for _, testBlock := range testBlocks {
+		block := chain[testBlock.blockNr]
+		byNumber, err := ec.BlockReceipts(ctx, block.Number())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		byHash, err := ec.BlockReceiptsByHash(ctx, block.Hash())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(byNumber) != len(block.Transactions()) || len(byHash) != len(byNumber) {
+			t.Fatalf("block %d: receipt count mismatch: have %d/%d, want %d", testBlock.blockNr, len(byNumber), len(byHash), len(block.Transactions()))
+		}
+		for i, tx := range block.Transactions() {
+			want, err := ec.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(byNumber[i], want) || !reflect.DeepEqual(byHash[i], want) {
+				t.Fatalf("block %d: receipt %d mismatch: have %+v, want %+v", testBlock.blockNr, i, byNumber[i], want)
+			}
+		}
+	}
+	// Check that the optional fields can be omitted
+	var receipts []map[string]interface{}
+	if err := client.CallContext(ctx, &receipts, "eth_getBlockReceipts", "latest", map[string]bool{"logs": false}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, receipt := range receipts {
+		if _, ok := receipt["logs"]; ok {
+			t.Fatalf("logs included in receipt %v", receipt)
+		}
+		if _, ok := receipt["effectiveGasPrice"]; !ok {
+			t.Fatalf("effective gas price missing in receipt %v", receipt)
+		}
+	}
+}
@@ -123,7 +123,12 @@ func NewCustom(file string, namespace string, customize func(options *opt.Option
 	if err != nil {
 		return nil, err
 	}
-	// Assemble the wrapper with all the registered metrics
+	return wrap(file, namespace, db, logger), nil
+}
+
+// wrap assembles the wrapper of an opened leveldb instance with all the
+// registered metrics and starts the metrics gathering.
+func wrap(file string, namespace string, db *leveldb.DB, logger log.Logger) *Database {
 	ldb := &Database{
 		fn:       file,
 		db:       db,
@@ -145,7 +150,7 @@ func NewCustom(file string, namespace string, customize func(options *opt.Option
 
 	// Start up the metrics gathering and return
 	go ldb.meter(metricsGatheringInterval)
-	return ldb, nil
+	return ldb
 }
 
 // configureOptions sets some default options, then runs the provided setter.
@@ -38,3 +38,50 @@ func TestLevelDB(t *testing.T) {
 		})
 	})
 }
+
+func TestSharedOpen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := New(dir, 16, 16, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Flush some data into tables and leave some in the journal
+	for i := 0; i < 100; i++ {
+		if err := db.Put([]byte{0x01, byte(i)}, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte{0x02}, []byte{0x02}); err != nil {
+		t.Fatal(err)
+	}
+	// Open a shared view while the writer holds the lock
+	shared, err := NewShared(dir, 16, 16, "")
+	if err != nil {
+		t.Fatalf("failed to open shared database: %v", err)
+	}
+	defer shared.Close()
+
+	for i := 0; i < 100; i++ {
+		if val, err := shared.Get([]byte{0x01, byte(i)}); err != nil || len(val) != 1 || val[0] != byte(i) {
+			t.Fatalf("item %d mismatch: have %x, err %v", i, val, err)
+		}
+	}
+	if val, err := shared.Get([]byte{0x02}); err != nil || len(val) != 1 || val[0] != 0x02 {
+		t.Fatalf("journaled item mismatch: have %x, err %v", val, err)
+	}
+	if err := shared.Put([]byte{0x03}, []byte{0x03}); err == nil {
+		t.Fatal("write to shared database succeeded")
+	}
+	// Writes after opening are not visible until reopened
+	if err := db.Put([]byte{0x04}, []byte{0x04}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := shared.Has([]byte{0x04}); ok {
+		t.Fatal("shared database observed a later write")
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !js
+
+package leveldb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// errSharedReadOnly is returned for any modification of a shared database.
+var errSharedReadOnly = errors.New("leveldb: shared database is read-only")
+
+// NewShared opens a read-only view of a LevelDB database without taking its
+// file lock, so that it can be read while another process is writing it. The
+// view reflects the database as of opening, reopen it to catch up with the
+// writer. Tables compacted away by the writer in the meantime fail to be read.
+func NewShared(file string, cache int, handles int, namespace string) (*Database, error) {
+	options := configureOptions(func(options *opt.Options) {
+		if cache < minCache {
+			cache = minCache
+		}
+		if handles < minHandles {
+			handles = minHandles
+		}
+		options.OpenFilesCacheCapacity = handles
+		options.BlockCacheCapacity = cache / 2 * opt.MiB
+		options.ReadOnly = true
+	})
+	logger := log.New("database", file)
+	logger.Debug("Opening shared read-only database", "cache", cache, "handles", handles)
+
+	db, err := leveldb.Open(&sharedStorage{path: file}, options)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(file, namespace, db, logger), nil
+}
+
+// sharedStorage is a read-only leveldb storage reading the files of a database
+// directory without locking it.
+type sharedStorage struct {
+	path string
+}
+
+type noopLocker struct{}
+
+func (noopLocker) Unlock() {}
+
+func (s *sharedStorage) Lock() (storage.Locker, error) { return noopLocker{}, nil }
+func (s *sharedStorage) Log(str string)                {}
+
+func (s *sharedStorage) SetMeta(fd storage.FileDesc) error { return errSharedReadOnly }
+
+func (s *sharedStorage) Create(fd storage.FileDesc) (storage.Writer, error) {
+	return nil, errSharedReadOnly
+}
+
+func (s *sharedStorage) Remove(fd storage.FileDesc) error { return errSharedReadOnly }
+
+func (s *sharedStorage) Rename(oldfd, newfd storage.FileDesc) error { return errSharedReadOnly }
+
+func (s *sharedStorage) Close() error { return nil }
+
+// GetMeta returns the manifest file the CURRENT file points to.
+func (s *sharedStorage) GetMeta() (storage.FileDesc, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(s.path, "CURRENT"))
+	if err != nil {
+		return storage.FileDesc{}, err
+	}
+	fd, ok := parseName(string(bytes.TrimSuffix(blob, []byte{'\n'})))
+	if !ok || fd.Type != storage.TypeManifest {
+		return storage.FileDesc{}, &storage.ErrCorrupted{Err: fmt.Errorf("invalid CURRENT file content %q", blob)}
+	}
+	return fd, nil
+}
+
+// List returns the files of the given types in the database directory.
+func (s *sharedStorage) List(ft storage.FileType) ([]storage.FileDesc, error) {
+	entries, err := ioutil.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	var fds []storage.FileDesc
+	for _, entry := range entries {
+		if fd, ok := parseName(entry.Name()); ok && fd.Type&ft != 0 {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, nil
+}
+
+// Open opens the given file for reading.
+func (s *sharedStorage) Open(fd storage.FileDesc) (storage.Reader, error) {
+	f, err := os.Open(filepath.Join(s.path, genName(fd)))
+	if os.IsNotExist(err) && fd.Type == storage.TypeTable {
+		f, err = os.Open(filepath.Join(s.path, fmt.Sprintf("%06d.sst", fd.Num)))
+	}
+	if os.IsNotExist(err) {
+		return nil, os.ErrNotExist
+	}
+	return f, err
+}
+
+// genName returns the file name of a database file, as used by leveldb.
+func genName(fd storage.FileDesc) string {
+	switch fd.Type {
+	case storage.TypeManifest:
+		return fmt.Sprintf("MANIFEST-%06d", fd.Num)
+	case storage.TypeJournal:
+		return fmt.Sprintf("%06d.log", fd.Num)
+	case storage.TypeTable:
+		return fmt.Sprintf("%06d.ldb", fd.Num)
+	default:
+		return fmt.Sprintf("%06d.tmp", fd.Num)
+	}
+}
+
+// parseName parses the file name of a database file, as generated by leveldb.
+func parseName(name string) (fd storage.FileDesc, ok bool) {
+	var tail string
+	if _, err := fmt.Sscanf(name, "%d.%s", &fd.Num, &tail); err == nil {
+		switch tail {
+		case "log":
+			fd.Type = storage.TypeJournal
+		case "ldb", "sst":
+			fd.Type = storage.TypeTable
+		case "tmp":
+			fd.Type = storage.TypeTemp
+		default:
+			return fd, false
+		}
+		return fd, true
+	}
+	if n, _ := fmt.Sscanf(name, "MANIFEST-%d%s", &fd.Num, &tail); n == 1 {
+		fd.Type = storage.TypeManifest
+		return fd, true
+	}
+	return fd, false
+}
@@ -0,0 +1,31 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pebble
+
+// WAL modes selecting how the write-ahead log backs the writes.
+const (
+	WALSync  = "sync"  // Every write is synced to disk before returning
+	WALAsync = "async" // Writes are logged, but syncing is left to the OS
+	WALOff   = "off"   // No log is kept, writes not yet flushed are lost on a crash
+)
+
+// Config contains the pebble specific tuning options. The zero value is the
+// default configuration.
+type Config struct {
+	Compactions int    // Maximum number of concurrent compactions, the number of CPUs if zero
+	WAL         string // Write-ahead log mode, WALSync if empty
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build pebble && (arm64 || amd64) && !openbsd
+// +build pebble
+// +build arm64 amd64
+// +build !openbsd
+
+package pebble
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+)
+
+func TestPebbleDB(t *testing.T) {
+	for _, wal := range []string{WALSync, WALAsync, WALOff} {
+		t.Run("DatabaseSuite/"+wal, func(t *testing.T) {
+			dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+				db, err := New(t.TempDir(), 16, 16, "", false, Config{Compactions: 2, WAL: wal})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return db
+			})
+		})
+	}
+}
+
+func TestPebbleInvalidWAL(t *testing.T) {
+	if _, err := New(t.TempDir(), 16, 16, "", false, Config{WAL: "sometimes"}); err == nil {
+		t.Fatal("invalid WAL mode accepted")
+	}
+}
+
+func TestPebbleShared(t *testing.T) {
+	dir := t.TempDir()
+	db, err := New(dir, 16, 16, "", false, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Flush some data into tables and leave some in the write-ahead log
+	for i := 0; i < 100; i++ {
+		if err := db.Put([]byte{0x01, byte(i)}, []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put([]byte{0x02}, []byte{0x02}); err != nil {
+		t.Fatal(err)
+	}
+	// Open a shared view while the writer holds the lock
+	shared, err := NewShared(dir, 16, 16, "")
+	if err != nil {
+		t.Fatalf("failed to open shared database: %v", err)
+	}
+	defer shared.Close()
+
+	for i := 0; i < 100; i++ {
+		if val, err := shared.Get([]byte{0x01, byte(i)}); err != nil || len(val) != 1 || val[0] != byte(i) {
+			t.Fatalf("item %d mismatch: have %x, err %v", i, val, err)
+		}
+	}
+	if val, err := shared.Get([]byte{0x02}); err != nil || len(val) != 1 || val[0] != 0x02 {
+		t.Fatalf("logged item mismatch: have %x, err %v", val, err)
+	}
+	if err := shared.Put([]byte{0x03}, []byte{0x03}); err == nil {
+		t.Fatal("write to shared database succeeded")
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package remotedb implements a read-only key-value database layer served by a
+// remote node over its debug RPC API.
+package remotedb
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errReadOnly is returned for any modification of the remote database.
+var errReadOnly = errors.New("remote database is read-only")
+
+// errNotSupported is returned for the operations the debug API doesn't expose.
+var errNotSupported = errors.New("not supported by remote database")
+
+// ancientInfo is the ancient store description returned by debug_dbAncients.
+type ancientInfo struct {
+	Ancients uint64 `json:"ancients"`
+	Items    uint64 `json:"items"`
+	Offset   uint64 `json:"offset"`
+}
+
+// Database is a read-only key-value database retrieving all its content from
+// a remote node through the debug_dbGet, debug_dbAncient and debug_dbAncients
+// RPC methods. Iteration is not supported.
+type Database struct {
+	remote *rpc.Client
+}
+
+// New creates a database reading from the node behind the given RPC client.
+func New(client *rpc.Client) *Database {
+	return &Database{remote: client}
+}
+
+// Has retrieves if a key is present in the remote database.
+func (db *Database) Has(key []byte) (bool, error) {
+	if _, err := db.Get(key); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Get retrieves the given key from the remote database.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	var resp hexutil.Bytes
+	if err := db.remote.Call(&resp, "debug_dbGet", hexutil.Bytes(key)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// HasAncient returns an indicator whether the specified data exists in the
+// remote ancient store.
+func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
+	if _, err := db.Ancient(kind, number); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Ancient retrieves an ancient binary blob from the remote ancient store.
+func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
+	var resp hexutil.Bytes
+	if err := db.remote.Call(&resp, "debug_dbAncient", kind, number); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (db *Database) ancients() (*ancientInfo, error) {
+	var resp ancientInfo
+	if err := db.remote.Call(&resp, "debug_dbAncients"); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Ancients returns the number of the first block not in the remote ancient store.
+func (db *Database) Ancients() (uint64, error) {
+	info, err := db.ancients()
+	if err != nil {
+		return 0, err
+	}
+	return info.Ancients, nil
+}
+
+// ItemAmountInAncient returns the number of blocks kept in the remote ancient store.
+func (db *Database) ItemAmountInAncient() (uint64, error) {
+	info, err := db.ancients()
+	if err != nil {
+		return 0, err
+	}
+	return info.Items, nil
+}
+
+// AncientOffSet returns the number of the first block kept in the remote ancient
+// store, zero if it can't be retrieved.
+func (db *Database) AncientOffSet() uint64 {
+	info, err := db.ancients()
+	if err != nil {
+		return 0
+	}
+	return info.Offset
+}
+
+// AncientSize is not supported by the remote database.
+func (db *Database) AncientSize(kind string) (uint64, error) {
+	return 0, errNotSupported
+}
+
+// Put is not supported by the read-only remote database.
+func (db *Database) Put(key []byte, value []byte) error {
+	return errReadOnly
+}
+
+// Delete is not supported by the read-only remote database.
+func (db *Database) Delete(key []byte) error {
+	return errReadOnly
+}
+
+// AppendAncient is not supported by the read-only remote database.
+func (db *Database) AppendAncient(number uint64, hash, header, body, receipts, td []byte) error {
+	return errReadOnly
+}
+
+// TruncateAncients is not supported by the read-only remote database.
+func (db *Database) TruncateAncients(n uint64) error {
+	return errReadOnly
+}
+
+// Sync is a noop, the remote database is never modified.
+func (db *Database) Sync() error {
+	return nil
+}
+
+// DiffStore returns nil, the remote database has no diff store.
+func (db *Database) DiffStore() ethdb.KeyValueStore {
+	return nil
+}
+
+// SetDiffStore is a noop, the remote database has no diff store.
+func (db *Database) SetDiffStore(diff ethdb.KeyValueStore) {}
+
+// NewBatch creates a batch failing to be written, as the remote database is
+// read-only.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{Batch: memorydb.New().NewBatch()}
+}
+
+// NewIterator returns an iterator yielding no items, as iteration is not
+// supported by the remote database.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	return new(errIterator)
+}
+
+// Stat is not supported by the remote database.
+func (db *Database) Stat(property string) (string, error) {
+	return "", errNotSupported
+}
+
+// Compact is not supported by the read-only remote database.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	return errReadOnly
+}
+
+// Close closes the connection to the remote node.
+func (db *Database) Close() error {
+	db.remote.Close()
+	return nil
+}
+
+// batch is a write-only batch which can't be written to the read-only remote
+// database.
+type batch struct {
+	ethdb.Batch
+}
+
+// Write fails, as the remote database is read-only.
+func (b *batch) Write() error {
+	return errReadOnly
+}
+
+// errIterator is an iterator failing with errNotSupported.
+type errIterator struct{}
+
+func (it *errIterator) Next() bool    { return false }
+func (it *errIterator) Error() error  { return errNotSupported }
+func (it *errIterator) Key() []byte   { return nil }
+func (it *errIterator) Value() []byte { return nil }
+func (it *errIterator) Release()      {}
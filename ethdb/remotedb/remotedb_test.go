@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testDebugAPI serves the database debug methods from an in-memory store.
+type testDebugAPI struct {
+	db      *memorydb.Database
+	ancient map[uint64][]byte
+}
+
+func (api *testDebugAPI) DbGet(key hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.db.Get(key)
+}
+
+func (api *testDebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error) {
+	if blob, ok := api.ancient[number]; ok && kind == "headers" {
+		return blob, nil
+	}
+	return nil, errors.New("out of bounds")
+}
+
+func (api *testDebugAPI) DbAncients() (*ancientInfo, error) {
+	return &ancientInfo{Ancients: 12, Items: 2, Offset: 10}, nil
+}
+
+// Tests that the remote database serves reads over RPC and rejects writes.
+func TestRemoteDatabase(t *testing.T) {
+	api := &testDebugAPI{db: memorydb.New(), ancient: map[uint64][]byte{10: {0x0a}, 11: {0x0b}}}
+	api.db.Put([]byte("key"), []byte("value"))
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	defer server.Stop()
+
+	var db ethdb.Database = New(rpc.DialInProc(server))
+	defer db.Close()
+
+	if value, err := db.Get([]byte("key")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Errorf("value mismatch: have %x, %v, want %x", value, err, []byte("value"))
+	}
+	if ok, _ := db.Has([]byte("key")); !ok {
+		t.Error("existing key reported missing")
+	}
+	if ok, _ := db.Has([]byte("missing")); ok {
+		t.Error("missing key reported existing")
+	}
+	if blob, err := db.Ancient("headers", 11); err != nil || !bytes.Equal(blob, []byte{0x0b}) {
+		t.Errorf("ancient mismatch: have %x, %v, want 0b", blob, err)
+	}
+	if ok, _ := db.HasAncient("headers", 9); ok {
+		t.Error("missing ancient reported existing")
+	}
+	if n, err := db.Ancients(); err != nil || n != 12 {
+		t.Errorf("ancients mismatch: have %d, %v, want 12", n, err)
+	}
+	if n, err := db.ItemAmountInAncient(); err != nil || n != 2 {
+		t.Errorf("ancient items mismatch: have %d, %v, want 2", n, err)
+	}
+	if offset := db.AncientOffSet(); offset != 10 {
+		t.Errorf("ancient offset mismatch: have %d, want 10", offset)
+	}
+	if err := db.Put([]byte("key"), nil); err != errReadOnly {
+		t.Errorf("put error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte("key"), nil)
+	if err := batch.Write(); err != errReadOnly {
+		t.Errorf("batch write error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	it := db.NewIterator(nil, nil)
+	if it.Next() || it.Error() == nil {
+		t.Error("iteration not rejected")
+	}
+	it.Release()
+}
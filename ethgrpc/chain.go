@@ -0,0 +1,250 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/ethgrpc/pb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chainHeadChanSize is the size of the channel buffering new heads for a stream.
+const chainHeadChanSize = 16
+
+// chainServer implements the Chain service.
+type chainServer struct {
+	pb.UnimplementedChainServer
+	backend ethapi.Backend
+}
+
+func (s *chainServer) header(ctx context.Context, ref *pb.BlockRef) (*types.Header, error) {
+	blockNrOrHash, err := blockNumberOrHash(ref)
+	if err != nil {
+		return nil, err
+	}
+	header, err := s.backend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if header == nil {
+		return nil, status.Error(codes.NotFound, "block not found")
+	}
+	return header, nil
+}
+
+func (s *chainServer) GetHeader(ctx context.Context, req *pb.BlockRequest) (*pb.Header, error) {
+	header, err := s.header(ctx, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	return marshalHeader(header)
+}
+
+func (s *chainServer) GetBlock(ctx context.Context, req *pb.BlockRequest) (*pb.Block, error) {
+	blockNrOrHash, err := blockNumberOrHash(req.Block)
+	if err != nil {
+		return nil, err
+	}
+	block, err := s.backend.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if block == nil {
+		return nil, status.Error(codes.NotFound, "block not found")
+	}
+	header, err := marshalHeader(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	result := &pb.Block{Header: header}
+	for _, tx := range block.Transactions() {
+		result.TransactionHashes = append(result.TransactionHashes, tx.Hash().Bytes())
+	}
+	for _, uncle := range block.Uncles() {
+		result.UncleHashes = append(result.UncleHashes, uncle.Hash().Bytes())
+	}
+	if req.FullTransactions {
+		signer := types.MakeSigner(s.backend.ChainConfig(), block.Number())
+		for i, tx := range block.Transactions() {
+			t, err := marshalTransaction(tx, signer, block.Hash(), block.NumberU64(), uint64(i))
+			if err != nil {
+				return nil, err
+			}
+			result.Transactions = append(result.Transactions, t)
+		}
+	}
+	return result, nil
+}
+
+func (s *chainServer) GetTransaction(ctx context.Context, req *pb.TransactionRequest) (*pb.Transaction, error) {
+	hash, err := toHash(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	tx, blockHash, blockNumber, index, err := s.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if tx != nil {
+		signer := types.MakeSigner(s.backend.ChainConfig(), new(big.Int).SetUint64(blockNumber))
+		return marshalTransaction(tx, signer, blockHash, blockNumber, index)
+	}
+	// Fall back to the pending transactions of the pool.
+	if tx = s.backend.GetPoolTransaction(hash); tx != nil {
+		signer := types.LatestSigner(s.backend.ChainConfig())
+		return marshalTransaction(tx, signer, common.Hash{}, 0, 0)
+	}
+	return nil, status.Error(codes.NotFound, "transaction not found")
+}
+
+func (s *chainServer) GetReceipt(ctx context.Context, req *pb.TransactionRequest) (*pb.Receipt, error) {
+	hash, err := toHash(req.Hash)
+	if err != nil {
+		return nil, err
+	}
+	tx, blockHash, _, index, err := s.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if tx == nil {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+	receipts, err := s.backend.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if uint64(len(receipts)) <= index {
+		return nil, status.Error(codes.NotFound, "receipt not found")
+	}
+	return marshalReceipt(receipts[index]), nil
+}
+
+func (s *chainServer) GetBlockReceipts(ctx context.Context, req *pb.BlockRequest) (*pb.Receipts, error) {
+	header, err := s.header(ctx, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.backend.GetReceipts(ctx, header.Hash())
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	result := &pb.Receipts{Receipts: make([]*pb.Receipt, len(receipts))}
+	for i, receipt := range receipts {
+		result.Receipts[i] = marshalReceipt(receipt)
+	}
+	return result, nil
+}
+
+func (s *chainServer) GetLogs(ctx context.Context, req *pb.LogFilter) (*pb.Logs, error) {
+	addresses := make([]common.Address, len(req.Addresses))
+	for i, address := range req.Addresses {
+		var err error
+		if addresses[i], err = toAddress(address); err != nil {
+			return nil, err
+		}
+	}
+	topics := make([][]common.Hash, len(req.Topics))
+	for i, alternatives := range req.Topics {
+		for _, topic := range alternatives.Topics {
+			hash, err := toHash(topic)
+			if err != nil {
+				return nil, err
+			}
+			topics[i] = append(topics[i], hash)
+		}
+	}
+	var filter *filters.Filter
+	if len(req.BlockHash) > 0 {
+		if req.FromBlock != nil || req.ToBlock != nil {
+			return nil, status.Error(codes.InvalidArgument, "block hash and block range are mutually exclusive")
+		}
+		hash, err := toHash(req.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		filter = filters.NewBlockFilter(s.backend, hash, addresses, topics)
+	} else {
+		begin, err := s.rangeBound(ctx, req.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		end, err := s.rangeBound(ctx, req.ToBlock)
+		if err != nil {
+			return nil, err
+		}
+		filter = filters.NewRangeFilter(s.backend, begin, end, addresses, topics, false)
+	}
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pb.Logs{Logs: marshalLogs(logs)}, nil
+}
+
+// rangeBound resolves a block reference to a bound of a log filter range.
+func (s *chainServer) rangeBound(ctx context.Context, ref *pb.BlockRef) (int64, error) {
+	blockNrOrHash, err := blockNumberOrHash(ref)
+	if err != nil {
+		return 0, err
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		if number == rpc.EarliestBlockNumber {
+			return 0, nil
+		}
+		return number.Int64(), nil
+	}
+	header, err := s.header(ctx, ref)
+	if err != nil {
+		return 0, err
+	}
+	return header.Number.Int64(), nil
+}
+
+func (s *chainServer) SubscribeNewHeads(req *pb.SubscribeNewHeadsRequest, stream pb.Chain_SubscribeNewHeadsServer) error {
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.backend.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			header, err := marshalHeader(ev.Block.Header())
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(header); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			if err == nil {
+				return status.Error(codes.Unavailable, "subscription closed")
+			}
+			return status.Error(codes.Unavailable, err.Error())
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
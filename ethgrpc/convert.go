@@ -0,0 +1,173 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc
+
+import (
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethgrpc/pb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockNumberOrHash converts a block reference to its RPC form.
+func blockNumberOrHash(ref *pb.BlockRef) (rpc.BlockNumberOrHash, error) {
+	switch ref := ref.GetRef().(type) {
+	case nil:
+		return rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil
+	case *pb.BlockRef_Number:
+		if ref.Number > math.MaxInt64 {
+			return rpc.BlockNumberOrHash{}, status.Errorf(codes.InvalidArgument, "block number %d out of range", ref.Number)
+		}
+		return rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(ref.Number)), nil
+	case *pb.BlockRef_Hash:
+		hash, err := toHash(ref.Hash)
+		if err != nil {
+			return rpc.BlockNumberOrHash{}, err
+		}
+		return rpc.BlockNumberOrHashWithHash(hash, false), nil
+	case *pb.BlockRef_Tag:
+		number, err := blockTag(ref.Tag)
+		if err != nil {
+			return rpc.BlockNumberOrHash{}, err
+		}
+		return rpc.BlockNumberOrHashWithNumber(number), nil
+	default:
+		return rpc.BlockNumberOrHash{}, status.Error(codes.InvalidArgument, "unknown block reference")
+	}
+}
+
+// blockTag converts a block tag to the block number it stands for.
+func blockTag(tag pb.BlockTag) (rpc.BlockNumber, error) {
+	switch tag {
+	case pb.BlockTag_LATEST:
+		return rpc.LatestBlockNumber, nil
+	case pb.BlockTag_PENDING:
+		return rpc.PendingBlockNumber, nil
+	case pb.BlockTag_EARLIEST:
+		return rpc.EarliestBlockNumber, nil
+	default:
+		return 0, status.Errorf(codes.InvalidArgument, "unknown block tag %v", tag)
+	}
+}
+
+func toHash(b []byte) (common.Hash, error) {
+	if len(b) != common.HashLength {
+		return common.Hash{}, status.Errorf(codes.InvalidArgument, "invalid hash length %d", len(b))
+	}
+	return common.BytesToHash(b), nil
+}
+
+func toAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, status.Errorf(codes.InvalidArgument, "invalid address length %d", len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
+
+func marshalHeader(header *types.Header) (*pb.Header, error) {
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.Header{
+		Hash:       header.Hash().Bytes(),
+		Number:     header.Number.Uint64(),
+		ParentHash: header.ParentHash.Bytes(),
+		Coinbase:   header.Coinbase.Bytes(),
+		StateRoot:  header.Root.Bytes(),
+		GasLimit:   header.GasLimit,
+		GasUsed:    header.GasUsed,
+		Time:       header.Time,
+		Rlp:        enc,
+	}, nil
+}
+
+// marshalTransaction converts a transaction included at the given position. The
+// block hash is zero for pending transactions.
+func marshalTransaction(tx *types.Transaction, signer types.Signer, blockHash common.Hash, blockNumber, index uint64) (*pb.Transaction, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	t := &pb.Transaction{
+		Hash: tx.Hash().Bytes(),
+		From: from.Bytes(),
+		Raw:  raw,
+	}
+	if blockHash != (common.Hash{}) {
+		t.BlockHash = blockHash.Bytes()
+		t.BlockNumber = blockNumber
+		t.Index = index
+	}
+	return t, nil
+}
+
+func marshalLog(log *types.Log) *pb.Log {
+	topics := make([][]byte, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = topic.Bytes()
+	}
+	return &pb.Log{
+		Address:          log.Address.Bytes(),
+		Topics:           topics,
+		Data:             log.Data,
+		BlockNumber:      log.BlockNumber,
+		BlockHash:        log.BlockHash.Bytes(),
+		TransactionHash:  log.TxHash.Bytes(),
+		TransactionIndex: uint64(log.TxIndex),
+		Index:            uint64(log.Index),
+		Removed:          log.Removed,
+	}
+}
+
+func marshalLogs(logs []*types.Log) []*pb.Log {
+	result := make([]*pb.Log, len(logs))
+	for i, log := range logs {
+		result[i] = marshalLog(log)
+	}
+	return result
+}
+
+func marshalReceipt(receipt *types.Receipt) *pb.Receipt {
+	r := &pb.Receipt{
+		TransactionHash:   receipt.TxHash.Bytes(),
+		TransactionIndex:  uint64(receipt.TransactionIndex),
+		BlockHash:         receipt.BlockHash.Bytes(),
+		Type:              uint64(receipt.Type),
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		GasUsed:           receipt.GasUsed,
+		LogsBloom:         receipt.Bloom.Bytes(),
+		Logs:              marshalLogs(receipt.Logs),
+	}
+	if receipt.BlockNumber != nil {
+		r.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		r.ContractAddress = receipt.ContractAddress.Bytes()
+	}
+	return r
+}
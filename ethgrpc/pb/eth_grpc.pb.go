@@ -0,0 +1,541 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ChainClient is the client API for Chain service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ChainClient interface {
+	GetHeader(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Header, error)
+	GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error)
+	GetTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	GetReceipt(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Receipt, error)
+	GetBlockReceipts(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Receipts, error)
+	GetLogs(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (*Logs, error)
+	// SubscribeNewHeads streams the headers of new chain heads.
+	SubscribeNewHeads(ctx context.Context, in *SubscribeNewHeadsRequest, opts ...grpc.CallOption) (Chain_SubscribeNewHeadsClient, error)
+}
+
+type chainClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChainClient(cc grpc.ClientConnInterface) ChainClient {
+	return &chainClient{cc}
+}
+
+func (c *chainClient) GetHeader(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Header, error) {
+	out := new(Header)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetHeader", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) GetBlock(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetBlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) GetTransaction(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) GetReceipt(ctx context.Context, in *TransactionRequest, opts ...grpc.CallOption) (*Receipt, error) {
+	out := new(Receipt)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetReceipt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) GetBlockReceipts(ctx context.Context, in *BlockRequest, opts ...grpc.CallOption) (*Receipts, error) {
+	out := new(Receipts)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetBlockReceipts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) GetLogs(ctx context.Context, in *LogFilter, opts ...grpc.CallOption) (*Logs, error) {
+	out := new(Logs)
+	err := c.cc.Invoke(ctx, "/ethgrpc.Chain/GetLogs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chainClient) SubscribeNewHeads(ctx context.Context, in *SubscribeNewHeadsRequest, opts ...grpc.CallOption) (Chain_SubscribeNewHeadsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Chain_ServiceDesc.Streams[0], "/ethgrpc.Chain/SubscribeNewHeads", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chainSubscribeNewHeadsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Chain_SubscribeNewHeadsClient interface {
+	Recv() (*Header, error)
+	grpc.ClientStream
+}
+
+type chainSubscribeNewHeadsClient struct {
+	grpc.ClientStream
+}
+
+func (x *chainSubscribeNewHeadsClient) Recv() (*Header, error) {
+	m := new(Header)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ChainServer is the server API for Chain service.
+// All implementations must embed UnimplementedChainServer
+// for forward compatibility
+type ChainServer interface {
+	GetHeader(context.Context, *BlockRequest) (*Header, error)
+	GetBlock(context.Context, *BlockRequest) (*Block, error)
+	GetTransaction(context.Context, *TransactionRequest) (*Transaction, error)
+	GetReceipt(context.Context, *TransactionRequest) (*Receipt, error)
+	GetBlockReceipts(context.Context, *BlockRequest) (*Receipts, error)
+	GetLogs(context.Context, *LogFilter) (*Logs, error)
+	// SubscribeNewHeads streams the headers of new chain heads.
+	SubscribeNewHeads(*SubscribeNewHeadsRequest, Chain_SubscribeNewHeadsServer) error
+	mustEmbedUnimplementedChainServer()
+}
+
+// UnimplementedChainServer must be embedded to have forward compatible implementations.
+type UnimplementedChainServer struct {
+}
+
+func (UnimplementedChainServer) GetHeader(context.Context, *BlockRequest) (*Header, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHeader not implemented")
+}
+func (UnimplementedChainServer) GetBlock(context.Context, *BlockRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlock not implemented")
+}
+func (UnimplementedChainServer) GetTransaction(context.Context, *TransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedChainServer) GetReceipt(context.Context, *TransactionRequest) (*Receipt, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReceipt not implemented")
+}
+func (UnimplementedChainServer) GetBlockReceipts(context.Context, *BlockRequest) (*Receipts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlockReceipts not implemented")
+}
+func (UnimplementedChainServer) GetLogs(context.Context, *LogFilter) (*Logs, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogs not implemented")
+}
+func (UnimplementedChainServer) SubscribeNewHeads(*SubscribeNewHeadsRequest, Chain_SubscribeNewHeadsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeNewHeads not implemented")
+}
+func (UnimplementedChainServer) mustEmbedUnimplementedChainServer() {}
+
+// UnsafeChainServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChainServer will
+// result in compilation errors.
+type UnsafeChainServer interface {
+	mustEmbedUnimplementedChainServer()
+}
+
+func RegisterChainServer(s grpc.ServiceRegistrar, srv ChainServer) {
+	s.RegisterService(&Chain_ServiceDesc, srv)
+}
+
+func _Chain_GetHeader_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetHeader(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetHeader",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetHeader(ctx, req.(*BlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetBlock(ctx, req.(*BlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetTransaction(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_GetReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetReceipt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetReceipt(ctx, req.(*TransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_GetBlockReceipts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetBlockReceipts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetBlockReceipts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetBlockReceipts(ctx, req.(*BlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_GetLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChainServer).GetLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.Chain/GetLogs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChainServer).GetLogs(ctx, req.(*LogFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Chain_SubscribeNewHeads_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeNewHeadsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChainServer).SubscribeNewHeads(m, &chainSubscribeNewHeadsServer{stream})
+}
+
+type Chain_SubscribeNewHeadsServer interface {
+	Send(*Header) error
+	grpc.ServerStream
+}
+
+type chainSubscribeNewHeadsServer struct {
+	grpc.ServerStream
+}
+
+func (x *chainSubscribeNewHeadsServer) Send(m *Header) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Chain_ServiceDesc is the grpc.ServiceDesc for Chain service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Chain_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ethgrpc.Chain",
+	HandlerType: (*ChainServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetHeader",
+			Handler:    _Chain_GetHeader_Handler,
+		},
+		{
+			MethodName: "GetBlock",
+			Handler:    _Chain_GetBlock_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Chain_GetTransaction_Handler,
+		},
+		{
+			MethodName: "GetReceipt",
+			Handler:    _Chain_GetReceipt_Handler,
+		},
+		{
+			MethodName: "GetBlockReceipts",
+			Handler:    _Chain_GetBlockReceipts_Handler,
+		},
+		{
+			MethodName: "GetLogs",
+			Handler:    _Chain_GetLogs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeNewHeads",
+			Handler:       _Chain_SubscribeNewHeads_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "eth.proto",
+}
+
+// StateClient is the client API for State service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type StateClient interface {
+	GetBalance(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Quantity, error)
+	GetNonce(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Nonce, error)
+	GetCode(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Code, error)
+	GetStorageAt(ctx context.Context, in *StorageRequest, opts ...grpc.CallOption) (*StorageValue, error)
+}
+
+type stateClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStateClient(cc grpc.ClientConnInterface) StateClient {
+	return &stateClient{cc}
+}
+
+func (c *stateClient) GetBalance(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Quantity, error) {
+	out := new(Quantity)
+	err := c.cc.Invoke(ctx, "/ethgrpc.State/GetBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stateClient) GetNonce(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Nonce, error) {
+	out := new(Nonce)
+	err := c.cc.Invoke(ctx, "/ethgrpc.State/GetNonce", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stateClient) GetCode(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*Code, error) {
+	out := new(Code)
+	err := c.cc.Invoke(ctx, "/ethgrpc.State/GetCode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stateClient) GetStorageAt(ctx context.Context, in *StorageRequest, opts ...grpc.CallOption) (*StorageValue, error) {
+	out := new(StorageValue)
+	err := c.cc.Invoke(ctx, "/ethgrpc.State/GetStorageAt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StateServer is the server API for State service.
+// All implementations must embed UnimplementedStateServer
+// for forward compatibility
+type StateServer interface {
+	GetBalance(context.Context, *AccountRequest) (*Quantity, error)
+	GetNonce(context.Context, *AccountRequest) (*Nonce, error)
+	GetCode(context.Context, *AccountRequest) (*Code, error)
+	GetStorageAt(context.Context, *StorageRequest) (*StorageValue, error)
+	mustEmbedUnimplementedStateServer()
+}
+
+// UnimplementedStateServer must be embedded to have forward compatible implementations.
+type UnimplementedStateServer struct {
+}
+
+func (UnimplementedStateServer) GetBalance(context.Context, *AccountRequest) (*Quantity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedStateServer) GetNonce(context.Context, *AccountRequest) (*Nonce, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNonce not implemented")
+}
+func (UnimplementedStateServer) GetCode(context.Context, *AccountRequest) (*Code, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCode not implemented")
+}
+func (UnimplementedStateServer) GetStorageAt(context.Context, *StorageRequest) (*StorageValue, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStorageAt not implemented")
+}
+func (UnimplementedStateServer) mustEmbedUnimplementedStateServer() {}
+
+// UnsafeStateServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StateServer will
+// result in compilation errors.
+type UnsafeStateServer interface {
+	mustEmbedUnimplementedStateServer()
+}
+
+func RegisterStateServer(s grpc.ServiceRegistrar, srv StateServer) {
+	s.RegisterService(&State_ServiceDesc, srv)
+}
+
+func _State_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.State/GetBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServer).GetBalance(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _State_GetNonce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServer).GetNonce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.State/GetNonce",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServer).GetNonce(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _State_GetCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServer).GetCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.State/GetCode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServer).GetCode(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _State_GetStorageAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StateServer).GetStorageAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/ethgrpc.State/GetStorageAt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StateServer).GetStorageAt(ctx, req.(*StorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// State_ServiceDesc is the grpc.ServiceDesc for State service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var State_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ethgrpc.State",
+	HandlerType: (*StateServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBalance",
+			Handler:    _State_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetNonce",
+			Handler:    _State_GetNonce_Handler,
+		},
+		{
+			MethodName: "GetCode",
+			Handler:    _State_GetCode_Handler,
+		},
+		{
+			MethodName: "GetStorageAt",
+			Handler:    _State_GetStorageAt_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "eth.proto",
+}
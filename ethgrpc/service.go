@@ -0,0 +1,85 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethgrpc implements a gRPC server serving blocks, transactions, receipts,
+// logs and state of the chain.
+package ethgrpc
+
+//go:generate protoc --proto_path=pb --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative eth.proto
+
+import (
+	"net"
+
+	"github.com/ethereum/go-ethereum/ethgrpc/pb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"google.golang.org/grpc"
+)
+
+// Service is a node lifecycle running the gRPC server.
+type Service struct {
+	backend  ethapi.Backend
+	endpoint string
+
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// New constructs a gRPC service listening on the given endpoint and registers it
+// with the node.
+func New(stack *node.Node, backend ethapi.Backend, endpoint string) (*Service, error) {
+	if backend == nil {
+		panic("missing backend")
+	}
+	s := &Service{backend: backend, endpoint: endpoint}
+	stack.RegisterLifecycle(s)
+	return s, nil
+}
+
+// Start implements node.Lifecycle, starting the gRPC server.
+func (s *Service) Start() error {
+	listener, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = grpc.NewServer()
+	pb.RegisterChainServer(s.server, &chainServer{backend: s.backend})
+	pb.RegisterStateServer(s.server, &stateServer{backend: s.backend})
+
+	go s.server.Serve(listener)
+	log.Info("gRPC endpoint opened", "addr", listener.Addr())
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the gRPC server and all open
+// streams.
+func (s *Service) Stop() error {
+	if s.server != nil {
+		s.server.Stop()
+		log.Info("gRPC endpoint closed", "addr", s.listener.Addr())
+	}
+	return nil
+}
+
+// Addr returns the address the server is listening on, or nil if it isn't running.
+func (s *Service) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
@@ -0,0 +1,222 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethgrpc/pb"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testDest    = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+)
+
+// newTestService creates a node with a short chain, the third block of which
+// contains a transfer from the test account, and starts a gRPC service on it.
+func newTestService(t *testing.T) (*node.Node, *eth.Ethereum, *Service, *types.Transaction) {
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	ethConf := &ethconfig.Config{
+		Genesis: &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc:      core.GenesisAlloc{testAddress: {Balance: big.NewInt(1000000000)}},
+		},
+		Ethash:                  ethash.Config{PowMode: ethash.ModeFake},
+		NetworkId:               1337,
+		TrieCleanCache:          5,
+		TrieCleanCacheRejournal: 60 * time.Minute,
+		TrieDirtyCache:          5,
+		TrieTimeout:             60 * time.Minute,
+		SnapshotCache:           5,
+	}
+	ethBackend, err := eth.New(stack, ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	signer := types.LatestSigner(ethConf.Genesis.Config)
+	tx, _ := types.SignNewTx(testKey, signer, &types.LegacyTx{
+		To:       &testDest,
+		Value:    big.NewInt(100),
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(),
+		ethash.NewFaker(), ethBackend.ChainDb(), 5, func(i int, gen *core.BlockGen) {
+			if i == 2 {
+				gen.AddTx(tx)
+			}
+		})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	service, err := New(stack, ethBackend.APIBackend, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not create grpc service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	return stack, ethBackend, service, tx
+}
+
+func TestService(t *testing.T) {
+	stack, _, service, tx := newTestService(t)
+	defer stack.Close()
+
+	conn, err := grpc.Dial(service.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var (
+		ctx   = context.Background()
+		chain = pb.NewChainClient(conn)
+		state = pb.NewStateClient(conn)
+	)
+	// Blocks can be selected by number, hash and tag.
+	header, err := chain.GetHeader(ctx, &pb.BlockRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Number != 5 {
+		t.Fatalf("latest block number mismatch: have %d, want 5", header.Number)
+	}
+	block, err := chain.GetBlock(ctx, &pb.BlockRequest{
+		Block:            &pb.BlockRef{Ref: &pb.BlockRef_Hash{Hash: header.ParentHash}},
+		FullTransactions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Header.Number != 4 {
+		t.Fatalf("parent block number mismatch: have %d, want 4", block.Header.Number)
+	}
+	block, err = chain.GetBlock(ctx, &pb.BlockRequest{
+		Block:            &pb.BlockRef{Ref: &pb.BlockRef_Number{Number: 3}},
+		FullTransactions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(block.Transactions) != 1 || common.BytesToAddress(block.Transactions[0].From) != testAddress {
+		t.Fatalf("unexpected transactions in block 3: %v", block.Transactions)
+	}
+	if _, err := chain.GetHeader(ctx, &pb.BlockRequest{Block: &pb.BlockRef{Ref: &pb.BlockRef_Number{Number: 100}}}); status.Code(err) != codes.NotFound {
+		t.Fatalf("missing block error mismatch: have %v, want NotFound", err)
+	}
+	// Transactions and receipts are looked up by hash.
+	txn, err := chain.GetTransaction(ctx, &pb.TransactionRequest{Hash: tx.Hash().Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.BlockNumber != 3 {
+		t.Fatalf("transaction block number mismatch: have %d, want 3", txn.BlockNumber)
+	}
+	receipt, err := chain.GetReceipt(ctx, &pb.TransactionRequest{Hash: tx.Hash().Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful || receipt.GasUsed != 21000 {
+		t.Fatalf("unexpected receipt: %v", receipt)
+	}
+	if _, err := chain.GetReceipt(ctx, &pb.TransactionRequest{Hash: []byte{1}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid hash error mismatch: have %v, want InvalidArgument", err)
+	}
+	// State reads default to the latest block.
+	balance, err := state.GetBalance(ctx, &pb.AccountRequest{Address: testDest.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(balance.Value).Int64() != 100 {
+		t.Fatalf("balance mismatch: have %x, want 100", balance.Value)
+	}
+	nonce, err := state.GetNonce(ctx, &pb.AccountRequest{
+		Address: testAddress.Bytes(),
+		Block:   &pb.BlockRef{Ref: &pb.BlockRef_Tag{Tag: pb.BlockTag_EARLIEST}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce.Nonce != 0 {
+		t.Fatalf("genesis nonce mismatch: have %d, want 0", nonce.Nonce)
+	}
+}
+
+func TestSubscribeNewHeads(t *testing.T) {
+	stack, ethBackend, service, _ := newTestService(t)
+	defer stack.Close()
+
+	conn, err := grpc.Dial(service.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := pb.NewChainClient(conn).SubscribeNewHeads(ctx, &pb.SubscribeNewHeadsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep extending the chain until the stream, which is established
+	// asynchronously, delivers a head.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		chain := ethBackend.BlockChain()
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+			blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, chain.CurrentBlock(),
+				ethash.NewFaker(), ethBackend.ChainDb(), 1, func(i int, gen *core.BlockGen) {})
+			chain.InsertChain(blocks)
+		}
+	}()
+	header, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head := ethBackend.BlockChain().GetHeaderByHash(common.BytesToHash(header.Hash)); head == nil || head.Number.Uint64() != header.Number || header.Number <= 5 {
+		t.Fatalf("unexpected head %d %x", header.Number, header.Hash)
+	}
+}
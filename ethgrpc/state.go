@@ -0,0 +1,90 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethgrpc
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethgrpc/pb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stateServer implements the State service.
+type stateServer struct {
+	pb.UnimplementedStateServer
+	backend ethapi.Backend
+}
+
+// account resolves the address and the state of the block selected by a request.
+func (s *stateServer) account(ctx context.Context, address []byte, ref *pb.BlockRef) (*state.StateDB, common.Address, error) {
+	addr, err := toAddress(address)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	blockNrOrHash, err := blockNumberOrHash(ref)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	statedb, header, err := s.backend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, common.Address{}, status.Error(codes.Unavailable, err.Error())
+	}
+	if statedb == nil || header == nil {
+		return nil, common.Address{}, status.Error(codes.NotFound, "block not found")
+	}
+	return statedb, addr, nil
+}
+
+func (s *stateServer) GetBalance(ctx context.Context, req *pb.AccountRequest) (*pb.Quantity, error) {
+	statedb, addr, err := s.account(ctx, req.Address, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Quantity{Value: statedb.GetBalance(addr).Bytes()}, nil
+}
+
+func (s *stateServer) GetNonce(ctx context.Context, req *pb.AccountRequest) (*pb.Nonce, error) {
+	statedb, addr, err := s.account(ctx, req.Address, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Nonce{Nonce: statedb.GetNonce(addr)}, nil
+}
+
+func (s *stateServer) GetCode(ctx context.Context, req *pb.AccountRequest) (*pb.Code, error) {
+	statedb, addr, err := s.account(ctx, req.Address, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Code{Code: statedb.GetCode(addr)}, nil
+}
+
+func (s *stateServer) GetStorageAt(ctx context.Context, req *pb.StorageRequest) (*pb.StorageValue, error) {
+	key, err := toHash(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	statedb, addr, err := s.account(ctx, req.Address, req.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StorageValue{Value: statedb.GetState(addr, key).Bytes()}, nil
+}
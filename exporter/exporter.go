@@ -0,0 +1,289 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package exporter publishes the canonical chain to a message broker.
+//
+// Every block which becomes canonical is published together with its receipts and
+// logs. When blocks which were already published are dropped from the canonical
+// chain, a reorg event naming them is published before the blocks replacing them.
+// The last published block is checkpointed in the database after the broker has
+// acknowledged it, so the exporter resumes where it left off after a restart and
+// every event is delivered at least once.
+package exporter
+
+//go:generate protoc --proto_path=pb --go_out=pb --go_opt=paths=source_relative exporter.proto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/exporter/pb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rlp"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// retryDelay is the time to wait before retrying a failed export.
+	retryDelay = 5 * time.Second
+
+	// publishTimeout is the maximum time to wait for the broker to acknowledge
+	// a single event.
+	publishTimeout = 30 * time.Second
+)
+
+var (
+	exportedBlockMeter = metrics.NewRegisteredMeter("exporter/blocks", nil)
+	exportedReorgMeter = metrics.NewRegisteredMeter("exporter/reorgs", nil)
+	exportFailureMeter = metrics.NewRegisteredMeter("exporter/failures", nil)
+	checkpointGauge    = metrics.NewRegisteredGauge("exporter/checkpoint", nil)
+)
+
+// blockChain is the chain access needed by the exporter, implemented by
+// core.BlockChain.
+type blockChain interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Exporter is a node lifecycle publishing the canonical chain to a sink.
+type Exporter struct {
+	chain blockChain
+	db    ethdb.KeyValueStore // database holding the checkpoint
+	sink  Sink
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates an exporter publishing to the sink at the given URL and registers
+// it with the node.
+func New(stack *node.Node, chain *core.BlockChain, db ethdb.KeyValueStore, url string) error {
+	sink, err := NewSink(url)
+	if err != nil {
+		return err
+	}
+	stack.RegisterLifecycle(newExporter(chain, db, sink))
+	return nil
+}
+
+func newExporter(chain blockChain, db ethdb.KeyValueStore, sink Sink) *Exporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Exporter{
+		chain:  chain,
+		db:     db,
+		sink:   sink,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start implements node.Lifecycle, starting the export loop.
+func (e *Exporter) Start() error {
+	e.wg.Add(1)
+	go e.loop()
+	log.Info("Started chain data exporter")
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the export loop and closing the sink.
+func (e *Exporter) Stop() error {
+	e.cancel()
+	e.wg.Wait()
+	log.Info("Stopped chain data exporter")
+	return e.sink.Close()
+}
+
+// loop exports the canonical chain every time the head changes. Failed exports
+// are retried until they succeed.
+func (e *Exporter) loop() {
+	defer e.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := e.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		var retry <-chan time.Time
+		if err := e.export(); err != nil {
+			if e.ctx.Err() != nil {
+				return
+			}
+			exportFailureMeter.Mark(1)
+			log.Warn("Failed to export chain data", "err", err)
+			retry = time.After(retryDelay)
+		}
+		select {
+		case <-heads:
+		case <-retry:
+		case <-sub.Err():
+			return
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// export publishes the canonical blocks after the checkpoint up to the current
+// head, preceded by a reorg event if the checkpoint is no longer canonical.
+func (e *Exporter) export() error {
+	head := e.chain.CurrentBlock()
+
+	hash, number, ok := rawdb.ReadExporterCheckpoint(e.db)
+	if !ok {
+		// Nothing was published yet, start with the current head.
+		if err := e.publishBlock(head); err != nil {
+			return err
+		}
+		hash, number = head.Hash(), head.NumberU64()
+	}
+	if e.chain.GetCanonicalHash(number) != hash {
+		var err error
+		if hash, number, err = e.publishReorg(hash, number); err != nil {
+			return err
+		}
+	}
+	for n := number + 1; n <= head.NumberU64(); n++ {
+		block := e.chain.GetBlockByNumber(n)
+		if block == nil {
+			return fmt.Errorf("canonical block #%d missing", n)
+		}
+		if block.ParentHash() != hash {
+			return fmt.Errorf("chain reorganised while exporting block #%d", n)
+		}
+		if err := e.publishBlock(block); err != nil {
+			return err
+		}
+		hash = block.Hash()
+	}
+	return nil
+}
+
+// publishBlock publishes a canonical block and advances the checkpoint to it.
+func (e *Exporter) publishBlock(block *types.Block) error {
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return err
+	}
+	msg := &pb.Block{
+		Hash:       block.Hash().Bytes(),
+		Number:     block.NumberU64(),
+		ParentHash: block.ParentHash().Bytes(),
+		Time:       block.Time(),
+		Rlp:        enc,
+	}
+	for _, receipt := range e.chain.GetReceiptsByHash(block.Hash()) {
+		msg.Receipts = append(msg.Receipts, marshalReceipt(receipt))
+	}
+	id := fmt.Sprintf("block-%x", block.Hash())
+	if err := e.publish(id, &pb.Event{Event: &pb.Event_Block{Block: msg}}); err != nil {
+		return err
+	}
+	e.checkpoint(block.Hash(), block.NumberU64())
+	exportedBlockMeter.Mark(1)
+	return nil
+}
+
+// publishReorg publishes the published blocks which are no longer canonical and
+// rewinds the checkpoint to their last canonical ancestor.
+func (e *Exporter) publishReorg(hash common.Hash, number uint64) (common.Hash, uint64, error) {
+	var (
+		oldHead = hash
+		dropped [][]byte
+	)
+	for e.chain.GetCanonicalHash(number) != hash {
+		header := e.chain.GetHeader(hash, number)
+		if header == nil {
+			return common.Hash{}, 0, fmt.Errorf("exported block #%d [%x…] missing", number, hash[:4])
+		}
+		dropped = append(dropped, hash.Bytes())
+		hash, number = header.ParentHash, number-1
+	}
+	msg := &pb.Reorg{
+		AncestorHash:   hash.Bytes(),
+		AncestorNumber: number,
+		Dropped:        dropped,
+	}
+	id := fmt.Sprintf("reorg-%x-%x", oldHead, hash)
+	if err := e.publish(id, &pb.Event{Event: &pb.Event_Reorg{Reorg: msg}}); err != nil {
+		return common.Hash{}, 0, err
+	}
+	e.checkpoint(hash, number)
+	exportedReorgMeter.Mark(1)
+	log.Info("Exported chain reorg", "ancestor", number, "hash", hash, "dropped", len(dropped))
+	return hash, number, nil
+}
+
+// publish encodes an event and waits for the sink to acknowledge it.
+func (e *Exporter) publish(id string, event *pb.Event) error {
+	data, err := proto.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, publishTimeout)
+	defer cancel()
+	return e.sink.Publish(ctx, id, data)
+}
+
+func (e *Exporter) checkpoint(hash common.Hash, number uint64) {
+	rawdb.WriteExporterCheckpoint(e.db, hash, number)
+	checkpointGauge.Update(int64(number))
+}
+
+func marshalReceipt(receipt *types.Receipt) *pb.Receipt {
+	r := &pb.Receipt{
+		TransactionHash:   receipt.TxHash.Bytes(),
+		TransactionIndex:  uint64(receipt.TransactionIndex),
+		Type:              uint64(receipt.Type),
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		GasUsed:           receipt.GasUsed,
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		r.ContractAddress = receipt.ContractAddress.Bytes()
+	}
+	for _, log := range receipt.Logs {
+		topics := make([][]byte, len(log.Topics))
+		for i, topic := range log.Topics {
+			topics[i] = topic.Bytes()
+		}
+		r.Logs = append(r.Logs, &pb.Log{
+			Address: log.Address.Bytes(),
+			Topics:  topics,
+			Data:    log.Data,
+			Index:   uint64(log.Index),
+		})
+	}
+	return r
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/exporter/pb"
+	"github.com/ethereum/go-ethereum/params"
+	"google.golang.org/protobuf/proto"
+)
+
+// testSink records the published events and fails publications while broken.
+type testSink struct {
+	events []*pb.Event
+	broken bool
+}
+
+func (s *testSink) Publish(ctx context.Context, id string, data []byte) error {
+	if s.broken {
+		return errors.New("broker unavailable")
+	}
+	event := new(pb.Event)
+	if err := proto.Unmarshal(data, event); err != nil {
+		return err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *testSink) Close() error { return nil }
+
+// numbers summarises the published events: block numbers, and the negated
+// ancestor number for reorgs.
+func (s *testSink) numbers() []int64 {
+	var numbers []int64
+	for _, event := range s.events {
+		if block := event.GetBlock(); block != nil {
+			numbers = append(numbers, int64(block.Number))
+		} else {
+			numbers = append(numbers, -int64(event.GetReorg().AncestorNumber))
+		}
+	}
+	return numbers
+}
+
+func checkNumbers(t *testing.T, have, want []int64) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("published events mismatch: have %v, want %v", have, want)
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			t.Fatalf("published events mismatch: have %v, want %v", have, want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = (&core.Genesis{}).MustCommit(db)
+		engine  = ethash.NewFaker()
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 5, func(i int, gen *core.BlockGen) {})
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		sink     = new(testSink)
+		exporter = newExporter(chain, db, sink)
+	)
+	// Without a checkpoint, the export starts at the current head.
+	if err := exporter.export(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, sink.numbers(), []int64{2})
+
+	// Failed publications don't advance the checkpoint and are retried.
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatal(err)
+	}
+	sink.broken = true
+	if err := exporter.export(); err == nil {
+		t.Fatal("export succeeded with a broken sink")
+	}
+	sink.broken = false
+	if err := exporter.export(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, sink.numbers(), []int64{2, 3, 4, 5})
+
+	// A restarted exporter resumes at the checkpoint.
+	if err := newExporter(chain, db, sink).export(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, sink.numbers(), []int64{2, 3, 4, 5})
+
+	// A heavier fork replacing published blocks is preceded by a reorg event.
+	fork, _ := core.GenerateChain(params.TestChainConfig, blocks[1], engine, db, 5, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.export(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, sink.numbers(), []int64{2, 3, 4, 5, -2, 3, 4, 5, 6, 7})
+
+	reorg := sink.events[4].GetReorg()
+	if len(reorg.Dropped) != 3 || common.BytesToHash(reorg.Dropped[0]) != blocks[4].Hash() {
+		t.Fatalf("dropped blocks mismatch: %x", reorg.Dropped)
+	}
+	if common.BytesToHash(sink.events[9].GetBlock().Hash) != fork[4].Hash() {
+		t.Fatal("last published block is not the new head")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// eventIDHeader is the message header carrying the event id.
+const eventIDHeader = "Event-Id"
+
+// Sink publishes encoded events to a message broker.
+type Sink interface {
+	// Publish delivers an event and returns once the broker acknowledged it. The id
+	// is attached to the message so consumers can detect redelivered events.
+	Publish(ctx context.Context, id string, data []byte) error
+
+	// Close flushes and releases the connection to the broker.
+	Close() error
+}
+
+// NewSink creates a sink from a URL of the form
+//
+//	nats://host:port[,host:port]/subject
+//	kafka://host:port[,host:port]/topic
+func NewSink(url string) (Sink, error) {
+	parts := strings.SplitN(url, "://", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid exporter URL %q", url)
+	}
+	scheme, rest := parts[0], parts[1]
+
+	slash := strings.IndexByte(rest, '/')
+	if slash <= 0 || slash == len(rest)-1 {
+		return nil, fmt.Errorf("invalid exporter URL %q, want %s://host:port/destination", url, scheme)
+	}
+	hosts, dest := rest[:slash], rest[slash+1:]
+
+	switch scheme {
+	case "nats":
+		return newNATSSink(hosts, dest)
+	case "kafka":
+		return newKafkaSink(hosts, dest), nil
+	default:
+		return nil, fmt.Errorf("unsupported exporter scheme %q", scheme)
+	}
+}
+
+// natsSink publishes events to a NATS JetStream subject. The subject must be bound
+// to a stream for the publications to be acknowledged.
+type natsSink struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+func newNATSSink(hosts, subject string) (*natsSink, error) {
+	var urls []string
+	for _, host := range strings.Split(hosts, ",") {
+		urls = append(urls, "nats://"+host)
+	}
+	conn, err := nats.Connect(strings.Join(urls, ","), nats.Name("geth-exporter"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &natsSink{conn: conn, js: js, subject: subject}, nil
+}
+
+func (s *natsSink) Publish(ctx context.Context, id string, data []byte) error {
+	msg := &nats.Msg{
+		Subject: s.subject,
+		Data:    data,
+		Header:  nats.Header{eventIDHeader: []string{id}},
+	}
+	_, err := s.js.PublishMsg(msg, nats.Context(ctx))
+	return err
+}
+
+func (s *natsSink) Close() error {
+	return s.conn.Drain()
+}
+
+// kafkaPartitionKey is the key of all published messages. Using a single key maps
+// all events to the same partition, which retains their order.
+var kafkaPartitionKey = []byte("chain")
+
+// kafkaSink publishes events to a Kafka topic, waiting for all in-sync replicas to
+// acknowledge them.
+type kafkaSink struct {
+	writer *kafka.Writer
+}
+
+func newKafkaSink(hosts, topic string) *kafkaSink {
+	return &kafkaSink{writer: &kafka.Writer{
+		Addr:         kafka.TCP(strings.Split(hosts, ",")...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}}
+}
+
+func (s *kafkaSink) Publish(ctx context.Context, id string, data []byte) error {
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:     kafkaPartitionKey,
+		Value:   data,
+		Headers: []kafka.Header{{Key: eventIDHeader, Value: []byte(id)}},
+	})
+}
+
+func (s *kafkaSink) Close() error {
+	return s.writer.Close()
+}
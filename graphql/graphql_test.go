@@ -86,6 +86,11 @@ func TestGraphQLBlockSerialization(t *testing.T) {
 			want: `{"data":{"block":{"number":0,"gasUsed":0,"gasLimit":11500000}}}`,
 			code: 200,
 		},
+		{ // Should return no validator set without a PoSA engine
+			body: `{"query": "{block{number,validators}}","variables": null}`,
+			want: `{"data":{"block":{"number":10,"validators":null}}}`,
+			code: 200,
+		},
 		{
 			body: `{"query": "{block(number:-1){number,gasUsed,gasLimit}}","variables": null}`,
 			want: `{"data":{"block":null}}`,
@@ -134,7 +139,7 @@ func TestGraphQLBlockSerialization(t *testing.T) {
 		// should return `estimateGas` as decimal
 		{
 			body: `{"query": "{block{ estimateGas(data:{}) }}"}`,
-			want: `{"data":{"block":{"estimateGas":53000}}}`,
+			want: `{"data":{"block":{"estimateGas":53793}}}`,
 			code: 200,
 		},
 		// should return `status` as decimal
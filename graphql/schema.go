@@ -170,6 +170,10 @@ const schema string = `
         receiptsRoot: Bytes32!
         # Miner is the account that mined this block.
         miner(block: Long): Account!
+        # Validators is the set of validators authorized to seal the blocks
+        # following this one. If the consensus engine has no validator set,
+        # this field will be null.
+        validators: [Address!]
         # ExtraData is an arbitrary data field supplied by the miner.
         extraData: Bytes!
         # GasLimit is the maximum amount of gas that was available to transactions in this block.
@@ -49,18 +49,41 @@ type HandlerT struct {
 	cpuFile   string
 	traceW    io.WriteCloser
 	traceFile string
+	profiler  *profiler
+	verbosity int
+	vmodule   string
 }
 
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
 // and source files can be raised using Vmodule.
-func (*HandlerT) Verbosity(level int) {
+func (h *HandlerT) Verbosity(level int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	glogger.Verbosity(log.Lvl(level))
+	h.verbosity = level
 }
 
 // Vmodule sets the log verbosity pattern. See package log for details on the
 // pattern syntax.
-func (*HandlerT) Vmodule(pattern string) error {
-	return glogger.Vmodule(pattern)
+func (h *HandlerT) Vmodule(pattern string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := glogger.Vmodule(pattern); err != nil {
+		return err
+	}
+	h.vmodule = pattern
+	return nil
+}
+
+// LogSettings returns the log verbosity ceiling and verbosity pattern currently
+// in effect.
+func LogSettings() (int, string) {
+	Handler.mu.Lock()
+	defer Handler.mu.Unlock()
+
+	return Handler.verbosity, Handler.vmodule
 }
 
 // BacktraceAt sets the log backtrace location. See package log for details on
@@ -130,6 +153,56 @@ func (h *HandlerT) StopCPUProfile() error {
 	return nil
 }
 
+// startContinuousProfiling starts capturing a CPU and a heap profile into the given
+// directory at every interval, keeping the given number of captures. The CPU is
+// profiled for cpuTime at each capture.
+func (h *HandlerT) startContinuousProfiling(dir string, interval, cpuTime time.Duration, history int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.profiler != nil {
+		return errors.New("continuous profiling already in progress")
+	}
+	p, err := newProfiler(expandHome(dir), interval, cpuTime, history)
+	if err != nil {
+		return err
+	}
+	h.profiler = p
+	return nil
+}
+
+// stopContinuousProfiling stops the continuous profiler. The captured profiles are
+// kept on disk.
+func (h *HandlerT) stopContinuousProfiling() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.profiler == nil {
+		return errors.New("continuous profiling not in progress")
+	}
+	h.profiler.stop()
+	h.profiler = nil
+	log.Info("Continuous profiling stopped")
+	return nil
+}
+
+// DumpProfiles returns the profiles captured by the continuous profiler between
+// the given unix times. Missing bounds leave the range open.
+func (h *HandlerT) DumpProfiles(from, to *uint64) ([]*Profile, error) {
+	h.mu.Lock()
+	p := h.profiler
+	h.mu.Unlock()
+	if p == nil {
+		return nil, errors.New("continuous profiling not enabled")
+	}
+	start, end := uint64(0), ^uint64(0)
+	if from != nil {
+		start = *from
+	}
+	if to != nil {
+		end = *to
+	}
+	return p.dump(start, end)
+}
+
 // GoTrace turns on tracing for nsec seconds and writes
 // trace data to file.
 func (h *HandlerT) GoTrace(file string, nsec uint) error {
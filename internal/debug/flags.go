@@ -23,6 +23,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -90,6 +91,25 @@ var (
 		Name:  "trace",
 		Usage: "Write execution trace to the given file",
 	}
+	continuousProfileFlag = cli.StringFlag{
+		Name:  "pprof.continuous",
+		Usage: "Periodically store CPU and heap profiles into the given directory, served by debug_dumpProfiles",
+	}
+	continuousProfileIntervalFlag = cli.DurationFlag{
+		Name:  "pprof.continuous.interval",
+		Usage: "Time between two continuous profile captures",
+		Value: time.Minute,
+	}
+	continuousProfileCPUFlag = cli.DurationFlag{
+		Name:  "pprof.continuous.cpu",
+		Usage: "Time the CPU is profiled for at each continuous profile capture",
+		Value: 10 * time.Second,
+	}
+	continuousProfileHistoryFlag = cli.IntFlag{
+		Name:  "pprof.continuous.history",
+		Usage: "Number of continuous profile captures kept on disk",
+		Value: 60,
+	}
 	// (Deprecated April 2020)
 	legacyPprofPortFlag = cli.IntFlag{
 		Name:  "pprofport",
@@ -139,6 +159,10 @@ var Flags = []cli.Flag{
 	blockprofilerateFlag,
 	cpuprofileFlag,
 	traceFlag,
+	continuousProfileFlag,
+	continuousProfileIntervalFlag,
+	continuousProfileCPUFlag,
+	continuousProfileHistoryFlag,
 }
 
 // This is the list of deprecated debugging flags.
@@ -156,7 +180,7 @@ var glogger *log.GlogHandler
 
 func init() {
 	glogger = log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
-	glogger.Verbosity(log.LvlInfo)
+	Handler.Verbosity(int(log.LvlInfo))
 	log.Root().SetHandler(glogger)
 }
 
@@ -178,9 +202,9 @@ func Setup(ctx *cli.Context) error {
 
 	// logging
 	verbosity := ctx.GlobalInt(verbosityFlag.Name)
-	glogger.Verbosity(log.Lvl(verbosity))
+	Handler.Verbosity(verbosity)
 	vmodule := ctx.GlobalString(vmoduleFlag.Name)
-	glogger.Vmodule(vmodule)
+	Handler.Vmodule(vmodule)
 
 	debug := ctx.GlobalBool(debugFlag.Name)
 	if ctx.GlobalIsSet(legacyDebugFlag.Name) {
@@ -236,6 +260,15 @@ func Setup(ctx *cli.Context) error {
 		}
 	}
 
+	if dir := ctx.GlobalString(continuousProfileFlag.Name); dir != "" {
+		interval := ctx.GlobalDuration(continuousProfileIntervalFlag.Name)
+		cpuTime := ctx.GlobalDuration(continuousProfileCPUFlag.Name)
+		history := ctx.GlobalInt(continuousProfileHistoryFlag.Name)
+		if err := Handler.startContinuousProfiling(dir, interval, cpuTime, history); err != nil {
+			return err
+		}
+	}
+
 	// pprof server
 	if ctx.GlobalBool(pprofFlag.Name) {
 		listenHost := ctx.GlobalString(pprofAddrFlag.Name)
@@ -268,6 +301,7 @@ func StartPProf(address string, withMetrics bool) {
 // Exit stops all running profiles, flushing their output to the
 // respective file.
 func Exit() {
+	Handler.stopContinuousProfiling()
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
 }
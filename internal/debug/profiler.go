@@ -0,0 +1,234 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Profile is a profile captured by the continuous profiler.
+type Profile struct {
+	Time uint64        `json:"time"` // Unix time the capture started at
+	Kind string        `json:"kind"` // Type of the profile, "cpu" or "heap"
+	Data hexutil.Bytes `json:"data"` // Gzipped protobuf, as read by go tool pprof
+}
+
+// profiler periodically captures CPU and heap profiles into a directory, keeping
+// the most recent ones. The profiles are stored on disk so they survive the node
+// being restarted after a stall.
+type profiler struct {
+	dir      string
+	interval time.Duration // Time between two captures
+	cpuTime  time.Duration // Time the CPU is profiled for at each capture
+	history  int           // Number of captures kept
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+func newProfiler(dir string, interval, cpuTime time.Duration, history int) (*profiler, error) {
+	if interval <= 0 || history <= 0 {
+		return nil, errors.New("invalid continuous profiling interval or history")
+	}
+	if cpuTime > interval {
+		cpuTime = interval
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// Drop the partial profiles of an earlier run which didn't shut down cleanly
+	partial, _ := filepath.Glob(filepath.Join(dir, "*.pprof.tmp"))
+	for _, file := range partial {
+		os.Remove(file)
+	}
+	p := &profiler{
+		dir:      dir,
+		interval: interval,
+		cpuTime:  cpuTime,
+		history:  history,
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.loop()
+	return p, nil
+}
+
+// stop terminates the profiler, discarding the capture in progress.
+func (p *profiler) stop() {
+	close(p.quit)
+	<-p.done
+}
+
+func (p *profiler) loop() {
+	defer close(p.done)
+
+	log.Info("Continuous profiling started", "dir", p.dir, "interval", p.interval, "history", p.history)
+	for {
+		start := time.Now()
+		if !p.capture(start) {
+			return
+		}
+		p.prune()
+
+		select {
+		case <-time.After(time.Until(start.Add(p.interval))):
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// capture stores the profiles of one round, returning false if the profiler was
+// stopped meanwhile.
+func (p *profiler) capture(start time.Time) bool {
+	// Profile the CPU, unless someone is already doing it through the debug API
+	cpuFile := p.path(uint64(start.Unix()), "cpu") + ".tmp"
+	f, err := os.Create(cpuFile)
+	if err != nil {
+		log.Warn("Failed to create CPU profile", "err", err)
+		return true
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		log.Debug("Skipping continuous CPU profile", "err", err)
+		f.Close()
+		os.Remove(cpuFile)
+	} else {
+		stopped := false
+		select {
+		case <-time.After(p.cpuTime):
+		case <-p.quit:
+			stopped = true
+		}
+		pprof.StopCPUProfile()
+		f.Close()
+		if stopped {
+			os.Remove(cpuFile)
+			return false
+		}
+		p.commit(cpuFile)
+	}
+	// Snapshot the heap at the end of the CPU profile
+	heapFile := p.path(uint64(start.Unix()), "heap") + ".tmp"
+	if f, err = os.Create(heapFile); err != nil {
+		log.Warn("Failed to create heap profile", "err", err)
+		return true
+	}
+	err = pprof.Lookup("heap").WriteTo(f, 0)
+	f.Close()
+	if err != nil {
+		log.Warn("Failed to write heap profile", "err", err)
+		os.Remove(heapFile)
+		return true
+	}
+	p.commit(heapFile)
+	return true
+}
+
+// commit makes a fully written profile visible.
+func (p *profiler) commit(tmp string) {
+	if err := os.Rename(tmp, strings.TrimSuffix(tmp, ".tmp")); err != nil {
+		log.Warn("Failed to store profile", "err", err)
+		os.Remove(tmp)
+	}
+}
+
+// path returns the file storing a profile of the given capture.
+func (p *profiler) path(time uint64, kind string) string {
+	return filepath.Join(p.dir, fmt.Sprintf("%d-%s.pprof", time, kind))
+}
+
+// list returns the stored profiles captured within the given range of unix times,
+// in capture order, without reading their data.
+func (p *profiler) list(from, to uint64) ([]*Profile, error) {
+	files, err := ioutil.ReadDir(p.dir)
+	if err != nil {
+		return nil, err
+	}
+	var profiles []*Profile
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".pprof")
+		if name == file.Name() {
+			continue
+		}
+		parts := strings.SplitN(name, "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		time, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil || time < from || time > to {
+			continue
+		}
+		profiles = append(profiles, &Profile{Time: time, Kind: parts[1]})
+	}
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Time < profiles[j].Time })
+	return profiles, nil
+}
+
+// prune deletes the profiles of the captures beyond the history.
+func (p *profiler) prune() {
+	profiles, err := p.list(0, ^uint64(0))
+	if err != nil {
+		log.Warn("Failed to list profiles", "err", err)
+		return
+	}
+	var captures []uint64
+	for _, profile := range profiles {
+		if len(captures) == 0 || captures[len(captures)-1] != profile.Time {
+			captures = append(captures, profile.Time)
+		}
+	}
+	if len(captures) <= p.history {
+		return
+	}
+	oldest := captures[len(captures)-p.history]
+	for _, profile := range profiles {
+		if profile.Time < oldest {
+			os.Remove(p.path(profile.Time, profile.Kind))
+		}
+	}
+}
+
+// dump returns the stored profiles captured within the given range of unix times.
+func (p *profiler) dump(from, to uint64) ([]*Profile, error) {
+	profiles, err := p.list(from, to)
+	if err != nil {
+		return nil, err
+	}
+	dumped := profiles[:0]
+	for _, profile := range profiles {
+		if profile.Data, err = ioutil.ReadFile(p.path(profile.Time, profile.Kind)); err != nil {
+			if os.IsNotExist(err) {
+				continue // pruned meanwhile
+			}
+			return nil, err
+		}
+		dumped = append(dumped, profile)
+	}
+	return dumped, nil
+}
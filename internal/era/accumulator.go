@@ -0,0 +1,88 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accumulatorDepth is the depth of the merkle tree of the header records,
+// log2(MaxEra1Size).
+const accumulatorDepth = 13
+
+// zeroHashes are the roots of the empty subtrees at every depth.
+var zeroHashes = func() [accumulatorDepth + 1][32]byte {
+	var hashes [accumulatorDepth + 1][32]byte
+	for i := 1; i <= accumulatorDepth; i++ {
+		hashes[i] = sha256.Sum256(append(hashes[i-1][:], hashes[i-1][:]...))
+	}
+	return hashes
+}()
+
+// ComputeAccumulator calculates the SSZ hash tree root of the header records
+// of an era, List[HeaderRecord, MaxEra1Size] with HeaderRecord being the pair
+// of the block hash and the little endian total difficulty.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("must have equal number hashes as td values")
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	layer := make([][32]byte, len(hashes))
+	for i := range hashes {
+		td := bigToBytes32(tds[i])
+		layer[i] = sha256.Sum256(append(hashes[i].Bytes(), td[:]...))
+	}
+	// Merkleize the records, padding with empty subtrees up to the list limit
+	for depth := 0; depth < accumulatorDepth; depth++ {
+		next := make([][32]byte, (len(layer)+1)/2)
+		for i := range next {
+			right := zeroHashes[depth]
+			if 2*i+1 < len(layer) {
+				right = layer[2*i+1]
+			}
+			next[i] = sha256.Sum256(append(layer[2*i][:], right[:]...))
+		}
+		layer = next
+	}
+	root := zeroHashes[accumulatorDepth]
+	if len(layer) > 0 {
+		root = layer[0]
+	}
+	// Mix in the length of the list
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(hashes)))
+	return sha256.Sum256(append(root[:], length[:]...)), nil
+}
+
+// bigToBytes32 converts a big integer into a 32 byte little endian array.
+func bigToBytes32(n *big.Int) [32]byte {
+	var (
+		b  [32]byte
+		be = n.Bytes()
+	)
+	for i := range be {
+		b[i] = be[len(be)-1-i]
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package e2store implements the e2store container format, a simple sequence
+// of type-length-value entries used by the era archives.
+package e2store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// headerSize is the size of an entry header: a 2 byte type, a 4 byte length
+// and 2 reserved bytes, all little endian.
+const headerSize = 8
+
+var errReservedNonZero = errors.New("reserved bytes of entry header are non-zero")
+
+// Entry is a single type-length-value record of an e2store file.
+type Entry struct {
+	Type  uint16
+	Value []byte
+}
+
+// Writer appends entries to an e2store stream.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter creates an e2store writer on top of the given stream.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a single entry, returning the number of bytes written including
+// the entry header.
+func (w *Writer) Write(typ uint16, value []byte) (int, error) {
+	if uint64(len(value)) > uint64(^uint32(0)) {
+		return 0, fmt.Errorf("entry too large: %d bytes", len(value))
+	}
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint16(header[0:], typ)
+	binary.LittleEndian.PutUint32(header[2:], uint32(len(value)))
+
+	n, err := w.w.Write(header[:])
+	if err != nil {
+		return n, err
+	}
+	m, err := w.w.Write(value)
+	return n + m, err
+}
+
+// Reader reads entries from an e2store file.
+type Reader struct {
+	r      io.ReaderAt
+	offset int64
+}
+
+// NewReader creates an e2store reader on top of the given file.
+func NewReader(r io.ReaderAt) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads the next entry of the file.
+func (r *Reader) Read() (*Entry, error) {
+	entry, n, err := r.ReadAt(r.offset)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(n)
+	return entry, nil
+}
+
+// ReadAt reads the entry at the given offset, returning it along with its size
+// including the header.
+func (r *Reader) ReadAt(off int64) (*Entry, int, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	entry := &Entry{Type: typ, Value: make([]byte, length)}
+	if _, err := r.r.ReadAt(entry.Value, off+headerSize); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	return entry, headerSize + int(length), nil
+}
+
+// ReaderAt returns the type of the entry at the given offset along with a
+// reader of its value, avoiding to load it into memory.
+func (r *Reader) ReaderAt(off int64) (uint16, io.Reader, int, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	return typ, io.NewSectionReader(r.r, off+headerSize, int64(length)), headerSize + int(length), nil
+}
+
+// ReadMetadataAt reads the header of the entry at the given offset.
+func (r *Reader) ReadMetadataAt(off int64) (uint16, uint32, error) {
+	var header [headerSize]byte
+	if _, err := r.r.ReadAt(header[:], off); err != nil {
+		return 0, 0, err
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return 0, 0, errReservedNonZero
+	}
+	return binary.LittleEndian.Uint16(header[0:]), binary.LittleEndian.Uint32(header[2:]), nil
+}
+
+// Find returns the first entry of the given type, or io.EOF if none exists.
+func (r *Reader) Find(typ uint16) (*Entry, error) {
+	for off := int64(0); ; {
+		entry, n, err := r.ReadAt(off)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Type == typ {
+			return entry, nil
+		}
+		off += int64(n)
+	}
+}
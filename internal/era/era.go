@@ -0,0 +1,406 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements the era1 archive format, storing a fixed sized range
+// of historical blocks along with their receipts and total difficulties.
+package era
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era/e2store"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+)
+
+// The entry types of an era1 archive.
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+
+	// MaxEra1Size is the maximum number of blocks in an archive.
+	MaxEra1Size = 8192
+)
+
+// Filename returns the name of an era1 archive: <network>-<epoch>-<root>.era1,
+// the root being shortened to its first 4 bytes.
+func Filename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
+}
+
+// ReadDir reads the era1 archives of the given network in a directory, making
+// sure the epochs are contiguous starting from zero.
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	var (
+		next = uint64(0)
+		eras []string
+	)
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		parts := strings.Split(entry.Name(), "-")
+		if len(parts) != 3 || parts[0] != network {
+			// Invalid era1 filename or another network, skip
+			continue
+		}
+		if epoch, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
+		} else if epoch != next {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		}
+		next += 1
+		eras = append(eras, entry.Name())
+	}
+	return eras, nil
+}
+
+// ReadAtSeekCloser is the interface of the files backing an archive.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era reads an era1 archive.
+type Era struct {
+	f   ReadAtSeekCloser
+	s   *e2store.Reader
+	m   metadata
+	buf [8]byte
+}
+
+// metadata holds the block range of an archive, read from its block index.
+type metadata struct {
+	start  uint64 // number of the first block
+	count  uint64 // number of blocks
+	length int64  // length of the file in bytes
+}
+
+// Open opens the era1 archive at the given path.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// From creates an era1 reader on top of an already opened file.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	e := &Era{f: f, s: e2store.NewReader(f)}
+	if err := e.readMetadata(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close closes the underlying file.
+func (e *Era) Close() error {
+	return e.f.Close()
+}
+
+// Start returns the number of the first block of the archive.
+func (e *Era) Start() uint64 {
+	return e.m.start
+}
+
+// Count returns the number of blocks in the archive.
+func (e *Era) Count() uint64 {
+	return e.m.count
+}
+
+// GetBlockByNumber returns the block with the given number.
+func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	header, n, err := e.readHeaderAt(num, off)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := e.snappyReaderAt(TypeCompressedBody, off+n)
+	if err != nil {
+		return nil, err
+	}
+	var body types.Body
+	if err := rlp.Decode(r, &body); err != nil {
+		return nil, fmt.Errorf("invalid body of block %d: %v", num, err)
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles), nil
+}
+
+// GetReceiptsByNumber returns the consensus fields of the receipts of the block
+// with the given number.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	// Skip over the header and the body
+	for i := 0; i < 2; i++ {
+		_, length, err := e.s.ReadMetadataAt(off)
+		if err != nil {
+			return nil, err
+		}
+		off += 8 + int64(length)
+	}
+	r, _, err := e.snappyReaderAt(TypeCompressedReceipts, off)
+	if err != nil {
+		return nil, err
+	}
+	var receipts types.Receipts
+	if err := rlp.Decode(r, &receipts); err != nil {
+		return nil, fmt.Errorf("invalid receipts of block %d: %v", num, err)
+	}
+	return receipts, nil
+}
+
+// GetTotalDifficultyByNumber returns the total difficulty of the chain up to
+// and including the block with the given number.
+func (e *Era) GetTotalDifficultyByNumber(num uint64) (*big.Int, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	// Skip over the header, the body and the receipts
+	for i := 0; i < 3; i++ {
+		_, length, err := e.s.ReadMetadataAt(off)
+		if err != nil {
+			return nil, err
+		}
+		off += 8 + int64(length)
+	}
+	entry, _, err := e.s.ReadAt(off)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != TypeTotalDifficulty {
+		return nil, fmt.Errorf("expected total difficulty entry, have type %#x", entry.Type)
+	}
+	if len(entry.Value) != 32 {
+		return nil, fmt.Errorf("invalid total difficulty length %d", len(entry.Value))
+	}
+	be := make([]byte, 32)
+	for i := range entry.Value {
+		be[i] = entry.Value[31-i]
+	}
+	return new(big.Int).SetBytes(be), nil
+}
+
+// Accumulator returns the accumulator root stored in the archive, which is
+// located right before the block index.
+func (e *Era) Accumulator() (common.Hash, error) {
+	entry, _, err := e.s.ReadAt(e.indexOffset() - 8 - common.HashLength)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if entry.Type != TypeAccumulator {
+		return common.Hash{}, fmt.Errorf("expected accumulator entry, have type %#x", entry.Type)
+	}
+	if len(entry.Value) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid accumulator length %d", len(entry.Value))
+	}
+	return common.BytesToHash(entry.Value), nil
+}
+
+// Verify recomputes the accumulator from the blocks of the archive and checks
+// it against the stored one.
+func (e *Era) Verify() error {
+	want, err := e.Accumulator()
+	if err != nil {
+		return err
+	}
+	var (
+		hashes = make([]common.Hash, 0, e.m.count)
+		tds    = make([]*big.Int, 0, e.m.count)
+	)
+	for num := e.m.start; num < e.m.start+e.m.count; num++ {
+		header, err := e.getHeaderByNumber(num)
+		if err != nil {
+			return err
+		}
+		td, err := e.GetTotalDifficultyByNumber(num)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, header.Hash())
+		tds = append(tds, td)
+	}
+	have, err := ComputeAccumulator(hashes, tds)
+	if err != nil {
+		return err
+	}
+	if have != want {
+		return fmt.Errorf("accumulator mismatch: have %x, want %x", have, want)
+	}
+	return nil
+}
+
+// getHeaderByNumber returns the header of the block with the given number.
+func (e *Era) getHeaderByNumber(num uint64) (*types.Header, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	header, _, err := e.readHeaderAt(num, off)
+	return header, err
+}
+
+// readHeaderAt decodes the header entry at the given offset, returning it along
+// with the entry size.
+func (e *Era) readHeaderAt(num uint64, off int64) (*types.Header, int64, error) {
+	r, n, err := e.snappyReaderAt(TypeCompressedHeader, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	var header types.Header
+	if err := rlp.Decode(r, &header); err != nil {
+		return nil, 0, fmt.Errorf("invalid header of block %d: %v", num, err)
+	}
+	return &header, n, nil
+}
+
+// readMetadata reads the block range from the tail of the block index.
+func (e *Era) readMetadata() error {
+	length, err := e.f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if length < 8 {
+		return fmt.Errorf("archive too short: %d bytes", length)
+	}
+	if _, err := e.f.ReadAt(e.buf[:], length-8); err != nil {
+		return err
+	}
+	count := binary.LittleEndian.Uint64(e.buf[:])
+	if count == 0 || count > MaxEra1Size || int64(count*8+24) > length {
+		return fmt.Errorf("invalid block count %d", count)
+	}
+	if _, err := e.f.ReadAt(e.buf[:], length-8-int64(count*8)-8); err != nil {
+		return err
+	}
+	e.m = metadata{
+		start:  binary.LittleEndian.Uint64(e.buf[:]),
+		count:  count,
+		length: length,
+	}
+	return nil
+}
+
+// readOffset returns the file offset of the block tuple of the given number.
+func (e *Era) readOffset(num uint64) (int64, error) {
+	if num < e.m.start || num >= e.m.start+e.m.count {
+		return 0, fmt.Errorf("block %d out of range [%d, %d)", num, e.m.start, e.m.start+e.m.count)
+	}
+	var (
+		index = e.indexOffset()
+		pos   = index + 16 + int64(num-e.m.start)*8
+	)
+	if _, err := e.f.ReadAt(e.buf[:], pos); err != nil {
+		return 0, err
+	}
+	return index + int64(binary.LittleEndian.Uint64(e.buf[:])), nil
+}
+
+// indexOffset returns the file offset of the block index entry.
+func (e *Era) indexOffset() int64 {
+	return e.m.length - 24 - int64(e.m.count*8)
+}
+
+// snappyReaderAt returns a decompressing reader of the entry at the given
+// offset, checking its type, along with the entry size.
+func (e *Era) snappyReaderAt(typ uint16, off int64) (io.Reader, int64, error) {
+	have, r, n, err := e.s.ReaderAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if have != typ {
+		return nil, 0, fmt.Errorf("expected entry type %#x, have %#x", typ, have)
+	}
+	return snappy.NewReader(r), int64(n), nil
+}
+
+// Iterator walks the blocks of an archive in order.
+type Iterator struct {
+	e    *Era
+	next uint64
+
+	block    *types.Block
+	receipts types.Receipts
+	td       *big.Int
+	err      error
+}
+
+// NewIterator creates an iterator over all blocks of the archive.
+func NewIterator(e *Era) *Iterator {
+	return &Iterator{e: e, next: e.m.start}
+}
+
+// Next advances the iterator, returning false when it is exhausted or failed.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.next >= it.e.m.start+it.e.m.count {
+		return false
+	}
+	if it.block, it.err = it.e.GetBlockByNumber(it.next); it.err != nil {
+		return false
+	}
+	if it.receipts, it.err = it.e.GetReceiptsByNumber(it.next); it.err != nil {
+		return false
+	}
+	if it.td, it.err = it.e.GetTotalDifficultyByNumber(it.next); it.err != nil {
+		return false
+	}
+	it.next++
+	return true
+}
+
+// Block returns the current block.
+func (it *Iterator) Block() *types.Block { return it.block }
+
+// Receipts returns the receipts of the current block.
+func (it *Iterator) Receipts() types.Receipts { return it.receipts }
+
+// TotalDifficulty returns the total difficulty of the current block.
+func (it *Iterator) TotalDifficulty() *big.Int { return it.td }
+
+// Error returns the error that stopped the iteration, if any.
+func (it *Iterator) Error() error { return it.err }
@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that blocks written by the builder are read back identically.
+func TestEra1Builder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "era1-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "test.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		builder = NewBuilder(f)
+		blocks  []*types.Block
+		tds     []*big.Int
+		start   = uint64(128)
+	)
+	for i := uint64(0); i < 16; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(start + i), Difficulty: big.NewInt(2), GasUsed: i}
+		receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: i, Logs: []*types.Log{}}}
+		header.ReceiptHash = types.DeriveSha(receipts, trie.NewStackTrie(nil))
+		block := types.NewBlockWithHeader(header)
+		td := big.NewInt(int64(1000 + 2*i))
+
+		if err := builder.Add(block, receipts, td); err != nil {
+			t.Fatalf("failed to add block %d: %v", i, err)
+		}
+		blocks = append(blocks, block)
+		tds = append(tds, td)
+	}
+	if err := builder.Add(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)}), nil, common.Big0); err == nil {
+		t.Fatal("non contiguous block accepted")
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	f.Close()
+
+	e, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	defer e.Close()
+
+	if e.Start() != start || e.Count() != uint64(len(blocks)) {
+		t.Fatalf("range mismatch: have [%d, +%d], want [%d, +%d]", e.Start(), e.Count(), start, len(blocks))
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("accumulator mismatch: have %x (%v), want %x", have, err, root)
+	}
+	if err := e.Verify(); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	it := NewIterator(e)
+	for i := 0; it.Next(); i++ {
+		if it.Block().Hash() != blocks[i].Hash() {
+			t.Fatalf("block %d: hash mismatch", i)
+		}
+		if have := types.DeriveSha(it.Receipts(), trie.NewStackTrie(nil)); have != blocks[i].ReceiptHash() {
+			t.Fatalf("block %d: receipt root mismatch: have %x, want %x", i, have, blocks[i].ReceiptHash())
+		}
+		if it.TotalDifficulty().Cmp(tds[i]) != 0 {
+			t.Fatalf("block %d: td mismatch: have %v, want %v", i, it.TotalDifficulty(), tds[i])
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if _, err := e.GetBlockByNumber(start + uint64(len(blocks))); err == nil {
+		t.Fatal("out of range block returned")
+	}
+}
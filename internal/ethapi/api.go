@@ -22,7 +22,10 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -71,6 +74,67 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// FeeHistory returns the fee market history of up to 1024 blocks ending with
+// lastBlock: the base fee and gas used ratio of each block, and the requested
+// percentiles of the rewards paid in each block, weighted by gas used. The base
+// fee is zero before the base fee fork.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
+	oldest, reward, baseFee, gasUsedRatio, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	results := &feeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsedRatio,
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, w := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(w))
+			for j, v := range w {
+				results.Reward[i][j] = (*hexutil.Big)(v)
+			}
+		}
+	}
+	if baseFee != nil {
+		results.BaseFee = make([]*hexutil.Big, len(baseFee))
+		for i, v := range baseFee {
+			results.BaseFee[i] = (*hexutil.Big)(v)
+		}
+	}
+	return results, nil
+}
+
+// GasTarget returns how full the blocks of the last epoch are relative to the
+// gas target, half the gas limit, as a hint whether the chain is congested and
+// higher gas prices are worth paying.
+func (s *PublicEthereumAPI) GasTarget(ctx context.Context) (map[string]interface{}, error) {
+	congestion, err := s.b.Congestion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	price, err := s.b.SuggestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"oldestBlock":  hexutil.Uint64(congestion.OldestBlock),
+		"newestBlock":  hexutil.Uint64(congestion.NewestBlock),
+		"gasTarget":    hexutil.Uint64(congestion.GasTarget),
+		"gasUsedRatio": congestion.GasUsedRatio,
+		"busyBlocks":   hexutil.Uint64(congestion.BusyBlocks),
+		"congested":    congestion.Congested,
+		"gasPrice":     (*hexutil.Big)(price),
+	}, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up to date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronise from
@@ -117,7 +181,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -125,7 +189,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -555,10 +619,11 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	defer traceStateReads(ctx, state)
 	return (*hexutil.Big)(state.GetBalance(address)), state.Error()
 }
 
@@ -580,11 +645,91 @@ type StorageResult struct {
 
 // GetProof returns the Merkle-proof for a given account and optionally some storage keys.
 func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	state, err := s.proofState(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	return proveAccount(state, address, storageKeys)
+}
 
+// ProofRequest is an account to prove in a batch, optionally along with some of
+// its storage slots.
+type ProofRequest struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []string       `json:"storageKeys"`
+}
+
+// maxProofBatchItems is the maximum number of accounts and storage slots proven
+// by a single eth_getProofs call.
+const maxProofBatchItems = 10000
+
+// GetProofs returns the Merkle-proofs of a batch of accounts and of their given
+// storage slots, all against the state of the same block. The proofs are built
+// concurrently, in the order of the requests.
+func (s *PublicBlockChainAPI) GetProofs(ctx context.Context, requests []ProofRequest, blockNrOrHash rpc.BlockNumberOrHash) ([]*AccountResult, error) {
+	items := len(requests)
+	for _, req := range requests {
+		items += len(req.StorageKeys)
+	}
+	if items > maxProofBatchItems {
+		return nil, fmt.Errorf("too many accounts and storage slots requested: %d, limit %d", items, maxProofBatchItems)
+	}
+	statedb, err := s.proofState(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	var (
+		results = make([]*AccountResult, len(requests))
+		errs    = make([]error, len(requests))
+		next    = make(chan int, len(requests))
+		workers = runtime.NumCPU()
+		wg      sync.WaitGroup
+	)
+	for i := range requests {
+		next <- i
+	}
+	close(next)
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+	// The state database isn't safe for concurrent use, every worker proves the
+	// accounts with a copy of its own
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(statedb *state.StateDB) {
+			defer wg.Done()
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i], errs[i] = proveAccount(statedb, requests[i].Address, requests[i].StorageKeys)
+			}
+		}(statedb.Copy())
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("account %d (%x): %w", i, requests[i].Address, err)
+		}
+	}
+	return results, nil
+}
+
+// proofState returns the state to build Merkle-proofs against, reporting the
+// historical states which are no longer retained by the node.
+func (s *PublicBlockChainAPI) proofState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, error) {
+	state, header, err := stateAndHeader(ctx, s.b, blockNrOrHash)
+	if err != nil && header != nil {
+		return nil, fmt.Errorf("state of block %d not available, historical proofs are only served by archive nodes: %w", header.Number, err)
+	}
+	return state, err
+}
+
+// proveAccount builds the Merkle-proof of an account and of the given storage
+// slots of it.
+func proveAccount(state *state.StateDB, address common.Address, storageKeys []string) (*AccountResult, error) {
 	storageTrie := state.StorageTrie(address)
 	storageHash := types.EmptyRootHash
 	codeHash := state.GetCodeHash(address)
@@ -744,10 +889,11 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 
 // GetCode returns the code stored at the given address in the state for the given block number.
 func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	defer traceStateReads(ctx, state)
 	code := state.GetCode(address)
 	return code, state.Error()
 }
@@ -756,14 +902,62 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	defer traceStateReads(ctx, state)
 	res := state.GetState(address, common.HexToHash(key))
 	return res[:], state.Error()
 }
 
+// maxStateReadBatch is the maximum number of values read by a single batched
+// state read call.
+const maxStateReadBatch = 10000
+
+// StorageSlot is a storage slot of an account read in a batch.
+type StorageSlot struct {
+	Address common.Address `json:"address"`
+	Key     string         `json:"key"`
+}
+
+// GetBalances returns the balances of the given accounts in the state of the
+// given block, all read from the same state.
+func (s *PublicBlockChainAPI) GetBalances(ctx context.Context, addresses []common.Address, blockNrOrHash rpc.BlockNumberOrHash) ([]*hexutil.Big, error) {
+	if len(addresses) > maxStateReadBatch {
+		return nil, fmt.Errorf("too many accounts requested: %d, limit %d", len(addresses), maxStateReadBatch)
+	}
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	defer traceStateReads(ctx, state)
+	balances := make([]*hexutil.Big, len(addresses))
+	for i, address := range addresses {
+		balances[i] = (*hexutil.Big)(state.GetBalance(address))
+	}
+	return balances, state.Error()
+}
+
+// GetStorageSlots returns the values of the given storage slots in the state of
+// the given block, all read from the same state.
+func (s *PublicBlockChainAPI) GetStorageSlots(ctx context.Context, slots []StorageSlot, blockNrOrHash rpc.BlockNumberOrHash) ([]hexutil.Bytes, error) {
+	if len(slots) > maxStateReadBatch {
+		return nil, fmt.Errorf("too many storage slots requested: %d, limit %d", len(slots), maxStateReadBatch)
+	}
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	defer traceStateReads(ctx, state)
+	values := make([]hexutil.Bytes, len(slots))
+	for i, slot := range slots {
+		res := state.GetState(slot.Address, common.HexToHash(slot.Key))
+		values[i] = res[:]
+	}
+	return values, state.Error()
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From       *common.Address   `json:"from"`
@@ -773,6 +967,8 @@ type CallArgs struct {
 	Value      *hexutil.Big      `json:"value"`
 	Data       *hexutil.Bytes    `json:"data"`
 	AccessList *types.AccessList `json:"accessList"`
+
+	AuthorizationList []types.SetCodeAuthorization `json:"authorizationList"`
 }
 
 // ToMessage converts CallArgs to the Message type used by the core evm
@@ -813,6 +1009,9 @@ func (args *CallArgs) ToMessage(globalGasCap uint64) types.Message {
 	}
 
 	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, accessList, false)
+	if args.AuthorizationList != nil {
+		msg = msg.WithSetCodeAuthorizations(args.AuthorizationList)
+	}
 	return msg
 }
 
@@ -868,18 +1067,106 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// applyPooledTxs applies the pooled transactions of an account on top of the
+// pending state, so that the calls made against the pending block see the effects
+// of the transactions the account already sent but which are not included in it
+// yet. The queued transactions are applied too as long as their nonces follow,
+// the application stopping at the first transaction failing. The replay is
+// aborted once the context is done.
+func applyPooledTxs(ctx context.Context, b Backend, state *state.StateDB, header *types.Header, from common.Address) error {
+	pending, queued := b.TxPoolContent()
+	txs := append(append(types.Transactions{}, pending[from]...), queued[from]...)
+	if len(txs) == 0 {
+		return nil
+	}
+	sort.Sort(types.TxByNonce(txs))
+
+	var (
+		signer = types.MakeSigner(b.ChainConfig(), header.Number)
+		gp     = new(core.GasPool).AddGas(math.MaxUint64)
+	)
+	for _, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		nonce := state.GetNonce(from)
+		if tx.Nonce() < nonce {
+			continue // Already included in the pending block
+		}
+		if tx.Nonce() > nonce {
+			break // Nonce gap, the following ones can't be executed
+		}
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			break
+		}
+		evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true})
+		if err != nil {
+			return err
+		}
+		done := make(chan struct{})
+		gopool.Submit(func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		})
+		_, err = core.ApplyMessage(evm, msg, gp)
+		close(done)
+		if err := vmError(); err != nil {
+			return err
+		}
+		if evm.Cancelled() {
+			return fmt.Errorf("pooled transaction replay aborted: %w", ctx.Err())
+		}
+		if err != nil {
+			log.Debug("Pooled transaction not applicable to the pending state", "hash", tx.Hash(), "err", err)
+			break
+		}
+		state.Finalise(true)
+	}
+	return nil
+}
 
+// stateAndHeader retrieves the state and header of a block, annotating the trace
+// of the call being served with the resolved block.
+func stateAndHeader(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
 	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header != nil {
+		rpc.TraceAttributes(ctx, "block.number", header.Number.Uint64(), "block.hash", header.Hash())
+	}
+	return state, header, err
+}
+
+// traceStateReads annotates the trace of the call being served with the number
+// of accounts and storage slots the given state loaded from the database.
+func traceStateReads(ctx context.Context, state *state.StateDB) {
+	rpc.TraceCount(ctx, "state.accounts", state.AccountLoaded)
+	rpc.TraceCount(ctx, "state.slots", state.StorageLoaded)
+}
+
+// stateAndHeaderForCall retrieves the state to execute a call of the given
+// sender on. The pending state includes the pooled transactions of the sender.
+func stateAndHeaderForCall(ctx context.Context, b Backend, from *common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	state, header, err := stateAndHeader(ctx, b, blockNrOrHash)
 	if state == nil || err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	if err := overrides.Apply(state); err != nil {
-		return nil, err
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber && from != nil {
+		if err := applyPooledTxs(ctx, b, state, header, *from); err != nil {
+			return nil, nil, err
+		}
 	}
+	return state, header, nil
+}
+
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
+	// The timeout covers the replay of the pooled transactions too.
 	var cancel context.CancelFunc
 	if timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, timeout)
@@ -890,9 +1177,27 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
+	state, header, err := stateAndHeaderForCall(ctx, b, args.From, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	defer traceStateReads(ctx, state)
+
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	return doCall(ctx, b, args, state, header, vmCfg, timeout, globalGasCap)
+}
+
+// doCall executes a call on the given state, which it modifies. The call is
+// aborted once the context is done.
+func doCall(ctx context.Context, b Backend, args CallArgs, state *state.StateDB, header *types.Header, vmCfg vm.Config, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Get a new instance of the EVM.
 	msg := args.ToMessage(globalGasCap)
-	evm, vmError, err := b.GetEVM(ctx, msg, state, header, nil)
+	evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vmCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -957,7 +1262,7 @@ func (e *revertError) ErrorData() interface{} {
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
 func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
-	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
+	result, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, vm.Config{NoBaseFee: true}, 5*time.Second, s.b.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -968,11 +1273,113 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	return result.Return(), result.Err
 }
 
-func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64) (hexutil.Uint64, error) {
-	// Binary search the gas requirement, as it may be higher than the amount used
+// CallManyArgs represents a single call of a call sequence, along with the state
+// overrides applied right before executing it.
+type CallManyArgs struct {
+	CallArgs
+	StateOverrides *StateOverride `json:"stateOverrides"`
+}
+
+// CallManyResult is the outcome of a single call of a call sequence.
+type CallManyResult struct {
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// DoCallMany executes the given calls in order on the state of the given block,
+// carrying the state changes of every call over to the next one. Reverted calls
+// are reported in their results, whereas calls failing consensus checks abort
+// the whole sequence.
+func DoCallMany(ctx context.Context, b Backend, calls []CallManyArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, timeout time.Duration, globalGasCap uint64) ([]*CallManyResult, error) {
+	defer func(start time.Time) {
+		log.Debug("Executing EVM call sequence finished", "calls", len(calls), "runtime", time.Since(start))
+	}(time.Now())
+
+	state, header, err := stateAndHeader(ctx, b, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	defer traceStateReads(ctx, state)
+
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled when the sequence has completed, the
+	// timeout applying to the whole sequence.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
 	var (
-		lo  uint64 = params.TxGas - 1
-		hi  uint64
+		gp      = new(core.GasPool).AddGas(math.MaxUint64)
+		results = make([]*CallManyResult, 0, len(calls))
+	)
+	for i, call := range calls {
+		if err := call.StateOverrides.Apply(state); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		msg := call.ToMessage(globalGasCap)
+		evm, vmError, err := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true})
+		if err != nil {
+			return nil, err
+		}
+		done := make(chan struct{})
+		gopool.Submit(func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		})
+		result, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: err: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		// Finalise the call the same way a transaction is in a block, so that the
+		// next call sees its effects
+		state.Finalise(true)
+
+		res := &CallManyResult{
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			ReturnData: result.Return(),
+		}
+		if len(result.Revert()) > 0 {
+			res.ReturnData = result.Revert()
+			res.Error = newRevertError(result).Error()
+		} else if result.Err != nil {
+			res.Error = result.Err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// CallMany executes the given calls in order on the state of the given block,
+// the state changes of every call being visible to the following ones. Each call
+// may carry its own state overrides, applied right before executing it.
+//
+// Note, this function doesn't make and changes in the state/blockchain and is
+// useful to simulate transaction sequences.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []CallManyArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) ([]*CallManyResult, error) {
+	return DoCallMany(ctx, s.b, calls, blockNrOrHash, overrides, 5*time.Second, s.b.RPCGasCap())
+}
+
+func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap uint64, opts *EstimateGasOptions) (hexutil.Uint64, error) {
+	var (
+		lo  uint64 // Highest gas limit the call failed with
+		hi  uint64 // Lowest gas limit the call succeeded with
 		cap uint64
 	)
 	// Use zero address if sender unspecified.
@@ -993,12 +1400,18 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 		}
 		hi = block.GasLimit()
 	}
-	// Recap the highest gas limit with account's available balance.
+	// Recap the highest gas limit with account's available balance. The pending
+	// state is resolved once, every execution below running on a copy of it.
+	state, header, err := stateAndHeaderForCall(ctx, b, args.From, blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	if state == nil {
+		return 0, errors.New("state not found")
+	}
+	defer traceStateReads(ctx, state)
+
 	if args.GasPrice != nil && args.GasPrice.ToInt().BitLen() != 0 {
-		state, _, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-		if err != nil {
-			return 0, err
-		}
 		balance := state.GetBalance(*args.From) // from can't be nil
 		available := new(big.Int).Set(balance)
 		if args.Value != nil {
@@ -1028,10 +1441,11 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
+	executable := func(gas uint64, vmCfg vm.Config) (bool, *core.ExecutionResult, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		result, err := DoCall(ctx, b, args, blockNrOrHash, nil, vm.Config{}, 0, gasCap)
+		vmCfg.NoBaseFee = true
+		result, err := doCall(ctx, b, args, state.Copy(), header, vmCfg, 0, gasCap)
 		if err != nil {
 			if errors.Is(err, core.ErrIntrinsicGas) {
 				return true, nil, nil // Special case, raise gas limit
@@ -1040,10 +1454,70 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 		}
 		return result.Failed(), result, nil
 	}
-	// Execute the binary search and hone in on an executable gas limit
+	// A plain transfer to an account without code only costs the intrinsic gas,
+	// a single execution checks that the sender can afford it
+	noData := args.Data == nil || len(*args.Data) == 0
+	noAccessList := args.AccessList == nil || len(*args.AccessList) == 0
+	if args.To != nil && noData && noAccessList && len(args.AuthorizationList) == 0 && len(state.GetCode(*args.To)) == 0 {
+		if hi < params.TxGas {
+			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
+		}
+		if _, _, err := executable(params.TxGas, vm.Config{}); err != nil {
+			return 0, err
+		}
+		return hexutil.Uint64(params.TxGas), nil
+	}
+	// Execute the call at the highest allowance first, rejecting it right away
+	// if it fails even then
+	failed, result, err := executable(hi, vm.Config{})
+	if err != nil {
+		return 0, err
+	}
+	if failed {
+		if result != nil && result.Err != vm.ErrOutOfGas {
+			if len(result.Revert()) > 0 {
+				if opts != nil && opts.FullError {
+					return 0, callFrameFailure(executable, hi, result)
+				}
+				return 0, newRevertError(result)
+			}
+			return 0, result.Err
+		}
+		// Otherwise, the specified gas cap is too low
+		return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
+	}
+	// The gas used by the unconstrained execution lower-bounds the gas limit
+	// required, except for the calls checking the remaining gas explicitly, for
+	// which the lowest limit isn't wanted anyway.
+	lo = result.UsedGas - 1
+
+	// Most calls succeed with the gas used plus the refund, along with the stipend
+	// and the 1/64th retained by the call frames. Try that optimistic limit first,
+	// which usually narrows the search down enough to stop right away.
+	optimistic := (result.UsedGas + result.RefundedGas + params.CallStipend) * 64 / 63
+	if optimistic < hi {
+		failed, _, err := executable(optimistic, vm.Config{})
+		if err != nil {
+			return 0, err
+		}
+		if failed {
+			lo = optimistic
+		} else {
+			hi = optimistic
+		}
+	}
+	// Binary search the remaining range, down to the accepted error ratio
 	for lo+1 < hi {
+		if float64(hi-lo)/float64(hi) < estimateGasErrorRatio {
+			break
+		}
 		mid := (hi + lo) / 2
-		failed, _, err := executable(mid)
+		if mid > lo*2 {
+			// Most calls don't need much more gas than the lower bound, which
+			// is tried first rather than the middle of a wide range
+			mid = lo * 2
+		}
+		failed, _, err := executable(mid, vm.Config{})
 
 		// If the error is not nil(consensus error), it means the provided message
 		// call or transaction will never be accepted no matter how much gas it is
@@ -1057,34 +1531,27 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash
 			hi = mid
 		}
 	}
-	// Reject the transaction as invalid if it still fails at the highest allowance
-	if hi == cap {
-		failed, result, err := executable(hi)
-		if err != nil {
-			return 0, err
-		}
-		if failed {
-			if result != nil && result.Err != vm.ErrOutOfGas {
-				if len(result.Revert()) > 0 {
-					return 0, newRevertError(result)
-				}
-				return 0, result.Err
-			}
-			// Otherwise, the specified gas cap is too low
-			return 0, fmt.Errorf("gas required exceeds allowance (%d)", cap)
-		}
-	}
 	return hexutil.Uint64(hi), nil
 }
 
+// callFrameFailure executes a reverting call again with the given gas, tracing
+// the call frames failing in it to build the error reporting the revert.
+func callFrameFailure(executable func(uint64, vm.Config) (bool, *core.ExecutionResult, error), gas uint64, result *core.ExecutionResult) error {
+	tracer := new(frameTracer)
+	if _, traced, err := executable(gas, vm.Config{Debug: true, Tracer: tracer}); err == nil && traced != nil {
+		result = traced
+	}
+	return &callFrameError{revertError: newRevertError(result), frames: tracer.failures()}
+}
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
 // given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, opts *EstimateGasOptions) (hexutil.Uint64, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	return DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap())
+	return DoEstimateGas(ctx, s.b, args, bNrOrHash, s.b.RPCGasCap(), opts)
 }
 
 // GetDiffAccounts returns changed accounts in a specific block number.
@@ -1354,7 +1821,7 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 
 // RPCMarshalHeader converts the given header to the RPC output .
 func RPCMarshalHeader(head *types.Header) map[string]interface{} {
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
 		"hash":             head.Hash(),
 		"parentHash":       head.ParentHash,
@@ -1373,6 +1840,10 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+	if head.BaseFee != nil {
+		result["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee)
+	}
+	return result
 }
 
 // RPCMarshalBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
@@ -1429,28 +1900,37 @@ func (s *PublicBlockChainAPI) rpcMarshalBlock(ctx context.Context, b *types.Bloc
 	if inclTx {
 		fields["totalDifficulty"] = (*hexutil.Big)(s.b.GetTd(ctx, b.Hash()))
 	}
+	if fullTx {
+		for _, tx := range fields["transactions"].([]interface{}) {
+			markSystemTx(s.b.Engine(), b, tx.(*RPCTransaction))
+		}
+	}
 	return fields, err
 }
 
 // RPCTransaction represents a transaction that will serialize to the RPC representation of a transaction
 type RPCTransaction struct {
-	BlockHash        *common.Hash      `json:"blockHash"`
-	BlockNumber      *hexutil.Big      `json:"blockNumber"`
-	From             common.Address    `json:"from"`
-	Gas              hexutil.Uint64    `json:"gas"`
-	GasPrice         *hexutil.Big      `json:"gasPrice"`
-	Hash             common.Hash       `json:"hash"`
-	Input            hexutil.Bytes     `json:"input"`
-	Nonce            hexutil.Uint64    `json:"nonce"`
-	To               *common.Address   `json:"to"`
-	TransactionIndex *hexutil.Uint64   `json:"transactionIndex"`
-	Value            *hexutil.Big      `json:"value"`
-	Type             hexutil.Uint64    `json:"type"`
-	Accesses         *types.AccessList `json:"accessList,omitempty"`
-	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
-	V                *hexutil.Big      `json:"v"`
-	R                *hexutil.Big      `json:"r"`
-	S                *hexutil.Big      `json:"s"`
+	BlockHash        *common.Hash                 `json:"blockHash"`
+	BlockNumber      *hexutil.Big                 `json:"blockNumber"`
+	From             common.Address               `json:"from"`
+	Gas              hexutil.Uint64               `json:"gas"`
+	GasPrice         *hexutil.Big                 `json:"gasPrice"`
+	Hash             common.Hash                  `json:"hash"`
+	Input            hexutil.Bytes                `json:"input"`
+	Nonce            hexutil.Uint64               `json:"nonce"`
+	To               *common.Address              `json:"to"`
+	TransactionIndex *hexutil.Uint64              `json:"transactionIndex"`
+	Value            *hexutil.Big                 `json:"value"`
+	Type             hexutil.Uint64               `json:"type"`
+	Accesses         *types.AccessList            `json:"accessList,omitempty"`
+	ChainID          *hexutil.Big                 `json:"chainId,omitempty"`
+	BlobFeeCap       *hexutil.Big                 `json:"maxFeePerBlobGas,omitempty"`
+	BlobHashes       []common.Hash                `json:"blobVersionedHashes,omitempty"`
+	AuthList         []types.SetCodeAuthorization `json:"authorizationList,omitempty"`
+	V                *hexutil.Big                 `json:"v"`
+	R                *hexutil.Big                 `json:"r"`
+	S                *hexutil.Big                 `json:"s"`
+	SystemTx         bool                         `json:"systemTx,omitempty"` // Whether the transaction was injected by the consensus engine
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1488,16 +1968,28 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
 		result.TransactionIndex = (*hexutil.Uint64)(&index)
 	}
-	if tx.Type() == types.AccessListTxType {
+	switch tx.Type() {
+	case types.AccessListTxType:
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+	case types.BlobTxType:
+		al := tx.AccessList()
+		result.Accesses = &al
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.BlobFeeCap = (*hexutil.Big)(tx.BlobGasFeeCap())
+		result.BlobHashes = tx.BlobHashes()
+	case types.SetCodeTxType:
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.AuthList = tx.SetCodeAuthorizations()
 	}
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1531,6 +2023,27 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	return blob
 }
 
+// markSystemTx flags the given transaction of a block if it was injected by the
+// PoSA consensus engine.
+func markSystemTx(engine consensus.Engine, b *types.Block, tx *RPCTransaction) {
+	if tx == nil || tx.TransactionIndex == nil {
+		return
+	}
+	tx.SystemTx = isSystemTx(engine, b.Transactions()[*tx.TransactionIndex], b.Header())
+}
+
+// isSystemTx returns whether the transaction of the given block was injected by
+// the PoSA consensus engine, e.g. to distribute the rewards or to update the
+// validator set.
+func isSystemTx(engine consensus.Engine, tx *types.Transaction, header *types.Header) bool {
+	posa, ok := engine.(consensus.PoSA)
+	if !ok || header == nil {
+		return false
+	}
+	isSystem, _ := posa.IsSystemTransaction(tx, header)
+	return isSystem
+}
+
 // newRPCTransactionFromBlockHash returns a transaction that will serialize to the RPC representation.
 func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransaction {
 	for idx, tx := range b.Transactions() {
@@ -1548,32 +2061,63 @@ type accessListResult struct {
 	Accesslist *types.AccessList `json:"accessList"`
 	Error      string            `json:"error,omitempty"`
 	GasUsed    hexutil.Uint64    `json:"gasUsed"`
+	Storage    []accessListSlot  `json:"storage,omitempty"`
+}
+
+// accessListSlot details how the transaction accessed a storage slot of the
+// access list, and the gas saved by listing it.
+type accessListSlot struct {
+	Address  common.Address `json:"address"`
+	Slot     common.Hash    `json:"slot"`
+	Read     bool           `json:"read"`
+	Written  bool           `json:"written"`
+	GasSaved hexutil.Uint64 `json:"gasSaved"`
 }
 
 // CreateAccessList creates a EIP-2930 type AccessList for the given transaction.
 // Reexec and BlockNrOrHash can be specified to create the accessList on top of a certain state.
-func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args SendTxArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*accessListResult, error) {
+// If storageDetails is set, the read/write classification of every listed storage
+// slot is returned too, along with the gas its access list entry saves.
+func (s *PublicBlockChainAPI) CreateAccessList(ctx context.Context, args SendTxArgs, blockNrOrHash *rpc.BlockNumberOrHash, storageDetails *bool) (*accessListResult, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	acl, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
+	tracer, gasUsed, vmerr, err := AccessList(ctx, s.b, bNrOrHash, args)
 	if err != nil {
 		return nil, err
 	}
+	acl := tracer.AccessList()
 	result := &accessListResult{Accesslist: &acl, GasUsed: hexutil.Uint64(gasUsed)}
 	if vmerr != nil {
 		result.Error = vmerr.Error()
 	}
+	if storageDetails != nil && *storageDetails {
+		result.Storage = []accessListSlot{}
+		for _, tuple := range acl {
+			for _, slot := range tuple.StorageKeys {
+				access := tracer.SlotAccess(tuple.Address, slot)
+				result.Storage = append(result.Storage, accessListSlot{
+					Address:  tuple.Address,
+					Slot:     slot,
+					Read:     access.Read,
+					Written:  access.Written,
+					GasSaved: hexutil.Uint64(access.GasSaved()),
+				})
+			}
+		}
+	}
 	return result, nil
 }
 
-// AccessList creates an access list for the given transaction.
+// AccessList creates an access list for the given transaction, executing it with
+// the access list gathered by the previous run until the list reaches a fixpoint.
+// The tracer of the last run is returned, holding the resulting access list.
 // If the accesslist creation fails an error is returned.
 // If the transaction itself fails, an vmErr is returned.
-func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args SendTxArgs) (acl types.AccessList, gasUsed uint64, vmErr error, err error) {
+func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash, args SendTxArgs) (tracer *vm.AccessListTracer, gasUsed uint64, vmErr error, err error) {
 	// Retrieve the execution context
-	db, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	db, header, err := stateAndHeader(ctx, b, blockNrOrHash)
 	if db == nil || err != nil {
 		return nil, 0, nil, err
 	}
@@ -1612,9 +2156,12 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 
 		// If no gas amount was specified, each unique access list needs it's own
 		// gas calculation. This is quite expensive, but we need to be accurate
-		// and it's convered by the sender only anyway.
+		// and it's convered by the sender only anyway. The estimation must use
+		// the list being expanded, otherwise the intrinsic gas of the new entries
+		// may starve the execution, yielding an insufficient list.
 		if nogas {
 			args.Gas = nil
+			args.AccessList = &accessList
 			if err := args.setDefaults(ctx, b); err != nil {
 				return nil, 0, nil, err // shouldn't happen, just in case
 			}
@@ -1625,17 +2172,18 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 
 		// Apply the transaction with the access list tracer
 		tracer := vm.NewAccessListTracer(accessList, args.From, to, precompiles)
-		config := vm.Config{Tracer: tracer, Debug: true}
+		config := vm.Config{Tracer: tracer, Debug: true, NoBaseFee: true}
 		vmenv, _, err := b.GetEVM(ctx, msg, statedb, header, &config)
 		if err != nil {
 			return nil, 0, nil, err
 		}
 		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+		traceStateReads(ctx, statedb)
 		if err != nil {
 			return nil, 0, nil, fmt.Errorf("failed to apply transaction: %v err: %v", args.toTransaction().Hash(), err)
 		}
 		if tracer.Equal(prevTracer) {
-			return accessList, res.UsedGas, res.Err, nil
+			return tracer, res.UsedGas, res.Err, nil
 		}
 		prevTracer = tracer
 	}
@@ -1677,7 +2225,11 @@ func (s *PublicTransactionPoolAPI) GetBlockTransactionCountByHash(ctx context.Co
 // GetTransactionsByBlockNumber returns all the transactions for the given block number.
 func (s *PublicTransactionPoolAPI) GetTransactionsByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) []*RPCTransaction {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
-		return newRPCTransactionsFromBlockIndex(block)
+		txs := newRPCTransactionsFromBlockIndex(block)
+		for _, tx := range txs {
+			markSystemTx(s.b.Engine(), block, tx)
+		}
+		return txs
 	}
 	return nil
 }
@@ -1685,7 +2237,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionsByBlockNumber(ctx context.Cont
 // GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) *RPCTransaction {
 	if block, _ := s.b.BlockByNumber(ctx, blockNr); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint64(index))
+		tx := newRPCTransactionFromBlockIndex(block, uint64(index))
+		markSystemTx(s.b.Engine(), block, tx)
+		return tx
 	}
 	return nil
 }
@@ -1693,7 +2247,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionByBlockNumberAndIndex(ctx conte
 // GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
 func (s *PublicTransactionPoolAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) *RPCTransaction {
 	if block, _ := s.b.BlockByHash(ctx, blockHash); block != nil {
-		return newRPCTransactionFromBlockIndex(block, uint64(index))
+		tx := newRPCTransactionFromBlockIndex(block, uint64(index))
+		markSystemTx(s.b.Engine(), block, tx)
+		return tx
 	}
 	return nil
 }
@@ -1714,6 +2270,64 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 	return nil
 }
 
+// maxInternalTransactions is the maximum number of transactions returned by a
+// single eth_getInternalTransactionsByAddress call.
+const maxInternalTransactions = 1000
+
+// GetInternalTransactionsByAddress returns the transactions of the canonical
+// chain whose internal calls were made from or to the given address, within the
+// given block range, as recorded by the internal call index. At most 1000
+// transactions are returned, further ones can be fetched by starting the range
+// after the block of the last one.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock *rpc.BlockNumber) ([]*RPCTransaction, error) {
+	db := s.b.ChainDb()
+	tail := rawdb.ReadInternalCallIndexTail(db)
+	if tail == nil {
+		return nil, errors.New("internal call index not enabled")
+	}
+	head, err := s.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	from, to := *tail, head.Number.Uint64()
+	if fromBlock != nil && *fromBlock >= 0 {
+		from = uint64(*fromBlock)
+	}
+	if toBlock != nil && *toBlock >= 0 && uint64(*toBlock) < to {
+		to = uint64(*toBlock)
+	}
+	if from < *tail {
+		return nil, fmt.Errorf("block %d not indexed, internal calls are indexed from block %d", from, *tail)
+	}
+	var (
+		txs      = make([]*RPCTransaction, 0)
+		block    *types.Block
+		fetchErr error
+	)
+	err = rawdb.IterateInternalCalls(db, address, from, to, func(call rawdb.InternalCall) bool {
+		// Skip the entries of blocks reorged out of the canonical chain
+		if rawdb.ReadCanonicalHash(db, call.BlockNumber) != call.BlockHash {
+			return true
+		}
+		if block == nil || block.Hash() != call.BlockHash {
+			if block, fetchErr = s.b.BlockByHash(ctx, call.BlockHash); block == nil {
+				return fetchErr == nil
+			}
+		}
+		if tx := newRPCTransactionFromBlockIndex(block, uint64(call.TxIndex)); tx != nil {
+			txs = append(txs, tx)
+		}
+		return len(txs) < maxInternalTransactions
+	})
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
 // GetTransactionCount returns the number of transactions the given address has sent for the given block number
 func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
 	// Ask transaction pool for the nonce which includes pending transactions
@@ -1725,14 +2339,57 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 		return (*hexutil.Uint64)(&nonce), nil
 	}
 	// Resolve block number and use its state to ask for the nonce
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	state, _, err := stateAndHeader(ctx, s.b, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, err
 	}
+	defer traceStateReads(ctx, state)
 	nonce := state.GetNonce(address)
 	return (*hexutil.Uint64)(&nonce), state.Error()
 }
 
+// txIndexGapError is an API error reporting a transaction missing from an index
+// keeping only the transactions of allowlisted addresses beyond its window.
+type txIndexGapError struct {
+	tail          uint64 // Oldest block whose transactions are all indexed
+	allowlistTail uint64 // Oldest block whose allowlisted transactions are indexed
+}
+
+func (e *txIndexGapError) Error() string {
+	msg := fmt.Sprintf("transaction not found: blocks before #%d are only indexed for allowlisted addresses", e.tail)
+	if e.allowlistTail > 0 {
+		msg += fmt.Sprintf(", blocks before #%d are not indexed yet", e.allowlistTail)
+	}
+	return msg
+}
+
+// ErrorData returns the indexed ranges.
+func (e *txIndexGapError) ErrorData() interface{} {
+	return map[string]hexutil.Uint64{
+		"indexTail":     hexutil.Uint64(e.tail),
+		"allowlistTail": hexutil.Uint64(e.allowlistTail),
+	}
+}
+
+// txIndexGap returns the error to report for a transaction missing from the
+// index, nil if all the transactions of the chain could be found in it or if
+// the index is limited to a recent window only.
+func txIndexGap(b Backend) error {
+	db := b.ChainDb()
+	if rawdb.ReadTxIndexAllowlist(db) == nil {
+		return nil
+	}
+	tail := rawdb.ReadTxIndexTail(db)
+	if tail == nil || *tail == 0 {
+		return nil
+	}
+	allowlistTail := *tail
+	if stored := rawdb.ReadTxIndexAllowlistTail(db); stored != nil && *stored < allowlistTail {
+		allowlistTail = *stored
+	}
+	return &txIndexGapError{tail: *tail, allowlistTail: allowlistTail}
+}
+
 // GetTransactionByHash returns the transaction for the given hash
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
@@ -1741,14 +2398,113 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 		return nil, err
 	}
 	if tx != nil {
-		return newRPCTransaction(tx, blockHash, blockNumber, index), nil
+		rpcTx := newRPCTransaction(tx, blockHash, blockNumber, index)
+		if header, _ := s.b.HeaderByHash(ctx, blockHash); header != nil {
+			rpcTx.SystemTx = isSystemTx(s.b.Engine(), tx, header)
+		}
+		return rpcTx, nil
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx), nil
+		return NewRPCPendingTransaction(tx), nil
 	}
 
 	// Transaction unknown, return as such
+	return nil, txIndexGap(s.b)
+}
+
+var errSenderIndexNotEnabled = errors.New("sender index not enabled")
+
+// GetTransactionBySenderAndNonce returns the transaction sent by an address with
+// the given nonce, the canonical one if it was included in a block and the pooled
+// one otherwise. It requires the sender index to be enabled.
+func (s *PublicTransactionPoolAPI) GetTransactionBySenderAndNonce(ctx context.Context, address common.Address, nonce hexutil.Uint64) (*RPCTransaction, error) {
+	db := s.b.ChainDb()
+	if rawdb.ReadSenderTxIndexTail(db) == nil {
+		return nil, errSenderIndexNotEnabled
+	}
+	// The entries of reorged or rewound blocks are kept, check the chain
+	if entry := rawdb.ReadSenderTx(db, address, uint64(nonce)); entry != nil {
+		block, err := s.b.BlockByNumber(ctx, rpc.BlockNumber(entry.BlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		if block != nil {
+			for i, tx := range block.Transactions() {
+				if tx.Hash() != entry.TxHash {
+					continue
+				}
+				rpcTx := newRPCTransaction(tx, block.Hash(), block.NumberU64(), uint64(i))
+				rpcTx.SystemTx = isSystemTx(s.b.Engine(), tx, block.Header())
+				return rpcTx, nil
+			}
+		}
+	}
+	pending, queue := s.b.TxPoolContent()
+	for _, txs := range []types.Transactions{pending[address], queue[address]} {
+		for _, tx := range txs {
+			if tx.Nonce() == uint64(nonce) {
+				return NewRPCPendingTransaction(tx), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+var errContractIndexNotEnabled = errors.New("contract creation index not enabled")
+
+// RPCContractCreation is the transaction deploying a contract, along with the
+// account creating it.
+type RPCContractCreation struct {
+	Address          common.Address `json:"contractAddress"`
+	Creator          common.Address `json:"creator"`
+	TxHash           common.Hash    `json:"transactionHash"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+}
+
+// GetContractCreation returns the transaction deploying a contract and the account
+// creating it, the sender of the transaction or the contract running the creation.
+// It requires the contract creation index to be enabled, nil is returned for the
+// contracts deployed before the oldest indexed block.
+func (s *PublicTransactionPoolAPI) GetContractCreation(ctx context.Context, address common.Address) (*RPCContractCreation, error) {
+	if rawdb.ReadContractCreationIndexTail(s.b.ChainDb()) == nil {
+		return nil, errContractIndexNotEnabled
+	}
+	return lookupContractCreation(ctx, s.b, address)
+}
+
+// lookupContractCreation retrieves the latest canonical creation of a contract
+// from the contract creation index, nil if none was indexed.
+func lookupContractCreation(ctx context.Context, b Backend, address common.Address) (*RPCContractCreation, error) {
+	db := b.ChainDb()
+	creations, err := rawdb.ReadContractCreations(db, address)
+	if err != nil {
+		return nil, err
+	}
+	// The entries of reorged blocks are kept, check the chain
+	for i := len(creations) - 1; i >= 0; i-- {
+		creation := creations[i]
+		if rawdb.ReadCanonicalHash(db, creation.BlockNumber) != creation.BlockHash {
+			continue
+		}
+		block, err := b.BlockByHash(ctx, creation.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil || int(creation.TxIndex) >= len(block.Transactions()) {
+			continue
+		}
+		return &RPCContractCreation{
+			Address:          address,
+			Creator:          creation.Creator,
+			TxHash:           block.Transactions()[creation.TxIndex].Hash(),
+			BlockHash:        creation.BlockHash,
+			BlockNumber:      hexutil.Uint64(creation.BlockNumber),
+			TransactionIndex: hexutil.Uint64(creation.TxIndex),
+		}, nil
+	}
 	return nil, nil
 }
 
@@ -1762,13 +2518,62 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 	if tx == nil {
 		if tx = s.b.GetPoolTransaction(hash); tx == nil {
 			// Transaction not found anywhere, abort
-			return nil, nil
+			return nil, txIndexGap(s.b)
 		}
 	}
 	// Serialize to RLP and return
 	return tx.MarshalBinary()
 }
 
+// BlockReceiptsConfig selects the optional fields of the receipts returned by
+// eth_getBlockReceipts, all of them being included by default.
+type BlockReceiptsConfig struct {
+	Logs              *bool `json:"logs"`              // Whether to include the logs emitted by the transactions
+	EffectiveGasPrice *bool `json:"effectiveGasPrice"` // Whether to include the gas price paid by the transactions
+}
+
+// GetBlockReceipts returns the receipts of all the transactions of a block, in
+// the format of eth_getTransactionReceipt, sparing a call per transaction.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *BlockReceiptsConfig) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("receipts of block %#x mismatch its transactions: have %d, want %d", block.Hash(), len(receipts), len(txs))
+	}
+	var (
+		logs              = config == nil || config.Logs == nil || *config.Logs
+		effectiveGasPrice = config == nil || config.EffectiveGasPrice == nil || *config.EffectiveGasPrice
+		signer            = types.MakeSigner(s.b.ChainConfig(), block.Number())
+		result            = make([]map[string]interface{}, len(receipts))
+	)
+	for i, receipt := range receipts {
+		fields := marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
+		if isSystemTx(s.b.Engine(), txs[i], block.Header()) {
+			fields["systemTx"] = true
+		}
+		if !logs {
+			delete(fields, "logs")
+		}
+		if !effectiveGasPrice {
+			delete(fields, "effectiveGasPrice")
+		}
+		result[i] = fields
+	}
+	return result, nil
+}
+
+// GetTransactionReceiptsByBlock is an alias of GetBlockReceipts.
+func (s *PublicTransactionPoolAPI) GetTransactionReceiptsByBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *BlockReceiptsConfig) ([]map[string]interface{}, error) {
+	return s.GetBlockReceipts(ctx, blockNrOrHash, config)
+}
+
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
 func (s *PublicTransactionPoolAPI) GetTransactionReceiptsByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) ([]map[string]interface{}, error) {
 	blockNumber := uint64(blockNr.Int64())
@@ -1805,6 +2610,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceiptsByBlockNumber(ctx conte
 			"to":                tx.To(),
 			"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 			"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+			"effectiveGasPrice": (*hexutil.Big)(tx.GasPrice()),
 			"contractAddress":   nil,
 			"logs":              receipt.Logs,
 			"logsBloom":         receipt.Bloom,
@@ -1823,6 +2629,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceiptsByBlockNumber(ctx conte
 		if receipt.ContractAddress != (common.Address{}) {
 			fields["contractAddress"] = receipt.ContractAddress
 		}
+		if isSystemTx(s.b.Engine(), tx, block.Header()) {
+			fields["systemTx"] = true
+		}
 
 		txReceipts = append(txReceipts, fields)
 	}
@@ -1834,7 +2643,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceiptsByBlockNumber(ctx conte
 func (s *PublicTransactionPoolAPI) GetTransactionDataAndReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
 	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
 	if tx == nil {
-		return nil, nil
+		return nil, txIndexGap(s.b)
 	}
 	receipts, err := s.b.GetReceipts(ctx, blockHash)
 	if err != nil {
@@ -1879,6 +2688,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionDataAndReceipt(ctx context.Cont
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(tx.GasPrice()),
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
@@ -1910,6 +2720,9 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if err != nil {
 		return nil, nil
 	}
+	if tx == nil {
+		return nil, txIndexGap(s.b)
+	}
 	receipts, err := s.b.GetReceipts(ctx, blockHash)
 	if err != nil {
 		return nil, err
@@ -1917,22 +2730,38 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
-
 	// Derive the sender.
 	bigblock := new(big.Int).SetUint64(blockNumber)
 	signer := types.MakeSigner(s.b.ChainConfig(), bigblock)
+	fields := marshalReceipt(receipts[index], blockHash, blockNumber, signer, tx, int(index))
+
+	// Flag the transactions injected by the consensus engine
+	if header, _ := s.b.HeaderByHash(ctx, blockHash); isSystemTx(s.b.Engine(), tx, header) {
+		fields["systemTx"] = true
+	}
+	// Attach the revert reason recorded on import to the failed transactions
+	if receipts[index].Status == types.ReceiptStatusFailed {
+		if revert := recordedRevert(s.b.ChainDb(), blockHash, blockNumber, index); revert != nil && len(revert.Output) > 0 {
+			fields["revertReason"] = hexutil.Bytes(revert.Output)
+		}
+	}
+	return fields, nil
+}
+
+// marshalReceipt converts the receipt of the given transaction to the RPC output.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, index int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(tx.GasPrice()),
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
@@ -1952,7 +2781,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1984,6 +2813,9 @@ type SendTxArgs struct {
 	// For non-legacy transactions
 	AccessList *types.AccessList `json:"accessList,omitempty"`
 	ChainID    *hexutil.Big      `json:"chainId,omitempty"`
+
+	// For EIP-7702 set-code transactions
+	AuthorizationList []types.SetCodeAuthorization `json:"authorizationList,omitempty"`
 }
 
 // setDefaults fills in default values for unspecified tx fields.
@@ -2019,6 +2851,9 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 		if len(input) == 0 {
 			return errors.New(`contract creation without any data provided`)
 		}
+		if args.AuthorizationList != nil {
+			return errors.New(`set-code transactions can't create contracts`)
+		}
 	}
 	// Estimate the gas usage if necessary.
 	if args.Gas == nil {
@@ -2035,9 +2870,11 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			Value:      args.Value,
 			Data:       input,
 			AccessList: args.AccessList,
+
+			AuthorizationList: args.AuthorizationList,
 		}
 		pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-		estimated, err := DoEstimateGas(ctx, b, callArgs, pendingBlockNr, b.RPCGasCap())
+		estimated, err := DoEstimateGas(ctx, b, callArgs, pendingBlockNr, b.RPCGasCap(), nil)
 		if err != nil {
 			return err
 		}
@@ -2061,7 +2898,23 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 		input = *args.Data
 	}
 	var data types.TxData
-	if args.AccessList == nil {
+	if args.AuthorizationList != nil {
+		var accessList types.AccessList
+		if args.AccessList != nil {
+			accessList = *args.AccessList
+		}
+		data = &types.SetCodeTx{
+			To:         *args.To,
+			ChainID:    (*big.Int)(args.ChainID),
+			Nonce:      uint64(*args.Nonce),
+			Gas:        uint64(*args.Gas),
+			GasPrice:   (*big.Int)(args.GasPrice),
+			Value:      (*big.Int)(args.Value),
+			Data:       input,
+			AccessList: accessList,
+			AuthList:   args.AuthorizationList,
+		}
+	} else if args.AccessList == nil {
 		data = &types.LegacyTx{
 			To:       args.To,
 			Nonce:    uint64(*args.Nonce),
@@ -2087,15 +2940,9 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 
 // SubmitTransaction is a helper function that submits tx to txPool and logs a message.
 func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
-	// If the transaction fee cap is already specified, ensure the
-	// fee of the given transaction is _reasonable_.
-	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
+	if err := checkSubmission(b, tx); err != nil {
 		return common.Hash{}, err
 	}
-	if !b.UnprotectedAllowed() && !tx.Protected() {
-		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
-		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
-	}
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
@@ -2115,6 +2962,21 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 	return tx.Hash(), nil
 }
 
+// checkSubmission ensures a transaction is acceptable over RPC before it is
+// submitted to the transaction pool.
+func checkSubmission(b Backend, tx *types.Transaction) error {
+	// If the transaction fee cap is already specified, ensure the
+	// fee of the given transaction is _reasonable_.
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), b.RPCTxFeeCap()); err != nil {
+		return err
+	}
+	if !b.UnprotectedAllowed() && !tx.Protected() {
+		// Ensure only eip155 signed transactions are submitted if EIP155Required is set.
+		return errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+	}
+	return nil
+}
+
 // SendTransaction creates a transaction for the given argument, sign it and submit it to the
 // transaction pool.
 func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args SendTxArgs) (common.Hash, error) {
@@ -2173,6 +3035,24 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, input
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateTransaction will add the signed transaction to the transaction pool
+// without gossiping it to the network. It is relayed only to the trusted private
+// transaction peers, keeping it hidden from front-running until it is mined.
+func (s *PublicTransactionPoolAPI) SendPrivateTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkSubmission(s.b, tx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendPrivateTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+	return tx.Hash(), nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -2252,7 +3132,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		from, _ := types.Sender(s.signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -2427,6 +3307,37 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 	return nil
 }
 
+// DbGet returns the raw value of a key stored in the key-value database.
+func (api *PrivateDebugAPI) DbGet(key hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.b.ChainDb().Get(key)
+}
+
+// DbAncient retrieves an ancient binary blob from the append-only immutable files.
+func (api *PrivateDebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error) {
+	return api.b.ChainDb().Ancient(kind, number)
+}
+
+// AncientInfo describes the content of the ancient store.
+type AncientInfo struct {
+	Ancients uint64 `json:"ancients"` // Number of the first block not in the ancient store
+	Items    uint64 `json:"items"`    // Number of blocks actually kept in the ancient store
+	Offset   uint64 `json:"offset"`   // Number of the first block kept in the ancient store
+}
+
+// DbAncients returns the range of blocks held in the ancient store.
+func (api *PrivateDebugAPI) DbAncients() (*AncientInfo, error) {
+	db := api.b.ChainDb()
+	ancients, err := db.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	items, err := db.ItemAmountInAncient()
+	if err != nil {
+		return nil, err
+	}
+	return &AncientInfo{Ancients: ancients, Items: items, Offset: db.AncientOffSet()}, nil
+}
+
 // SetHead rewinds the head of the blockchain to a previous block.
 func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
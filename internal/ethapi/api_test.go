@@ -0,0 +1,247 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(params.Ether)
+
+	// testStore stores the first calldata word in slot 0, reverting if it's stored
+	// already, or returns the slot if called without data.
+	testStore     = common.HexToAddress("0x0100")
+	testStoreCode = common.FromHex("0x36600f5760005460005260206000f35b6000358060005414601f57600055005b600080fd")
+)
+
+// testBackend is a Backend serving the API from a local chain and a mocked
+// transaction pool. The methods not needed by the tests are left unimplemented.
+type testBackend struct {
+	Backend
+
+	db      ethdb.Database
+	chain   *core.BlockChain
+	pending map[common.Address]types.Transactions
+	queued  map[common.Address]types.Transactions
+	pooled  int // Number of times the pool content was retrieved
+}
+
+func newTestBackend(t *testing.T, n int, generator func(i int, b *core.BlockGen)) *testBackend {
+	var (
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 30_000_000,
+			Alloc: core.GenesisAlloc{
+				testAddr:  {Balance: testBalance},
+				testStore: {Balance: new(big.Int), Code: testStoreCode},
+			},
+		}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, engine, gendb, n, generator)
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	return &testBackend{
+		db:      db,
+		chain:   chain,
+		pending: make(map[common.Address]types.Transactions),
+		queued:  make(map[common.Address]types.Transactions),
+	}
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *testBackend) ChainDb() ethdb.Database          { return b.db }
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
+func (b *testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, txHash)
+	return tx, blockHash, blockNumber, index, nil
+}
+
+func (b *testBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return b.chain.GetHeaderByHash(hash), nil
+	}
+	number, _ := blockNrOrHash.Number()
+	if number < 0 {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	header, _ := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil {
+		return nil, nil
+	}
+	return b.chain.GetBlock(header.Hash(), header.Number.Uint64()), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil {
+		return nil, nil, errors.New("header not found")
+	}
+	state, err := b.chain.StateAt(header.Root)
+	return state, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmConfig *vm.Config) (*vm.EVM, func() error, error) {
+	txContext := core.NewEVMTxContext(msg)
+	context := core.NewEVMBlockContext(header, b.chain, nil)
+	return vm.NewEVM(context, txContext, state, b.chain.Config(), *vmConfig), func() error { return nil }, nil
+}
+
+func (b *testBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	b.pooled++
+	return b.pending, b.queued
+}
+
+// storeTx creates a transaction of the test account storing the given value in
+// the test contract.
+func storeTx(t *testing.T, nonce uint64, value byte) *types.Transaction {
+	tx, err := types.SignTx(types.NewTransaction(nonce, testStore, new(big.Int), 100000, new(big.Int), common.LeftPadBytes([]byte{value}, 32)), types.HomesteadSigner{}, testKey)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+var (
+	pendingBlock = rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	latestBlock  = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+)
+
+// Tests that calls against the pending block see the pooled transactions of the
+// sender, up to the first nonce gap.
+func TestCallPendingPooledTxs(t *testing.T) {
+	backend := newTestBackend(t, 1, nil)
+	backend.pending[testAddr] = types.Transactions{storeTx(t, 0, 1)}
+	backend.queued[testAddr] = types.Transactions{storeTx(t, 2, 3)}
+
+	other := common.Address{0xaa}
+	tests := []struct {
+		from  common.Address
+		block rpc.BlockNumberOrHash
+		want  byte
+	}{
+		{testAddr, pendingBlock, 1},
+		{testAddr, latestBlock, 0},
+		{other, pendingBlock, 0},
+	}
+	for i, tt := range tests {
+		from := tt.from
+		result, err := DoCall(context.Background(), backend, CallArgs{From: &from, To: &testStore}, tt.block, nil, vm.Config{NoBaseFee: true}, 0, 0)
+		if err != nil {
+			t.Fatalf("test %d: call failed: %v", i, err)
+		}
+		if have := new(big.Int).SetBytes(result.Return()); have.Uint64() != uint64(tt.want) {
+			t.Errorf("test %d: result mismatch: have %v, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the replay of the pooled transactions is aborted once the context
+// of the call is done.
+func TestCallPendingCancelled(t *testing.T) {
+	backend := newTestBackend(t, 1, nil)
+	backend.pending[testAddr] = types.Transactions{storeTx(t, 0, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DoCall(ctx, backend, CallArgs{From: &testAddr, To: &testStore}, pendingBlock, nil, vm.Config{NoBaseFee: true}, 0, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
+
+// Tests that gas estimations against the pending block account for the pooled
+// transactions of the sender, every execution of the search starting from the
+// same pending state.
+func TestEstimateGasPooledTx(t *testing.T) {
+	backend := newTestBackend(t, 1, nil)
+	backend.pending[testAddr] = types.Transactions{storeTx(t, 0, 1)}
+
+	data := hexutil.Bytes(common.LeftPadBytes([]byte{2}, 32))
+	args := CallArgs{From: &testAddr, To: &testStore, Data: &data}
+
+	latest, err := DoEstimateGas(context.Background(), backend, args, latestBlock, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to estimate against the latest block: %v", err)
+	}
+	pending, err := DoEstimateGas(context.Background(), backend, args, pendingBlock, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to estimate against the pending block: %v", err)
+	}
+	// Overwriting the slot set by the pooled transaction is cheaper than setting it,
+	// the executions of the search reverting if they observed each other's writes
+	if pending >= latest {
+		t.Fatalf("pending estimate not below latest: pending %d, latest %d", pending, latest)
+	}
+	if backend.pooled != 1 {
+		t.Fatalf("pooled transactions replayed %d times, want once", backend.pooled)
+	}
+	// The estimate must suffice on the pending state
+	gas := pending
+	args.Gas = &gas
+	result, err := DoCall(context.Background(), backend, args, pendingBlock, nil, vm.Config{NoBaseFee: true}, 0, 0)
+	if err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	if result.Failed() {
+		t.Fatalf("call with the estimated gas %d failed: %v", pending, result.Err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// estimateGasErrorRatio is the amount of overestimation eth_estimateGas is
+// allowed to produce in order to speed up calculations.
+const estimateGasErrorRatio = 0.015
+
+// EstimateGasOptions tunes the reporting of eth_estimateGas.
+type EstimateGasOptions struct {
+	// FullError reports the call frames failing during a reverted estimation,
+	// along with their decoded revert reasons.
+	FullError bool `json:"fullError"`
+}
+
+// CallFrameFailure is a call frame failing during the execution of a call.
+type CallFrameFailure struct {
+	Type   string         `json:"type"`
+	Depth  int            `json:"depth"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Error  string         `json:"error"`
+	Reason string         `json:"reason,omitempty"` // Decoded revert reason
+	Output hexutil.Bytes  `json:"output,omitempty"` // Raw revert data
+}
+
+// callFrameError is an API error reporting a reverted call along with the call
+// frames failing during its execution, in the order they were entered.
+type callFrameError struct {
+	*revertError
+	frames []*CallFrameFailure
+}
+
+// ErrorData returns the hex encoded revert reason and the failing call frames.
+func (e *callFrameError) ErrorData() interface{} {
+	return map[string]interface{}{
+		"data":   e.reason,
+		"frames": e.frames,
+	}
+}
+
+// frameTracer is an EVM logger collecting the call frames failing during the
+// execution of a call.
+type frameTracer struct {
+	frames []*CallFrameFailure
+	open   []*CallFrameFailure // Call frames entered but not exited yet
+}
+
+// failures returns the failing call frames, in the order they were entered.
+func (t *frameTracer) failures() []*CallFrameFailure {
+	var failed []*CallFrameFailure
+	for _, frame := range t.frames {
+		if frame.Error != "" {
+			failed = append(failed, frame)
+		}
+	}
+	return failed
+}
+
+func (t *frameTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.enter(typ, from, to)
+}
+
+func (t *frameTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.enter(typ, from, to)
+}
+
+func (t *frameTracer) enter(typ vm.OpCode, from common.Address, to common.Address) {
+	frame := &CallFrameFailure{
+		Type:  typ.String(),
+		Depth: len(t.open),
+		From:  from,
+		To:    to,
+	}
+	t.frames = append(t.frames, frame)
+	t.open = append(t.open, frame)
+}
+
+func (t *frameTracer) exit(output []byte, err error) {
+	if len(t.open) == 0 {
+		return
+	}
+	frame := t.open[len(t.open)-1]
+	t.open = t.open[:len(t.open)-1]
+
+	if err == nil {
+		return
+	}
+	frame.Error = err.Error()
+	if err == vm.ErrExecutionReverted && len(output) > 0 {
+		frame.Output = common.CopyBytes(output)
+		if reason, err := abi.UnpackRevert(output); err == nil {
+			frame.Reason = reason
+		}
+	}
+}
+
+func (t *frameTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit(output, err)
+}
+
+func (t *frameTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	t.exit(output, err)
+}
+
+func (t *frameTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *frameTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *frameTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *frameTracer) CaptureTxEnd(restGas uint64) {}
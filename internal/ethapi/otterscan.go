@@ -0,0 +1,714 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// otterscanAPILevel is the version of the Otterscan API implemented.
+	otterscanAPILevel = 8
+
+	// otterscanReexec is the number of blocks re-executed to regenerate the
+	// state missing to replay a transaction.
+	otterscanReexec = 128
+
+	// otterscanSearchWindow is the number of blocks whose index entries are
+	// loaded at once while searching the transactions of an address.
+	otterscanSearchWindow = 100000
+)
+
+var errOtterscanNotEnabled = errors.New("otterscan indexes not enabled")
+
+// The kinds of internal operations.
+const (
+	opTransfer     = 0
+	opSelfDestruct = 1
+	opCreate       = 2
+	opCreate2      = 3
+)
+
+// InternalOperation is a value transfer, a self destruct or a contract creation
+// made by an internal call of a transaction.
+type InternalOperation struct {
+	Type  int            `json:"type"`
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+// OtsTraceEntry is a call frame of a transaction, in the Otterscan format.
+type OtsTraceEntry struct {
+	Type   string         `json:"type"`
+	Depth  int            `json:"depth"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *hexutil.Big   `json:"value"`
+	Input  hexutil.Bytes  `json:"input"`
+	Output hexutil.Bytes  `json:"output"`
+}
+
+// ContractCreator is the transaction deploying a contract and its sender.
+type ContractCreator struct {
+	Tx      common.Hash    `json:"hash"`
+	Creator common.Address `json:"creator"`
+}
+
+// TransactionsWithReceipts is a page of the transactions of an address, most
+// recent first.
+type TransactionsWithReceipts struct {
+	Txs       []*RPCTransaction        `json:"txs"`
+	Receipts  []map[string]interface{} `json:"receipts"`
+	FirstPage bool                     `json:"firstPage"` // Whether the page holds the most recent transactions
+	LastPage  bool                     `json:"lastPage"`  // Whether the page holds the oldest transactions
+}
+
+// otsTracer is an EVM logger collecting the call frames and the internal
+// operations of a transaction.
+type otsTracer struct {
+	entries []*OtsTraceEntry
+	open    []*OtsTraceEntry // Call frames entered but not exited yet
+	ops     []*InternalOperation
+}
+
+func (t *otsTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.enter(typ, from, to, input, value)
+}
+
+func (t *otsTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.enter(typ, from, to, input, value)
+
+	switch {
+	case typ == vm.CALL && value != nil && value.Sign() > 0:
+		t.ops = append(t.ops, &InternalOperation{Type: opTransfer, From: from, To: to, Value: (*hexutil.Big)(new(big.Int).Set(value))})
+	case typ == vm.SELFDESTRUCT:
+		t.ops = append(t.ops, &InternalOperation{Type: opSelfDestruct, From: from, To: to, Value: (*hexutil.Big)(new(big.Int).Set(value))})
+	case typ == vm.CREATE:
+		t.ops = append(t.ops, &InternalOperation{Type: opCreate, From: from, To: to, Value: (*hexutil.Big)(new(big.Int).Set(value))})
+	case typ == vm.CREATE2:
+		t.ops = append(t.ops, &InternalOperation{Type: opCreate2, From: from, To: to, Value: (*hexutil.Big)(new(big.Int).Set(value))})
+	}
+}
+
+func (t *otsTracer) enter(typ vm.OpCode, from common.Address, to common.Address, input []byte, value *big.Int) {
+	entry := &OtsTraceEntry{
+		Type:  typ.String(),
+		Depth: len(t.open),
+		From:  from,
+		To:    to,
+		Input: common.CopyBytes(input),
+	}
+	if typ != vm.DELEGATECALL && typ != vm.STATICCALL && value != nil {
+		entry.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	t.entries = append(t.entries, entry)
+	t.open = append(t.open, entry)
+}
+
+func (t *otsTracer) exit(output []byte) {
+	if len(t.open) == 0 {
+		return
+	}
+	t.open[len(t.open)-1].Output = common.CopyBytes(output)
+	t.open = t.open[:len(t.open)-1]
+}
+
+func (t *otsTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit(output)
+}
+
+func (t *otsTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	t.exit(output)
+}
+
+func (t *otsTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *otsTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *otsTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *otsTracer) CaptureTxEnd(restGas uint64) {}
+
+// txPosition locates a transaction in the chain.
+type txPosition struct {
+	number uint64
+	hash   common.Hash
+	index  uint32
+}
+
+// PublicOtterscanAPI provides the APIs of the Otterscan block explorer, the
+// searches by address being served by the account transaction and the internal
+// call indexes.
+type PublicOtterscanAPI struct {
+	b Backend
+}
+
+// NewPublicOtterscanAPI creates a new Otterscan API.
+func NewPublicOtterscanAPI(b Backend) *PublicOtterscanAPI {
+	return &PublicOtterscanAPI{b}
+}
+
+// GetApiLevel returns the version of the Otterscan API implemented by the node.
+func (api *PublicOtterscanAPI) GetApiLevel() uint64 {
+	return otterscanAPILevel
+}
+
+// replayTransaction executes a transaction on top of the state it was included
+// at, collecting its call frames and internal operations.
+func (api *PublicOtterscanAPI) replayTransaction(ctx context.Context, hash common.Hash) (*otsTracer, *core.ExecutionResult, error) {
+	tx, blockHash, _, index, err := api.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tx == nil {
+		return nil, nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block, err := api.b.BlockByHash(ctx, blockHash)
+	if block == nil {
+		return nil, nil, fmt.Errorf("block %#x not found: %v", blockHash, err)
+	}
+	msg, blockCtx, statedb, err := api.b.StateAtTransaction(ctx, block, int(index), otterscanReexec)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracer := new(otsTracer)
+	vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.b.ChainConfig(), vm.Config{Debug: true, Tracer: tracer})
+
+	if posa, ok := api.b.Engine().(consensus.PoSA); ok {
+		if isSystem, _ := posa.IsSystemTransaction(tx, block.Header()); isSystem {
+			balance := statedb.GetBalance(consensus.SystemAddress)
+			if balance.Cmp(common.Big0) > 0 {
+				statedb.SetBalance(consensus.SystemAddress, big.NewInt(0))
+				statedb.AddBalance(block.Coinbase(), balance)
+			}
+			if blockRewards := posa.BlockRewards(block.Number()); blockRewards != nil {
+				statedb.AddBalance(block.Coinbase(), blockRewards)
+			}
+		}
+	}
+	statedb.Prepare(hash, blockHash, int(index))
+
+	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("transaction %#x failed: %v", hash, err)
+	}
+	return tracer, result, nil
+}
+
+// GetInternalOperations returns the value transfers, self destructs and
+// contract creations made by the internal calls of a transaction.
+func (api *PublicOtterscanAPI) GetInternalOperations(ctx context.Context, hash common.Hash) ([]*InternalOperation, error) {
+	tracer, _, err := api.replayTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tracer.ops == nil {
+		return []*InternalOperation{}, nil
+	}
+	return tracer.ops, nil
+}
+
+// TraceTransaction returns the call frames of a transaction, in the order they
+// were entered.
+func (api *PublicOtterscanAPI) TraceTransaction(ctx context.Context, hash common.Hash) ([]*OtsTraceEntry, error) {
+	tracer, _, err := api.replayTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return tracer.entries, nil
+}
+
+// GetTransactionError returns the revert data of a failed transaction, empty if
+// the transaction succeeded.
+func (api *PublicOtterscanAPI) GetTransactionError(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	_, result, err := api.replayTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return result.Revert(), nil
+}
+
+// HasCode returns whether the given address has code at the given block.
+func (api *PublicOtterscanAPI) HasCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (bool, error) {
+	state, _, err := stateAndHeader(ctx, api.b, blockNrOrHash)
+	if state == nil || err != nil {
+		return false, err
+	}
+	defer traceStateReads(ctx, state)
+
+	return len(state.GetCode(address)) > 0, state.Error()
+}
+
+// GetBlockDetails returns a block without its transactions, along with its
+// transaction count, the amounts it issued and the fees it collected.
+func (api *PublicOtterscanAPI) GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error) {
+	block, err := api.b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return api.blockDetails(ctx, block)
+}
+
+// GetBlockDetailsByHash returns a block without its transactions, along with
+// its transaction count, the amounts it issued and the fees it collected.
+func (api *PublicOtterscanAPI) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	block, err := api.b.BlockByHash(ctx, hash)
+	if block == nil {
+		return nil, err
+	}
+	return api.blockDetails(ctx, block)
+}
+
+func (api *PublicOtterscanAPI) blockDetails(ctx context.Context, block *types.Block) (map[string]interface{}, error) {
+	fields, err := RPCMarshalBlock(block, false, false)
+	if err != nil {
+		return nil, err
+	}
+	fields["transactionCount"] = len(block.Transactions())
+	fields["totalDifficulty"] = (*hexutil.Big)(api.b.GetTd(ctx, block.Hash()))
+	fields["logsBloom"] = nil
+
+	receipts, err := api.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	fees := new(big.Int)
+	for i, tx := range block.Transactions() {
+		if i < len(receipts) {
+			fees.Add(fees, new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipts[i].GasUsed)))
+		}
+	}
+	blockReward, uncleReward := new(big.Int), new(big.Int)
+	switch engine := api.b.Engine().(type) {
+	case consensus.PoSA:
+		if rewards := engine.BlockRewards(block.Number()); rewards != nil {
+			blockReward.Set(rewards)
+		}
+	case *ethash.Ethash:
+		blockReward, uncleReward = ethash.Rewards(api.b.ChainConfig(), block.Header(), block.Uncles())
+	}
+	return map[string]interface{}{
+		"block": fields,
+		"issuance": map[string]interface{}{
+			"blockReward": (*hexutil.Big)(blockReward),
+			"uncleReward": (*hexutil.Big)(uncleReward),
+			"issuance":    (*hexutil.Big)(new(big.Int).Add(blockReward, uncleReward)),
+		},
+		"totalFees": (*hexutil.Big)(fees),
+	}, nil
+}
+
+// GetBlockTransactions returns a page of the transactions of a block, with their
+// input cropped to the method selector, and their receipts without the logs. The
+// pages are counted from the end of the block.
+func (api *PublicOtterscanAPI) GetBlockTransactions(ctx context.Context, number rpc.BlockNumber, pageNumber uint8, pageSize uint8) (map[string]interface{}, error) {
+	block, err := api.b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	receipts, err := api.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	fields, err := RPCMarshalBlock(block, true, true)
+	if err != nil {
+		return nil, err
+	}
+	fields["transactionCount"] = len(block.Transactions())
+	fields["totalDifficulty"] = (*hexutil.Big)(api.b.GetTd(ctx, block.Hash()))
+	fields["logsBloom"] = nil
+
+	txs := fields["transactions"].([]interface{})
+	end := len(txs) - int(pageNumber)*int(pageSize)
+	start := end - int(pageSize)
+	if end < 0 {
+		end = 0
+	}
+	if start < 0 {
+		start = 0
+	}
+	var (
+		signer       = types.MakeSigner(api.b.ChainConfig(), block.Number())
+		pageReceipts = make([]map[string]interface{}, 0, end-start)
+	)
+	for i := start; i < end; i++ {
+		if tx, ok := txs[i].(*RPCTransaction); ok && len(tx.Input) > 4 {
+			cropped := *tx
+			cropped.Input = tx.Input[:4]
+			txs[i] = &cropped
+		}
+		if i < len(receipts) {
+			receipt := marshalReceipt(receipts[i], block.Hash(), block.NumberU64(), signer, block.Transactions()[i], i)
+			receipt["logs"], receipt["logsBloom"] = nil, nil
+			receipt["timestamp"] = hexutil.Uint64(block.Time())
+			pageReceipts = append(pageReceipts, receipt)
+		}
+	}
+	fields["transactions"] = txs[start:end]
+
+	return map[string]interface{}{
+		"fullblock": fields,
+		"receipts":  pageReceipts,
+	}, nil
+}
+
+// indexTail returns the oldest block whose transactions are indexed by address,
+// i.e. by sender and recipient as well as by internal calls.
+func (api *PublicOtterscanAPI) indexTail() (uint64, error) {
+	db := api.b.ChainDb()
+	accountTail, callTail := rawdb.ReadAccountTxIndexTail(db), rawdb.ReadInternalCallIndexTail(db)
+	if accountTail == nil || callTail == nil {
+		return 0, errOtterscanNotEnabled
+	}
+	if *accountTail > *callTail {
+		return *accountTail, nil
+	}
+	return *callTail, nil
+}
+
+// addressTxs returns the positions of the canonical transactions sent by,
+// sent to or internally calling from or to an address in the [from, to] block
+// range, in ascending order.
+func (api *PublicOtterscanAPI) addressTxs(address common.Address, from, to uint64) ([]txPosition, error) {
+	var (
+		db        = api.b.ChainDb()
+		seen      = make(map[txPosition]bool)
+		positions []txPosition
+	)
+	add := func(number uint64, hash common.Hash, index uint32) {
+		pos := txPosition{number: number, hash: hash, index: index}
+		// Skip the entries of blocks reorged out of the canonical chain
+		if seen[pos] || rawdb.ReadCanonicalHash(db, number) != hash {
+			return
+		}
+		seen[pos] = true
+		positions = append(positions, pos)
+	}
+	err := rawdb.IterateAccountTxs(db, address, from, to, func(tx rawdb.AccountTx) bool {
+		add(tx.BlockNumber, tx.BlockHash, tx.TxIndex)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = rawdb.IterateInternalCalls(db, address, from, to, func(call rawdb.InternalCall) bool {
+		add(call.BlockNumber, call.BlockHash, call.TxIndex)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].number != positions[j].number {
+			return positions[i].number < positions[j].number
+		}
+		return positions[i].index < positions[j].index
+	})
+	return positions, nil
+}
+
+// txsWithReceipts retrieves the transactions at the given positions along with
+// their receipts.
+func (api *PublicOtterscanAPI) txsWithReceipts(ctx context.Context, positions []txPosition) (*TransactionsWithReceipts, error) {
+	var (
+		res = &TransactionsWithReceipts{
+			Txs:      make([]*RPCTransaction, 0, len(positions)),
+			Receipts: make([]map[string]interface{}, 0, len(positions)),
+		}
+		block    *types.Block
+		receipts types.Receipts
+		err      error
+	)
+	for _, pos := range positions {
+		if block == nil || block.Hash() != pos.hash {
+			if block, err = api.b.BlockByHash(ctx, pos.hash); block == nil {
+				return nil, fmt.Errorf("block %#x not found: %v", pos.hash, err)
+			}
+			if receipts, err = api.b.GetReceipts(ctx, pos.hash); err != nil {
+				return nil, err
+			}
+		}
+		txs := block.Transactions()
+		if int(pos.index) >= len(txs) || int(pos.index) >= len(receipts) {
+			continue
+		}
+		signer := types.MakeSigner(api.b.ChainConfig(), block.Number())
+		receipt := marshalReceipt(receipts[pos.index], pos.hash, pos.number, signer, txs[pos.index], int(pos.index))
+		receipt["timestamp"] = hexutil.Uint64(block.Time())
+
+		res.Txs = append(res.Txs, newRPCTransaction(txs[pos.index], pos.hash, pos.number, uint64(pos.index)))
+		res.Receipts = append(res.Receipts, receipt)
+	}
+	return res, nil
+}
+
+// SearchTransactionsBefore returns the transactions of an address included before
+// the given block, or up to the head if zero, most recent first. The blocks are
+// returned whole, so the page may exceed the requested size.
+func (api *PublicOtterscanAPI) SearchTransactionsBefore(ctx context.Context, address common.Address, blockNumber uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+	tail, err := api.indexTail()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		head      = api.b.CurrentBlock().NumberU64()
+		firstPage = blockNumber == 0 || blockNumber > head
+		lastPage  = true
+		hi        = head
+		found     []txPosition
+	)
+	if !firstPage {
+		hi = blockNumber - 1
+	}
+search:
+	for hi >= tail {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lo := tail
+		if hi-tail >= otterscanSearchWindow {
+			lo = hi - otterscanSearchWindow + 1
+		}
+		positions, err := api.addressTxs(address, lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(positions) - 1; i >= 0; i-- {
+			if len(found) >= int(pageSize) && positions[i].number != found[len(found)-1].number {
+				lastPage = false
+				break search
+			}
+			found = append(found, positions[i])
+		}
+		if lo == tail {
+			break
+		}
+		hi = lo - 1
+	}
+	res, err := api.txsWithReceipts(ctx, found)
+	if err != nil {
+		return nil, err
+	}
+	res.FirstPage, res.LastPage = firstPage, lastPage
+	return res, nil
+}
+
+// SearchTransactionsAfter returns the transactions of an address included after
+// the given block, or from the oldest indexed block if zero, most recent first.
+// The blocks are returned whole, so the page may exceed the requested size.
+func (api *PublicOtterscanAPI) SearchTransactionsAfter(ctx context.Context, address common.Address, blockNumber uint64, pageSize uint16) (*TransactionsWithReceipts, error) {
+	tail, err := api.indexTail()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		head      = api.b.CurrentBlock().NumberU64()
+		firstPage = true
+		lastPage  = blockNumber == 0
+		lo        = tail
+		found     []txPosition
+	)
+	if blockNumber >= tail {
+		lo = blockNumber + 1
+	}
+search:
+	for lo <= head {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hi := head
+		if head-lo >= otterscanSearchWindow {
+			hi = lo + otterscanSearchWindow - 1
+		}
+		positions, err := api.addressTxs(address, lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if len(found) >= int(pageSize) && pos.number != found[len(found)-1].number {
+				firstPage = false
+				break search
+			}
+			found = append(found, pos)
+		}
+		lo = hi + 1
+	}
+	// Return the most recent transactions first
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	res, err := api.txsWithReceipts(ctx, found)
+	if err != nil {
+		return nil, err
+	}
+	res.FirstPage, res.LastPage = firstPage, lastPage
+	return res, nil
+}
+
+// GetTransactionBySenderAndNonce returns the hash of the canonical transaction
+// sent by an address with the given nonce, nil if not found.
+func (api *PublicOtterscanAPI) GetTransactionBySenderAndNonce(ctx context.Context, address common.Address, nonce uint64) (*common.Hash, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadAccountTxIndexTail(db)
+	if tail == nil {
+		return nil, errOtterscanNotEnabled
+	}
+	var (
+		found    *common.Hash
+		block    *types.Block
+		fetchErr error
+	)
+	err := rawdb.IterateAccountTxs(db, address, *tail, api.b.CurrentBlock().NumberU64(), func(entry rawdb.AccountTx) bool {
+		if rawdb.ReadCanonicalHash(db, entry.BlockNumber) != entry.BlockHash {
+			return true
+		}
+		if block == nil || block.Hash() != entry.BlockHash {
+			if block, fetchErr = api.b.BlockByHash(ctx, entry.BlockHash); block == nil {
+				return false
+			}
+		}
+		txs := block.Transactions()
+		if int(entry.TxIndex) >= len(txs) {
+			return true
+		}
+		tx := txs[entry.TxIndex]
+		if from, _ := types.Sender(types.MakeSigner(api.b.ChainConfig(), block.Number()), tx); from != address {
+			return true
+		}
+		if tx.Nonce() == nonce {
+			hash := tx.Hash()
+			found = &hash
+		}
+		// Nonces are sequential, stop at the first one reaching the requested one
+		return tx.Nonce() < nonce
+	})
+	if block == nil && fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// GetContractCreator returns the transaction deploying a contract and the
+// account creating it, which is the sender of the transaction for contracts
+// deployed by top level creations. Nil is returned if the address has no code,
+// or if the contract was deployed before the oldest indexed block.
+func (api *PublicOtterscanAPI) GetContractCreator(ctx context.Context, address common.Address) (*ContractCreator, error) {
+	tail, err := api.indexTail()
+	if err != nil {
+		return nil, err
+	}
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if len(state.GetCode(address)) == 0 {
+		return nil, nil
+	}
+	// Look the contract up in the contract creation index if it's maintained
+	if rawdb.ReadContractCreationIndexTail(api.b.ChainDb()) != nil {
+		creation, err := lookupContractCreation(ctx, api.b, address)
+		if err != nil {
+			return nil, err
+		}
+		if creation != nil {
+			return &ContractCreator{Tx: creation.TxHash, Creator: creation.Creator}, nil
+		}
+	}
+	head := api.b.CurrentBlock().NumberU64()
+	for lo := tail; lo <= head; lo += otterscanSearchWindow {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hi := head
+		if head-lo >= otterscanSearchWindow {
+			hi = lo + otterscanSearchWindow - 1
+		}
+		positions, err := api.addressTxs(address, lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			creator, err := api.contractCreator(ctx, address, pos)
+			if err != nil {
+				return nil, err
+			}
+			if creator != nil {
+				return creator, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// contractCreator returns the creator of a contract if it was deployed by the
+// transaction at the given position, nil otherwise.
+func (api *PublicOtterscanAPI) contractCreator(ctx context.Context, address common.Address, pos txPosition) (*ContractCreator, error) {
+	block, err := api.b.BlockByHash(ctx, pos.hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found: %v", pos.hash, err)
+	}
+	txs := block.Transactions()
+	if int(pos.index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[pos.index]
+	if tx.To() == nil {
+		receipts, err := api.b.GetReceipts(ctx, pos.hash)
+		if err != nil {
+			return nil, err
+		}
+		if int(pos.index) < len(receipts) && receipts[pos.index].ContractAddress == address {
+			from, _ := types.Sender(types.MakeSigner(api.b.ChainConfig(), block.Number()), tx)
+			return &ContractCreator{Tx: tx.Hash(), Creator: from}, nil
+		}
+	}
+	tracer, _, err := api.replayTransaction(ctx, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range tracer.ops {
+		if (op.Type == opCreate || op.Type == opCreate2) && op.To == address {
+			return &ContractCreator{Tx: tx.Hash(), Creator: op.From}, nil
+		}
+	}
+	return nil, nil
+}
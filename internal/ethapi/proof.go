@@ -0,0 +1,150 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// InclusionProof is a Merkle proof of the inclusion of a transaction or of a
+// receipt in a block, against the transactions or the receipts root of its
+// header. The key is the RLP encoded transaction index and the value is the
+// consensus encoding of the proven item.
+type InclusionProof struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Root             common.Hash    `json:"root"`
+	Key              hexutil.Bytes  `json:"key"`
+	Value            hexutil.Bytes  `json:"value"`
+	Proof            []string       `json:"proof"`
+}
+
+// proofList collects the trie nodes of a Merkle proof, from the root down.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// proveInclusion builds the trie of a derivable list and proves the item at the
+// given index against the expected root.
+func proveInclusion(list types.DerivableList, index uint64, root common.Hash) (key, value []byte, proof []string, err error) {
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if hash := types.DeriveSha(list, tr); hash != root {
+		return nil, nil, nil, fmt.Errorf("derived root %x mismatches header root %x", hash, root)
+	}
+	key, err = rlp.EncodeToBytes(index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var nodes proofList
+	if err := tr.Prove(key, 0, &nodes); err != nil {
+		return nil, nil, nil, err
+	}
+	return key, tr.Get(key), toHexSlice(nodes), nil
+}
+
+// GetTransactionInclusionProof returns the Merkle proof of the inclusion of the
+// given transaction against the transactions root of its block.
+func (s *PublicTransactionPoolAPI) GetTransactionInclusionProof(ctx context.Context, hash common.Hash) (*InclusionProof, error) {
+	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, txIndexGap(s.b)
+	}
+	block, err := s.b.BlockByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	key, value, proof, err := proveInclusion(block.Transactions(), index, block.TxHash())
+	if err != nil {
+		return nil, err
+	}
+	return &InclusionProof{
+		BlockHash:        blockHash,
+		BlockNumber:      hexutil.Uint64(blockNumber),
+		TransactionHash:  hash,
+		TransactionIndex: hexutil.Uint64(index),
+		Root:             block.TxHash(),
+		Key:              key,
+		Value:            value,
+		Proof:            proof,
+	}, nil
+}
+
+// GetReceiptProof returns the Merkle proof of the inclusion of the receipt of the
+// given transaction against the receipts root of its block.
+func (s *PublicTransactionPoolAPI) GetReceiptProof(ctx context.Context, hash common.Hash) (*InclusionProof, error) {
+	tx, blockHash, blockNumber, index, err := s.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, txIndexGap(s.b)
+	}
+	header, err := s.b.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("header %x not found", blockHash)
+	}
+	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(receipts)) <= index {
+		return nil, fmt.Errorf("receipt of transaction %x not found", hash)
+	}
+	key, value, proof, err := proveInclusion(receipts, index, header.ReceiptHash)
+	if err != nil {
+		return nil, err
+	}
+	return &InclusionProof{
+		BlockHash:        blockHash,
+		BlockNumber:      hexutil.Uint64(blockNumber),
+		TransactionHash:  hash,
+		TransactionIndex: hexutil.Uint64(index),
+		Root:             header.ReceiptHash,
+		Key:              key,
+		Value:            value,
+		Proof:            proof,
+	}, nil
+}
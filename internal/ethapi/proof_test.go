@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// newProofTestBackend creates a chain whose first block holds enough transfers
+// for the indices to span both single and multi byte RLP keys.
+func newProofTestBackend(t *testing.T, txs int) (*testBackend, *types.Block) {
+	backend := newTestBackend(t, 1, func(i int, b *core.BlockGen) {
+		for nonce := uint64(0); nonce < uint64(txs); nonce++ {
+			tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{0xaa}, big.NewInt(1), params.TxGas, big.NewInt(2*params.GWei), nil), types.HomesteadSigner{}, testKey)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+	return backend, backend.chain.GetBlockByNumber(1)
+}
+
+// verifyInclusionProof checks a proof against the given root, returning the
+// proven value.
+func verifyInclusionProof(t *testing.T, proof *InclusionProof, root common.Hash, index uint64) []byte {
+	t.Helper()
+
+	if proof.Root != root {
+		t.Fatalf("index %d: root mismatch: have %x, want %x", index, proof.Root, root)
+	}
+	if key, _ := rlp.EncodeToBytes(index); !bytes.Equal(proof.Key, key) {
+		t.Fatalf("index %d: key mismatch: have %x, want %x", index, proof.Key, key)
+	}
+	nodes := memorydb.New()
+	for _, node := range proof.Proof {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			t.Fatalf("index %d: invalid proof node %q: %v", index, node, err)
+		}
+		nodes.Put(crypto.Keccak256(blob), blob)
+	}
+	value, err := trie.VerifyProof(root, proof.Key, nodes)
+	if err != nil {
+		t.Fatalf("index %d: invalid proof: %v", index, err)
+	}
+	if !bytes.Equal(value, proof.Value) {
+		t.Fatalf("index %d: proven value mismatch: have %x, want %x", index, value, proof.Value)
+	}
+	return value
+}
+
+// Tests that the transaction and receipt proofs verify against the roots of the
+// header, for indices encoded both into a single byte and into a string.
+func TestInclusionProofs(t *testing.T) {
+	backend, block := newProofTestBackend(t, 130)
+	var (
+		api      = NewPublicTransactionPoolAPI(backend, nil)
+		receipts = backend.chain.GetReceiptsByHash(block.Hash())
+	)
+	if len(block.Transactions()) != 130 || len(receipts) != 130 {
+		t.Fatalf("transaction count mismatch: have %d txs and %d receipts, want 130", len(block.Transactions()), len(receipts))
+	}
+	for _, index := range []uint64{0, 1, 0x7f, 0x80, 129} {
+		tx := block.Transactions()[index]
+
+		proof, err := api.GetTransactionInclusionProof(context.Background(), tx.Hash())
+		if err != nil {
+			t.Fatalf("index %d: failed to prove transaction: %v", index, err)
+		}
+		if proof.BlockHash != block.Hash() || uint64(proof.BlockNumber) != 1 || proof.TransactionHash != tx.Hash() || uint64(proof.TransactionIndex) != index {
+			t.Fatalf("index %d: transaction proof position mismatch: have %+v", index, proof)
+		}
+		want, _ := tx.MarshalBinary()
+		if have := verifyInclusionProof(t, proof, block.TxHash(), index); !bytes.Equal(have, want) {
+			t.Errorf("index %d: proven transaction mismatch: have %x, want %x", index, have, want)
+		}
+
+		proof, err = api.GetReceiptProof(context.Background(), tx.Hash())
+		if err != nil {
+			t.Fatalf("index %d: failed to prove receipt: %v", index, err)
+		}
+		var buf bytes.Buffer
+		receipts.EncodeIndex(int(index), &buf)
+		if have := verifyInclusionProof(t, proof, block.ReceiptHash(), index); !bytes.Equal(have, buf.Bytes()) {
+			t.Errorf("index %d: proven receipt mismatch: have %x, want %x", index, have, buf.Bytes())
+		}
+	}
+}
+
+// Tests that proving against a root the list doesn't derive fails.
+func TestInclusionProofRootMismatch(t *testing.T) {
+	_, block := newProofTestBackend(t, 2)
+
+	_, _, _, err := proveInclusion(block.Transactions(), 0, block.ReceiptHash())
+	if err == nil || !strings.Contains(err.Error(), "mismatches header root") {
+		t.Fatalf("error mismatch: have %v, want root mismatch", err)
+	}
+}
+
+// Tests that unknown transactions aren't proven, the lookup failing if the
+// transaction may be missing from a pruned index.
+func TestInclusionProofUnknownHash(t *testing.T) {
+	backend, _ := newProofTestBackend(t, 2)
+	api := NewPublicTransactionPoolAPI(backend, nil)
+
+	unknown := common.Hash{0xff}
+	if proof, err := api.GetTransactionInclusionProof(context.Background(), unknown); proof != nil || err != nil {
+		t.Fatalf("unknown transaction proven: proof %v, err %v", proof, err)
+	}
+	if proof, err := api.GetReceiptProof(context.Background(), unknown); proof != nil || err != nil {
+		t.Fatalf("unknown receipt proven: proof %v, err %v", proof, err)
+	}
+	// Only index the allowlisted transactions below the tail
+	rawdb.WriteTxIndexAllowlist(backend.db, []common.Address{testAddr})
+	rawdb.WriteTxIndexTail(backend.db, 1)
+
+	var gap *txIndexGapError
+	if _, err := api.GetTransactionInclusionProof(context.Background(), unknown); !errors.As(err, &gap) {
+		t.Fatalf("transaction proof error mismatch: have %v, want index gap", err)
+	}
+	if _, err := api.GetReceiptProof(context.Background(), unknown); !errors.As(err, &gap) {
+		t.Fatalf("receipt proof error mismatch: have %v, want index gap", err)
+	}
+}
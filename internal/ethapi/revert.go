@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var errRevertsNotEnabled = errors.New("revert reason recording not enabled")
+
+// RPCRevertReason is the failure of a transaction recorded on import, along
+// with the call frame it originated in.
+type RPCRevertReason struct {
+	TxHash           common.Hash    `json:"transactionHash"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Error            string         `json:"error"`
+	Reason           string         `json:"reason,omitempty"` // Decoded Error(string) revert reason
+	Output           hexutil.Bytes  `json:"output"`
+	Address          common.Address `json:"address"` // Contract of the frame the failure originated in
+	Depth            hexutil.Uint64 `json:"depth"`
+	Op               string         `json:"op,omitempty"`
+	Pc               hexutil.Uint64 `json:"pc"`
+}
+
+// recordedRevert retrieves the failure recorded for a transaction of a block,
+// nil if none was recorded.
+func recordedRevert(db ethdb.KeyValueReader, blockHash common.Hash, blockNumber uint64, index uint64) *types.RevertTrace {
+	reverts := rawdb.ReadBlockReverts(db, blockHash, blockNumber)
+	if reverts == nil {
+		return nil
+	}
+	for i := range reverts.Txs {
+		if reverts.Txs[i].TxIndex == index {
+			return &reverts.Txs[i]
+		}
+	}
+	return nil
+}
+
+// GetRevertReason returns the failure of a transaction recorded while importing
+// its block, along with the call frame it originated in, without executing the
+// transaction again. Nil is returned for the transactions which succeeded.
+func (api *PublicDebugAPI) GetRevertReason(ctx context.Context, hash common.Hash) (*RPCRevertReason, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadBlockRevertsTail(db)
+	if tail == nil {
+		return nil, errRevertsNotEnabled
+	}
+	tx, blockHash, blockNumber, index, err := api.b.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, txIndexGap(api.b)
+	}
+	if blockNumber < *tail {
+		return nil, fmt.Errorf("block %d not recorded, revert reasons are recorded from block %d", blockNumber, *tail)
+	}
+	if rawdb.ReadBlockReverts(db, blockHash, blockNumber) == nil {
+		return nil, fmt.Errorf("revert reasons of block %d not found", blockNumber)
+	}
+	revert := recordedRevert(db, blockHash, blockNumber, index)
+	if revert == nil {
+		return nil, nil
+	}
+	result := &RPCRevertReason{
+		TxHash:           hash,
+		BlockHash:        blockHash,
+		BlockNumber:      hexutil.Uint64(blockNumber),
+		TransactionIndex: hexutil.Uint64(index),
+		Error:            revert.Error,
+		Output:           revert.Output,
+		Address:          revert.Address,
+		Depth:            hexutil.Uint64(revert.Depth),
+		Op:               revert.Op,
+		Pc:               hexutil.Uint64(revert.Pc),
+	}
+	if reason, err := abi.UnpackRevert(revert.Output); err == nil {
+		result.Reason = reason
+	}
+	return result, nil
+}
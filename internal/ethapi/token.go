@@ -0,0 +1,240 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxTokenTransfers is the number of transfers past which a single
+// token_getTransfers call stops, at the end of the block being returned.
+const maxTokenTransfers = 1000
+
+var errTokenIndexNotEnabled = errors.New("token index not enabled")
+
+// tokenStandards are the names of the indexed token standards.
+var tokenStandards = map[uint16]string{
+	rawdb.TokenERC20:   "erc20",
+	rawdb.TokenERC721:  "erc721",
+	rawdb.TokenERC1155: "erc1155",
+}
+
+// TokenTransfer is a transfer of a token from or to an account, as recorded by
+// the token index.
+type TokenTransfer struct {
+	Token            common.Address `json:"token"`
+	Standard         string         `json:"standard"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	TokenID          *hexutil.Big   `json:"tokenId,omitempty"`
+	Value            *hexutil.Big   `json:"value"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	LogIndex         hexutil.Uint   `json:"logIndex"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+}
+
+// TokenBalance is the balance of an account in a token, or in a token id for
+// the ERC-721 and ERC-1155 tokens.
+type TokenBalance struct {
+	Token    common.Address `json:"token"`
+	Standard string         `json:"standard"`
+	TokenID  *hexutil.Big   `json:"tokenId,omitempty"`
+	Balance  *hexutil.Big   `json:"balance"`
+}
+
+// PublicTokenAPI provides the token APIs, serving the ERC-20, ERC-721 and
+// ERC-1155 transfers recorded by the token index while importing the blocks.
+type PublicTokenAPI struct {
+	b Backend
+}
+
+// NewPublicTokenAPI creates a new token API.
+func NewPublicTokenAPI(b Backend) *PublicTokenAPI {
+	return &PublicTokenAPI{b}
+}
+
+// GetTransfers returns the token transfers of the canonical chain sent or
+// received by the given address within the given block range, in chain order.
+// The transfers are returned up to the end of the block in which 1000 of them
+// are reached, further ones can be fetched by starting the range after the
+// block of the last one.
+func (api *PublicTokenAPI) GetTransfers(ctx context.Context, address common.Address, fromBlock, toBlock *rpc.BlockNumber) ([]*TokenTransfer, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadTokenTransferIndexTail(db)
+	if tail == nil {
+		return nil, errTokenIndexNotEnabled
+	}
+	head, err := api.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	from, to := *tail, head.Number.Uint64()
+	if fromBlock != nil && *fromBlock >= 0 {
+		from = uint64(*fromBlock)
+	}
+	if toBlock != nil && *toBlock >= 0 && uint64(*toBlock) < to {
+		to = uint64(*toBlock)
+	}
+	if from < *tail {
+		return nil, fmt.Errorf("block %d not indexed, token transfers are indexed from block %d", from, *tail)
+	}
+	var (
+		transfers = make([]*TokenTransfer, 0)
+		block     *types.Block
+		fetchErr  error
+	)
+	err = rawdb.IterateTokenTransfers(db, address, from, to, func(entry rawdb.TokenTransfer) bool {
+		if len(transfers) >= maxTokenTransfers && uint64(transfers[len(transfers)-1].BlockNumber) != entry.BlockNumber {
+			return false
+		}
+		// Skip the entries of blocks reorged out of the canonical chain
+		if rawdb.ReadCanonicalHash(db, entry.BlockNumber) != entry.BlockHash {
+			return true
+		}
+		if block == nil || block.Hash() != entry.BlockHash {
+			if block, fetchErr = api.b.BlockByHash(ctx, entry.BlockHash); block == nil {
+				return fetchErr == nil
+			}
+		}
+		if int(entry.TxIndex) >= len(block.Transactions()) {
+			return true
+		}
+		transfer := &TokenTransfer{
+			Token:            entry.Token,
+			Standard:         tokenStandards[entry.Standard],
+			From:             entry.From,
+			To:               entry.To,
+			Value:            (*hexutil.Big)(entry.Value),
+			TransactionHash:  block.Transactions()[entry.TxIndex].Hash(),
+			TransactionIndex: hexutil.Uint(entry.TxIndex),
+			LogIndex:         hexutil.Uint(entry.LogIndex),
+			BlockHash:        entry.BlockHash,
+			BlockNumber:      hexutil.Uint64(entry.BlockNumber),
+		}
+		if entry.ID != nil {
+			transfer.TokenID = (*hexutil.Big)(entry.ID)
+		}
+		transfers = append(transfers, transfer)
+		return true
+	})
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// GetBalancesAt returns the non-zero token balances of the given address at the
+// given canonical block, summing up the indexed transfers it sent and received.
+// As the balances are derived from the transfer events, the tokens must have
+// been indexed from genesis, and the balances changed without emitting events
+// (e.g. genesis allocations, rebasing tokens) are not accounted for.
+func (api *PublicTokenAPI) GetBalancesAt(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) ([]*TokenBalance, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadTokenTransferIndexTail(db)
+	if tail == nil {
+		return nil, errTokenIndexNotEnabled
+	}
+	if *tail > 1 {
+		return nil, fmt.Errorf("token transfers are indexed from block %d, balances need them from genesis", *tail)
+	}
+	header, err := api.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	number := header.Number.Uint64()
+	if rawdb.ReadCanonicalHash(db, number) != header.Hash() {
+		return nil, fmt.Errorf("block %#x not canonical", header.Hash())
+	}
+	type holding struct {
+		token    common.Address
+		standard uint16
+		id       common.Hash
+	}
+	var (
+		balances = make(map[holding]*big.Int)
+		ids      = make(map[holding]*big.Int)
+	)
+	err = rawdb.IterateTokenTransfers(db, address, 0, number, func(entry rawdb.TokenTransfer) bool {
+		// Skip the entries of blocks reorged out of the canonical chain
+		if rawdb.ReadCanonicalHash(db, entry.BlockNumber) != entry.BlockHash {
+			return true
+		}
+		key := holding{token: entry.Token, standard: entry.Standard}
+		if entry.ID != nil {
+			key.id, ids[key] = common.BigToHash(entry.ID), entry.ID
+		}
+		balance := balances[key]
+		if balance == nil {
+			balance = new(big.Int)
+			balances[key] = balance
+		}
+		if entry.To == address {
+			balance.Add(balance, entry.Value)
+		}
+		if entry.From == address {
+			balance.Sub(balance, entry.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]holding, 0, len(balances))
+	for key, balance := range balances {
+		if balance.Sign() != 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if c := bytes.Compare(keys[i].token[:], keys[j].token[:]); c != 0 {
+			return c < 0
+		}
+		if keys[i].standard != keys[j].standard {
+			return keys[i].standard < keys[j].standard
+		}
+		return bytes.Compare(keys[i].id[:], keys[j].id[:]) < 0
+	})
+	result := make([]*TokenBalance, len(keys))
+	for i, key := range keys {
+		result[i] = &TokenBalance{
+			Token:    key.token,
+			Standard: tokenStandards[key.standard],
+			TokenID:  (*hexutil.Big)(ids[key]),
+			Balance:  (*hexutil.Big)(balances[key]),
+		}
+	}
+	return result, nil
+}
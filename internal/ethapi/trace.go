@@ -0,0 +1,445 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxTraceFilterBlocks is the maximum number of blocks scanned by a single
+// trace_filter call.
+const maxTraceFilterBlocks = 10000
+
+var errTracesNotEnabled = errors.New("block trace recording not enabled")
+
+type traceCallAction struct {
+	CallType string         `json:"callType"`
+	From     common.Address `json:"from"`
+	Gas      hexutil.Uint64 `json:"gas"`
+	Input    hexutil.Bytes  `json:"input"`
+	To       common.Address `json:"to"`
+	Value    *hexutil.Big   `json:"value"`
+}
+
+type traceCreateAction struct {
+	From  common.Address `json:"from"`
+	Gas   hexutil.Uint64 `json:"gas"`
+	Init  hexutil.Bytes  `json:"init"`
+	Value *hexutil.Big   `json:"value"`
+}
+
+type traceSuicideAction struct {
+	Address       common.Address `json:"address"`
+	Balance       *hexutil.Big   `json:"balance"`
+	RefundAddress common.Address `json:"refundAddress"`
+}
+
+type traceRewardAction struct {
+	Author     common.Address `json:"author"`
+	RewardType string         `json:"rewardType"`
+	Value      *hexutil.Big   `json:"value"`
+}
+
+type traceCallResult struct {
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Output  hexutil.Bytes  `json:"output"`
+}
+
+type traceCreateResult struct {
+	Address common.Address `json:"address"`
+	Code    hexutil.Bytes  `json:"code"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+}
+
+// TraceEntry is a call frame or a reward, in the OpenEthereum trace format.
+type TraceEntry struct {
+	Action       interface{} `json:"action"`
+	Error        string      `json:"error,omitempty"`
+	Result       interface{} `json:"result"`
+	Subtraces    uint64      `json:"subtraces"`
+	TraceAddress []uint64    `json:"traceAddress"`
+	Type         string      `json:"type"`
+
+	from, to common.Address // Addresses matched by trace_filter
+}
+
+// LocalizedTrace is a trace entry along with its position in the chain.
+type LocalizedTrace struct {
+	TraceEntry
+	BlockHash           common.Hash  `json:"blockHash"`
+	BlockNumber         uint64       `json:"blockNumber"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *uint64      `json:"transactionPosition"`
+}
+
+// AccountStateDiff is the change of an account made by a transaction, in the
+// OpenEthereum state diff format: "=" if the field was left unchanged, or an
+// object keyed by "+" (created), "-" (deleted) or "*" (modified).
+type AccountStateDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// TraceResults are the traces of a transaction returned by the trace replay
+// methods, the kinds not requested being left empty.
+type TraceResults struct {
+	Output          hexutil.Bytes                        `json:"output"`
+	StateDiff       map[common.Address]*AccountStateDiff `json:"stateDiff"`
+	Trace           []*TraceEntry                        `json:"trace"`
+	VMTrace         interface{}                          `json:"vmTrace"`
+	TransactionHash *common.Hash                         `json:"transactionHash,omitempty"`
+}
+
+// TraceFilterArgs are the criteria of a trace_filter query. A trace matches if
+// its sender is in FromAddress and its recipient in ToAddress, an empty list
+// matching any address.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"` // Number of matching traces to skip
+	Count       *uint64          `json:"count"` // Maximum number of traces to return
+}
+
+// matches reports whether the trace entry satisfies the address criteria.
+func (args *TraceFilterArgs) matches(entry *TraceEntry) bool {
+	contains := func(addrs []common.Address, addr common.Address) bool {
+		if len(addrs) == 0 {
+			return true
+		}
+		for _, a := range addrs {
+			if a == addr {
+				return true
+			}
+		}
+		return false
+	}
+	if entry.Type != "reward" && !contains(args.FromAddress, entry.from) {
+		return false
+	}
+	if entry.Type == "reward" && len(args.FromAddress) > 0 {
+		return false
+	}
+	return contains(args.ToAddress, entry.to)
+}
+
+// newTraceEntry converts a recorded call frame into the OpenEthereum format.
+func newTraceEntry(call *types.CallTrace) *TraceEntry {
+	entry := &TraceEntry{
+		Error:        call.Error,
+		Subtraces:    call.Subtraces,
+		TraceAddress: call.TraceAddress,
+		Type:         call.Type,
+		from:         call.From,
+		to:           call.To,
+	}
+	if entry.TraceAddress == nil {
+		entry.TraceAddress = []uint64{}
+	}
+	switch call.Type {
+	case types.CallTraceCreate:
+		entry.Action = &traceCreateAction{From: call.From, Gas: hexutil.Uint64(call.Gas), Init: call.Input, Value: (*hexutil.Big)(call.Value)}
+		entry.Result = &traceCreateResult{Address: call.To, Code: call.Output, GasUsed: hexutil.Uint64(call.GasUsed)}
+	case types.CallTraceSuicide:
+		entry.Action = &traceSuicideAction{Address: call.From, Balance: (*hexutil.Big)(call.Value), RefundAddress: call.To}
+	default:
+		entry.Action = &traceCallAction{CallType: call.CallType, From: call.From, Gas: hexutil.Uint64(call.Gas), Input: call.Input, To: call.To, Value: (*hexutil.Big)(call.Value)}
+		entry.Result = &traceCallResult{GasUsed: hexutil.Uint64(call.GasUsed), Output: call.Output}
+	}
+	if call.Error != "" {
+		entry.Result = nil
+	}
+	return entry
+}
+
+// newRewardEntry converts a recorded reward into the OpenEthereum format.
+func newRewardEntry(reward *types.RewardTrace) *TraceEntry {
+	return &TraceEntry{
+		Action:       &traceRewardAction{Author: reward.Author, RewardType: reward.Type, Value: (*hexutil.Big)(reward.Value)},
+		TraceAddress: []uint64{},
+		Type:         "reward",
+		to:           reward.Author,
+	}
+}
+
+// diffValue formats the change of an account field in the state diff format.
+func diffValue(existed, exists, unchanged bool, from, to interface{}) interface{} {
+	switch {
+	case !existed && exists:
+		return map[string]interface{}{"+": to}
+	case existed && !exists:
+		return map[string]interface{}{"-": from}
+	case unchanged:
+		return "="
+	default:
+		return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}
+	}
+}
+
+// newStateDiff converts the recorded state diff of a transaction into the
+// OpenEthereum format.
+func newStateDiff(diffs []types.AccountDiff) map[common.Address]*AccountStateDiff {
+	res := make(map[common.Address]*AccountStateDiff, len(diffs))
+	for _, diff := range diffs {
+		account := &AccountStateDiff{
+			Balance: diffValue(diff.Existed, diff.Exists, diff.BalanceFrom.Cmp(diff.BalanceTo) == 0, (*hexutil.Big)(diff.BalanceFrom), (*hexutil.Big)(diff.BalanceTo)),
+			Code:    diffValue(diff.Existed, diff.Exists, string(diff.CodeFrom) == string(diff.CodeTo), hexutil.Bytes(diff.CodeFrom), hexutil.Bytes(diff.CodeTo)),
+			Nonce:   diffValue(diff.Existed, diff.Exists, diff.NonceFrom == diff.NonceTo, hexutil.Uint64(diff.NonceFrom), hexutil.Uint64(diff.NonceTo)),
+			Storage: make(map[common.Hash]interface{}, len(diff.Storage)),
+		}
+		for _, slot := range diff.Storage {
+			account.Storage[slot.Key] = diffValue(diff.Existed, diff.Exists, false, slot.From, slot.To)
+		}
+		res[diff.Address] = account
+	}
+	return res
+}
+
+// PublicTraceAPI provides the OpenEthereum compatible trace APIs, serving the
+// traces recorded while importing the blocks.
+type PublicTraceAPI struct {
+	b Backend
+}
+
+// NewPublicTraceAPI creates a new trace API.
+func NewPublicTraceAPI(b Backend) *PublicTraceAPI {
+	return &PublicTraceAPI{b}
+}
+
+// blockTraces retrieves the traces recorded for the given block.
+func (api *PublicTraceAPI) blockTraces(block *types.Block) (*types.BlockTraces, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadBlockTracesTail(db)
+	if tail == nil {
+		return nil, errTracesNotEnabled
+	}
+	if block.NumberU64() < *tail {
+		return nil, fmt.Errorf("block %d not traced, traces are recorded from block %d", block.NumberU64(), *tail)
+	}
+	traces := rawdb.ReadBlockTraces(db, block.Hash(), block.NumberU64())
+	if traces == nil {
+		return nil, fmt.Errorf("traces of block %d not found", block.NumberU64())
+	}
+	return traces, nil
+}
+
+// localize returns the trace entries of a block along with their position. If
+// the transaction index is non-negative, only the entries of that transaction
+// are returned.
+func localize(block *types.Block, traces *types.BlockTraces, index int) []*LocalizedTrace {
+	var (
+		txs = block.Transactions()
+		res = make([]*LocalizedTrace, 0)
+	)
+	for i := range traces.Txs {
+		if i >= len(txs) || (index >= 0 && i != index) {
+			continue
+		}
+		hash, position := txs[i].Hash(), uint64(i)
+		for j := range traces.Txs[i].Calls {
+			res = append(res, &LocalizedTrace{
+				TraceEntry:          *newTraceEntry(&traces.Txs[i].Calls[j]),
+				BlockHash:           block.Hash(),
+				BlockNumber:         block.NumberU64(),
+				TransactionHash:     &hash,
+				TransactionPosition: &position,
+			})
+		}
+	}
+	if index < 0 {
+		for i := range traces.Rewards {
+			res = append(res, &LocalizedTrace{
+				TraceEntry:  *newRewardEntry(&traces.Rewards[i]),
+				BlockHash:   block.Hash(),
+				BlockNumber: block.NumberU64(),
+			})
+		}
+	}
+	return res
+}
+
+// Block returns the traces of the transactions and the rewards of a block.
+func (api *PublicTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*LocalizedTrace, error) {
+	block, err := api.b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	traces, err := api.blockTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	return localize(block, traces, -1), nil
+}
+
+// Transaction returns the traces of a transaction.
+func (api *PublicTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*LocalizedTrace, error) {
+	tx, blockHash, _, index, err := api.b.GetTransaction(ctx, hash)
+	if tx == nil {
+		return nil, err
+	}
+	block, err := api.b.BlockByHash(ctx, blockHash)
+	if block == nil {
+		return nil, err
+	}
+	traces, err := api.blockTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	return localize(block, traces, int(index)), nil
+}
+
+// Filter returns the traces of the canonical chain matching the given criteria,
+// within a range of at most 10000 blocks.
+func (api *PublicTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*LocalizedTrace, error) {
+	db := api.b.ChainDb()
+	tail := rawdb.ReadBlockTracesTail(db)
+	if tail == nil {
+		return nil, errTracesNotEnabled
+	}
+	head, err := api.b.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	from, to := *tail, head.Number.Uint64()
+	if args.FromBlock != nil && *args.FromBlock >= 0 {
+		from = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 && uint64(*args.ToBlock) < to {
+		to = uint64(*args.ToBlock)
+	}
+	if from < *tail {
+		return nil, fmt.Errorf("block %d not traced, traces are recorded from block %d", from, *tail)
+	}
+	if to >= from && to-from >= maxTraceFilterBlocks {
+		return nil, fmt.Errorf("exceed maximum block range: %d", maxTraceFilterBlocks)
+	}
+	var skip, count uint64
+	if args.After != nil {
+		skip = *args.After
+	}
+	res := make([]*LocalizedTrace, 0)
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash := rawdb.ReadCanonicalHash(db, number)
+		traces := rawdb.ReadBlockTraces(db, hash, number)
+		if traces == nil {
+			continue
+		}
+		block, err := api.b.BlockByHash(ctx, hash)
+		if block == nil {
+			return nil, err
+		}
+		for _, trace := range localize(block, traces, -1) {
+			if !args.matches(&trace.TraceEntry) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			res = append(res, trace)
+			if count++; args.Count != nil && count >= *args.Count {
+				return res, nil
+			}
+		}
+	}
+	return res, nil
+}
+
+// traceResults assembles the requested kinds of traces of a transaction.
+func traceResults(traces *types.TxTraces, traceTypes []string) (*TraceResults, error) {
+	res := new(TraceResults)
+	if len(traces.Calls) > 0 {
+		res.Output = traces.Calls[0].Output
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case "trace":
+			res.Trace = make([]*TraceEntry, len(traces.Calls))
+			for i := range traces.Calls {
+				res.Trace[i] = newTraceEntry(&traces.Calls[i])
+			}
+		case "stateDiff":
+			res.StateDiff = newStateDiff(traces.StateDiff)
+		default:
+			return nil, fmt.Errorf("unsupported trace type: %s", typ)
+		}
+	}
+	return res, nil
+}
+
+// ReplayTransaction returns the requested kinds of traces of a transaction,
+// "trace" and "stateDiff" being supported.
+func (api *PublicTraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*TraceResults, error) {
+	tx, blockHash, _, index, err := api.b.GetTransaction(ctx, hash)
+	if tx == nil {
+		return nil, err
+	}
+	block, err := api.b.BlockByHash(ctx, blockHash)
+	if block == nil {
+		return nil, err
+	}
+	traces, err := api.blockTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	if int(index) >= len(traces.Txs) {
+		return nil, fmt.Errorf("traces of transaction %x not found", hash)
+	}
+	return traceResults(&traces.Txs[index], traceTypes)
+}
+
+// ReplayBlockTransactions returns the requested kinds of traces of all the
+// transactions of a block, "trace" and "stateDiff" being supported.
+func (api *PublicTraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*TraceResults, error) {
+	block, err := api.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil {
+		return nil, err
+	}
+	traces, err := api.blockTraces(block)
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	res := make([]*TraceResults, 0, len(txs))
+	for i := range traces.Txs {
+		if i >= len(txs) {
+			break
+		}
+		result, err := traceResults(&traces.Txs[i], traceTypes)
+		if err != nil {
+			return nil, err
+		}
+		hash := txs[i].Hash()
+		result.TransactionHash = &hash
+		res = append(res, result)
+	}
+	return res, nil
+}
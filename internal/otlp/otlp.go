@@ -0,0 +1,289 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package otlp exports trace spans to an OpenTelemetry collector, using the
+// JSON encoding of the OTLP/HTTP protocol.
+package otlp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	batchSize     = 512             // Maximum number of spans sent in a single request
+	queueSize     = 4096            // Number of spans queued for sending before dropping new ones
+	flushInterval = 5 * time.Second // Interval to send the queued spans at
+	sendTimeout   = 10 * time.Second
+)
+
+// TraceID identifies the trace a span belongs to.
+type TraceID [16]byte
+
+// SpanID identifies a span within its trace.
+type SpanID [8]byte
+
+// String implements fmt.Stringer.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String implements fmt.Stringer.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// NewTraceID returns a random trace identifier.
+func NewTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+// NewSpanID returns a random span identifier.
+func NewSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// Span is a timed operation, part of a trace.
+type Span struct {
+	Trace  TraceID
+	ID     SpanID
+	Parent SpanID // Zero for the root span of the trace
+	Name   string
+	Start  time.Time
+	End    time.Time
+	Attrs  []interface{} // Key/value pairs, an error value marks the span failed
+}
+
+// Exporter sends spans to an OpenTelemetry collector in batches.
+type Exporter struct {
+	endpoint string
+	scope    string
+	client   *http.Client
+	spans    chan *Span
+	quit     chan struct{}
+	done     chan struct{}
+
+	sentMeter    metrics.Meter
+	droppedMeter metrics.Meter
+	failedMeter  metrics.Meter
+}
+
+// NewExporter creates an exporter sending the spans of the given instrumentation
+// scope to a collector endpoint, usually ending in /v1/traces.
+func NewExporter(endpoint, scope string) *Exporter {
+	return newExporter(endpoint, scope, flushInterval)
+}
+
+func newExporter(endpoint, scope string, flush time.Duration) *Exporter {
+	e := &Exporter{
+		endpoint:     endpoint,
+		scope:        scope,
+		client:       &http.Client{Timeout: sendTimeout},
+		spans:        make(chan *Span, queueSize),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+		sentMeter:    metrics.GetOrRegisterMeter("otlp/"+scope+"/sent", nil),
+		droppedMeter: metrics.GetOrRegisterMeter("otlp/"+scope+"/dropped", nil),
+		failedMeter:  metrics.GetOrRegisterMeter("otlp/"+scope+"/failed", nil),
+	}
+	go e.loop(flush)
+	return e
+}
+
+// Export queues a span for sending, dropping it if the collector can't keep up.
+func (e *Exporter) Export(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		e.droppedMeter.Mark(1)
+	}
+}
+
+// Close sends the queued spans and stops the exporter.
+func (e *Exporter) Close() {
+	close(e.quit)
+	<-e.done
+}
+
+func (e *Exporter) loop(flush time.Duration) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(flush)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s := <-e.spans:
+			if batch = append(batch, s); len(batch) >= batchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.send(batch)
+				batch = nil
+			}
+		case <-e.quit:
+			for len(e.spans) > 0 {
+				batch = append(batch, <-e.spans)
+			}
+			if len(batch) > 0 {
+				e.send(batch)
+			}
+			return
+		}
+	}
+}
+
+// send posts a batch of spans to the collector.
+func (e *Exporter) send(batch []*Span) {
+	body, err := json.Marshal(encodeRequest(e.scope, batch))
+	if err != nil {
+		log.Error("Failed to encode trace spans", "scope", e.scope, "err", err)
+		return
+	}
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err == nil {
+		res.Body.Close()
+		if res.StatusCode >= 300 {
+			err = fmt.Errorf("unexpected status %s", res.Status)
+		}
+	}
+	if err != nil {
+		e.failedMeter.Mark(int64(len(batch)))
+		log.Warn("Failed to export trace spans", "scope", e.scope, "endpoint", e.endpoint, "spans", len(batch), "err", err)
+		return
+	}
+	e.sentMeter.Mark(int64(len(batch)))
+}
+
+// The OTLP/HTTP JSON encoding of the spans, see the OpenTelemetry protocol
+// specification for the details.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"` // 64 bit integers are strings in JSON
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+// encodeRequest converts a batch of spans into an OTLP export request.
+func encodeRequest(scope string, batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		enc := otlpSpan{
+			TraceID:           s.Trace.String(),
+			SpanID:            s.ID.String(),
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.Parent != (SpanID{}) {
+			enc.ParentSpanID = s.Parent.String()
+		}
+		for i := 0; i+1 < len(s.Attrs); i += 2 {
+			key := fmt.Sprint(s.Attrs[i])
+			switch value := s.Attrs[i+1].(type) {
+			case nil:
+			case error:
+				enc.Status = &otlpStatus{Code: otlpStatusError, Message: value.Error()}
+			default:
+				enc.Attributes = append(enc.Attributes, otlpAttribute{Key: key, Value: encodeValue(value)})
+			}
+		}
+		spans = append(spans, enc)
+	}
+	service := "geth"
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: &service}}}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scope}, Spans: spans}},
+	}}}
+}
+
+// encodeValue converts an attribute value into its OTLP form.
+func encodeValue(value interface{}) otlpValue {
+	var s string
+	switch value := value.(type) {
+	case bool:
+		return otlpValue{BoolValue: &value}
+	case int:
+		s = strconv.FormatInt(int64(value), 10)
+	case int64:
+		s = strconv.FormatInt(value, 10)
+	case uint64:
+		s = strconv.FormatUint(value, 10)
+	default:
+		s = fmt.Sprint(value)
+		return otlpValue{StringValue: &s}
+	}
+	return otlpValue{IntValue: &s}
+}
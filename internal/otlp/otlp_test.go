@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package otlp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeRequest(t *testing.T) {
+	span := &Span{
+		Trace:  TraceID{1},
+		ID:     SpanID{2},
+		Parent: SpanID{3},
+		Name:   "test",
+		Start:  time.Unix(1, 0),
+		End:    time.Unix(2, 0),
+		Attrs:  []interface{}{"number", uint64(7), "cached", true, "method", "eth_call", "unset", nil, "err", errors.New("failed")},
+	}
+	req := encodeRequest("scope", []*Span{span})
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("invalid request layout: %+v", req)
+	}
+	scope := req.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != "scope" || len(scope.Spans) != 1 {
+		t.Fatalf("invalid scope: %+v", scope)
+	}
+	enc := scope.Spans[0]
+	if enc.TraceID != "01000000000000000000000000000000" || enc.SpanID != "0200000000000000" || enc.ParentSpanID != "0300000000000000" {
+		t.Errorf("identifier mismatch: trace %s span %s parent %s", enc.TraceID, enc.SpanID, enc.ParentSpanID)
+	}
+	if enc.StartTimeUnixNano != "1000000000" || enc.EndTimeUnixNano != "2000000000" {
+		t.Errorf("time mismatch: start %s end %s", enc.StartTimeUnixNano, enc.EndTimeUnixNano)
+	}
+	if enc.Status == nil || enc.Status.Code != otlpStatusError || enc.Status.Message != "failed" {
+		t.Errorf("status mismatch: have %+v", enc.Status)
+	}
+	have, _ := json.Marshal(enc.Attributes)
+	want := `[{"key":"number","value":{"intValue":"7"}},{"key":"cached","value":{"boolValue":true}},{"key":"method","value":{"stringValue":"eth_call"}}]`
+	if string(have) != want {
+		t.Errorf("attribute mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+func TestExporter(t *testing.T) {
+	requests := make(chan *otlpRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(otlpRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	exp := newExporter(server.URL, "test", 10*time.Millisecond)
+	exp.Export(&Span{Trace: NewTraceID(), ID: NewSpanID(), Name: "flushed"})
+
+	select {
+	case req := <-requests:
+		if spans := req.ResourceSpans[0].ScopeSpans[0].Spans; len(spans) != 1 || spans[0].Name != "flushed" {
+			t.Errorf("flushed spans mismatch: have %+v", spans)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("spans not flushed")
+	}
+	// Closing the exporter sends the queued spans
+	exp.Export(&Span{Trace: NewTraceID(), ID: NewSpanID(), Name: "closed"})
+	exp.Close()
+
+	select {
+	case req := <-requests:
+		var names []string
+		for _, span := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+			names = append(names, span.Name)
+		}
+		if !reflect.DeepEqual(names, []string{"closed"}) {
+			t.Errorf("spans sent on close mismatch: have %v", names)
+		}
+	default:
+		t.Fatalf("spans not sent on close")
+	}
+}
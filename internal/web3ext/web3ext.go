@@ -23,17 +23,24 @@ var Modules = map[string]string{
 	"chequebook": ChequebookJs,
 	"clique":     CliqueJs,
 	"ethash":     EthashJs,
+	"ha":         HaJs,
 	"debug":      DebugJs,
 	"eth":        EthJs,
 	"miner":      MinerJs,
 	"net":        NetJs,
+	"ots":        OtsJs,
+	"parlia":     ParliaJs,
 	"personal":   PersonalJs,
 	"rpc":        RpcJs,
 	"shh":        ShhJs,
 	"swarmfs":    SwarmfsJs,
+	"token":      TokenJs,
+	"trace":      TraceJs,
 	"txpool":     TxpoolJs,
+	"validator":  ValidatorJs,
 	"les":        LESJs,
 	"vflux":      VfluxJs,
+	"webhook":    WebhookJs,
 }
 
 const ChequebookJs = `
@@ -118,6 +125,66 @@ web3._extend({
 });
 `
 
+const ParliaJs = `
+web3._extend({
+	property: 'parlia',
+	methods: [
+		new web3._extend.Method({
+			name: 'getSnapshot',
+			call: 'parlia_getSnapshot',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSnapshotAtHash',
+			call: 'parlia_getSnapshotAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getValidators',
+			call: 'parlia_getValidators',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsAtHash',
+			call: 'parlia_getValidatorsAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTurnInfo',
+			call: 'parlia_getTurnInfo',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'exportSnapshot',
+			call: 'parlia_exportSnapshot',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'validatorStats',
+			call: 'parlia_validatorStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'epochStats',
+			call: 'parlia_epochStats',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getDoubleSignEvidence',
+			call: 'parlia_getDoubleSignEvidence',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	]
+});
+`
+
 const EthashJs = `
 web3._extend({
 	property: 'ethash',
@@ -170,6 +237,16 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'startCapture',
+			call: 'admin_startCapture',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'stopCapture',
+			call: 'admin_stopCapture',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -181,6 +258,11 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'importParliaSnapshot',
+			call: 'admin_importParliaSnapshot',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -218,12 +300,20 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'reloadConfig',
+			call: 'admin_reloadConfig'
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
 		}),
+		new web3._extend.Property({
+			name: 'config',
+			getter: 'admin_config'
+		}),
 		new web3._extend.Property({
 			name: 'peers',
 			getter: 'admin_peers'
@@ -246,6 +336,25 @@ web3._extend({
 			params: 6,
 			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter, null, null, null, null, null],
 		}),
+		new web3._extend.Method({
+			name: 'blockPropagation',
+			call: 'debug_blockPropagation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'propagationReport',
+			call: 'debug_propagationReport',
+		}),
+		new web3._extend.Method({
+			name: 'snapshotHealth',
+			call: 'debug_snapshotHealth',
+		}),
+		new web3._extend.Method({
+			name: 'healState',
+			call: 'debug_healState',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new web3._extend.Method({
 			name: 'printBlock',
 			call: 'debug_printBlock',
@@ -257,6 +366,11 @@ web3._extend({
 			call: 'debug_getBlockRlp',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getRevertReason',
+			call: 'debug_getRevertReason',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'testSignCliqueBlock',
 			call: 'debug_testSignCliqueBlock',
@@ -279,6 +393,18 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'executionWitness',
+			call: 'debug_executionWitness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'gasProfile',
+			call: 'debug_gasProfile',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -289,6 +415,21 @@ web3._extend({
 			name: 'chaindbCompact',
 			call: 'debug_chaindbCompact',
 		}),
+		new web3._extend.Method({
+			name: 'dbGet',
+			call: 'debug_dbGet',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'dbAncient',
+			call: 'debug_dbAncient',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'dbAncients',
+			call: 'debug_dbAncients',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'verbosity',
 			call: 'debug_verbosity',
@@ -335,6 +476,12 @@ web3._extend({
 			call: 'debug_cpuProfile',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'dumpProfiles',
+			call: 'debug_dumpProfiles',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'startCPUProfile',
 			call: 'debug_startCPUProfile',
@@ -455,6 +602,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'simulateValidation',
+			call: 'debug_simulateValidation',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -466,6 +619,12 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'getReorgs',
+			call: 'debug_getReorgs',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -497,6 +656,17 @@ const EthJs = `
 web3._extend({
 	property: 'eth',
 	methods: [
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'gasTarget',
+			call: 'eth_gasTarget',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'chainId',
 			call: 'eth_chainId',
@@ -523,8 +693,8 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'estimateGas',
 			call: 'eth_estimateGas',
-			params: 2,
-			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputBlockNumberFormatter, null],
 			outputFormatter: web3._extend.utils.toDecimal
 		}),
 		new web3._extend.Method({
@@ -539,6 +709,41 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendPrivateTransaction',
+			call: 'eth_sendPrivateTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getContractCreation',
+			call: 'eth_getContractCreation',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByAddress',
+			call: 'eth_getInternalTransactionsByAddress',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'callMany',
+			call: 'eth_callMany',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'eth_getBlockReceipts',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getLogsPage',
+			call: 'eth_getLogsPage',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'getHeaderByNumber',
 			call: 'eth_getHeaderByNumber',
@@ -581,11 +786,40 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getBalances',
+			call: 'eth_getBalances',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter],
+			outputFormatter: function(balances) { return balances.map(web3._extend.utils.toBigNumber); }
+		}),
+		new web3._extend.Method({
+			name: 'getStorageSlots',
+			call: 'eth_getStorageSlots',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getProofs',
+			call: 'eth_getProofs',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionInclusionProof',
+			call: 'eth_getTransactionInclusionProof',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getReceiptProof',
+			call: 'eth_getReceiptProof',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'createAccessList',
 			call: 'eth_createAccessList',
-			params: 2,
-			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter],
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null],
 		}),
 	],
 	properties: [
@@ -605,6 +839,50 @@ web3._extend({
 });
 `
 
+const ValidatorJs = `
+web3._extend({
+	property: 'validator',
+	methods: [
+		new web3._extend.Method({
+			name: 'enterMaintenance',
+			call: 'validator_enterMaintenance'
+		}),
+		new web3._extend.Method({
+			name: 'exitMaintenance',
+			call: 'validator_exitMaintenance'
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'maintenance',
+			getter: 'validator_maintenance'
+		}),
+	]
+});
+`
+
+const HaJs = `
+web3._extend({
+	property: 'ha',
+	methods: [
+		new web3._extend.Method({
+			name: 'hold',
+			call: 'ha_hold'
+		}),
+		new web3._extend.Method({
+			name: 'release',
+			call: 'ha_release'
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'ha_status'
+		}),
+	]
+});
+`
+
 const MinerJs = `
 web3._extend({
 	property: 'miner',
@@ -634,7 +912,7 @@ web3._extend({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
 			params: 1,
-			inputFormatter: [web3._extend.utils.fromDecimal]
+			inputFormatter: [null]
 		}),
 		new web3._extend.Method({
 			name: 'setRecommitInterval',
@@ -645,6 +923,10 @@ web3._extend({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
 		}),
+		new web3._extend.Method({
+			name: 'buildStats',
+			call: 'miner_buildStats'
+		}),
 	],
 	properties: []
 });
@@ -931,3 +1213,168 @@ web3._extend({
 	]
 });
 `
+
+const TraceJs = `
+web3._extend({
+	property: 'trace',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'block',
+			call: 'trace_block',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'transaction',
+			call: 'trace_transaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'filter',
+			call: 'trace_filter',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'replayTransaction',
+			call: 'trace_replayTransaction',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'replayBlockTransactions',
+			call: 'trace_replayBlockTransactions',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+	]
+});
+`
+
+const TokenJs = `
+web3._extend({
+	property: 'token',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'getTransfers',
+			call: 'token_getTransfers',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBalancesAt',
+			call: 'token_getBalancesAt',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+	]
+});
+`
+
+const OtsJs = `
+web3._extend({
+	property: 'ots',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'getApiLevel',
+			call: 'ots_getApiLevel',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getInternalOperations',
+			call: 'ots_getInternalOperations',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'traceTransaction',
+			call: 'ots_traceTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionError',
+			call: 'ots_getTransactionError',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'hasCode',
+			call: 'ots_hasCode',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockDetails',
+			call: 'ots_getBlockDetails',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockDetailsByHash',
+			call: 'ots_getBlockDetailsByHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlockTransactions',
+			call: 'ots_getBlockTransactions',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'searchTransactionsBefore',
+			call: 'ots_searchTransactionsBefore',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'searchTransactionsAfter',
+			call: 'ots_searchTransactionsAfter',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionBySenderAndNonce',
+			call: 'ots_getTransactionBySenderAndNonce',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getContractCreator',
+			call: 'ots_getContractCreator',
+			params: 1
+		}),
+	]
+});
+`
+
+const WebhookJs = `
+web3._extend({
+	property: 'webhook',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'register',
+			call: 'webhook_register',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'addAddresses',
+			call: 'webhook_addAddresses',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'removeAddresses',
+			call: 'webhook_removeAddresses',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'unregister',
+			call: 'webhook_unregister',
+			params: 1
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'list',
+			getter: 'webhook_list'
+		}),
+	]
+});
+`
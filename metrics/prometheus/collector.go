@@ -31,6 +31,8 @@ var (
 	typeSummaryTpl         = "# TYPE %s summary\n"
 	keyValueTpl            = "%s %v\n\n"
 	keyQuantileTagValueTpl = "%s {quantile=\"%s\"} %v\n"
+	keySampleTpl           = "%s %v\n"
+	keyLabelsValueTpl      = "%s{%s} %v\n"
 )
 
 // collector is a collection of byte buffers that aggregate Prometheus reports
@@ -98,6 +100,23 @@ func (c *collector) addResettingTimer(name string, m metrics.ResettingTimer) {
 	c.buff.WriteRune('\n')
 }
 
+func (c *collector) addLabeledGauge(name string, samples []LabeledValue) {
+	name = mutateKey(name)
+	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
+	for _, sample := range samples {
+		if len(sample.Labels) == 0 {
+			c.buff.WriteString(fmt.Sprintf(keySampleTpl, name, sample.Value))
+			continue
+		}
+		labels := make([]string, 0, len(sample.Labels))
+		for _, label := range sortedLabels(sample.Labels) {
+			labels = append(labels, fmt.Sprintf("%s=%q", label, sample.Labels[label]))
+		}
+		c.buff.WriteString(fmt.Sprintf(keyLabelsValueTpl, name, strings.Join(labels, ","), sample.Value))
+	}
+	c.buff.WriteRune('\n')
+}
+
 func (c *collector) writeGaugeCounter(name string, value interface{}) {
 	name = mutateKey(name)
 	c.buff.WriteString(fmt.Sprintf(typeGaugeTpl, name))
@@ -58,6 +58,11 @@ func TestCollector(t *testing.T) {
 	emptyResettingTimer := metrics.NewResettingTimer().Snapshot()
 	c.addResettingTimer("test/empty_resetting_timer", emptyResettingTimer)
 
+	c.addLabeledGauge("test/labeled", []LabeledValue{
+		{Labels: map[string]string{"validator": "0x01", "kind": "in-turn"}, Value: 3},
+		{Labels: map[string]string{"validator": "0x02", "kind": "in-turn"}, Value: 4},
+	})
+
 	const expectedOutput = `# TYPE test_counter gauge
 test_counter 12345
 
@@ -100,6 +105,10 @@ test_resetting_timer {quantile="0.50"} 12000000
 test_resetting_timer {quantile="0.95"} 120000000
 test_resetting_timer {quantile="0.99"} 120000000
 
+# TYPE test_labeled gauge
+test_labeled{kind="in-turn",validator="0x01"} 3
+test_labeled{kind="in-turn",validator="0x02"} 4
+
 `
 	exp := c.buff.String()
 	if exp != expectedOutput {
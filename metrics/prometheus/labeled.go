@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"sort"
+	"sync"
+)
+
+// LabeledValue is a sample of a gauge family, distinguished from the other
+// samples of the family by its labels.
+type LabeledValue struct {
+	Labels map[string]string
+	Value  interface{}
+}
+
+// LabeledGauges gathers the current samples of a set of gauge families, keyed
+// by family name.
+type LabeledGauges func() map[string][]LabeledValue
+
+var (
+	labeledSources = make(map[string]LabeledGauges)
+	labeledLock    sync.Mutex
+)
+
+// RegisterLabeledGauges adds a source of labeled gauges to the reports, replacing
+// any previous source registered under the same name. The go-metrics registry
+// can't represent labels, so these are gathered from the sources on every scrape.
+func RegisterLabeledGauges(name string, source LabeledGauges) {
+	labeledLock.Lock()
+	defer labeledLock.Unlock()
+
+	labeledSources[name] = source
+}
+
+// UnregisterLabeledGauges removes a source of labeled gauges from the reports.
+func UnregisterLabeledGauges(name string) {
+	labeledLock.Lock()
+	defer labeledLock.Unlock()
+
+	delete(labeledSources, name)
+}
+
+// gatherLabeledGauges collects the samples of all the registered sources.
+func gatherLabeledGauges() map[string][]LabeledValue {
+	labeledLock.Lock()
+	sources := make([]LabeledGauges, 0, len(labeledSources))
+	for _, source := range labeledSources {
+		sources = append(sources, source)
+	}
+	labeledLock.Unlock()
+
+	families := make(map[string][]LabeledValue)
+	for _, source := range sources {
+		for name, samples := range source() {
+			families[name] = append(families[name], samples...)
+		}
+	}
+	return families
+}
+
+// sortedLabels returns the label names of a sample in alphabetical order.
+func sortedLabels(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
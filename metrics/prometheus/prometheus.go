@@ -61,6 +61,15 @@ func Handler(reg metrics.Registry) http.Handler {
 				log.Warn("Unknown Prometheus metric type", "type", fmt.Sprintf("%T", i))
 			}
 		}
+		families := gatherLabeledGauges()
+		names = names[:0]
+		for name := range families {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c.addLabeledGauge(name, families[name])
+		}
 		w.Header().Add("Content-Type", "text/plain")
 		w.Header().Add("Content-Length", fmt.Sprint(c.buff.Len()))
 		w.Write(c.buff.Bytes())
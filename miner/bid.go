@@ -0,0 +1,216 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxBidTxs is the maximum number of transactions accepted in a single bid.
+const maxBidTxs = 10000
+
+var (
+	errBuilderAPIDisabled = errors.New("no builders configured")
+	errUnknownBuilder     = errors.New("builder not allowed to bid")
+	errMissingBid         = errors.New("missing bid")
+	errInvalidBidValue    = errors.New("bid without gas fee")
+	errStaleBid           = errors.New("bid not on top of the current head")
+	errBidUnderpaid       = errors.New("bid pays less than promised")
+
+	bidReceivedMeter = metrics.NewRegisteredMeter("miner/bid/received", nil)
+	bidInvalidMeter  = metrics.NewRegisteredMeter("miner/bid/invalid", nil)
+	bidWonMeter      = metrics.NewRegisteredMeter("miner/bid/won", nil)
+)
+
+// RawBid is a block candidate submitted by an external builder. It lists the
+// transactions of the block on top of the parent, leaving all header fields to
+// the validator, along with the value the block pays to it.
+type RawBid struct {
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	ParentHash  common.Hash     `json:"parentHash"`
+	Txs         []hexutil.Bytes `json:"txs"`
+	GasUsed     hexutil.Uint64  `json:"gasUsed"`
+	GasFee      *hexutil.Big    `json:"gasFee"` // Gas fees and direct payments received by the validator
+}
+
+// Hash returns the hash signed by the builder of the bid. It commits to the chain
+// and to the validator the bid is offered to, so the bid can't be replayed to
+// another validator or on another chain.
+func (b *RawBid) Hash(chainID *big.Int, validator common.Address) common.Hash {
+	txs := make([][]byte, len(b.Txs))
+	for i, tx := range b.Txs {
+		txs[i] = tx
+	}
+	fee := new(big.Int)
+	if b.GasFee != nil {
+		fee = b.GasFee.ToInt()
+	}
+	enc, _ := rlp.EncodeToBytes([]interface{}{chainID, validator, uint64(b.BlockNumber), b.ParentHash, txs, uint64(b.GasUsed), fee})
+	return crypto.Keccak256Hash(enc)
+}
+
+// BidArgs is a bid signed by the builder submitting it.
+type BidArgs struct {
+	RawBid    *RawBid       `json:"rawBid"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Bid is a decoded bid of an authorized builder.
+type Bid struct {
+	Builder     common.Address
+	Hash        common.Hash
+	BlockNumber uint64
+	ParentHash  common.Hash
+	Txs         types.Transactions
+	GasUsed     uint64
+	GasFee      *big.Int
+}
+
+// newBid authenticates a bid signed for the given chain and validator against
+// the allowed builders and decodes its transactions.
+func newBid(args *BidArgs, chainID *big.Int, validator common.Address, builders []common.Address) (*Bid, error) {
+	if len(builders) == 0 {
+		return nil, errBuilderAPIDisabled
+	}
+	raw := args.RawBid
+	if raw == nil {
+		return nil, errMissingBid
+	}
+	if raw.GasFee == nil || raw.GasFee.ToInt().Sign() <= 0 {
+		return nil, errInvalidBidValue
+	}
+	if len(raw.Txs) > maxBidTxs {
+		return nil, fmt.Errorf("too many transactions in bid: have %d, max %d", len(raw.Txs), maxBidTxs)
+	}
+	hash := raw.Hash(chainID, validator)
+	pubkey, err := crypto.SigToPub(hash[:], args.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bid signature: %v", err)
+	}
+	builder := crypto.PubkeyToAddress(*pubkey)
+
+	allowed := false
+	for _, addr := range builders {
+		if addr == builder {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, errUnknownBuilder
+	}
+	bid := &Bid{
+		Builder:     builder,
+		Hash:        hash,
+		BlockNumber: uint64(raw.BlockNumber),
+		ParentHash:  raw.ParentHash,
+		Txs:         make(types.Transactions, len(raw.Txs)),
+		GasUsed:     uint64(raw.GasUsed),
+		GasFee:      new(big.Int).Set(raw.GasFee.ToInt()),
+	}
+	seen := make(map[common.Hash]struct{}, len(raw.Txs))
+	for i, enc := range raw.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		if _, ok := seen[tx.Hash()]; ok {
+			return nil, fmt.Errorf("duplicate transaction %x", tx.Hash())
+		}
+		seen[tx.Hash()] = struct{}{}
+		bid.Txs[i] = tx
+	}
+	return bid, nil
+}
+
+// bidPool keeps the most valuable bid of every builder for the next block.
+type bidPool struct {
+	bids map[common.Hash]map[common.Address]*Bid // Bids by parent hash and builder
+	lock sync.Mutex
+}
+
+func newBidPool() *bidPool {
+	return &bidPool{bids: make(map[common.Hash]map[common.Address]*Bid)}
+}
+
+// add inserts a bid building on top of the given head, replacing any less
+// valuable one of the same builder. It returns whether the bid is the most
+// valuable one known for the head.
+func (p *bidPool) add(bid *Bid, head *types.Header) (bool, error) {
+	if bid.ParentHash != head.Hash() || bid.BlockNumber != head.Number.Uint64()+1 {
+		return false, errStaleBid
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Drop the bids of any previous head, they can't be included anymore
+	for parent := range p.bids {
+		if parent != bid.ParentHash {
+			delete(p.bids, parent)
+		}
+	}
+	bids := p.bids[bid.ParentHash]
+	if bids == nil {
+		bids = make(map[common.Address]*Bid)
+		p.bids[bid.ParentHash] = bids
+	}
+	if old := bids[bid.Builder]; old != nil && old.GasFee.Cmp(bid.GasFee) >= 0 {
+		return false, nil
+	}
+	bids[bid.Builder] = bid
+	return p.best(bid.ParentHash) == bid, nil
+}
+
+// bestBid returns the most valuable bid on top of the given parent.
+func (p *bidPool) bestBid(parent common.Hash) *Bid {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.best(parent)
+}
+
+// best returns the most valuable bid on top of the given parent. The lock must
+// be held by the caller.
+func (p *bidPool) best(parent common.Hash) *Bid {
+	var best *Bid
+	for _, bid := range p.bids[parent] {
+		if best == nil || bid.GasFee.Cmp(best.GasFee) > 0 {
+			best = bid
+		}
+	}
+	return best
+}
+
+// remove drops a bid, e.g. because it failed the simulation.
+func (p *bidPool) remove(bid *Bid) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if bids := p.bids[bid.ParentHash]; bids != nil && bids[bid.Builder] == bid {
+		delete(bids, bid.Builder)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testBuilderKey, _  = crypto.GenerateKey()
+	testBuilderAddress = crypto.PubkeyToAddress(testBuilderKey.PublicKey)
+
+	// The chain and validator the test bids are offered to, those of the test worker
+	testBidChainID   = params.AllEthashProtocolChanges.ChainID
+	testBidValidator = testBankAddress
+)
+
+// newTestBid creates a bid of the test builder on top of the given parent.
+func newTestBid(t *testing.T, parent *types.Header, txs types.Transactions, gasUsed uint64, fee *big.Int) *BidArgs {
+	return newTestBidFor(t, testBidChainID, testBidValidator, parent, txs, gasUsed, fee)
+}
+
+// newTestBidFor creates a bid of the test builder offered to the given chain and
+// validator.
+func newTestBidFor(t *testing.T, chainID *big.Int, validator common.Address, parent *types.Header, txs types.Transactions, gasUsed uint64, fee *big.Int) *BidArgs {
+	raw := &RawBid{
+		BlockNumber: hexutil.Uint64(parent.Number.Uint64() + 1),
+		ParentHash:  parent.Hash(),
+		GasUsed:     hexutil.Uint64(gasUsed),
+		GasFee:      (*hexutil.Big)(fee),
+	}
+	for _, tx := range txs {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode transaction: %v", err)
+		}
+		raw.Txs = append(raw.Txs, enc)
+	}
+	hash := raw.Hash(chainID, validator)
+	sig, err := crypto.Sign(hash[:], testBuilderKey)
+	if err != nil {
+		t.Fatalf("failed to sign bid: %v", err)
+	}
+	return &BidArgs{RawBid: raw, Signature: sig}
+}
+
+func TestBidAuthentication(t *testing.T) {
+	parent := &types.Header{Number: big.NewInt(10)}
+	args := newTestBid(t, parent, nil, 0, big.NewInt(1))
+
+	if _, err := newBid(args, testBidChainID, testBidValidator, nil); err != errBuilderAPIDisabled {
+		t.Fatalf("error mismatch: have %v, want %v", err, errBuilderAPIDisabled)
+	}
+	if _, err := newBid(args, testBidChainID, testBidValidator, []common.Address{testUserAddress}); err != errUnknownBuilder {
+		t.Fatalf("error mismatch: have %v, want %v", err, errUnknownBuilder)
+	}
+	bid, err := newBid(args, testBidChainID, testBidValidator, []common.Address{testBuilderAddress})
+	if err != nil {
+		t.Fatalf("failed to decode bid: %v", err)
+	}
+	if bid.Builder != testBuilderAddress {
+		t.Fatalf("builder mismatch: have %x, want %x", bid.Builder, testBuilderAddress)
+	}
+	// Tampering with the bid must invalidate the signature
+	args.RawBid.GasFee = (*hexutil.Big)(big.NewInt(2))
+	if _, err := newBid(args, testBidChainID, testBidValidator, []common.Address{testBuilderAddress}); err != errUnknownBuilder {
+		t.Fatalf("error mismatch: have %v, want %v", err, errUnknownBuilder)
+	}
+}
+
+// Tests that a bid signed for one chain or validator is rejected by another.
+func TestBidReplay(t *testing.T) {
+	var (
+		parent     = &types.Header{Number: big.NewInt(10)}
+		builders   = []common.Address{testBuilderAddress}
+		chainA     = big.NewInt(56)
+		chainB     = big.NewInt(97)
+		validatorA = common.Address{0xaa}
+		validatorB = common.Address{0xbb}
+	)
+	args := newTestBidFor(t, chainA, validatorA, parent, nil, 0, big.NewInt(1))
+	if _, err := newBid(args, chainA, validatorA, builders); err != nil {
+		t.Fatalf("bid rejected by its target: %v", err)
+	}
+	tests := []struct {
+		chainID   *big.Int
+		validator common.Address
+	}{
+		{chainB, validatorA},
+		{chainA, validatorB},
+		{chainB, validatorB},
+	}
+	for i, tt := range tests {
+		if _, err := newBid(args, tt.chainID, tt.validator, builders); err != errUnknownBuilder {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, errUnknownBuilder)
+		}
+	}
+}
+
+func TestBidPool(t *testing.T) {
+	var (
+		pool     = newBidPool()
+		head     = &types.Header{Number: big.NewInt(10)}
+		builders = []common.Address{testBuilderAddress}
+	)
+	bid := func(fee int64) *Bid {
+		b, err := newBid(newTestBid(t, head, nil, 0, big.NewInt(fee)), testBidChainID, testBidValidator, builders)
+		if err != nil {
+			t.Fatalf("failed to decode bid: %v", err)
+		}
+		return b
+	}
+	if best, err := pool.add(bid(10), head); err != nil || !best {
+		t.Fatalf("first bid not the best: %v %v", best, err)
+	}
+	if best, err := pool.add(bid(5), head); err != nil || best {
+		t.Fatalf("cheaper bid replaced the best: %v %v", best, err)
+	}
+	better := bid(20)
+	if best, err := pool.add(better, head); err != nil || !best {
+		t.Fatalf("better bid not the best: %v %v", best, err)
+	}
+	if have := pool.bestBid(head.Hash()); have != better {
+		t.Fatalf("best bid mismatch: have %v, want %v", have, better)
+	}
+	if _, err := pool.add(bid(30), &types.Header{Number: big.NewInt(11)}); err != errStaleBid {
+		t.Fatalf("error mismatch: have %v, want %v", err, errStaleBid)
+	}
+	pool.remove(better)
+	if have := pool.bestBid(head.Hash()); have != nil {
+		t.Fatalf("removed bid still the best: %v", have)
+	}
+}
+
+func TestSimulateBid(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	w, b := newTestWorker(t, params.AllEthashProtocolChanges, ethash.NewFaker(), db, 0)
+	defer w.close()
+
+	var (
+		parent   = b.chain.CurrentBlock()
+		gasPrice = big.NewInt(params.GWei)
+		fee      = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(params.TxGas))
+		signer   = types.LatestSigner(params.AllEthashProtocolChanges)
+		header   = &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(1),
+			GasLimit:   parent.GasLimit(),
+			Coinbase:   common.Address{0xc0},
+			Difficulty: big.NewInt(1),
+			Time:       parent.Time() + 1,
+		}
+	)
+	tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    0,
+		To:       &testUserAddress,
+		Value:    big.NewInt(1000),
+		Gas:      params.TxGas,
+		GasPrice: gasPrice,
+	})
+	tests := []struct {
+		gasUsed uint64
+		fee     *big.Int
+		fail    bool
+	}{
+		{params.TxGas, fee, false},
+		{params.TxGas, new(big.Int).Add(fee, common.Big1), true}, // promises more than paid
+		{params.TxGas + 1, fee, true},                            // wrong gas used
+	}
+	for i, tt := range tests {
+		bid, err := newBid(newTestBid(t, parent.Header(), types.Transactions{tx}, tt.gasUsed, tt.fee), testBidChainID, testBidValidator, []common.Address{testBuilderAddress})
+		if err != nil {
+			t.Fatalf("test %d: failed to decode bid: %v", i, err)
+		}
+		env, err := w.simulateBid(parent, header, bid)
+		if tt.fail {
+			if err == nil {
+				t.Fatalf("test %d: invalid bid accepted", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: valid bid rejected: %v", i, err)
+		}
+		if len(env.txs) != 1 || env.profit().Cmp(fee) != 0 {
+			t.Fatalf("test %d: environment mismatch: txs %d, profit %v", i, len(env.txs), env.profit())
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// maxBundleTxs is the maximum number of transactions accepted in a single bundle.
+	maxBundleTxs = 64
+
+	// maxBundles is the maximum number of bundles queued for the upcoming blocks.
+	maxBundles = 1024
+)
+
+var (
+	errEmptyBundle      = errors.New("bundle without transactions")
+	errStaleBundle      = errors.New("bundle targets an already mined block")
+	errBundleTimestamp  = errors.New("bundle with min timestamp above max timestamp")
+	errBundlePoolFull   = errors.New("bundle pool full")
+	errBundleReverted   = errors.New("bundle transaction reverted")
+	errBundleUnderpaid  = errors.New("bundle pays less than the minimum gas price")
+	errBundleBlobGasCap = errors.New("bundle exceeds the blob gas limit")
+
+	bundleReceivedMeter = metrics.NewRegisteredMeter("miner/bundle/received", nil)
+	bundleInvalidMeter  = metrics.NewRegisteredMeter("miner/bundle/invalid", nil)
+	bundleIncludedMeter = metrics.NewRegisteredMeter("miner/bundle/included", nil)
+)
+
+// SendBundleArgs is an ordered list of transactions submitted by a searcher to
+// be included atomically at the top of the target block.
+type SendBundleArgs struct {
+	Txs          []hexutil.Bytes `json:"txs"`
+	BlockNumber  hexutil.Uint64  `json:"blockNumber"`
+	MinTimestamp *hexutil.Uint64 `json:"minTimestamp,omitempty"`
+	MaxTimestamp *hexutil.Uint64 `json:"maxTimestamp,omitempty"`
+}
+
+// Bundle is a decoded bundle of transactions waiting for its target block.
+type Bundle struct {
+	Hash         common.Hash
+	Txs          types.Transactions
+	BlockNumber  uint64
+	MinTimestamp uint64
+	MaxTimestamp uint64 // Zero if the bundle can be included at any time
+}
+
+// newBundle validates the arguments of a bundle submission and decodes its
+// transactions.
+func newBundle(args *SendBundleArgs) (*Bundle, error) {
+	if len(args.Txs) == 0 {
+		return nil, errEmptyBundle
+	}
+	if len(args.Txs) > maxBundleTxs {
+		return nil, fmt.Errorf("too many transactions in bundle: have %d, max %d", len(args.Txs), maxBundleTxs)
+	}
+	bundle := &Bundle{
+		Txs:         make(types.Transactions, len(args.Txs)),
+		BlockNumber: uint64(args.BlockNumber),
+	}
+	if args.MinTimestamp != nil {
+		bundle.MinTimestamp = uint64(*args.MinTimestamp)
+	}
+	if args.MaxTimestamp != nil {
+		bundle.MaxTimestamp = uint64(*args.MaxTimestamp)
+		if bundle.MaxTimestamp < bundle.MinTimestamp {
+			return nil, errBundleTimestamp
+		}
+	}
+	var (
+		seen   = make(map[common.Hash]struct{}, len(args.Txs))
+		hashes = make([]byte, 0, len(args.Txs)*common.HashLength)
+	)
+	for i, enc := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		if _, ok := seen[tx.Hash()]; ok {
+			return nil, fmt.Errorf("duplicate transaction %x", tx.Hash())
+		}
+		seen[tx.Hash()] = struct{}{}
+		hashes = append(hashes, tx.Hash().Bytes()...)
+		bundle.Txs[i] = tx
+	}
+	bundle.Hash = crypto.Keccak256Hash(hashes)
+	return bundle, nil
+}
+
+// includable returns whether the bundle may be included in a block with the
+// given number and timestamp.
+func (b *Bundle) includable(number, time uint64) bool {
+	if b.BlockNumber != number || time < b.MinTimestamp {
+		return false
+	}
+	return b.MaxTimestamp == 0 || time <= b.MaxTimestamp
+}
+
+// bundlePool keeps the bundles submitted for the upcoming blocks.
+type bundlePool struct {
+	bundles map[common.Hash]*Bundle // Bundles by hash
+	lock    sync.Mutex
+}
+
+func newBundlePool() *bundlePool {
+	return &bundlePool{bundles: make(map[common.Hash]*Bundle)}
+}
+
+// add queues a bundle targeting a block after the given head.
+func (p *bundlePool) add(bundle *Bundle, head *types.Header) error {
+	if bundle.BlockNumber <= head.Number.Uint64() {
+		return errStaleBundle
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(head.Number.Uint64() + 1)
+	if _, ok := p.bundles[bundle.Hash]; !ok && len(p.bundles) >= maxBundles {
+		return errBundlePoolFull
+	}
+	p.bundles[bundle.Hash] = bundle
+	return nil
+}
+
+// pending returns the bundles includable in a block with the given number and
+// timestamp, dropping the ones targeting earlier blocks.
+func (p *bundlePool) pending(number, time uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+
+	var bundles []*Bundle
+	for _, bundle := range p.bundles {
+		if bundle.includable(number, time) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// prune drops the bundles targeting blocks before the given number. The lock
+// must be held by the caller.
+func (p *bundlePool) prune(number uint64) {
+	for hash, bundle := range p.bundles {
+		if bundle.BlockNumber < number {
+			delete(p.bundles, hash)
+		}
+	}
+}
+
+// remove drops a bundle, e.g. because it failed the simulation.
+func (p *bundlePool) remove(bundle *Bundle) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.bundles, bundle.Hash)
+}
+
+// simulatedBundle is a bundle executed on top of a block along with the value
+// it pays to the validator.
+type simulatedBundle struct {
+	bundle  *Bundle
+	gasUsed uint64
+	profit  *big.Int
+}
+
+// price returns the value paid to the validator per unit of gas.
+func (b *simulatedBundle) price() *big.Int {
+	if b.gasUsed == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(b.profit, new(big.Int).SetUint64(b.gasUsed))
+}
+
+// sortBundles orders the simulated bundles by decreasing price, breaking ties
+// by hash to keep the block contents deterministic.
+func sortBundles(bundles []*simulatedBundle) {
+	sort.Slice(bundles, func(i, j int) bool {
+		if cmp := bundles[i].price().Cmp(bundles[j].price()); cmp != 0 {
+			return cmp > 0
+		}
+		return bundles[i].bundle.Hash.Hex() < bundles[j].bundle.Hash.Hex()
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTestBundle creates the arguments of a bundle submission for the given block.
+func newTestBundle(t *testing.T, number uint64, txs types.Transactions) *SendBundleArgs {
+	args := &SendBundleArgs{BlockNumber: hexutil.Uint64(number)}
+	for _, tx := range txs {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			t.Fatalf("failed to encode transaction: %v", err)
+		}
+		args.Txs = append(args.Txs, enc)
+	}
+	return args
+}
+
+func TestBundlePool(t *testing.T) {
+	var (
+		pool   = newBundlePool()
+		head   = &types.Header{Number: big.NewInt(10)}
+		signer = types.LatestSigner(params.TestChainConfig)
+	)
+	bundle := func(number uint64, nonce uint64, minTime, maxTime uint64) *Bundle {
+		tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{Nonce: nonce, To: &testUserAddress, Gas: params.TxGas, GasPrice: big.NewInt(1)})
+		args := newTestBundle(t, number, types.Transactions{tx})
+		if minTime != 0 {
+			args.MinTimestamp = (*hexutil.Uint64)(&minTime)
+		}
+		if maxTime != 0 {
+			args.MaxTimestamp = (*hexutil.Uint64)(&maxTime)
+		}
+		b, err := newBundle(args)
+		if err != nil {
+			t.Fatalf("failed to decode bundle: %v", err)
+		}
+		return b
+	}
+	if _, err := newBundle(newTestBundle(t, 11, nil)); err != errEmptyBundle {
+		t.Fatalf("error mismatch: have %v, want %v", err, errEmptyBundle)
+	}
+	if err := pool.add(bundle(10, 0, 0, 0), head); err != errStaleBundle {
+		t.Fatalf("error mismatch: have %v, want %v", err, errStaleBundle)
+	}
+	var (
+		next   = bundle(11, 0, 0, 0)
+		timed  = bundle(11, 1, 100, 200)
+		future = bundle(12, 2, 0, 0)
+	)
+	for _, b := range []*Bundle{next, timed, future} {
+		if err := pool.add(b, head); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	if have := pool.pending(11, 50); len(have) != 1 || have[0] != next {
+		t.Fatalf("pending bundles mismatch before min timestamp: %v", have)
+	}
+	if have := pool.pending(11, 150); len(have) != 2 {
+		t.Fatalf("pending bundles mismatch within timestamps: have %d, want 2", len(have))
+	}
+	if have := pool.pending(11, 250); len(have) != 1 || have[0] != next {
+		t.Fatalf("pending bundles mismatch after max timestamp: %v", have)
+	}
+	// Moving on to the next block must drop the bundles of the previous one
+	if have := pool.pending(12, 0); len(have) != 1 || have[0] != future {
+		t.Fatalf("pending bundles mismatch for next block: %v", have)
+	}
+	if len(pool.bundles) != 1 {
+		t.Fatalf("stale bundles not pruned: have %d, want 1", len(pool.bundles))
+	}
+}
+
+func TestCommitBundles(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	w, b := newTestWorker(t, params.AllEthashProtocolChanges, ethash.NewFaker(), db, 0)
+	defer w.close()
+
+	var (
+		parent = b.chain.CurrentBlock()
+		signer = types.LatestSigner(params.AllEthashProtocolChanges)
+		header = &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(1),
+			GasLimit:   parent.GasLimit(),
+			Coinbase:   common.Address{0xc0},
+			Difficulty: big.NewInt(1),
+			Time:       parent.Time() + 1,
+		}
+	)
+	bundle := func(gasPrice int64) *Bundle {
+		tx := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+			Nonce:    0,
+			To:       &testUserAddress,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: big.NewInt(gasPrice),
+		})
+		b, err := newBundle(newTestBundle(t, 1, types.Transactions{tx}))
+		if err != nil {
+			t.Fatalf("failed to decode bundle: %v", err)
+		}
+		if err := w.bundles.add(b, parent.Header()); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+		return b
+	}
+	var (
+		best       = bundle(2 * params.GWei)
+		conflicted = bundle(params.GWei) // spends the same nonce as the best one
+		underpaid  = bundle(0)
+	)
+	env, err := w.makeEnv(parent, header)
+	if err != nil {
+		t.Fatalf("failed to create environment: %v", err)
+	}
+	w.commitBundles(env)
+
+	if len(env.txs) != 1 || env.txs[0].Hash() != best.Txs[0].Hash() {
+		t.Fatalf("included transactions mismatch: have %d txs, want the best bundle", len(env.txs))
+	}
+	if fee := new(big.Int).Mul(big.NewInt(2*params.GWei), new(big.Int).SetUint64(params.TxGas)); env.profit().Cmp(fee) != 0 {
+		t.Fatalf("profit mismatch: have %v, want %v", env.profit(), fee)
+	}
+	if env.header.GasUsed != params.TxGas || len(env.receipts) != 1 {
+		t.Fatalf("environment mismatch: gas used %d, receipts %d", env.header.GasUsed, len(env.receipts))
+	}
+	// The conflicting bundle must be kept, the underpaid one dropped
+	if _, ok := w.bundles.bundles[conflicted.Hash]; !ok {
+		t.Fatalf("conflicting bundle dropped")
+	}
+	if _, ok := w.bundles.bundles[underpaid.Hash]; ok {
+		t.Fatalf("underpaid bundle kept")
+	}
+}
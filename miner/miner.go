@@ -52,6 +52,12 @@ type Config struct {
 	GasPrice      *big.Int       // Minimum gas price for mining a transaction
 	Recommit      time.Duration  // The time interval for miner to re-create mining work.
 	Noverify      bool           // Disable remote mining solution verification(only useful in ethash).
+
+	Builders []common.Address `toml:",omitempty"` // External block builders allowed to submit bids
+
+	Ordering          string           `toml:",omitempty"` // Name of the transaction ordering policy (default = price)
+	PriorityAddresses []common.Address `toml:",omitempty"` // Senders whose transactions the priority ordering includes first
+	BuildRounds       []float64        `toml:",omitempty"` // Fractions of the slot at which the block candidate is extended
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -239,6 +245,23 @@ func (miner *Miner) DisablePreseal() {
 	miner.worker.disablePreseal()
 }
 
+// SendBid submits a block candidate of an external builder for the next block.
+// The bid replaces the locally built block if it pays the validator more.
+func (miner *Miner) SendBid(bid *BidArgs) (common.Hash, error) {
+	return miner.worker.submitBid(bid)
+}
+
+// SendBundle queues a bundle of transactions for its target block. The bundle
+// is included atomically at the top of the block if it is profitable.
+func (miner *Miner) SendBundle(bundle *SendBundleArgs) (common.Hash, error) {
+	return miner.worker.submitBundle(bundle)
+}
+
+// BuildStats returns the building statistics of the recently sealed blocks.
+func (miner *Miner) BuildStats() []BuildStats {
+	return miner.worker.buildStats.list()
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
@@ -0,0 +1,325 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultOrdering is the ordering policy used if the config doesn't select one.
+const DefaultOrdering = "price"
+
+// TxIterator yields the pending transactions in the order a block includes them,
+// honouring the nonce order of each account.
+type TxIterator interface {
+	// Peek returns the next transaction, nil if there is none left.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction with the following one of its account.
+	Shift()
+
+	// Pop drops the next transaction along with the following ones of its
+	// account, as none of them can be included anymore.
+	Pop()
+
+	// CurrentSize returns the number of accounts with transactions left.
+	CurrentSize() int
+}
+
+// OrderingPolicy decides the order the pending transactions of the pool fill a
+// block in.
+type OrderingPolicy interface {
+	// Order returns an iterator over the pending transactions, given nonce-sorted
+	// by sender, for the block with the given header. The map is reowned.
+	Order(header *types.Header, signer types.Signer, txs map[common.Address]types.Transactions) TxIterator
+}
+
+// BundleMerger is implemented by the ordering policies having the bundles of the
+// searchers compete on price with the pool transactions, rather than having them
+// fill the top of the block.
+type BundleMerger interface {
+	MergeBundles() bool
+}
+
+// orderingPolicies are the ordering policy constructors by name.
+var orderingPolicies = make(map[string]func(config *Config) OrderingPolicy)
+
+func init() {
+	RegisterOrderingPolicy("price", func(*Config) OrderingPolicy { return priceOrdering{} })
+	RegisterOrderingPolicy("tip", func(*Config) OrderingPolicy { return tipOrdering{} })
+	RegisterOrderingPolicy("priority", newPriorityOrdering)
+	RegisterOrderingPolicy("bundles", func(*Config) OrderingPolicy { return bundleOrdering{} })
+}
+
+// RegisterOrderingPolicy registers a constructor of an ordering policy under the
+// given name, for the miner config to select. Registrations have to be done
+// before the miner starts, usually from init functions.
+func RegisterOrderingPolicy(name string, policy func(config *Config) OrderingPolicy) {
+	if _, exist := orderingPolicies[name]; exist {
+		panic(fmt.Sprintf("ordering policy %q registered twice", name))
+	}
+	orderingPolicies[name] = policy
+}
+
+// CheckOrdering checks that the ordering policy selected by the config is
+// registered.
+func CheckOrdering(config *Config) error {
+	if config.Ordering == "" {
+		return nil
+	}
+	if _, exist := orderingPolicies[config.Ordering]; !exist {
+		names := make([]string, 0, len(orderingPolicies))
+		for name := range orderingPolicies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown ordering policy %q, want one of %s", config.Ordering, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// newOrderingPolicy creates the ordering policy selected by the config.
+func newOrderingPolicy(config *Config) (OrderingPolicy, error) {
+	if err := CheckOrdering(config); err != nil {
+		return nil, err
+	}
+	name := config.Ordering
+	if name == "" {
+		name = DefaultOrdering
+	}
+	return orderingPolicies[name](config), nil
+}
+
+// priceOrdering includes the transactions by decreasing gas price, the earliest
+// seen first on ties.
+type priceOrdering struct{}
+
+func (priceOrdering) Order(header *types.Header, signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	return types.NewTransactionsByPriceAndNonce(signer, txs)
+}
+
+// tipOrdering includes the transactions by decreasing effective tip, the gas
+// price above the base fee, the earliest seen first on ties. The accounts whose
+// next transaction doesn't pay the base fee are skipped. Before the base fee
+// fork, the tip is the whole gas price.
+type tipOrdering struct{}
+
+func (tipOrdering) Order(header *types.Header, signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		baseFee = new(big.Int)
+	}
+	tip := func(tx *types.Transaction) *big.Int {
+		return new(big.Int).Sub(tx.GasPrice(), baseFee)
+	}
+	return newTxsByKey(signer, txs, tip, func(tx *types.Transaction) bool { return tip(tx).Sign() >= 0 })
+}
+
+// priorityOrdering includes the transactions of the allowlisted senders first,
+// then the others, each lane by decreasing gas price.
+type priorityOrdering struct {
+	priority map[common.Address]struct{}
+}
+
+func newPriorityOrdering(config *Config) OrderingPolicy {
+	policy := &priorityOrdering{priority: make(map[common.Address]struct{}, len(config.PriorityAddresses))}
+	for _, addr := range config.PriorityAddresses {
+		policy.priority[addr] = struct{}{}
+	}
+	return policy
+}
+
+func (p *priorityOrdering) Order(header *types.Header, signer types.Signer, txs map[common.Address]types.Transactions) TxIterator {
+	priority := make(map[common.Address]types.Transactions)
+	for addr := range p.priority {
+		if accTxs, ok := txs[addr]; ok {
+			priority[addr] = accTxs
+			delete(txs, addr)
+		}
+	}
+	return &txLanes{lanes: []TxIterator{
+		types.NewTransactionsByPriceAndNonce(signer, priority),
+		types.NewTransactionsByPriceAndNonce(signer, txs),
+	}}
+}
+
+// bundleOrdering includes the transactions by decreasing gas price like the
+// price ordering, with the bundles merged in by the price they pay.
+type bundleOrdering struct {
+	priceOrdering
+}
+
+func (bundleOrdering) MergeBundles() bool { return true }
+
+// txLanes yields the transactions of its lanes one lane after the other.
+type txLanes struct {
+	lanes []TxIterator
+}
+
+// lane returns the first lane with transactions left.
+func (l *txLanes) lane() TxIterator {
+	for _, lane := range l.lanes {
+		if lane.Peek() != nil {
+			return lane
+		}
+	}
+	return nil
+}
+
+func (l *txLanes) Peek() *types.Transaction {
+	if lane := l.lane(); lane != nil {
+		return lane.Peek()
+	}
+	return nil
+}
+
+func (l *txLanes) Shift() {
+	if lane := l.lane(); lane != nil {
+		lane.Shift()
+	}
+}
+
+func (l *txLanes) Pop() {
+	if lane := l.lane(); lane != nil {
+		lane.Pop()
+	}
+}
+
+func (l *txLanes) CurrentSize() int {
+	var size int
+	for _, lane := range l.lanes {
+		size += lane.CurrentSize()
+	}
+	return size
+}
+
+// txsByKey yields the transactions by decreasing key, the earliest seen first on
+// ties, skipping the accounts whose next transaction isn't includable.
+type txsByKey struct {
+	txs        map[common.Address]types.Transactions
+	heads      txHeads
+	signer     types.Signer
+	key        func(tx *types.Transaction) *big.Int
+	includable func(tx *types.Transaction) bool
+}
+
+func newTxsByKey(signer types.Signer, txs map[common.Address]types.Transactions, key func(tx *types.Transaction) *big.Int, includable func(tx *types.Transaction) bool) *txsByKey {
+	t := &txsByKey{
+		txs:        txs,
+		heads:      make(txHeads, 0, len(txs)),
+		signer:     signer,
+		key:        key,
+		includable: includable,
+	}
+	for from, accTxs := range txs {
+		// Ensure the sender address is from the signer
+		if acc, _ := types.Sender(signer, accTxs[0]); acc != from || !includable(accTxs[0]) {
+			delete(txs, from)
+			continue
+		}
+		t.heads = append(t.heads, &txHead{tx: accTxs[0], from: from, key: key(accTxs[0])})
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&t.heads)
+	return t
+}
+
+func (t *txsByKey) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+func (t *txsByKey) Shift() {
+	head := t.heads[0]
+	if txs := t.txs[head.from]; len(txs) > 0 && t.includable(txs[0]) {
+		head.tx, head.key, t.txs[head.from] = txs[0], t.key(txs[0]), txs[1:]
+		heap.Fix(&t.heads, 0)
+		return
+	}
+	heap.Pop(&t.heads)
+}
+
+func (t *txsByKey) Pop() {
+	heap.Pop(&t.heads)
+}
+
+func (t *txsByKey) CurrentSize() int {
+	return len(t.heads)
+}
+
+// txHead is the next transaction of an account along with its key.
+type txHead struct {
+	tx   *types.Transaction
+	from common.Address
+	key  *big.Int
+}
+
+// txHeads is a heap of the next transactions of the accounts, by decreasing key.
+type txHeads []*txHead
+
+func (h txHeads) Len() int { return len(h) }
+func (h txHeads) Less(i, j int) bool {
+	if cmp := h[i].key.Cmp(h[j].key); cmp != 0 {
+		return cmp > 0
+	}
+	return h[i].tx.Time().Before(h[j].tx.Time())
+}
+func (h txHeads) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *txHeads) Push(x interface{}) {
+	*h = append(*h, x.(*txHead))
+}
+
+func (h *txHeads) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
+
+// txsAbove yields the transactions of an iterator as long as they pay at least
+// the given price per gas above the base fee.
+type txsAbove struct {
+	TxIterator
+	price   *big.Int
+	baseFee *big.Int // Nil before the base fee fork
+}
+
+func (t *txsAbove) Peek() *types.Transaction {
+	tx := t.TxIterator.Peek()
+	if tx == nil {
+		return nil
+	}
+	tip := tx.GasPrice()
+	if t.baseFee != nil {
+		tip.Sub(tip, t.baseFee)
+	}
+	if tip.Cmp(t.price) < 0 {
+		return nil
+	}
+	return tx
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// orderTestTxs returns the pending transactions of three accounts paying 1, 2
+// and 3 GWei, two transactions each.
+func orderTestTxs(t *testing.T, signer types.Signer) (map[common.Address]types.Transactions, []common.Address) {
+	var (
+		txs   = make(map[common.Address]types.Transactions)
+		addrs []common.Address
+	)
+	for i := 1; i <= 3; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			txs[addr] = append(txs[addr], signTestTx(t, key, signer, nonce, int64(i)*params.GWei))
+		}
+		addrs = append(addrs, addr)
+	}
+	return txs, addrs
+}
+
+func signTestTx(t *testing.T, key *ecdsa.PrivateKey, signer types.Signer, nonce uint64, price int64) *types.Transaction {
+	tx, err := types.SignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &common.Address{}, Gas: params.TxGas, GasPrice: big.NewInt(price)})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// drainOrder returns the senders of the transactions of the iterator in order.
+func drainOrder(signer types.Signer, txs TxIterator) []common.Address {
+	var order []common.Address
+	for tx := txs.Peek(); tx != nil; tx = txs.Peek() {
+		from, _ := types.Sender(signer, tx)
+		order = append(order, from)
+		txs.Shift()
+	}
+	return order
+}
+
+func TestOrderingPolicies(t *testing.T) {
+	signer := types.HomesteadSigner{}
+
+	for _, tt := range []struct {
+		config   Config
+		baseFee  *big.Int
+		priority bool  // Whether the cheapest sender has priority
+		want     []int // Indexes of the senders in inclusion order
+	}{
+		{config: Config{}, want: []int{2, 2, 1, 1, 0, 0}},
+		{config: Config{Ordering: "price"}, want: []int{2, 2, 1, 1, 0, 0}},
+		{config: Config{Ordering: "bundles"}, want: []int{2, 2, 1, 1, 0, 0}},
+		{config: Config{Ordering: "tip"}, want: []int{2, 2, 1, 1, 0, 0}},
+		// The transactions not paying the base fee are skipped
+		{config: Config{Ordering: "tip"}, baseFee: big.NewInt(2 * params.GWei), want: []int{2, 2, 1, 1}},
+		// The priority senders are included first
+		{config: Config{Ordering: "priority"}, want: []int{2, 2, 1, 1, 0, 0}},
+		{config: Config{Ordering: "priority"}, priority: true, want: []int{0, 0, 2, 2, 1, 1}},
+	} {
+		txs, addrs := orderTestTxs(t, signer)
+		if tt.priority {
+			tt.config.PriorityAddresses = []common.Address{addrs[0]}
+		}
+		policy, err := newOrderingPolicy(&tt.config)
+		if err != nil {
+			t.Fatalf("ordering %q: failed to create policy: %v", tt.config.Ordering, err)
+		}
+		header := &types.Header{Number: big.NewInt(1), BaseFee: tt.baseFee}
+		have := drainOrder(signer, policy.Order(header, signer, txs))
+		if len(have) != len(tt.want) {
+			t.Errorf("ordering %q: included %d transactions, want %d", tt.config.Ordering, len(have), len(tt.want))
+			continue
+		}
+		for i, index := range tt.want {
+			if have[i] != addrs[index] {
+				t.Errorf("ordering %q: transaction %d sender mismatch: have %x, want %x", tt.config.Ordering, i, have[i], addrs[index])
+			}
+		}
+		_, merges := policy.(BundleMerger)
+		if want := tt.config.Ordering == "bundles"; merges != want {
+			t.Errorf("ordering %q: bundle merging mismatch: have %v, want %v", tt.config.Ordering, merges, want)
+		}
+	}
+	if err := CheckOrdering(&Config{Ordering: "unknown"}); err == nil {
+		t.Error("unknown ordering policy accepted")
+	}
+}
+
+func TestTxsAbove(t *testing.T) {
+	signer := types.HomesteadSigner{}
+	txs, addrs := orderTestTxs(t, signer)
+	iter := types.NewTransactionsByPriceAndNonce(signer, txs)
+
+	// Only the transactions paying a 1 GWei tip above a 1 GWei base fee pass
+	above := &txsAbove{TxIterator: iter, price: big.NewInt(params.GWei), baseFee: big.NewInt(params.GWei)}
+	if have := drainOrder(signer, above); len(have) != 4 || have[0] != addrs[2] || have[3] != addrs[1] {
+		t.Fatalf("transactions above the price mismatch: %x", have)
+	}
+	// The cheaper ones are left in the iterator
+	if have := drainOrder(signer, iter); len(have) != 2 || have[0] != addrs[0] {
+		t.Fatalf("transactions below the price mismatch: %x", have)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var buildRoundMeter = metrics.NewRegisteredMeter("miner/round/committed", nil)
+
+// DefaultBuildRounds are the fractions of the time left until the seal deadline
+// at which the worker rebuilds its block candidate with the newly arrived
+// transactions.
+var DefaultBuildRounds = []float64{0.5, 0.8, 0.95}
+
+// buildRounds schedules the incremental rebuilds of the block candidate within
+// the slot of a block. All methods but active are only used by the main loop.
+type buildRounds struct {
+	fractions []float64 // Sorted fractions of the slot at which the rounds run
+
+	number    uint64        // Number of the block the rounds are scheduled for
+	start     time.Time     // Time the first candidate of the block was built
+	left      time.Duration // Time left from the start until the seal deadline
+	next      int           // Index of the next round to run
+	timer     *time.Timer   // Timer firing at the next round, nil if none
+	interrupt *int32        // Interrupt signal of the latest work request
+	running   int32         // Flag whether rounds are scheduled, accessed atomically
+}
+
+// newBuildRounds creates a round schedule out of the given fractions, dropping
+// the ones outside of (0, 1).
+func newBuildRounds(fractions []float64) *buildRounds {
+	var valid []float64
+	for _, f := range fractions {
+		if f <= 0 || f >= 1 {
+			log.Warn("Ignoring invalid miner build round", "fraction", f)
+			continue
+		}
+		valid = append(valid, f)
+	}
+	sort.Float64s(valid)
+	return &buildRounds{fractions: valid}
+}
+
+// schedule arms the rounds of the given block, whose seal deadline is left away
+// from now. Rebuilding the same block, e.g. for a new bid, keeps the rounds that
+// are still to run.
+func (r *buildRounds) schedule(number uint64, now time.Time, left time.Duration, interrupt *int32) {
+	r.interrupt = interrupt
+	if r.timer != nil && r.number == number {
+		return
+	}
+	r.stop()
+	if len(r.fractions) == 0 || left <= 0 {
+		return
+	}
+	r.number, r.start, r.left, r.next = number, now, left, 0
+	r.arm(now)
+}
+
+// arm starts the timer of the next round not yet passed at now.
+func (r *buildRounds) arm(now time.Time) {
+	for ; r.next < len(r.fractions); r.next++ {
+		at := r.start.Add(time.Duration(r.fractions[r.next] * float64(r.left)))
+		if at.After(now) {
+			r.timer = time.NewTimer(at.Sub(now))
+			atomic.StoreInt32(&r.running, 1)
+			return
+		}
+	}
+	r.stop()
+}
+
+// wait returns the channel firing at the next round, or nil if there is none.
+func (r *buildRounds) wait() <-chan time.Time {
+	if r.timer == nil {
+		return nil
+	}
+	return r.timer.C
+}
+
+// advance moves the schedule past the round which just fired.
+func (r *buildRounds) advance(now time.Time) {
+	r.timer = nil
+	r.next++
+	r.arm(now)
+}
+
+// stop cancels the remaining rounds.
+func (r *buildRounds) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	atomic.StoreInt32(&r.running, 0)
+}
+
+// active returns whether rounds are scheduled for the current block. It is safe
+// to call from any goroutine.
+func (r *buildRounds) active() bool {
+	return atomic.LoadInt32(&r.running) == 1
+}
+
+// saveRoundBase keeps a copy of the unfinalized current environment for the
+// next rounds to build upon, as finalizing mutates its state.
+func (w *worker) saveRoundBase() {
+	w.roundBase = w.current.copy()
+}
+
+// scheduleRounds arms the rebuild rounds of the block just built, if its engine
+// seals it against a deadline.
+func (w *worker) scheduleRounds(interrupt *int32) {
+	if !w.isRunning() || w.roundBase == nil {
+		w.rounds.stop()
+		return
+	}
+	delay := w.engine.Delay(w.chain, w.roundBase.header)
+	if delay == nil {
+		w.rounds.stop()
+		return
+	}
+	w.rounds.schedule(w.roundBase.header.Number.Uint64(), time.Now(), *delay-w.config.DelayLeftOver, interrupt)
+}
+
+// commitRound extends the latest block candidate with the transactions which
+// arrived since it was built, and submits it for sealing in place of the old
+// one if anything was added.
+func (w *worker) commitRound() {
+	defer w.rounds.advance(time.Now())
+
+	interrupt := w.rounds.interrupt
+	if !w.isRunning() || w.roundBase == nil || (interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone) {
+		return
+	}
+	start := time.Now()
+	pending, err := w.eth.TxPool().Pending()
+	w.building.Selection += time.Since(start)
+	if err != nil {
+		log.Error("Failed to fetch pending transactions", "err", err)
+		return
+	}
+	// Drop the transactions already included in the candidate
+	base := w.roundBase.copy()
+	for from, txs := range pending {
+		nonce := base.state.GetNonce(from)
+		for len(txs) > 0 && txs[0].Nonce() < nonce {
+			txs = txs[1:]
+		}
+		if len(txs) == 0 {
+			delete(pending, from)
+		} else {
+			pending[from] = txs
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	prev := w.current
+	w.current = base
+	if w.fillTransactions(pending, nil, interrupt) || w.current.tcount == w.roundBase.tcount {
+		w.current = prev
+		return
+	}
+	commitTxsTimer.UpdateSince(start)
+	buildRoundMeter.Mark(1)
+	log.Debug("Extended block candidate", "number", base.header.Number, "round", w.rounds.next+1,
+		"txs", base.tcount, "added", base.tcount-w.roundBase.tcount)
+
+	w.saveRoundBase()
+	w.commit(nil, w.fullTaskHook, false, start)
+}
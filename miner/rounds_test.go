@@ -0,0 +1,60 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+
+package miner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildRoundsSchedule(t *testing.T) {
+	rounds := newBuildRounds([]float64{0.95, 0.5, 1.2, 0, 0.8})
+	if want := []float64{0.5, 0.8, 0.95}; len(rounds.fractions) != len(want) {
+		t.Fatalf("fractions mismatch: have %v, want %v", rounds.fractions, want)
+	}
+	now := time.Now()
+	rounds.schedule(1, now, time.Second, nil)
+	if !rounds.active() || rounds.wait() == nil || rounds.next != 0 {
+		t.Fatalf("rounds not armed: active %v, next %d", rounds.active(), rounds.next)
+	}
+	// Rebuilding the same block keeps the schedule, a new block resets it
+	rounds.advance(now.Add(600 * time.Millisecond))
+	rounds.schedule(1, now.Add(700*time.Millisecond), time.Second, nil)
+	if rounds.next != 1 || rounds.start != now {
+		t.Fatalf("schedule reset on rebuild: next %d", rounds.next)
+	}
+	// Rounds already passed are skipped
+	rounds.advance(now.Add(900 * time.Millisecond))
+	if rounds.next != 2 || !rounds.active() {
+		t.Fatalf("round not advanced: next %d, active %v", rounds.next, rounds.active())
+	}
+	rounds.advance(now.Add(time.Second))
+	if rounds.active() || rounds.wait() != nil {
+		t.Fatalf("rounds still active past the deadline")
+	}
+	// A new block resets the schedule, no rounds without a deadline
+	rounds.schedule(2, now, time.Second, nil)
+	if rounds.number != 2 || rounds.next != 0 || !rounds.active() {
+		t.Fatalf("rounds not rescheduled for new block")
+	}
+	rounds.stop()
+	rounds.schedule(3, now, 0, nil)
+	if rounds.active() {
+		t.Fatalf("rounds scheduled without time left")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// buildStatsLimit is the number of recently sealed blocks to keep the building
+// statistics of.
+const buildStatsLimit = 128
+
+// BuildStats are the statistics of building a locally sealed block, to tune the
+// time budget of the block slots. Durations are in nanoseconds.
+type BuildStats struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Txs    int         `json:"txs"`
+	Rounds int         `json:"rounds"` // Number of candidates committed for sealing
+
+	Selection    time.Duration `json:"selection"`    // Time spent fetching and ordering the transactions
+	Processing   time.Duration `json:"processing"`   // Time spent executing the transactions
+	Finalization time.Duration `json:"finalization"` // Time spent finalizing and assembling the block
+	SealWait     time.Duration `json:"sealWait"`     // Time between the submission and the sealing of the block
+
+	DroppedGas     int `json:"droppedGas"`     // Transactions skipped for lack of gas in the block
+	DroppedTimeout int `json:"droppedTimeout"` // Accounts with transactions left when the build deadline hit
+}
+
+// buildStatsLog keeps the building statistics of the recently sealed blocks.
+type buildStatsLog struct {
+	stats map[uint64]BuildStats
+	lock  sync.RWMutex
+}
+
+func newBuildStatsLog() *buildStatsLog {
+	return &buildStatsLog{stats: make(map[uint64]BuildStats)}
+}
+
+// add records the statistics of a sealed block, replacing the ones of a block
+// previously sealed at the same height and dropping the oldest ones above the
+// limit.
+func (l *buildStatsLog) add(stats BuildStats) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.stats[stats.Number] = stats
+	for number := range l.stats {
+		if number+buildStatsLimit <= stats.Number {
+			delete(l.stats, number)
+		}
+	}
+}
+
+// list returns the recorded statistics, sorted by block number.
+func (l *buildStatsLog) list() []BuildStats {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	list := make([]BuildStats, 0, len(l.stats))
+	for _, stats := range l.stats {
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+	return list
+}
@@ -0,0 +1,46 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+
+package miner
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestBuildStatsLog(t *testing.T) {
+	stats := newBuildStatsLog()
+	for number := uint64(1); number <= buildStatsLimit+10; number++ {
+		stats.add(BuildStats{Number: number})
+	}
+	// Sealing a block again at the same height replaces its statistics
+	stats.add(BuildStats{Number: buildStatsLimit + 10, Hash: common.Hash{0x01}})
+
+	list := stats.list()
+	if len(list) != buildStatsLimit {
+		t.Fatalf("stats count mismatch: have %d, want %d", len(list), buildStatsLimit)
+	}
+	for i, s := range list {
+		if want := uint64(i + 11); s.Number != want {
+			t.Fatalf("stats %d: number mismatch: have %d, want %d", i, s.Number, want)
+		}
+	}
+	if last := list[len(list)-1]; last.Hash != (common.Hash{0x01}) {
+		t.Fatalf("stats not replaced: have %x", last.Hash)
+	}
+}
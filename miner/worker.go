@@ -19,6 +19,7 @@ package miner
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
 	"sync"
 	"sync/atomic"
@@ -94,10 +95,38 @@ type environment struct {
 	uncles    mapset.Set     // uncle set
 	tcount    int            // tx count in cycle
 	gasPool   *core.GasPool  // available gas used to pack transactions
+	blobGas   uint64         // blob gas used by the transactions in the block
 
 	header   *types.Header
 	txs      []*types.Transaction
 	receipts []*types.Receipt
+
+	initValue *big.Int // balance of the validator and the fee collector before any transaction
+}
+
+// copy creates a deep copy of the environment, to keep building a block on top
+// of it without touching the original.
+func (env *environment) copy() *environment {
+	cpy := &environment{
+		signer:    env.signer,
+		state:     env.state.Copy(),
+		ancestors: env.ancestors.Clone(),
+		family:    env.family.Clone(),
+		uncles:    env.uncles.Clone(),
+		tcount:    env.tcount,
+		blobGas:   env.blobGas,
+		header:    types.CopyHeader(env.header),
+		txs:       make([]*types.Transaction, len(env.txs)),
+		receipts:  make([]*types.Receipt, len(env.receipts)),
+		initValue: env.initValue,
+	}
+	if env.gasPool != nil {
+		gasPool := *env.gasPool
+		cpy.gasPool = &gasPool
+	}
+	copy(cpy.txs, env.txs)
+	copy(cpy.receipts, env.receipts)
+	return cpy
 }
 
 // task contains all information for consensus engine sealing and result submitting.
@@ -106,6 +135,7 @@ type task struct {
 	state     *state.StateDB
 	block     *types.Block
 	createdAt time.Time
+	stats     BuildStats
 }
 
 const (
@@ -156,11 +186,19 @@ type worker struct {
 	exitCh             chan struct{}
 	resubmitIntervalCh chan time.Duration
 	resubmitAdjustCh   chan *intervalAdjust
+	newBidCh           chan struct{}
 
 	current      *environment                 // An environment for current running cycle.
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bids         *bidPool                     // A set of block candidates submitted by external builders.
+	bundles      *bundlePool                  // A set of transaction bundles submitted by searchers.
+	ordering     OrderingPolicy               // The policy ordering the pending transactions in blocks.
+	rounds       *buildRounds                 // The schedule of the rounds rebuilding the block candidates.
+	roundBase    *environment                 // The unfinalized environment of the latest block candidate.
+	building     BuildStats                   // The statistics of building the current block.
+	buildStats   *buildStatsLog               // The statistics of building the recently sealed blocks.
 
 	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
 	coinbase common.Address
@@ -206,6 +244,8 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
+		bids:               newBidPool(),
+		bundles:            newBundlePool(),
 		pendingTasks:       make(map[common.Hash]*task),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
@@ -217,7 +257,17 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		startCh:            make(chan struct{}, 1),
 		resubmitIntervalCh: make(chan time.Duration),
 		resubmitAdjustCh:   make(chan *intervalAdjust, resubmitAdjustChanSize),
+		newBidCh:           make(chan struct{}, 1),
 	}
+	ordering, err := newOrderingPolicy(config)
+	if err != nil {
+		log.Warn("Falling back to the default transaction ordering", "err", err, "ordering", DefaultOrdering)
+		ordering, _ = newOrderingPolicy(&Config{Ordering: DefaultOrdering})
+	}
+	worker.ordering = ordering
+	worker.rounds = newBuildRounds(config.BuildRounds)
+	worker.buildStats = newBuildStatsLog()
+
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
@@ -307,6 +357,12 @@ func (w *worker) isRunning() bool {
 	return atomic.LoadInt32(&w.running) == 1
 }
 
+// nextBlockNumber returns the number of the block to be sealed on top of the
+// current head.
+func (w *worker) nextBlockNumber() *big.Int {
+	return new(big.Int).Add(w.chain.CurrentBlock().Number(), common.Big1)
+}
+
 // close terminates all background threads maintained by the worker.
 // Note the worker does not support being closed multiple times.
 func (w *worker) close() {
@@ -383,6 +439,13 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 			timestamp = time.Now().Unix()
 			commit(true, commitInterruptNewHead)
 
+		case <-w.newBidCh:
+			// A more valuable bid arrived, rebuild the block to weigh it against
+			// the local transactions.
+			if w.isRunning() {
+				commit(true, commitInterruptResubmit)
+			}
+
 		case head := <-w.chainHeadCh:
 			if !w.isRunning() {
 				continue
@@ -406,9 +469,10 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 			// If mining is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
 			if w.isRunning() && ((w.chainConfig.Ethash != nil) || (w.chainConfig.Clique != nil &&
-				w.chainConfig.Clique.Period > 0) || (w.chainConfig.Parlia != nil && w.chainConfig.Parlia.Period > 0)) {
-				// Short circuit if no new transaction arrives.
-				if atomic.LoadInt32(&w.newTxs) == 0 {
+				w.chainConfig.Clique.Period > 0) || (w.chainConfig.Parlia != nil && w.chainConfig.Parlia.PeriodMs(w.nextBlockNumber()) > 0)) {
+				// Short circuit if no new transaction arrives, or if the build
+				// rounds already pull them into the block.
+				if atomic.LoadInt32(&w.newTxs) == 0 || w.rounds.active() {
 					timer.Reset(recommit)
 					continue
 				}
@@ -461,6 +525,10 @@ func (w *worker) mainLoop() {
 		select {
 		case req := <-w.newWorkCh:
 			w.commitNewWork(req.interrupt, req.noempty, req.timestamp)
+			w.scheduleRounds(req.interrupt)
+
+		case <-w.rounds.wait():
+			w.commitRound()
 
 		case ev := <-w.chainSideCh:
 			// Short circuit for duplicate side blocks
@@ -509,7 +577,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+				txset := w.ordering.Order(w.current.header, w.current.signer, txs)
 				tcount := w.current.tcount
 				w.commitTransactions(txset, coinbase, nil)
 				// Only update the snapshot if any new transactons were added
@@ -522,7 +590,7 @@ func (w *worker) mainLoop() {
 				// submit mining work here since all empty submission will be rejected
 				// by clique. Of course the advance sealing(empty submission) is disabled.
 				if (w.chainConfig.Clique != nil && w.chainConfig.Clique.Period == 0) ||
-					(w.chainConfig.Parlia != nil && w.chainConfig.Parlia.Period == 0) {
+					(w.chainConfig.Parlia != nil && w.chainConfig.Parlia.PeriodMs(w.nextBlockNumber()) == 0) {
 					w.commitNewWork(nil, true, time.Now().Unix())
 				}
 			}
@@ -643,6 +711,10 @@ func (w *worker) resultLoop() {
 			log.Info("Successfully sealed new block", "number", block.Number(), "sealhash", sealhash, "hash", hash,
 				"elapsed", common.PrettyDuration(time.Since(task.createdAt)))
 
+			stats := task.stats
+			stats.Hash, stats.SealWait = hash, time.Since(task.createdAt)
+			w.buildStats.add(stats)
+
 			// Broadcast the block and announce chain insertion event
 			w.mux.Post(core.NewMinedBlockEvent{Block: block})
 
@@ -657,14 +729,29 @@ func (w *worker) resultLoop() {
 
 // makeCurrent creates a new environment for the current cycle.
 func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
-	// Retrieve the parent state to execute on top and start a prefetcher for
-	// the miner to speed block sealing up a bit
-	state, err := w.chain.StateAt(parent.Root())
+	env, err := w.makeEnv(parent, header)
 	if err != nil {
 		return err
 	}
-	state.StartPrefetcher("miner")
+	// Start a prefetcher for the miner to speed block sealing up a bit
+	env.state.StartPrefetcher("miner")
+
+	// Swap out the old work with the new one, terminating any leftover prefetcher
+	// processes in the mean time and starting a new one.
+	if w.current != nil && w.current.state != nil {
+		w.current.state.StopPrefetcher()
+	}
+	w.current = env
+	return nil
+}
 
+// makeEnv creates a new environment for building a block on top of the parent.
+func (w *worker) makeEnv(parent *types.Block, header *types.Header) (*environment, error) {
+	// Retrieve the parent state to execute on top
+	state, err := w.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
 	env := &environment{
 		signer:    types.MakeSigner(w.chainConfig, header.Number),
 		state:     state,
@@ -675,14 +762,18 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 	}
 	// Keep track of transactions which return errors so they can be removed
 	env.tcount = 0
+	env.initValue = env.value()
+	return env, nil
+}
 
-	// Swap out the old work with the new one, terminating any leftover prefetcher
-	// processes in the mean time and starting a new one.
-	if w.current != nil && w.current.state != nil {
-		w.current.state.StopPrefetcher()
-	}
-	w.current = env
-	return nil
+// value returns the balance of the validator and the fee collector.
+func (env *environment) value() *big.Int {
+	return new(big.Int).Add(env.state.GetBalance(env.header.Coinbase), env.state.GetBalance(consensus.SystemAddress))
+}
+
+// profit returns the value earned by the validator from the transactions.
+func (env *environment) profit() *big.Int {
+	return new(big.Int).Sub(env.value(), env.initValue)
 }
 
 // commitUncle adds the given block to uncle block set, returns error if failed to add.
@@ -751,7 +842,7 @@ func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Addres
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(txs *types.TransactionsByPriceAndNonce, coinbase common.Address, interrupt *int32) bool {
+func (w *worker) commitTransactions(txs TxIterator, coinbase common.Address, interrupt *int32) bool {
 	// Short circuit if current is nil
 	if w.current == nil {
 		return true
@@ -803,12 +894,14 @@ LOOP:
 		// If we don't have enough gas for any further transactions then we're done
 		if w.current.gasPool.Gas() < params.TxGas {
 			log.Trace("Not enough gas for further transactions", "have", w.current.gasPool, "want", params.TxGas)
+			w.building.DroppedGas += txs.CurrentSize()
 			break
 		}
 		if stopTimer != nil {
 			select {
 			case <-stopTimer.C:
 				log.Info("Not enough time for further transactions", "txs", len(w.current.txs))
+				w.building.DroppedTimeout += txs.CurrentSize()
 				break LOOP
 			default:
 			}
@@ -830,14 +923,24 @@ LOOP:
 			txs.Pop()
 			continue
 		}
+		// Skip the blob transactions not fitting into the blob gas left
+		if blobGas := tx.BlobGas(); blobGas > 0 && w.current.blobGas+blobGas > params.MaxBlobGasPerBlock {
+			log.Trace("Blob gas limit exceeded for current block", "hash", tx.Hash(), "blobgas", blobGas)
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		w.current.state.Prepare(tx.Hash(), common.Hash{}, w.current.tcount)
 
+		start := time.Now()
 		logs, err := w.commitTransaction(tx, coinbase, bloomProcessors)
+		w.building.Processing += time.Since(start)
+
 		switch {
 		case errors.Is(err, core.ErrGasLimitReached):
 			// Pop the current out-of-gas transaction without shifting in the next from the account
 			//log.Trace("Gas limit exceeded for current block", "sender", from)
+			w.building.DroppedGas++
 			txs.Pop()
 
 		case errors.Is(err, core.ErrNonceTooLow):
@@ -850,10 +953,17 @@ LOOP:
 			//log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
 			txs.Pop()
 
+		case errors.Is(err, core.ErrFeeCapTooLow):
+			// Pop the transaction paying less than the base fee, the next ones from the
+			// account can't be included without it
+			//log.Trace("Skipping account paying less than the base fee", "sender", from, "price", tx.GasPrice())
+			txs.Pop()
+
 		case errors.Is(err, nil):
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			w.current.tcount++
+			w.current.blobGas += tx.BlobGas()
 			txs.Shift()
 
 		case errors.Is(err, core.ErrTxTypeNotSupported):
@@ -900,6 +1010,8 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 
 	tstart := time.Now()
 	parent := w.chain.CurrentBlock()
+	w.roundBase = nil
+	w.building = BuildStats{Number: parent.NumberU64() + 1}
 
 	if parent.Time() >= uint64(timestamp) {
 		timestamp = int64(parent.Time() + 1)
@@ -912,6 +1024,10 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 		Extra:      w.extra,
 		Time:       uint64(timestamp),
 	}
+	// Set the base fee once the fork activated it
+	if w.chainConfig.IsBaseFee(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header())
+	}
 	// Only set the coinbase if our consensus engine is running (avoid spurious block rewards)
 	if w.isRunning() {
 		if w.coinbase == (common.Address{}) {
@@ -955,41 +1071,312 @@ func (w *worker) commitNewWork(interrupt *int32, noempty bool, timestamp int64)
 	if !noempty && atomic.LoadUint32(&w.noempty) == 0 {
 		w.commit(uncles, nil, false, tstart)
 	}
+	// Include the profitable bundles of the searchers at the top of the block,
+	// unless the ordering policy merges them with the pool transactions.
+	var bundles []*simulatedBundle
+	if merger, ok := w.ordering.(BundleMerger); ok && merger.MergeBundles() {
+		bundles = w.simulateBundles(env)
+	} else {
+		w.commitBundles(env)
+	}
 
 	// Fill the block with all available pending transactions.
+	selectStart := time.Now()
 	pending, err := w.eth.TxPool().Pending()
+	w.building.Selection += time.Since(selectStart)
 	if err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
 	}
 	// Short circuit if there is no available pending transactions
-	if len(pending) != 0 {
+	if len(pending) != 0 || len(bundles) != 0 {
 		start := time.Now()
-		// Split the pending transactions into locals and remotes
-		localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-		for _, account := range w.eth.TxPool().Locals() {
-			if txs := remoteTxs[account]; len(txs) > 0 {
-				delete(remoteTxs, account)
-				localTxs[account] = txs
-			}
-		}
-		if len(localTxs) > 0 {
-			txs := types.NewTransactionsByPriceAndNonce(w.current.signer, localTxs)
-			if w.commitTransactions(txs, w.coinbase, interrupt) {
-				return
-			}
-		}
-		if len(remoteTxs) > 0 {
-			txs := types.NewTransactionsByPriceAndNonce(w.current.signer, remoteTxs)
-			if w.commitTransactions(txs, w.coinbase, interrupt) {
-				return
-			}
+		if w.fillTransactions(pending, bundles, interrupt) {
+			return
 		}
 		commitTxsTimer.UpdateSince(start)
 		log.Info("Gas pool", "height", header.Number.String(), "pool", w.current.gasPool.String())
 	}
+	// Replace the local block by the most valuable bid of the external builders
+	// if it pays more, falling back to local building on any failure.
+	local := w.current
+	if w.isRunning() {
+		w.commitBestBid(parent, header)
+	}
+	// Only locally built blocks are extended by the later rounds
+	if w.current == local {
+		w.saveRoundBase()
+	}
 	w.commit(uncles, w.fullTaskHook, false, tstart)
 }
 
+// fillTransactions fills the current block with the pending transactions, the
+// local ones first, merging the simulated bundles in with the remote ones. It
+// returns whether the filling was interrupted by a new head.
+func (w *worker) fillTransactions(pending map[common.Address]types.Transactions, bundles []*simulatedBundle, interrupt *int32) bool {
+	// Split the pending transactions into locals and remotes
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
+	for _, account := range w.eth.TxPool().Locals() {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	if len(localTxs) > 0 {
+		start := time.Now()
+		txs := w.ordering.Order(w.current.header, w.current.signer, localTxs)
+		w.building.Selection += time.Since(start)
+		if w.commitTransactions(txs, w.coinbase, interrupt) {
+			return true
+		}
+	}
+	if len(remoteTxs) > 0 || len(bundles) > 0 {
+		start := time.Now()
+		txs := w.ordering.Order(w.current.header, w.current.signer, remoteTxs)
+		w.building.Selection += time.Since(start)
+		if w.commitMergedTransactions(txs, bundles, interrupt) {
+			return true
+		}
+	}
+	return false
+}
+
+// commitBestBid simulates the most valuable bid on top of the parent and swaps
+// it in as the current environment if it pays more than the local block.
+func (w *worker) commitBestBid(parent *types.Block, header *types.Header) {
+	bid := w.bids.bestBid(parent.Hash())
+	if bid == nil {
+		return
+	}
+	local := w.current.profit()
+	if bid.GasFee.Cmp(local) <= 0 {
+		log.Debug("Local block more valuable than bids", "number", header.Number, "local", local, "bid", bid.GasFee)
+		return
+	}
+	env, err := w.simulateBid(parent, header, bid)
+	if err != nil {
+		bidInvalidMeter.Mark(1)
+		w.bids.remove(bid)
+		log.Warn("Rejected invalid bid", "number", header.Number, "builder", bid.Builder, "hash", bid.Hash, "err", err)
+		return
+	}
+	bidWonMeter.Mark(1)
+	log.Info("Using builder bid", "number", header.Number, "builder", bid.Builder, "hash", bid.Hash,
+		"txs", len(bid.Txs), "value", bid.GasFee, "local", local)
+
+	if w.current.state != nil {
+		w.current.state.StopPrefetcher()
+	}
+	w.current = env
+}
+
+// simulateBid executes the transactions of a bid on top of the parent, verifying
+// that it fits the block and pays the validator the promised value.
+func (w *worker) simulateBid(parent *types.Block, header *types.Header, bid *Bid) (*environment, error) {
+	header = types.CopyHeader(header)
+	header.GasUsed = 0
+
+	env, err := w.makeEnv(parent, header)
+	if err != nil {
+		return nil, err
+	}
+	if w.chainConfig.DAOForkSupport && w.chainConfig.DAOForkBlock != nil && w.chainConfig.DAOForkBlock.Cmp(header.Number) == 0 {
+		misc.ApplyDAOHardFork(env.state)
+	}
+	env.gasPool = new(core.GasPool).AddGas(header.GasLimit)
+	env.gasPool.SubGas(params.SystemTxsGas)
+
+	bloomProcessor := core.NewReceiptBloomGenerator()
+	for i, tx := range bid.Txs {
+		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &header.Coinbase, env.gasPool, env.state, header, tx, &header.GasUsed, *w.chain.GetVMConfig(), bloomProcessor)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x) failed: %v", i, tx.Hash(), err)
+		}
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+		env.blobGas += tx.BlobGas()
+	}
+	if env.blobGas > params.MaxBlobGasPerBlock {
+		return nil, fmt.Errorf("%w: have %d, max %d", core.ErrBlobGasLimitReached, env.blobGas, params.MaxBlobGasPerBlock)
+	}
+	if header.GasUsed != bid.GasUsed {
+		return nil, fmt.Errorf("gas used mismatch: have %d, want %d", header.GasUsed, bid.GasUsed)
+	}
+	if profit := env.profit(); profit.Cmp(bid.GasFee) < 0 {
+		return nil, fmt.Errorf("%w: have %v, want %v", errBidUnderpaid, profit, bid.GasFee)
+	}
+	return env, nil
+}
+
+// submitBid validates a bid of an external builder and queues it for the next
+// block, triggering a rebuild if it is the most valuable one.
+func (w *worker) submitBid(args *BidArgs) (common.Hash, error) {
+	bidReceivedMeter.Mark(1)
+
+	w.mu.RLock()
+	validator := w.coinbase
+	w.mu.RUnlock()
+
+	bid, err := newBid(args, w.chainConfig.ChainID, validator, w.config.Builders)
+	if err != nil {
+		bidInvalidMeter.Mark(1)
+		return common.Hash{}, err
+	}
+	head := w.chain.CurrentBlock().Header()
+	if bid.GasUsed > head.GasLimit {
+		bidInvalidMeter.Mark(1)
+		return common.Hash{}, fmt.Errorf("gas used %d above gas limit %d", bid.GasUsed, head.GasLimit)
+	}
+	best, err := w.bids.add(bid, head)
+	if err != nil {
+		bidInvalidMeter.Mark(1)
+		return common.Hash{}, err
+	}
+	log.Debug("Received builder bid", "number", bid.BlockNumber, "builder", bid.Builder, "hash", bid.Hash, "value", bid.GasFee, "best", best)
+	if best {
+		select {
+		case w.newBidCh <- struct{}{}:
+		default:
+		}
+	}
+	return bid.Hash, nil
+}
+
+// commitBundles includes the profitable bundles targeting the block of the
+// environment at its top, most valuable per gas first. Bundles failing on top
+// of the parent are dropped, the ones conflicting with a more valuable bundle
+// are only skipped.
+func (w *worker) commitBundles(env *environment) {
+	for _, sim := range w.simulateBundles(env) {
+		// Re-execute the bundle as the ones before may have changed its outcome
+		if _, err := w.applyBundle(env, sim.bundle, true); err != nil {
+			log.Debug("Skipped conflicting bundle", "number", env.header.Number, "hash", sim.bundle.Hash, "err", err)
+			continue
+		}
+		bundleIncludedMeter.Mark(1)
+		log.Debug("Included bundle", "number", env.header.Number, "hash", sim.bundle.Hash, "txs", len(sim.bundle.Txs), "value", sim.profit)
+	}
+}
+
+// simulateBundles executes the pending bundles on top of the environment,
+// dropping the invalid ones, and returns the others by decreasing price.
+func (w *worker) simulateBundles(env *environment) []*simulatedBundle {
+	bundles := w.bundles.pending(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return nil
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+		env.gasPool.SubGas(params.SystemTxsGas)
+	}
+	simulated := make([]*simulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		sim, err := w.applyBundle(env, bundle, false)
+		if err != nil {
+			bundleInvalidMeter.Mark(1)
+			w.bundles.remove(bundle)
+			log.Debug("Rejected invalid bundle", "number", env.header.Number, "hash", bundle.Hash, "err", err)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	sortBundles(simulated)
+	return simulated
+}
+
+// commitMergedTransactions fills the block with the transactions and the
+// simulated bundles by decreasing price, each bundle being included once the
+// transactions left pay less per gas than it did in the simulation.
+func (w *worker) commitMergedTransactions(txs TxIterator, bundles []*simulatedBundle, interrupt *int32) bool {
+	for _, sim := range bundles {
+		above := &txsAbove{TxIterator: txs, price: sim.price(), baseFee: w.current.header.BaseFee}
+		if above.Peek() != nil && w.commitTransactions(above, w.coinbase, interrupt) {
+			return true
+		}
+		// Re-execute the bundle as the transactions before may have changed its outcome
+		if _, err := w.applyBundle(w.current, sim.bundle, true); err != nil {
+			log.Debug("Skipped conflicting bundle", "number", w.current.header.Number, "hash", sim.bundle.Hash, "err", err)
+			continue
+		}
+		bundleIncludedMeter.Mark(1)
+		log.Debug("Included bundle", "number", w.current.header.Number, "hash", sim.bundle.Hash, "txs", len(sim.bundle.Txs), "value", sim.profit)
+	}
+	if txs.Peek() == nil {
+		return false
+	}
+	return w.commitTransactions(txs, w.coinbase, interrupt)
+}
+
+// applyBundle executes the transactions of a bundle atomically on a copy of the
+// environment's state, failing if any of them is invalid or reverts, or if the
+// bundle pays the validator less than the minimum gas price. If commit is set,
+// the executed bundle is added to the environment.
+func (w *worker) applyBundle(env *environment, bundle *Bundle, commit bool) (*simulatedBundle, error) {
+	var (
+		statedb        = env.state.Copy()
+		gasPool        = *env.gasPool
+		gasUsed        = env.header.GasUsed
+		blobGas        = env.blobGas
+		receipts       = make([]*types.Receipt, 0, len(bundle.Txs))
+		bloomProcessor = core.NewReceiptBloomGenerator()
+	)
+	for i, tx := range bundle.Txs {
+		if blobGas += tx.BlobGas(); blobGas > params.MaxBlobGasPerBlock {
+			return nil, errBundleBlobGasCap
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, env.tcount+i)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.header.Coinbase, &gasPool, statedb, env.header, tx, &gasUsed, *w.chain.GetVMConfig(), bloomProcessor)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x) failed: %v", i, tx.Hash(), err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			return nil, fmt.Errorf("%w: %x", errBundleReverted, tx.Hash())
+		}
+		receipts = append(receipts, receipt)
+	}
+	value := new(big.Int).Add(statedb.GetBalance(env.header.Coinbase), statedb.GetBalance(consensus.SystemAddress))
+	sim := &simulatedBundle{
+		bundle:  bundle,
+		gasUsed: gasUsed - env.header.GasUsed,
+		profit:  value.Sub(value, env.value()),
+	}
+	if sim.profit.Sign() <= 0 || (w.config.GasPrice != nil && sim.price().Cmp(w.config.GasPrice) < 0) {
+		return nil, fmt.Errorf("%w: paid %v for %d gas", errBundleUnderpaid, sim.profit, sim.gasUsed)
+	}
+	if commit {
+		env.state.StopPrefetcher()
+		env.state = statedb
+		*env.gasPool = gasPool
+		env.header.GasUsed = gasUsed
+		env.blobGas = blobGas
+		env.txs = append(env.txs, bundle.Txs...)
+		env.receipts = append(env.receipts, receipts...)
+		env.tcount += len(bundle.Txs)
+	}
+	return sim, nil
+}
+
+// submitBundle validates a bundle of a searcher and queues it for its target
+// block.
+func (w *worker) submitBundle(args *SendBundleArgs) (common.Hash, error) {
+	bundleReceivedMeter.Mark(1)
+
+	bundle, err := newBundle(args)
+	if err != nil {
+		bundleInvalidMeter.Mark(1)
+		return common.Hash{}, err
+	}
+	if err := w.bundles.add(bundle, w.chain.CurrentBlock().Header()); err != nil {
+		bundleInvalidMeter.Mark(1)
+		return common.Hash{}, err
+	}
+	log.Debug("Received bundle", "number", bundle.BlockNumber, "hash", bundle.Hash, "txs", len(bundle.Txs))
+	return bundle.Hash, nil
+}
+
 // commit runs any post-transaction state modifications, assembles the final block
 // and commits new work if consensus engine is running.
 func (w *worker) commit(uncles []*types.Header, interval func(), update bool, start time.Time) error {
@@ -998,16 +1385,21 @@ func (w *worker) commit(uncles []*types.Header, interval func(), update bool, st
 	if err != nil {
 		return err
 	}
+	finalizeStart := time.Now()
 	block, receipts, err := w.engine.FinalizeAndAssemble(w.chain, types.CopyHeader(w.current.header), s, w.current.txs, uncles, w.current.receipts)
 	if err != nil {
 		return err
 	}
+	w.building.Finalization += time.Since(finalizeStart)
+	w.building.Rounds++
+	w.building.Txs = len(block.Transactions())
+
 	if w.isRunning() {
 		if interval != nil {
 			interval()
 		}
 		select {
-		case w.taskCh <- &task{receipts: receipts, state: s, block: block, createdAt: time.Now()}:
+		case w.taskCh <- &task{receipts: receipts, state: s, block: block, createdAt: time.Now(), stats: w.building}:
 			w.unconfirmed.Shift(block.NumberU64() - 1)
 			log.Info("Commit new mining work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
 				"uncles", len(uncles), "txs", w.current.tcount,
@@ -254,6 +254,13 @@ func testGenerateBlockAndImport(t *testing.T, isClique bool) {
 			if _, err := chain.InsertChain([]*types.Block{block}); err != nil {
 				t.Fatalf("failed to insert new mined block %d: %v", block.NumberU64(), err)
 			}
+			var found bool
+			for _, stats := range w.buildStats.list() {
+				found = found || stats.Number == block.NumberU64()
+			}
+			if !found {
+				t.Fatalf("missing build stats of mined block %d", block.NumberU64())
+			}
 		case <-time.After(3 * time.Second): // Worker needs 1s to include new changes.
 			t.Fatalf("timeout")
 		}
@@ -126,6 +126,41 @@ func (api *privateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// StartCapture starts recording the eth and snap messages exchanged with the
+// given peer to the capture directory, for replaying them.
+func (api *privateAdminAPI) StartCapture(id string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	nodeID, err := enode.ParseID(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid node ID: %v", err)
+	}
+	if err := server.StartCapture(nodeID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StopCapture stops recording the messages exchanged with the given peer.
+func (api *privateAdminAPI) StopCapture(id string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	nodeID, err := enode.ParseID(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid node ID: %v", err)
+	}
+	if err := server.StopCapture(nodeID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *privateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/external"
@@ -33,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -70,6 +72,22 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the engine of new key-value databases, "leveldb" or "pebble".
+	// Existing databases are always opened with the engine they were created with.
+	DBEngine string `toml:",omitempty"`
+
+	// DBPebble contains the tuning options of pebble databases.
+	DBPebble pebble.Config `toml:",omitempty"`
+
+	// DataDirReadOnly opens the data directory of another running instance for
+	// reading. The directory is not locked and its databases are opened as
+	// secondaries, which never modify the files of the owning instance.
+	DataDirReadOnly bool `toml:",omitempty"`
+
+	// DataDirRefresh is the interval at which the databases of a read-only data
+	// directory catch up with the writes of the owning instance.
+	DataDirRefresh time.Duration `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -180,6 +198,19 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSMaxSubscriptions is the maximum number of subscriptions a single websocket
+	// connection may hold. Zero means unlimited.
+	WSMaxSubscriptions int `toml:",omitempty"`
+
+	// WSMaxPendingNotifications is the number of notifications queued for a websocket
+	// connection before the client is considered stalled and disconnected. Zero means
+	// notifications are written synchronously without a queue.
+	WSMaxPendingNotifications int `toml:",omitempty"`
+
+	// WSMaxBytesPerSecond throttles the notification throughput of a single websocket
+	// connection. Zero means unlimited.
+	WSMaxBytesPerSecond int `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -194,6 +225,13 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GRPCHost is the host interface on which to start the gRPC server. If this
+	// field is empty, no gRPC server will be started.
+	GRPCHost string `toml:",omitempty"`
+
+	// GRPCPort is the TCP port number on which to start the gRPC server.
+	GRPCPort int `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -205,6 +243,35 @@ type Config struct {
 
 	// AllowUnprotectedTxs allows non EIP-155 protected transactions to be send over RPC.
 	AllowUnprotectedTxs bool `toml:",omitempty"`
+
+	// BatchRequestLimit is the maximum number of requests in a batch served over
+	// HTTP or websocket. Zero means unlimited.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of result bytes of a batch served
+	// over HTTP or websocket. Zero means unlimited.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// BatchParallelism is the number of requests of a batch executed concurrently.
+	BatchParallelism int `toml:",omitempty"`
+
+	// RPCMetering configures execution deadlines and per-client compute unit budgets
+	// for calls served over HTTP or websocket. Calls are not metered if it is nil.
+	RPCMetering *rpc.MeteringConfig `toml:",omitempty"`
+
+	// RPCAuthFile is the path of a TOML file listing the API keys and JWT secrets
+	// accepted by the HTTP and websocket servers, together with the namespaces and
+	// call rate granted to each. Requests are not authenticated if it is empty. The
+	// file is reloaded when it changes.
+	RPCAuthFile string `toml:",omitempty"`
+
+	// RPCTracingEndpoint is the OTLP/HTTP endpoint of an OpenTelemetry collector
+	// receiving the calls served over HTTP or websocket as spans. Calls are not
+	// traced if it is empty.
+	RPCTracingEndpoint string `toml:",omitempty"`
+
+	// RPCTracingThreshold is the minimum duration of a call for it to be traced.
+	RPCTracingThreshold time.Duration `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -234,8 +301,8 @@ func (c *Config) IPCEndpoint() string {
 
 // NodeDB returns the path to the discovery node database.
 func (c *Config) NodeDB() string {
-	if c.DataDir == "" {
-		return "" // ephemeral
+	if c.DataDir == "" || c.DataDirReadOnly {
+		return "" // ephemeral, or owned by another instance
 	}
 	return c.ResolvePath(datadirNodeDatabase)
 }
@@ -282,6 +349,15 @@ func DefaultWSEndpoint() string {
 	return config.WSEndpoint()
 }
 
+// GRPCEndpoint resolves the gRPC endpoint based on the configured host interface
+// and port parameters.
+func (c *Config) GRPCEndpoint() string {
+	if c.GRPCHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.GRPCHost, c.GRPCPort)
+}
+
 // ExtRPCEnabled returns the indicator whether node enables the external
 // RPC(http, ws or graphql).
 func (c *Config) ExtRPCEnabled() bool {
@@ -366,8 +442,9 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	if c.P2P.PrivateKey != nil {
 		return c.P2P.PrivateKey
 	}
-	// Generate ephemeral key if no datadir is being used.
-	if c.DataDir == "" {
+	// Generate ephemeral key if no datadir is being used, or if it belongs to
+	// another instance.
+	if c.DataDir == "" || c.DataDirReadOnly {
 		key, err := crypto.GenerateKey()
 		if err != nil {
 			log.Crit(fmt.Sprintf("Failed to generate ephemeral node key: %v", err))
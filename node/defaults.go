@@ -34,6 +34,8 @@ const (
 	DefaultWSPort      = 8546        // Default TCP port for the websocket RPC server
 	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
 	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
+	DefaultGRPCHost    = "localhost" // Default host interface for the gRPC server
+	DefaultGRPCPort    = 8548        // Default TCP port for the gRPC server
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -46,6 +48,7 @@ var DefaultConfig = Config{
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
 	GraphQLVirtualHosts: []string{"localhost"},
+	GRPCPort:            DefaultGRPCPort,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -53,6 +56,29 @@ var DefaultConfig = Config{
 	},
 }
 
+// DefaultRPCMethodCosts are the compute unit costs of expensive RPC methods. Methods
+// which are not listed cost a single unit.
+var DefaultRPCMethodCosts = map[string]int{
+	"eth_call":                      10,
+	"eth_estimateGas":               10,
+	"eth_createAccessList":          10,
+	"eth_getLogs":                   50,
+	"eth_getBlockReceipts":          20,
+	"eth_subscribe":                 10,
+	"debug_traceTransaction":        200,
+	"debug_traceCall":               200,
+	"debug_traceBlockByNumber":      500,
+	"debug_traceBlockByHash":        500,
+	"trace_block":                   50,
+	"trace_transaction":             20,
+	"trace_filter":                  200,
+	"trace_replayTransaction":       200,
+	"trace_replayBlockTransactions": 500,
+	"ots_traceTransaction":          200,
+	"ots_searchTransactionsBefore":  100,
+	"ots_searchTransactionsAfter":   100,
+}
+
 // DefaultDataDir is the default data directory to use for the databases and other
 // persistence requirements.
 func DefaultDataDir() string {
@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/otlp"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -60,6 +61,8 @@ type Node struct {
 	ipc           *ipcServer  // Stores information about the ipc http server
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
+	tracer *otlp.Exporter // Span exporter of the traced RPC calls, nil if disabled
+
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
 
@@ -324,6 +327,13 @@ func (n *Node) openDataDir() error {
 	}
 
 	instdir := filepath.Join(n.config.DataDir, n.config.name())
+	if n.config.DataDirReadOnly {
+		// The instance directory is locked by the instance owning it
+		if _, err := os.Stat(instdir); err != nil {
+			return fmt.Errorf("read-only data directory: %v", err)
+		}
+		return nil
+	}
 	if err := os.MkdirAll(instdir, 0700); err != nil {
 		return err
 	}
@@ -362,6 +372,23 @@ func (n *Node) startRPC() error {
 		}
 	}
 
+	// Load the credentials of the HTTP and websocket clients.
+	var auth *rpcAuth
+	if n.config.RPCAuthFile != "" && (n.config.HTTPHost != "" || n.config.WSHost != "") {
+		var err error
+		if auth, err = newRPCAuth(n.config.RPCAuthFile, n.log); err != nil {
+			return err
+		}
+	}
+
+	// Start exporting the traced calls of the HTTP and websocket servers.
+	var tracing *rpc.TracingConfig
+	if n.config.RPCTracingEndpoint != "" && (n.config.HTTPHost != "" || n.config.WSHost != "") {
+		n.tracer = otlp.NewExporter(n.config.RPCTracingEndpoint, "rpc")
+		tracing = &rpc.TracingConfig{Exporter: n.tracer, Threshold: n.config.RPCTracingThreshold}
+		n.log.Info("Tracing RPC calls", "endpoint", n.config.RPCTracingEndpoint, "threshold", n.config.RPCTracingThreshold)
+	}
+
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
 		config := httpConfig{
@@ -369,6 +396,10 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			batch:              n.batchLimits(),
+			metering:           n.config.RPCMetering,
+			tracing:            tracing,
+			auth:               auth,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -385,6 +416,15 @@ func (n *Node) startRPC() error {
 			Modules: n.config.WSModules,
 			Origins: n.config.WSOrigins,
 			prefix:  n.config.WSPathPrefix,
+			limits: rpc.ConnLimits{
+				MaxSubscriptions:        n.config.WSMaxSubscriptions,
+				MaxPendingNotifications: n.config.WSMaxPendingNotifications,
+				MaxBytesPerSecond:       n.config.WSMaxBytesPerSecond,
+			},
+			batch:    n.batchLimits(),
+			metering: n.config.RPCMetering,
+			tracing:  tracing,
+			auth:     auth,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -400,6 +440,15 @@ func (n *Node) startRPC() error {
 	return n.ws.start()
 }
 
+// batchLimits returns the batch execution limits of the HTTP and websocket servers.
+func (n *Node) batchLimits() rpc.BatchLimits {
+	return rpc.BatchLimits{
+		MaxItems:        n.config.BatchRequestLimit,
+		MaxResponseSize: n.config.BatchResponseMaxSize,
+		Parallelism:     n.config.BatchParallelism,
+	}
+}
+
 func (n *Node) wsServerForPort(port int) *httpServer {
 	if n.config.HTTPHost == "" || n.http.port == port {
 		return n.http
@@ -412,6 +461,10 @@ func (n *Node) stopRPC() {
 	n.ws.stop()
 	n.ipc.stop()
 	n.stopInProc()
+	if n.tracer != nil {
+		n.tracer.Close()
+		n.tracer = nil
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -502,6 +555,25 @@ func (n *Node) RPCHandler() (*rpc.Server, error) {
 	return n.inprocHandler, nil
 }
 
+// SetRPCMetering replaces the call metering settings of the HTTP and WebSocket
+// RPC endpoints, which must have been started with metering enabled.
+func (n *Node) SetRPCMetering(cfg rpc.MeteringConfig) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.state == closedState {
+		return ErrNodeStopped
+	}
+	updated := n.http.updateMetering(cfg)
+	if n.ws.updateMetering(cfg) {
+		updated = true
+	}
+	if !updated {
+		return ErrNoRPCMetering
+	}
+	return nil
+}
+
 // Config returns the configuration of node.
 func (n *Node) Config() *Config {
 	return n.config
@@ -573,7 +645,17 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.NewLevelDBDatabase(n.ResolvePath(name), cache, handles, namespace, readonly)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:      n.config.DBEngine,
+			Directory: n.ResolvePath(name),
+			Namespace: namespace,
+			Cache:     cache,
+			Handles:   handles,
+			ReadOnly:  readonly,
+			Pebble:    n.config.DBPebble,
+			Secondary: n.config.DataDirReadOnly,
+			Refresh:   n.config.DataDirRefresh,
+		})
 	}
 
 	if err == nil {
@@ -619,14 +701,20 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer,
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		root := n.ResolvePath(name)
-		switch {
-		case freezer == "":
-			freezer = filepath.Join(root, "ancient")
-		case !filepath.IsAbs(freezer):
-			freezer = n.ResolvePath(freezer)
-		}
-		db, err = rawdb.NewLevelDBDatabaseWithFreezer(root, cache, handles, freezer, namespace, readonly, disableFreeze, isLastOffset)
+		db, err = rawdb.Open(rawdb.OpenOptions{
+			Type:              n.config.DBEngine,
+			Directory:         n.ResolvePath(name),
+			AncientsDirectory: n.ResolveAncient(name, freezer),
+			Namespace:         namespace,
+			Cache:             cache,
+			Handles:           handles,
+			ReadOnly:          readonly,
+			DisableFreeze:     disableFreeze,
+			IsLastOffset:      isLastOffset,
+			Pebble:            n.config.DBPebble,
+			Secondary:         n.config.DataDirReadOnly,
+			Refresh:           n.config.DataDirRefresh,
+		})
 	}
 
 	if err == nil {
@@ -664,6 +752,21 @@ func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
 }
 
+// ResolveAncient returns the location of the freezer of the named database. An
+// empty freezer location means the default one within the database directory.
+func (n *Node) ResolveAncient(name, freezer string) string {
+	root := n.ResolvePath(name)
+	switch {
+	case freezer == "":
+		return filepath.Join(root, "ancient")
+	case rawdb.IsRemoteAncient(freezer):
+		return rawdb.WithLocalAncientDir(freezer, filepath.Join(root, "ancient"))
+	case !filepath.IsAbs(freezer):
+		return n.ResolvePath(freezer)
+	}
+	return freezer
+}
+
 // closeTrackingDB wraps the Close method of a database. When the database is closed by the
 // service, the wrapper removes it from the node's database map. This ensures that Node
 // won't auto-close the database if it is closed by the service that opened it.
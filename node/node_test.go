@@ -111,6 +111,40 @@ func TestNodeUsedDataDir(t *testing.T) {
 	}
 }
 
+// Tests that a data dir in use can be opened read-only, sharing its databases.
+func TestNodeReadOnlyDataDir(t *testing.T) {
+	dir := t.TempDir()
+
+	original, err := New(&Config{DataDir: dir})
+	if err != nil {
+		t.Fatalf("failed to create original protocol stack: %v", err)
+	}
+	defer original.Close()
+
+	db, err := original.OpenDatabaseWithFreezer("chaindata", 0, 0, "", "", false, true, false)
+	if err != nil {
+		t.Fatalf("failed to open original database: %v", err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+
+	readonly, err := New(&Config{DataDir: dir, DataDirReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to create read-only protocol stack: %v", err)
+	}
+	defer readonly.Close()
+
+	shared, err := readonly.OpenDatabaseWithFreezer("chaindata", 0, 0, "", "", false, true, false)
+	if err != nil {
+		t.Fatalf("failed to open shared database: %v", err)
+	}
+	if value, err := shared.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Fatalf("shared value mismatch: have %q, err %v", value, err)
+	}
+	if readonly.Config().NodeDB() != "" {
+		t.Fatal("read-only protocol stack uses the node database")
+	}
+}
+
 // Tests whether a Lifecycle can be registered.
 func TestLifecycleRegistry_Successful(t *testing.T) {
 	stack, err := New(testNodeConfig())
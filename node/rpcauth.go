@@ -0,0 +1,351 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/naoina/toml"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rpcAuthReloadInterval is how often the authentication file is checked for changes.
+	rpcAuthReloadInterval = 5 * time.Second
+
+	// rpcJWTIssuedAtSkew is how far the issuing time of a JWT may deviate from the
+	// local clock, which bounds the validity of a leaked token.
+	rpcJWTIssuedAtSkew = 60 * time.Second
+)
+
+// rpcAuthFile is the layout of the RPC authentication file:
+//
+//	[[Keys]]
+//	Name = "wallet"
+//	Key = "a-static-api-key"
+//	Namespaces = ["eth", "net"]
+//	RequestsPerSecond = 20
+//
+//	[[Keys]]
+//	Name = "indexer"
+//	JWTSecret = "0x..."
+type rpcAuthFile struct {
+	Keys []rpcAuthEntry
+}
+
+// rpcAuthEntry describes the credentials of a client and the access granted to it.
+// Clients present either the static key or a HS256 JWT signed with the secret as
+// bearer token in the Authorization header. JWTs must carry an "iat" claim within
+// a minute of the local time.
+type rpcAuthEntry struct {
+	Name              string
+	Key               string   `toml:",omitempty"` // static API key
+	JWTSecret         string   `toml:",omitempty"` // hex encoded JWT signing secret
+	Namespaces        []string `toml:",omitempty"` // allowed namespaces, all if empty
+	RequestsPerSecond int      `toml:",omitempty"` // call rate limit, unlimited if zero
+	Burst             int      `toml:",omitempty"` // defaults to the rate limit
+}
+
+var rpcAuthTOMLSettings = toml.Config{
+	NormFieldName: func(rt reflect.Type, key string) string {
+		return key
+	},
+	FieldToKey: func(rt reflect.Type, field string) string {
+		return field
+	},
+	MissingField: func(rt reflect.Type, field string) error {
+		return fmt.Errorf("field '%s' is not defined in %s", field, rt.String())
+	},
+}
+
+// rpcAuthError is returned to calls rejected by the access policy of their client.
+type rpcAuthError struct {
+	code int
+	msg  string
+}
+
+func (e *rpcAuthError) Error() string  { return e.msg }
+func (e *rpcAuthError) ErrorCode() int { return e.code }
+
+// rpcAuthPolicy is the access granted to the clients of an authentication entry.
+type rpcAuthPolicy struct {
+	name       string
+	secret     []byte // JWT secret, nil for static keys
+	namespaces map[string]bool
+	limiter    *rate.Limiter
+}
+
+// Allow implements rpc.ConnPolicy.
+func (p *rpcAuthPolicy) Allow(method string) error {
+	if len(p.namespaces) > 0 {
+		namespace := method
+		if i := strings.IndexByte(method, '_'); i >= 0 {
+			namespace = method[:i]
+		}
+		if !p.namespaces[namespace] {
+			return &rpcAuthError{-32601, fmt.Sprintf("the method %s is not available for %s", method, p.name)}
+		}
+	}
+	if p.limiter != nil && !p.limiter.Allow() {
+		return &rpcAuthError{-32005, fmt.Sprintf("rate limit of %s exceeded", p.name)}
+	}
+	return nil
+}
+
+// rpcAuthSession is the access of an authenticated connection. The policy of its
+// credentials is looked up on every call, so keys revoked or changed by reloading
+// the authentication file also apply to the open websocket connections.
+type rpcAuthSession struct {
+	auth   *rpcAuth
+	key    string    // static key, empty for JWTs
+	secret []byte    // JWT secret, nil for static keys
+	expiry time.Time // expiry of the JWT, zero if none
+}
+
+// Allow implements rpc.ConnPolicy.
+func (s *rpcAuthSession) Allow(method string) error {
+	if !s.expiry.IsZero() && !time.Now().Before(s.expiry) {
+		return &rpcAuthError{-32001, "the credentials of the connection expired"}
+	}
+	policy := s.auth.policy(s.key, s.secret)
+	if policy == nil {
+		return &rpcAuthError{-32001, "the credentials of the connection were revoked"}
+	}
+	return policy.Allow(method)
+}
+
+// rpcAuth authenticates RPC clients against an authentication file. The file is
+// reloaded when it changes, so keys can be added and revoked at runtime.
+type rpcAuth struct {
+	path string
+	log  log.Logger
+
+	mu        sync.Mutex
+	keys      map[string]*rpcAuthPolicy // static key -> policy
+	jwts      []*rpcAuthPolicy          // policies of JWT secrets
+	modTime   time.Time                 // modification time of the loaded file
+	lastCheck time.Time                 // last time the file was checked for changes
+}
+
+func newRPCAuth(path string, log log.Logger) (*rpcAuth, error) {
+	a := &rpcAuth{path: path, log: log}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// load reads the authentication file and replaces the current policies.
+func (a *rpcAuth) load() error {
+	stat, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(a.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var file rpcAuthFile
+	if err := rpcAuthTOMLSettings.NewDecoder(bufio.NewReader(f)).Decode(&file); err != nil {
+		return fmt.Errorf("%s: %v", a.path, err)
+	}
+	var (
+		keys = make(map[string]*rpcAuthPolicy)
+		jwts []*rpcAuthPolicy
+	)
+	for _, entry := range file.Keys {
+		policy := &rpcAuthPolicy{name: entry.Name}
+		if len(entry.Namespaces) > 0 {
+			policy.namespaces = make(map[string]bool)
+			for _, namespace := range entry.Namespaces {
+				policy.namespaces[namespace] = true
+			}
+		}
+		if entry.RequestsPerSecond > 0 {
+			burst := entry.Burst
+			if burst == 0 {
+				burst = entry.RequestsPerSecond
+			}
+			policy.limiter = rate.NewLimiter(rate.Limit(entry.RequestsPerSecond), burst)
+		}
+		switch {
+		case entry.Key != "" && entry.JWTSecret != "":
+			return fmt.Errorf("%s: key %q has both a static key and a JWT secret", a.path, entry.Name)
+		case entry.Key != "":
+			keys[entry.Key] = policy
+		case entry.JWTSecret != "":
+			if policy.secret, err = hexutil.Decode(entry.JWTSecret); err != nil {
+				return fmt.Errorf("%s: invalid JWT secret of key %q: %v", a.path, entry.Name, err)
+			}
+			jwts = append(jwts, policy)
+		default:
+			return fmt.Errorf("%s: key %q has neither a static key nor a JWT secret", a.path, entry.Name)
+		}
+	}
+	a.mu.Lock()
+	a.keys, a.jwts, a.modTime = keys, jwts, stat.ModTime()
+	a.mu.Unlock()
+	return nil
+}
+
+// maybeReload reloads the authentication file if it changed since it was loaded.
+// Invalid files are reported and the previous policies stay in effect.
+func (a *rpcAuth) maybeReload() {
+	a.mu.Lock()
+	if time.Since(a.lastCheck) < rpcAuthReloadInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastCheck = time.Now()
+	modTime := a.modTime
+	a.mu.Unlock()
+
+	stat, err := os.Stat(a.path)
+	if err != nil || stat.ModTime().Equal(modTime) {
+		return
+	}
+	if err := a.load(); err != nil {
+		a.log.Warn("Failed to reload RPC authentication file", "err", err)
+		return
+	}
+	a.log.Info("Reloaded RPC authentication file", "path", a.path)
+}
+
+// authenticate returns the session of the client presenting the given bearer
+// token, or nil if the token is invalid.
+func (a *rpcAuth) authenticate(token string) *rpcAuthSession {
+	a.maybeReload()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if policy := a.keys[token]; policy != nil {
+		return &rpcAuthSession{auth: a, key: token}
+	}
+	for _, policy := range a.jwts {
+		if expiry, ok := verifyJWT(token, policy.secret, time.Now()); ok {
+			return &rpcAuthSession{auth: a, secret: policy.secret, expiry: expiry}
+		}
+	}
+	return nil
+}
+
+// policy returns the current policy of the given static key or JWT secret, or nil
+// if the credentials were revoked.
+func (a *rpcAuth) policy(key string, secret []byte) *rpcAuthPolicy {
+	a.maybeReload()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if key != "" {
+		return a.keys[key]
+	}
+	for _, policy := range a.jwts {
+		if hmac.Equal(policy.secret, secret) {
+			return policy
+		}
+	}
+	return nil
+}
+
+// verifyJWT checks that token is a HS256 JWT signed with secret which is valid at
+// the given time, returning its expiry time if it has one. The token must have
+// been issued within rpcJWTIssuedAtSkew of now.
+func verifyJWT(token string, secret []byte, now time.Time) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(blob, &header) != nil {
+		return time.Time{}, false
+	}
+	if header.Alg != "HS256" {
+		return time.Time{}, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Iat *int64 `json:"iat"`
+		Exp *int64 `json:"exp"`
+		Nbf *int64 `json:"nbf"`
+	}
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(blob, &claims) != nil {
+		return time.Time{}, false
+	}
+	if claims.Iat == nil {
+		return time.Time{}, false
+	}
+	if skew := now.Sub(time.Unix(*claims.Iat, 0)); skew > rpcJWTIssuedAtSkew || skew < -rpcJWTIssuedAtSkew {
+		return time.Time{}, false
+	}
+	if claims.Nbf != nil && now.Unix() < *claims.Nbf {
+		return time.Time{}, false
+	}
+	var expiry time.Time
+	if claims.Exp != nil {
+		if expiry = time.Unix(*claims.Exp, 0); !now.Before(expiry) {
+			return time.Time{}, false
+		}
+	}
+	return expiry, true
+}
+
+// newRPCAuthHandler rejects requests which don't present valid credentials and
+// attaches the access policy of the client to the others.
+func newRPCAuthHandler(auth *rpcAuth, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var session *rpcAuthSession
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			session = auth.authenticate(strings.TrimPrefix(header, "Bearer "))
+		}
+		if session == nil {
+			http.Error(w, "missing or invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(rpc.WithConnPolicy(r.Context(), session)))
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// signJWT creates a HS256 JWT with the given claims.
+func signJWT(secret []byte, claims string) string {
+	var (
+		header  = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+		payload = base64.RawURLEncoding.EncodeToString([]byte(claims))
+		mac     = hmac.New(sha256.New, secret)
+	)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRPCAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpcauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "auth.toml")
+	ioutil.WriteFile(path, []byte(`
+[[Keys]]
+Name = "wallet"
+Key = "static-key"
+Namespaces = ["eth"]
+RequestsPerSecond = 1
+
+[[Keys]]
+Name = "indexer"
+JWTSecret = "0x0102030405060708"
+`), 0600)
+
+	auth, err := newRPCAuth(path, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Static keys are restricted to their namespaces and rate.
+	wallet := auth.authenticate("static-key")
+	if wallet == nil {
+		t.Fatal("static key not accepted")
+	}
+	if err := wallet.Allow("debug_traceTransaction"); err == nil {
+		t.Error("call outside of the allowed namespaces accepted")
+	}
+	if err := wallet.Allow("eth_blockNumber"); err != nil {
+		t.Errorf("allowed call rejected: %v", err)
+	}
+	if err := wallet.Allow("eth_blockNumber"); err == nil {
+		t.Error("call over the rate limit accepted")
+	}
+	// JWTs must be signed with the secret, freshly issued and not be expired.
+	var (
+		secret = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+		now    = time.Now().Unix()
+	)
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d,"exp":%d}`, now, now+3600))) == nil {
+		t.Error("valid JWT not accepted")
+	}
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d}`, now-5))) == nil {
+		t.Error("valid JWT without expiry not accepted")
+	}
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d,"exp":%d}`, now, now-3600))) != nil {
+		t.Error("expired JWT accepted")
+	}
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"exp":%d}`, now+3600))) != nil {
+		t.Error("JWT without issuing time accepted")
+	}
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d}`, now-3600))) != nil {
+		t.Error("stale JWT accepted")
+	}
+	if auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d}`, now+3600))) != nil {
+		t.Error("JWT issued in the future accepted")
+	}
+	if auth.authenticate(signJWT([]byte("wrong"), fmt.Sprintf(`{"iat":%d}`, now))) != nil {
+		t.Error("JWT with a wrong signature accepted")
+	}
+	if auth.authenticate("unknown") != nil {
+		t.Error("unknown key accepted")
+	}
+	// Connections stop accepting calls once their JWT expires.
+	expiring := auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d,"exp":%d}`, now, now+3600)))
+	if expiring == nil {
+		t.Fatal("valid JWT not accepted")
+	}
+	expiring.expiry = time.Now().Add(-time.Second)
+	if err := expiring.Allow("eth_blockNumber"); err == nil {
+		t.Error("call of an expired connection accepted")
+	}
+	// Revoked keys are rejected once the file is reloaded, including by the
+	// connections opened with them before.
+	indexer := auth.authenticate(signJWT(secret, fmt.Sprintf(`{"iat":%d}`, now)))
+	if indexer == nil {
+		t.Fatal("valid JWT not accepted")
+	}
+	ioutil.WriteFile(path, []byte(`
+[[Keys]]
+Name = "wallet"
+Key = "rotated-key"
+`), 0600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	auth.lastCheck = time.Time{}
+
+	if auth.authenticate("static-key") != nil {
+		t.Error("revoked key accepted after reload")
+	}
+	if err := wallet.Allow("eth_blockNumber"); err == nil {
+		t.Error("call of a connection with a revoked key accepted")
+	}
+	if err := indexer.Allow("eth_blockNumber"); err == nil {
+		t.Error("call of a connection with a revoked JWT secret accepted")
+	}
+	if auth.authenticate("rotated-key") == nil {
+		t.Error("new key not accepted after reload")
+	}
+}
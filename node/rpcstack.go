@@ -40,13 +40,22 @@ type httpConfig struct {
 	CorsAllowedOrigins []string
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
+	batch              rpc.BatchLimits
+	metering           *rpc.MeteringConfig
+	tracing            *rpc.TracingConfig
+	auth               *rpcAuth
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins []string
-	Modules []string
-	prefix  string // path prefix on which to mount ws handler
+	Origins  []string
+	Modules  []string
+	prefix   string // path prefix on which to mount ws handler
+	limits   rpc.ConnLimits
+	batch    rpc.BatchLimits
+	metering *rpc.MeteringConfig
+	tracing  *rpc.TracingConfig
+	auth     *rpcAuth
 }
 
 type rpcHandler struct {
@@ -283,9 +292,16 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	srv.SetBatchLimits(config.batch)
+	if config.metering != nil {
+		srv.SetMetering(*config.metering)
+	}
+	if config.tracing != nil {
+		srv.SetTracing(*config.tracing)
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
+		Handler: NewHTTPHandlerStack(newRPCAuthHandler(config.auth, srv), config.CorsAllowedOrigins, config.Vhosts),
 		server:  srv,
 	})
 	return nil
@@ -315,9 +331,17 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	srv.SetConnLimits(config.limits)
+	srv.SetBatchLimits(config.batch)
+	if config.metering != nil {
+		srv.SetMetering(*config.metering)
+	}
+	if config.tracing != nil {
+		srv.SetTracing(*config.tracing)
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: newRPCAuthHandler(config.auth, srv.WebsocketHandler(config.Origins)),
 		server:  srv,
 	})
 	return nil
@@ -345,6 +369,18 @@ func (h *httpServer) disableWS() bool {
 	return ws != nil
 }
 
+// updateMetering replaces the call metering settings of the running HTTP and
+// WebSocket handlers, returning false if none of them meters calls.
+func (h *httpServer) updateMetering(cfg rpc.MeteringConfig) bool {
+	var updated bool
+	for _, handler := range []*rpcHandler{h.httpHandler.Load().(*rpcHandler), h.wsHandler.Load().(*rpcHandler)} {
+		if handler != nil && handler.server.UpdateMetering(cfg) {
+			updated = true
+		}
+	}
+	return updated
+}
+
 // rpcAllowed returns true when JSON-RPC over HTTP is enabled.
 func (h *httpServer) rpcAllowed() bool {
 	return h.httpHandler.Load().(*rpcHandler) != nil
@@ -103,6 +103,9 @@ compile_fuzzer tests/fuzzers/difficulty Fuzz fuzzDifficulty
 compile_fuzzer tests/fuzzers/abi        Fuzz fuzzAbi
 compile_fuzzer tests/fuzzers/les        Fuzz fuzzLes
 compile_fuzzer tests/fuzzers/vflux      FuzzClientPool fuzzClientPool
+compile_fuzzer tests/fuzzers/ethpackets   Fuzz fuzzEthPackets
+compile_fuzzer tests/fuzzers/parliaheader Fuzz fuzzParliaHeader
+compile_fuzzer tests/fuzzers/txpool       Fuzz fuzzTxPool
 
 compile_fuzzer tests/fuzzers/bls12381  FuzzG1Add fuzz_g1_add
 compile_fuzzer tests/fuzzers/bls12381  FuzzG1Mul fuzz_g1_mul
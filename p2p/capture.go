@@ -0,0 +1,274 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// captureProtocols are the protocols whose messages are captured.
+var captureProtocols = map[string]bool{"eth": true, "snap": true}
+
+var errCaptureDisabled = errors.New("message capture disabled, no capture directory configured")
+
+// CaptureHeader is the first record of a capture, describing the peer whose
+// connection was captured.
+type CaptureHeader struct {
+	Peer  enode.ID
+	Name  string
+	Caps  []Cap
+	Start uint64 // Unix time the capture started at, in nanoseconds
+}
+
+// CapturedMsg is a message exchanged with a captured peer.
+type CapturedMsg struct {
+	Time     uint64 // Time since the start of the capture, in nanoseconds
+	Protocol string
+	Version  uint
+	Inbound  bool
+	Code     uint64
+	Payload  []byte
+}
+
+// ReadCapture reads a capture written by the server, returning its header and
+// messages in the order they were exchanged.
+func ReadCapture(path string) (*CaptureHeader, []*CapturedMsg, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	stream := rlp.NewStream(file, 0)
+	header := new(CaptureHeader)
+	if err := stream.Decode(header); err != nil {
+		return nil, nil, fmt.Errorf("invalid capture header: %v", err)
+	}
+	var msgs []*CapturedMsg
+	for {
+		msg := new(CapturedMsg)
+		if err := stream.Decode(msg); err == io.EOF {
+			return header, msgs, nil
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("invalid captured message %d: %v", len(msgs), err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// captureFile is the capture of a single connection to a peer.
+type captureFile struct {
+	lock  sync.Mutex
+	file  *os.File
+	start time.Time
+}
+
+// createCapture creates the capture of the given peer connection in dir.
+func createCapture(dir string, p *Peer) (*captureFile, error) {
+	start := time.Now()
+	id := p.ID()
+	path := filepath.Join(dir, fmt.Sprintf("%x-%s.rlp", id[:8], start.UTC().Format("20060102T150405.000")))
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	header := &CaptureHeader{Peer: id, Name: p.Fullname(), Caps: p.Caps(), Start: uint64(start.UnixNano())}
+	if err := rlp.Encode(file, header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &captureFile{file: file, start: start}, nil
+}
+
+// write appends a message to the capture.
+func (f *captureFile) write(proto Protocol, inbound bool, code uint64, payload []byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return rlp.Encode(f.file, &CapturedMsg{
+		Time:     uint64(time.Since(f.start)),
+		Protocol: proto.Name,
+		Version:  proto.Version,
+		Inbound:  inbound,
+		Code:     code,
+		Payload:  payload,
+	})
+}
+
+func (f *captureFile) close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.file.Close()
+}
+
+// capturer records the messages exchanged with the selected peers, one file per
+// connection.
+type capturer struct {
+	dir string
+	log log.Logger
+
+	lock  sync.Mutex
+	peers map[enode.ID]bool      // Peers selected for capturing
+	files map[*Peer]*captureFile // Captures of the connections to selected peers
+}
+
+func newCapturer(dir string, peers []enode.ID, logger log.Logger) (*capturer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &capturer{
+		dir:   dir,
+		log:   logger,
+		peers: make(map[enode.ID]bool),
+		files: make(map[*Peer]*captureFile),
+	}
+	for _, id := range peers {
+		c.peers[id] = true
+	}
+	return c, nil
+}
+
+// start selects a peer for capturing. A connection already established with the
+// peer is captured from its next message on.
+func (c *capturer) start(id enode.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.peers[id] = true
+}
+
+// stop deselects a peer, closing the capture of its connection.
+func (c *capturer) stop(id enode.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.peers, id)
+	for p, f := range c.files {
+		if p.ID() == id {
+			c.closeFile(p, f)
+		}
+	}
+}
+
+// dropped closes the capture of a peer connection once it is dropped.
+func (c *capturer) dropped(p *Peer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if f := c.files[p]; f != nil {
+		c.closeFile(p, f)
+	}
+}
+
+func (c *capturer) closeFile(p *Peer, f *captureFile) {
+	delete(c.files, p)
+	if err := f.close(); err != nil {
+		c.log.Warn("Failed to close message capture", "peer", p.ID(), "err", err)
+	}
+}
+
+// file returns the capture of a peer connection, creating it on the first message
+// exchanged with a selected peer, or nil if the peer isn't captured.
+func (c *capturer) file(p *Peer) *captureFile {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.peers[p.ID()] {
+		return nil
+	}
+	if f := c.files[p]; f != nil {
+		return f
+	}
+	f, err := createCapture(c.dir, p)
+	if err != nil {
+		// Deselect the peer to avoid failing on every message
+		c.log.Warn("Failed to create message capture", "peer", p.ID(), "err", err)
+		delete(c.peers, p.ID())
+		return nil
+	}
+	c.log.Info("Capturing peer messages", "peer", p.ID(), "file", f.file.Name())
+	c.files[p] = f
+	return f
+}
+
+// msgRecorder wraps a MsgReadWriter and records the messages sent or received to
+// the capture of the peer connection, if the peer is selected.
+type msgRecorder struct {
+	MsgReadWriter
+
+	capture *capturer
+	peer    *Peer
+	proto   Protocol
+}
+
+func newMsgRecorder(rw MsgReadWriter, capture *capturer, peer *Peer, proto Protocol) *msgRecorder {
+	return &msgRecorder{MsgReadWriter: rw, capture: capture, peer: peer, proto: proto}
+}
+
+// ReadMsg reads a message from the underlying MsgReadWriter and records it.
+func (r *msgRecorder) ReadMsg() (Msg, error) {
+	msg, err := r.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	if f := r.capture.file(r.peer); f != nil {
+		payload, err := ioutil.ReadAll(msg.Payload)
+		if err != nil {
+			return msg, err
+		}
+		msg.Payload = bytes.NewReader(payload)
+		r.record(f, true, msg.Code, payload)
+	}
+	return msg, nil
+}
+
+// WriteMsg writes a message to the underlying MsgReadWriter and records it.
+func (r *msgRecorder) WriteMsg(msg Msg) error {
+	f := r.capture.file(r.peer)
+	if f == nil {
+		return r.MsgReadWriter.WriteMsg(msg)
+	}
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	// Record before writing, so that the reply can't precede the message
+	r.record(f, false, msg.Code, payload)
+
+	msg.Payload = bytes.NewReader(payload)
+	return r.MsgReadWriter.WriteMsg(msg)
+}
+
+func (r *msgRecorder) record(f *captureFile, inbound bool, code uint64, payload []byte) {
+	if err := f.write(r.proto, inbound, code, payload); err != nil {
+		r.peer.log.Warn("Failed to capture message", "protocol", r.proto.Name, "code", code, "err", err)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// echoProtocol answers every message with the next message code.
+var echoProtocol = Protocol{
+	Name:    "eth",
+	Version: 66,
+	Length:  16,
+	Run: func(p *Peer, rw MsgReadWriter) error {
+		for {
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				return err
+			}
+			payload, _ := ioutil.ReadAll(msg.Payload)
+			if err := rw.WriteMsg(Msg{Code: msg.Code + 1, Size: uint32(len(payload)), Payload: bytes.NewReader(payload)}); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+// captureEcho captures an exchange of messages with the echo protocol, returning
+// the path of the capture.
+func captureEcho(t *testing.T, dir string, id enode.ID) string {
+	capture, err := newCapturer(dir, []enode.ID{id}, log.Root())
+	if err != nil {
+		t.Fatalf("failed to create capturer: %v", err)
+	}
+	peer := NewPeer(id, "remote", []Cap{{"eth", 66}})
+	local, remote := MsgPipe()
+	defer remote.Close()
+
+	rw := newMsgRecorder(local, capture, peer, echoProtocol)
+	go echoProtocol.Run(peer, rw)
+
+	for code := uint64(0); code < 8; code += 2 {
+		if err := Send(remote, code, []uint{uint(code)}); err != nil {
+			t.Fatalf("failed to send message %d: %v", code, err)
+		}
+		if err := ExpectMsg(remote, code+1, []uint{uint(code)}); err != nil {
+			t.Fatalf("unexpected reply: %v", err)
+		}
+	}
+	capture.dropped(peer)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.rlp"))
+	if len(files) != 1 {
+		t.Fatalf("capture files mismatch: have %v, want 1", files)
+	}
+	return files[0]
+}
+
+func TestCaptureRoundtrip(t *testing.T) {
+	id := enode.ID{0x01}
+	header, msgs, err := ReadCapture(captureEcho(t, t.TempDir(), id))
+	if err != nil {
+		t.Fatalf("failed to read capture: %v", err)
+	}
+	if header.Peer != id || header.Name != "remote" || len(header.Caps) != 1 {
+		t.Errorf("capture header mismatch: %+v", header)
+	}
+	if len(msgs) != 8 {
+		t.Fatalf("captured message count mismatch: have %d, want 8", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.Inbound != (i%2 == 0) || msg.Code != uint64(i) || msg.Protocol != "eth" || msg.Version != 66 {
+			t.Errorf("message %d: mismatch: %+v", i, msg)
+		}
+	}
+}
+
+func TestCaptureUnselectedPeer(t *testing.T) {
+	capture, err := newCapturer(t.TempDir(), nil, log.Root())
+	if err != nil {
+		t.Fatalf("failed to create capturer: %v", err)
+	}
+	peer := NewPeer(enode.ID{0x01}, "remote", nil)
+	if capture.file(peer) != nil {
+		t.Fatal("unselected peer captured")
+	}
+	capture.start(peer.ID())
+	if capture.file(peer) == nil {
+		t.Fatal("selected peer not captured")
+	}
+	capture.stop(peer.ID())
+	if capture.file(peer) != nil {
+		t.Fatal("deselected peer captured")
+	}
+}
+
+func TestReplayCapture(t *testing.T) {
+	path := captureEcho(t, t.TempDir(), enode.ID{0x01})
+
+	stats, err := ReplayCapture([]Protocol{echoProtocol}, path)
+	if err != nil {
+		t.Fatalf("failed to replay capture: %v", err)
+	}
+	if stats.Fed != 4 || stats.Sent != 4 || stats.Diverged != 0 {
+		t.Errorf("replay stats mismatch: %+v", stats)
+	}
+}
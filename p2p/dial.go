@@ -80,6 +80,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errPeerDiversity    = errors.New("too many peers from the same network")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -104,6 +105,7 @@ type dialScheduler struct {
 	remStaticCh chan *enode.Node
 	addPeerCh   chan *conn
 	remPeerCh   chan *conn
+	setLimitCh  chan int
 
 	// Everything below here belongs to loop and
 	// should only be accessed by code on the loop goroutine.
@@ -135,6 +137,7 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP whitelist, disabled if nil
+	diversity      *peerDiversity   // Limits of the peers per address, nil if unlimited
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -174,6 +177,7 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		remStaticCh: make(chan *enode.Node),
 		addPeerCh:   make(chan *conn),
 		remPeerCh:   make(chan *conn),
+		setLimitCh:  make(chan int),
 	}
 	d.lastStatsLog = d.clock.Now()
 	d.ctx, d.cancel = context.WithCancel(context.Background())
@@ -226,6 +230,15 @@ func (d *dialScheduler) peerRemoved(c *conn) {
 	}
 }
 
+// setMaxDialPeers changes the maximum number of dialed peers. Peers above the new
+// limit stay connected, but no new dials are launched until they drop.
+func (d *dialScheduler) setMaxDialPeers(n int) {
+	select {
+	case d.setLimitCh <- n:
+	case <-d.ctx.Done():
+	}
+}
+
 // loop is the main loop of the dialer.
 func (d *dialScheduler) loop(it enode.Iterator) {
 	var (
@@ -248,7 +261,7 @@ loop:
 
 		select {
 		case node := <-nodesCh:
-			if err := d.checkDial(node); err != nil {
+			if err := d.checkDynDial(node); err != nil {
 				d.log.Trace("Discarding dial candidate", "id", node.ID(), "ip", node.IP(), "reason", err)
 			} else {
 				d.startDial(newDialTask(node, dynDialedConn))
@@ -266,6 +279,9 @@ loop:
 			}
 			id := c.node.ID()
 			d.peers[id] = c.flags
+			if d.diversity != nil {
+				d.diversity.add(id, diversityIP(c))
+			}
 			// Remove from static pool because the node is now connected.
 			task := d.static[id]
 			if task != nil && task.staticPoolIndex >= 0 {
@@ -278,6 +294,9 @@ loop:
 				d.dialPeers--
 			}
 			delete(d.peers, c.node.ID())
+			if d.diversity != nil {
+				d.diversity.remove(c.node.ID())
+			}
 			d.updateStaticPool(c.node.ID())
 
 		case node := <-d.addStaticCh:
@@ -304,6 +323,10 @@ loop:
 				}
 			}
 
+		case n := <-d.setLimitCh:
+			d.log.Debug("Updated dialed peer limit", "old", d.maxDialPeers, "new", n)
+			d.maxDialPeers = n
+
 		case <-historyExp:
 			d.expireHistory()
 
@@ -395,9 +418,9 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if n.ID() == d.self {
 		return errSelf
 	}
-	if n.IP() != nil && n.TCP() == 0 {
-		// This check can trigger if a non-TCP node is found
-		// by discovery. If there is no IP, the node is a static
+	if n.IP() != nil && n.TCP() == 0 && n.QUIC() == 0 {
+		// This check can trigger if a node without an RLPx port
+		// is found by discovery. If there is no IP, the node is a static
 		// node and the actual endpoint will be resolved later in dialTask.
 		return errNoPort
 	}
@@ -416,6 +439,18 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	return nil
 }
 
+// checkDynDial returns an error if dynamic dialing the node would exceed the
+// limits of the peers per address.
+func (d *dialScheduler) checkDynDial(n *enode.Node) error {
+	if err := d.checkDial(n); err != nil {
+		return err
+	}
+	if d.diversity != nil && n.IP() != nil && !netutil.IsLAN(n.IP()) && !d.diversity.allowed(n.IP()) {
+		return errPeerDiversity
+	}
+	return nil
+}
+
 // startStaticDials starts n static dial tasks.
 func (d *dialScheduler) startStaticDials(n int) (started int) {
 	for started = 0; started < n && len(d.staticPool) > 0; started++ {
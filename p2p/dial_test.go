@@ -116,6 +116,66 @@ func TestDialSchedDynDial(t *testing.T) {
 	})
 }
 
+// This test checks that changing the dialed peer limit applies to new dials.
+func TestDialSchedSetLimit(t *testing.T) {
+	t.Parallel()
+
+	config := dialConfig{
+		maxActiveDials: 5,
+		maxDialPeers:   2,
+	}
+	runDialTest(t, config, []dialTestRound{
+		// 1 out of 2 peers is connected, leaving 2 dial slots.
+		{
+			peersAdded: []*conn{
+				{flags: dynDialedConn, node: newNode(uintID(0x00), "")},
+			},
+			discovered: []*enode.Node{
+				newNode(uintID(0x01), "127.0.0.1:30303"),
+				newNode(uintID(0x02), "127.0.0.1:30303"),
+				newNode(uintID(0x03), "127.0.0.1:30303"), // not dialed because there are only two slots
+			},
+			wantNewDials: []*enode.Node{
+				newNode(uintID(0x01), "127.0.0.1:30303"),
+				newNode(uintID(0x02), "127.0.0.1:30303"),
+			},
+		},
+		// Both dials complete and fill the peer slots, raising the limit frees
+		// up new slots.
+		{
+			update: func(d *dialScheduler) {
+				d.setMaxDialPeers(4)
+			},
+			succeeded: []enode.ID{
+				uintID(0x01),
+			},
+			failed: []enode.ID{
+				uintID(0x02),
+			},
+			discovered: []*enode.Node{
+				newNode(uintID(0x04), "127.0.0.1:30303"),
+			},
+			wantNewDials: []*enode.Node{
+				newNode(uintID(0x03), "127.0.0.1:30303"),
+				newNode(uintID(0x04), "127.0.0.1:30303"),
+			},
+		},
+		// Lowering the limit keeps the connected peers, but stops dialing.
+		{
+			update: func(d *dialScheduler) {
+				d.setMaxDialPeers(1)
+			},
+			failed: []enode.ID{
+				uintID(0x03),
+				uintID(0x04),
+			},
+			discovered: []*enode.Node{
+				newNode(uintID(0x05), "127.0.0.1:30303"), // not dialed because there are no free slots
+			},
+		},
+	})
+}
+
 // This test checks that candidates that do not match the netrestrict list are not dialed.
 func TestDialSchedNetRestrict(t *testing.T) {
 	t.Parallel()
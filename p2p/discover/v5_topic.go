@@ -0,0 +1,417 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+const (
+	topicAdLifetime       = 15 * time.Minute // how long a registrar keeps an advertisement
+	topicAdLimit          = 100              // advertisements kept per topic
+	topicLimit            = 50               // topics kept by a registrar
+	topicTicketLifetime   = 10 * time.Second // how long a ticket can be used to register
+	topicRegistrars       = 8                // nodes a topic is advertised with
+	topicRegisterInterval = 10 * time.Minute // renewal of the advertisements, within their lifetime
+	topicRetryInterval    = 30 * time.Second // retry if no registrar took the advertisement
+	topicQueryDelay       = 10 * time.Second // wait between the rounds of topic queries
+
+	topicTicketSize = len(Topic{}) + 8 + sha256.Size
+)
+
+var (
+	errInvalidTicket   = errors.New("invalid ticket")
+	errExpiredTicket   = errors.New("expired ticket")
+	errTopicRegistrant = errors.New("record doesn't match the registrant")
+	errTopicRejected   = errors.New("topic registration rejected")
+)
+
+// Topic is the hash of the name of a service nodes advertise themselves under, so
+// that the others can find the ones providing it. The advertisements are stored
+// by the nodes closest to the topic hash.
+type Topic [32]byte
+
+// NewTopic creates the topic of the named service.
+func NewTopic(name string) Topic {
+	return sha256.Sum256([]byte(name))
+}
+
+func (t Topic) String() string {
+	return fmt.Sprintf("%x", t[:8])
+}
+
+// topicAd is a node advertised under a topic.
+type topicAd struct {
+	node    *enode.Node
+	expires mclock.AbsTime
+}
+
+// topicTable holds the advertisements registered with the local node, and the
+// topics it advertises itself under.
+type topicTable struct {
+	mu     sync.Mutex
+	ads    map[Topic][]*topicAd // by registration time
+	own    map[Topic]bool       // topics of the local node
+	secret [32]byte             // authenticates the tickets issued
+}
+
+func newTopicTable() *topicTable {
+	tt := &topicTable{ads: make(map[Topic][]*topicAd), own: make(map[Topic]bool)}
+	crand.Read(tt.secret[:])
+	return tt
+}
+
+// advertise adds a topic of the local node.
+func (tt *topicTable) advertise(topic Topic) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.own[topic] = true
+}
+
+// advertises returns whether the local node advertises the topic.
+func (tt *topicTable) advertises(topic Topic) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	return tt.own[topic]
+}
+
+// expire drops the expired advertisements of the topic.
+func (tt *topicTable) expire(topic Topic, now mclock.AbsTime) {
+	ads := tt.ads[topic]
+	for len(ads) > 0 && ads[0].expires <= now {
+		ads = ads[1:]
+	}
+	if len(ads) == 0 {
+		delete(tt.ads, topic)
+	} else {
+		tt.ads[topic] = ads
+	}
+}
+
+// register advertises the node under the topic, or renews its advertisement. It
+// returns false if the table is full.
+func (tt *topicTable) register(topic Topic, n *enode.Node, now mclock.AbsTime) bool {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	tt.expire(topic, now)
+	ads, ok := tt.ads[topic]
+	if !ok && len(tt.ads) >= topicLimit {
+		return false
+	}
+	for i, ad := range ads {
+		if ad.node.ID() == n.ID() {
+			ads = append(ads[:i:i], ads[i+1:]...)
+			break
+		}
+	}
+	if len(ads) >= topicAdLimit {
+		return false
+	}
+	tt.ads[topic] = append(ads, &topicAd{node: n, expires: now.Add(topicAdLifetime)})
+	return true
+}
+
+// nodes returns the most recently registered nodes advertised under the topic.
+func (tt *topicTable) nodes(topic Topic, now mclock.AbsTime, limit int) []*enode.Node {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	tt.expire(topic, now)
+	ads := tt.ads[topic]
+	nodes := make([]*enode.Node, 0, min(limit, len(ads)))
+	for i := len(ads) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, ads[i].node)
+	}
+	return nodes
+}
+
+// ticket creates the ticket the node can register under the topic with. The
+// registration request doesn't name the topic, the ticket carries it.
+func (tt *topicTable) ticket(id enode.ID, topic Topic, now mclock.AbsTime) []byte {
+	ticket := make([]byte, len(topic)+8, topicTicketSize)
+	copy(ticket, topic[:])
+	binary.BigEndian.PutUint64(ticket[len(topic):], uint64(now))
+	return append(ticket, tt.ticketMAC(id, ticket)...)
+}
+
+// checkTicket verifies that the ticket was issued to the node recently, returning
+// the topic of it.
+func (tt *topicTable) checkTicket(id enode.ID, ticket []byte, now mclock.AbsTime) (Topic, error) {
+	var topic Topic
+	if len(ticket) != topicTicketSize {
+		return topic, errInvalidTicket
+	}
+	body := ticket[:len(topic)+8]
+	if !hmac.Equal(ticket[len(body):], tt.ticketMAC(id, body)) {
+		return topic, errInvalidTicket
+	}
+	issued := mclock.AbsTime(binary.BigEndian.Uint64(body[len(topic):]))
+	if issued > now || now.Sub(issued) > topicTicketLifetime {
+		return topic, errExpiredTicket
+	}
+	copy(topic[:], body)
+	return topic, nil
+}
+
+func (tt *topicTable) ticketMAC(id enode.ID, body []byte) []byte {
+	mac := hmac.New(sha256.New, tt.secret[:])
+	mac.Write(id[:])
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// RegisterTopic advertises the local node under the topic with the nodes closest
+// to it, renewing the advertisements until the transport is closed.
+func (t *UDPv5) RegisterTopic(topic Topic) {
+	t.topics.advertise(topic)
+	t.wg.Add(1)
+	go t.topicRegisterLoop(topic)
+}
+
+func (t *UDPv5) topicRegisterLoop(topic Topic) {
+	defer t.wg.Done()
+
+	timer := t.clock.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+		case <-t.closeCtx.Done():
+			return
+		}
+		if t.registerTopic(topic) == 0 {
+			timer.Reset(topicRetryInterval)
+		} else {
+			timer.Reset(topicRegisterInterval)
+		}
+	}
+}
+
+// registerTopic advertises the local node with the registrars of the topic,
+// returning the number of them that took the advertisement.
+func (t *UDPv5) registerTopic(topic Topic) int {
+	var registered int
+	for _, n := range t.Lookup(enode.ID(topic)) {
+		if registered == topicRegistrars || t.closeCtx.Err() != nil {
+			break
+		}
+		if err := t.regtopic(n, topic); err != nil {
+			t.log.Trace("Topic registration failed", "topic", topic, "id", n.ID(), "err", err)
+			continue
+		}
+		registered++
+	}
+	t.log.Debug("Advertised topic", "topic", topic, "registrars", registered)
+	return registered
+}
+
+// requestTicket calls REQUESTTICKET on a node and waits for a TICKET response.
+func (t *UDPv5) requestTicket(n *enode.Node, topic Topic) ([]byte, error) {
+	resp := t.call(n, v5wire.TicketMsg, &v5wire.RequestTicket{Topic: topic[:]})
+	defer t.callDone(resp)
+
+	select {
+	case ticket := <-resp.ch:
+		return ticket.(*v5wire.Ticket).Ticket, nil
+	case err := <-resp.err:
+		return nil, err
+	}
+}
+
+// regtopic registers the local node under the topic with a node.
+func (t *UDPv5) regtopic(n *enode.Node, topic Topic) error {
+	ticket, err := t.requestTicket(n, topic)
+	if err != nil {
+		return err
+	}
+	resp := t.call(n, v5wire.RegconfirmationMsg, &v5wire.Regtopic{Ticket: ticket, ENR: t.Self().Record()})
+	defer t.callDone(resp)
+
+	select {
+	case conf := <-resp.ch:
+		if !conf.(*v5wire.Regconfirmation).Registered {
+			return errTopicRejected
+		}
+		return nil
+	case err := <-resp.err:
+		return err
+	}
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for the advertised nodes.
+func (t *UDPv5) topicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.call(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic[:]})
+	return t.waitForNodes(resp, nil)
+}
+
+// TopicNodes returns an iterator of the nodes advertised under the topic, found by
+// asking the nodes closest to it.
+func (t *UDPv5) TopicNodes(topic Topic) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{t: t, topic: topic, ctx: ctx, cancel: cancel}
+}
+
+// queryTopic asks the registrars of the topic for the nodes advertised under it,
+// including the local node if it is one.
+func (t *UDPv5) queryTopic(ctx context.Context, topic Topic) []*enode.Node {
+	var (
+		nodes []*enode.Node
+		seen  = map[enode.ID]bool{t.Self().ID(): true}
+	)
+	for _, n := range t.topics.nodes(topic, t.clock.Now(), findnodeResultLimit) {
+		seen[n.ID()] = true
+		nodes = append(nodes, n)
+	}
+	for _, n := range t.newLookup(ctx, enode.ID(topic)).run() {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := t.topicQuery(n, topic)
+		if err != nil {
+			t.log.Trace("Topic query failed", "topic", topic, "id", n.ID(), "err", err)
+		}
+		for _, rn := range result {
+			if !seen[rn.ID()] {
+				seen[rn.ID()] = true
+				nodes = append(nodes, rn)
+			}
+		}
+	}
+	return nodes
+}
+
+// topicIterator iterates the nodes advertised under a topic, querying its
+// registrars again once the ones found are used up.
+type topicIterator struct {
+	t      *UDPv5
+	topic  Topic
+	ctx    context.Context
+	cancel context.CancelFunc
+	buffer []*enode.Node
+	cur    *enode.Node
+	rounds int
+}
+
+func (it *topicIterator) Next() bool {
+	it.cur = nil
+	for len(it.buffer) == 0 {
+		if it.rounds > 0 {
+			select {
+			case <-time.After(topicQueryDelay):
+			case <-it.ctx.Done():
+			}
+		}
+		if it.ctx.Err() != nil {
+			return false
+		}
+		it.buffer = it.t.queryTopic(it.ctx, it.topic)
+		it.rounds++
+	}
+	it.cur, it.buffer = it.buffer[0], it.buffer[1:]
+	return true
+}
+
+func (it *topicIterator) Node() *enode.Node {
+	return it.cur
+}
+
+func (it *topicIterator) Close() {
+	it.cancel()
+}
+
+// handleRequestTicket issues a ticket for registering under the requested topic.
+func (t *UDPv5) handleRequestTicket(p *v5wire.RequestTicket, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var topic Topic
+	if len(p.Topic) != len(topic) {
+		t.log.Debug("Invalid topic in "+p.Name(), "id", fromID, "addr", fromAddr)
+		return
+	}
+	copy(topic[:], p.Topic)
+	t.sendResponse(fromID, fromAddr, &v5wire.Ticket{ReqID: p.ReqID, Ticket: t.topics.ticket(fromID, topic, t.clock.Now())})
+}
+
+// handleRegtopic stores the advertisement of the sender under the topic of its ticket.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	registered, err := t.registerAd(p, fromID, fromAddr)
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	}
+	t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Registered: registered})
+}
+
+// registerAd verifies a registration request and stores the advertisement. The
+// record advertised must be the sender's, at the address it registers from.
+func (t *UDPv5) registerAd(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) (bool, error) {
+	now := t.clock.Now()
+	topic, err := t.topics.checkTicket(fromID, p.Ticket, now)
+	if err != nil {
+		return false, err
+	}
+	if p.ENR == nil {
+		return false, errTopicRegistrant
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return false, err
+	}
+	if n.ID() != fromID || !n.IP().Equal(fromAddr.IP) {
+		return false, errTopicRegistrant
+	}
+	return t.topics.register(topic, n, now), nil
+}
+
+// handleTopicQuery returns the nodes advertised under the topic, the local node
+// first if it advertises the topic too.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var topic Topic
+	if len(p.Topic) != len(topic) {
+		t.log.Debug("Invalid topic in "+p.Name(), "id", fromID, "addr", fromAddr)
+		return
+	}
+	copy(topic[:], p.Topic)
+
+	var nodes []*enode.Node
+	if t.topics.advertises(topic) {
+		nodes = append(nodes, t.Self())
+	}
+	for _, n := range t.topics.nodes(topic, t.clock.Now(), topicAdLimit) {
+		if n.ID() == fromID || netutil.CheckRelayIP(fromAddr.IP, n.IP()) != nil {
+			continue
+		}
+		if nodes = append(nodes, n); len(nodes) == findnodeResultLimit {
+			break
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestTopicTable(t *testing.T) {
+	var (
+		tt    = newTopicTable()
+		topic = NewTopic("test")
+		nodes = nodesAtDistance(enode.ID{}, 256, 3)
+	)
+	for i, n := range nodes {
+		if !tt.register(topic, n, mclock.AbsTime(i)) {
+			t.Fatalf("node %d not registered", i)
+		}
+	}
+	// Renewing moves the advertisement to the front.
+	tt.register(topic, nodes[0], 3)
+	if have := tt.nodes(topic, 3, 2); len(have) != 2 || have[0] != nodes[0] || have[1] != nodes[2] {
+		t.Errorf("wrong nodes: %v", have)
+	}
+	// Advertisements expire in registration order.
+	if have := tt.nodes(topic, mclock.AbsTime(topicAdLifetime)+2, 10); len(have) != 1 || have[0] != nodes[0] {
+		t.Errorf("wrong nodes after expiry: %v", have)
+	}
+	if have := tt.nodes(topic, mclock.AbsTime(topicAdLifetime)+3, 10); len(have) != 0 || len(tt.ads) != 0 {
+		t.Errorf("topic not dropped after expiry: %v", have)
+	}
+
+	// Tickets are bound to the node and expire.
+	id := nodes[1].ID()
+	ticket := tt.ticket(id, topic, 100)
+	if have, err := tt.checkTicket(id, ticket, 100+mclock.AbsTime(time.Second)); err != nil || have != topic {
+		t.Errorf("valid ticket rejected: %v %v", have, err)
+	}
+	if _, err := tt.checkTicket(nodes[0].ID(), ticket, 100); err != errInvalidTicket {
+		t.Errorf("ticket of other node accepted: %v", err)
+	}
+	if _, err := tt.checkTicket(id, ticket, 100+mclock.AbsTime(topicTicketLifetime)+1); err != errExpiredTicket {
+		t.Errorf("expired ticket accepted: %v", err)
+	}
+	forged := append([]byte{}, ticket...)
+	other := NewTopic("other")
+	copy(forged, other[:])
+	if _, err := tt.checkTicket(id, forged, 100); err != errInvalidTicket {
+		t.Errorf("forged ticket accepted: %v", err)
+	}
+}
+
+// This test checks that incoming topic registrations and queries are handled.
+func TestUDPv5_topicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	topic := NewTopic("test")
+	test.packetIn(&v5wire.RequestTicket{ReqID: []byte{0}, Topic: topic[:]})
+	var ticket []byte
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		ticket = p.Ticket
+	})
+
+	// Records of other nodes can't be registered.
+	registrant := test.getNode(test.remotekey, test.remoteaddr).Node()
+	other := test.getNode(newkey(), &net.UDPAddr{IP: net.IP{10, 0, 1, 100}, Port: 30303}).Node()
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Ticket: ticket, ENR: other.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Registered {
+			t.Error("registered record of other node")
+		}
+	})
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{2}, Ticket: ticket, ENR: registrant.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !p.Registered {
+			t.Error("registration rejected")
+		}
+	})
+
+	// Other nodes find the registrant under the topic, but not under others.
+	querier := newkey()
+	queryAddr := &net.UDPAddr{IP: net.IP{10, 0, 1, 101}, Port: 30303}
+	test.packetInFrom(querier, queryAddr, &v5wire.TopicQuery{ReqID: []byte{3}, Topic: topic[:]})
+	test.expectNodes([]byte{3}, 1, []*enode.Node{registrant})
+
+	unknown := NewTopic("unknown")
+	test.packetInFrom(querier, queryAddr, &v5wire.TopicQuery{ReqID: []byte{4}, Topic: unknown[:]})
+	test.expectNodes([]byte{4}, 1, nil)
+
+	// Registrars advertising the topic return themselves too.
+	test.udp.topics.advertise(unknown)
+	test.packetInFrom(querier, queryAddr, &v5wire.TopicQuery{ReqID: []byte{5}, Topic: unknown[:]})
+	test.expectNodes([]byte{5}, 1, []*enode.Node{test.udp.Self()})
+}
+
+// This test checks that nodes advertising a topic are found by the others.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	topic := NewTopic("test")
+	for _, n := range nodes[1:3] {
+		if n.registerTopic(topic) == 0 {
+			t.Fatalf("node %v found no registrars", n.Self().ID())
+		}
+	}
+
+	it := nodes[N-1].TopicNodes(topic)
+	defer it.Close()
+	time.AfterFunc(5*time.Second, it.Close)
+	found := make(map[enode.ID]bool)
+	for len(found) < 2 && it.Next() {
+		found[it.Node().ID()] = true
+	}
+	for _, n := range nodes[1:3] {
+		if !found[n.Self().ID()] {
+			t.Errorf("advertising node %v not found", n.Self().ID())
+		}
+	}
+	if len(found) != 2 {
+		t.Errorf("found %d nodes, want 2", len(found))
+	}
+}
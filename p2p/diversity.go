@@ -0,0 +1,174 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+// Prefix lengths of the networks the peers per subnet are limited in.
+const (
+	diversitySubnet4 = 24
+	diversitySubnet6 = 48
+)
+
+// peerDiversity limits the peers connected from the same IP address or network,
+// making it harder for a few hosts to eclipse the node.
+type peerDiversity struct {
+	ips      *netutil.DistinctNetSet // Peers per IP address, nil if unlimited
+	subnets4 *netutil.DistinctNetSet // Peers per IPv4 network, nil if unlimited
+	subnets6 *netutil.DistinctNetSet // Peers per IPv6 network, nil if unlimited
+	members  map[enode.ID]net.IP     // Addresses of the peers counted
+}
+
+// newPeerDiversity creates the limits of the peers per IP address and subnet,
+// returning nil if neither is limited.
+func newPeerDiversity(perIP, perSubnet int) *peerDiversity {
+	if perIP <= 0 && perSubnet <= 0 {
+		return nil
+	}
+	d := &peerDiversity{members: make(map[enode.ID]net.IP)}
+	if perIP > 0 {
+		d.ips = &netutil.DistinctNetSet{Subnet: 128, Limit: uint(perIP)}
+	}
+	if perSubnet > 0 {
+		d.subnets4 = &netutil.DistinctNetSet{Subnet: diversitySubnet4, Limit: uint(perSubnet)}
+		d.subnets6 = &netutil.DistinctNetSet{Subnet: diversitySubnet6, Limit: uint(perSubnet)}
+	}
+	return d
+}
+
+// diversityIP returns the address a connection counts towards the limits with,
+// nil if exempt. Trusted peers, static peers and the peers on the LAN are.
+func diversityIP(c *conn) net.IP {
+	if c.is(trustedConn) || c.is(staticDialedConn) {
+		return nil
+	}
+	if ip := c.node.IP(); ip != nil && !netutil.IsLAN(ip) {
+		return ip
+	}
+	return nil
+}
+
+// subnets returns the set limiting the network of the address.
+func (d *peerDiversity) subnets(ip net.IP) *netutil.DistinctNetSet {
+	if ip.To4() != nil {
+		return d.subnets4
+	}
+	return d.subnets6
+}
+
+// allowed returns whether another peer can connect from the address.
+func (d *peerDiversity) allowed(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	for _, set := range []*netutil.DistinctNetSet{d.ips, d.subnets(ip)} {
+		if set == nil {
+			continue
+		}
+		if !set.Add(ip) {
+			return false
+		}
+		set.Remove(ip)
+	}
+	return true
+}
+
+// add counts a connected peer.
+func (d *peerDiversity) add(id enode.ID, ip net.IP) {
+	if ip == nil {
+		return
+	}
+	for _, set := range []*netutil.DistinctNetSet{d.ips, d.subnets(ip)} {
+		if set != nil {
+			set.Add(ip)
+		}
+	}
+	d.members[id] = ip
+}
+
+// remove stops counting a disconnected peer.
+func (d *peerDiversity) remove(id enode.ID) {
+	ip, ok := d.members[id]
+	if !ok {
+		return
+	}
+	for _, set := range []*netutil.DistinctNetSet{d.ips, d.subnets(ip)} {
+		if set != nil {
+			set.Remove(ip)
+		}
+	}
+	delete(d.members, id)
+}
+
+// subnetKey returns the network the peers of an address are grouped by when
+// choosing the one to evict.
+func subnetKey(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(diversitySubnet4, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(diversitySubnet6, 128)).String()
+}
+
+// evictInbound disconnects an inbound peer to make room for a new inbound
+// connection. The ProtectedPeers longest connected inbound peers are never
+// evicted, of the others the most recently connected one of the best represented
+// network is. It returns false if no peer could be evicted.
+func (srv *Server) evictInbound(peers map[enode.ID]*Peer) bool {
+	if srv.ProtectedPeers <= 0 {
+		return false
+	}
+	var candidates []*Peer
+	for id, p := range peers {
+		if p.Inbound() && !p.rw.is(trustedConn) && !srv.evicting[id] {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) <= srv.ProtectedPeers {
+		return false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].created < candidates[j].created
+	})
+	candidates = candidates[srv.ProtectedPeers:]
+
+	// Evict the youngest peer of the network with the most candidates
+	var (
+		networks = make(map[string][]*Peer)
+		victims  []*Peer
+	)
+	for _, p := range candidates {
+		key := subnetKey(p.Node().IP())
+		networks[key] = append(networks[key], p)
+		if len(networks[key]) >= len(victims) {
+			victims = networks[key]
+		}
+	}
+	victim := victims[len(victims)-1]
+	srv.log.Debug("Evicting inbound peer", "id", victim.ID(), "addr", victim.RemoteAddr(), "network", len(victims))
+	srv.evicting[victim.ID()] = true
+	victim.Disconnect(DiscTooManyPeers)
+	return true
+}
@@ -128,6 +128,13 @@ func (n *Node) TCP() int {
 	return int(port)
 }
 
+// QUIC returns the QUIC port of the node, zero if it doesn't accept RLPx over QUIC.
+func (n *Node) QUIC() int {
+	var port enr.QUIC
+	n.Load(&port)
+	return int(port)
+}
+
 // Pubkey returns the secp256k1 public key of the node, if present.
 func (n *Node) Pubkey() *ecdsa.PublicKey {
 	var key ecdsa.PublicKey
@@ -61,12 +61,36 @@ type Interface interface {
 //     "upnp"               uses the Universal Plug and Play protocol
 //     "pmp"                uses NAT-PMP with an auto-detected gateway address
 //     "pmp:192.168.0.1"    uses NAT-PMP with the given gateway address
+//     "stun"               asks a public STUN server for the external IP
+//     "stun:host:port"     asks the given STUN server for the external IP
+//
+// Several mechanisms separated by commas are tried in order, e.g. "upnp,stun"
+// falls back to STUN while no UPnP router answers.
 func Parse(spec string) (Interface, error) {
+	if strings.Contains(spec, ",") {
+		var mechs []Interface
+		for _, part := range strings.Split(spec, ",") {
+			mech, err := Parse(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			if mech != nil {
+				mechs = append(mechs, mech)
+			}
+		}
+		return Fallback(mechs...), nil
+	}
 	var (
 		parts = strings.SplitN(spec, ":", 2)
 		mech  = strings.ToLower(parts[0])
 		ip    net.IP
 	)
+	if mech == "stun" {
+		if len(parts) > 1 {
+			return STUN(parts[1]), nil
+		}
+		return STUN(DefaultSTUNServer), nil
+	}
 	if len(parts) > 1 {
 		ip = net.ParseIP(parts[1])
 		if ip == nil {
@@ -177,43 +201,54 @@ func PMP(gateway net.IP) Interface {
 // autodisc represents a port mapping mechanism that is still being
 // auto-discovered. Calls to the Interface methods on this type will
 // wait until the discovery is done and then call the method on the
-// discovered mechanism.
+// discovered mechanism. If none was discovered, or the discovered one
+// fails, the next call discovers again as the router may have changed.
 //
 // This type is useful because discovery can take a while but we
 // want return an Interface value from UPnP, PMP and Auto immediately.
 type autodisc struct {
 	what string // type of interface being autodiscovered
-	once sync.Once
 	doit func() Interface
 
-	mu    sync.Mutex
+	mu    sync.Mutex // serializes the discoveries
 	found Interface
 }
 
 func startautodisc(what string, doit func() Interface) Interface {
-	// TODO: monitor network configuration and rerun doit when it changes.
 	return &autodisc{what: what, doit: doit}
 }
 
 func (n *autodisc) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error {
-	if err := n.wait(); err != nil {
+	found, err := n.wait()
+	if err != nil {
+		return err
+	}
+	if err := found.AddMapping(protocol, extport, intport, name, lifetime); err != nil {
+		n.reset()
 		return err
 	}
-	return n.found.AddMapping(protocol, extport, intport, name, lifetime)
+	return nil
 }
 
 func (n *autodisc) DeleteMapping(protocol string, extport, intport int) error {
-	if err := n.wait(); err != nil {
+	found, err := n.wait()
+	if err != nil {
 		return err
 	}
-	return n.found.DeleteMapping(protocol, extport, intport)
+	return found.DeleteMapping(protocol, extport, intport)
 }
 
 func (n *autodisc) ExternalIP() (net.IP, error) {
-	if err := n.wait(); err != nil {
+	found, err := n.wait()
+	if err != nil {
+		return nil, err
+	}
+	ip, err := found.ExternalIP()
+	if err != nil {
+		n.reset()
 		return nil, err
 	}
-	return n.found.ExternalIP()
+	return ip, nil
 }
 
 func (n *autodisc) String() string {
@@ -226,14 +261,79 @@ func (n *autodisc) String() string {
 }
 
 // wait blocks until auto-discovery has been performed.
-func (n *autodisc) wait() error {
-	n.once.Do(func() {
-		n.mu.Lock()
+func (n *autodisc) wait() (Interface, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.found == nil {
 		n.found = n.doit()
-		n.mu.Unlock()
-	})
+	}
 	if n.found == nil {
-		return fmt.Errorf("no %s router discovered", n.what)
+		return nil, fmt.Errorf("no %s router discovered", n.what)
 	}
-	return nil
+	return n.found, nil
+}
+
+// reset discards the discovered mechanism after it failed.
+func (n *autodisc) reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.found != nil {
+		log.Debug("Port mapping mechanism failed, rediscovering", "interface", n.found)
+		n.found = nil
+	}
+}
+
+// Fallback returns a port mapper trying the given mechanisms in order, using the
+// first one that works.
+func Fallback(mechs ...Interface) Interface {
+	if len(mechs) == 1 {
+		return mechs[0]
+	}
+	return fallback(mechs)
+}
+
+type fallback []Interface
+
+func (f fallback) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) error {
+	errs := make([]string, 0, len(f))
+	for _, mech := range f {
+		err := mech.AddMapping(protocol, extport, intport, name, lifetime)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%v: %v", mech, err))
+	}
+	return errors.New(strings.Join(errs, ", "))
+}
+
+func (f fallback) DeleteMapping(protocol string, extport, intport int) error {
+	var err error
+	for _, mech := range f {
+		if e := mech.DeleteMapping(protocol, extport, intport); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (f fallback) ExternalIP() (net.IP, error) {
+	errs := make([]string, 0, len(f))
+	for _, mech := range f {
+		ip, err := mech.ExternalIP()
+		if err == nil {
+			return ip, nil
+		}
+		errs = append(errs, fmt.Sprintf("%v: %v", mech, err))
+	}
+	return nil, errors.New(strings.Join(errs, ", "))
+}
+
+func (f fallback) String() string {
+	names := make([]string, len(f))
+	for i, mech := range f {
+		names[i] = mech.String()
+	}
+	return strings.Join(names, ",")
 }
@@ -17,6 +17,7 @@
 package nat
 
 import (
+	"errors"
 	"net"
 	"testing"
 	"time"
@@ -61,3 +62,36 @@ func TestAutoDiscRace(t *testing.T) {
 		}
 	}
 }
+
+// failingIP is a mechanism whose external IP can't be retrieved.
+type failingIP struct{ ExtIP }
+
+func (*failingIP) ExternalIP() (net.IP, error) { return nil, errors.New("unavailable") }
+
+// This test checks that autodisc discovers again after the discovery or the
+// discovered mechanism failed.
+func TestAutoDiscRediscover(t *testing.T) {
+	var found []Interface
+	ad := startautodisc("thing", func() Interface {
+		var next Interface
+		if len(found) > 0 {
+			next, found = found[0], found[1:]
+		}
+		return next
+	})
+	found = []Interface{nil, &failingIP{}, ExtIP{33, 44, 55, 66}}
+
+	if _, err := ad.ExternalIP(); err == nil {
+		t.Fatal("no error without mechanism")
+	}
+	if _, err := ad.ExternalIP(); err == nil {
+		t.Fatal("no error with failing mechanism")
+	}
+	ip, err := ad.ExternalIP()
+	if err != nil || !ip.Equal(net.IP{33, 44, 55, 66}) {
+		t.Fatalf("rediscovery failed: %v %v", ip, err)
+	}
+	if len(found) != 0 {
+		t.Errorf("discovered again while working")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultSTUNServer is the STUN server asked for the external IP if none is given.
+const DefaultSTUNServer = "stun.l.google.com:19302"
+
+const (
+	stunTimeout          = 3 * time.Second
+	stunMagicCookie      = 0x2112A442
+	stunBindingRequest   = 0x0001
+	stunBindingSuccess   = 0x0101
+	stunMappedAddress    = 0x0001
+	stunXorMappedAddress = 0x0020
+)
+
+// stun learns the external IP from a STUN server (RFC 5389), by sending a binding
+// request and reading the address it came from off the response. It can't map
+// ports, the mapping operations do nothing.
+type stun struct {
+	server string
+}
+
+// STUN returns a mechanism asking the given STUN server for the external IP. The
+// ports must be mapped manually, if needed.
+func STUN(server string) Interface {
+	return &stun{server: server}
+}
+
+func (n *stun) String() string {
+	return fmt.Sprintf("STUN(%s)", n.server)
+}
+
+// These do nothing.
+
+func (*stun) AddMapping(string, int, int, string, time.Duration) error { return nil }
+func (*stun) DeleteMapping(string, int, int) error                     { return nil }
+
+func (n *stun) ExternalIP() (net.IP, error) {
+	conn, err := net.DialTimeout("udp", n.server, stunTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(stunTimeout))
+
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	if _, err := rand.Read(req[8:20]); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	res := make([]byte, 1500)
+	for {
+		size, err := conn.Read(res)
+		if err != nil {
+			return nil, err
+		}
+		// Skip the stray responses to other requests
+		if size < 20 || !bytes.Equal(res[4:20], req[4:20]) {
+			continue
+		}
+		return parseSTUNResponse(res[:size])
+	}
+}
+
+// parseSTUNResponse returns the address the binding request came from, as seen
+// by the STUN server.
+func parseSTUNResponse(res []byte) (net.IP, error) {
+	if binary.BigEndian.Uint16(res[0:]) != stunBindingSuccess {
+		return nil, fmt.Errorf("STUN binding failed, response type %#x", binary.BigEndian.Uint16(res[0:]))
+	}
+	var mapped net.IP
+	for attrs := res[20:]; len(attrs) >= 4; {
+		typ, size := binary.BigEndian.Uint16(attrs[0:]), int(binary.BigEndian.Uint16(attrs[2:]))
+		if len(attrs) < 4+size {
+			break
+		}
+		value := attrs[4 : 4+size]
+		switch typ {
+		case stunXorMappedAddress:
+			if ip := stunAddress(value); ip != nil {
+				// The address is XOR'ed with the magic cookie and transaction ID
+				for i := range ip {
+					ip[i] ^= res[4+i]
+				}
+				return ip, nil
+			}
+		case stunMappedAddress:
+			mapped = stunAddress(value)
+		}
+		// Attributes are padded to a multiple of 4 bytes
+		next := 4 + (size+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	if mapped == nil {
+		return nil, errors.New("no mapped address in STUN response")
+	}
+	return mapped, nil
+}
+
+// stunAddress decodes the IP of a (XOR-)MAPPED-ADDRESS attribute.
+func stunAddress(value []byte) net.IP {
+	switch {
+	case len(value) == 8 && value[1] == 0x01:
+		return append(net.IP{}, value[4:8]...)
+	case len(value) == 20 && value[1] == 0x02:
+		return append(net.IP{}, value[4:20]...)
+	default:
+		return nil
+	}
+}
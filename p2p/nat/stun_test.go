@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// serveSTUN answers binding requests with the given address, XOR'ed as a
+// XOR-MAPPED-ADDRESS after a padded unknown attribute.
+func serveSTUN(t *testing.T, conn net.PacketConn, ip net.IP) {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n != 20 || binary.BigEndian.Uint16(buf) != stunBindingRequest {
+			t.Errorf("invalid binding request: %x", buf[:n])
+			continue
+		}
+		res := make([]byte, 20, 64)
+		binary.BigEndian.PutUint16(res, stunBindingSuccess)
+		copy(res[4:20], buf[4:20])
+		res = append(res, 0x80, 0x22, 0x00, 0x03, 'g', 'o', '!', 0x00) // SOFTWARE
+
+		family, addrlen := byte(0x01), 4
+		if ip.To4() == nil {
+			family, addrlen = 0x02, 16
+		} else {
+			ip = ip.To4()
+		}
+		attr := []byte{0x00, 0x20, 0x00, byte(4 + addrlen), 0x00, family, 0x00, 0x00}
+		for i := 0; i < addrlen; i++ {
+			attr = append(attr, ip[i]^res[4+i])
+		}
+		res = append(res, attr...)
+		binary.BigEndian.PutUint16(res[2:], uint16(len(res)-20))
+		conn.WriteTo(res, addr)
+	}
+}
+
+func TestSTUN(t *testing.T) {
+	for _, want := range []net.IP{net.ParseIP("77.12.33.4"), net.ParseIP("2001:db8::77")} {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go serveSTUN(t, conn, want)
+
+		ip, err := STUN(conn.LocalAddr().String()).ExternalIP()
+		conn.Close()
+		if err != nil {
+			t.Fatalf("STUN failed: %v", err)
+		}
+		if !ip.Equal(want) {
+			t.Errorf("IP mismatch: have %v, want %v", ip, want)
+		}
+	}
+}
+
+func TestParseFallback(t *testing.T) {
+	mech, err := Parse("upnp, stun:127.0.0.1:3478, extip:77.12.33.4")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if s := mech.String(); s != "UPnP,STUN(127.0.0.1:3478),ExtIP(77.12.33.4)" {
+		t.Errorf("mechanisms mismatch: %s", s)
+	}
+	if _, err := Parse("upnp,extip"); err == nil {
+		t.Errorf("invalid mechanism accepted")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	// A STUN server not answering falls back to the next mechanism
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	mech := Fallback(STUN(conn.LocalAddr().String()), ExtIP{77, 12, 33, 4})
+	ip, err := mech.ExternalIP()
+	if err != nil || !ip.Equal(net.IP{77, 12, 33, 4}) {
+		t.Errorf("fallback failed: %v %v", ip, err)
+	}
+}
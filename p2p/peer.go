@@ -117,6 +117,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// capture records the eth and snap messages if set and the peer is selected
+	capture *capturer
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -400,6 +403,9 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
 		}
+		if p.capture != nil && captureProtocols[proto.Name] {
+			rw = newMsgRecorder(rw, p.capture, p, proto.Protocol)
+		}
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			defer p.wg.Done()
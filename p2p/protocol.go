@@ -59,6 +59,12 @@ type Protocol struct {
 	// attempts to create connections to them.
 	DialCandidates enode.Iterator
 
+	// Topic, if set, is the name of the topic the node is advertised under with
+	// discovery v5, and the nodes advertising it are searched as dial candidates.
+	// TopicFilter, if set, drops the ones found that are not worth dialing.
+	Topic       string
+	TopicFilter func(*enode.Node) bool
+
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
 }
@@ -0,0 +1,225 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build quic
+// +build quic
+
+package p2p
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/quic-go/quic-go"
+)
+
+const (
+	// quicALPN is the application protocol negotiated for RLPx over QUIC.
+	quicALPN = "devp2p"
+
+	quicDialTimeout   = 5 * time.Second
+	quicAcceptTimeout = 5 * time.Second
+	quicIdleTimeout   = 30 * time.Second
+)
+
+// quicConfig is the configuration of the QUIC connections. Keep-alives hold the
+// NAT bindings open, letting the connections migrate if the address of the dialer
+// changes.
+var quicConfig = &quic.Config{
+	HandshakeIdleTimeout: quicDialTimeout,
+	MaxIdleTimeout:       quicIdleTimeout,
+	KeepAlivePeriod:      quicIdleTimeout / 2,
+}
+
+// quicTLSConfig creates the TLS configuration of the QUIC transport. QUIC can't
+// run without TLS, but the peers are authenticated by the RLPx handshake running
+// on top of it, so an ephemeral self-signed certificate is presented and the
+// certificates of the peers are not verified.
+func quicTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		NextProtos:         []string{quicALPN},
+		InsecureSkipVerify: true,
+	}, nil
+}
+
+// quicTransport is the experimental QUIC transport for RLPx. It's only built with
+// the quic tag, keeping the quic-go dependency and the Go version it needs out of
+// the default builds.
+type quicTransport struct {
+	tr  *quic.Transport
+	tls *tls.Config
+	ln  *quicListener
+}
+
+// listenQUIC starts accepting RLPx sessions over QUIC on the given UDP address.
+func listenQUIC(addr string) (*quicTransport, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := quicTLSConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tr := &quic.Transport{Conn: conn}
+	ln, err := tr.Listen(tlsConfig, quicConfig)
+	if err != nil {
+		tr.Close()
+		conn.Close()
+		return nil, err
+	}
+	return &quicTransport{tr: tr, tls: tlsConfig, ln: newQUICListener(ln)}, nil
+}
+
+// listener returns the listener accepting the inbound sessions.
+func (t *quicTransport) listener() net.Listener {
+	return t.ln
+}
+
+// dialer returns a dialer trying QUIC before the fallback dialer.
+func (t *quicTransport) dialer(fallback NodeDialer, log log.Logger) NodeDialer {
+	return &quicDialer{tr: t.tr, tls: t.tls, fallback: fallback, log: log}
+}
+
+// close shuts down the transport along with all its connections.
+func (t *quicTransport) close() {
+	t.tr.Close()
+	t.tr.Conn.Close()
+}
+
+// quicConn is an RLPx session running on the single stream of a QUIC connection.
+type quicConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// Close tears down the whole QUIC connection, closing the stream only ends the
+// sending side of it.
+func (c *quicConn) Close() error {
+	c.Stream.CancelRead(0)
+	c.Stream.Close()
+	return c.conn.CloseWithError(0, "")
+}
+
+// quicListener accepts the RLPx sessions over QUIC as a net.Listener.
+type quicListener struct {
+	ln     *quic.Listener
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newQUICListener(ln *quic.Listener) *quicListener {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &quicListener{ln: ln, ctx: ctx, cancel: cancel}
+}
+
+func (l *quicListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.ln.Accept(l.ctx)
+		if err != nil {
+			return nil, err
+		}
+		// The dialer sends the RLPx auth message right after opening the stream,
+		// give up on the connection if it doesn't.
+		ctx, cancel := context.WithTimeout(l.ctx, quicAcceptTimeout)
+		stream, err := conn.AcceptStream(ctx)
+		cancel()
+		if err != nil {
+			conn.CloseWithError(0, "")
+			if l.ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		return &quicConn{Stream: stream, conn: conn}, nil
+	}
+}
+
+func (l *quicListener) Close() error {
+	l.cancel()
+	return l.ln.Close()
+}
+
+func (l *quicListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// quicDialer dials the nodes announcing a QUIC port in their record over QUIC,
+// and the others, or if that fails, with the fallback dialer.
+type quicDialer struct {
+	tr       *quic.Transport
+	tls      *tls.Config
+	fallback NodeDialer
+	log      log.Logger
+}
+
+func (d *quicDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	if dest.QUIC() != 0 && dest.IP() != nil {
+		conn, err := d.dial(ctx, dest)
+		if err == nil {
+			return conn, nil
+		}
+		d.log.Trace("QUIC dial failed, falling back to TCP", "id", dest.ID(), "err", err)
+	}
+	return d.fallback.Dial(ctx, dest)
+}
+
+func (d *quicDialer) dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, quicDialTimeout)
+	defer cancel()
+
+	conn, err := d.tr.Dial(ctx, &net.UDPAddr{IP: dest.IP(), Port: dest.QUIC()}, d.tls, quicConfig)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: conn}, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !quic
+// +build !quic
+
+package p2p
+
+import (
+	"errors"
+	"net"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errQUICDisabled is returned when the QUIC transport is configured in a build
+// without it.
+var errQUICDisabled = errors.New("QUIC transport not available, rebuild with the quic build tag")
+
+// quicTransport stands in for the experimental QUIC transport, which is only
+// built with the quic tag.
+type quicTransport struct{}
+
+func listenQUIC(addr string) (*quicTransport, error) {
+	return nil, errQUICDisabled
+}
+
+func (t *quicTransport) listener() net.Listener                                { return nil }
+func (t *quicTransport) dialer(fallback NodeDialer, log log.Logger) NodeDialer { return fallback }
+func (t *quicTransport) close()                                                {}
@@ -0,0 +1,47 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !quic
+// +build !quic
+
+package p2p
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Tests that configuring the QUIC transport fails in builds without it, instead
+// of silently running without the announced transport.
+func TestQUICDisabled(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			Name:        "test",
+			MaxPeers:    10,
+			QUICAddr:    "127.0.0.1:0",
+			NoDiscovery: true,
+			PrivateKey:  newkey(),
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); !errors.Is(err, errQUICDisabled) {
+		srv.Stop()
+		t.Fatalf("error mismatch: have %v, want %v", err, errQUICDisabled)
+	}
+}
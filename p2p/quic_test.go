@@ -0,0 +1,102 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build quic
+// +build quic
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+func startQUICTestServer(t *testing.T, listenAddr, quicAddr string) *Server {
+	srv := &Server{
+		Config: Config{
+			Name:        "test",
+			MaxPeers:    10,
+			ListenAddr:  listenAddr,
+			QUICAddr:    quicAddr,
+			NoDiscovery: true,
+			PrivateKey:  newkey(),
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start server: %v", err)
+	}
+	return srv
+}
+
+// waitPeerAddr waits for a peer to connect to the server, returning its address.
+func waitPeerAddr(t *testing.T, srv *Server, timeout time.Duration) net.Addr {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(10 * time.Millisecond) {
+		if peers := srv.Peers(); len(peers) > 0 {
+			return peers[0].RemoteAddr()
+		}
+	}
+	t.Fatal("peer didn't connect")
+	return nil
+}
+
+// This test checks that a node announcing a QUIC port is connected to over QUIC.
+func TestServerQUIC(t *testing.T) {
+	listener := startQUICTestServer(t, "", "127.0.0.1:0")
+	defer listener.Stop()
+	dialer := startQUICTestServer(t, "", "127.0.0.1:0")
+	defer dialer.Stop()
+
+	if listener.Self().QUIC() == 0 {
+		t.Fatal("QUIC port not announced")
+	}
+	dialer.AddPeer(listener.Self())
+
+	if addr, ok := waitPeerAddr(t, listener, 5*time.Second).(*net.UDPAddr); !ok || addr.Port != dialer.Self().QUIC() {
+		t.Errorf("inbound peer address mismatch: have %v, want UDP port %d", addr, dialer.Self().QUIC())
+	}
+	if _, ok := waitPeerAddr(t, dialer, 5*time.Second).(*net.UDPAddr); !ok {
+		t.Error("outbound peer not connected over QUIC")
+	}
+}
+
+// This test checks that the dialer falls back to TCP if the node can't be reached
+// over QUIC.
+func TestServerQUICFallback(t *testing.T) {
+	listener := startQUICTestServer(t, "127.0.0.1:0", "")
+	defer listener.Stop()
+	dialer := startQUICTestServer(t, "", "127.0.0.1:0")
+	defer dialer.Stop()
+
+	// Announce a QUIC port nothing answers on.
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	listener.localnode.Set(enr.QUIC(conn.LocalAddr().(*net.UDPAddr).Port))
+	dialer.AddPeer(listener.Self())
+
+	if _, ok := waitPeerAddr(t, listener, 2*quicDialTimeout).(*net.TCPAddr); !ok {
+		t.Error("peer not connected over TCP")
+	}
+}
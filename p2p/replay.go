@@ -0,0 +1,187 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// replayTimeout is the time a replay waits for the protocol handler to send the
+// messages the captured node sent, before feeding it the next captured message.
+const replayTimeout = 5 * time.Second
+
+// ReplayStats summarises the replay of a capture.
+type ReplayStats struct {
+	Fed      int   // Captured inbound messages fed to the protocol handlers
+	Sent     int   // Messages sent by the protocol handlers
+	Diverged int   // Captured outbound messages the handlers didn't send alike
+	Dropped  error // Error the handlers dropped the replayed peer with, if any
+}
+
+// replayConn is the connection of a protocol handler to the replayed peer.
+type replayConn struct {
+	proto  Protocol
+	feed   *MsgPipeRW // End of the pipe the captured messages are fed into
+	expect []uint64   // Codes of the captured outbound messages
+	next   int        // Index of the next outbound message to check
+
+	lock   sync.Mutex
+	sent   []uint64      // Codes of the messages sent by the handler
+	notify chan struct{} // Signalled on every message sent by the handler
+	done   chan struct{} // Closed when the handler returns
+}
+
+// drain reads the messages sent by the handler until the pipe is closed.
+func (rc *replayConn) drain() {
+	for {
+		msg, err := rc.feed.ReadMsg()
+		if err != nil {
+			return
+		}
+		msg.Discard()
+
+		rc.lock.Lock()
+		rc.sent = append(rc.sent, msg.Code)
+		rc.lock.Unlock()
+
+		select {
+		case rc.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// check waits for the handler to send the captured outbound messages, and counts
+// the ones it didn't send or sent with a different code.
+func (rc *replayConn) check(stats *ReplayStats) {
+	timeout := time.NewTimer(replayTimeout)
+	defer timeout.Stop()
+
+	for {
+		rc.lock.Lock()
+		sent := rc.sent
+		rc.lock.Unlock()
+
+		for ; rc.next < len(rc.expect) && rc.next < len(sent); rc.next++ {
+			if have, want := sent[rc.next], rc.expect[rc.next]; have != want {
+				log.Warn("Replay diverged from capture", "protocol", rc.proto.Name, "index", rc.next, "have", have, "want", want)
+				stats.Diverged++
+			}
+		}
+		if rc.next == len(rc.expect) {
+			return
+		}
+		select {
+		case <-rc.notify:
+		case <-rc.done:
+			stats.Diverged += len(rc.expect) - rc.next
+			rc.next = len(rc.expect)
+			return
+		case <-timeout.C:
+			log.Warn("Replay missing captured messages", "protocol", rc.proto.Name, "index", rc.next, "missing", len(rc.expect)-rc.next)
+			stats.Diverged += len(rc.expect) - rc.next
+			rc.next = len(rc.expect)
+			return
+		}
+	}
+}
+
+// ReplayCapture connects the given protocols to a peer impersonating the captured
+// one and feeds them the captured inbound messages, for deterministic debugging.
+// Each message is fed once the handlers sent what the captured node had sent
+// before receiving it. The replay ends when all messages are fed, or the handlers
+// drop the peer.
+func ReplayCapture(protocols []Protocol, path string) (*ReplayStats, error) {
+	header, msgs, err := ReadCapture(path)
+	if err != nil {
+		return nil, err
+	}
+	// Run the captured protocols with the versions of the captured connection
+	var caps []Cap
+	seen := make(map[Cap]bool)
+	for _, msg := range msgs {
+		if cap := (Cap{msg.Protocol, msg.Version}); !seen[cap] {
+			seen[cap] = true
+			caps = append(caps, cap)
+		}
+	}
+	peer := NewPeerWithProtocols(header.Peer, protocols, header.Name, caps)
+	if len(peer.running) == 0 {
+		return nil, errors.New("no captured protocol supported")
+	}
+	var (
+		stats = new(ReplayStats)
+		conns = make(map[string]*replayConn)
+		errc  = make(chan error, len(peer.running))
+	)
+	for name, proto := range peer.running {
+		run, feed := MsgPipe()
+		conn := &replayConn{proto: proto.Protocol, feed: feed, notify: make(chan struct{}, 1), done: make(chan struct{})}
+		conns[name] = conn
+
+		go conn.drain()
+		go func(proto Protocol) {
+			err := proto.Run(peer, run)
+			run.Close()
+			close(conn.done)
+			errc <- err
+		}(proto.Protocol)
+	}
+	for _, msg := range msgs {
+		conn := conns[msg.Protocol]
+		if conn == nil || conn.proto.Version != msg.Version {
+			continue
+		}
+		if !msg.Inbound {
+			conn.expect = append(conn.expect, msg.Code)
+			continue
+		}
+		conn.check(stats)
+		err := conn.feed.WriteMsg(Msg{Code: msg.Code, Size: uint32(len(msg.Payload)), Payload: bytes.NewReader(msg.Payload)})
+		if err != nil {
+			break // Peer dropped by the handler
+		}
+		stats.Fed++
+	}
+	// Check the messages sent after the last one fed, then disconnect
+	for _, conn := range conns {
+		conn.check(stats)
+	}
+	running := len(conns)
+	select {
+	case stats.Dropped = <-errc:
+		running--
+	default:
+	}
+	for _, conn := range conns {
+		conn.feed.Close()
+	}
+	for ; running > 0; running-- {
+		<-errc
+	}
+	for _, conn := range conns {
+		conn.lock.Lock()
+		stats.Sent += len(conn.sent)
+		conn.lock.Unlock()
+	}
+	return stats, nil
+}
@@ -53,6 +53,7 @@ const (
 	// Connectivity defaults.
 	defaultMaxPendingPeers = 50
 	defaultDialRatio       = 3
+	defaultNATRecheck      = 5 * time.Minute
 
 	// This time limits inbound connection attempts per source IP.
 	inboundThrottleTime = 30 * time.Second
@@ -89,6 +90,18 @@ type Config struct {
 	// Setting DialRatio to zero defaults it to 3.
 	DialRatio int `toml:",omitempty"`
 
+	// MaxPeersPerIP and MaxPeersPerSubnet limit the peers connected from the same
+	// IP address and from the same /24 IPv4 or /48 IPv6 network, for a few hosts
+	// not to eclipse the node. Trusted, static and LAN peers are exempt. Zero means
+	// no limit.
+	MaxPeersPerIP     int `toml:",omitempty"`
+	MaxPeersPerSubnet int `toml:",omitempty"`
+
+	// ProtectedPeers enables evicting an inbound peer for a new inbound connection
+	// when the inbound slots are full, protecting this many of the longest
+	// connected ones. Zero disables eviction.
+	ProtectedPeers int `toml:",omitempty"`
+
 	// NoDiscovery can be used to disable the peer discovery mechanism.
 	// Disabling is useful for protocol debugging (manual topology).
 	NoDiscovery bool
@@ -140,11 +153,23 @@ type Config struct {
 	// the server is started.
 	ListenAddr string
 
+	// QUICAddr is the UDP address the experimental QUIC transport for RLPx listens
+	// on, announced in the local node record. Nodes announcing it are dialed over
+	// QUIC, falling back to TCP. It must not share the port of discovery. If empty,
+	// the transport is disabled. The transport is only available in builds with
+	// the quic tag, starting the server fails otherwise.
+	QUICAddr string `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
 	NAT nat.Interface `toml:",omitempty"`
 
+	// NATRecheck is the interval the external IP is asked from the NAT mechanism
+	// again at, as it may change, e.g. when the ISP rotates the addresses. Zero
+	// defaults to 5 minutes.
+	NATRecheck time.Duration `toml:",omitempty"`
+
 	// If Dialer is set to a non-nil value, the given Dialer
 	// is used to dial outbound peer connections.
 	Dialer NodeDialer `toml:"-"`
@@ -156,6 +181,14 @@ type Config struct {
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
 
+	// CaptureDir is the directory the eth and snap protocol messages exchanged
+	// with the peers in CapturePeers are recorded to, for replaying them.
+	CaptureDir string `toml:",omitempty"`
+
+	// CapturePeers are the peers whose messages are captured from the start.
+	// More can be selected while running with StartCapture.
+	CapturePeers []enode.ID `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -177,9 +210,11 @@ type Server struct {
 	running bool
 
 	listener     net.Listener
+	quic         *quicTransport // nil if the QUIC transport is disabled
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
 	peerFeed     event.Feed
+	capture      *capturer
 	log          log.Logger
 
 	nodedb    *enode.DB
@@ -201,6 +236,8 @@ type Server struct {
 
 	// State of run loop and listenLoop.
 	inboundHistory expHeap
+	diversity      *peerDiversity    // Limits of the peers per address, nil if unlimited
+	evicting       map[enode.ID]bool // Inbound peers disconnected to make room
 }
 
 type peerOpFunc func(map[enode.ID]*Peer)
@@ -327,6 +364,16 @@ func (srv *Server) AddPeer(node *enode.Node) {
 	srv.dialsched.addStatic(node)
 }
 
+// SetMaxPeers changes the maximum number of peers that can be connected. Peers
+// above the new limit are not disconnected, but no new ones are accepted until
+// the peer count drops below it.
+func (srv *Server) SetMaxPeers(n int) {
+	srv.doPeerOp(func(map[enode.ID]*Peer) {
+		srv.MaxPeers = n
+		srv.dialsched.setMaxDialPeers(srv.maxDialedConns())
+	})
+}
+
 // RemovePeer removes a node from the static node set. It also disconnects from the given
 // node if it is currently connected as a peer.
 //
@@ -374,6 +421,27 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node) {
 	}
 }
 
+// StartCapture starts recording the eth and snap messages exchanged with the
+// given peer to the capture directory. Only connections captured from their
+// start can be replayed, an established one being captured from its next
+// message on.
+func (srv *Server) StartCapture(id enode.ID) error {
+	if srv.capture == nil {
+		return errCaptureDisabled
+	}
+	srv.capture.start(id)
+	return nil
+}
+
+// StopCapture stops recording the messages exchanged with the given peer.
+func (srv *Server) StopCapture(id enode.ID) error {
+	if srv.capture == nil {
+		return errCaptureDisabled
+	}
+	srv.capture.stop(id)
+	return nil
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -404,6 +472,9 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quic != nil {
+		srv.quic.listener().Close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 
@@ -418,6 +489,9 @@ func (srv *Server) Stop() {
 	case <-time.After(stopTimeout):
 		srv.log.Warn("stop p2p server timeout, forcing stop")
 	}
+	if srv.quic != nil {
+		srv.quic.close()
+	}
 }
 
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
@@ -484,15 +558,27 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.diversity = newPeerDiversity(srv.MaxPeersPerIP, srv.MaxPeersPerSubnet)
+	srv.evicting = make(map[enode.ID]bool)
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
+	if srv.CaptureDir != "" {
+		if srv.capture, err = newCapturer(srv.CaptureDir, srv.CapturePeers, srv.log); err != nil {
+			return err
+		}
+	}
 	if srv.ListenAddr != "" {
 		if err := srv.setupListening(); err != nil {
 			return err
 		}
 	}
+	if srv.QUICAddr != "" {
+		if err := srv.setupQUIC(); err != nil {
+			return err
+		}
+	}
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -537,16 +623,53 @@ func (srv *Server) setupLocalNode() error {
 		// Ask the router about the IP. This takes a while and blocks startup,
 		// do it in the background.
 		srv.loopWG.Add(1)
-		go func() {
-			defer srv.loopWG.Done()
-			if ip, err := srv.NAT.ExternalIP(); err == nil {
-				srv.localnode.SetStaticIP(ip)
-			}
-		}()
+		go srv.natLoop()
 	}
 	return nil
 }
 
+// natLoop keeps the IP of the local node record in sync with the external IP
+// reported by the NAT mechanism. While the mechanism fails, the endpoint predicted
+// from what the discovery peers report is used instead.
+func (srv *Server) natLoop() {
+	defer srv.loopWG.Done()
+
+	interval := srv.NATRecheck
+	if interval == 0 {
+		interval = defaultNATRecheck
+	}
+	var (
+		ip    net.IP
+		check = time.NewTimer(0)
+	)
+	defer check.Stop()
+	for {
+		select {
+		case <-check.C:
+			newip, err := srv.NAT.ExternalIP()
+			switch {
+			case err != nil && ip != nil:
+				srv.log.Warn("External IP unavailable, predicting it", "interface", srv.NAT, "err", err)
+				srv.localnode.ClearStaticIP(ip)
+				ip = nil
+			case err != nil:
+				srv.log.Debug("External IP unavailable", "interface", srv.NAT, "err", err)
+			case !newip.Equal(ip):
+				if ip != nil {
+					srv.log.Info("External IP changed", "interface", srv.NAT, "old", ip, "new", newip)
+					srv.localnode.ClearStaticIP(ip)
+				}
+				srv.localnode.SetStaticIP(newip)
+				ip = newip
+			}
+			check.Reset(interval)
+
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
 func (srv *Server) setupDiscovery() error {
 	srv.discmix = enode.NewFairMix(discmixTimeout)
 
@@ -625,10 +748,32 @@ func (srv *Server) setupDiscovery() error {
 		if err != nil {
 			return err
 		}
+		srv.setupTopics()
 	}
 	return nil
 }
 
+// setupTopics advertises the topics of the protocols with discovery v5, and adds
+// the nodes advertising them as dial candidates.
+func (srv *Server) setupTopics() {
+	added := make(map[string]bool)
+	for _, proto := range srv.Protocols {
+		if proto.Topic == "" || added[proto.Topic] {
+			continue
+		}
+		added[proto.Topic] = true
+
+		topic := discover.NewTopic(proto.Topic)
+		srv.DiscV5.RegisterTopic(topic)
+		it := srv.DiscV5.TopicNodes(topic)
+		if proto.TopicFilter != nil {
+			it = enode.Filter(it, proto.TopicFilter)
+		}
+		srv.discmix.AddSource(it)
+		srv.log.Debug("Advertising discovery topic", "name", proto.Topic, "topic", topic)
+	}
+}
+
 func (srv *Server) setupDialScheduler() {
 	config := dialConfig{
 		self:           srv.localnode.ID(),
@@ -636,6 +781,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		diversity:      newPeerDiversity(srv.MaxPeersPerIP, srv.MaxPeersPerSubnet),
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -645,6 +791,9 @@ func (srv *Server) setupDialScheduler() {
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	if srv.quic != nil {
+		config.dialer = srv.quic.dialer(config.dialer, srv.log)
+	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
@@ -692,7 +841,32 @@ func (srv *Server) setupListening() error {
 	}
 
 	srv.loopWG.Add(1)
-	go srv.listenLoop()
+	go srv.listenLoop("TCP", srv.listener)
+	return nil
+}
+
+// setupQUIC launches the listener of the experimental QUIC transport.
+func (srv *Server) setupQUIC() error {
+	quic, err := listenQUIC(srv.QUICAddr)
+	if err != nil {
+		return err
+	}
+	srv.quic = quic
+	srv.QUICAddr = quic.listener().Addr().String()
+
+	// Announce the port in the local node record and map it if NAT is configured.
+	laddr := quic.listener().Addr().(*net.UDPAddr)
+	srv.localnode.Set(enr.QUIC(laddr.Port))
+	if !laddr.IP.IsLoopback() && srv.NAT != nil {
+		srv.loopWG.Add(1)
+		gopool.Submit(func() {
+			nat.Map(srv.NAT, srv.quit, "udp", laddr.Port, laddr.Port, "ethereum quic")
+			srv.loopWG.Done()
+		})
+	}
+
+	srv.loopWG.Add(1)
+	go srv.listenLoop("QUIC", quic.listener())
 	return nil
 }
 
@@ -772,6 +946,9 @@ running:
 				// The handshakes are done and it passed all checks.
 				p := srv.launchPeer(c)
 				peers[c.node.ID()] = p
+				if srv.diversity != nil {
+					srv.diversity.add(c.node.ID(), diversityIP(c))
+				}
 				srv.log.Debug("Adding p2p peer", "peercount", len(peers), "id", p.ID(), "conn", c.flags, "addr", p.RemoteAddr(), "name", p.Name())
 				srv.dialsched.peerAdded(c)
 				if p.Inbound() {
@@ -784,6 +961,10 @@ running:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
+			delete(srv.evicting, pd.ID())
+			if srv.diversity != nil {
+				srv.diversity.remove(pd.ID())
+			}
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
@@ -816,15 +997,23 @@ running:
 }
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
+	// The peers being evicted already made room for others
+	var (
+		total       = len(peers) - len(srv.evicting)
+		inbound     = inboundCount - len(srv.evicting)
+		inboundFull = !c.is(trustedConn) && c.is(inboundConn) && inbound >= srv.maxInboundConns()
+	)
 	switch {
-	case !c.is(trustedConn) && len(peers) >= srv.MaxPeers:
-		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+	case !c.is(trustedConn) && total >= srv.MaxPeers && !inboundFull:
 		return DiscTooManyPeers
 	case peers[c.node.ID()] != nil:
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case srv.diversity != nil && !srv.diversity.allowed(diversityIP(c)):
+		return DiscTooManyPeers
+	case inboundFull && !srv.evictInbound(peers):
+		return DiscTooManyPeers
 	default:
 		return nil
 	}
@@ -842,8 +1031,8 @@ func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *
 
 // listenLoop runs in its own goroutine and accepts
 // inbound connections.
-func (srv *Server) listenLoop() {
-	srv.log.Debug("TCP listener up", "addr", srv.listener.Addr())
+func (srv *Server) listenLoop(name string, listener net.Listener) {
+	srv.log.Debug(name+" listener up", "addr", listener.Addr())
 
 	// The slots channel limits accepts of new connections.
 	tokens := defaultMaxPendingPeers
@@ -874,7 +1063,7 @@ func (srv *Server) listenLoop() {
 			lastLog time.Time
 		)
 		for {
-			fd, err = srv.listener.Accept()
+			fd, err = listener.Accept()
 			if netutil.IsTemporaryError(err) {
 				if time.Since(lastLog) > 1*time.Second {
 					srv.log.Debug("Temporary read error", "err", err)
@@ -1009,9 +1198,11 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 func nodeFromConn(pubkey *ecdsa.PublicKey, conn net.Conn) *enode.Node {
 	var ip net.IP
 	var port int
-	if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-		ip = tcp.IP
-		port = tcp.Port
+	switch addr := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		ip, port = addr.IP, addr.Port
+	case *net.UDPAddr:
+		ip, port = addr.IP, addr.Port
 	}
 	return enode.NewV4(pubkey, ip, port, port)
 }
@@ -1034,6 +1225,7 @@ func (srv *Server) launchPeer(c *conn) *Peer {
 		// to the peer.
 		p.events = &srv.peerFeed
 	}
+	p.capture = srv.capture
 	gopool.Submit(func() {
 		srv.runPeer(p)
 	})
@@ -1059,6 +1251,9 @@ func (srv *Server) runPeer(p *Peer) {
 	// The main loop waits for existing peers to be sent on srv.delpeer
 	// before returning, so this send should not select on srv.quit.
 	srv.delpeer <- peerDrop{p, err, remoteRequested}
+	if srv.capture != nil {
+		srv.capture.dropped(p)
+	}
 
 	// Broadcast peer drop to external subscribers. This needs to be
 	// after the send to delpeer so subscribers have a consistent view of
@@ -1081,8 +1276,9 @@ type NodeInfo struct {
 	ENR   string `json:"enr"`   // Ethereum Node Record
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
-		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
-		Listener  int `json:"listener"`  // TCP listening port for RLPx
+		Discovery int `json:"discovery"`      // UDP listening port for discovery protocol
+		Listener  int `json:"listener"`       // TCP listening port for RLPx
+		QUIC      int `json:"quic,omitempty"` // UDP listening port for RLPx over QUIC
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
@@ -1102,6 +1298,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = node.UDP()
 	info.Ports.Listener = node.TCP()
+	info.Ports.QUIC = node.QUIC()
 	info.ENR = node.String()
 
 	// Gather all the running protocol infos (only once per protocol type)
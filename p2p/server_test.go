@@ -24,6 +24,7 @@ import (
 	"math/rand"
 	"net"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -323,6 +324,129 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+// This test checks that the peers per IP address and network are limited, except
+// for the trusted and LAN ones.
+func TestServerPeerDiversity(t *testing.T) {
+	trustedNode := newkey()
+	trustedID := enode.PubkeyToIDV4(&trustedNode.PublicKey)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:        newkey(),
+			MaxPeers:          10,
+			MaxPeersPerIP:     1,
+			MaxPeersPerSubnet: 2,
+			NoDial:            true,
+			NoDiscovery:       true,
+			TrustedNodes:      []*enode.Node{newNode(trustedID, "")},
+			Logger:            testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID, ip string) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&trustedNode.PublicKey, fd, nil)
+		var r enr.Record
+		r.Set(enr.IP(net.ParseIP(ip)))
+		node := enode.SignNull(&r, id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	tests := []struct {
+		id   enode.ID
+		ip   string
+		want error
+	}{
+		{randomID(), "1.2.3.4", nil},
+		{randomID(), "1.2.3.4", DiscTooManyPeers},
+		{randomID(), "1.2.3.5", nil},
+		{randomID(), "1.2.3.6", DiscTooManyPeers},
+		{randomID(), "1.2.4.1", nil},
+		{randomID(), "2001:db8::1", nil},
+		{randomID(), "2001:db8:0:1::1", nil},
+		{randomID(), "2001:db8:0:2::1", DiscTooManyPeers},
+		{randomID(), "10.0.0.1", nil},
+		{randomID(), "10.0.0.1", nil},
+		{trustedID, "1.2.3.4", nil},
+	}
+	for i, test := range tests {
+		c := newconn(test.id, test.ip)
+		err := srv.checkpoint(c, srv.checkpointPostHandshake)
+		if err == nil {
+			err = srv.checkpoint(c, srv.checkpointAddPeer)
+		}
+		if err != test.want {
+			t.Errorf("conn %d from %s: error mismatch: have %v, want %v", i, test.ip, err, test.want)
+		}
+	}
+	// Disconnect the first peer and check its network has room again
+	for _, p := range srv.Peers() {
+		if p.ID() == tests[0].id {
+			p.Disconnect(DiscRequested)
+		}
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if err := srv.checkpoint(newconn(randomID(), "1.2.3.6"), srv.checkpointPostHandshake); err == nil {
+			break
+		} else if time.Since(start) > time.Second {
+			t.Fatalf("network still full after disconnect: %v", err)
+		}
+	}
+}
+
+// This test checks that an inbound peer is evicted for a new inbound connection
+// when the inbound slots are full, sparing the protected ones.
+func TestServerInboundEviction(t *testing.T) {
+	remote := newkey()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:     newkey(),
+			MaxPeers:       4,
+			ProtectedPeers: 1,
+			NoDial:         true,
+			NoDiscovery:    true,
+			Logger:         testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID, ip string) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remote.PublicKey, fd, nil)
+		var r enr.Record
+		r.Set(enr.IP(net.ParseIP(ip)))
+		node := enode.SignNull(&r, id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	// Fill the inbound slots, the first one is protected and the youngest of the
+	// best represented network is to be evicted
+	ids := []enode.ID{randomID(), randomID(), randomID(), randomID()}
+	for i, ip := range []string{"1.1.1.1", "5.5.5.1", "5.5.5.2", "7.7.7.7"} {
+		if err := srv.checkpoint(newconn(ids[i], ip), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := srv.checkpoint(newconn(randomID(), "9.9.9.9"), srv.checkpointAddPeer); err != nil {
+		t.Fatalf("inbound conn not accepted: %v", err)
+	}
+	for start := time.Now(); srv.PeerCount() != 4; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatalf("peer count mismatch: have %d, want %d", srv.PeerCount(), 4)
+		}
+	}
+	for _, p := range srv.Peers() {
+		if p.ID() == ids[2] {
+			t.Errorf("wrong peer evicted")
+		}
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -386,6 +510,16 @@ func TestServerPeerLimits(t *testing.T) {
 		t.Errorf("unexpected close error: %q", tp.closeErr)
 	}
 	conn.Close()
+
+	srv.SetMaxPeers(1)
+
+	// Check that raising the limit makes room for the peer.
+	conn, _ = net.Pipe()
+	srv.SetupConn(conn, flags, dialDest)
+	if tp.closeErr != DiscUselessPeer {
+		t.Errorf("unexpected close error: %q", tp.closeErr)
+	}
+	conn.Close()
 }
 
 func TestServerSetupConn(t *testing.T) {
@@ -527,6 +661,66 @@ func randomID() (id enode.ID) {
 }
 
 // This test checks that inbound connections are throttled by IP.
+// rotatingNAT is a NAT mechanism whose external IP can be changed.
+type rotatingNAT struct {
+	mu sync.Mutex
+	ip net.IP
+}
+
+func (n *rotatingNAT) AddMapping(string, int, int, string, time.Duration) error { return nil }
+func (n *rotatingNAT) DeleteMapping(string, int, int) error                     { return nil }
+func (n *rotatingNAT) String() string                                           { return "rotating" }
+
+func (n *rotatingNAT) ExternalIP() (net.IP, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ip == nil {
+		return nil, errors.New("unavailable")
+	}
+	return n.ip, nil
+}
+
+func (n *rotatingNAT) set(ip net.IP) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ip = ip
+}
+
+// This test checks that the IP of the local node record follows the external IP
+// changing, and falls back to the predicted one while it is unavailable.
+func TestServerNATRecheck(t *testing.T) {
+	mech := &rotatingNAT{ip: net.IP{77, 12, 33, 4}}
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			NAT:         mech,
+			NATRecheck:  10 * time.Millisecond,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	waitIP := func(want net.IP) {
+		t.Helper()
+		for start := time.Now(); !srv.Self().IP().Equal(want); time.Sleep(5 * time.Millisecond) {
+			if time.Since(start) > time.Second {
+				t.Fatalf("IP mismatch: have %v, want %v", srv.Self().IP(), want)
+			}
+		}
+	}
+	waitIP(net.IP{77, 12, 33, 4})
+	mech.set(net.IP{77, 12, 33, 5})
+	waitIP(net.IP{77, 12, 33, 5})
+	mech.set(nil)
+	waitIP(net.IP{127, 0, 0, 1})
+}
+
 func TestServerInboundThrottle(t *testing.T) {
 	const timeout = 5 * time.Second
 	newTransportCalled := make(chan struct{})
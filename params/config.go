@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"golang.org/x/crypto/sha3"
 
@@ -359,6 +360,12 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		new(EthashConfig),
 		nil, nil,
 	}
@@ -393,6 +400,12 @@ var (
 		big.NewInt(0),
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		&CliqueConfig{Period: 0, Epoch: 30000},
 		nil,
 	}
@@ -421,6 +434,12 @@ var (
 		big.NewInt(0),
 		big.NewInt(0),
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		new(EthashConfig),
 		nil, nil,
 	}
@@ -518,12 +537,152 @@ type ChainConfig struct {
 	MirrorSyncBlock   *big.Int `json:"mirrorSyncBlock,omitempty" toml:",omitempty"` // mirrorSyncBlock switch block (nil = no fork, 0 = already activated)
 	BrunoBlock        *big.Int `json:"brunoBlock,omitempty" toml:",omitempty"`      // brunoBlock switch block (nil = no fork, 0 = already activated)
 	BlockRewardsBlock *big.Int `json:"blockRewardsBlock,omitempty" toml:",omitempty"`
+	CancunBlock       *big.Int `json:"cancunBlock,omitempty" toml:",omitempty"`     // EIP-4844 blob transactions switch block (nil = no fork, 0 = already activated)
+	PragueBlock       *big.Int `json:"pragueBlock,omitempty" toml:",omitempty"`     // EIP-7702 set-code transactions switch block (nil = no fork, 0 = already activated)
+	P256VerifyBlock   *big.Int `json:"p256VerifyBlock,omitempty" toml:",omitempty"` // RIP-7212 secp256r1 verification precompile switch block (nil = no fork, 0 = already activated)
+	BaseFeeBlock      *big.Int `json:"baseFeeBlock,omitempty" toml:",omitempty"`    // EIP-1559 base fee switch block (nil = no fork, 0 = already activated)
+
+	BaseFee *BaseFeeConfig `json:"baseFee,omitempty" toml:",omitempty"` // Base fee parameters, the EIP-1559 ones if unset
+
+	Precompiles []*PrecompileConfig `json:"precompiles,omitempty" toml:",omitempty"` // Custom precompiled contracts activated at hard forks
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty" toml:",omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty" toml:",omitempty"`
 	Parlia *ParliaConfig `json:"parlia,omitempty" toml:",omitempty"`
 }
 
+// BaseFeeConfig holds the parameters of the base fee. Unset ones take the values
+// of EIP-1559.
+type BaseFeeConfig struct {
+	InitialBaseFee       uint64          `json:"initialBaseFee,omitempty"`       // Base fee of the fork block
+	MinBaseFee           uint64          `json:"minBaseFee,omitempty"`           // Lower bound of the base fee
+	ChangeDenominator    uint64          `json:"changeDenominator,omitempty"`    // Bounds the change of the base fee between blocks
+	ElasticityMultiplier uint64          `json:"elasticityMultiplier,omitempty"` // Gas limit divided by the gas target
+	Recipient            *common.Address `json:"recipient,omitempty"`            // Receives the base fees instead of them being burnt
+}
+
+// String implements the stringer interface.
+func (c *BaseFeeConfig) String() string {
+	recipient := "burnt"
+	if c.Recipient != nil {
+		recipient = c.Recipient.Hex()
+	}
+	return fmt.Sprintf("{initial: %d min: %d denominator: %d elasticity: %d recipient: %s}",
+		c.InitialBaseFee, c.MinBaseFee, c.ChangeDenominator, c.ElasticityMultiplier, recipient)
+}
+
+// InitialBaseFee returns the base fee of the block activating the base fee.
+func (c *ChainConfig) InitialBaseFee() uint64 {
+	if c.BaseFee != nil && c.BaseFee.InitialBaseFee != 0 {
+		return c.BaseFee.InitialBaseFee
+	}
+	return InitialBaseFee
+}
+
+// MinBaseFee returns the lower bound of the base fee.
+func (c *ChainConfig) MinBaseFee() uint64 {
+	if c.BaseFee != nil {
+		return c.BaseFee.MinBaseFee
+	}
+	return 0
+}
+
+// BaseFeeChangeDenominator bounds the amount the base fee can change between
+// blocks.
+func (c *ChainConfig) BaseFeeChangeDenominator() uint64 {
+	if c.BaseFee != nil && c.BaseFee.ChangeDenominator != 0 {
+		return c.BaseFee.ChangeDenominator
+	}
+	return BaseFeeChangeDenominator
+}
+
+// ElasticityMultiplier bounds the gas used of a block relative to its gas target.
+func (c *ChainConfig) ElasticityMultiplier() uint64 {
+	if c.BaseFee != nil && c.BaseFee.ElasticityMultiplier != 0 {
+		return c.BaseFee.ElasticityMultiplier
+	}
+	return ElasticityMultiplier
+}
+
+// BaseFeeRecipient returns the account receiving the base fees, nil if they are
+// burnt.
+func (c *ChainConfig) BaseFeeRecipient() *common.Address {
+	if c.BaseFee != nil {
+		return c.BaseFee.Recipient
+	}
+	return nil
+}
+
+// PrecompileConfig activates a custom precompiled contract from a given block on.
+// The contract is implemented by the one registered with the EVM under the given
+// name.
+type PrecompileConfig struct {
+	Block          *big.Int       `json:"block"`          // First block the contract is active at
+	Address        common.Address `json:"address"`        // Address the contract is called at
+	Implementation string         `json:"implementation"` // Name the implementation is registered under
+	Gas            *PrecompileGas `json:"gas,omitempty"`  // Gas schedule replacing the one of the implementation
+}
+
+// PrecompileGas is a gas schedule linear in the size of the input.
+type PrecompileGas struct {
+	Base    uint64 `json:"base"`
+	PerWord uint64 `json:"perWord"` // Gas per 32 byte word of input, partial words rounded up
+}
+
+// activePrecompiles returns the custom precompiled contracts active at the given
+// block by address, nil if none.
+func (c *ChainConfig) activePrecompiles(num *big.Int) map[common.Address]*PrecompileConfig {
+	var active map[common.Address]*PrecompileConfig
+	for _, precompile := range c.Precompiles {
+		if !isForked(precompile.Block, num) {
+			continue
+		}
+		if active == nil {
+			active = make(map[common.Address]*PrecompileConfig)
+		}
+		active[precompile.Address] = precompile
+	}
+	return active
+}
+
+// checkPrecompiles checks that the custom precompiled contracts are complete and
+// at distinct addresses.
+func (c *ChainConfig) checkPrecompiles() error {
+	seen := make(map[common.Address]bool)
+	for i, precompile := range c.Precompiles {
+		if precompile.Block == nil {
+			return fmt.Errorf("precompile %d has no block", i)
+		}
+		if precompile.Implementation == "" {
+			return fmt.Errorf("precompile %d has no implementation", i)
+		}
+		if seen[precompile.Address] {
+			return fmt.Errorf("precompile %d at %v: duplicate address", i, precompile.Address)
+		}
+		seen[precompile.Address] = true
+	}
+	return nil
+}
+
+// isPrecompileIncompatible returns the first block below head at which the two
+// configs activate different custom precompiled contracts, or nil if they match
+// up to it.
+func isPrecompileIncompatible(c1, c2 *ChainConfig, head *big.Int) *big.Int {
+	var first *big.Int
+	for _, config := range []*ChainConfig{c1, c2} {
+		for _, precompile := range config.Precompiles {
+			block := precompile.Block
+			if !isForked(block, head) || (first != nil && first.Cmp(block) <= 0) {
+				continue
+			}
+			if !reflect.DeepEqual(c1.activePrecompiles(block), c2.activePrecompiles(block)) {
+				first = block
+			}
+		}
+	}
+	return first
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
 type EthashConfig struct{}
 
@@ -548,6 +707,93 @@ type ParliaConfig struct {
 	Period       uint64   `json:"period"`       // Number of seconds between blocks to enforce
 	Epoch        uint64   `json:"epoch"`        // Epoch length to update validatorSet
 	BlockRewards *big.Int `json:"blockRewards"` // Block rewards to be paid for each produced block
+
+	PeriodForks []*ParliaPeriodFork `json:"periodForks,omitempty"` // Block interval changes scheduled at hard forks, in ascending order
+}
+
+// ParliaPeriodFork changes the block interval of parlia from a given block on.
+// Once any period fork is active, block timestamps gain millisecond precision.
+type ParliaPeriodFork struct {
+	Block      *big.Int `json:"block"`                // First block sealed with the new interval
+	PeriodMs   uint64   `json:"periodMs"`             // Number of milliseconds between blocks to enforce
+	TurnLength uint64   `json:"turnLength,omitempty"` // Number of consecutive blocks sealed by the in-turn validator (0 = 1)
+}
+
+// periodFork returns the period fork active at the given block, or nil if the
+// legacy period in seconds still applies.
+func (b *ParliaConfig) periodFork(num *big.Int) *ParliaPeriodFork {
+	var active *ParliaPeriodFork
+	for _, fork := range b.PeriodForks {
+		if isForked(fork.Block, num) {
+			active = fork
+		}
+	}
+	return active
+}
+
+// IsMilliPeriod returns whether num is covered by a period fork, switching the
+// block timestamps to millisecond precision.
+func (b *ParliaConfig) IsMilliPeriod(num *big.Int) bool {
+	return b.periodFork(num) != nil
+}
+
+// PeriodMs returns the number of milliseconds between blocks at the given block.
+func (b *ParliaConfig) PeriodMs(num *big.Int) uint64 {
+	if fork := b.periodFork(num); fork != nil {
+		return fork.PeriodMs
+	}
+	return b.Period * 1000
+}
+
+// TurnLength returns the number of consecutive blocks the in-turn validator
+// seals at the given block.
+func (b *ParliaConfig) TurnLength(num *big.Int) uint64 {
+	if fork := b.periodFork(num); fork != nil && fork.TurnLength > 1 {
+		return fork.TurnLength
+	}
+	return 1
+}
+
+// checkPeriodForks verifies that the period forks are ordered and take effect
+// at epoch boundaries, where every validator switches its schedule at once.
+func (b *ParliaConfig) checkPeriodForks() error {
+	var last *big.Int
+	for i, fork := range b.PeriodForks {
+		if fork.Block == nil {
+			return fmt.Errorf("parlia period fork %d has no block", i)
+		}
+		if last != nil && last.Cmp(fork.Block) >= 0 {
+			return fmt.Errorf("unsupported parlia period fork ordering: fork at %v after fork at %v", fork.Block, last)
+		}
+		if b.Epoch != 0 && new(big.Int).Mod(fork.Block, new(big.Int).SetUint64(b.Epoch)).Sign() != 0 {
+			return fmt.Errorf("parlia period fork at %v not on an epoch boundary (epoch %d)", fork.Block, b.Epoch)
+		}
+		last = fork.Block
+	}
+	return nil
+}
+
+// isPeriodForkIncompatible returns the first block below head at which the
+// two period schedules disagree, or nil if they match up to it.
+func isPeriodForkIncompatible(c1, c2 *ParliaConfig, head *big.Int) *big.Int {
+	var blocks []*big.Int
+	for _, fork := range c1.PeriodForks {
+		blocks = append(blocks, fork.Block)
+	}
+	for _, fork := range c2.PeriodForks {
+		blocks = append(blocks, fork.Block)
+	}
+	var first *big.Int
+	for _, block := range blocks {
+		if !isForked(block, head) || (first != nil && first.Cmp(block) <= 0) {
+			continue
+		}
+		if c1.PeriodMs(block) != c2.PeriodMs(block) || c1.TurnLength(block) != c2.TurnLength(block) ||
+			c1.IsMilliPeriod(block) != c2.IsMilliPeriod(block) {
+			first = block
+		}
+	}
+	return first
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -568,7 +814,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Ramanujan: %v, Niels: %v, MirrorSync: %v, Bruno: %v, Berlin: %v, YOLO v3: %v, Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v, Muir Glacier: %v, Ramanujan: %v, Niels: %v, MirrorSync: %v, Bruno: %v, Berlin: %v, YOLO v3: %v, Cancun: %v, Prague: %v, P256Verify: %v, BaseFee: %v, Engine: %v}",
 		c.ChainID,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -587,6 +833,10 @@ func (c *ChainConfig) String() string {
 		c.BrunoBlock,
 		c.BerlinBlock,
 		c.YoloV3Block,
+		c.CancunBlock,
+		c.PragueBlock,
+		c.P256VerifyBlock,
+		c.BaseFeeBlock,
 		engine,
 	)
 }
@@ -702,6 +952,28 @@ func (c *ChainConfig) IsBlockRewardsBlock(num *big.Int) bool {
 	return isForked(c.BlockRewardsBlock, num)
 }
 
+// IsCancun returns whether num is either equal to the Cancun fork block or greater.
+func (c *ChainConfig) IsCancun(num *big.Int) bool {
+	return isForked(c.CancunBlock, num)
+}
+
+// IsPrague returns whether num is either equal to the Prague fork block or greater.
+func (c *ChainConfig) IsPrague(num *big.Int) bool {
+	return isForked(c.PragueBlock, num)
+}
+
+// IsP256Verify returns whether num is either equal to the block activating the
+// secp256r1 verification precompile or greater.
+func (c *ChainConfig) IsP256Verify(num *big.Int) bool {
+	return isForked(c.P256VerifyBlock, num)
+}
+
+// IsBaseFee returns whether num is either equal to the block activating the base
+// fee or greater.
+func (c *ChainConfig) IsBaseFee(num *big.Int) bool {
+	return isForked(c.BaseFeeBlock, num)
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -733,6 +1005,8 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "mirrorSyncBlock", block: c.MirrorSyncBlock},
 		{name: "brunoBlock", block: c.BrunoBlock},
 		{name: "berlinBlock", block: c.BerlinBlock},
+		{name: "cancunBlock", block: c.CancunBlock, optional: true},
+		{name: "pragueBlock", block: c.PragueBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -752,6 +1026,12 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if err := c.checkPrecompiles(); err != nil {
+		return err
+	}
+	if c.Parlia != nil {
+		return c.Parlia.checkPeriodForks()
+	}
 	return nil
 }
 
@@ -814,6 +1094,26 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.BrunoBlock, newcfg.BrunoBlock, head) {
 		return newCompatError("bruno fork block", c.BrunoBlock, newcfg.BrunoBlock)
 	}
+	if isForkIncompatible(c.CancunBlock, newcfg.CancunBlock, head) {
+		return newCompatError("cancun fork block", c.CancunBlock, newcfg.CancunBlock)
+	}
+	if isForkIncompatible(c.PragueBlock, newcfg.PragueBlock, head) {
+		return newCompatError("prague fork block", c.PragueBlock, newcfg.PragueBlock)
+	}
+	if isForkIncompatible(c.P256VerifyBlock, newcfg.P256VerifyBlock, head) {
+		return newCompatError("p256Verify fork block", c.P256VerifyBlock, newcfg.P256VerifyBlock)
+	}
+	if isForkIncompatible(c.BaseFeeBlock, newcfg.BaseFeeBlock, head) {
+		return newCompatError("base fee fork block", c.BaseFeeBlock, newcfg.BaseFeeBlock)
+	}
+	if c.Parlia != nil && newcfg.Parlia != nil {
+		if block := isPeriodForkIncompatible(c.Parlia, newcfg.Parlia, head); block != nil {
+			return newCompatError("parlia period fork block", block, block)
+		}
+	}
+	if block := isPrecompileIncompatible(c, newcfg, head); block != nil {
+		return newCompatError("precompile fork block", block, block)
+	}
 	return nil
 }
 
@@ -881,13 +1181,16 @@ type Rules struct {
 	ChainID                                                 *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158               bool
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
-	IsBerlin, IsCatalyst                                    bool
+	IsBerlin, IsCatalyst, IsCancun, IsPrague                bool
+	IsP256Verify, IsBaseFee                                 bool
 	HasBlockRewards                                         bool
 	// features
 	HasRuntimeUpgrade    bool
 	HasDeployOrigin      bool
 	HasDeploymentHookFix bool
 	DeployerFactory      bool
+
+	Precompiles map[common.Address]*PrecompileConfig // Custom precompiled contracts active, nil if none
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -908,11 +1211,16 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsIstanbul:       c.IsIstanbul(num),
 		IsBerlin:         c.IsBerlin(num),
 		IsCatalyst:       c.IsCatalyst(num),
+		IsCancun:         c.IsCancun(num),
+		IsPrague:         c.IsPrague(num),
+		IsP256Verify:     c.IsP256Verify(num),
+		IsBaseFee:        c.IsBaseFee(num),
 		HasBlockRewards:  c.IsBlockRewardsBlock(num),
 		// features
 		HasRuntimeUpgrade:    isForked(c.RuntimeUpgradeBlock, num),
 		HasDeployOrigin:      isForked(c.DeployOriginBlock, num),
 		HasDeploymentHookFix: isForked(c.DeploymentHookFixBlock, num),
 		DeployerFactory:      isForked(c.DeployerFactoryBlock, num),
+		Precompiles:          c.activePrecompiles(num),
 	}
 }
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -96,3 +98,118 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestParliaPeriodForks(t *testing.T) {
+	config := &ParliaConfig{
+		Period: 3,
+		Epoch:  200,
+		PeriodForks: []*ParliaPeriodFork{
+			{Block: big.NewInt(1000), PeriodMs: 1500, TurnLength: 4},
+			{Block: big.NewInt(2000), PeriodMs: 750},
+		},
+	}
+	if err := config.checkPeriodForks(); err != nil {
+		t.Fatalf("valid schedule rejected: %v", err)
+	}
+	tests := []struct {
+		number     int64
+		milli      bool
+		periodMs   uint64
+		turnLength uint64
+	}{
+		{999, false, 3000, 1},
+		{1000, true, 1500, 4},
+		{1999, true, 1500, 4},
+		{2000, true, 750, 1},
+	}
+	for _, tt := range tests {
+		num := big.NewInt(tt.number)
+		if milli := config.IsMilliPeriod(num); milli != tt.milli {
+			t.Errorf("block %d: milli period mismatch: have %v, want %v", tt.number, milli, tt.milli)
+		}
+		if period := config.PeriodMs(num); period != tt.periodMs {
+			t.Errorf("block %d: period mismatch: have %d, want %d", tt.number, period, tt.periodMs)
+		}
+		if turn := config.TurnLength(num); turn != tt.turnLength {
+			t.Errorf("block %d: turn length mismatch: have %d, want %d", tt.number, turn, tt.turnLength)
+		}
+	}
+	// Forks off the epoch boundaries or out of order must be rejected
+	for _, forks := range [][]*ParliaPeriodFork{
+		{{Block: big.NewInt(1001), PeriodMs: 1500}},
+		{{Block: big.NewInt(2000), PeriodMs: 1500}, {Block: big.NewInt(1000), PeriodMs: 750}},
+		{{PeriodMs: 1500}},
+	} {
+		if err := (&ParliaConfig{Epoch: 200, PeriodForks: forks}).checkPeriodForks(); err == nil {
+			t.Errorf("invalid schedule accepted: %v", forks)
+		}
+	}
+}
+
+func TestParliaPeriodForkCompatible(t *testing.T) {
+	stored := &ChainConfig{Parlia: &ParliaConfig{Period: 3, Epoch: 200, PeriodForks: []*ParliaPeriodFork{
+		{Block: big.NewInt(1000), PeriodMs: 1500},
+	}}}
+	moved := &ChainConfig{Parlia: &ParliaConfig{Period: 3, Epoch: 200, PeriodForks: []*ParliaPeriodFork{
+		{Block: big.NewInt(1200), PeriodMs: 1500},
+	}}}
+	if err := stored.CheckCompatible(moved, 999); err != nil {
+		t.Errorf("rescheduling a future fork failed: %v", err)
+	}
+	err := stored.CheckCompatible(moved, 1100)
+	if err == nil || err.RewindTo != 999 {
+		t.Errorf("rescheduling a past fork accepted or wrong rewind: %v", err)
+	}
+}
+
+func TestPrecompiles(t *testing.T) {
+	var (
+		bls  = &PrecompileConfig{Block: big.NewInt(10), Address: common.HexToAddress("0x0100"), Implementation: "bls12381Pairing"}
+		p256 = &PrecompileConfig{Block: big.NewInt(20), Address: common.HexToAddress("0x0101"), Implementation: "p256Verify"}
+	)
+	config := &ChainConfig{Precompiles: []*PrecompileConfig{bls, p256}}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("valid precompiles rejected: %v", err)
+	}
+	for _, tt := range []struct {
+		number uint64
+		want   map[common.Address]*PrecompileConfig
+	}{
+		{9, nil},
+		{10, map[common.Address]*PrecompileConfig{bls.Address: bls}},
+		{25, map[common.Address]*PrecompileConfig{bls.Address: bls, p256.Address: p256}},
+	} {
+		if have := config.Rules(new(big.Int).SetUint64(tt.number)).Precompiles; !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("block #%d: active precompiles mismatch: have %v, want %v", tt.number, have, tt.want)
+		}
+	}
+	for i, invalid := range [][]*PrecompileConfig{
+		{{Address: bls.Address, Implementation: "p256Verify"}},
+		{{Block: big.NewInt(1), Address: bls.Address}},
+		{bls, {Block: big.NewInt(30), Address: bls.Address, Implementation: "p256Verify"}},
+	} {
+		if err := (&ChainConfig{Precompiles: invalid}).CheckConfigForkOrder(); err == nil {
+			t.Errorf("invalid precompiles %d accepted", i)
+		}
+	}
+}
+
+func TestPrecompileCompatible(t *testing.T) {
+	address := common.HexToAddress("0x0100")
+	stored := &ChainConfig{Precompiles: []*PrecompileConfig{{Block: big.NewInt(1000), Address: address, Implementation: "p256Verify"}}}
+	moved := &ChainConfig{Precompiles: []*PrecompileConfig{{Block: big.NewInt(1200), Address: address, Implementation: "p256Verify"}}}
+	repriced := &ChainConfig{Precompiles: []*PrecompileConfig{{Block: big.NewInt(1000), Address: address, Implementation: "p256Verify", Gas: &PrecompileGas{Base: 1}}}}
+
+	if err := stored.CheckCompatible(moved, 999); err != nil {
+		t.Errorf("rescheduling a future precompile failed: %v", err)
+	}
+	if err := stored.CheckCompatible(moved, 1100); err == nil || err.RewindTo != 999 {
+		t.Errorf("rescheduling an active precompile accepted or wrong rewind: %v", err)
+	}
+	if err := stored.CheckCompatible(repriced, 1100); err == nil || err.RewindTo != 999 {
+		t.Errorf("repricing an active precompile accepted or wrong rewind: %v", err)
+	}
+	if err := stored.CheckCompatible(&ChainConfig{}, 1100); err == nil {
+		t.Error("dropping an active precompile accepted")
+	}
+}
@@ -80,6 +80,19 @@ const (
 	TxAccessListAddressGas    uint64 = 2400 // Per address specified in EIP 2930 access list
 	TxAccessListStorageKeyGas uint64 = 1900 // Per storage key specified in EIP 2930 access list
 
+	BlobTxBlobGasPerBlob  uint64 = 1 << 17                  // Gas consumption of a single data blob (== blob byte size)
+	MaxBlobGasPerBlock    uint64 = 6 * BlobTxBlobGasPerBlob // Maximum consumable blob gas for data blobs per block
+	BlobTxMinBlobGasprice uint64 = 1                        // Minimum gas price for data blobs
+	BlobTxHashVersion     byte   = 0x01                     // Version byte of the blob commitment hashes
+	BlobHashGas           uint64 = 3                        // Gas cost of the BLOBHASH opcode
+
+	BaseFeeChangeDenominator uint64 = 8          // Bounds the amount the base fee can change between blocks
+	ElasticityMultiplier     uint64 = 2          // Bounds the gas used of a block relative to its gas target
+	InitialBaseFee           uint64 = 1000000000 // Base fee of the block activating the base fee
+
+	TxAuthTupleGas        uint64 = 12500 // Per authorization tuple specified in an EIP-7702 set-code transaction
+	TxAuthEmptyAccountGas uint64 = 25000 // Per authorization tuple whose authority doesn't exist yet, refunded down to TxAuthTupleGas otherwise
+
 	// These have been changed during the course of the chain
 	CallGasFrontier              uint64 = 40  // Once per CALL operation & message call transaction.
 	CallGasEIP150                uint64 = 700 // Static portion of gas for CALL-derivates after EIP 150 (Tangerine)
@@ -138,6 +151,8 @@ const (
 	Bls12381PairingPerPairGas uint64 = 23000  // Per-point pair gas price for BLS12-381 elliptic curve pairing check
 	Bls12381MapG1Gas          uint64 = 5500   // Gas price for BLS12-381 mapping field element to G1 operation
 	Bls12381MapG2Gas          uint64 = 110000 // Gas price for BLS12-381 mapping field element to G2 operation
+
+	P256VerifyGas uint64 = 3450 // Gas price for secp256r1 signature verification (RIP-7212)
 )
 
 // Gas discount table for BLS12-381 G1 and G2 multi exponentiation operations
@@ -54,6 +54,9 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(responseTooLargeError)
+	_ Error = new(budgetExceededError)
+	_ Error = new(timeoutError)
 )
 
 const defaultErrorCode = -32000
@@ -95,6 +98,27 @@ func (e *invalidMessageError) ErrorCode() int { return -32700 }
 
 func (e *invalidMessageError) Error() string { return e.message }
 
+// aggregate response of a batch exceeds the configured limit
+type responseTooLargeError struct{}
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string { return "batch response too large" }
+
+// client has spent its compute unit budget
+type budgetExceededError struct{}
+
+func (e *budgetExceededError) ErrorCode() int { return -32005 }
+
+func (e *budgetExceededError) Error() string { return "compute unit budget exceeded" }
+
+// method did not complete within its execution deadline
+type timeoutError struct{ method string }
+
+func (e *timeoutError) ErrorCode() int { return -32002 }
+
+func (e *timeoutError) Error() string { return fmt.Sprintf("%s timed out", e.method) }
+
 // unable to decode supplied params, or an invalid number of parameters
 type invalidParamsError struct{ message string }
 
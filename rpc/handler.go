@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -28,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/gopool"
 
 	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/time/rate"
 )
 
 // handler handles JSON-RPC messages. There is one handler per connection. Note that
@@ -66,6 +68,20 @@ type handler struct {
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
+	subPending int // subscriptions being created, counted against the limit
+
+	limits    ConnLimits
+	notifyCh  chan *jsonrpcMessage // queued notifications, nil if written synchronously
+	notifyLim *rate.Limiter        // notification throughput limiter, nil if unlimited
+	dropOnce  sync.Once
+
+	batch BatchLimits
+
+	meter  *meter     // nil if calls are not metered
+	client string     // identity the calls of this connection are metered under
+	policy ConnPolicy // nil if calls are unrestricted
+
+	tracing *TracingConfig // nil if calls are not traced
 }
 
 type callProc struct {
@@ -73,7 +89,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, cfg handlerConfig) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -86,10 +102,27 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		limits:         cfg.limits,
+		batch:          cfg.batch,
+		meter:          cfg.meter,
+		tracing:        cfg.tracing,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
 	}
+	if h.meter != nil {
+		h.client = clientKey(conn)
+	}
+	if c, ok := conn.(interface{ connPolicy() ConnPolicy }); ok {
+		h.policy = c.connPolicy()
+	}
+	if h.limits.MaxBytesPerSecond > 0 {
+		h.notifyLim = rate.NewLimiter(rate.Limit(h.limits.MaxBytesPerSecond), h.limits.MaxBytesPerSecond)
+	}
+	if h.limits.MaxPendingNotifications > 0 {
+		h.notifyCh = make(chan *jsonrpcMessage, h.limits.MaxPendingNotifications)
+		go h.notifyLoop()
+	}
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -103,6 +136,13 @@ func (h *handler) handleBatch(ctx context.Context, msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if limit := h.batch.MaxItems; limit > 0 && len(msgs) > limit {
+		h.startCallProc(func(cp *callProc) {
+			err := &invalidRequestError{fmt.Sprintf("batch too large: %d items, limit is %d", len(msgs), limit)}
+			h.conn.writeJSON(cp.ctx, errorMessage(err))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -116,12 +156,7 @@ func (h *handler) handleBatch(ctx context.Context, msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, ctx, msg); answer != nil {
-				answers = append(answers, answer)
-			}
-		}
+		answers := h.handleBatchCalls(cp, ctx, calls)
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
 			h.conn.writeJSON(cp.ctx, answers)
@@ -132,6 +167,66 @@ func (h *handler) handleBatch(ctx context.Context, msgs []*jsonrpcMessage) {
 	})
 }
 
+// handleBatchCalls executes the calls of a batch and returns their answers in order.
+// Up to BatchLimits.Parallelism calls are executed concurrently. Once the aggregate
+// result size exceeds BatchLimits.MaxResponseSize, the remaining calls are answered
+// with an error instead of being executed.
+func (h *handler) handleBatchCalls(cp *callProc, ctx context.Context, calls []*jsonrpcMessage) []*jsonrpcMessage {
+	var (
+		results   = make([]*jsonrpcMessage, len(calls))
+		notifiers = make([][]*Notifier, len(calls))
+		sem       chan struct{}
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		size      int
+	)
+	if h.batch.Parallelism > 1 {
+		sem = make(chan struct{}, h.batch.Parallelism)
+	}
+	run := func(i int) {
+		proc := &callProc{ctx: cp.ctx}
+		answer := h.handleCallMsg(proc, ctx, calls[i])
+		if answer != nil {
+			mu.Lock()
+			size += len(answer.Result)
+			mu.Unlock()
+		}
+		results[i], notifiers[i] = answer, proc.notifiers
+	}
+	for i, msg := range calls {
+		mu.Lock()
+		exceeded := h.batch.MaxResponseSize > 0 && size > h.batch.MaxResponseSize
+		mu.Unlock()
+		if exceeded {
+			if msg.hasValidID() {
+				results[i] = msg.errorResponse(&responseTooLargeError{})
+			}
+			continue
+		}
+		if sem == nil {
+			run(i)
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	answers := make([]*jsonrpcMessage, 0, len(calls))
+	for i, answer := range results {
+		if answer != nil {
+			answers = append(answers, answer)
+		}
+		cp.notifiers = append(cp.notifiers, notifiers[i]...)
+	}
+	return answers
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(ctx context.Context, msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -199,6 +294,7 @@ func (h *handler) addSubscriptions(nn []*Notifier) {
 	h.subLock.Lock()
 	defer h.subLock.Unlock()
 
+	h.subPending -= len(nn)
 	for _, n := range nn {
 		if sub := n.takeSubscription(); sub != nil {
 			h.serverSubs[sub.ID] = sub
@@ -206,6 +302,29 @@ func (h *handler) addSubscriptions(nn []*Notifier) {
 	}
 }
 
+// reserveSubscription reserves a slot for a subscription being created, failing
+// if the connection reached its subscription limit. The slot is released when the
+// notifier of the subscription is passed to addSubscriptions, or by calling
+// releaseSubscription if the subscribe call fails before creating one.
+func (h *handler) reserveSubscription() bool {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	if limit := h.limits.MaxSubscriptions; limit > 0 && len(h.serverSubs)+h.subPending >= limit {
+		return false
+	}
+	h.subPending++
+	return true
+}
+
+// releaseSubscription releases a slot reserved by reserveSubscription.
+func (h *handler) releaseSubscription() {
+	h.subLock.Lock()
+	defer h.subLock.Unlock()
+
+	h.subPending--
+}
+
 // cancelServerSubscriptions removes all subscriptions and closes their error channels.
 func (h *handler) cancelServerSubscriptions(err error) {
 	h.subLock.Lock()
@@ -218,6 +337,61 @@ func (h *handler) cancelServerSubscriptions(err error) {
 	}
 }
 
+// notify sends a subscription notification. If a notification queue is configured
+// the message is handed to notifyLoop, and the connection is dropped when the client
+// falls so far behind that the queue is full.
+func (h *handler) notify(msg *jsonrpcMessage) error {
+	if h.notifyCh == nil {
+		if err := h.throttle(msg); err != nil {
+			return err
+		}
+		return h.conn.writeJSON(context.Background(), msg)
+	}
+	select {
+	case h.notifyCh <- msg:
+		return nil
+	default:
+		h.dropOnce.Do(func() {
+			h.log.Warn("Dropping stalled RPC connection", "pending", len(h.notifyCh))
+			stalledConnGauge.Inc(1)
+			h.conn.close()
+		})
+		return ErrNotificationQueueFull
+	}
+}
+
+// notifyLoop writes queued notifications until the handler is closed.
+func (h *handler) notifyLoop() {
+	for {
+		select {
+		case msg := <-h.notifyCh:
+			if err := h.throttle(msg); err != nil {
+				return
+			}
+			if err := h.conn.writeJSON(context.Background(), msg); err != nil {
+				h.log.Debug("Failed to write RPC notification", "err", err)
+				h.conn.close()
+				return
+			}
+		case <-h.rootCtx.Done():
+			return
+		}
+	}
+}
+
+// throttle blocks until the notification payload fits into the connection's
+// throughput quota.
+func (h *handler) throttle(msg *jsonrpcMessage) error {
+	if h.notifyLim == nil {
+		return nil
+	}
+	size := len(msg.Params)
+	if size > h.notifyLim.Burst() {
+		size = h.notifyLim.Burst()
+	}
+	return h.notifyLim.WaitN(h.rootCtx, size)
+}
+
 // startCallProc runs fn in a new goroutine and starts tracking it in the h.calls wait group.
 func (h *handler) startCallProc(fn func(*callProc)) {
 	h.callWG.Add(1)
@@ -334,12 +508,27 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if callb != h.unsubscribeCb {
+		if err := h.authorize(msg); err != nil {
+			return msg.errorResponse(err)
+		}
+		if err := h.charge(msg); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args)
+	ctx, trace := h.startTrace(cp.ctx)
+	var answer *jsonrpcMessage
+	if timeout := h.timeout(msg); timeout > 0 && callb != h.unsubscribeCb {
+		answer = h.runMethodWithTimeout(ctx, msg, callb, args, timeout)
+	} else {
+		answer = h.runMethod(ctx, msg, callb, args)
+	}
+	h.finishTrace(trace, msg, answer, start)
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
@@ -362,6 +551,16 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if !h.allowSubscribe {
 		return msg.errorResponse(ErrNotificationsUnsupported)
 	}
+	if !h.reserveSubscription() {
+		return msg.errorResponse(ErrSubscriptionLimit)
+	}
+	// The reserved slot is handed over to the notifier once it's installed
+	reserved := true
+	defer func() {
+		if reserved {
+			h.releaseSubscription()
+		}
+	}()
 
 	// Subscription method name is first argument.
 	name, err := parseSubscriptionName(msg.Params)
@@ -373,6 +572,12 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if err := h.authorize(msg); err != nil {
+		return msg.errorResponse(err)
+	}
+	if err := h.charge(msg); err != nil {
+		return msg.errorResponse(err)
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
@@ -385,6 +590,7 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	// Install notifier in context so the subscription handler can find it.
 	n := &Notifier{h: h, namespace: namespace}
 	cp.notifiers = append(cp.notifiers, n)
+	reserved = false
 	ctx := context.WithValue(cp.ctx, notifierKey{}, n)
 
 	return h.runMethod(ctx, msg, callb, args)
@@ -399,6 +605,50 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	return msg.response(result)
 }
 
+// runMethodWithTimeout runs the Go callback for an RPC method, answering with an
+// error if it does not complete within the timeout. The context of the callback
+// is canceled on timeout, but callbacks which ignore it keep running.
+func (h *handler) runMethodWithTimeout(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, timeout time.Duration) *jsonrpcMessage {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	answer := make(chan *jsonrpcMessage, 1)
+	go func() {
+		answer <- h.runMethod(ctx, msg, callb, args)
+	}()
+	select {
+	case resp := <-answer:
+		return resp
+	case <-ctx.Done():
+		timedOutRequestGauge.Inc(1)
+		return msg.errorResponse(&timeoutError{msg.Method})
+	}
+}
+
+// authorize checks a call against the access policy of the connection.
+func (h *handler) authorize(msg *jsonrpcMessage) error {
+	if h.policy == nil {
+		return nil
+	}
+	return h.policy.Allow(msg.Method)
+}
+
+// charge deducts the cost of a call from the budget of the connection's client.
+func (h *handler) charge(msg *jsonrpcMessage) error {
+	if h.meter == nil {
+		return nil
+	}
+	return h.meter.charge(h.client, msg.Method)
+}
+
+// timeout returns the execution deadline of a call, or zero if it has none.
+func (h *handler) timeout(msg *jsonrpcMessage) time.Duration {
+	if h.meter == nil {
+		return 0
+	}
+	return h.meter.timeout(msg.Method)
+}
+
 // unsubscribe is the callback function for all *_unsubscribe calls.
 func (h *handler) unsubscribe(ctx context.Context, id ID) (bool, error) {
 	h.subLock.Lock()
@@ -256,9 +256,30 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", contentType)
 	codec := newHTTPServerConn(r, w)
 	defer codec.close()
+	s.attachRequest(codec, r)
 	s.serveSingleRequest(ctx, codec)
 }
 
+// attachRequest records the properties of the HTTP request which opened a server
+// connection on its codec: the API key used for metering and the access policy.
+func (s *Server) attachRequest(codec ServerCodec, r *http.Request) {
+	var c *jsonCodec
+	switch codec := codec.(type) {
+	case *jsonCodec:
+		c = codec
+	case *websocketCodec:
+		c = codec.jsonCodec
+	default:
+		return
+	}
+	if s.cfg.meter != nil {
+		if header := s.cfg.meter.apiKeyHeader(); header != "" {
+			c.apiKey = r.Header.Get(header)
+		}
+	}
+	c.policy = connPolicyFromContext(r.Context())
+}
+
 // validateRequest returns a non-zero response code and error message if the
 // request is invalid.
 func validateRequest(r *http.Request) (int, error) {
@@ -165,6 +165,8 @@ type ConnRemoteAddr interface {
 // support for parsing arguments and serializing (result) objects.
 type jsonCodec struct {
 	remote  string
+	apiKey  string                    // API key presented by the client, used for metering
+	policy  ConnPolicy                // access policy of the client, nil if unrestricted
 	closer  sync.Once                 // close closed channel once
 	closeCh chan interface{}          // closed on Close
 	decode  func(v interface{}) error // decoder to allow multiple transports
@@ -202,6 +204,14 @@ func (c *jsonCodec) remoteAddr() string {
 	return c.remote
 }
 
+func (c *jsonCodec) clientAPIKey() string {
+	return c.apiKey
+}
+
+func (c *jsonCodec) connPolicy() ConnPolicy {
+	return c.policy
+}
+
 func (c *jsonCodec) readBatch() (messages []*jsonrpcMessage, batch bool, err error) {
 	// Decode the next JSON object in the input stream.
 	// This verifies basic syntax, etc.
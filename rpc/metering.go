@@ -0,0 +1,153 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+// maxMeteredClients is the number of client budgets tracked at once. The budgets of
+// the least recently seen clients are evicted beyond this limit.
+const maxMeteredClients = 16384
+
+// MeteringConfig configures the execution deadlines and compute unit budgets of
+// method calls.
+type MeteringConfig struct {
+	// MethodCosts assigns a cost in compute units to methods. Methods which are not
+	// listed cost DefaultCost units.
+	MethodCosts map[string]int
+	DefaultCost int
+
+	// MethodTimeouts assigns execution deadlines to methods. Methods which are not
+	// listed use DefaultTimeout. A zero timeout disables the deadline.
+	MethodTimeouts map[string]time.Duration
+	DefaultTimeout time.Duration
+
+	// UnitsPerSecond is the number of compute units credited to every client each
+	// second, up to a maximum of Burst units. Calls are rejected once a client has
+	// spent its budget. A zero value disables budgets.
+	UnitsPerSecond int
+	Burst          int
+
+	// APIKeyHeader is the HTTP header carrying the API key which identifies a client.
+	// Clients which do not present a key are identified by their IP address.
+	APIKeyHeader string
+}
+
+// meter charges method calls against the budgets of their clients.
+type meter struct {
+	cfg     MeteringConfig
+	budgets *lru.Cache // client key -> *rate.Limiter
+	lock    sync.RWMutex
+}
+
+func newMeter(cfg MeteringConfig) *meter {
+	m := new(meter)
+	m.update(cfg)
+	return m
+}
+
+// update replaces the settings of the meter. The budgets of all clients start
+// over with the new settings.
+func (m *meter) update(cfg MeteringConfig) {
+	budgets, _ := lru.New(maxMeteredClients)
+	if cfg.DefaultCost == 0 {
+		cfg.DefaultCost = 1
+	}
+	if cfg.Burst < cfg.UnitsPerSecond {
+		cfg.Burst = cfg.UnitsPerSecond
+	}
+	m.lock.Lock()
+	m.cfg, m.budgets = cfg, budgets
+	m.lock.Unlock()
+}
+
+// settings returns the current settings of the meter and the budgets they apply to.
+func (m *meter) settings() (MeteringConfig, *lru.Cache) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.cfg, m.budgets
+}
+
+// apiKeyHeader returns the HTTP header carrying the API key of a client.
+func (m *meter) apiKeyHeader() string {
+	cfg, _ := m.settings()
+	return cfg.APIKeyHeader
+}
+
+// cost returns the compute units charged for a call of the given method.
+func (cfg MeteringConfig) cost(method string) int {
+	if cost, ok := cfg.MethodCosts[method]; ok {
+		return cost
+	}
+	return cfg.DefaultCost
+}
+
+// timeout returns the execution deadline of the given method.
+func (m *meter) timeout(method string) time.Duration {
+	cfg, _ := m.settings()
+	if timeout, ok := cfg.MethodTimeouts[method]; ok {
+		return timeout
+	}
+	return cfg.DefaultTimeout
+}
+
+// charge deducts the cost of a method call from the budget of the client, failing
+// if the budget is spent.
+func (m *meter) charge(client string, method string) error {
+	cfg, budgets := m.settings()
+	cost := cfg.cost(method)
+	newRPCComputeUnitsMeter(method).Mark(int64(cost))
+
+	if cfg.UnitsPerSecond == 0 || cost == 0 {
+		return nil
+	}
+	budgets.ContainsOrAdd(client, rate.NewLimiter(rate.Limit(cfg.UnitsPerSecond), cfg.Burst))
+	budget, ok := budgets.Get(client)
+	if !ok {
+		return nil // evicted concurrently, let the call through
+	}
+	if cost > cfg.Burst {
+		cost = cfg.Burst
+	}
+	if !budget.(*rate.Limiter).AllowN(time.Now(), cost) {
+		rejectedRequestGauge.Inc(1)
+		return &budgetExceededError{}
+	}
+	return nil
+}
+
+// clientKey returns the identity under which the calls of a connection are metered:
+// the API key presented by the client if any, otherwise its IP address.
+func clientKey(conn jsonWriter) string {
+	if c, ok := conn.(interface{ clientAPIKey() string }); ok {
+		if key := c.clientAPIKey(); key != "" {
+			return "key:" + key
+		}
+	}
+	remote := conn.remoteAddr()
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		return host
+	}
+	return remote
+}
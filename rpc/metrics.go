@@ -27,6 +27,9 @@ var (
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
 	failedReqeustGauge     = metrics.NewRegisteredGauge("rpc/failure", nil)
 	RpcServingTimer        = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+	stalledConnGauge       = metrics.NewRegisteredGauge("rpc/stalled", nil)
+	rejectedRequestGauge   = metrics.NewRegisteredGauge("rpc/rejected", nil)
+	timedOutRequestGauge   = metrics.NewRegisteredGauge("rpc/timeout", nil)
 )
 
 func newRPCServingTimer(method string, valid bool) metrics.Timer {
@@ -42,3 +45,8 @@ func newRPCRequestGauge(method string) metrics.Gauge {
 	m := fmt.Sprintf("rpc/count/%s", method)
 	return metrics.GetOrRegisterGauge(m, nil)
 }
+
+func newRPCComputeUnitsMeter(method string) metrics.Meter {
+	m := fmt.Sprintf("rpc/cu/%s", method)
+	return metrics.GetOrRegisterMeter(m, nil)
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import "context"
+
+// ConnPolicy restricts the method calls of a connection. A policy is attached to the
+// HTTP request which opens the connection using WithConnPolicy, typically by an
+// authenticating middleware in front of the server.
+type ConnPolicy interface {
+	// Allow returns the error to answer a call of method with, or nil if the call
+	// may proceed.
+	Allow(method string) error
+}
+
+type connPolicyKey struct{}
+
+// WithConnPolicy returns a copy of ctx carrying the access policy of a connection.
+func WithConnPolicy(ctx context.Context, policy ConnPolicy) context.Context {
+	return context.WithValue(ctx, connPolicyKey{}, policy)
+}
+
+func connPolicyFromContext(ctx context.Context) ConnPolicy {
+	policy, _ := ctx.Value(connPolicyKey{}).(ConnPolicy)
+	return policy
+}
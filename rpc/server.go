@@ -46,6 +46,48 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	cfg      handlerConfig
+}
+
+// handlerConfig holds the settings a Server applies to the handler of each connection.
+type handlerConfig struct {
+	limits  ConnLimits
+	batch   BatchLimits
+	meter   *meter
+	tracing *TracingConfig
+}
+
+// ConnLimits configures per-connection quotas for the subscription system. A zero
+// value disables the corresponding limit.
+type ConnLimits struct {
+	// MaxSubscriptions is the maximum number of active subscriptions a single
+	// connection may hold. Further subscribe calls fail with ErrSubscriptionLimit.
+	MaxSubscriptions int
+
+	// MaxPendingNotifications is the number of notifications which may be queued
+	// for a connection. When set, notifications are written asynchronously and a
+	// client which falls behind by more than this many notifications is considered
+	// stalled and disconnected.
+	MaxPendingNotifications int
+
+	// MaxBytesPerSecond throttles the notification payload written to a connection.
+	MaxBytesPerSecond int
+}
+
+// BatchLimits configures how batch requests are executed. A zero value disables the
+// corresponding limit.
+type BatchLimits struct {
+	// MaxItems is the maximum number of requests in a batch. Larger batches are
+	// rejected without executing any of their requests.
+	MaxItems int
+
+	// MaxResponseSize is the maximum aggregate size of the results of a batch. Once
+	// it is exceeded, the remaining requests are answered with an error.
+	MaxResponseSize int
+
+	// Parallelism is the number of requests of a batch executed concurrently. Values
+	// below two execute batches sequentially.
+	Parallelism int
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -58,6 +100,41 @@ func NewServer() *Server {
 	return server
 }
 
+// SetConnLimits sets the per-connection quotas applied to connections served after
+// this call.
+func (s *Server) SetConnLimits(limits ConnLimits) {
+	s.cfg.limits = limits
+}
+
+// SetBatchLimits sets the batch execution limits applied to requests served after
+// this call.
+func (s *Server) SetBatchLimits(limits BatchLimits) {
+	s.cfg.batch = limits
+}
+
+// SetMetering enables per-method execution deadlines and compute unit budgets for
+// requests served after this call.
+func (s *Server) SetMetering(cfg MeteringConfig) {
+	s.cfg.meter = newMeter(cfg)
+}
+
+// UpdateMetering replaces the settings of the call metering enabled by SetMetering,
+// applying to calls served after this call. The budgets of all clients start over.
+// It returns false if metering is not enabled.
+func (s *Server) UpdateMetering(cfg MeteringConfig) bool {
+	if s.cfg.meter == nil {
+		return false
+	}
+	s.cfg.meter.update(cfg)
+	return true
+}
+
+// SetTracing enables the export of calls served after this call as OpenTelemetry
+// spans.
+func (s *Server) SetTracing(cfg TracingConfig) {
+	s.cfg.tracing = &cfg
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -83,7 +160,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.cfg)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +174,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.cfg)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -22,6 +22,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -150,3 +152,141 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	tests := []struct {
+		limits   BatchLimits
+		request  string
+		wantResp string
+	}{
+		{
+			limits:   BatchLimits{MaxItems: 2},
+			request:  `[{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]},{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["x",2]},{"jsonrpc":"2.0","id":3,"method":"test_echo","params":["x",3]}]`,
+			wantResp: `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch too large: 3 items, limit is 2"}}`,
+		},
+		{
+			limits:   BatchLimits{MaxResponseSize: 10},
+			request:  `[{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]},{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["x",2]}]`,
+			wantResp: `[{"jsonrpc":"2.0","id":1,"result":{"String":"x","Int":1,"Args":null}},{"jsonrpc":"2.0","id":2,"error":{"code":-32003,"message":"batch response too large"}}]`,
+		},
+		{
+			limits:   BatchLimits{Parallelism: 4},
+			request:  `[{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]},{"jsonrpc":"2.0","method":"test_echo","params":["x",2]},{"jsonrpc":"2.0","id":3,"method":"test_echo","params":["x",3]}]`,
+			wantResp: `[{"jsonrpc":"2.0","id":1,"result":{"String":"x","Int":1,"Args":null}},{"jsonrpc":"2.0","id":3,"result":{"String":"x","Int":3,"Args":null}}]`,
+		},
+	}
+	for i, test := range tests {
+		server := newTestServer()
+		server.SetBatchLimits(test.limits)
+
+		clientConn, serverConn := net.Pipe()
+		go server.ServeCodec(NewCodec(serverConn), 0)
+
+		clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := io.WriteString(clientConn, test.request+"\n"); err != nil {
+			t.Fatalf("test %d: write error: %v", i, err)
+		}
+		resp, err := bufio.NewReader(clientConn).ReadString('\n')
+		if err != nil {
+			t.Fatalf("test %d: read error: %v", i, err)
+		}
+		if resp = strings.TrimRight(resp, "\r\n"); resp != test.wantResp {
+			t.Errorf("test %d: wrong response\ngot:  %s\nwant: %s", i, resp, test.wantResp)
+		}
+		clientConn.Close()
+		server.Stop()
+	}
+}
+
+func TestServerMetering(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetMetering(MeteringConfig{
+		MethodCosts:    map[string]int{"test_echo": 2},
+		MethodTimeouts: map[string]time.Duration{"test_sleep": 50 * time.Millisecond},
+		UnitsPerSecond: 1,
+		Burst:          4,
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Calls exceeding the execution deadline are answered with an error.
+	err := client.Call(nil, "test_sleep", time.Second)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32002 {
+		t.Fatalf("wrong error for slow call: %v", err)
+	}
+	// The remaining budget covers one more echo, the next one is rejected.
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1, nil); err != nil {
+		t.Fatalf("first echo failed: %v", err)
+	}
+	err = client.Call(&result, "test_echo", "x", 2, nil)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("wrong error for call over budget: %v", err)
+	}
+}
+
+func TestServerUpdateMetering(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if server.UpdateMetering(MeteringConfig{}) {
+		t.Fatal("metering updated without being enabled")
+	}
+	server.SetMetering(MeteringConfig{
+		UnitsPerSecond: 1,
+		Burst:          1,
+	})
+	client := DialInProc(server)
+	defer client.Close()
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1, nil); err != nil {
+		t.Fatalf("first echo failed: %v", err)
+	}
+	err := client.Call(&result, "test_echo", "x", 2, nil)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("wrong error for call over budget: %v", err)
+	}
+	// Raising the budget applies to the connected clients too.
+	if !server.UpdateMetering(MeteringConfig{UnitsPerSecond: 1, Burst: 2}) {
+		t.Fatal("metering not updated")
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Call(&result, "test_echo", "x", i, nil); err != nil {
+			t.Fatalf("echo %d after update failed: %v", i, err)
+		}
+	}
+}
+
+type denyPolicy string
+
+func (p denyPolicy) Allow(method string) error {
+	if method == string(p) {
+		return &methodNotFoundError{method}
+	}
+	return nil
+}
+
+func TestServerConnPolicy(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r.WithContext(WithConnPolicy(r.Context(), denyPolicy("test_echo"))))
+	}))
+	defer httpsrv.Close()
+
+	client, err := Dial(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Call(nil, "test_echo", "x", 1, nil); err == nil {
+		t.Fatal("call denied by the policy succeeded")
+	}
+	var result string
+	if err := client.Call(&result, "test_rets"); err != nil {
+		t.Fatalf("call allowed by the policy failed: %v", err)
+	}
+}
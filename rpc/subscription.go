@@ -36,6 +36,10 @@ var (
 	ErrNotificationsUnsupported = errors.New("notifications not supported")
 	// ErrNotificationNotFound is returned when the notification for the given id is not found
 	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrSubscriptionLimit is returned when the connection holds the maximum number of subscriptions
+	ErrSubscriptionLimit = errors.New("too many subscriptions")
+	// ErrNotificationQueueFull is returned when a notification is sent to a stalled connection
+	ErrNotificationQueueFull = errors.New("notification queue full")
 )
 
 var globalGen = randomIDGenerator()
@@ -163,19 +167,22 @@ func (n *Notifier) activate() error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
-	for _, data := range n.buffer {
+	// Mark the notifier active first, so a failed send doesn't leave it buffering
+	// notifications for a connection which is gone.
+	n.activated = true
+	buffer := n.buffer
+	n.buffer = nil
+	for _, data := range buffer {
 		if err := n.send(n.sub, data); err != nil {
 			return err
 		}
 	}
-	n.activated = true
 	return nil
 }
 
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
 	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
-	ctx := context.Background()
-	return n.h.conn.writeJSON(ctx, &jsonrpcMessage{
+	return n.h.notify(&jsonrpcMessage{
 		Version: vsn,
 		Method:  n.namespace + notificationMethodSuffix,
 		Params:  params,
@@ -17,6 +17,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -171,6 +172,130 @@ func TestServerUnsubscribe(t *testing.T) {
 	}
 }
 
+func TestSubscriptionLimit(t *testing.T) {
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+
+	server := newTestServer()
+	server.SetConnLimits(ConnLimits{MaxSubscriptions: 1})
+	server.RegisterName("nftest", &notificationTestService{})
+	go server.ServeCodec(NewCodec(p1), 0)
+
+	p2.SetDeadline(time.Now().Add(10 * time.Second))
+	in := json.NewDecoder(p2)
+	for i := 1; i <= 2; i++ {
+		fmt.Fprintf(p2, `{"jsonrpc":"2.0","id":%d,"method":"nftest_subscribe","params":["someSubscription",0,10]}`, i)
+		_, _, err := readAndValidateMessage(in)
+		switch {
+		case i == 1 && err != nil:
+			t.Fatalf("first subscription failed: %v", err)
+		case i == 2 && (err == nil || err.Error() != ErrSubscriptionLimit.Error()):
+			t.Fatalf("second subscription: got error %v, want %v", err, ErrSubscriptionLimit)
+		}
+	}
+}
+
+// Tests that the subscription limit holds for the calls of a batch executed in
+// parallel.
+func TestSubscriptionLimitBatch(t *testing.T) {
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+
+	server := newTestServer()
+	server.SetConnLimits(ConnLimits{MaxSubscriptions: 2})
+	server.SetBatchLimits(BatchLimits{Parallelism: 8})
+	server.RegisterName("nftest", &notificationTestService{})
+	go server.ServeCodec(NewCodec(p1), 0)
+
+	p2.SetDeadline(time.Now().Add(10 * time.Second))
+	batch := make([]string, 8)
+	for i := range batch {
+		batch[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"nftest_subscribe","params":["someSubscription",0,10]}`, i)
+	}
+	fmt.Fprintf(p2, "[%s]", strings.Join(batch, ","))
+
+	var responses []*jsonrpcMessage
+	if err := json.NewDecoder(p2).Decode(&responses); err != nil {
+		t.Fatalf("failed to read batch response: %v", err)
+	}
+	if len(responses) != len(batch) {
+		t.Fatalf("response count mismatch: have %d, want %d", len(responses), len(batch))
+	}
+	var subscribed, limited int
+	for _, resp := range responses {
+		switch {
+		case resp.Error == nil:
+			subscribed++
+		case resp.Error.Message == ErrSubscriptionLimit.Error():
+			limited++
+		default:
+			t.Errorf("unexpected error: %v", resp.Error)
+		}
+	}
+	if subscribed != 2 || limited != len(batch)-2 {
+		t.Fatalf("subscription count mismatch: have %d subscribed and %d limited, want 2 and %d", subscribed, limited, len(batch)-2)
+	}
+}
+
+// floodService sends notifications as fast as possible and reports the error which
+// ended the subscription.
+type floodService struct {
+	notifyErr chan error
+}
+
+func (s *floodService) Flood(ctx context.Context) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	go func() {
+		for i := 0; ; i++ {
+			if err := notifier.Notify(subscription.ID, i); err != nil {
+				s.notifyErr <- err
+				return
+			}
+		}
+	}()
+	return subscription, nil
+}
+
+func TestStalledSubscriberDropped(t *testing.T) {
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+
+	server := newTestServer()
+	server.SetConnLimits(ConnLimits{MaxPendingNotifications: 8})
+	service := &floodService{notifyErr: make(chan error, 1)}
+	server.RegisterName("flood", service)
+	go server.ServeCodec(NewCodec(p1), 0)
+
+	// Read the subscription ID, then stop consuming notifications.
+	p2.SetDeadline(time.Now().Add(10 * time.Second))
+	in := json.NewDecoder(p2)
+	p2.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"flood_subscribe","params":["flood"]}`))
+	if _, _, err := readAndValidateMessage(in); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-service.notifyErr:
+		if err != ErrNotificationQueueFull {
+			t.Fatalf("wrong notify error: got %v, want %v", err, ErrNotificationQueueFull)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled subscriber was not detected")
+	}
+	// The server should have closed the connection.
+	for {
+		if _, _, err := readAndValidateMessage(in); err != nil {
+			if strings.Contains(err.Error(), "timeout") {
+				t.Fatal("connection was not closed")
+			}
+			break
+		}
+	}
+}
+
 type subConfirmation struct {
 	reqid int
 	subid ID
@@ -0,0 +1,132 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/otlp"
+)
+
+// TracingConfig configures the export of served calls as OpenTelemetry spans.
+type TracingConfig struct {
+	// Exporter receives a span for every traced call.
+	Exporter *otlp.Exporter
+
+	// Threshold is the minimum duration of a call for it to be exported. Zero
+	// exports every call.
+	Threshold time.Duration
+}
+
+// traceKey is the context key of the trace collecting the attributes of a call.
+type traceKey struct{}
+
+// callTrace collects the attributes a method handler attaches to its call.
+type callTrace struct {
+	lock  sync.Mutex
+	attrs []interface{}
+}
+
+// TraceAttributes attaches key/value pairs to the span of the call being served
+// with the given context, replacing the earlier values of the same keys. It does
+// nothing if the call is not traced.
+func TraceAttributes(ctx context.Context, kv ...interface{}) {
+	t, ok := ctx.Value(traceKey{}).(*callTrace)
+	if !ok {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for i := 0; i+1 < len(kv); i += 2 {
+		*t.value(kv[i]) = kv[i+1]
+	}
+}
+
+// TraceCount adds n to a counter attribute of the span of the call being served
+// with the given context. It does nothing if the call is not traced.
+func TraceCount(ctx context.Context, key string, n int) {
+	t, ok := ctx.Value(traceKey{}).(*callTrace)
+	if !ok {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	v := t.value(key)
+	count, _ := (*v).(int)
+	*v = count + n
+}
+
+// value returns the slot holding the value of an attribute, adding the attribute
+// if it is not set yet. The caller must hold the lock.
+func (t *callTrace) value(key interface{}) *interface{} {
+	for i := 0; i < len(t.attrs); i += 2 {
+		if t.attrs[i] == key {
+			return &t.attrs[i+1]
+		}
+	}
+	t.attrs = append(t.attrs, key, nil)
+	return &t.attrs[len(t.attrs)-1]
+}
+
+// startTrace installs an attribute collector in the context of a call, if calls
+// are traced.
+func (h *handler) startTrace(ctx context.Context) (context.Context, *callTrace) {
+	if h.tracing == nil {
+		return ctx, nil
+	}
+	t := new(callTrace)
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// finishTrace exports the span of a served call, if it took long enough.
+func (h *handler) finishTrace(t *callTrace, msg *jsonrpcMessage, answer *jsonrpcMessage, start time.Time) {
+	if t == nil {
+		return
+	}
+	end := time.Now()
+	if end.Sub(start) < h.tracing.Threshold {
+		return
+	}
+	attrs := []interface{}{
+		"rpc.method", msg.Method,
+		"rpc.params.size", len(msg.Params),
+		"rpc.response.size", len(answer.Result),
+	}
+	if answer.Error != nil {
+		attrs = append(attrs, "rpc.error.code", answer.Error.Code, "err", errors.New(answer.Error.Message))
+	}
+	if h.client != "" {
+		attrs = append(attrs, "rpc.client", h.client)
+	}
+	t.lock.Lock()
+	attrs = append(attrs, t.attrs...)
+	t.lock.Unlock()
+
+	h.tracing.Exporter.Export(&otlp.Span{
+		Trace: otlp.NewTraceID(),
+		ID:    otlp.NewSpanID(),
+		Name:  msg.Method,
+		Start: start,
+		End:   end,
+		Attrs: attrs,
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/otlp"
+)
+
+type tracedService struct{}
+
+func (s *tracedService) Annotate(ctx context.Context, d time.Duration) string {
+	time.Sleep(d)
+	TraceAttributes(ctx, "block.number", 6)
+	TraceAttributes(ctx, "block.number", 7)
+	TraceCount(ctx, "state.slots", 2)
+	TraceCount(ctx, "state.slots", 3)
+	return "ok"
+}
+
+// exportedSpan is the part of an exported span checked by the tests.
+type exportedSpan struct {
+	Name       string `json:"name"`
+	Attributes []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+			IntValue    string `json:"intValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+}
+
+func TestServerTracing(t *testing.T) {
+	spans := make(chan exportedSpan, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans <- span
+				}
+			}
+		}
+	}))
+	defer collector.Close()
+
+	exporter := otlp.NewExporter(collector.URL, "rpc")
+	server := NewServer()
+	defer server.Stop()
+	server.RegisterName("traced", new(tracedService))
+	server.SetTracing(TracingConfig{Exporter: exporter, Threshold: 20 * time.Millisecond})
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Only the calls exceeding the threshold are exported
+	var result string
+	if err := client.Call(&result, "traced_annotate", 0); err != nil {
+		t.Fatalf("fast call failed: %v", err)
+	}
+	if err := client.Call(&result, "traced_annotate", 50*time.Millisecond); err != nil {
+		t.Fatalf("slow call failed: %v", err)
+	}
+	exporter.Close()
+
+	if len(spans) != 1 {
+		t.Fatalf("exported span count mismatch: have %d, want 1", len(spans))
+	}
+	span := <-spans
+	if span.Name != "traced_annotate" {
+		t.Errorf("span name mismatch: have %q", span.Name)
+	}
+	attrs := make(map[string]string)
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value.StringValue + attr.Value.IntValue
+	}
+	want := map[string]string{
+		"rpc.method":        "traced_annotate",
+		"rpc.params.size":   "10",
+		"rpc.response.size": "4",
+		"block.number":      "7",
+		"state.slots":       "5",
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("span attributes mismatch:\nhave %v\nwant %v", attrs, want)
+	}
+}
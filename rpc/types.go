@@ -40,7 +40,6 @@ type API struct {
 // multiple go-routines concurrently.
 type ServerCodec interface {
 	readBatch() (msgs []*jsonrpcMessage, isBatch bool, err error)
-	close()
 	jsonWriter
 }
 
@@ -48,6 +47,8 @@ type ServerCodec interface {
 // Implementations must be safe for concurrent use.
 type jsonWriter interface {
 	writeJSON(context.Context, interface{}) error
+	// Close closes the underlying connection.
+	close()
 	// Closed returns a channel which is closed when the connection is closed.
 	closed() <-chan interface{}
 	// RemoteAddr returns the peer address of the connection.
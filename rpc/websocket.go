@@ -60,6 +60,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
+		s.attachRequest(codec, r)
 		s.ServeCodec(codec, 0)
 	})
 }
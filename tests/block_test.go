@@ -17,7 +17,18 @@
 package tests
 
 import (
+	"encoding/json"
+	"math/big"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 )
 
 func TestBlockchain(t *testing.T) {
@@ -59,3 +70,79 @@ func TestBlockchain(t *testing.T) {
 	// prior to Istanbul. However, they are all derived from GeneralStateTests,
 	// which run natively, so there's no reason to run them here.
 }
+
+func TestExportBlockchain(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0x000000000000000000000000000000000000aaaa")
+		config  = *params.TestChainConfig
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+	)
+	config.BerlinBlock = big.NewInt(4)
+	genesis := (&core.Genesis{
+		Config: &config,
+		Alloc: core.GenesisAlloc{
+			addr: {Balance: big.NewInt(1000000000000000000)},
+			// sstore(0, sload(0) + 1)
+			counter: {Balance: common.Big0, Code: []byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD), byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}},
+			// untouched by the exported blocks
+			common.HexToAddress("0xbbbb"): {Balance: big.NewInt(1)},
+		},
+	}).MustCommit(db)
+
+	signer := types.LatestSigner(&config)
+	blocks, _ := core.GenerateChain(&config, genesis, engine, db, 8, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0xcc, byte(i)})
+		call, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), counter, common.Big0, 100000, big.NewInt(1), nil), signer, key)
+		gen.AddTx(call)
+		transfer, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{byte(i + 1)}, common.Big1, params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(transfer)
+	})
+	chain, err := core.NewBlockChain(db, nil, &config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := ExportBlockTest(chain, 0, 4); err == nil {
+		t.Fatal("exported the genesis block")
+	}
+	test, err := ExportBlockTest(chain, 3, 6)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(test.json.Blocks) != 4 {
+		t.Fatalf("block count mismatch: have %d, want 4", len(test.json.Blocks))
+	}
+	if _, ok := test.json.Pre[common.HexToAddress("0xbbbb")]; ok {
+		t.Error("untouched account exported")
+	}
+	if have := test.json.Pre[counter].Storage[common.Hash{}]; have != common.BigToHash(big.NewInt(2)) {
+		t.Errorf("counter pre state mismatch: have %x, want 2", have)
+	}
+	if have := test.json.Post[counter].Storage[common.Hash{}]; have != common.BigToHash(big.NewInt(6)) {
+		t.Errorf("counter post state mismatch: have %x, want 6", have)
+	}
+	if have := test.json.Config.BerlinBlock.Uint64(); have != 2 {
+		t.Errorf("berlin block mismatch: have %d, want 2", have)
+	}
+	// The test must pass, also after a roundtrip through JSON
+	if err := test.Run(false); err != nil {
+		t.Fatalf("exported test failed: %v", err)
+	}
+	enc, err := json.Marshal(test)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	dec := new(BlockTest)
+	if err := json.Unmarshal(enc, dec); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if err := dec.Run(true); err != nil {
+		t.Fatalf("decoded test failed: %v", err)
+	}
+}
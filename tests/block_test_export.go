@@ -0,0 +1,414 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tests
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ExportBlockTest converts the blocks first..last of the chain into a blockchain
+// test, so that the execution of their transactions can be compared with other
+// clients.
+//
+// Blockchain tests start at a genesis block and are sealed by ethash, so the blocks
+// are sealed anew: the transactions run on top of the accounts they touch, as of
+// the parent of the first block, in blocks numbered from 1 keeping the coinbases
+// and timestamps of the originals. The fork schedule of the chain config is shifted
+// along, and the consensus engine replaced by ethash, dropping the transactions
+// specific to the engine, like the system transactions of parlia.
+//
+// The state of the parent of the first block must be available.
+func ExportBlockTest(chain *core.BlockChain, first, last uint64) (*BlockTest, error) {
+	if first == 0 || first > last {
+		return nil, fmt.Errorf("invalid block range %d..%d", first, last)
+	}
+	parent := chain.GetHeaderByNumber(first - 1)
+	if parent == nil {
+		return nil, fmt.Errorf("block #%d not found", first-1)
+	}
+	var (
+		blocks   []*types.Block
+		txs      [][]*types.Transaction
+		gasLimit = parent.GasLimit
+	)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		included, err := userTransactions(chain.Engine(), block)
+		if err != nil {
+			return nil, err
+		}
+		// The gas limit is fixed at the highest one for every block to fit
+		if block.GasLimit() > gasLimit {
+			gasLimit = block.GasLimit()
+		}
+		blocks = append(blocks, block)
+		txs = append(txs, included)
+	}
+	var (
+		config   = exportConfig(chain.Config(), first-1)
+		engine   = ethash.NewFaker()
+		recorder = &accessRecorder{accounts: make(map[common.Address]map[common.Hash]struct{})}
+		genesis  = &core.Genesis{
+			Config:     config,
+			Timestamp:  parent.Time,
+			GasLimit:   gasLimit,
+			Difficulty: params.GenesisDifficulty,
+			Coinbase:   parent.Coinbase,
+		}
+	)
+	// Execute the transactions on top of the state of the chain first, recording
+	// the accounts touched to be put into the genesis.
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("state of block #%d not available: %v", parent.Number, err)
+	}
+	var (
+		source   = &rebasedChain{BlockChain: chain, config: config, offset: first - 1}
+		header   = genesis.ToBlock(nil).Header()
+		expected = make([]types.Receipts, len(blocks))
+	)
+	for i, block := range blocks {
+		header = exportHeader(config, header, block.Header(), gasLimit)
+		header.ParentHash = block.ParentHash() // BLOCKHASH is served by the chain
+		if _, expected[i], err = exportBlock(config, source, engine, statedb, header, txs[i], recorder); err != nil {
+			return nil, fmt.Errorf("block #%d: %v", block.NumberU64(), err)
+		}
+	}
+	if statedb, err = chain.StateAt(parent.Root); err != nil {
+		return nil, fmt.Errorf("state of block #%d not available: %v", parent.Number, err)
+	}
+	genesis.Alloc = recorder.alloc(statedb)
+
+	// Seal the blocks on top of the genesis, checking the transactions execute as
+	// they did on the chain. They can only differ by the hashes of the blocks.
+	db := rawdb.NewMemoryDatabase()
+	gblock, err := genesis.Commit(db)
+	if err != nil {
+		return nil, err
+	}
+	sealer, err := core.NewBlockChain(db, nil, config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sealer.Stop()
+
+	test := &BlockTest{json: btJSON{
+		Genesis:    *exportBtHeader(gblock.Header()),
+		Pre:        genesis.Alloc,
+		Network:    exportNetwork(config),
+		SealEngine: "NoProof",
+		Config:     config,
+	}}
+	header = gblock.Header()
+	for i, block := range blocks {
+		if statedb, err = sealer.StateAt(header.Root); err != nil {
+			return nil, err
+		}
+		header = exportHeader(config, header, block.Header(), gasLimit)
+		sealed, receipts, err := exportBlock(config, sealer, engine, statedb, header, txs[i], recorder)
+		if err != nil {
+			return nil, fmt.Errorf("block #%d: %v", block.NumberU64(), err)
+		}
+		for j, receipt := range receipts {
+			if receipt.Status != expected[i][j].Status || receipt.GasUsed != expected[i][j].GasUsed {
+				return nil, fmt.Errorf("block #%d: transaction %x executed differently after sealing, it likely depends on BLOCKHASH", block.NumberU64(), receipt.TxHash)
+			}
+		}
+		if _, err := sealer.InsertChain(types.Blocks{sealed}); err != nil {
+			return nil, fmt.Errorf("block #%d: %v", block.NumberU64(), err)
+		}
+		enc, err := rlp.EncodeToBytes(sealed)
+		if err != nil {
+			return nil, err
+		}
+		test.json.Blocks = append(test.json.Blocks, btBlock{
+			BlockHeader:  exportBtHeader(sealed.Header()),
+			Rlp:          hexutil.Encode(enc),
+			UncleHeaders: []*btHeader{},
+		})
+		header = sealed.Header()
+	}
+	if statedb, err = sealer.State(); err != nil {
+		return nil, err
+	}
+	test.json.Post = recorder.alloc(statedb)
+	test.json.BestBlock = common.UnprefixedHash(header.Hash())
+	return test, nil
+}
+
+// userTransactions returns the transactions of the block, except the ones issued
+// by the consensus engine.
+func userTransactions(engine consensus.Engine, block *types.Block) ([]*types.Transaction, error) {
+	posa, isPoSA := engine.(consensus.PoSA)
+	if !isPoSA {
+		return block.Transactions(), nil
+	}
+	var txs []*types.Transaction
+	for _, tx := range block.Transactions() {
+		system, err := posa.IsSystemTransaction(tx, block.Header())
+		if err != nil {
+			return nil, fmt.Errorf("block #%d: %v", block.NumberU64(), err)
+		}
+		if !system {
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
+// exportConfig returns the chain config of the exported blocks: the forks are
+// moved back by the given number of blocks, and the engine replaced by ethash.
+func exportConfig(config *params.ChainConfig, offset uint64) *params.ChainConfig {
+	cpy := *config
+	cpy.Ethash, cpy.Clique, cpy.Parlia = new(params.EthashConfig), nil, nil
+
+	fields := reflect.ValueOf(&cpy).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		block, ok := fields.Field(i).Interface().(*big.Int)
+		if !ok || block == nil || fields.Type().Field(i).Name == "ChainID" {
+			continue
+		}
+		shifted := new(big.Int).Sub(block, new(big.Int).SetUint64(offset))
+		if shifted.Sign() < 0 {
+			shifted.SetUint64(0)
+		}
+		fields.Field(i).Set(reflect.ValueOf(shifted))
+	}
+	cpy.Precompiles = nil
+	for _, precompile := range config.Precompiles {
+		shifted := *precompile
+		if shifted.Block = new(big.Int).Sub(precompile.Block, new(big.Int).SetUint64(offset)); shifted.Block.Sign() < 0 {
+			shifted.Block.SetUint64(0)
+		}
+		cpy.Precompiles = append(cpy.Precompiles, &shifted)
+	}
+	return &cpy
+}
+
+// exportNetwork returns the name of the latest network of the standard tests the
+// exported blocks start on, for the clients not reading the embedded config.
+func exportNetwork(config *params.ChainConfig) string {
+	number := common.Big1
+	switch {
+	case config.IsBerlin(number):
+		return "Berlin"
+	case config.IsIstanbul(number):
+		return "Istanbul"
+	case config.IsPetersburg(number):
+		return "ConstantinopleFix"
+	case config.IsConstantinople(number):
+		return "Constantinople"
+	case config.IsByzantium(number):
+		return "Byzantium"
+	case config.IsEIP158(number):
+		return "EIP158"
+	case config.IsEIP150(number):
+		return "EIP150"
+	case config.IsHomestead(number):
+		return "Homestead"
+	default:
+		return "Frontier"
+	}
+}
+
+// exportHeader creates the header sealing the transactions of an exported block
+// on top of the given parent.
+func exportHeader(config *params.ChainConfig, parent *types.Header, orig *types.Header, gasLimit uint64) *types.Header {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Coinbase:   orig.Coinbase,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   gasLimit,
+		Time:       orig.Time,
+	}
+	// Blocks may share a second with sub-second block intervals
+	if header.Time <= parent.Time {
+		header.Time = parent.Time + 1
+	}
+	header.Difficulty = ethash.CalcDifficulty(config, header.Time, parent)
+	return header
+}
+
+// exportChain is the chain the exported blocks are executed on.
+type exportChain interface {
+	core.ChainContext
+	consensus.ChainHeaderReader
+}
+
+// exportBlock executes the transactions on top of the state, assembling the block
+// of the header with them.
+func exportBlock(config *params.ChainConfig, chain exportChain, engine consensus.Engine, statedb *state.StateDB, header *types.Header, txs []*types.Transaction, tracer vm.EVMLogger) (*types.Block, types.Receipts, error) {
+	var (
+		gp       = new(core.GasPool).AddGas(header.GasLimit)
+		receipts types.Receipts
+	)
+	for i, tx := range txs {
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+		receipt, err := core.ApplyTransaction(config, chain, &header.Coinbase, gp, statedb, header, tx, &header.GasUsed, vm.Config{Debug: true, Tracer: tracer})
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	return engine.FinalizeAndAssemble(chain, header, statedb, txs, nil, receipts)
+}
+
+func exportBtHeader(header *types.Header) *btHeader {
+	return &btHeader{
+		Bloom:            header.Bloom,
+		Coinbase:         header.Coinbase,
+		MixHash:          header.MixDigest,
+		Nonce:            header.Nonce,
+		Number:           header.Number,
+		Hash:             header.Hash(),
+		ParentHash:       header.ParentHash,
+		ReceiptTrie:      header.ReceiptHash,
+		StateRoot:        header.Root,
+		TransactionsTrie: header.TxHash,
+		UncleHash:        header.UncleHash,
+		ExtraData:        header.Extra,
+		Difficulty:       header.Difficulty,
+		GasLimit:         header.GasLimit,
+		GasUsed:          header.GasUsed,
+		Timestamp:        header.Time,
+	}
+}
+
+// rebasedChain serves the headers of the chain renumbered like the exported
+// blocks, with the exported chain config.
+type rebasedChain struct {
+	*core.BlockChain
+	config *params.ChainConfig
+	offset uint64
+}
+
+func (c *rebasedChain) Config() *params.ChainConfig { return c.config }
+
+func (c *rebasedChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.BlockChain.GetHeader(hash, number+c.offset)
+	if header == nil {
+		return nil
+	}
+	header = types.CopyHeader(header)
+	header.Number.SetUint64(number)
+	return header
+}
+
+// accessRecorder is a tracer recording the accounts and storage slots accessed.
+type accessRecorder struct {
+	env      *vm.EVM
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func (r *accessRecorder) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := r.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		r.accounts[addr] = slots
+	}
+	return slots
+}
+
+// alloc returns the accounts recorded as found in the state, with the non-empty
+// storage slots recorded.
+func (r *accessRecorder) alloc(statedb *state.StateDB) core.GenesisAlloc {
+	alloc := make(core.GenesisAlloc)
+	for addr, slots := range r.accounts {
+		if !statedb.Exist(addr) {
+			continue
+		}
+		account := core.GenesisAccount{
+			Balance: statedb.GetBalance(addr),
+			Nonce:   statedb.GetNonce(addr),
+			Code:    statedb.GetCode(addr),
+		}
+		for slot := range slots {
+			if value := statedb.GetState(addr, slot); value != (common.Hash{}) {
+				if account.Storage == nil {
+					account.Storage = make(map[common.Hash]common.Hash)
+				}
+				account.Storage[slot] = value
+			}
+		}
+		alloc[addr] = account
+	}
+	return alloc
+}
+
+func (r *accessRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	r.env = env
+	r.touch(from)
+	r.touch(to)
+	r.touch(env.Context.Coinbase)
+}
+
+func (r *accessRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil {
+		return
+	}
+	var (
+		stack    = scope.Stack
+		stackLen = len(stack.Data())
+		caller   = scope.Contract.Address()
+	)
+	switch {
+	case stackLen >= 1 && (op == vm.SLOAD || op == vm.SSTORE):
+		r.touch(caller)[common.Hash(stack.Back(0).Bytes32())] = struct{}{}
+	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
+		r.touch(common.Address(stack.Back(0).Bytes20()))
+	case stackLen >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
+		r.touch(common.Address(stack.Back(1).Bytes20()))
+	case op == vm.CREATE:
+		r.touch(crypto.CreateAddress(caller, r.env.StateDB.GetNonce(caller)))
+	case stackLen >= 4 && op == vm.CREATE2:
+		init := scope.Memory.GetCopy(int64(stack.Back(1).Uint64()), int64(stack.Back(2).Uint64()))
+		r.touch(crypto.CreateAddress2(caller, common.Hash(stack.Back(3).Bytes32()), crypto.Keccak256(init)))
+	}
+}
+
+// CaptureEnter records the accounts called and created, including the calls made
+// and the contracts created without going through the interpreter.
+func (r *accessRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	r.touch(to)
+}
+
+func (r *accessRecorder) CaptureTxStart(gasLimit uint64)                       {}
+func (r *accessRecorder) CaptureTxEnd(restGas uint64)                          {}
+func (r *accessRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (r *accessRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (r *accessRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {}
@@ -48,6 +48,11 @@ func (t *BlockTest) UnmarshalJSON(in []byte) error {
 	return json.Unmarshal(in, &t.json)
 }
 
+// MarshalJSON implements json.Marshaler interface.
+func (t *BlockTest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&t.json)
+}
+
 type btJSON struct {
 	Blocks     []btBlock             `json:"blocks"`
 	Genesis    btHeader              `json:"genesisBlockHeader"`
@@ -56,33 +61,37 @@ type btJSON struct {
 	BestBlock  common.UnprefixedHash `json:"lastblockhash"`
 	Network    string                `json:"network"`
 	SealEngine string                `json:"sealEngine"`
+
+	// Config overrides the chain config of the network, set by the tests
+	// exported from a chain with its own fork schedule.
+	Config *params.ChainConfig `json:"config,omitempty"`
 }
 
 type btBlock struct {
-	BlockHeader  *btHeader
-	Rlp          string
-	UncleHeaders []*btHeader
+	BlockHeader  *btHeader   `json:"blockHeader"`
+	Rlp          string      `json:"rlp"`
+	UncleHeaders []*btHeader `json:"uncleHeaders"`
 }
 
 //go:generate gencodec -type btHeader -field-override btHeaderMarshaling -out gen_btheader.go
 
 type btHeader struct {
-	Bloom            types.Bloom
-	Coinbase         common.Address
-	MixHash          common.Hash
-	Nonce            types.BlockNonce
-	Number           *big.Int
-	Hash             common.Hash
-	ParentHash       common.Hash
-	ReceiptTrie      common.Hash
-	StateRoot        common.Hash
-	TransactionsTrie common.Hash
-	UncleHash        common.Hash
-	ExtraData        []byte
-	Difficulty       *big.Int
-	GasLimit         uint64
-	GasUsed          uint64
-	Timestamp        uint64
+	Bloom            types.Bloom      `json:"bloom"`
+	Coinbase         common.Address   `json:"coinbase"`
+	MixHash          common.Hash      `json:"mixHash"`
+	Nonce            types.BlockNonce `json:"nonce"`
+	Number           *big.Int         `json:"number"`
+	Hash             common.Hash      `json:"hash"`
+	ParentHash       common.Hash      `json:"parentHash"`
+	ReceiptTrie      common.Hash      `json:"receiptTrie"`
+	StateRoot        common.Hash      `json:"stateRoot"`
+	TransactionsTrie common.Hash      `json:"transactionsTrie"`
+	UncleHash        common.Hash      `json:"uncleHash"`
+	ExtraData        []byte           `json:"extraData"`
+	Difficulty       *big.Int         `json:"difficulty"`
+	GasLimit         uint64           `json:"gasLimit"`
+	GasUsed          uint64           `json:"gasUsed"`
+	Timestamp        uint64           `json:"timestamp"`
 }
 
 type btHeaderMarshaling struct {
@@ -96,6 +105,9 @@ type btHeaderMarshaling struct {
 
 func (t *BlockTest) Run(snapshotter bool) error {
 	config, ok := Forks[t.json.Network]
+	if t.json.Config != nil {
+		config, ok = t.json.Config, true
+	}
 	if !ok {
 		return UnsupportedForkError{t.json.Network}
 	}
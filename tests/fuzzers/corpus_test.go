@@ -0,0 +1,57 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package fuzzers
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/tests/fuzzers/ethpackets"
+	"github.com/ethereum/go-ethereum/tests/fuzzers/parliaheader"
+	"github.com/ethereum/go-ethereum/tests/fuzzers/txpool"
+)
+
+// Tests that the seed corpora run through their fuzzers, keeping them meaningful
+// as the fuzzed code changes.
+func TestCorpus(t *testing.T) {
+	fuzzers := []struct {
+		name string
+		fuzz func([]byte) int
+	}{
+		{"ethpackets", ethpackets.Fuzz},
+		{"parliaheader", parliaheader.Fuzz},
+		{"txpool", txpool.Fuzz},
+	}
+	for _, fuzzer := range fuzzers {
+		files, err := filepath.Glob(filepath.Join(fuzzer.name, "corpus", "*"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("%s: no corpus: %v", fuzzer.name, err)
+		}
+		accepted := 0
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accepted += fuzzer.fuzz(data)
+		}
+		if accepted == 0 {
+			t.Errorf("%s: no corpus input accepted", fuzzer.name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package ethpackets fuzzes the decoding of the eth/66 to eth/68 protocol packets
+// and of the vote envelopes.
+package ethpackets
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Packets are the packets the fuzzer decodes, selected by the first input byte.
+var Packets = []func() interface{}{
+	func() interface{} { return new(eth.StatusPacket) },
+	func() interface{} { return new(eth.UpgradeStatusPacket) },
+	func() interface{} { return new(eth.NewBlockHashesPacket) },
+	func() interface{} { return new(eth.TransactionsPacket) },
+	func() interface{} { return new(eth.PrivateTransactionsPacket) },
+	func() interface{} { return new(eth.GetBlockHeadersPacket66) },
+	func() interface{} { return new(eth.BlockHeadersPacket66) },
+	func() interface{} { return new(eth.GetBlockBodiesPacket66) },
+	func() interface{} { return new(eth.BlockBodiesPacket66) },
+	func() interface{} { return new(eth.NewBlockPacket) },
+	func() interface{} { return new(eth.GetNodeDataPacket66) },
+	func() interface{} { return new(eth.NodeDataPacket66) },
+	func() interface{} { return new(eth.GetReceiptsPacket66) },
+	func() interface{} { return new(eth.ReceiptsPacket66) },
+	func() interface{} { return new(eth.NewPooledTransactionHashesPacket) },
+	func() interface{} { return new(eth.NewPooledTransactionHashesPacket68) },
+	func() interface{} { return new(eth.GetPooledTransactionsPacket66) },
+	func() interface{} { return new(eth.PooledTransactionsPacket66) },
+	func() interface{} { return new([]*types.VoteEnvelope) },
+}
+
+// Fuzz decodes the input, after the packet selector byte, into the selected
+// packet. Decoded packets must survive an encoding round trip unchanged.
+func Fuzz(input []byte) int {
+	if len(input) == 0 || len(input) > 128*1024 {
+		return 0
+	}
+	index := int(input[0]) % len(Packets)
+
+	packet := Packets[index]()
+	if err := rlp.DecodeBytes(input[1:], packet); err != nil {
+		return 0
+	}
+	// Decoding is canonical, so encoding must reproduce the input
+	enc, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		panic(fmt.Sprintf("packet %d (%T): failed to encode decoded packet: %v", index, packet, err))
+	}
+	if !bytes.Equal(enc, input[1:]) {
+		panic(fmt.Sprintf("packet %d (%T): encoding mismatch\ninput : %x\noutput: %x", index, packet, input[1:], enc))
+	}
+	return 1
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package parliaheader fuzzes the verification of the headers by the parlia
+// consensus engine.
+package parliaheader
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/config"
+	"github.com/ethereum/go-ethereum/consensus/parlia"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	extraVanity = 32 // Fixed number of extra-data prefix bytes reserved for signer vanity
+	extraSeal   = 65 // Fixed number of extra-data suffix bytes reserved for signer seal
+)
+
+// Input mode flags, taken from the first input byte
+const (
+	modeAnchor    = 1 << iota // Attach the header to the genesis block
+	modeNormalize             // Set the consensus fields to valid values
+	modeSeal                  // Sign the header with the validator key
+)
+
+var (
+	key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	validator = crypto.PubkeyToAddress(key.PublicKey)
+
+	chain  *headerChain
+	engine *parlia.Parlia
+)
+
+func init() {
+	genesis := config.DeveloperGenesis(3, validator)
+	block := genesis.MustCommit(rawdb.NewMemoryDatabase())
+
+	chain = &headerChain{config: genesis.Config, genesis: block.Header()}
+	engine = parlia.New(genesis.Config, rawdb.NewMemoryDatabase(), nil, block.Hash())
+}
+
+// Fuzz decodes a header from the input, after the mode byte, and verifies it
+// against a single validator chain holding only the genesis block.
+func Fuzz(input []byte) int {
+	if len(input) == 0 || len(input) > 16*1024 {
+		return 0
+	}
+	mode := input[0]
+
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(input[1:], header); err != nil {
+		return 0
+	}
+	genesis := chain.genesis
+	if mode&modeAnchor != 0 {
+		header.Number = big.NewInt(1)
+		header.ParentHash = genesis.Hash()
+	}
+	if mode&modeNormalize != 0 {
+		header.UncleHash = types.EmptyUncleHash
+		header.MixDigest = common.Hash{}
+		header.Difficulty = big.NewInt(2)
+		header.Coinbase = validator
+		header.Time = genesis.Time + chain.config.Parlia.Period
+		header.Extra = make([]byte, extraVanity+extraSeal)
+	}
+	if mode&modeSeal != 0 && len(header.Extra) >= extraVanity+extraSeal && header.Number != nil {
+		sig, err := crypto.Sign(crypto.Keccak256(parlia.ParliaRLP(header, chain.config.ChainID)), key)
+		if err != nil {
+			panic(err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	}
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// headerChain is a chain of the genesis block only.
+type headerChain struct {
+	config  *params.ChainConfig
+	genesis *types.Header
+}
+
+func (c *headerChain) Config() *params.ChainConfig { return c.config }
+
+func (c *headerChain) CurrentHeader() *types.Header { return c.genesis }
+
+func (c *headerChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number == 0 && hash == c.genesis.Hash() {
+		return c.genesis
+	}
+	return nil
+}
+
+func (c *headerChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number == 0 {
+		return c.genesis
+	}
+	return nil
+}
+
+func (c *headerChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if hash == c.genesis.Hash() {
+		return c.genesis
+	}
+	return nil
+}
+
+func (c *headerChain) GetHighestVerifiedHeader() *types.Header { return c.genesis }
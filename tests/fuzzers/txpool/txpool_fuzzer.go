@@ -0,0 +1,199 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txpool fuzzes the ingestion of transactions into the transaction pool.
+package txpool
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Operations decoded from the input, selected by their first byte
+const (
+	opLegacyTx = iota
+	opAccessListTx
+	opBlobTx
+	opSetCodeTx
+	opRawTx // Transaction in its binary encoding, taken verbatim from the input
+
+	opCount
+)
+
+var (
+	keys   []*ecdsa.PrivateKey
+	chain  *core.BlockChain
+	signer types.Signer
+)
+
+func init() {
+	config := *params.AllEthashProtocolChanges
+	config.CancunBlock = big.NewInt(0)
+	config.PragueBlock = big.NewInt(0)
+
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.ToECDSA(crypto.Keccak256([]byte{byte(i)}))
+		keys = append(keys, key)
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(params.Ether)}
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis := &core.Genesis{Config: &config, GasLimit: 8000000, Alloc: alloc}
+	genesis.MustCommit(db)
+
+	var err error
+	if chain, err = core.NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil, nil); err != nil {
+		panic(err)
+	}
+	signer = types.LatestSigner(&config)
+}
+
+// Fuzz decodes a batch of transactions from the input and adds them to a fresh
+// transaction pool, as remote transactions unless the first input byte is odd.
+// The pool must only hold transactions it accepted.
+func Fuzz(input []byte) int {
+	if len(input) == 0 || len(input) > 64*1024 {
+		return 0
+	}
+	local := input[0]&1 == 1
+	r := bytes.NewReader(input[1:])
+
+	var txs []*types.Transaction
+	for len(txs) < 256 {
+		tx, err := decodeTx(r)
+		if err != nil {
+			break
+		}
+		if tx != nil {
+			txs = append(txs, tx)
+		}
+	}
+	if len(txs) == 0 {
+		return 0
+	}
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+
+	pool := core.NewTxPool(config, chain.Config(), chain)
+	defer pool.Stop()
+
+	var errs []error
+	if local {
+		errs = pool.AddLocals(txs)
+	} else {
+		errs = pool.AddRemotesSync(txs)
+	}
+	accepted := make(map[common.Hash]bool)
+	for i, err := range errs {
+		if err == nil {
+			accepted[txs[i].Hash()] = true
+		}
+	}
+	pending, queued := pool.Content()
+	for _, content := range []map[common.Address]types.Transactions{pending, queued} {
+		for from, list := range content {
+			for _, tx := range list {
+				if !accepted[tx.Hash()] {
+					panic(fmt.Sprintf("pool holds rejected transaction %x", tx.Hash()))
+				}
+				if sender, err := types.Sender(signer, tx); err != nil || sender != from {
+					panic(fmt.Sprintf("pool holds transaction %x under %v, sender %v (%v)", tx.Hash(), from, sender, err))
+				}
+			}
+		}
+	}
+	if len(accepted) == 0 {
+		return 0
+	}
+	return 1
+}
+
+// decodeTx reads the next operation from the input, returning the transaction
+// it yields, if any.
+func decodeTx(r *bytes.Reader) (*types.Transaction, error) {
+	op, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if op%opCount == opRawTx {
+		size, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		blob := make([]byte, size)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return nil, err
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(blob); err != nil {
+			return nil, nil
+		}
+		return tx, nil
+	}
+	// Transactions signed by the funded keys, with the fields taken from the input
+	fields := make([]byte, 7)
+	if _, err := io.ReadFull(r, fields); err != nil {
+		return nil, err
+	}
+	var (
+		key      = keys[int(fields[0])%len(keys)]
+		nonce    = uint64(fields[1] % 32)
+		gasPrice = big.NewInt(int64(fields[2]))
+		gas      = params.TxGas + (uint64(fields[3])<<8 | uint64(fields[4]))
+		to       = common.Address{fields[5]}
+		value    = big.NewInt(int64(fields[6]))
+		data     = make([]byte, int(fields[6]%64))
+	)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	var inner types.TxData
+	switch op % opCount {
+	case opLegacyTx:
+		inner = &types.LegacyTx{Nonce: nonce, GasPrice: gasPrice, Gas: gas, To: &to, Value: value, Data: data}
+	case opAccessListTx:
+		inner = &types.AccessListTx{ChainID: signer.ChainID(), Nonce: nonce, GasPrice: gasPrice, Gas: gas, To: &to, Value: value, Data: data,
+			AccessList: types.AccessList{{Address: to, StorageKeys: []common.Hash{{fields[0]}}}}}
+	case opBlobTx:
+		hash := common.Hash{params.BlobTxHashVersion, fields[0]}
+		inner = &types.BlobTx{ChainID: signer.ChainID(), Nonce: nonce, GasPrice: gasPrice, Gas: gas, To: to, Value: value, Data: data,
+			BlobFeeCap: big.NewInt(int64(fields[3])), BlobHashes: []common.Hash{hash}}
+	case opSetCodeTx:
+		auth, err := types.SignSetCode(keys[int(fields[5])%len(keys)], types.SetCodeAuthorization{ChainID: signer.ChainID(), Address: to, Nonce: uint64(fields[4] % 4)})
+		if err != nil {
+			panic(err)
+		}
+		inner = &types.SetCodeTx{ChainID: signer.ChainID(), Nonce: nonce, GasPrice: gasPrice, Gas: gas, To: to, Value: value, Data: data,
+			AuthList: []types.SetCodeAuthorization{auth}}
+	}
+	tx, err := types.SignNewTx(key, signer, inner)
+	if err != nil {
+		panic(err)
+	}
+	return tx, nil
+}
@@ -333,6 +333,19 @@ func (s *Sync) Pending() int {
 	return len(s.nodeReqs) + len(s.codeReqs)
 }
 
+// Fetched returns the trie nodes already retrieved but not yet committed, as
+// they are still waiting for some of their children. Feeding them back into a
+// fresh scheduler for the same root restores the retrieval progress.
+func (s *Sync) Fetched() [][]byte {
+	var blobs [][]byte
+	for _, req := range s.nodeReqs {
+		if req.data != nil {
+			blobs = append(blobs, req.data)
+		}
+	}
+	return blobs
+}
+
 // schedule inserts a new state retrieval request into the fetch queue. If there
 // is already a pending request for this node, the new request will be discarded
 // and only a parent reference added to the old one.
@@ -213,6 +213,77 @@ func TestIterativeDelayedSync(t *testing.T) {
 // Tests that given a root hash, a trie can sync iteratively on a single thread,
 // requesting retrieval tasks and returning all of them in one go, however in a
 // random order.
+// Tests that the nodes fetched but not committed by an interrupted sync can be
+// fed into a fresh scheduler, resuming without retrieving them again.
+func TestFetchedResumeSync(t *testing.T) {
+	// Create a random trie to copy
+	srcDb, srcTrie, srcData := makeTestTrie()
+
+	// Sync the top two levels of the trie and abandon the scheduler
+	diskdb := memorydb.New()
+	triedb := NewDatabase(diskdb)
+	sched := NewSync(srcTrie.Hash(), diskdb, nil, NewSyncBloom(1, diskdb))
+
+	for i := 0; i < 2; i++ {
+		nodes, _, _ := sched.Missing(10000)
+		for _, hash := range nodes {
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+			}
+			if err := sched.Process(SyncResult{hash, data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+	}
+	fetched := sched.Fetched()
+	if len(fetched) == 0 {
+		t.Fatalf("no fetched nodes reported")
+	}
+	journaled := make(map[common.Hash][]byte)
+	for _, blob := range fetched {
+		journaled[crypto.Keccak256Hash(blob)] = blob
+	}
+	// Replay the fetched nodes into a new scheduler and finish the sync
+	sched = NewSync(srcTrie.Hash(), diskdb, nil, NewSyncBloom(1, diskdb))
+
+	nodes, _, codes := sched.Missing(10000)
+	queue := append(append([]common.Hash{}, nodes...), codes...)
+	for len(queue) > 0 {
+		results := make([]SyncResult, len(queue))
+		for i, hash := range queue {
+			if data, ok := journaled[hash]; ok {
+				results[i] = SyncResult{hash, data}
+				continue
+			}
+			data, err := srcDb.Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x: %v", hash, err)
+			}
+			results[i] = SyncResult{hash, data}
+		}
+		for _, result := range results {
+			delete(journaled, result.Hash)
+			if err := sched.Process(result); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		batch := diskdb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+
+		nodes, _, codes = sched.Missing(10000)
+		queue = append(append(queue[:0], nodes...), codes...)
+	}
+	if len(journaled) != 0 {
+		t.Fatalf("journaled nodes not replayed: %d", len(journaled))
+	}
+	// Cross check that the two tries are in sync
+	checkTrieContents(t, triedb, srcTrie.Hash().Bytes(), srcData)
+}
+
 func TestIterativeRandomSyncIndividual(t *testing.T) { testIterativeRandomSync(t, 1) }
 func TestIterativeRandomSyncBatched(t *testing.T)    { testIterativeRandomSync(t, 100) }
 
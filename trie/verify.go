@@ -0,0 +1,130 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// NodeIssue is a damaged trie node found by VerifyTrie.
+type NodeIssue struct {
+	Owner   common.Hash   `json:"owner"`   // Hash of the account owning the storage trie, zero for the account trie
+	Path    hexutil.Bytes `json:"path"`    // Nibble path of the node in its trie
+	Hash    common.Hash   `json:"hash"`    // Hash the node is referenced by
+	Corrupt bool          `json:"corrupt"` // Whether the node is present but invalid, missing otherwise
+}
+
+func (issue NodeIssue) String() string {
+	kind := "missing"
+	if issue.Corrupt {
+		kind = "corrupt"
+	}
+	return fmt.Sprintf("%s node %x (owner %x, path %x)", kind, issue.Hash, issue.Owner, []byte(issue.Path))
+}
+
+// SyncPath returns the path the trie sync scheduler tracks the node by, which
+// for storage trie nodes is prefixed with the path of the owning account.
+func (issue NodeIssue) SyncPath() []byte {
+	if issue.Owner == (common.Hash{}) {
+		return common.CopyBytes(issue.Path)
+	}
+	return append(keybytesToHex(issue.Owner[:])[:2*common.HashLength], issue.Path...)
+}
+
+// VerifyTrie walks all the nodes of the trie with the given root, checking that
+// every one is present in the database and hashes to its reference. Unlike the
+// trie iterators it doesn't stop at the damaged nodes, but reports them to
+// onIssue and skips their subtries. The key and value of every leaf reached is
+// passed to onLeaf, an error of which aborts the walk. It returns the number of
+// nodes verified.
+func VerifyTrie(db ethdb.KeyValueReader, owner common.Hash, root common.Hash, onLeaf func(key, value []byte) error, onIssue func(NodeIssue)) (int, error) {
+	if root == emptyRoot || root == (common.Hash{}) {
+		return 0, nil
+	}
+	v := &trieVerifier{db: db, owner: owner, onLeaf: onLeaf, onIssue: onIssue}
+	if err := v.verify(hashNode(root[:]), nil); err != nil {
+		return v.nodes, err
+	}
+	return v.nodes, nil
+}
+
+// trieVerifier is the state of a VerifyTrie walk.
+type trieVerifier struct {
+	db      ethdb.KeyValueReader
+	owner   common.Hash
+	onLeaf  func(key, value []byte) error
+	onIssue func(NodeIssue)
+	nodes   int
+}
+
+func (v *trieVerifier) verify(n node, path []byte) error {
+	switch n := n.(type) {
+	case hashNode:
+		hash := common.BytesToHash(n)
+		blob := rawdb.ReadTrieNode(v.db, hash)
+		if len(blob) == 0 {
+			v.report(path, hash, false)
+			return nil
+		}
+		if crypto.Keccak256Hash(blob) != hash {
+			v.report(path, hash, true)
+			return nil
+		}
+		resolved, err := decodeNode(n, blob)
+		if err != nil {
+			v.report(path, hash, true)
+			return nil
+		}
+		v.nodes++
+		return v.verify(resolved, path)
+
+	case *shortNode:
+		return v.verify(n.Val, append(append([]byte{}, path...), n.Key...))
+
+	case *fullNode:
+		for i, child := range &n.Children {
+			if child == nil {
+				continue
+			}
+			if err := v.verify(child, append(append([]byte{}, path...), byte(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case valueNode:
+		if v.onLeaf == nil {
+			return nil
+		}
+		return v.onLeaf(hexToKeybytes(path), n)
+
+	default:
+		return fmt.Errorf("unexpected node type %T", n)
+	}
+}
+
+func (v *trieVerifier) report(path []byte, hash common.Hash, corrupt bool) {
+	if v.onIssue != nil {
+		v.onIssue(NodeIssue{Owner: v.owner, Path: common.CopyBytes(path), Hash: hash, Corrupt: corrupt})
+	}
+}
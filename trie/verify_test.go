@@ -0,0 +1,93 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that VerifyTrie walks every leaf of an intact trie, and reports missing
+// and corrupt nodes without aborting the walk.
+func TestVerifyTrie(t *testing.T) {
+	triedb, trie, content := makeTestTrie()
+	root := trie.Hash()
+	if err := triedb.Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	diskdb := triedb.DiskDB()
+
+	count := func() (int, int, []NodeIssue) {
+		var (
+			leaves int
+			issues []NodeIssue
+		)
+		nodes, err := VerifyTrie(diskdb, common.Hash{}, root, func(key, value []byte) error {
+			leaves++
+			return nil
+		}, func(issue NodeIssue) {
+			issues = append(issues, issue)
+		})
+		if err != nil {
+			t.Fatalf("failed to verify trie: %v", err)
+		}
+		return nodes, leaves, issues
+	}
+	nodes, leaves, issues := count()
+	if leaves != len(content) {
+		t.Fatalf("leaf count mismatch: have %d, want %d", leaves, len(content))
+	}
+	if len(issues) != 0 {
+		t.Fatalf("issues reported for intact trie: %v", issues)
+	}
+	// Pick two nodes below the root, delete one and corrupt the other
+	var damaged []common.Hash
+	it := trie.NodeIterator(nil)
+	for it.Next(true) && len(damaged) < 2 {
+		if hash := it.Hash(); hash != (common.Hash{}) && hash != root && !it.Leaf() {
+			damaged = append(damaged, hash)
+			it.Next(false) // don't pick a descendant of the same node
+		}
+	}
+	if len(damaged) != 2 {
+		t.Fatalf("failed to pick nodes to damage")
+	}
+	rawdb.DeleteTrieNode(diskdb, damaged[0])
+	rawdb.WriteTrieNode(diskdb, damaged[1], []byte{0xde, 0xad})
+
+	damagedNodes, damagedLeaves, issues := count()
+	if len(issues) != 2 {
+		t.Fatalf("issue count mismatch: have %d, want 2: %v", len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.Hash != damaged[i] {
+			t.Errorf("issue %d: hash mismatch: have %x, want %x", i, issue.Hash, damaged[i])
+		}
+		if issue.Corrupt != (i == 1) {
+			t.Errorf("issue %d: corrupt flag mismatch: have %v", i, issue.Corrupt)
+		}
+		if crypto.Keccak256Hash(rawdb.ReadTrieNode(diskdb, issue.Hash)) == issue.Hash {
+			t.Errorf("issue %d: node is intact", i)
+		}
+	}
+	if damagedNodes >= nodes || damagedLeaves == 0 || damagedLeaves >= leaves {
+		t.Errorf("walk did not skip only the damaged subtries: nodes %d/%d, leaves %d/%d", damagedNodes, nodes, damagedLeaves, leaves)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// maxWatchedAddresses is the maximum number of addresses watched by a webhook.
+const maxWatchedAddresses = 100000
+
+var errUnknownWebhook = errors.New("unknown webhook")
+
+// Registration is a newly registered webhook, along with the secret signing its
+// notifications.
+type Registration struct {
+	ID     string        `json:"id"`
+	Secret hexutil.Bytes `json:"secret"`
+}
+
+// WebhookInfo describes a registered webhook.
+type WebhookInfo struct {
+	ID        string           `json:"id"`
+	URL       string           `json:"url"`
+	Addresses []common.Address `json:"addresses"`
+	Block     *hexutil.Uint64  `json:"block"` // Last block notified
+}
+
+// API manages the webhooks of the webhook service.
+type API struct {
+	service *Service
+}
+
+// Register registers a webhook to be notified of the activity of the given
+// addresses in the blocks after the current head, returning its id and the
+// secret signing its notifications.
+func (api *API) Register(rawurl string, addresses []common.Address) (*Registration, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported webhook URL scheme %q", u.Scheme)
+	}
+	if len(addresses) > maxWatchedAddresses {
+		return nil, fmt.Errorf("too many addresses, at most %d can be watched", maxWatchedAddresses)
+	}
+	var id, secret [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret[:]); err != nil {
+		return nil, err
+	}
+	hook := &rawdb.Webhook{
+		ID:        hex.EncodeToString(id[:]),
+		URL:       rawurl,
+		Secret:    secret[:],
+		Addresses: dedupAddresses(addresses),
+	}
+	api.service.register(hook)
+	return &Registration{ID: hook.ID, Secret: secret[:]}, nil
+}
+
+// AddAddresses adds addresses to the watch-list of a webhook.
+func (api *API) AddAddresses(id string, addresses []common.Address) error {
+	var err error
+	ok := api.service.update(id, func(hook *rawdb.Webhook) {
+		merged := dedupAddresses(append(append([]common.Address{}, hook.Addresses...), addresses...))
+		if len(merged) > maxWatchedAddresses {
+			err = fmt.Errorf("too many addresses, at most %d can be watched", maxWatchedAddresses)
+			return
+		}
+		hook.Addresses = merged
+	})
+	if !ok {
+		return errUnknownWebhook
+	}
+	return err
+}
+
+// RemoveAddresses removes addresses from the watch-list of a webhook.
+func (api *API) RemoveAddresses(id string, addresses []common.Address) error {
+	removed := make(map[common.Address]bool, len(addresses))
+	for _, address := range addresses {
+		removed[address] = true
+	}
+	ok := api.service.update(id, func(hook *rawdb.Webhook) {
+		kept := make([]common.Address, 0, len(hook.Addresses))
+		for _, address := range hook.Addresses {
+			if !removed[address] {
+				kept = append(kept, address)
+			}
+		}
+		hook.Addresses = kept
+	})
+	if !ok {
+		return errUnknownWebhook
+	}
+	return nil
+}
+
+// Unregister stops and deletes a webhook.
+func (api *API) Unregister(id string) error {
+	if !api.service.unregister(id) {
+		return errUnknownWebhook
+	}
+	return nil
+}
+
+// List returns the registered webhooks, without their secrets.
+func (api *API) List() []*WebhookInfo {
+	hooks := api.service.webhooks()
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+
+	infos := make([]*WebhookInfo, len(hooks))
+	for i, hook := range hooks {
+		infos[i] = &WebhookInfo{ID: hook.ID, URL: hook.URL, Addresses: hook.Addresses}
+		if _, number, ok := rawdb.ReadWebhookCheckpoint(api.service.db, hook.ID); ok {
+			infos[i].Block = (*hexutil.Uint64)(&number)
+		}
+	}
+	return infos
+}
+
+// dedupAddresses returns the distinct addresses of a list, in their first order.
+func dedupAddresses(addresses []common.Address) []common.Address {
+	var (
+		seen   = make(map[common.Address]bool, len(addresses))
+		unique = make([]common.Address, 0, len(addresses))
+	)
+	for _, address := range addresses {
+		if !seen[address] {
+			seen[address] = true
+			unique = append(unique, address)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,490 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package webhook notifies external services of the activity of watched addresses.
+//
+// Operators register address watch-lists along with a callback URL. Every time a
+// watched address sends or receives a transaction, or emits a log, in a block
+// becoming canonical, a JSON notification is POSTed to the URL, signed with an
+// HMAC-SHA256 of the body keyed by the secret of the webhook. When notified
+// blocks are dropped from the canonical chain, their activity is notified again
+// marked as removed. The last notified block is checkpointed per webhook, so a
+// failing endpoint is retried without delaying the others, and notifications
+// are delivered at least once across restarts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// minRetryDelay and maxRetryDelay bound the exponential backoff between the
+	// attempts to deliver a notification.
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+
+	// deliveryTimeout is the maximum time to wait for an endpoint to accept a
+	// single notification.
+	deliveryTimeout = 10 * time.Second
+
+	// SignatureHeader is the HTTP header carrying the hex encoded HMAC-SHA256
+	// of the notification body, prefixed with "sha256=".
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+var (
+	deliveryMeter = metrics.NewRegisteredMeter("webhook/deliveries", nil)
+	failureMeter  = metrics.NewRegisteredMeter("webhook/failures", nil)
+)
+
+// blockChain is the chain access needed by the webhook service, implemented by
+// core.BlockChain.
+type blockChain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetCanonicalHash(number uint64) common.Hash
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Activity is an appearance of a watched address in a block.
+type Activity struct {
+	Address          common.Address  `json:"address"`
+	Role             string          `json:"role"` // "sender", "recipient" or "log"
+	TransactionHash  common.Hash     `json:"transactionHash"`
+	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
+	LogIndex         *hexutil.Uint64 `json:"logIndex,omitempty"`
+}
+
+// Notification is the body POSTed to a webhook, listing the activity of its
+// watched addresses in a block.
+type Notification struct {
+	ID          string         `json:"id"` // Unique per webhook, block and removal, to deduplicate retries
+	Webhook     string         `json:"webhook"`
+	Removed     bool           `json:"removed"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	Activity    []*Activity    `json:"activity"`
+}
+
+// Service is a node lifecycle notifying the registered webhooks of the activity
+// of their watched addresses.
+type Service struct {
+	chain  blockChain
+	db     ethdb.KeyValueStore // database holding the webhooks and their checkpoints
+	client *http.Client
+
+	mu       sync.Mutex
+	watchers map[string]*watcher
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a webhook service and registers it, along with its webhook API
+// namespace, with the node.
+func New(stack *node.Node, chain *core.BlockChain, db ethdb.KeyValueStore) {
+	service := newService(chain, db)
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "webhook",
+		Version:   "1.0",
+		Service:   &API{service},
+	}})
+	stack.RegisterLifecycle(service)
+}
+
+func newService(chain blockChain, db ethdb.KeyValueStore) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		chain:    chain,
+		db:       db,
+		client:   &http.Client{Timeout: deliveryTimeout},
+		watchers: make(map[string]*watcher),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start implements node.Lifecycle, resuming the registered webhooks.
+func (s *Service) Start() error {
+	hooks := rawdb.ReadWebhooks(s.db)
+
+	s.mu.Lock()
+	for _, hook := range hooks {
+		s.startWatcher(hook)
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.loop()
+	log.Info("Started webhook service", "webhooks", len(hooks))
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the deliveries.
+func (s *Service) Stop() error {
+	s.cancel()
+	s.wg.Wait()
+	log.Info("Stopped webhook service")
+	return nil
+}
+
+// loop wakes the webhooks up every time the head changes.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-heads:
+			s.mu.Lock()
+			for _, w := range s.watchers {
+				w.wake()
+			}
+			s.mu.Unlock()
+		case <-sub.Err():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// register stores a new webhook, to be notified of the blocks after the current
+// head, and starts delivering its notifications.
+func (s *Service) register(hook *rawdb.Webhook) {
+	head := s.chain.CurrentBlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawdb.WriteWebhook(s.db, hook)
+	rawdb.WriteWebhookCheckpoint(s.db, hook.ID, head.Hash(), head.NumberU64())
+	s.startWatcher(hook)
+}
+
+// update replaces the watch-list of a webhook, returning false if it's unknown.
+func (s *Service) update(id string, fn func(hook *rawdb.Webhook)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.watchers[id]
+	if w == nil {
+		return false
+	}
+	w.stop()
+	hook := *w.hook
+	fn(&hook)
+	rawdb.WriteWebhook(s.db, &hook)
+	s.startWatcher(&hook)
+	return true
+}
+
+// unregister stops and deletes a webhook, returning false if it's unknown.
+func (s *Service) unregister(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.watchers[id]
+	if w == nil {
+		return false
+	}
+	w.stop()
+	delete(s.watchers, id)
+	rawdb.DeleteWebhook(s.db, id)
+	return true
+}
+
+// webhooks returns the registered webhooks.
+func (s *Service) webhooks() []*rawdb.Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks := make([]*rawdb.Webhook, 0, len(s.watchers))
+	for _, w := range s.watchers {
+		hooks = append(hooks, w.hook)
+	}
+	return hooks
+}
+
+// startWatcher starts delivering the notifications of a webhook. The lock must
+// be held.
+func (s *Service) startWatcher(hook *rawdb.Webhook) {
+	w := newWatcher(s, hook)
+	s.watchers[hook.ID] = w
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		w.loop()
+	}()
+}
+
+// watcher delivers the notifications of a single webhook.
+type watcher struct {
+	service   *Service
+	hook      *rawdb.Webhook
+	addresses map[common.Address]struct{}
+
+	wakeCh chan struct{}
+	quitCh chan struct{}
+	done   chan struct{}
+}
+
+func newWatcher(service *Service, hook *rawdb.Webhook) *watcher {
+	addresses := make(map[common.Address]struct{}, len(hook.Addresses))
+	for _, address := range hook.Addresses {
+		addresses[address] = struct{}{}
+	}
+	return &watcher{
+		service:   service,
+		hook:      hook,
+		addresses: addresses,
+		wakeCh:    make(chan struct{}, 1),
+		quitCh:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// wake schedules the processing of the blocks past the checkpoint.
+func (w *watcher) wake() {
+	select {
+	case w.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// stop terminates the watcher and waits for its in-flight delivery.
+func (w *watcher) stop() {
+	close(w.quitCh)
+	<-w.done
+}
+
+// loop notifies the blocks past the checkpoint every time the watcher is woken
+// up. Failed deliveries are retried with an exponential backoff.
+func (w *watcher) loop() {
+	defer close(w.done)
+
+	delay := minRetryDelay
+	for {
+		var retry <-chan time.Time
+		if err := w.process(); err != nil {
+			failureMeter.Mark(1)
+			log.Warn("Failed to deliver webhook notification", "id", w.hook.ID, "url", w.hook.URL, "retry", delay, "err", err)
+			retry = time.After(delay)
+			if delay *= 2; delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+		} else {
+			delay = minRetryDelay
+		}
+		select {
+		case <-w.wakeCh:
+		case <-retry:
+		case <-w.quitCh:
+			return
+		case <-w.service.ctx.Done():
+			return
+		}
+	}
+}
+
+// process notifies the activity in the blocks dropped from the canonical chain
+// since the checkpoint, then in the canonical blocks after it up to the current
+// head, advancing the checkpoint after every block.
+func (w *watcher) process() error {
+	var (
+		chain = w.service.chain
+		head  = chain.CurrentBlock()
+	)
+	hash, number, ok := rawdb.ReadWebhookCheckpoint(w.service.db, w.hook.ID)
+	if !ok {
+		hash, number = head.Hash(), head.NumberU64()
+		w.checkpoint(hash, number)
+	}
+	for chain.GetCanonicalHash(number) != hash {
+		block := chain.GetBlock(hash, number)
+		if block == nil {
+			return fmt.Errorf("notified block #%d [%x…] missing", number, hash[:4])
+		}
+		if err := w.notify(block, true); err != nil {
+			return err
+		}
+		hash, number = block.ParentHash(), number-1
+		w.checkpoint(hash, number)
+	}
+	for n := number + 1; n <= head.NumberU64(); n++ {
+		if w.stopped() {
+			return nil
+		}
+		block := chain.GetBlockByNumber(n)
+		if block == nil {
+			return fmt.Errorf("canonical block #%d missing", n)
+		}
+		if block.ParentHash() != hash {
+			return fmt.Errorf("chain reorganised while notifying block #%d", n)
+		}
+		if err := w.notify(block, false); err != nil {
+			return err
+		}
+		hash = block.Hash()
+		w.checkpoint(hash, n)
+	}
+	return nil
+}
+
+// stopped reports whether the watcher or the whole service is shutting down.
+func (w *watcher) stopped() bool {
+	select {
+	case <-w.quitCh:
+		return true
+	case <-w.service.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *watcher) checkpoint(hash common.Hash, number uint64) {
+	rawdb.WriteWebhookCheckpoint(w.service.db, w.hook.ID, hash, number)
+}
+
+// notify delivers the activity of the watched addresses in a block, if any.
+func (w *watcher) notify(block *types.Block, removed bool) error {
+	activity := w.activity(block)
+	if len(activity) == 0 {
+		return nil
+	}
+	kind := "added"
+	if removed {
+		kind = "removed"
+	}
+	body, err := json.Marshal(&Notification{
+		ID:          fmt.Sprintf("%s-%x-%s", w.hook.ID, block.Hash(), kind),
+		Webhook:     w.hook.ID,
+		Removed:     removed,
+		BlockHash:   block.Hash(),
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		Activity:    activity,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(w.service.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-w.quitCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, "sha256="+Sign(w.hook.Secret, body))
+
+	res, err := w.service.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %s", res.Status)
+	}
+	deliveryMeter.Mark(1)
+	return nil
+}
+
+// activity collects the appearances of the watched addresses in a block, as
+// sender or recipient (or created contract) of a transaction, or as emitter of
+// a log.
+func (w *watcher) activity(block *types.Block) []*Activity {
+	var (
+		signer   = types.MakeSigner(w.service.chain.Config(), block.Number())
+		receipts = w.service.chain.GetReceiptsByHash(block.Hash())
+		activity []*Activity
+	)
+	for i, tx := range block.Transactions() {
+		add := func(address common.Address, role string, logIndex *hexutil.Uint64) {
+			if _, ok := w.addresses[address]; ok {
+				activity = append(activity, &Activity{
+					Address:          address,
+					Role:             role,
+					TransactionHash:  tx.Hash(),
+					TransactionIndex: hexutil.Uint(i),
+					LogIndex:         logIndex,
+				})
+			}
+		}
+		if from, err := types.Sender(signer, tx); err == nil {
+			add(from, "sender", nil)
+		}
+		if to := tx.To(); to != nil {
+			add(*to, "recipient", nil)
+		} else if i < len(receipts) && receipts[i].ContractAddress != (common.Address{}) {
+			add(receipts[i].ContractAddress, "recipient", nil)
+		}
+		if i < len(receipts) {
+			for _, log := range receipts[i].Logs {
+				index := hexutil.Uint64(log.Index)
+				add(log.Address, "log", &index)
+			}
+		}
+	}
+	return activity
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of a notification body, keyed by the
+// secret of the webhook.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testEndpoint records the notifications it receives, checking their signature,
+// and rejects them while broken.
+type testEndpoint struct {
+	secret []byte
+
+	mu            sync.Mutex
+	notifications []*Notification
+	broken        bool
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if e.broken || r.Header.Get(SignatureHeader) != "sha256="+Sign(e.secret, body) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	notification := new(Notification)
+	if err := json.Unmarshal(body, notification); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	e.notifications = append(e.notifications, notification)
+}
+
+// numbers summarises the received notifications: block numbers, negated for the
+// removed blocks.
+func (e *testEndpoint) numbers() []int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var numbers []int64
+	for _, n := range e.notifications {
+		if n.Removed {
+			numbers = append(numbers, -int64(n.BlockNumber))
+		} else {
+			numbers = append(numbers, int64(n.BlockNumber))
+		}
+	}
+	return numbers
+}
+
+func checkNumbers(t *testing.T, have, want []int64) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("notifications mismatch: have %v, want %v", have, want)
+	}
+	for i := range have {
+		if have[i] != want[i] {
+			t.Fatalf("notifications mismatch: have %v, want %v", have, want)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		watched = common.HexToAddress("0xbeef")
+		db      = rawdb.NewMemoryDatabase()
+		engine  = ethash.NewFaker()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}}}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	// Every block sends a transaction to the watched address
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 4, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(sender), watched, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		endpoint = &testEndpoint{secret: []byte("secret")}
+		server   = httptest.NewServer(endpoint)
+		hook     = &rawdb.Webhook{ID: "test", URL: server.URL, Secret: endpoint.secret, Addresses: []common.Address{watched}}
+		service  = newService(chain, db)
+	)
+	defer server.Close()
+
+	// Without a checkpoint, the notifications start after the current head.
+	if err := newWatcher(service, hook).process(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, endpoint.numbers(), nil)
+
+	// Failed deliveries don't advance the checkpoint and are retried.
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatal(err)
+	}
+	endpoint.broken = true
+	if err := newWatcher(service, hook).process(); err == nil {
+		t.Fatal("delivery succeeded to a broken endpoint")
+	}
+	endpoint.broken = false
+	if err := newWatcher(service, hook).process(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, endpoint.numbers(), []int64{3, 4})
+
+	activity := endpoint.notifications[0].Activity
+	if len(activity) != 1 || activity[0].Address != watched || activity[0].Role != "recipient" {
+		t.Fatalf("activity mismatch: %+v", activity)
+	}
+	// A heavier fork without activity replacing notified blocks notifies them
+	// as removed, from the head down.
+	fork, _ := core.GenerateChain(params.TestChainConfig, blocks[1], engine, db, 4, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatal(err)
+	}
+	if err := newWatcher(service, hook).process(); err != nil {
+		t.Fatal(err)
+	}
+	checkNumbers(t, endpoint.numbers(), []int64{3, 4, -4, -3})
+
+	if hash, number, _ := rawdb.ReadWebhookCheckpoint(db, hook.ID); hash != fork[3].Hash() || number != 6 {
+		t.Fatalf("checkpoint mismatch: have #%d [%x], want #6 [%x]", number, hash, fork[3].Hash())
+	}
+}